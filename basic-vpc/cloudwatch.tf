@@ -154,6 +154,24 @@ resource "aws_cloudwatch_dashboard" "security_dashboard" {
           region = "us-east-1"
           title  = "EC2 Network In"
         }
+      },
+      {
+        type   = "metric"
+        x      = 0
+        y      = 6
+        width  = 12
+        height = 6
+
+        properties = {
+          metrics = [
+            ["AWS/EC2", "StatusCheckFailed", "InstanceId", aws_instance.public.id],
+            [".", ".", ".", aws_instance.private.id]
+          ]
+          period = 300
+          stat   = "Maximum"
+          region = "us-east-1"
+          title  = "EC2 Status Check Failed"
+        }
       }
     ]
   })
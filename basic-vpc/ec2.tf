@@ -28,7 +28,7 @@ resource "aws_security_group" "public_sg" {
   }
 }
 
-# Security Group for Private EC2 (allow inbound HTTP from public SG, outbound all, including 443 for SSM)
+# Security Group for Private EC2 (allow inbound HTTP from public SG, outbound per egress_profile)
 resource "aws_security_group" "private_sg" {
   name        = "private-ec2-sg-${var.environment}"
   description = "Security group for private EC2 instance with restricted access"
@@ -42,12 +42,17 @@ resource "aws_security_group" "private_sg" {
     security_groups = [aws_security_group.public_sg.id] # Allow from public instance
   }
 
-  egress {
-    description = "Allow all outbound traffic for updates and SSM"
-    from_port   = 0
-    to_port     = 0
-    protocol    = "-1"
-    cidr_blocks = ["0.0.0.0/0"]
+  # Outbound traffic depends on the egress profile (see egress.tf)
+  dynamic "egress" {
+    for_each = local.private_sg_egress
+    content {
+      description     = egress.value.description
+      from_port       = egress.value.from_port
+      to_port         = egress.value.to_port
+      protocol        = egress.value.protocol
+      cidr_blocks     = egress.value.cidr_blocks
+      prefix_list_ids = egress.value.prefix_list_ids
+    }
   }
 
   tags = {
@@ -56,7 +61,7 @@ resource "aws_security_group" "private_sg" {
   }
 }
 
-# AMI for Amazon Linux 2 (latest)
+# AMI for Amazon Linux 2 (latest). The encrypted gp3 root needs it EBS-backed.
 data "aws_ami" "amazon_linux" {
   most_recent = true
   owners      = ["amazon"]
@@ -65,51 +70,80 @@ data "aws_ami" "amazon_linux" {
     name   = "name"
     values = ["amzn2-ami-hvm-*-x86_64-gp2"]
   }
+
+  filter {
+    name   = "root-device-type"
+    values = ["ebs"]
+  }
+
+  lifecycle {
+    postcondition {
+      condition     = self.root_device_type == "ebs"
+      error_message = "AMI ${self.id} is ${self.root_device_type}-backed; the instances need an EBS-backed AMI."
+    }
+  }
+}
+
+# The instance type must boot the AMI, and have local storage for scratch disks
+data "aws_ec2_instance_type" "selected" {
+  instance_type = var.instance_type
+
+  lifecycle {
+    postcondition {
+      condition     = !var.enable_scratch_disks || self.instance_storage_supported
+      error_message = "enable_scratch_disks needs an instance type with instance storage; ${var.instance_type} has none."
+    }
+    postcondition {
+      condition     = contains(self.supported_architectures, data.aws_ami.amazon_linux.architecture)
+      error_message = "${var.instance_type} supports ${join(", ", self.supported_architectures)}, but AMI ${data.aws_ami.amazon_linux.id} is ${data.aws_ami.amazon_linux.architecture}."
+    }
+  }
 }
 
 # User Data script for Apache HTTP server with security hardening
 locals {
-  user_data_script = <<-EOF
-    #!/bin/bash
-    # Security hardening script
-    yum update -y
-    yum install -y httpd mod_security mod_ssl
-    
-    # Configure Apache security headers
-    cat > /etc/httpd/conf.d/security-headers.conf << 'APACHE_EOF'
-    Header always set X-Content-Type-Options nosniff
-    Header always set X-Frame-Options DENY
-    Header always set X-XSS-Protection "1; mode=block"
-    Header always set Strict-Transport-Security "max-age=31536000; includeSubDomains"
-    Header always set Referrer-Policy "strict-origin-when-cross-origin"
-    Header always set Content-Security-Policy "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'"
-    APACHE_EOF
-    
-    # Disable Apache server signature
-    sed -i 's/ServerTokens OS/ServerTokens Prod/' /etc/httpd/conf/httpd.conf
-    sed -i 's/ServerSignature On/ServerSignature Off/' /etc/httpd/conf/httpd.conf
-    
-    # Start and enable Apache
-    systemctl start httpd
-    systemctl enable httpd
-    
-    # Get instance private IP
-    PRIVATE_IP=$(curl http://169.254.169.254/latest/meta-data/local-ipv4)
-    echo $PRIVATE_IP > /var/www/html/index.html
-    
-    # Security: Remove default Apache welcome page
-    rm -f /etc/httpd/conf.d/welcome.conf
-    
-    # Security: Set proper file permissions
-    chown -R apache:apache /var/www/html
-    chmod -R 755 /var/www/html
-  EOF
+  root_volume_size = 20
+
+  user_data_vars = {
+    packages             = var.user_data_packages
+    web_root_content     = var.web_root_content
+    health_check_content = var.health_check_content
+  }
+
+  private_user_data = templatefile("${path.module}/templates/user_data.sh.tftpl", merge(local.user_data_vars, {
+    probe_url = ""
+  }))
+
+  # Public instance additionally probes the private instance once it is up
+  public_user_data = templatefile("${path.module}/templates/user_data.sh.tftpl", merge(local.user_data_vars, {
+    probe_url = "http://${aws_instance.private.private_ip}:80"
+  }))
+}
+
+# Customer-managed key for the root volumes. The default key policy leaves
+# access to IAM in this account, which is all EBS needs.
+resource "aws_kms_key" "ebs" {
+  count                   = local.create_kms_key ? 1 : 0
+  description             = "Customer-managed key for basic-vpc EBS root volumes"
+  enable_key_rotation     = true
+  deletion_window_in_days = 30
+
+  tags = {
+    Name        = "basic-vpc-${var.environment}-ebs"
+    Environment = var.environment
+  }
+}
+
+resource "aws_kms_alias" "ebs" {
+  count         = local.create_kms_key ? 1 : 0
+  name          = "alias/basic-vpc-${var.environment}-ebs"
+  target_key_id = aws_kms_key.ebs[0].key_id
 }
 
 # Private EC2 Instance with encryption at rest
 resource "aws_instance" "private" {
   ami                    = data.aws_ami.amazon_linux.id
-  instance_type          = "t3.micro"
+  instance_type          = data.aws_ec2_instance_type.selected.instance_type
   subnet_id              = aws_subnet.private.id
   vpc_security_group_ids = [aws_security_group.private_sg.id]
   iam_instance_profile   = aws_iam_instance_profile.ssm_profile.name
@@ -117,15 +151,50 @@ resource "aws_instance" "private" {
   # Enable encryption at rest
   root_block_device {
     volume_type           = "gp3"
-    volume_size           = 20
+    volume_size           = local.root_volume_size
+    iops                  = var.root_volume_iops
+    throughput            = var.root_volume_throughput
     encrypted             = true
+    kms_key_id            = one(aws_kms_key.ebs[*].arn)
     delete_on_termination = true
   }
 
-  user_data = local.user_data_script
+  # Optionally map the instance type's local storage as scratch space
+  dynamic "ephemeral_block_device" {
+    for_each = var.enable_scratch_disks ? [1] : []
+    content {
+      device_name  = "/dev/sdb"
+      virtual_name = "ephemeral0"
+    }
+  }
+
+  user_data = local.private_user_data
 
-  # Enable detailed monitoring
-  monitoring = true
+  # Detailed monitoring is on unless cost matters more than 1-minute metrics
+  monitoring = var.detailed_monitoring
+
+  # IMDSv2 only, with a hop limit of 1 so containers on the host can't reach it
+  metadata_options {
+    http_endpoint               = "enabled"
+    http_tokens                 = "required"
+    http_put_response_hop_limit = 1
+    instance_metadata_tags      = var.enable_instance_metadata_tags ? "enabled" : "disabled"
+  }
+
+  # Guard against accidental termination from the console/API
+  disable_api_termination = local.enable_deletion_protection
+
+  # gp3 ties the ceilings together: 500 IOPS per GiB and 0.25 MiB/s per IOPS
+  lifecycle {
+    precondition {
+      condition     = var.root_volume_iops <= 500 * local.root_volume_size
+      error_message = "root_volume_iops can be at most ${500 * local.root_volume_size} for the ${local.root_volume_size} GiB root volumes."
+    }
+    precondition {
+      condition     = var.root_volume_throughput <= var.root_volume_iops / 4
+      error_message = "root_volume_throughput can be at most root_volume_iops / 4 MiB/s (${floor(var.root_volume_iops / 4)} for ${var.root_volume_iops} IOPS)."
+    }
+  }
 
   tags = {
     Name        = "private-ec2"
@@ -136,7 +205,7 @@ resource "aws_instance" "private" {
 # Public EC2 Instance with encryption at rest
 resource "aws_instance" "public" {
   ami                    = data.aws_ami.amazon_linux.id
-  instance_type          = "t3.micro"
+  instance_type          = data.aws_ec2_instance_type.selected.instance_type
   subnet_id              = aws_subnet.public.id
   vpc_security_group_ids = [aws_security_group.public_sg.id]
   iam_instance_profile   = aws_iam_instance_profile.ssm_profile.name
@@ -144,19 +213,36 @@ resource "aws_instance" "public" {
   # Enable encryption at rest
   root_block_device {
     volume_type           = "gp3"
-    volume_size           = 20
+    volume_size           = local.root_volume_size
+    iops                  = var.root_volume_iops
+    throughput            = var.root_volume_throughput
     encrypted             = true
+    kms_key_id            = one(aws_kms_key.ebs[*].arn)
     delete_on_termination = true
   }
 
-  user_data = <<-EOF
-    ${local.user_data_script}
-    # Curl the private instance and log the response
-    curl http://${aws_instance.private.private_ip}:80 > /tmp/private_ip_response.log
-  EOF
+  dynamic "ephemeral_block_device" {
+    for_each = var.enable_scratch_disks ? [1] : []
+    content {
+      device_name  = "/dev/sdb"
+      virtual_name = "ephemeral0"
+    }
+  }
+
+  user_data = local.public_user_data
+
+  monitoring = var.detailed_monitoring
+
+  # Same metadata options as the private instance
+  metadata_options {
+    http_endpoint               = "enabled"
+    http_tokens                 = "required"
+    http_put_response_hop_limit = 1
+    instance_metadata_tags      = var.enable_instance_metadata_tags ? "enabled" : "disabled"
+  }
 
-  # Enable detailed monitoring
-  monitoring = true
+  # Guard against accidental termination from the console/API
+  disable_api_termination = local.enable_deletion_protection
 
   tags = {
     Name        = "public-ec2"
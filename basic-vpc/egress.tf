@@ -0,0 +1,40 @@
+# Private subnet egress profile: composes NACL egress, security group egress
+# and endpoint usage so the private instance can be limited to AWS APIs
+locals {
+  egress_restricted = local.egress_profile != "open"
+  egress_ports      = local.egress_profile == "custom-ports" ? var.egress_allowed_ports : []
+
+  # NACLs can't reference the S3 prefix list, so restricted profiles allow
+  # HTTPS anywhere here and leave the narrowing to the security group
+  private_nacl_egress = local.egress_restricted ? concat(
+    [{ rule_no = 100, protocol = "tcp", cidr_block = "0.0.0.0/0", from_port = 443, to_port = 443 }],
+    [for i, port in local.egress_ports : { rule_no = 110 + i * 10, protocol = "tcp", cidr_block = "0.0.0.0/0", from_port = port, to_port = port } if port != 443],
+    [{ rule_no = 200, protocol = "tcp", cidr_block = var.vpc_cidr, from_port = 1024, to_port = 65535 }],
+    ) : [
+    { rule_no = 100, protocol = "-1", cidr_block = "0.0.0.0/0", from_port = 0, to_port = 0 },
+  ]
+
+  private_sg_egress = local.egress_restricted ? concat(
+    [
+      { description = "HTTPS to the interface endpoints in the VPC", from_port = 443, to_port = 443, protocol = "tcp", cidr_blocks = [var.vpc_cidr], prefix_list_ids = [] },
+      { description = "HTTPS to S3 through the gateway endpoint", from_port = 443, to_port = 443, protocol = "tcp", cidr_blocks = [], prefix_list_ids = [aws_vpc_endpoint.s3[0].prefix_list_id] },
+    ],
+    [for port in local.egress_ports : { description = "Allowed internet port ${port}", from_port = port, to_port = port, protocol = "tcp", cidr_blocks = ["0.0.0.0/0"], prefix_list_ids = [] }],
+    ) : [
+    { description = "Allow all outbound traffic for updates and SSM", from_port = 0, to_port = 0, protocol = "-1", cidr_blocks = ["0.0.0.0/0"], prefix_list_ids = [] },
+  ]
+}
+
+# S3 gateway endpoint keeps package repositories and S3 APIs reachable without the NAT
+resource "aws_vpc_endpoint" "s3" {
+  count             = local.egress_restricted ? 1 : 0
+  vpc_id            = aws_vpc.main.id
+  service_name      = "com.amazonaws.${var.region}.s3"
+  vpc_endpoint_type = "Gateway"
+  route_table_ids   = [aws_route_table.private.id]
+
+  tags = {
+    Name        = "s3-endpoint"
+    Environment = var.environment
+  }
+}
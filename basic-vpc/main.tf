@@ -11,10 +11,12 @@ resource "aws_vpc" "main" {
 
 # VPC Flow Logs for network monitoring
 resource "aws_flow_log" "vpc_flow_log" {
-  iam_role_arn    = aws_iam_role.vpc_flow_log_role.arn
-  log_destination = aws_cloudwatch_log_group.vpc_flow_log.arn
-  traffic_type    = "ALL"
-  vpc_id          = aws_vpc.main.id
+  iam_role_arn             = aws_iam_role.vpc_flow_log_role.arn
+  log_destination          = aws_cloudwatch_log_group.vpc_flow_log.arn
+  log_format               = var.flow_log_format
+  max_aggregation_interval = var.flow_log_max_aggregation_interval
+  traffic_type             = "ALL"
+  vpc_id                   = aws_vpc.main.id
 
   tags = {
     Name        = "vpc-flow-log"
@@ -25,7 +27,7 @@ resource "aws_flow_log" "vpc_flow_log" {
 # CloudWatch Log Group for VPC Flow Logs
 resource "aws_cloudwatch_log_group" "vpc_flow_log" {
   name              = "/aws/vpc/flowlogs"
-  retention_in_days = 30
+  retention_in_days = local.flow_log_retention_days
 
   tags = {
     Name        = "vpc-flow-logs"
@@ -127,6 +129,42 @@ resource "aws_s3_bucket_versioning" "cloudtrail_bucket" {
   }
 }
 
+# Expire trail logs (and their noncurrent versions) so the bucket doesn't grow forever
+resource "aws_s3_bucket_lifecycle_configuration" "cloudtrail_bucket" {
+  bucket = aws_s3_bucket.cloudtrail_bucket.id
+
+  rule {
+    id     = "expire-trail-logs"
+    status = "Enabled"
+
+    filter {}
+
+    expiration {
+      days = var.cloudtrail_bucket_retention_days
+    }
+
+    noncurrent_version_expiration {
+      noncurrent_days = 30
+    }
+
+    abort_incomplete_multipart_upload {
+      days_after_initiation = 7
+    }
+  }
+
+  # Lifecycle rules on a versioned bucket need versioning in place first
+  depends_on = [aws_s3_bucket_versioning.cloudtrail_bucket]
+}
+
+# CloudTrail still sends the bucket-owner-full-control ACL the policy below
+# requires; BucketOwnerEnforced accepts it and ignores every other ACL
+resource "aws_s3_bucket_ownership_controls" "cloudtrail_bucket" {
+  bucket = aws_s3_bucket.cloudtrail_bucket.id
+  rule {
+    object_ownership = "BucketOwnerEnforced"
+  }
+}
+
 # S3 Bucket server-side encryption for CloudTrail
 resource "aws_s3_bucket_server_side_encryption_configuration" "cloudtrail_bucket" {
   bucket = aws_s3_bucket.cloudtrail_bucket.id
@@ -1,3 +1,8 @@
+# In ssm-only mode no NACL admits port 22
+locals {
+  ssh_enabled = local.access_mode == "ssh+ssm"
+}
+
 # Network ACLs for additional security layer
 resource "aws_network_acl" "public" {
   vpc_id     = aws_vpc.main.id
@@ -23,14 +28,17 @@ resource "aws_network_acl" "public" {
     to_port    = 443
   }
 
-  # Allow inbound SSH from specific IPs
-  ingress {
-    protocol   = "tcp"
-    rule_no    = 120
-    action     = "allow"
-    cidr_block = var.allowed_ssh_cidrs[0] != "" ? var.allowed_ssh_cidrs[0] : "10.0.0.0/8"
-    from_port  = 22
-    to_port    = 22
+  # Allow inbound SSH from each allowed CIDR (not in ssm-only mode)
+  dynamic "ingress" {
+    for_each = local.ssh_enabled ? var.allowed_ssh_cidrs : []
+    content {
+      protocol   = "tcp"
+      rule_no    = 120 + ingress.key
+      action     = "allow"
+      cidr_block = ingress.value
+      from_port  = 22
+      to_port    = 22
+    }
   }
 
   # Allow inbound ephemeral ports for return traffic
@@ -73,14 +81,17 @@ resource "aws_network_acl" "private" {
     to_port    = 80
   }
 
-  # Allow inbound SSH from public subnet
-  ingress {
-    protocol   = "tcp"
-    rule_no    = 110
-    action     = "allow"
-    cidr_block = var.public_subnet_cidr
-    from_port  = 22
-    to_port    = 22
+  # Allow inbound SSH from public subnet (not in ssm-only mode)
+  dynamic "ingress" {
+    for_each = local.ssh_enabled ? [1] : []
+    content {
+      protocol   = "tcp"
+      rule_no    = 110
+      action     = "allow"
+      cidr_block = var.public_subnet_cidr
+      from_port  = 22
+      to_port    = 22
+    }
   }
 
   # Allow inbound HTTPS for SSM
@@ -103,14 +114,17 @@ resource "aws_network_acl" "private" {
     to_port    = 65535
   }
 
-  # Allow all outbound traffic
-  egress {
-    protocol   = "-1"
-    rule_no    = 100
-    action     = "allow"
-    cidr_block = "0.0.0.0/0"
-    from_port  = 0
-    to_port    = 0
+  # Outbound traffic depends on the egress profile (see egress.tf)
+  dynamic "egress" {
+    for_each = local.private_nacl_egress
+    content {
+      protocol   = egress.value.protocol
+      rule_no    = egress.value.rule_no
+      action     = "allow"
+      cidr_block = egress.value.cidr_block
+      from_port  = egress.value.from_port
+      to_port    = egress.value.to_port
+    }
   }
 
   tags = {
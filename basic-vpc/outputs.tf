@@ -1,3 +1,43 @@
+output "vpc_id" {
+  value = aws_vpc.main.id
+}
+
+output "vpc_cidr_block" {
+  value = aws_vpc.main.cidr_block
+}
+
+output "vpc_enable_dns_support" {
+  value = aws_vpc.main.enable_dns_support
+}
+
+output "vpc_enable_dns_hostnames" {
+  value = aws_vpc.main.enable_dns_hostnames
+}
+
+output "availability_zone" {
+  value = aws_subnet.public.availability_zone
+}
+
+output "public_subnet_id" {
+  value = aws_subnet.public.id
+}
+
+output "private_subnet_id" {
+  value = aws_subnet.private.id
+}
+
+output "public_subnet_cidr" {
+  value = aws_subnet.public.cidr_block
+}
+
+output "private_subnet_cidr" {
+  value = aws_subnet.private.cidr_block
+}
+
+output "nat_gateway_id" {
+  value = aws_nat_gateway.nat.id
+}
+
 output "public_instance_public_ip" {
   value = aws_instance.public.public_ip
 }
@@ -8,4 +48,210 @@ output "public_instance_private_ip" {
 
 output "private_instance_private_ip" {
   value = aws_instance.private.private_ip
-}
\ No newline at end of file
+}
+
+output "public_instance_user_data_sha256" {
+  value = sha256(local.public_user_data)
+}
+
+output "private_instance_user_data_sha256" {
+  value = sha256(local.private_user_data)
+}
+
+output "public_instance_id" {
+  value = aws_instance.public.id
+}
+
+output "private_instance_id" {
+  value = aws_instance.private.id
+}
+
+output "ssm_role_name" {
+  value = aws_iam_role.ssm_role.name
+}
+
+output "ssm_role_arn" {
+  value = aws_iam_role.ssm_role.arn
+}
+
+output "ssm_instance_profile_name" {
+  value = aws_iam_instance_profile.ssm_profile.name
+}
+
+output "ssm_instance_profile_arn" {
+  value = aws_iam_instance_profile.ssm_profile.arn
+}
+
+output "ssm_endpoint_id" {
+  value = aws_vpc_endpoint.ssm.id
+}
+
+output "ec2messages_endpoint_id" {
+  value = aws_vpc_endpoint.ec2messages.id
+}
+
+output "ssmmessages_endpoint_id" {
+  value = aws_vpc_endpoint.ssmmessages.id
+}
+
+output "vpc_endpoint_policies_restricted" {
+  value = local.restrict_endpoint_policies || local.egress_restricted
+}
+
+output "vpc_arn" {
+  value = aws_vpc.main.arn
+}
+
+output "public_instance_arn" {
+  value = aws_instance.public.arn
+}
+
+output "private_instance_arn" {
+  value = aws_instance.private.arn
+}
+
+output "cloudtrail_id" {
+  value = aws_cloudtrail.main.id
+}
+
+output "cloudtrail_arn" {
+  value = aws_cloudtrail.main.arn
+}
+
+output "cloudtrail_name" {
+  value = aws_cloudtrail.main.name
+}
+
+output "cloudtrail_bucket_id" {
+  value = aws_s3_bucket.cloudtrail_bucket.id
+}
+
+output "cloudtrail_bucket_arn" {
+  value = aws_s3_bucket.cloudtrail_bucket.arn
+}
+
+output "cloudtrail_bucket_retention_days" {
+  value = var.cloudtrail_bucket_retention_days
+}
+
+output "instance_termination_protection" {
+  value = local.enable_deletion_protection
+}
+
+output "public_security_group_id" {
+  value = aws_security_group.public_sg.id
+}
+
+output "private_security_group_id" {
+  value = aws_security_group.private_sg.id
+}
+
+output "vpc_endpoint_security_group_id" {
+  value = aws_security_group.vpc_endpoint_sg.id
+}
+
+output "public_nacl_id" {
+  value = aws_network_acl.public.id
+}
+
+output "private_nacl_id" {
+  value = aws_network_acl.private.id
+}
+
+output "vpc_flow_log_id" {
+  value = aws_flow_log.vpc_flow_log.id
+}
+
+output "vpc_flow_log_group_name" {
+  value = aws_cloudwatch_log_group.vpc_flow_log.name
+}
+
+output "vpc_flow_log_role_name" {
+  value = aws_iam_role.vpc_flow_log_role.name
+}
+
+output "vpc_flow_log_role_arn" {
+  value = aws_iam_role.vpc_flow_log_role.arn
+}
+
+output "vpc_flow_log_format" {
+  value = aws_flow_log.vpc_flow_log.log_format
+}
+
+output "vpc_flow_log_max_aggregation_interval" {
+  value = aws_flow_log.vpc_flow_log.max_aggregation_interval
+}
+
+output "vpc_flow_log_retention_days" {
+  value = aws_cloudwatch_log_group.vpc_flow_log.retention_in_days
+}
+
+output "kms_key_arn" {
+  value = one(aws_kms_key.ebs[*].arn)
+}
+
+output "profile" {
+  value = var.profile
+}
+
+output "egress_profile" {
+  value = local.egress_profile
+}
+
+output "access_mode" {
+  value = local.access_mode
+}
+
+output "root_device_type" {
+  value = data.aws_ami.amazon_linux.root_device_type
+}
+
+output "public_instance_type" {
+  value = aws_instance.public.instance_type
+}
+
+output "private_instance_type" {
+  value = aws_instance.private.instance_type
+}
+
+output "public_ebs_encrypted" {
+  value = aws_instance.public.root_block_device[0].encrypted
+}
+
+output "private_ebs_encrypted" {
+  value = aws_instance.private.root_block_device[0].encrypted
+}
+
+output "public_ebs_volume_type" {
+  value = aws_instance.public.root_block_device[0].volume_type
+}
+
+output "private_ebs_volume_type" {
+  value = aws_instance.private.root_block_device[0].volume_type
+}
+
+output "root_volume_iops" {
+  value = aws_instance.private.root_block_device[0].iops
+}
+
+output "root_volume_throughput" {
+  value = aws_instance.private.root_block_device[0].throughput
+}
+
+output "instance_ephemeral_devices" {
+  value = var.enable_scratch_disks ? [for device in aws_instance.public.ephemeral_block_device : device.virtual_name] : []
+}
+
+output "detailed_monitoring" {
+  value = aws_instance.public.monitoring && aws_instance.private.monitoring
+}
+
+output "instance_metadata_options" {
+  value = {
+    for name, instance in { public = aws_instance.public, private = aws_instance.private } : name => {
+      http_tokens                 = instance.metadata_options[0].http_tokens
+      http_put_response_hop_limit = instance.metadata_options[0].http_put_response_hop_limit
+      instance_metadata_tags      = instance.metadata_options[0].instance_metadata_tags
+    }
+  }
+}
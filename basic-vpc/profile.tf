@@ -0,0 +1,33 @@
+# minimal keeps SSH and open egress for poking at the instances; hardened
+# moves access to SSM, limits egress to AWS endpoints and protects the
+# instances. A null variable takes the profile's value, anything else wins.
+# IMDSv2 is required under both: minimal only relaxes settings that cost
+# money or get in the way of SSH.
+locals {
+  profiles = {
+    minimal = {
+      restrict_endpoint_policies = false
+      egress_profile             = "open"
+      access_mode                = "ssh+ssm"
+      enable_deletion_protection = false
+      create_kms_key             = false
+      flow_log_retention_days    = 30
+    }
+    hardened = {
+      restrict_endpoint_policies = true
+      egress_profile             = "aws-only"
+      access_mode                = "ssm-only"
+      enable_deletion_protection = true
+      create_kms_key             = true
+      flow_log_retention_days    = 365
+    }
+  }
+  profile = local.profiles[var.profile]
+
+  restrict_endpoint_policies = coalesce(var.restrict_endpoint_policies, local.profile.restrict_endpoint_policies)
+  egress_profile             = coalesce(var.egress_profile, local.profile.egress_profile)
+  access_mode                = coalesce(var.access_mode, local.profile.access_mode)
+  enable_deletion_protection = coalesce(var.enable_deletion_protection, local.profile.enable_deletion_protection)
+  create_kms_key             = coalesce(var.create_kms_key, local.profile.create_kms_key)
+  flow_log_retention_days    = coalesce(var.flow_log_retention_days, local.profile.flow_log_retention_days)
+}
@@ -39,10 +39,11 @@ resource "aws_security_group" "vpc_endpoint_sg" {
   vpc_id = aws_vpc.main.id
 
   ingress {
+    description     = "HTTPS to the SSM endpoints from the instances"
     from_port       = 443
     to_port         = 443
     protocol        = "tcp"
-    security_groups = [aws_security_group.private_sg.id]
+    security_groups = [aws_security_group.private_sg.id, aws_security_group.public_sg.id] # Private DNS sends the public instance here too
   }
 
   tags = {
@@ -51,6 +52,30 @@ resource "aws_security_group" "vpc_endpoint_sg" {
   }
 }
 
+data "aws_caller_identity" "current" {}
+
+# Restrictive endpoint policy: only principals from this account, calling from this VPC
+locals {
+  ssm_endpoint_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "AllowSessionManagerFromThisAccountAndVpc"
+        Effect    = "Allow"
+        Principal = "*"
+        Action    = ["ssm:*", "ssmmessages:*", "ec2messages:*"]
+        Resource  = "*"
+        Condition = {
+          StringEquals = {
+            "aws:PrincipalAccount" = data.aws_caller_identity.current.account_id
+            "aws:SourceVpc"        = aws_vpc.main.id
+          }
+        }
+      },
+    ]
+  })
+}
+
 # VPC Endpoint for SSM
 resource "aws_vpc_endpoint" "ssm" {
   vpc_id              = aws_vpc.main.id
@@ -59,6 +84,7 @@ resource "aws_vpc_endpoint" "ssm" {
   subnet_ids          = [aws_subnet.private.id]
   security_group_ids  = [aws_security_group.vpc_endpoint_sg.id]
   private_dns_enabled = true
+  policy              = local.restrict_endpoint_policies || local.egress_restricted ? local.ssm_endpoint_policy : null
 
   tags = {
     Name        = "ssm-endpoint"
@@ -74,6 +100,7 @@ resource "aws_vpc_endpoint" "ec2messages" {
   subnet_ids          = [aws_subnet.private.id]
   security_group_ids  = [aws_security_group.vpc_endpoint_sg.id]
   private_dns_enabled = true
+  policy              = local.restrict_endpoint_policies || local.egress_restricted ? local.ssm_endpoint_policy : null
 
   tags = {
     Name        = "ec2messages-endpoint"
@@ -89,6 +116,7 @@ resource "aws_vpc_endpoint" "ssmmessages" {
   subnet_ids          = [aws_subnet.private.id]
   security_group_ids  = [aws_security_group.vpc_endpoint_sg.id]
   private_dns_enabled = true
+  policy              = local.restrict_endpoint_policies || local.egress_restricted ? local.ssm_endpoint_policy : null
 
   tags = {
     Name        = "ssmmessages-endpoint"
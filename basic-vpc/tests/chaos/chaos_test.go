@@ -10,6 +10,8 @@ import (
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestChaosInstanceFailure(t *testing.T) {
@@ -65,8 +67,8 @@ func TestChaosInstanceFailure(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	// Wait for instance to start
-	time.Sleep(60 * time.Second)
+	// Recovered means the status checks pass, not just that it's running
+	require.NoError(t, testkit.WaitForInstanceStatusOk(ec2Svc, publicInstanceID, 10*time.Minute))
 
 	// Verify instance is running again
 	result, err = ec2Svc.DescribeInstances(descInput)
@@ -279,8 +281,10 @@ func TestChaosResourceExhaustion(t *testing.T) {
 	_, err = ec2Svc.StartInstances(startInput)
 	require.NoError(t, err)
 
-	// Wait for instances to start
-	time.Sleep(60 * time.Second)
+	// Both instances must pass their status checks on the constrained instance type
+	for _, instanceID := range []string{publicInstanceID, privateInstanceID} {
+		require.NoError(t, testkit.WaitForInstanceStatusOk(ec2Svc, instanceID, 10*time.Minute))
+	}
 
 	// Verify instances are still functional despite resource constraints
 	descInput := &ec2.DescribeInstancesInput{
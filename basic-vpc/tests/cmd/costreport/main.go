@@ -0,0 +1,150 @@
+// Command costreport plans each Terraform module in the playground and prints
+// a rough monthly cost estimate per module, without deploying anything.
+//
+// Usage (from basic-vpc/tests):
+//
+//	go run ./cmd/costreport [-root ../..] [-json] [module ...]
+//
+// Modules needing variables without defaults should have a terraform.tfvars.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+var defaultModules = []string{"basic-vpc", "bastion-host", "static-website", "cspm-monitor"}
+
+type moduleReport struct {
+	Module    string           `json:"module"`
+	Resources []pricedResource `json:"resources"`
+	Err       error            `json:"-"`
+}
+
+type pricedResource struct {
+	Address string  `json:"address"`
+	Monthly float64 `json:"monthly"`
+	Note    string  `json:"note,omitempty"`
+}
+
+func (r moduleReport) total() float64 {
+	var total float64
+	for _, resource := range r.Resources {
+		total += resource.Monthly
+	}
+	return total
+}
+
+func main() {
+	root := flag.String("root", "../..", "repository root containing the modules")
+	terraformBinary := flag.String("terraform", "terraform", "terraform (or tofu) binary to run")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	modules := flag.Args()
+	if len(modules) == 0 {
+		modules = defaultModules
+	}
+
+	var reports []moduleReport
+	for _, module := range modules {
+		report := moduleReport{Module: module}
+		planJSON, err := planModule(*terraformBinary, filepath.Join(*root, module))
+		if err == nil {
+			report.Resources, err = priceModule(planJSON)
+		}
+		report.Err = err
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		if err := writeJSONReport(os.Stdout, reports); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else {
+		writeReport(os.Stdout, reports)
+	}
+	for _, report := range reports {
+		if report.Err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// priceModule prices every planned resource that has a pricing table entry
+func priceModule(planJSON []byte) ([]pricedResource, error) {
+	resources, err := parsePlan(planJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var priced []pricedResource
+	for _, resource := range resources {
+		monthly, ok, err := monthlyCost(resource.Type, resource.After)
+		if !ok {
+			continue
+		}
+		entry := pricedResource{Address: resource.Address, Monthly: monthly}
+		if note, ok := costNotes[resource.Type]; ok {
+			entry.Note = note(resource.After)
+		}
+		if err != nil {
+			entry.Note = err.Error()
+		}
+		priced = append(priced, entry)
+	}
+	return priced, nil
+}
+
+func writeReport(out io.Writer, reports []moduleReport) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	var total float64
+	for _, report := range reports {
+		fmt.Fprintf(w, "%s\t\t\n", report.Module)
+		if report.Err != nil {
+			fmt.Fprintf(w, "  error: %v\t\t\n\n", report.Err)
+			continue
+		}
+		for _, resource := range report.Resources {
+			fmt.Fprintf(w, "  %s\t$%.2f\t%s\n", resource.Address, resource.Monthly, resource.Note)
+		}
+		fmt.Fprintf(w, "  subtotal\t$%.2f\t\n\n", report.total())
+		total += report.total()
+	}
+	fmt.Fprintf(w, "total (estimated monthly)\t$%.2f\t\n", total)
+	w.Flush()
+}
+
+// writeJSONReport prints the same figures as writeReport for other tools to consume
+func writeJSONReport(out io.Writer, reports []moduleReport) error {
+	type jsonModule struct {
+		moduleReport
+		Subtotal float64 `json:"subtotal"`
+		Error    string  `json:"error,omitempty"`
+	}
+	document := struct {
+		Modules []jsonModule `json:"modules"`
+		Total   float64      `json:"total"`
+	}{Modules: []jsonModule{}}
+
+	for _, report := range reports {
+		module := jsonModule{moduleReport: report}
+		if report.Err != nil {
+			module.Error = report.Err.Error()
+		} else {
+			module.Subtotal = report.total()
+			document.Total += module.Subtotal
+		}
+		document.Modules = append(document.Modules, module)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// plannedResource is a managed resource that will exist once the plan is applied
+type plannedResource struct {
+	Address string
+	Type    string
+	After   map[string]interface{}
+}
+
+// parsePlan extracts the resources a `terraform show -json` plan leaves in
+// place. Data sources and resources being destroyed don't cost anything.
+func parsePlan(planJSON []byte) ([]plannedResource, error) {
+	var plan struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Mode    string `json:"mode"`
+			Type    string `json:"type"`
+			Change  struct {
+				Actions []string               `json:"actions"`
+				After   map[string]interface{} `json:"after"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(planJSON, &plan); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+
+	var resources []plannedResource
+	for _, change := range plan.ResourceChanges {
+		if change.Mode != "managed" || isDelete(change.Change.Actions) {
+			continue
+		}
+		resources = append(resources, plannedResource{
+			Address: change.Address,
+			Type:    change.Type,
+			After:   change.Change.After,
+		})
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Address < resources[j].Address
+	})
+	return resources, nil
+}
+
+func isDelete(actions []string) bool {
+	return len(actions) == 1 && actions[0] == "delete"
+}
+
+// planModule runs init, plan and show for a module directory and returns the plan JSON
+func planModule(terraformBinary, dir string) ([]byte, error) {
+	tmp, err := os.MkdirTemp("", "costreport")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	planFile := filepath.Join(tmp, "plan.out")
+
+	if _, err := runTerraform(terraformBinary, dir, "init", "-input=false", "-no-color"); err != nil {
+		return nil, err
+	}
+	if _, err := runTerraform(terraformBinary, dir, "plan", "-input=false", "-lock=false", "-no-color", "-out="+planFile); err != nil {
+		return nil, err
+	}
+	return runTerraform(terraformBinary, dir, "show", "-json", planFile)
+}
+
+func runTerraform(terraformBinary, dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command(terraformBinary, args...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("terraform %s in %s: %w", args[0], dir, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const samplePlan = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{
+			"address": "aws_instance.public",
+			"mode": "managed",
+			"type": "aws_instance",
+			"change": {"actions": ["create"], "after": {"instance_type": "t3.micro", "monitoring": true}}
+		},
+		{
+			"address": "aws_nat_gateway.main[0]",
+			"mode": "managed",
+			"type": "aws_nat_gateway",
+			"change": {"actions": ["no-op"], "after": {}}
+		},
+		{
+			"address": "aws_eip.old",
+			"mode": "managed",
+			"type": "aws_eip",
+			"change": {"actions": ["delete"], "after": null}
+		},
+		{
+			"address": "aws_instance.replaced",
+			"mode": "managed",
+			"type": "aws_instance",
+			"change": {"actions": ["delete", "create"], "after": {"instance_type": "x9.huge"}}
+		},
+		{
+			"address": "data.aws_ami.amazon_linux",
+			"mode": "data",
+			"type": "aws_ami",
+			"change": {"actions": ["read"], "after": {}}
+		},
+		{
+			"address": "aws_vpc.main",
+			"mode": "managed",
+			"type": "aws_vpc",
+			"change": {"actions": ["create"], "after": {"cidr_block": "10.0.0.0/16"}}
+		}
+	]
+}`
+
+func TestParsePlan(t *testing.T) {
+	t.Parallel()
+
+	resources, err := parsePlan([]byte(samplePlan))
+	require.NoError(t, err)
+
+	var addresses []string
+	for _, resource := range resources {
+		addresses = append(addresses, resource.Address)
+	}
+	// Deletes and data sources are dropped; replacements still cost money
+	assert.Equal(t, []string{"aws_instance.public", "aws_instance.replaced", "aws_nat_gateway.main[0]", "aws_vpc.main"}, addresses)
+	assert.Equal(t, "t3.micro", resources[0].After["instance_type"])
+
+	_, err = parsePlan([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestPriceModule(t *testing.T) {
+	t.Parallel()
+
+	priced, err := priceModule([]byte(samplePlan))
+	require.NoError(t, err)
+	require.Len(t, priced, 3, "Only resources with a pricing entry are reported")
+
+	assert.Equal(t, "aws_instance.public", priced[0].Address)
+	assert.Equal(t, "includes $2.10/mo detailed monitoring", priced[0].Note)
+	assert.Equal(t, "aws_instance.replaced", priced[1].Address)
+	assert.Contains(t, priced[1].Note, "x9.huge", "Unknown prices are flagged rather than dropped")
+
+	report := moduleReport{Module: "basic-vpc", Resources: priced}
+	assert.InDelta(t, 0.0104*730+2.10+0.045*730, report.total(), 0.001)
+
+	var out bytes.Buffer
+	writeReport(&out, []moduleReport{report})
+	assert.Contains(t, out.String(), "basic-vpc")
+	assert.Contains(t, out.String(), "$42.54")
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	t.Parallel()
+
+	priced, err := priceModule([]byte(samplePlan))
+	require.NoError(t, err)
+	reports := []moduleReport{
+		{Module: "basic-vpc", Resources: priced},
+		{Module: "bastion-host", Err: errors.New("terraform plan in ../../bastion-host: exit status 1")},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, writeJSONReport(&out, reports))
+
+	var document struct {
+		Modules []struct {
+			Module    string  `json:"module"`
+			Subtotal  float64 `json:"subtotal"`
+			Error     string  `json:"error"`
+			Resources []struct {
+				Address string  `json:"address"`
+				Monthly float64 `json:"monthly"`
+				Note    string  `json:"note"`
+			} `json:"resources"`
+		} `json:"modules"`
+		Total float64 `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &document), out.String())
+	require.Len(t, document.Modules, 2)
+
+	vpc := document.Modules[0]
+	assert.Equal(t, "basic-vpc", vpc.Module)
+	assert.InDelta(t, reports[0].total(), vpc.Subtotal, 0.001)
+	assert.Equal(t, "aws_instance.public", vpc.Resources[0].Address)
+	assert.Equal(t, "includes $2.10/mo detailed monitoring", vpc.Resources[0].Note)
+
+	assert.Contains(t, document.Modules[1].Error, "exit status 1")
+	assert.InDelta(t, vpc.Subtotal, document.Total, 0.001, "Failed modules don't count toward the total")
+}
@@ -0,0 +1,191 @@
+package main
+
+import "fmt"
+
+// hoursPerMonth is the AWS billing convention for monthly estimates
+const hoursPerMonth = 730
+
+// On-demand us-east-1 Linux prices per hour. Rough figures for estimates only.
+var instanceHourly = map[string]float64{
+	"t2.nano":   0.0058,
+	"t2.micro":  0.0116,
+	"t2.small":  0.023,
+	"t2.medium": 0.0464,
+	"t3.nano":   0.0052,
+	"t3.micro":  0.0104,
+	"t3.small":  0.0208,
+	"t3.medium": 0.0416,
+	"t3.large":  0.0832,
+	"t4g.micro": 0.0084,
+	"t4g.small": 0.0168,
+	"m5.large":  0.096,
+	"m5d.large": 0.113,
+}
+
+const (
+	natGatewayHourly = 0.045
+	eipHourly        = 0.005
+	wafWebACLMonthly = 5.0
+	wafRuleMonthly   = 1.0
+)
+
+// Detailed monitoring bills the seven standard EC2 metrics as custom metrics
+const (
+	detailedMonitoringMetrics      = 7
+	cloudWatchMetricMonthly        = 0.30
+	detailedMonitoringMonthlyDelta = detailedMonitoringMetrics * cloudWatchMetricMonthly
+)
+
+// CloudFront has no fixed fee, so distributions are priced for an assumed
+// monthly transfer at the first-tier rate of the most expensive region each
+// price class includes.
+const cloudFrontAssumedGB = 100
+
+var cloudFrontPerGB = map[string]float64{
+	"PriceClass_100": 0.085,
+	"PriceClass_200": 0.120,
+	"PriceClass_All": 0.170,
+}
+
+// Flow logs are billed as vended log ingestion, priced for an assumed monthly
+// volume at the default 600-second aggregation. A 60-second interval flushes
+// partially aggregated records and is assumed to produce twice as many.
+const (
+	flowLogAssumedGB          = 5
+	flowLogFineGrainedFactor  = 2
+	flowLogDefaultAggregation = 600
+)
+
+var flowLogIngestionPerGB = map[string]float64{
+	"cloud-watch-logs":      0.50,
+	"s3":                    0.25,
+	"kinesis-data-firehose": 0.25,
+}
+
+// estimator prices one planned resource from its planned attribute values
+type estimator func(after map[string]interface{}) (float64, error)
+
+var pricingTable = map[string]estimator{
+	"aws_instance":                estimateInstance,
+	"aws_nat_gateway":             fixedHourly(natGatewayHourly),
+	"aws_eip":                     fixedHourly(eipHourly),
+	"aws_cloudfront_distribution": estimateDistribution,
+	"aws_wafv2_web_acl":           estimateWebACL,
+	"aws_flow_log":                estimateFlowLog,
+}
+
+// Notes explaining a priced resource's cost drivers, keyed by resource type
+var costNotes = map[string]func(after map[string]interface{}) string{
+	"aws_instance": instanceMonitoringNote,
+	"aws_flow_log": flowLogAggregationNote,
+}
+
+// monthlyCost looks up the estimator for a resource type. Types without an
+// entry are treated as free (or negligible) and reported as unpriced.
+func monthlyCost(resourceType string, after map[string]interface{}) (float64, bool, error) {
+	estimate, ok := pricingTable[resourceType]
+	if !ok {
+		return 0, false, nil
+	}
+	cost, err := estimate(after)
+	return cost, true, err
+}
+
+func fixedHourly(hourly float64) estimator {
+	return func(map[string]interface{}) (float64, error) {
+		return hourly * hoursPerMonth, nil
+	}
+}
+
+func estimateInstance(after map[string]interface{}) (float64, error) {
+	instanceType, _ := after["instance_type"].(string)
+	hourly, ok := instanceHourly[instanceType]
+	if !ok {
+		return 0, fmt.Errorf("no price for instance type %q", instanceType)
+	}
+	monthly := hourly * hoursPerMonth
+	if monitoring, _ := after["monitoring"].(bool); monitoring {
+		monthly += detailedMonitoringMonthlyDelta
+	}
+	return monthly, nil
+}
+
+// instanceMonitoringNote states what detailed monitoring adds to (or would add to) an instance
+func instanceMonitoringNote(after map[string]interface{}) string {
+	if monitoring, _ := after["monitoring"].(bool); monitoring {
+		return fmt.Sprintf("includes $%.2f/mo detailed monitoring", detailedMonitoringMonthlyDelta)
+	}
+	return fmt.Sprintf("detailed monitoring off (would add $%.2f/mo)", detailedMonitoringMonthlyDelta)
+}
+
+func estimateDistribution(after map[string]interface{}) (float64, error) {
+	priceClass, _ := after["price_class"].(string)
+	if priceClass == "" {
+		priceClass = "PriceClass_All"
+	}
+	perGB, ok := cloudFrontPerGB[priceClass]
+	if !ok {
+		return 0, fmt.Errorf("no price for CloudFront price class %q", priceClass)
+	}
+	return perGB * cloudFrontAssumedGB, nil
+}
+
+func estimateWebACL(after map[string]interface{}) (float64, error) {
+	rules, _ := after["rule"].([]interface{})
+	return wafWebACLMonthly + wafRuleMonthly*float64(len(rules)), nil
+}
+
+func estimateFlowLog(after map[string]interface{}) (float64, error) {
+	perGB, err := flowLogPerGB(after)
+	if err != nil {
+		return 0, err
+	}
+	interval, err := flowLogAggregationInterval(after)
+	if err != nil {
+		return 0, err
+	}
+	monthly := perGB * flowLogAssumedGB
+	if interval != flowLogDefaultAggregation {
+		monthly *= flowLogFineGrainedFactor
+	}
+	return monthly, nil
+}
+
+// flowLogAggregationNote states what 60-second aggregation adds to (or would add to) a flow log
+func flowLogAggregationNote(after map[string]interface{}) string {
+	perGB, err := flowLogPerGB(after)
+	if err != nil {
+		return err.Error()
+	}
+	delta := perGB * flowLogAssumedGB * (flowLogFineGrainedFactor - 1)
+	if interval, _ := flowLogAggregationInterval(after); interval != flowLogDefaultAggregation {
+		return fmt.Sprintf("60s aggregation adds $%.2f/mo over 600s", delta)
+	}
+	return fmt.Sprintf("600s aggregation (60s would add $%.2f/mo)", delta)
+}
+
+func flowLogPerGB(after map[string]interface{}) (float64, error) {
+	destination, _ := after["log_destination_type"].(string)
+	if destination == "" {
+		destination = "cloud-watch-logs"
+	}
+	perGB, ok := flowLogIngestionPerGB[destination]
+	if !ok {
+		return 0, fmt.Errorf("no price for flow log destination %q", destination)
+	}
+	return perGB, nil
+}
+
+// Plan JSON decodes numbers as float64; an unset interval means the 600s default
+func flowLogAggregationInterval(after map[string]interface{}) (int, error) {
+	raw, ok := after["max_aggregation_interval"].(float64)
+	if !ok {
+		return flowLogDefaultAggregation, nil
+	}
+	switch interval := int(raw); interval {
+	case 60, 600:
+		return interval, nil
+	default:
+		return 0, fmt.Errorf("unsupported flow log aggregation interval %v", raw)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonthlyCost(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		resourceType string
+		after        map[string]interface{}
+		want         float64
+		priced       bool
+		wantErr      bool
+	}{
+		{"instance", "aws_instance", map[string]interface{}{"instance_type": "t3.micro"}, 0.0104 * 730, true, false},
+		{"instance with detailed monitoring", "aws_instance", map[string]interface{}{"instance_type": "t3.micro", "monitoring": true}, 0.0104*730 + 2.10, true, false},
+		{"unknown instance type", "aws_instance", map[string]interface{}{"instance_type": "x9.huge"}, 0, true, true},
+		{"nat gateway", "aws_nat_gateway", nil, 0.045 * 730, true, false},
+		{"elastic ip", "aws_eip", nil, 0.005 * 730, true, false},
+		{"cloudfront price class 100", "aws_cloudfront_distribution", map[string]interface{}{"price_class": "PriceClass_100"}, 8.5, true, false},
+		{"cloudfront defaults to all", "aws_cloudfront_distribution", map[string]interface{}{}, 17, true, false},
+		{"cloudfront unknown class", "aws_cloudfront_distribution", map[string]interface{}{"price_class": "PriceClass_Bogus"}, 0, true, true},
+		{"waf with rules", "aws_wafv2_web_acl", map[string]interface{}{"rule": []interface{}{map[string]interface{}{}, map[string]interface{}{}}}, 7, true, false},
+		{"waf without rules", "aws_wafv2_web_acl", map[string]interface{}{}, 5, true, false},
+		{"flow log at 600s", "aws_flow_log", map[string]interface{}{"max_aggregation_interval": 600.0}, 2.5, true, false},
+		{"flow log at 60s", "aws_flow_log", map[string]interface{}{"max_aggregation_interval": 60.0}, 5, true, false},
+		{"flow log to s3 defaults to 600s", "aws_flow_log", map[string]interface{}{"log_destination_type": "s3"}, 1.25, true, false},
+		{"flow log unknown interval", "aws_flow_log", map[string]interface{}{"max_aggregation_interval": 300.0}, 0, true, true},
+		{"flow log unknown destination", "aws_flow_log", map[string]interface{}{"log_destination_type": "carrier-pigeon"}, 0, true, true},
+		{"unpriced type", "aws_vpc", map[string]interface{}{}, 0, false, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, priced, err := monthlyCost(tc.resourceType, tc.after)
+			assert.Equal(t, tc.priced, priced)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tc.want, got, 0.001)
+		})
+	}
+}
+
+func TestInstanceMonitoringNote(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "includes $2.10/mo detailed monitoring", instanceMonitoringNote(map[string]interface{}{"monitoring": true}))
+	assert.Equal(t, "detailed monitoring off (would add $2.10/mo)", instanceMonitoringNote(map[string]interface{}{"monitoring": false}))
+	assert.Equal(t, "detailed monitoring off (would add $2.10/mo)", instanceMonitoringNote(map[string]interface{}{}))
+}
+
+func TestFlowLogAggregationNote(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "600s aggregation (60s would add $2.50/mo)", flowLogAggregationNote(map[string]interface{}{"max_aggregation_interval": 600.0}))
+	assert.Equal(t, "600s aggregation (60s would add $2.50/mo)", flowLogAggregationNote(map[string]interface{}{}))
+	assert.Equal(t, "60s aggregation adds $2.50/mo over 600s", flowLogAggregationNote(map[string]interface{}{"max_aggregation_interval": 60.0}))
+	assert.Equal(t, "60s aggregation adds $1.25/mo over 600s", flowLogAggregationNote(map[string]interface{}{"max_aggregation_interval": 60.0, "log_destination_type": "s3"}))
+}
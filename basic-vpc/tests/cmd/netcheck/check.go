@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"basic-vpc-tests/testutil"
+)
+
+// report is the reachability summary for one VPC
+type report struct {
+	VPCID              string
+	Subnets            []subnetReport
+	InterfaceEndpoints []string
+	Warnings           []string
+}
+
+// subnetReport describes where one subnet's outbound traffic goes
+type subnetReport struct {
+	SubnetID         string
+	CIDR             string
+	AZ               string
+	Public           bool
+	RouteTableID     string
+	Egress           string
+	GatewayEndpoints []string
+}
+
+// analyze works out each subnet's internet egress path and flags routing that
+// contradicts the subnet's role. A subnet counts as public when it assigns
+// public IPs on launch or hosts a NAT gateway.
+func analyze(snapshot *vpcSnapshot) report {
+	result := report{VPCID: snapshot.VPCID}
+	warn := func(format string, args ...interface{}) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	natGateways := map[string]*ec2.NatGateway{}
+	natSubnets := map[string]bool{}
+	for _, nat := range snapshot.NatGateways {
+		natGateways[aws.StringValue(nat.NatGatewayId)] = nat
+		natSubnets[aws.StringValue(nat.SubnetId)] = true
+	}
+
+	// Only gateways attached to this VPC can carry its traffic
+	var internetGatewayIDs []string
+	for _, igw := range snapshot.InternetGateways {
+		internetGatewayIDs = append(internetGatewayIDs, aws.StringValue(igw.InternetGatewayId))
+	}
+	internetGatewayRoute := func(routeTable *ec2.RouteTable) (string, bool) {
+		for _, igwID := range internetGatewayIDs {
+			if routeTable != nil && testutil.HasDefaultRouteToGateway(routeTable, igwID) {
+				return igwID, true
+			}
+		}
+		return "", false
+	}
+
+	subnets := append([]*ec2.Subnet(nil), snapshot.Subnets...)
+	sort.Slice(subnets, func(i, j int) bool {
+		return aws.StringValue(subnets[i].CidrBlock) < aws.StringValue(subnets[j].CidrBlock)
+	})
+
+	for _, subnet := range subnets {
+		entry := subnetReport{
+			SubnetID: aws.StringValue(subnet.SubnetId),
+			CIDR:     aws.StringValue(subnet.CidrBlock),
+			AZ:       aws.StringValue(subnet.AvailabilityZone),
+			Public:   aws.BoolValue(subnet.MapPublicIpOnLaunch) || natSubnets[aws.StringValue(subnet.SubnetId)],
+			Egress:   "none",
+		}
+
+		routeTable := testutil.SubnetRouteTable(snapshot.RouteTables, entry.SubnetID)
+		if routeTable == nil {
+			warn("subnet %s has no route table", entry.SubnetID)
+			result.Subnets = append(result.Subnets, entry)
+			continue
+		}
+		entry.RouteTableID = aws.StringValue(routeTable.RouteTableId)
+		entry.GatewayEndpoints = gatewayEndpoints(snapshot.Endpoints, entry.RouteTableID)
+
+		igwID, direct := internetGatewayRoute(routeTable)
+		route := testutil.DefaultRoute(routeTable)
+		switch {
+		case direct:
+			entry.Egress = "internet gateway " + igwID
+			if !entry.Public {
+				warn("private subnet %s routes 0.0.0.0/0 to internet gateway %s", entry.SubnetID, igwID)
+			}
+		case route == nil:
+		case aws.StringValue(route.State) != ec2.RouteStateActive:
+			warn("subnet %s default route to %s is %s", entry.SubnetID, testutil.RouteTarget(route), aws.StringValue(route.State))
+		case aws.StringValue(route.NatGatewayId) != "":
+			natID := aws.StringValue(route.NatGatewayId)
+			nat, ok := natGateways[natID]
+			if !ok {
+				warn("subnet %s routes to NAT gateway %s, which is not in %s", entry.SubnetID, natID, snapshot.VPCID)
+				break
+			}
+			if state := aws.StringValue(nat.State); state != ec2.NatGatewayStateAvailable {
+				warn("NAT gateway %s used by subnet %s is %s", natID, entry.SubnetID, state)
+				break
+			}
+			natSubnetID := aws.StringValue(nat.SubnetId)
+			if _, ok := internetGatewayRoute(testutil.SubnetRouteTable(snapshot.RouteTables, natSubnetID)); !ok {
+				warn("NAT gateway %s used by subnet %s sits in subnet %s, which has no internet gateway route", natID, entry.SubnetID, natSubnetID)
+				break
+			}
+			entry.Egress = fmt.Sprintf("NAT gateway %s in %s", natID, natSubnetID)
+		default:
+			target := testutil.RouteTarget(route)
+			if testutil.IsInternetGateway(target) {
+				warn("subnet %s routes to internet gateway %s, which is not attached to %s", entry.SubnetID, target, snapshot.VPCID)
+				break
+			}
+			// Transit gateways, peering and appliances may or may not lead to the internet
+			entry.Egress = target + " (unverified)"
+		}
+		if entry.Public && !direct {
+			warn("public subnet %s has no route to an internet gateway", entry.SubnetID)
+		}
+		result.Subnets = append(result.Subnets, entry)
+	}
+
+	for _, endpoint := range snapshot.Endpoints {
+		if state := aws.StringValue(endpoint.State); !strings.EqualFold(state, ec2.StateAvailable) {
+			warn("VPC endpoint %s (%s) is %s", aws.StringValue(endpoint.VpcEndpointId), endpointService(endpoint), state)
+		}
+		if aws.StringValue(endpoint.VpcEndpointType) == ec2.VpcEndpointTypeInterface {
+			result.InterfaceEndpoints = append(result.InterfaceEndpoints, endpointService(endpoint))
+		}
+	}
+	sort.Strings(result.InterfaceEndpoints)
+	return result
+}
+
+// gatewayEndpoints lists the services reachable through gateway endpoints
+// attached to a route table
+func gatewayEndpoints(endpoints []*ec2.VpcEndpoint, routeTableID string) []string {
+	var services []string
+	for _, endpoint := range endpoints {
+		if aws.StringValue(endpoint.VpcEndpointType) != ec2.VpcEndpointTypeGateway {
+			continue
+		}
+		for _, id := range endpoint.RouteTableIds {
+			if aws.StringValue(id) == routeTableID {
+				services = append(services, endpointService(endpoint))
+			}
+		}
+	}
+	sort.Strings(services)
+	return services
+}
+
+// endpointService shortens com.amazonaws.<region>.<service> to <service>
+func endpointService(endpoint *ec2.VpcEndpoint) string {
+	name := aws.StringValue(endpoint.ServiceName)
+	if parts := strings.SplitN(name, ".", 4); len(parts) == 4 && parts[0] == "com" {
+		return parts[3]
+	}
+	return name
+}
+
+func writeReport(out io.Writer, result report) {
+	fmt.Fprintf(out, "VPC %s\n\n", result.VPCID)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SUBNET\tCIDR\tAZ\tROLE\tROUTE TABLE\tINTERNET EGRESS\tGATEWAY ENDPOINTS")
+	for _, subnet := range result.Subnets {
+		role := "private"
+		if subnet.Public {
+			role = "public"
+		}
+		endpoints := "-"
+		if len(subnet.GatewayEndpoints) > 0 {
+			endpoints = strings.Join(subnet.GatewayEndpoints, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", subnet.SubnetID, subnet.CIDR, subnet.AZ, role, subnet.RouteTableID, subnet.Egress, endpoints)
+	}
+	w.Flush()
+
+	if len(result.InterfaceEndpoints) > 0 {
+		fmt.Fprintf(out, "\nInterface endpoints: %s\n", strings.Join(result.InterfaceEndpoints, ", "))
+	}
+	if len(result.Warnings) == 0 {
+		fmt.Fprintln(out, "\nNo warnings")
+		return
+	}
+	fmt.Fprintln(out, "\nWarnings:")
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(out, "  - %s\n", warning)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// basicVPCSnapshot mirrors a healthy basic-vpc deployment: a public subnet
+// with the NAT gateway, a private subnet behind it, an S3 gateway endpoint and
+// the SSM interface endpoints
+func basicVPCSnapshot() *vpcSnapshot {
+	return &vpcSnapshot{
+		VPCID: "vpc-1",
+		Subnets: []*ec2.Subnet{
+			{SubnetId: aws.String("subnet-private"), CidrBlock: aws.String("10.0.2.0/24"), AvailabilityZone: aws.String("us-east-1a"), MapPublicIpOnLaunch: aws.Bool(false)},
+			{SubnetId: aws.String("subnet-public"), CidrBlock: aws.String("10.0.1.0/24"), AvailabilityZone: aws.String("us-east-1a"), MapPublicIpOnLaunch: aws.Bool(true)},
+		},
+		RouteTables: []*ec2.RouteTable{
+			{
+				RouteTableId: aws.String("rtb-main"),
+				Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}},
+				Routes:       []*ec2.Route{localRoute()},
+			},
+			{
+				RouteTableId: aws.String("rtb-public"),
+				Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String("subnet-public")}},
+				Routes: []*ec2.Route{
+					localRoute(),
+					{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-1"), State: aws.String(ec2.RouteStateActive)},
+				},
+			},
+			{
+				RouteTableId: aws.String("rtb-private"),
+				Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String("subnet-private")}},
+				Routes: []*ec2.Route{
+					localRoute(),
+					{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-1"), State: aws.String(ec2.RouteStateActive)},
+				},
+			},
+		},
+		NatGateways: []*ec2.NatGateway{
+			{NatGatewayId: aws.String("nat-1"), SubnetId: aws.String("subnet-public"), State: aws.String(ec2.NatGatewayStateAvailable)},
+		},
+		InternetGateways: []*ec2.InternetGateway{{InternetGatewayId: aws.String("igw-1")}},
+		Endpoints: []*ec2.VpcEndpoint{
+			{VpcEndpointId: aws.String("vpce-s3"), ServiceName: aws.String("com.amazonaws.us-east-1.s3"), VpcEndpointType: aws.String(ec2.VpcEndpointTypeGateway), State: aws.String("available"), RouteTableIds: aws.StringSlice([]string{"rtb-private"})},
+			{VpcEndpointId: aws.String("vpce-ssm"), ServiceName: aws.String("com.amazonaws.us-east-1.ssm"), VpcEndpointType: aws.String(ec2.VpcEndpointTypeInterface), State: aws.String("available")},
+			{VpcEndpointId: aws.String("vpce-ec2messages"), ServiceName: aws.String("com.amazonaws.us-east-1.ec2messages"), VpcEndpointType: aws.String(ec2.VpcEndpointTypeInterface), State: aws.String("available")},
+		},
+	}
+}
+
+func localRoute() *ec2.Route {
+	return &ec2.Route{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local"), State: aws.String(ec2.RouteStateActive)}
+}
+
+func TestAnalyzeHealthyVPC(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(basicVPCSnapshot())
+	assert.Empty(t, result.Warnings)
+	assert.Equal(t, []subnetReport{
+		{SubnetID: "subnet-public", CIDR: "10.0.1.0/24", AZ: "us-east-1a", Public: true, RouteTableID: "rtb-public", Egress: "internet gateway igw-1"},
+		{SubnetID: "subnet-private", CIDR: "10.0.2.0/24", AZ: "us-east-1a", Public: false, RouteTableID: "rtb-private", Egress: "NAT gateway nat-1 in subnet-public", GatewayEndpoints: []string{"s3"}},
+	}, result.Subnets)
+	assert.Equal(t, []string{"ec2messages", "ssm"}, result.InterfaceEndpoints)
+
+	var out bytes.Buffer
+	writeReport(&out, result)
+	assert.Contains(t, out.String(), "VPC vpc-1")
+	assert.Regexp(t, `subnet-private\s+10.0.2.0/24\s+us-east-1a\s+private\s+rtb-private\s+NAT gateway nat-1 in subnet-public\s+s3`, out.String())
+	assert.Contains(t, out.String(), "Interface endpoints: ec2messages, ssm")
+	assert.Contains(t, out.String(), "No warnings")
+}
+
+func TestAnalyzeMisroutedSubnets(t *testing.T) {
+	t.Parallel()
+
+	// Swapped associations send the private subnet out the IGW and the public one through NAT
+	snapshot := basicVPCSnapshot()
+	snapshot.RouteTables[1].Associations[0].SubnetId = aws.String("subnet-private")
+	snapshot.RouteTables[2].Associations[0].SubnetId = aws.String("subnet-public")
+
+	result := analyze(snapshot)
+	assert.Equal(t, []string{
+		"NAT gateway nat-1 used by subnet subnet-public sits in subnet subnet-public, which has no internet gateway route",
+		"public subnet subnet-public has no route to an internet gateway",
+		"private subnet subnet-private routes 0.0.0.0/0 to internet gateway igw-1",
+	}, result.Warnings)
+	assert.Equal(t, "none", result.Subnets[0].Egress)
+	assert.Equal(t, "internet gateway igw-1", result.Subnets[1].Egress)
+
+	var out bytes.Buffer
+	writeReport(&out, result)
+	assert.Contains(t, out.String(), "Warnings:\n  - NAT gateway nat-1")
+}
+
+func TestAnalyzeBrokenGateways(t *testing.T) {
+	t.Parallel()
+
+	snapshot := basicVPCSnapshot()
+	snapshot.NatGateways[0].State = aws.String(ec2.NatGatewayStatePending)
+	snapshot.Endpoints[1].State = aws.String("pendingAcceptance")
+	assert.Equal(t, []string{
+		"NAT gateway nat-1 used by subnet subnet-private is pending",
+		"VPC endpoint vpce-ssm (ssm) is pendingAcceptance",
+	}, analyze(snapshot).Warnings)
+
+	// A detached IGW leaves its routes as blackholes
+	snapshot = basicVPCSnapshot()
+	snapshot.InternetGateways = nil
+	snapshot.RouteTables[1].Routes[1].State = aws.String(ec2.RouteStateBlackhole)
+	assert.Equal(t, []string{
+		"subnet subnet-public default route to igw-1 is blackhole",
+		"public subnet subnet-public has no route to an internet gateway",
+		"NAT gateway nat-1 used by subnet subnet-private sits in subnet subnet-public, which has no internet gateway route",
+	}, analyze(snapshot).Warnings)
+
+	// Unassociated subnets fall back to the main table, which has no default route
+	snapshot = basicVPCSnapshot()
+	snapshot.Subnets = append(snapshot.Subnets, &ec2.Subnet{SubnetId: aws.String("subnet-extra"), CidrBlock: aws.String("10.0.3.0/24"), MapPublicIpOnLaunch: aws.Bool(true)})
+	result := analyze(snapshot)
+	assert.Equal(t, []string{"public subnet subnet-extra has no route to an internet gateway"}, result.Warnings)
+	assert.Equal(t, "rtb-main", result.Subnets[2].RouteTableID)
+	assert.Equal(t, "none", result.Subnets[2].Egress)
+}
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	snapshot := basicVPCSnapshot()
+	svc := &fakeVPCClient{snapshot: snapshot}
+	got, err := collect(svc, "vpc-1")
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, got)
+
+	assert.Equal(t, "vpc-1", aws.StringValue(svc.subnetFilters[0].Values[0]))
+	assert.Equal(t, "attachment.vpc-id", aws.StringValue(svc.igwFilters[0].Name))
+	assert.Equal(t, "state", aws.StringValue(svc.natFilters[0].Name), "Deleted NAT gateways should be filtered out")
+
+	_, err = collect(&fakeVPCClient{snapshot: &vpcSnapshot{}}, "vpc-missing")
+	assert.EqualError(t, err, "no subnets found in vpc-missing")
+}
+
+// fakeVPCClient serves a snapshot as single-page Describe responses
+type fakeVPCClient struct {
+	ec2iface.EC2API
+	snapshot      *vpcSnapshot
+	subnetFilters []*ec2.Filter
+	natFilters    []*ec2.Filter
+	igwFilters    []*ec2.Filter
+}
+
+func (f *fakeVPCClient) DescribeSubnetsPages(input *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool) error {
+	f.subnetFilters = input.Filters
+	fn(&ec2.DescribeSubnetsOutput{Subnets: f.snapshot.Subnets}, true)
+	return nil
+}
+
+func (f *fakeVPCClient) DescribeRouteTablesPages(input *ec2.DescribeRouteTablesInput, fn func(*ec2.DescribeRouteTablesOutput, bool) bool) error {
+	fn(&ec2.DescribeRouteTablesOutput{RouteTables: f.snapshot.RouteTables}, true)
+	return nil
+}
+
+func (f *fakeVPCClient) DescribeNatGatewaysPages(input *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool) error {
+	f.natFilters = input.Filter
+	fn(&ec2.DescribeNatGatewaysOutput{NatGateways: f.snapshot.NatGateways}, true)
+	return nil
+}
+
+func (f *fakeVPCClient) DescribeInternetGatewaysPages(input *ec2.DescribeInternetGatewaysInput, fn func(*ec2.DescribeInternetGatewaysOutput, bool) bool) error {
+	f.igwFilters = input.Filters
+	fn(&ec2.DescribeInternetGatewaysOutput{InternetGateways: f.snapshot.InternetGateways}, true)
+	return nil
+}
+
+func (f *fakeVPCClient) DescribeVpcEndpointsPages(input *ec2.DescribeVpcEndpointsInput, fn func(*ec2.DescribeVpcEndpointsOutput, bool) bool) error {
+	fn(&ec2.DescribeVpcEndpointsOutput{VpcEndpoints: f.snapshot.Endpoints}, true)
+	return nil
+}
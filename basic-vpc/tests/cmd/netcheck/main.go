@@ -0,0 +1,114 @@
+// Command netcheck audits a deployed VPC and prints which subnets can reach
+// the internet and how, plus warnings for routing that looks wrong.
+//
+// Usage (from basic-vpc/tests):
+//
+//	go run ./cmd/netcheck -vpc vpc-0123456789abcdef0 [-region us-east-1]
+//
+// It only calls Describe APIs and exits 1 when there are warnings.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// vpcSnapshot is everything in a VPC that decides where subnet traffic goes
+type vpcSnapshot struct {
+	VPCID            string
+	Subnets          []*ec2.Subnet
+	RouteTables      []*ec2.RouteTable
+	NatGateways      []*ec2.NatGateway
+	InternetGateways []*ec2.InternetGateway
+	Endpoints        []*ec2.VpcEndpoint
+}
+
+func main() {
+	vpcID := flag.String("vpc", "", "ID of the VPC to check")
+	region := flag.String("region", "us-east-1", "AWS region the VPC lives in")
+	flag.Parse()
+
+	if *vpcID == "" {
+		fmt.Fprintln(os.Stderr, "netcheck: -vpc is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String(*region),
+	}))
+	snapshot, err := collect(ec2.New(sess), *vpcID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "netcheck: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := analyze(snapshot)
+	writeReport(os.Stdout, report)
+	if len(report.Warnings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// collect describes the VPC's subnets, route tables, gateways and endpoints
+func collect(ec2Svc ec2iface.EC2API, vpcID string) (*vpcSnapshot, error) {
+	snapshot := &vpcSnapshot{VPCID: vpcID}
+	inVPC := []*ec2.Filter{{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{vpcID})}}
+
+	err := ec2Svc.DescribeSubnetsPages(&ec2.DescribeSubnetsInput{Filters: inVPC}, func(page *ec2.DescribeSubnetsOutput, _ bool) bool {
+		snapshot.Subnets = append(snapshot.Subnets, page.Subnets...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing subnets: %w", err)
+	}
+	if len(snapshot.Subnets) == 0 {
+		return nil, fmt.Errorf("no subnets found in %s", vpcID)
+	}
+
+	err = ec2Svc.DescribeRouteTablesPages(&ec2.DescribeRouteTablesInput{Filters: inVPC}, func(page *ec2.DescribeRouteTablesOutput, _ bool) bool {
+		snapshot.RouteTables = append(snapshot.RouteTables, page.RouteTables...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing route tables: %w", err)
+	}
+
+	// Deleted NAT gateways linger in Describe results for about an hour
+	natFilters := append([]*ec2.Filter{{
+		Name:   aws.String("state"),
+		Values: aws.StringSlice([]string{ec2.NatGatewayStatePending, ec2.NatGatewayStateAvailable, ec2.NatGatewayStateFailed}),
+	}}, inVPC...)
+	err = ec2Svc.DescribeNatGatewaysPages(&ec2.DescribeNatGatewaysInput{Filter: natFilters}, func(page *ec2.DescribeNatGatewaysOutput, _ bool) bool {
+		snapshot.NatGateways = append(snapshot.NatGateways, page.NatGateways...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing NAT gateways: %w", err)
+	}
+
+	err = ec2Svc.DescribeInternetGatewaysPages(&ec2.DescribeInternetGatewaysInput{
+		Filters: []*ec2.Filter{{Name: aws.String("attachment.vpc-id"), Values: aws.StringSlice([]string{vpcID})}},
+	}, func(page *ec2.DescribeInternetGatewaysOutput, _ bool) bool {
+		snapshot.InternetGateways = append(snapshot.InternetGateways, page.InternetGateways...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing internet gateways: %w", err)
+	}
+
+	err = ec2Svc.DescribeVpcEndpointsPages(&ec2.DescribeVpcEndpointsInput{Filters: inVPC}, func(page *ec2.DescribeVpcEndpointsOutput, _ bool) bool {
+		snapshot.Endpoints = append(snapshot.Endpoints, page.VpcEndpoints...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing VPC endpoints: %w", err)
+	}
+	return snapshot, nil
+}
@@ -1,26 +1,36 @@
 package test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestCostOptimizationInstanceSizing(t *testing.T) {
 	t.Parallel()
 
+	// The smallest allowed size, so the apply also proves the variable is honoured
+	instanceType := "t3.nano"
+
 	terraformOptions := &terraform.Options{
 		TerraformDir: "../../",
 		Vars: map[string]interface{}{
 			"environment":        "cost-test",
 			"allowed_http_cidrs": []string{"10.0.0.0/8"},
 			"allowed_ssh_cidrs":  []string{"10.0.0.0/8"},
+			"instance_type":      instanceType,
 		},
 	}
 
@@ -32,8 +42,8 @@ func TestCostOptimizationInstanceSizing(t *testing.T) {
 	privateInstanceType := terraform.Output(t, terraformOptions, "private_instance_type")
 
 	// Assert cost-effective instance types
-	assert.Equal(t, "t3.micro", publicInstanceType, "Should use cost-effective t3.micro instance")
-	assert.Equal(t, "t3.micro", privateInstanceType, "Should use cost-effective t3.micro instance")
+	assert.Equal(t, instanceType, publicInstanceType, "Public instance should use the requested instance type")
+	assert.Equal(t, instanceType, privateInstanceType, "Private instance should use the requested instance type")
 
 	// Verify instances are using gp3 volumes (more cost-effective than gp2)
 	publicVolumeType := terraform.Output(t, terraformOptions, "public_instance_volume_type")
@@ -118,10 +128,13 @@ func TestCostOptimizationResourceUtilization(t *testing.T) {
 func TestCostOptimizationUnusedResources(t *testing.T) {
 	t.Parallel()
 
+	// A unique environment keeps EIPs from other parallel cost tests out of the count
+	environment := fmt.Sprintf("cost-eip-%s", strings.ToLower(random.UniqueId()))
+
 	terraformOptions := &terraform.Options{
 		TerraformDir: "../../",
 		Vars: map[string]interface{}{
-			"environment":        "cost-test",
+			"environment":        environment,
 			"allowed_http_cidrs": []string{"10.0.0.0/8"},
 			"allowed_ssh_cidrs":  []string{"10.0.0.0/8"},
 		},
@@ -130,14 +143,20 @@ func TestCostOptimizationUnusedResources(t *testing.T) {
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Verify no unused Elastic IPs
-	eipCount := terraform.Output(t, terraformOptions, "elastic_ip_count")
-	assert.Equal(t, "1", eipCount, "Should only have 1 EIP (for NAT Gateway)")
-
 	// Verify NAT Gateway is being used (not idle)
 	natGatewayID := terraform.Output(t, terraformOptions, "nat_gateway_id")
 	assert.NotEmpty(t, natGatewayID, "NAT Gateway should exist for private subnet egress")
 
+	// Verify no unused Elastic IPs: basic-vpc shares one NAT Gateway across
+	// its single availability zone and gives no instance an EIP of its own
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	azs := []string{terraform.Output(t, terraformOptions, "availability_zone")}
+	expectedEIPs, err := testkit.ExpectedEIPCount(testkit.NatGatewayMode(natGatewayID != "", true), len(azs), false)
+	require.NoError(t, err)
+	testkit.AssertEIPCount(t, ec2.New(sess), environment, expectedEIPs)
+
 	// Verify VPC Endpoints are configured (cost-effective alternative to NAT for AWS services)
 	ssmEndpointID := terraform.Output(t, terraformOptions, "ssm_vpc_endpoint_id")
 	ec2MessagesEndpointID := terraform.Output(t, terraformOptions, "ec2messages_vpc_endpoint_id")
@@ -196,12 +215,16 @@ func TestCostOptimizationMonitoringCosts(t *testing.T) {
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Verify detailed monitoring is enabled (minimal cost impact)
-	publicMonitoring := terraform.Output(t, terraformOptions, "public_instance_monitoring")
-	privateMonitoring := terraform.Output(t, terraformOptions, "private_instance_monitoring")
+	// Detailed monitoring should match the configured setting
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
 
-	assert.Equal(t, "true", publicMonitoring, "Detailed monitoring should be enabled")
-	assert.Equal(t, "true", privateMonitoring, "Detailed monitoring should be enabled")
+	expectedState, err := testkit.MonitoringStateFor(terraform.Output(t, terraformOptions, "detailed_monitoring"))
+	require.NoError(t, err)
+	testkit.AssertInstanceMonitoring(t, ec2Svc, terraform.Output(t, terraformOptions, "public_instance_id"), expectedState)
+	testkit.AssertInstanceMonitoring(t, ec2Svc, terraform.Output(t, terraformOptions, "private_instance_id"), expectedState)
 
 	// Verify CloudWatch log retention is reasonable
 	vpcFlowLogRetention := terraform.Output(t, terraformOptions, "vpc_flow_log_retention_days")
@@ -214,6 +237,8 @@ func TestCostOptimizationMonitoringCosts(t *testing.T) {
 	// Verify SNS topic exists for alerts (cost-effective notification system)
 	snsTopicArn := terraform.Output(t, terraformOptions, "sns_topic_arn")
 	assert.NotEmpty(t, snsTopicArn, "SNS topic should exist for cost-effective alerting")
+
+	testkit.AssertLogGroupRetention(t, terraform.Show(t, terraformOptions), testkit.LogsClient)
 }
 
 func TestCostOptimizationDataTransfer(t *testing.T) {
@@ -305,3 +330,37 @@ func TestCostOptimizationReservedInstances(t *testing.T) {
 
 	assert.Equal(t, publicAZ, privateAZ, "Instances in same AZ enable better RI utilization")
 }
+
+// TestDetailedMonitoringToggle applies with detailed monitoring on, then off,
+// and checks EC2 reports each state on both instances
+func TestDetailedMonitoringToggle(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":         "cost-test",
+			"allowed_http_cidrs":  []string{"10.0.0.0/8"},
+			"allowed_ssh_cidrs":   []string{"10.0.0.0/8"},
+			"detailed_monitoring": true,
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+
+	// Turning monitoring off is an in-place update, so one stack covers both states
+	for _, enabled := range []bool{true, false} {
+		terraformOptions.Vars["detailed_monitoring"] = enabled
+		terraform.InitAndApply(t, terraformOptions)
+
+		expectedState, err := testkit.MonitoringStateFor(terraform.Output(t, terraformOptions, "detailed_monitoring"))
+		require.NoError(t, err)
+		testkit.AssertInstanceMonitoring(t, ec2Svc, terraform.Output(t, terraformOptions, "public_instance_id"), expectedState)
+		testkit.AssertInstanceMonitoring(t, ec2Svc, terraform.Output(t, terraformOptions, "private_instance_id"), expectedState)
+	}
+}
@@ -0,0 +1,38 @@
+# Renders the instance user_data template in isolation so tests can inspect
+# the script without launching instances.
+
+variable "packages" {
+  type = list(string)
+}
+
+variable "web_root_content" {
+  type    = string
+  default = ""
+}
+
+variable "health_check_content" {
+  type    = string
+  default = "OK"
+}
+
+variable "probe_url" {
+  type    = string
+  default = ""
+}
+
+locals {
+  rendered = templatefile("${path.module}/../../../templates/user_data.sh.tftpl", {
+    packages             = var.packages
+    web_root_content     = var.web_root_content
+    health_check_content = var.health_check_content
+    probe_url            = var.probe_url
+  })
+}
+
+output "rendered" {
+  value = local.rendered
+}
+
+output "sha256" {
+  value = sha256(local.rendered)
+}
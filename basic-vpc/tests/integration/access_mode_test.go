@@ -0,0 +1,182 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSSMOnlyAccessMode validates ssm-only leaves no security group or NACL
+// open on port 22 while Session Manager still reaches both instances
+func TestSSMOnlyAccessMode(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":        "test",
+			"allowed_http_cidrs": []string{"10.0.0.0/8"},
+			"allowed_ssh_cidrs":  []string{"10.0.0.0/8"},
+			"access_mode":        "ssm-only",
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "ssm-only", terraform.Output(t, terraformOptions, "access_mode"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+
+	groups, err := ec2.New(sess).DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: aws.StringSlice([]string{
+			terraform.Output(t, terraformOptions, "public_security_group_id"),
+			terraform.Output(t, terraformOptions, "private_security_group_id"),
+			terraform.Output(t, terraformOptions, "vpc_endpoint_security_group_id"),
+		}),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, ingressRulesAdmittingPort(groups.SecurityGroups, 22), "No security group should admit SSH in ssm-only mode")
+
+	acls, err := ec2.New(sess).DescribeNetworkAcls(&ec2.DescribeNetworkAclsInput{
+		NetworkAclIds: aws.StringSlice([]string{
+			terraform.Output(t, terraformOptions, "public_nacl_id"),
+			terraform.Output(t, terraformOptions, "private_nacl_id"),
+		}),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, naclEntriesAdmittingPort(acls.NetworkAcls, 22), "No NACL should admit SSH in ssm-only mode")
+
+	ssmSvc := ssm.New(sess)
+	for _, output := range []string{"public_instance_id", "private_instance_id"} {
+		instanceID := terraform.Output(t, terraformOptions, output)
+		waitForSSMAgent(t, ssmSvc, instanceID)
+
+		stdout, err := runShellScript(ssmSvc, instanceID, []string{"echo ssm-ok"})
+		require.NoError(t, err)
+		assert.Equal(t, "ssm-ok", strings.TrimSpace(stdout), "Run Command should work on %s", instanceID)
+	}
+}
+
+func TestIngressRulesAdmittingPort(t *testing.T) {
+	t.Parallel()
+
+	groups := []*ec2.SecurityGroup{
+		{
+			GroupId: aws.String("sg-public"),
+			IpPermissions: []*ec2.IpPermission{
+				{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(80), ToPort: aws.Int64(80), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("10.0.0.0/8")}}},
+				{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(22), ToPort: aws.Int64(22), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("10.0.0.0/8")}}},
+			},
+		},
+		{
+			GroupId: aws.String("sg-private"),
+			IpPermissions: []*ec2.IpPermission{
+				// Ranges and all-traffic rules admit 22 without naming it
+				{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(0), ToPort: aws.Int64(1023), UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-public")}}},
+				{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("10.0.1.0/24")}}},
+				{IpProtocol: aws.String("udp"), FromPort: aws.Int64(22), ToPort: aws.Int64(22), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("10.0.1.0/24")}}},
+			},
+		},
+		{
+			GroupId:       aws.String("sg-endpoints"),
+			IpPermissions: []*ec2.IpPermission{{IpProtocol: aws.String("tcp"), FromPort: aws.Int64(443), ToPort: aws.Int64(443)}},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"sg-public tcp/22-22",
+		"sg-private tcp/0-1023",
+		"sg-private all",
+	}, ingressRulesAdmittingPort(groups, 22))
+	assert.Empty(t, ingressRulesAdmittingPort(groups[2:], 22))
+}
+
+func TestNACLEntriesAdmittingPort(t *testing.T) {
+	t.Parallel()
+
+	acls := []*ec2.NetworkAcl{
+		{
+			NetworkAclId: aws.String("acl-public"),
+			Entries: []*ec2.NetworkAclEntry{
+				{RuleNumber: aws.Int64(100), Protocol: aws.String("6"), RuleAction: aws.String("allow"), PortRange: &ec2.PortRange{From: aws.Int64(80), To: aws.Int64(80)}},
+				{RuleNumber: aws.Int64(120), Protocol: aws.String("6"), RuleAction: aws.String("allow"), PortRange: &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)}},
+				{RuleNumber: aws.Int64(130), Protocol: aws.String("6"), RuleAction: aws.String("allow"), PortRange: &ec2.PortRange{From: aws.Int64(1024), To: aws.Int64(65535)}},
+				// Egress entries and denies don't admit anything
+				{RuleNumber: aws.Int64(100), Protocol: aws.String("-1"), RuleAction: aws.String("allow"), Egress: aws.Bool(true)},
+				{RuleNumber: aws.Int64(140), Protocol: aws.String("6"), RuleAction: aws.String("deny"), PortRange: &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)}},
+			},
+		},
+		{
+			NetworkAclId: aws.String("acl-private"),
+			Entries: []*ec2.NetworkAclEntry{
+				{RuleNumber: aws.Int64(32767), Protocol: aws.String("-1"), RuleAction: aws.String("deny")},
+				{RuleNumber: aws.Int64(150), Protocol: aws.String("-1"), RuleAction: aws.String("allow")},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"acl-public #120 tcp/22-22",
+		"acl-private #150 all",
+	}, naclEntriesAdmittingPort(acls, 22))
+	assert.Empty(t, naclEntriesAdmittingPort(acls[:1], 443))
+}
+
+// Helper function to list ingress rules that admit TCP traffic on a port,
+// including port ranges and all-traffic rules that cover it
+func ingressRulesAdmittingPort(groups []*ec2.SecurityGroup, port int64) []string {
+	var rules []string
+	for _, group := range groups {
+		for _, permission := range group.IpPermissions {
+			protocol := aws.StringValue(permission.IpProtocol)
+			if protocol == "-1" {
+				rules = append(rules, aws.StringValue(group.GroupId)+" all")
+				continue
+			}
+			if protocol != "tcp" && protocol != "6" {
+				continue
+			}
+			if aws.Int64Value(permission.FromPort) <= port && port <= aws.Int64Value(permission.ToPort) {
+				rules = append(rules, fmt.Sprintf("%s tcp/%d-%d", aws.StringValue(group.GroupId), aws.Int64Value(permission.FromPort), aws.Int64Value(permission.ToPort)))
+			}
+		}
+	}
+	return rules
+}
+
+// Helper function to list the ingress NACL entries that allow TCP traffic on
+// a port, including port ranges and all-traffic entries
+func naclEntriesAdmittingPort(acls []*ec2.NetworkAcl, port int64) []string {
+	var entries []string
+	for _, acl := range acls {
+		for _, entry := range acl.Entries {
+			if aws.BoolValue(entry.Egress) || aws.StringValue(entry.RuleAction) != "allow" {
+				continue
+			}
+			protocol := aws.StringValue(entry.Protocol)
+			if protocol == "-1" {
+				entries = append(entries, fmt.Sprintf("%s #%d all", aws.StringValue(acl.NetworkAclId), aws.Int64Value(entry.RuleNumber)))
+				continue
+			}
+			if protocol != "6" || entry.PortRange == nil {
+				continue
+			}
+			if aws.Int64Value(entry.PortRange.From) <= port && port <= aws.Int64Value(entry.PortRange.To) {
+				entries = append(entries, fmt.Sprintf("%s #%d tcp/%d-%d", aws.StringValue(acl.NetworkAclId), aws.Int64Value(entry.RuleNumber),
+					aws.Int64Value(entry.PortRange.From), aws.Int64Value(entry.PortRange.To)))
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,282 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectivityProbe is a TCP destination the private instance tries to reach
+type connectivityProbe struct {
+	Host string
+	Port int
+	AWS  bool // Reachable through a VPC endpoint rather than the internet
+}
+
+func (p connectivityProbe) String() string {
+	return fmt.Sprintf("%s:%d", p.Host, p.Port)
+}
+
+// Destinations probed for every profile: the SSM interface endpoint, S3 via
+// the gateway endpoint, and an arbitrary internet host on HTTPS and HTTP
+var connectivityProbes = []connectivityProbe{
+	{Host: "ssm.us-east-1.amazonaws.com", Port: 443, AWS: true},
+	{Host: "s3.us-east-1.amazonaws.com", Port: 443, AWS: true},
+	{Host: "example.com", Port: 443},
+	{Host: "example.com", Port: 80},
+}
+
+// TestEgressProfileConnectivity applies each egress profile and checks what the
+// private instance can reach through SSM Run Command
+func TestEgressProfileConnectivity(t *testing.T) {
+	t.Parallel()
+
+	profiles := []struct {
+		name  string
+		ports []int
+	}{
+		{name: "open"},
+		{name: "aws-only"},
+		{name: "custom-ports", ports: []int{80}},
+	}
+
+	for _, profile := range profiles {
+		profile := profile
+		t.Run(profile.name, func(t *testing.T) {
+			t.Parallel()
+
+			vars := map[string]interface{}{
+				"environment":        "test",
+				"allowed_http_cidrs": []string{"10.0.0.0/8"},
+				"allowed_ssh_cidrs":  []string{"10.0.0.0/8"},
+				"egress_profile":     profile.name,
+			}
+			if profile.ports != nil {
+				vars["egress_allowed_ports"] = profile.ports
+			}
+			terraformOptions := &terraform.Options{
+				TerraformDir: "../../",
+				Vars:         vars,
+			}
+
+			defer terraform.Destroy(t, terraformOptions)
+			terraform.InitAndApply(t, terraformOptions)
+
+			assert.Equal(t, profile.name, terraform.Output(t, terraformOptions, "egress_profile"))
+			instanceID := terraform.Output(t, terraformOptions, "private_instance_id")
+
+			sess := session.Must(session.NewSession(&aws.Config{
+				Region: aws.String("us-east-1"),
+			}))
+			ssmSvc := ssm.New(sess)
+
+			// The agent registers through the interface endpoints in every profile
+			waitForSSMAgent(t, ssmSvc, instanceID)
+
+			output, err := runShellScript(ssmSvc, instanceID, probeScript(connectivityProbes))
+			require.NoError(t, err)
+			got, err := parseProbeResults(output)
+			require.NoError(t, err)
+
+			assert.Equal(t, expectedConnectivity(profile.name, profile.ports, connectivityProbes), got, "Connectivity matrix for %s", profile.name)
+		})
+	}
+}
+
+func TestExpectedConnectivity(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, map[string]bool{
+		"ssm.us-east-1.amazonaws.com:443": true,
+		"s3.us-east-1.amazonaws.com:443":  true,
+		"example.com:443":                 true,
+		"example.com:80":                  true,
+	}, expectedConnectivity("open", nil, connectivityProbes))
+
+	assert.Equal(t, map[string]bool{
+		"ssm.us-east-1.amazonaws.com:443": true,
+		"s3.us-east-1.amazonaws.com:443":  true,
+		"example.com:443":                 false,
+		"example.com:80":                  false,
+	}, expectedConnectivity("aws-only", []int{80}, connectivityProbes), "aws-only ignores the allowed ports")
+
+	assert.Equal(t, map[string]bool{
+		"ssm.us-east-1.amazonaws.com:443": true,
+		"s3.us-east-1.amazonaws.com:443":  true,
+		"example.com:443":                 false,
+		"example.com:80":                  true,
+	}, expectedConnectivity("custom-ports", []int{80}, connectivityProbes))
+}
+
+func TestProbeResults(t *testing.T) {
+	t.Parallel()
+
+	script := probeScript(connectivityProbes[2:])
+	assert.Equal(t, []string{
+		"if timeout 5 bash -c '</dev/tcp/example.com/443' 2>/dev/null; then echo 'example.com:443 open'; else echo 'example.com:443 closed'; fi",
+		"if timeout 5 bash -c '</dev/tcp/example.com/80' 2>/dev/null; then echo 'example.com:80 open'; else echo 'example.com:80 closed'; fi",
+	}, script)
+
+	got, err := parseProbeResults("example.com:443 closed\nexample.com:80 open\n")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"example.com:443": false, "example.com:80": true}, got)
+
+	_, err = parseProbeResults("example.com:443 filtered\n")
+	assert.Error(t, err)
+}
+
+func TestRunShellScript(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeRunCommandClient{statuses: []string{ssm.CommandInvocationStatusInProgress, ssm.CommandInvocationStatusSuccess}, output: "example.com:80 open\n"}
+	output, err := runShellScript(svc, "i-0123456789abcdef0", []string{"echo hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "example.com:80 open\n", output)
+	assert.Equal(t, 2, svc.polls, "Should poll until the invocation finishes")
+
+	svc = &fakeRunCommandClient{statuses: []string{ssm.CommandInvocationStatusFailed}, output: "bash: boom"}
+	_, err = runShellScript(svc, "i-0123456789abcdef0", []string{"boom"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bash: boom")
+}
+
+type fakeRunCommandClient struct {
+	ssmiface.SSMAPI
+	statuses []string
+	output   string
+	polls    int
+}
+
+func (f *fakeRunCommandClient) SendCommand(input *ssm.SendCommandInput) (*ssm.SendCommandOutput, error) {
+	return &ssm.SendCommandOutput{Command: &ssm.Command{CommandId: aws.String("cmd-1")}}, nil
+}
+
+func (f *fakeRunCommandClient) GetCommandInvocation(input *ssm.GetCommandInvocationInput) (*ssm.GetCommandInvocationOutput, error) {
+	status := f.statuses[f.polls]
+	f.polls++
+	return &ssm.GetCommandInvocationOutput{
+		Status:                aws.String(status),
+		StandardOutputContent: aws.String(f.output),
+		StandardErrorContent:  aws.String(f.output),
+	}, nil
+}
+
+// Helper function to build the connectivity matrix a profile should produce.
+// AWS destinations are always reachable through the endpoints; internet
+// destinations only under open, or on an allowed port under custom-ports.
+func expectedConnectivity(profile string, ports []int, probes []connectivityProbe) map[string]bool {
+	allowed := map[int]bool{}
+	if profile == "custom-ports" {
+		for _, port := range ports {
+			allowed[port] = true
+		}
+	}
+
+	expected := map[string]bool{}
+	for _, probe := range probes {
+		expected[probe.String()] = probe.AWS || profile == "open" || allowed[probe.Port]
+	}
+	return expected
+}
+
+// Helper function to build shell commands that report each probe as open or closed
+func probeScript(probes []connectivityProbe) []string {
+	var commands []string
+	for _, probe := range probes {
+		commands = append(commands, fmt.Sprintf(
+			"if timeout 5 bash -c '</dev/tcp/%s/%d' 2>/dev/null; then echo '%s open'; else echo '%s closed'; fi",
+			probe.Host, probe.Port, probe, probe))
+	}
+	return commands
+}
+
+// Helper function to parse "host:port open|closed" lines into a connectivity matrix
+func parseProbeResults(output string) (map[string]bool, error) {
+	results := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || (fields[1] != "open" && fields[1] != "closed") {
+			return nil, fmt.Errorf("unexpected probe output %q", line)
+		}
+		results[fields[0]] = fields[1] == "open"
+	}
+	return results, nil
+}
+
+// Helper function to wait until an instance's SSM agent reports online
+func waitForSSMAgent(t *testing.T, svc ssmiface.SSMAPI, instanceID string) {
+	require.NoError(t, waitForSSMAgentE(t, svc, instanceID))
+}
+
+// Helper function to wait for an instance's SSM agent, returning an error
+// rather than failing so callers can add their own context. Permission
+// errors are returned straight away.
+func waitForSSMAgentE(t *testing.T, svc ssmiface.SSMAPI, instanceID string) error {
+	_, err := retry.DoWithRetryE(t, "Waiting for SSM agent on "+instanceID, 30, 10*time.Second, func() (string, error) {
+		info, err := svc.DescribeInstanceInformation(&ssm.DescribeInstanceInformationInput{
+			Filters: []*ssm.InstanceInformationStringFilter{
+				{Key: aws.String("InstanceIds"), Values: []*string{aws.String(instanceID)}},
+			},
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "AccessDenied") {
+				return "", retry.FatalError{Underlying: err}
+			}
+			return "", err
+		}
+		if len(info.InstanceInformationList) == 0 || aws.StringValue(info.InstanceInformationList[0].PingStatus) != ssm.PingStatusOnline {
+			return "", fmt.Errorf("SSM agent on %s is not online yet", instanceID)
+		}
+		return "online", nil
+	})
+	return err
+}
+
+// Helper function to run shell commands on an instance through SSM Run Command
+// and return their output once the invocation finishes
+func runShellScript(svc ssmiface.SSMAPI, instanceID string, commands []string) (string, error) {
+	sent, err := svc.SendCommand(&ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		InstanceIds:  []*string{aws.String(instanceID)},
+		Parameters:   map[string][]*string{"commands": aws.StringSlice(commands)},
+	})
+	if err != nil {
+		return "", err
+	}
+	commandID := aws.StringValue(sent.Command.CommandId)
+
+	for attempt := 0; attempt < 30; attempt++ {
+		invocation, err := svc.GetCommandInvocation(&ssm.GetCommandInvocationInput{
+			CommandId:  aws.String(commandID),
+			InstanceId: aws.String(instanceID),
+		})
+		if err != nil {
+			// The invocation isn't visible for a moment after SendCommand
+			if strings.Contains(err.Error(), ssm.ErrCodeInvocationDoesNotExist) {
+				time.Sleep(2 * time.Second)
+				continue
+			}
+			return "", err
+		}
+
+		switch status := aws.StringValue(invocation.Status); status {
+		case ssm.CommandInvocationStatusSuccess:
+			return aws.StringValue(invocation.StandardOutputContent), nil
+		case ssm.CommandInvocationStatusPending, ssm.CommandInvocationStatusInProgress, ssm.CommandInvocationStatusDelayed:
+			time.Sleep(2 * time.Second)
+		default:
+			return "", fmt.Errorf("command %s on %s finished %s: %s", commandID, instanceID, status, aws.StringValue(invocation.StandardErrorContent))
+		}
+	}
+	return "", fmt.Errorf("command %s on %s did not finish", commandID, instanceID)
+}
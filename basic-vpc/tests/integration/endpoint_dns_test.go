@@ -0,0 +1,176 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Interface endpoint hostnames that private DNS should answer inside the VPC
+var interfaceEndpointHosts = []string{
+	"ssm.us-east-1.amazonaws.com",
+	"ec2messages.us-east-1.amazonaws.com",
+	"ssmmessages.us-east-1.amazonaws.com",
+}
+
+// TestEndpointPrivateDNS resolves the SSM service hostnames from the private
+// instance and checks every answer is an endpoint ENI inside the VPC, proving
+// private DNS works end to end rather than only in the endpoint configuration
+func TestEndpointPrivateDNS(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":        "test",
+			"allowed_http_cidrs": []string{"10.0.0.0/8"},
+			"allowed_ssh_cidrs":  []string{"10.0.0.0/8"},
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	vpcCIDR := terraform.Output(t, terraformOptions, "vpc_cidr_block")
+	instanceID := terraform.Output(t, terraformOptions, "private_instance_id")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ssmSvc := ssm.New(sess)
+	// The resolver check runs through SSM, so an agent that never registers is a failure too
+	require.NoError(t, waitForSSMAgentE(t, ssmSvc, instanceID), "SSM agent on %s never registered", instanceID)
+
+	assertResolvesWithinCIDR(t, ssmSvc, instanceID, interfaceEndpointHosts, vpcCIDR)
+}
+
+func TestResolvedAddressProblems(t *testing.T) {
+	t.Parallel()
+
+	script := endpointDNSScript([]string{"ssm.us-east-1.amazonaws.com"})
+	assert.Equal(t, []string{
+		`echo "ssm.us-east-1.amazonaws.com $(getent ahostsv4 ssm.us-east-1.amazonaws.com | awk '{print $1}' | sort -u | tr '\n' ' ')"`,
+	}, script)
+
+	resolved, err := parseResolvedAddresses("ssm.us-east-1.amazonaws.com 10.0.2.15 10.0.2.16 \nec2messages.us-east-1.amazonaws.com 52.46.128.10 \nssmmessages.us-east-1.amazonaws.com \n")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"ssm.us-east-1.amazonaws.com":         {"10.0.2.15", "10.0.2.16"},
+		"ec2messages.us-east-1.amazonaws.com": {"52.46.128.10"},
+		"ssmmessages.us-east-1.amazonaws.com": nil,
+	}, resolved)
+
+	problems, err := resolvedAddressProblems(resolved, interfaceEndpointHosts, "10.0.0.0/16")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"ec2messages.us-east-1.amazonaws.com resolves to 52.46.128.10, outside 10.0.0.0/16",
+		"ssmmessages.us-east-1.amazonaws.com did not resolve",
+	}, problems)
+
+	_, err = parseResolvedAddresses("ssm.us-east-1.amazonaws.com not-an-ip\n")
+	assert.Error(t, err)
+	_, err = resolvedAddressProblems(resolved, interfaceEndpointHosts, "10.0.0.0")
+	assert.Error(t, err)
+}
+
+func TestWaitForSSMAgentE(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeInstanceInformationClient{err: fmt.Errorf("AccessDeniedException: not authorized")}
+	err := waitForSSMAgentE(t, svc, "i-0123456789abcdef0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AccessDeniedException")
+	assert.Equal(t, 1, svc.calls, "Permission errors should not be retried")
+}
+
+type fakeInstanceInformationClient struct {
+	ssmiface.SSMAPI
+	err   error
+	calls int
+}
+
+func (f *fakeInstanceInformationClient) DescribeInstanceInformation(input *ssm.DescribeInstanceInformationInput) (*ssm.DescribeInstanceInformationOutput, error) {
+	f.calls++
+	return nil, f.err
+}
+
+// Helper function to resolve hostnames on an instance through SSM Run Command
+// and assert every address falls inside a CIDR
+func assertResolvesWithinCIDR(t *testing.T, svc ssmiface.SSMAPI, instanceID string, hosts []string, cidr string) {
+	output, err := runShellScript(svc, instanceID, endpointDNSScript(hosts))
+	require.NoError(t, err)
+	resolved, err := parseResolvedAddresses(output)
+	require.NoError(t, err)
+
+	problems, err := resolvedAddressProblems(resolved, hosts, cidr)
+	require.NoError(t, err)
+	for _, problem := range problems {
+		assert.Fail(t, "Endpoint private DNS not effective", problem)
+	}
+}
+
+// Helper function to build shell commands printing "host addr..." for each host's IPv4 answers
+func endpointDNSScript(hosts []string) []string {
+	var commands []string
+	for _, host := range hosts {
+		commands = append(commands, fmt.Sprintf(
+			`echo "%s $(getent ahostsv4 %s | awk '{print $1}' | sort -u | tr '\n' ' ')"`, host, host))
+	}
+	return commands
+}
+
+// Helper function to parse "host addr..." lines into each host's addresses
+func parseResolvedAddresses(output string) (map[string][]string, error) {
+	resolved := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var addresses []string
+		for _, address := range fields[1:] {
+			if net.ParseIP(address) == nil {
+				return nil, fmt.Errorf("unexpected address %q for %s", address, fields[0])
+			}
+			addresses = append(addresses, address)
+		}
+		resolved[fields[0]] = addresses
+	}
+	return resolved, nil
+}
+
+// Helper function to list hosts that didn't resolve or resolved outside a CIDR
+func resolvedAddressProblems(resolved map[string][]string, hosts []string, cidr string) ([]string, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := append([]string(nil), hosts...)
+	sort.Strings(sorted)
+
+	var problems []string
+	for _, host := range sorted {
+		addresses := resolved[host]
+		if len(addresses) == 0 {
+			problems = append(problems, host+" did not resolve")
+			continue
+		}
+		for _, address := range addresses {
+			if !network.Contains(net.ParseIP(address)) {
+				problems = append(problems, fmt.Sprintf("%s resolves to %s, outside %s", host, address, cidr))
+			}
+		}
+	}
+	return problems, nil
+}
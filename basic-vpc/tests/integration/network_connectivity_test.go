@@ -1,10 +1,25 @@
 package test
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
-
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"basic-vpc-tests/testutil"
+	"testkit"
 )
 
 func TestNetworkConnectivity(t *testing.T) {
@@ -97,13 +112,89 @@ func TestInternetGatewayAndNatGateway(t *testing.T) {
 	natId := terraform.Output(t, terraformOptions, "nat_gateway_id")
 	assert.NotEmpty(t, natId)
 
-	natState := terraform.Output(t, terraformOptions, "nat_gateway_state")
-	assert.Equal(t, "available", natState)
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+
+	// The NAT Gateway can still be provisioning when apply returns
+	require.NoError(t, waitForNatAvailable(ec2Svc, natId, 10*time.Minute))
 
 	// Test NAT Gateway is in public subnet
 	natSubnetId := terraform.Output(t, terraformOptions, "nat_gateway_subnet_id")
 	publicSubnetId := terraform.Output(t, terraformOptions, "public_subnet_id")
 	assert.Equal(t, publicSubnetId, natSubnetId)
+
+	// Verify the NAT Gateway really lives in the public subnet
+	natResult, err := ec2Svc.DescribeNatGateways(&ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: []*string{aws.String(natId)},
+	})
+	require.NoError(t, err)
+	require.Len(t, natResult.NatGateways, 1)
+	assert.Equal(t, publicSubnetId, aws.StringValue(natResult.NatGateways[0].SubnetId))
+
+	// Verify the NAT's subnet routes 0.0.0.0/0 to the Internet Gateway
+	rtResult, err := ec2Svc.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("association.subnet-id"),
+				Values: []*string{aws.String(natSubnetId)},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, rtResult.RouteTables, 1, "NAT subnet should have an explicit route table association")
+	assert.True(t, testutil.HasDefaultRouteToGateway(rtResult.RouteTables[0], igwId),
+		"Route table for the NAT subnet should route 0.0.0.0/0 to the Internet Gateway")
+}
+
+func TestWaitForNatAvailable(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeNatGatewayClient{
+		states: []string{ec2.NatGatewayStatePending, ec2.NatGatewayStatePending, ec2.NatGatewayStateAvailable},
+	}
+
+	start := time.Now()
+	require.NoError(t, waitForNatAvailable(svc, "nat-0123456789abcdef0", 2*time.Second))
+	assert.Less(t, time.Since(start), time.Second, "Helper should return as soon as the NAT Gateway is available")
+	assert.Equal(t, 3, svc.calls)
+	assert.Equal(t, []string{"nat-0123456789abcdef0"}, aws.StringValueSlice(svc.lastInput.NatGatewayIds))
+
+	// A failed NAT Gateway will never become available
+	failed := &fakeNatGatewayClient{states: []string{ec2.NatGatewayStatePending, ec2.NatGatewayStateFailed}}
+	err := waitForNatAvailable(failed, "nat-0123456789abcdef0", 2*time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed")
+	assert.Equal(t, 2, failed.calls)
+
+	stuck := &fakeNatGatewayClient{states: []string{ec2.NatGatewayStatePending}}
+	err = waitForNatAvailable(stuck, "nat-0123456789abcdef0", 200*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "last state pending")
+}
+
+// fakeNatGatewayClient reports the next state on each DescribeNatGateways call,
+// holding the final state once the sequence is exhausted
+type fakeNatGatewayClient struct {
+	ec2iface.EC2API
+	states    []string
+	calls     int
+	lastInput *ec2.DescribeNatGatewaysInput
+}
+
+func (f *fakeNatGatewayClient) DescribeNatGateways(input *ec2.DescribeNatGatewaysInput) (*ec2.DescribeNatGatewaysOutput, error) {
+	f.lastInput = input
+	f.calls++
+	state := f.states[len(f.states)-1]
+	if f.calls <= len(f.states) {
+		state = f.states[f.calls-1]
+	}
+	return &ec2.DescribeNatGatewaysOutput{
+		NatGateways: []*ec2.NatGateway{
+			{NatGatewayId: input.NatGatewayIds[0], State: aws.String(state)},
+		},
+	}, nil
 }
 
 func TestRouteTables(t *testing.T) {
@@ -166,3 +257,217 @@ func TestVpcFlowLogs(t *testing.T) {
 	logGroupRetention := terraform.Output(t, terraformOptions, "vpc_flow_log_retention_days")
 	assert.Equal(t, "30", logGroupRetention)
 }
+
+func TestVpcFlowLogFormat(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":        "test",
+			"allowed_http_cidrs": []string{"0.0.0.0/0"},
+			"allowed_ssh_cidrs":  []string{"10.0.0.0/8"},
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	format := terraform.Output(t, terraformOptions, "vpc_flow_log_format")
+	fields := flowLogFields(format)
+	for _, field := range []string{"pkt-srcaddr", "tcp-flags", "action"} {
+		require.Contains(t, fields, field, "Flow log format should include %s", field)
+	}
+
+	// Generate accepted TCP traffic to the public instance
+	publicIP := terraform.Output(t, terraformOptions, "public_instance_public_ip")
+	client := &http.Client{Timeout: 10 * time.Second}
+	for i := 0; i < 5; i++ {
+		if resp, err := client.Get(fmt.Sprintf("http://%s/", publicIP)); err == nil {
+			resp.Body.Close()
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	logsSvc := cloudwatchlogs.New(sess)
+	logGroupName := terraform.Output(t, terraformOptions, "vpc_flow_log_group_name")
+
+	// Flow logs are aggregated and delivered every few minutes
+	var record map[string]string
+	retry.DoWithRetry(t, "Waiting for a flow log record", 20, 30*time.Second, func() (string, error) {
+		events, err := logsSvc.FilterLogEvents(&cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName: aws.String(logGroupName),
+			// Only records carrying data populate every field
+			FilterPattern: aws.String(`"OK"`),
+			Limit:         aws.Int64(50),
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, event := range events.Events {
+			parsed, err := parseFlowLogRecord(format, aws.StringValue(event.Message))
+			if err != nil {
+				return "", retry.FatalError{Underlying: err}
+			}
+			if parsed["log-status"] == "OK" && parsed["protocol"] == "6" {
+				record = parsed
+				return aws.StringValue(event.Message), nil
+			}
+		}
+		return "", fmt.Errorf("no TCP flow log records in %s yet", logGroupName)
+	})
+
+	for _, field := range []string{"pkt-srcaddr", "tcp-flags", "action"} {
+		assert.NotEmpty(t, record[field], "Field %s should be present", field)
+		assert.NotEqual(t, "-", record[field], "Field %s should be populated", field)
+	}
+	assert.Contains(t, []string{"ACCEPT", "REJECT"}, record["action"])
+}
+
+func TestParseFlowLogRecord(t *testing.T) {
+	t.Parallel()
+
+	format := "${version} ${srcaddr} ${pkt-srcaddr} ${dstport} ${action} ${tcp-flags} ${log-status}"
+	assert.Equal(t, []string{"version", "srcaddr", "pkt-srcaddr", "dstport", "action", "tcp-flags", "log-status"}, flowLogFields(format))
+
+	record, err := parseFlowLogRecord(format, "5 203.0.113.10 203.0.113.10 80 ACCEPT 19 OK")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"version":     "5",
+		"srcaddr":     "203.0.113.10",
+		"pkt-srcaddr": "203.0.113.10",
+		"dstport":     "80",
+		"action":      "ACCEPT",
+		"tcp-flags":   "19",
+		"log-status":  "OK",
+	}, record)
+
+	// A record with a different field count doesn't match the format
+	_, err = parseFlowLogRecord(format, "5 203.0.113.10 80 ACCEPT OK")
+	assert.Error(t, err)
+}
+
+// TestVpcFlowLogAggregationInterval applies the finer 60-second interval and
+// checks the flow log AWS describes uses it, not the cheaper 600s default
+func TestVpcFlowLogAggregationInterval(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":                       "test",
+			"allowed_http_cidrs":                []string{"10.0.0.0/8"},
+			"allowed_ssh_cidrs":                 []string{"10.0.0.0/8"},
+			"flow_log_max_aggregation_interval": 60,
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "60", terraform.Output(t, terraformOptions, "vpc_flow_log_max_aggregation_interval"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	flowLogID := terraform.Output(t, terraformOptions, "vpc_flow_log_id")
+	interval, err := flowLogAggregationInterval(ec2.New(sess), flowLogID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), interval)
+}
+
+func TestFlowLogAggregationInterval(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeFlowLogClient{flowLogs: []*ec2.FlowLog{
+		{FlowLogId: aws.String("fl-0123456789abcdef0"), MaxAggregationInterval: aws.Int64(600)},
+	}}
+	interval, err := flowLogAggregationInterval(svc, "fl-0123456789abcdef0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), interval)
+	assert.Equal(t, []string{"fl-0123456789abcdef0"}, aws.StringValueSlice(svc.lastInput.FlowLogIds))
+
+	_, err = flowLogAggregationInterval(&fakeFlowLogClient{}, "fl-0123456789abcdef0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "found 0")
+}
+
+type fakeFlowLogClient struct {
+	ec2iface.EC2API
+	flowLogs  []*ec2.FlowLog
+	lastInput *ec2.DescribeFlowLogsInput
+}
+
+func (f *fakeFlowLogClient) DescribeFlowLogs(input *ec2.DescribeFlowLogsInput) (*ec2.DescribeFlowLogsOutput, error) {
+	f.lastInput = input
+	return &ec2.DescribeFlowLogsOutput{FlowLogs: f.flowLogs}, nil
+}
+
+// Helper function to list field names from a flow log format such as "${version} ${srcaddr}"
+func flowLogFields(format string) []string {
+	var fields []string
+	for _, token := range strings.Fields(format) {
+		fields = append(fields, strings.TrimSuffix(strings.TrimPrefix(token, "${"), "}"))
+	}
+	return fields
+}
+
+// Helper function to parse a space-separated flow log record into field values
+func parseFlowLogRecord(format, message string) (map[string]string, error) {
+	fields := flowLogFields(format)
+	values := strings.Fields(message)
+	if len(values) != len(fields) {
+		return nil, fmt.Errorf("flow log record has %d values, format has %d fields: %q", len(values), len(fields), message)
+	}
+
+	record := make(map[string]string, len(fields))
+	for i, field := range fields {
+		record[field] = values[i]
+	}
+	return record, nil
+}
+
+// Helper function to poll DescribeNatGateways until a NAT Gateway is available.
+// Returns early with an error if it fails or is being deleted.
+func waitForNatAvailable(ec2Svc ec2iface.EC2API, natID string, timeout time.Duration) error {
+	var state string
+	err := testkit.Poll(timeout, func() (bool, error) {
+		result, err := ec2Svc.DescribeNatGateways(&ec2.DescribeNatGatewaysInput{
+			NatGatewayIds: []*string{aws.String(natID)},
+		})
+		if err != nil {
+			return false, err
+		}
+		if len(result.NatGateways) != 1 {
+			return false, fmt.Errorf("expected 1 NAT Gateway %s, found %d", natID, len(result.NatGateways))
+		}
+
+		state = aws.StringValue(result.NatGateways[0].State)
+		switch state {
+		case ec2.NatGatewayStateFailed, ec2.NatGatewayStateDeleting, ec2.NatGatewayStateDeleted:
+			return false, fmt.Errorf("NAT Gateway %s is %s: %s", natID, state, aws.StringValue(result.NatGateways[0].FailureMessage))
+		}
+		return state == ec2.NatGatewayStateAvailable, nil
+	})
+	if errors.Is(err, testkit.ErrPollTimeout) {
+		return fmt.Errorf("NAT Gateway %s not available within %s (last state %s)", natID, timeout, state)
+	}
+	return err
+}
+
+// Helper function to read a flow log's maximum aggregation interval in seconds
+func flowLogAggregationInterval(ec2Svc ec2iface.EC2API, flowLogID string) (int64, error) {
+	result, err := ec2Svc.DescribeFlowLogs(&ec2.DescribeFlowLogsInput{
+		FlowLogIds: []*string{aws.String(flowLogID)},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(result.FlowLogs) != 1 {
+		return 0, fmt.Errorf("expected 1 flow log %s, found %d", flowLogID, len(result.FlowLogs))
+	}
+	return aws.Int64Value(result.FlowLogs[0].MaxAggregationInterval), nil
+}
@@ -3,6 +3,9 @@ package test
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -10,9 +13,12 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"basic-vpc-tests/testutil"
 )
 
 func TestPerformanceBaseline(t *testing.T) {
@@ -187,6 +193,145 @@ func TestLoadHandling(t *testing.T) {
 		assert.Less(t, maxDuration, 30*time.Second, "Max response time should be under 30 seconds")
 		assert.Greater(t, minDuration, time.Millisecond, "Min response time should be reasonable")
 	}
+
+	if testing.Short() {
+		t.Log("Skipping ramped load profile in short mode")
+		return
+	}
+
+	// Step up concurrency to find where the instance starts to struggle
+	profile, err := rampProfileFromEnv(os.Getenv)
+	require.NoError(t, err)
+	url := fmt.Sprintf("http://%s", publicIP)
+
+	var steps []loadStep
+	for concurrency := profile.Start; concurrency <= profile.Max; concurrency += profile.Step {
+		step := runLoadStep(url, concurrency, profile.Requests)
+		t.Logf("Concurrency %d: %d requests, error rate %.1f%%, p95 %v", step.Concurrency, step.Requests, step.errorRate()*100, step.P95)
+		steps = append(steps, step)
+		if profile.exceeded(step) {
+			break
+		}
+	}
+
+	knee, found := findKnee(steps, profile)
+	if !found {
+		t.Logf("No knee up to concurrency %d (error rate <= %.1f%%, p95 <= %v)", profile.Max, profile.MaxErrorRate*100, profile.P95Threshold)
+		return
+	}
+	t.Logf("Knee at concurrency %d: error rate %.1f%%, p95 %v; estimated capacity %d concurrent requests",
+		knee.Concurrency, knee.errorRate()*100, knee.P95, knee.Concurrency-profile.Step)
+	assert.Greater(t, knee.Concurrency, profile.Start, "Instance should handle the starting concurrency")
+}
+
+// rampProfile steps concurrency from Start to Max, sending Requests per step
+type rampProfile struct {
+	Start        int
+	Max          int
+	Step         int
+	Requests     int
+	MaxErrorRate float64
+	P95Threshold time.Duration
+}
+
+var defaultRampProfile = rampProfile{
+	Start:        5,
+	Max:          100,
+	Step:         5,
+	Requests:     100,
+	MaxErrorRate: 0.01,
+	P95Threshold: 2 * time.Second,
+}
+
+// loadStep summarizes the requests sent at one concurrency level
+type loadStep struct {
+	Concurrency int
+	Requests    int
+	Errors      int
+	P95         time.Duration
+}
+
+func (s loadStep) errorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// exceeded reports whether a step is past the knee
+func (p rampProfile) exceeded(step loadStep) bool {
+	return step.errorRate() > p.MaxErrorRate || step.P95 > p.P95Threshold
+}
+
+func TestRampProfileFromEnv(t *testing.T) {
+	t.Parallel()
+
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+
+	profile, err := rampProfileFromEnv(env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, defaultRampProfile, profile)
+
+	profile, err = rampProfileFromEnv(env(map[string]string{
+		"LOAD_RAMP_START":    "10",
+		"LOAD_RAMP_MAX":      "50",
+		"LOAD_RAMP_STEP":     "10",
+		"LOAD_RAMP_REQUESTS": "200",
+		"LOAD_RAMP_P95":      "750ms",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, rampProfile{Start: 10, Max: 50, Step: 10, Requests: 200, MaxErrorRate: 0.01, P95Threshold: 750 * time.Millisecond}, profile)
+
+	for _, values := range []map[string]string{
+		{"LOAD_RAMP_STEP": "0"},
+		{"LOAD_RAMP_START": "many"},
+		{"LOAD_RAMP_START": "20", "LOAD_RAMP_MAX": "10"},
+		{"LOAD_RAMP_P95": "fast"},
+	} {
+		_, err := rampProfileFromEnv(env(values))
+		assert.Error(t, err, "%v", values)
+	}
+}
+
+func TestFindKnee(t *testing.T) {
+	t.Parallel()
+
+	profile := defaultRampProfile
+	steps := []loadStep{
+		{Concurrency: 5, Requests: 100, P95: 200 * time.Millisecond},
+		{Concurrency: 10, Requests: 100, Errors: 1, P95: 400 * time.Millisecond},
+		{Concurrency: 15, Requests: 100, Errors: 2, P95: 900 * time.Millisecond},
+		{Concurrency: 20, Requests: 100, Errors: 9, P95: 3 * time.Second},
+	}
+
+	// 1% errors is still within budget; 2% is the first step over it
+	knee, found := findKnee(steps, profile)
+	require.True(t, found)
+	assert.Equal(t, 15, knee.Concurrency)
+
+	// Latency alone can mark the knee
+	steps[2].Errors = 0
+	knee, found = findKnee(steps, profile)
+	require.True(t, found)
+	assert.Equal(t, 20, knee.Concurrency)
+
+	_, found = findKnee(steps[:2], profile)
+	assert.False(t, found)
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	var durations []time.Duration
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 95*time.Millisecond, percentile(durations, 0.95))
+	assert.Equal(t, 100*time.Millisecond, percentile(durations, 1))
+	assert.Equal(t, 1*time.Millisecond, percentile(durations[99:], 0.95))
+	assert.Zero(t, percentile(nil, 0.95))
 }
 
 func TestScalabilityMetrics(t *testing.T) {
@@ -341,3 +486,186 @@ func TestResourceLimits(t *testing.T) {
 
 	t.Log("Resource limits test completed successfully")
 }
+
+// TestRootVolumePerformance provisions the root volumes above the gp3
+// baseline and checks the live volumes carry the requested IOPS and throughput
+func TestRootVolumePerformance(t *testing.T) {
+	t.Parallel()
+
+	iops := 4000
+	throughput := 250
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":            "volume-perf-test",
+			"allowed_http_cidrs":     []string{"10.0.0.0/8"},
+			"allowed_ssh_cidrs":      []string{"10.0.0.0/8"},
+			"root_volume_iops":       iops,
+			"root_volume_throughput": throughput,
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, strconv.Itoa(iops), terraform.Output(t, terraformOptions, "root_volume_iops"))
+	assert.Equal(t, strconv.Itoa(throughput), terraform.Output(t, terraformOptions, "root_volume_throughput"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+	for _, output := range []string{"public_instance_id", "private_instance_id"} {
+		instanceID := terraform.Output(t, terraformOptions, output)
+		instances, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+			InstanceIds: []*string{aws.String(instanceID)},
+		})
+		require.NoError(t, err)
+		require.Len(t, instances.Reservations, 1)
+
+		volumeID := testutil.RootVolumeID(instances.Reservations[0].Instances[0])
+		require.NotEmpty(t, volumeID, "Instance %s should have an EBS root volume", instanceID)
+		volumes, err := ec2Svc.DescribeVolumes(&ec2.DescribeVolumesInput{
+			VolumeIds: []*string{aws.String(volumeID)},
+		})
+		require.NoError(t, err)
+		require.Len(t, volumes.Volumes, 1)
+
+		for _, problem := range volumePerformanceProblems(volumes.Volumes[0], int64(iops), int64(throughput)) {
+			assert.Fail(t, "Root volume performance not as provisioned", "%s: %s", instanceID, problem)
+		}
+	}
+}
+
+func TestVolumePerformanceProblems(t *testing.T) {
+	t.Parallel()
+
+	volume := &ec2.Volume{VolumeId: aws.String("vol-root"), Iops: aws.Int64(3000), Throughput: aws.Int64(125)}
+	assert.Empty(t, volumePerformanceProblems(volume, 3000, 125))
+
+	assert.Equal(t, []string{
+		"volume vol-root has 3000 IOPS, want 4000",
+		"volume vol-root has 125 MiB/s throughput, want 250",
+	}, volumePerformanceProblems(volume, 4000, 250))
+
+	// gp2 volumes report no throughput at all
+	volume = &ec2.Volume{VolumeId: aws.String("vol-gp2"), Iops: aws.Int64(100)}
+	assert.Equal(t, []string{
+		"volume vol-gp2 has 100 IOPS, want 3000",
+		"volume vol-gp2 has 0 MiB/s throughput, want 125",
+	}, volumePerformanceProblems(volume, 3000, 125))
+}
+
+// Helper function to list how a volume's provisioned performance differs from what was requested
+func volumePerformanceProblems(volume *ec2.Volume, iops, throughput int64) []string {
+	var problems []string
+	if got := aws.Int64Value(volume.Iops); got != iops {
+		problems = append(problems, fmt.Sprintf("volume %s has %d IOPS, want %d", aws.StringValue(volume.VolumeId), got, iops))
+	}
+	if got := aws.Int64Value(volume.Throughput); got != throughput {
+		problems = append(problems, fmt.Sprintf("volume %s has %d MiB/s throughput, want %d", aws.StringValue(volume.VolumeId), got, throughput))
+	}
+	return problems
+}
+
+// Helper function to read the ramp profile from LOAD_RAMP_* variables, falling back to defaults
+func rampProfileFromEnv(getenv func(string) string) (rampProfile, error) {
+	profile := defaultRampProfile
+	for key, target := range map[string]*int{
+		"LOAD_RAMP_START":    &profile.Start,
+		"LOAD_RAMP_MAX":      &profile.Max,
+		"LOAD_RAMP_STEP":     &profile.Step,
+		"LOAD_RAMP_REQUESTS": &profile.Requests,
+	} {
+		value := getenv(key)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return rampProfile{}, fmt.Errorf("%s must be a positive integer, got %q", key, value)
+		}
+		*target = n
+	}
+
+	if value := getenv("LOAD_RAMP_P95"); value != "" {
+		threshold, err := time.ParseDuration(value)
+		if err != nil {
+			return rampProfile{}, fmt.Errorf("LOAD_RAMP_P95 must be a duration such as 2s, got %q", value)
+		}
+		profile.P95Threshold = threshold
+	}
+
+	if profile.Start > profile.Max {
+		return rampProfile{}, fmt.Errorf("LOAD_RAMP_START (%d) must not exceed LOAD_RAMP_MAX (%d)", profile.Start, profile.Max)
+	}
+	return profile, nil
+}
+
+// Helper function to send a batch of GET requests at a fixed concurrency
+func runLoadStep(url string, concurrency, requests int) loadStep {
+	client := &http.Client{Timeout: 30 * time.Second}
+	step := loadStep{Concurrency: concurrency, Requests: requests}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var durations []time.Duration
+	sem := make(chan struct{}, concurrency)
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			resp, err := client.Get(url)
+			duration := time.Since(start)
+			ok := err == nil && resp.StatusCode == http.StatusOK
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			durations = append(durations, duration)
+			if !ok {
+				step.Errors++
+			}
+		}()
+	}
+	wg.Wait()
+
+	step.P95 = percentile(durations, 0.95)
+	return step
+}
+
+// Helper function to find the first step whose error rate or p95 crosses the profile limits
+func findKnee(steps []loadStep, profile rampProfile) (loadStep, bool) {
+	for _, step := range steps {
+		if profile.exceeded(step) {
+			return step, true
+		}
+	}
+	return loadStep{}, false
+}
+
+// Helper function to compute a nearest-rank percentile
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(p*float64(len(sorted)) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
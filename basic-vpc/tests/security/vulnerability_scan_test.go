@@ -189,9 +189,14 @@ func scanForExposedSecurityGroups(t *testing.T, sess *session.Session) []string
 func scanForUnencryptedResources(t *testing.T, terraformOptions *terraform.Options) []string {
 	var unencrypted []string
 
+	// Roots are always EBS-backed; scratch disks are mapped alongside them
+	if terraformOptions.Vars["enable_scratch_disks"] == true {
+		assert.NotEmpty(t, terraform.OutputList(t, terraformOptions, "instance_ephemeral_devices"), "Scratch disks should map an ephemeral device")
+	}
+
 	// Check EBS volumes
-	publicEncrypted := terraform.Output(t, terraformOptions, "public_instance_encrypted")
-	privateEncrypted := terraform.Output(t, terraformOptions, "private_instance_encrypted")
+	publicEncrypted := terraform.Output(t, terraformOptions, "public_ebs_encrypted")
+	privateEncrypted := terraform.Output(t, terraformOptions, "private_ebs_encrypted")
 
 	if publicEncrypted != "true" {
 		unencrypted = append(unencrypted, "public-instance-ebs")
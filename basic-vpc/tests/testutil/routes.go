@@ -0,0 +1,69 @@
+// Package testutil holds helpers shared by the test suites and the commands
+// under cmd/, which can't import code from _test.go files.
+package testutil
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// DefaultRoute returns the route table's IPv4 default route, or nil if it has none
+func DefaultRoute(routeTable *ec2.RouteTable) *ec2.Route {
+	for _, route := range routeTable.Routes {
+		if aws.StringValue(route.DestinationCidrBlock) == "0.0.0.0/0" {
+			return route
+		}
+	}
+	return nil
+}
+
+// HasDefaultRouteToGateway reports whether a route table sends 0.0.0.0/0 to
+// the given gateway through an active route
+func HasDefaultRouteToGateway(routeTable *ec2.RouteTable, gatewayID string) bool {
+	route := DefaultRoute(routeTable)
+	return route != nil &&
+		RouteTarget(route) == gatewayID &&
+		aws.StringValue(route.State) == ec2.RouteStateActive
+}
+
+// RouteTarget returns the ID of whatever a route sends traffic to
+func RouteTarget(route *ec2.Route) string {
+	for _, target := range []*string{
+		route.GatewayId,
+		route.NatGatewayId,
+		route.TransitGatewayId,
+		route.VpcPeeringConnectionId,
+		route.EgressOnlyInternetGatewayId,
+		route.NetworkInterfaceId,
+		route.InstanceId,
+	} {
+		if id := aws.StringValue(target); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// IsInternetGateway reports whether a route target is an internet gateway
+func IsInternetGateway(targetID string) bool {
+	return strings.HasPrefix(targetID, "igw-")
+}
+
+// SubnetRouteTable returns the route table a subnet uses: its explicit
+// association, or the VPC's main route table when it has none
+func SubnetRouteTable(routeTables []*ec2.RouteTable, subnetID string) *ec2.RouteTable {
+	var main *ec2.RouteTable
+	for _, routeTable := range routeTables {
+		for _, association := range routeTable.Associations {
+			if aws.StringValue(association.SubnetId) == subnetID {
+				return routeTable
+			}
+			if aws.BoolValue(association.Main) {
+				main = routeTable
+			}
+		}
+	}
+	return main
+}
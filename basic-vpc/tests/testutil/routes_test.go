@@ -0,0 +1,56 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasDefaultRouteToGateway(t *testing.T) {
+	t.Parallel()
+
+	routeTable := &ec2.RouteTable{Routes: []*ec2.Route{
+		{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local"), State: aws.String(ec2.RouteStateActive)},
+		{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-1"), State: aws.String(ec2.RouteStateActive)},
+	}}
+	assert.True(t, HasDefaultRouteToGateway(routeTable, "igw-1"))
+	assert.False(t, HasDefaultRouteToGateway(routeTable, "igw-2"))
+
+	// A route left behind by a deleted gateway no longer carries traffic
+	routeTable.Routes[1].State = aws.String(ec2.RouteStateBlackhole)
+	assert.False(t, HasDefaultRouteToGateway(routeTable, "igw-1"))
+
+	assert.False(t, HasDefaultRouteToGateway(&ec2.RouteTable{}, "igw-1"))
+}
+
+func TestRouteTarget(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "nat-1", RouteTarget(&ec2.Route{NatGatewayId: aws.String("nat-1")}))
+	assert.Equal(t, "tgw-1", RouteTarget(&ec2.Route{TransitGatewayId: aws.String("tgw-1")}))
+	assert.Equal(t, "igw-1", RouteTarget(&ec2.Route{GatewayId: aws.String("igw-1")}))
+	assert.Empty(t, RouteTarget(&ec2.Route{}))
+
+	assert.True(t, IsInternetGateway("igw-1"))
+	assert.False(t, IsInternetGateway("vpce-1"))
+}
+
+func TestSubnetRouteTable(t *testing.T) {
+	t.Parallel()
+
+	main := &ec2.RouteTable{
+		RouteTableId: aws.String("rtb-main"),
+		Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}},
+	}
+	public := &ec2.RouteTable{
+		RouteTableId: aws.String("rtb-public"),
+		Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String("subnet-public")}},
+	}
+	routeTables := []*ec2.RouteTable{main, public}
+
+	assert.Equal(t, public, SubnetRouteTable(routeTables, "subnet-public"))
+	assert.Equal(t, main, SubnetRouteTable(routeTables, "subnet-unassociated"), "Unassociated subnets use the main route table")
+	assert.Nil(t, SubnetRouteTable([]*ec2.RouteTable{public}, "subnet-other"))
+}
@@ -0,0 +1,17 @@
+package testutil
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// RootVolumeID returns the EBS volume mapped at the instance's root device,
+// or "" when the root is instance store
+func RootVolumeID(instance *ec2.Instance) string {
+	for _, mapping := range instance.BlockDeviceMappings {
+		if aws.StringValue(mapping.DeviceName) == aws.StringValue(instance.RootDeviceName) && mapping.Ebs != nil {
+			return aws.StringValue(mapping.Ebs.VolumeId)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,27 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootVolumeID(t *testing.T) {
+	t.Parallel()
+
+	instance := &ec2.Instance{
+		RootDeviceType: aws.String("ebs"),
+		RootDeviceName: aws.String("/dev/xvda"),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String("/dev/sdf"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")}},
+			{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+		},
+	}
+	assert.Equal(t, "vol-root", RootVolumeID(instance))
+
+	// Instance-store roots have no volume
+	instance = &ec2.Instance{RootDeviceType: aws.String("instance-store"), RootDeviceName: aws.String("/dev/sda1")}
+	assert.Empty(t, RootVolumeID(instance))
+}
@@ -3,8 +3,14 @@ package test
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"testkit"
 )
 
 func TestCloudTrail(t *testing.T) {
@@ -19,6 +25,7 @@ func TestCloudTrail(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -30,15 +37,12 @@ func TestCloudTrail(t *testing.T) {
 	assert.NotEmpty(t, cloudtrailArn)
 	assert.Contains(t, cloudtrailArn, "basic-vpc-cloudtrail")
 
-	// Test CloudTrail configuration
-	isMultiRegion := terraform.Output(t, terraformOptions, "cloudtrail_is_multi_region")
-	assert.Equal(t, "true", isMultiRegion)
-
-	isLoggingEnabled := terraform.Output(t, terraformOptions, "cloudtrail_logging_enabled")
-	assert.Equal(t, "true", isLoggingEnabled)
-
-	includeGlobalEvents := terraform.Output(t, terraformOptions, "cloudtrail_include_global_events")
-	assert.Equal(t, "true", includeGlobalEvents)
+	// Test CloudTrail configuration against the live trail
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	trailName := terraform.Output(t, terraformOptions, "cloudtrail_name")
+	testkit.AssertTrailLogging(t, cloudtrail.New(sess), trailName)
 }
 
 func TestCloudTrailS3Bucket(t *testing.T) {
@@ -53,6 +57,7 @@ func TestCloudTrailS3Bucket(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -68,6 +73,12 @@ func TestCloudTrailS3Bucket(t *testing.T) {
 	// Test server-side encryption
 	bucketEncryptionAlgorithm := terraform.Output(t, terraformOptions, "cloudtrail_bucket_encryption")
 	assert.Equal(t, "AES256", bucketEncryptionAlgorithm)
+
+	// Trail logs and their noncurrent versions must expire
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertNoLifecycleGap(t, s3.New(sess), bucketId, "")
 }
 
 func TestCloudTrailS3Security(t *testing.T) {
@@ -82,6 +93,7 @@ func TestCloudTrailS3Security(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -97,6 +109,13 @@ func TestCloudTrailS3Security(t *testing.T) {
 
 	restrictPublicBuckets := terraform.Output(t, terraformOptions, "cloudtrail_bucket_restrict_public_buckets")
 	assert.Equal(t, "true", restrictPublicBuckets)
+
+	// CloudTrail delivers with the bucket policy alone, so ACLs are disabled
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	bucketId := terraform.Output(t, terraformOptions, "cloudtrail_bucket_id")
+	testkit.AssertBucketObjectOwnership(t, s3.New(sess), bucketId, false)
 }
 
 func TestCloudTrailBucketPolicy(t *testing.T) {
@@ -111,20 +130,32 @@ func TestCloudTrailBucketPolicy(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Test bucket policy allows CloudTrail access
-	bucketPolicyAllowsCloudTrail := terraform.Output(t, terraformOptions, "bucket_policy_allows_cloudtrail")
-	assert.Equal(t, "true", bucketPolicyAllowsCloudTrail)
+	bucketId := terraform.Output(t, terraformOptions, "cloudtrail_bucket_id")
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	s3Svc := s3.New(sess)
 
 	// Test bucket policy allows CloudTrail to get bucket ACL
-	bucketPolicyAllowsAclCheck := terraform.Output(t, terraformOptions, "bucket_policy_allows_acl_check")
-	assert.Equal(t, "true", bucketPolicyAllowsAclCheck)
-
-	// Test bucket policy allows CloudTrail to put objects
-	bucketPolicyAllowsPutObject := terraform.Output(t, terraformOptions, "bucket_policy_allows_put_object")
-	assert.Equal(t, "true", bucketPolicyAllowsPutObject)
+	testkit.AssertBucketPolicyStatement(t, s3Svc, bucketId, testkit.StatementMatcher{
+		Effect:    "Allow",
+		Principal: "cloudtrail.amazonaws.com",
+		Action:    "s3:GetBucketAcl",
+	})
+
+	// Test bucket policy allows CloudTrail to put objects it hands over to the bucket owner
+	testkit.AssertBucketPolicyStatement(t, s3Svc, bucketId, testkit.StatementMatcher{
+		Effect:    "Allow",
+		Principal: "cloudtrail.amazonaws.com",
+		Action:    "s3:PutObject",
+		Condition: map[string]map[string]string{
+			"StringEquals": {"s3:x-amz-acl": "bucket-owner-full-control"},
+		},
+	})
 }
 
 func TestCloudTrailEventSelectors(t *testing.T) {
@@ -139,6 +170,7 @@ func TestCloudTrailEventSelectors(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
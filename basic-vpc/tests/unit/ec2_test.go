@@ -1,10 +1,21 @@
 package test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"basic-vpc-tests/testutil"
+	"testkit"
 )
 
 func TestEc2Instances(t *testing.T) {
@@ -19,6 +30,7 @@ func TestEc2Instances(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -42,6 +54,10 @@ func TestEc2Instances(t *testing.T) {
 
 	privateInstanceType := terraform.Output(t, terraformOptions, "private_instance_type")
 	assert.Equal(t, "t3.micro", privateInstanceType)
+
+	// Rendered user_data hashes make script changes visible in plan output
+	assert.Regexp(t, "^[0-9a-f]{64}$", terraform.Output(t, terraformOptions, "public_instance_user_data_sha256"))
+	assert.Regexp(t, "^[0-9a-f]{64}$", terraform.Output(t, terraformOptions, "private_instance_user_data_sha256"))
 }
 
 func TestEc2Encryption(t *testing.T) {
@@ -56,9 +72,13 @@ func TestEc2Encryption(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
+	// The AMI boots from an EBS root, which the checks below rely on
+	assert.Equal(t, "ebs", terraform.Output(t, terraformOptions, "root_device_type"))
+
 	// Test EBS encryption
 	publicEbsEncrypted := terraform.Output(t, terraformOptions, "public_ebs_encrypted")
 	assert.Equal(t, "true", publicEbsEncrypted)
@@ -72,6 +92,159 @@ func TestEc2Encryption(t *testing.T) {
 
 	privateEbsVolumeType := terraform.Output(t, terraformOptions, "private_ebs_volume_type")
 	assert.Equal(t, "gp3", privateEbsVolumeType)
+
+	// Confirm the live root volumes rather than the configuration
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+	for _, output := range []string{"public_instance_id", "private_instance_id"} {
+		instanceId := terraform.Output(t, terraformOptions, output)
+		instances, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+			InstanceIds: []*string{aws.String(instanceId)},
+		})
+		require.NoError(t, err)
+		require.Len(t, instances.Reservations, 1)
+		instance := instances.Reservations[0].Instances[0]
+
+		rootVolumeId := testutil.RootVolumeID(instance)
+		require.NotEmpty(t, rootVolumeId, "Instance %s should have an EBS root volume", instanceId)
+		volumes, err := ec2Svc.DescribeVolumes(&ec2.DescribeVolumesInput{
+			VolumeIds: []*string{aws.String(rootVolumeId)},
+		})
+		require.NoError(t, err)
+		require.Len(t, volumes.Volumes, 1)
+
+		assert.Empty(t, rootDeviceProblems(instance, volumes.Volumes[0]), "Instance %s root device", instanceId)
+	}
+}
+
+func TestEc2InstanceTypeValidation(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":   "test",
+			"instance_type": "t3.small",
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	for _, address := range []string{"aws_instance.public", "aws_instance.private"} {
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+		assert.Equal(t, "t3.small", plan.ResourcePlannedValuesMap[address].AttributeValues["instance_type"], "%s instance type", address)
+	}
+
+	// Types outside the allowlist never reach the instances
+	for _, instanceType := range []string{"t2.micro", "p4d.24xlarge"} {
+		terraformOptions.Vars["instance_type"] = instanceType
+		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, "%s should not be accepted", instanceType)
+		assert.Contains(t, err.Error(), "instance_type must be one of")
+	}
+}
+
+func TestEc2ScratchDiskValidation(t *testing.T) {
+	t.Parallel()
+
+	// t3 instances have no instance storage, so the plan must be rejected
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":          "test",
+			"enable_scratch_disks": true,
+			"instance_type":        "t3.micro",
+		},
+	}
+
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "t3.micro has none")
+}
+
+func TestEc2ScratchDisksKeepEncryptedRoot(t *testing.T) {
+	t.Parallel()
+
+	// Scratch disks are mapped on top of the encrypted gp3 root, not instead of it
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":          "test",
+			"enable_scratch_disks": true,
+			"instance_type":        "m5d.large",
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	for _, address := range []string{"aws_instance.public", "aws_instance.private"} {
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+		values := plan.ResourcePlannedValuesMap[address].AttributeValues
+
+		rootDevices, ok := values["root_block_device"].([]interface{})
+		require.True(t, ok, "%s should plan a root block device", address)
+		require.Len(t, rootDevices, 1)
+		root := rootDevices[0].(map[string]interface{})
+		assert.Equal(t, true, root["encrypted"], "%s root encrypted", address)
+		assert.Equal(t, "gp3", root["volume_type"], "%s root volume type", address)
+
+		ephemeral, ok := values["ephemeral_block_device"].([]interface{})
+		require.True(t, ok, "%s should plan an ephemeral block device", address)
+		assert.Len(t, ephemeral, 1, "%s ephemeral devices", address)
+	}
+}
+
+func TestRootVolumePerformanceValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		iops       int
+		throughput int
+		message    string
+	}{
+		"iops below baseline":       {iops: 2000, throughput: 125, message: "root_volume_iops must be a whole number between 3000 and 16000"},
+		"throughput above gp3":      {iops: 3000, throughput: 2000, message: "root_volume_throughput must be a whole number between 125 and 1000"},
+		"iops beyond the root size": {iops: 12000, throughput: 125, message: "root_volume_iops can be at most 10000"},
+		"throughput beyond iops":    {iops: 3000, throughput: 1000, message: "root_volume_throughput can be at most root_volume_iops / 4"},
+	}
+
+	for name, tc := range testCases {
+		terraformOptions := &terraform.Options{
+			TerraformDir: "../../",
+			Vars: map[string]interface{}{
+				"environment":            "test",
+				"root_volume_iops":       tc.iops,
+				"root_volume_throughput": tc.throughput,
+			},
+		}
+
+		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), tc.message, name)
+	}
+}
+
+func TestRootDeviceProblems(t *testing.T) {
+	t.Parallel()
+
+	instance := &ec2.Instance{
+		RootDeviceType: aws.String("ebs"),
+		RootDeviceName: aws.String("/dev/xvda"),
+		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String("/dev/sdf"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")}},
+			{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")}},
+		},
+	}
+
+	volume := &ec2.Volume{VolumeId: aws.String("vol-root"), VolumeType: aws.String("gp3"), Encrypted: aws.Bool(true)}
+	assert.Empty(t, rootDeviceProblems(instance, volume))
+
+	volume = &ec2.Volume{VolumeId: aws.String("vol-root"), VolumeType: aws.String("gp2"), Encrypted: aws.Bool(false)}
+	assert.Equal(t, []string{"root volume vol-root is gp2, not gp3", "root volume vol-root is not encrypted"}, rootDeviceProblems(instance, volume))
+
+	// Instance-store roots have no volume to check
+	instance = &ec2.Instance{RootDeviceType: aws.String("instance-store"), RootDeviceName: aws.String("/dev/sda1")}
+	assert.Equal(t, []string{"root device is instance-store, not ebs"}, rootDeviceProblems(instance, nil))
 }
 
 func TestEc2Monitoring(t *testing.T) {
@@ -86,6 +259,7 @@ func TestEc2Monitoring(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -109,6 +283,7 @@ func TestEc2IamProfiles(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -119,3 +294,103 @@ func TestEc2IamProfiles(t *testing.T) {
 	privateIamProfile := terraform.Output(t, terraformOptions, "private_iam_instance_profile")
 	assert.Contains(t, privateIamProfile, "ssm-profile")
 }
+
+func TestEc2TerminationProtection(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":                "test",
+			"allowed_http_cidrs":         []string{"10.0.0.0/8"},
+			"allowed_ssh_cidrs":          []string{"10.0.0.0/8"},
+			"enable_deletion_protection": true,
+		},
+	}
+
+	testkit.AcquireApplySlot(t)
+	// Deferred calls run in reverse, so protection is lifted before destroy
+	defer terraform.Destroy(t, terraformOptions)
+	defer testkit.DisableDeletionProtection(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "true", terraform.Output(t, terraformOptions, "instance_termination_protection"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+
+	for _, output := range []string{"public_instance_id", "private_instance_id"} {
+		instanceId := terraform.Output(t, terraformOptions, output)
+		attribute, err := ec2Svc.DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+			InstanceId: aws.String(instanceId),
+			Attribute:  aws.String(ec2.InstanceAttributeNameDisableApiTermination),
+		})
+		require.NoError(t, err)
+		require.NotNil(t, attribute.DisableApiTermination)
+		assert.True(t, aws.BoolValue(attribute.DisableApiTermination.Value), "Instance %s should have termination protection", instanceId)
+	}
+}
+
+func TestUserDataTemplate(t *testing.T) {
+	t.Parallel()
+
+	// The fixture only renders the template, so no AWS resources are created
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../fixtures/user_data",
+		Vars: map[string]interface{}{
+			"packages":             []string{"nginx", "amazon-cloudwatch-agent"},
+			"web_root_content":     "<h1>basic-vpc</h1>",
+			"health_check_content": "healthy",
+			"probe_url":            "http://10.0.2.10:80",
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	rendered := terraform.Output(t, terraformOptions, "rendered")
+	assert.True(t, strings.HasPrefix(rendered, "#!/bin/bash\n"), "user_data should start with a shebang")
+	assert.Contains(t, rendered, "yum install -y nginx amazon-cloudwatch-agent")
+	assert.Contains(t, rendered, "<h1>basic-vpc</h1>\nINDEX_EOF")
+	assert.Contains(t, rendered, "healthy\nHEALTH_EOF")
+	assert.Contains(t, rendered, "curl http://10.0.2.10:80 > /tmp/private_ip_response.log")
+	assert.NotContains(t, rendered, "meta-data/local-ipv4", "Explicit web root content should replace the private IP page")
+	assert.NotContains(t, rendered, "%{", "Template directives should not leak into the script")
+
+	sum := sha256.Sum256([]byte(rendered))
+	assert.Equal(t, hex.EncodeToString(sum[:]), terraform.Output(t, terraformOptions, "sha256"))
+
+	// Defaults fall back to the private IP page and skip the probe
+	terraformOptions.Vars = map[string]interface{}{
+		"packages": []string{"httpd"},
+	}
+	terraform.Apply(t, terraformOptions)
+
+	rendered = terraform.Output(t, terraformOptions, "rendered")
+	assert.Contains(t, rendered, "yum install -y httpd\n")
+	assert.Contains(t, rendered, "echo $PRIVATE_IP > /var/www/html/index.html")
+	// Instances require IMDSv2, so the lookup must fetch a session token first
+	assert.Contains(t, rendered, "curl -s -X PUT http://169.254.169.254/latest/api/token")
+	assert.Contains(t, rendered, `-H "X-aws-ec2-metadata-token: $IMDS_TOKEN"`)
+	assert.Contains(t, rendered, "OK\nHEALTH_EOF")
+	assert.NotContains(t, rendered, "private_ip_response.log")
+}
+
+// Helper function to list how an instance's root device differs from an
+// encrypted gp3 EBS volume
+func rootDeviceProblems(instance *ec2.Instance, volume *ec2.Volume) []string {
+	if rootType := aws.StringValue(instance.RootDeviceType); rootType != ec2.DeviceTypeEbs {
+		return []string{fmt.Sprintf("root device is %s, not ebs", rootType)}
+	}
+
+	var problems []string
+	if volumeType := aws.StringValue(volume.VolumeType); volumeType != ec2.VolumeTypeGp3 {
+		problems = append(problems, fmt.Sprintf("root volume %s is %s, not gp3", aws.StringValue(volume.VolumeId), volumeType))
+	}
+	if !aws.BoolValue(volume.Encrypted) {
+		problems = append(problems, fmt.Sprintf("root volume %s is not encrypted", aws.StringValue(volume.VolumeId)))
+	}
+	return problems
+}
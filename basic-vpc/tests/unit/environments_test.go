@@ -0,0 +1,56 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"testkit"
+)
+
+// vpcEnvironment is what each environment's tfvars changes on the instances
+type vpcEnvironment struct {
+	InstanceType       string
+	DeletionProtection bool
+	AccessMode         string
+}
+
+func TestEnvironmentPlans(t *testing.T) {
+	t.Parallel()
+
+	baseVars := map[string]interface{}{
+		"allowed_http_cidrs": []string{"10.0.0.0/8"},
+		"allowed_ssh_cidrs":  []string{"10.0.0.0/8"},
+	}
+	want := map[string]vpcEnvironment{
+		"dev":     {InstanceType: "t3.micro", DeletionProtection: false, AccessMode: "ssh+ssm"},
+		"staging": {InstanceType: "t3.small", DeletionProtection: false, AccessMode: "ssh+ssm"},
+		"prod":    {InstanceType: "t3.medium", DeletionProtection: true, AccessMode: "ssm-only"},
+	}
+
+	testkit.RunEnvironmentPlans(t, "../../", baseVars, want, func(t *testing.T, env string, plan *terraform.PlanStruct) vpcEnvironment {
+		for _, address := range []string{"aws_instance.public", "aws_instance.private"} {
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+			assert.Equal(t, env, plan.ResourcePlannedValuesMap[address].AttributeValues["tags"].(map[string]interface{})["Environment"], "%s Environment tag", address)
+		}
+
+		// Both instances share the type and protection, so the public one stands in
+		public := plan.ResourcePlannedValuesMap["aws_instance.public"].AttributeValues
+		private := plan.ResourcePlannedValuesMap["aws_instance.private"].AttributeValues
+		assert.Equal(t, public["instance_type"], private["instance_type"], "Both instances should share a type")
+		assert.Equal(t, public["disable_api_termination"], private["disable_api_termination"], "Both instances should share termination protection")
+
+		var got vpcEnvironment
+		got.InstanceType, _ = public["instance_type"].(string)
+		got.DeletionProtection, _ = public["disable_api_termination"].(bool)
+		got.AccessMode, _ = plan.RawPlan.PlannedValues.Outputs["access_mode"].Value.(string)
+		return got
+	})
+}
+
+func TestEnvironmentFiles(t *testing.T) {
+	t.Parallel()
+
+	testkit.AssertEnvironmentFiles(t, "../../")
+}
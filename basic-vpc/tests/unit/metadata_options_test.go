@@ -0,0 +1,55 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"testkit"
+)
+
+// metadataOptionsOutput is one instance's entry in the instance_metadata_options output
+type metadataOptionsOutput struct {
+	HttpTokens              string `json:"http_tokens"`
+	HttpPutResponseHopLimit int64  `json:"http_put_response_hop_limit"`
+	InstanceMetadataTags    string `json:"instance_metadata_tags"`
+}
+
+// TestEc2MetadataOptions validates both instances require IMDSv2 with a hop
+// limit of 1 and expose tags in metadata only when opted in
+func TestEc2MetadataOptions(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":                   "test",
+			"allowed_http_cidrs":            []string{"10.0.0.0/8"},
+			"allowed_ssh_cidrs":             []string{"10.0.0.0/8"},
+			"enable_instance_metadata_tags": true,
+		},
+	}
+
+	testkit.AcquireApplySlot(t)
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	var outputs map[string]metadataOptionsOutput
+	require.NoError(t, json.Unmarshal([]byte(terraform.OutputJson(t, terraformOptions, "instance_metadata_options")), &outputs))
+	want := metadataOptionsOutput{HttpTokens: "required", HttpPutResponseHopLimit: 1, InstanceMetadataTags: "enabled"}
+	assert.Equal(t, map[string]metadataOptionsOutput{"public": want, "private": want}, outputs)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+	for _, output := range []string{"public_instance_id", "private_instance_id"} {
+		testkit.AssertMetadataOptions(t, ec2Svc, terraform.Output(t, terraformOptions, output), true)
+	}
+}
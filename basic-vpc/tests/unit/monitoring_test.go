@@ -1,10 +1,21 @@
 package test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestCloudWatchAlarms(t *testing.T) {
@@ -19,6 +30,7 @@ func TestCloudWatchAlarms(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -36,6 +48,29 @@ func TestCloudWatchAlarms(t *testing.T) {
 	// Test Status Check alarms
 	publicStatusAlarmName := terraform.Output(t, terraformOptions, "public_status_alarm_name")
 	assert.Contains(t, publicStatusAlarmName, "status-check-public-test")
+
+	// Force the status alarm through ALARM and back so notification wiring is
+	// exercised without waiting for a real status check failure
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	cwSvc := cloudwatch.New(sess)
+
+	_, err := cwSvc.SetAlarmState(&cloudwatch.SetAlarmStateInput{
+		AlarmName:   aws.String(publicStatusAlarmName),
+		StateValue:  aws.String(cloudwatch.StateValueAlarm),
+		StateReason: aws.String("Terratest forcing alarm state"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, waitForAlarmState(cwSvc, publicStatusAlarmName, cloudwatch.StateValueAlarm, 2*time.Minute))
+
+	_, err = cwSvc.SetAlarmState(&cloudwatch.SetAlarmStateInput{
+		AlarmName:   aws.String(publicStatusAlarmName),
+		StateValue:  aws.String(cloudwatch.StateValueOk),
+		StateReason: aws.String("Terratest resetting alarm state"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, waitForAlarmState(cwSvc, publicStatusAlarmName, cloudwatch.StateValueOk, 2*time.Minute))
 }
 
 func TestCloudWatchAlarmConfiguration(t *testing.T) {
@@ -50,6 +85,7 @@ func TestCloudWatchAlarmConfiguration(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -80,6 +116,7 @@ func TestCloudWatchDashboard(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -91,12 +128,11 @@ func TestCloudWatchDashboard(t *testing.T) {
 	dashboardWidgets := terraform.OutputList(t, terraformOptions, "dashboard_widgets")
 	assert.Greater(t, len(dashboardWidgets), 0)
 
-	// Test dashboard has CPU and Network widgets
-	dashboardHasCpuWidget := terraform.Output(t, terraformOptions, "dashboard_has_cpu_widget")
-	assert.Equal(t, "true", dashboardHasCpuWidget)
-
-	dashboardHasNetworkWidget := terraform.Output(t, terraformOptions, "dashboard_has_network_widget")
-	assert.Equal(t, "true", dashboardHasNetworkWidget)
+	// Test the deployed dashboard definition has CPU, Network and Status Check widgets
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	assertDashboardHasMetrics(t, cloudwatch.New(sess), dashboardName, "CPUUtilization", "NetworkIn", "StatusCheckFailed")
 }
 
 func TestSnsTopic(t *testing.T) {
@@ -111,6 +147,7 @@ func TestSnsTopic(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -127,3 +164,236 @@ func TestSnsTopic(t *testing.T) {
 	snsAllowsCloudWatch := terraform.Output(t, terraformOptions, "sns_allows_cloudwatch")
 	assert.Equal(t, "true", snsAllowsCloudWatch)
 }
+
+const sampleDashboardBody = `{
+  "widgets": [
+    {
+      "type": "metric",
+      "properties": {
+        "metrics": [
+          ["AWS/EC2", "CPUUtilization", "InstanceId", "i-0123456789abcdef0"],
+          [".", ".", ".", "i-0fedcba9876543210"]
+        ],
+        "title": "EC2 CPU Utilization"
+      }
+    },
+    {
+      "type": "metric",
+      "properties": {
+        "metrics": [
+          ["AWS/EC2", "NetworkIn", "InstanceId", "i-0123456789abcdef0", {"stat": "Sum"}],
+          [{"expression": "m1 * 8", "label": "bits"}]
+        ],
+        "title": "EC2 Network In"
+      }
+    },
+    {
+      "type": "text",
+      "properties": {
+        "markdown": "# Security dashboard"
+      }
+    }
+  ]
+}`
+
+func TestDashboardMetricNames(t *testing.T) {
+	t.Parallel()
+
+	metrics, err := dashboardMetricNames(sampleDashboardBody)
+	require.NoError(t, err)
+
+	assert.True(t, metrics["CPUUtilization"], "CPUUtilization widget should be found")
+	assert.True(t, metrics["NetworkIn"], "NetworkIn widget should be found, even with a rendering options object")
+	assert.False(t, metrics["StatusCheckFailed"], "StatusCheckFailed widget is not in the sample")
+	assert.False(t, metrics["."], "Shorthand rows should resolve to the previous metric name")
+	assert.Len(t, metrics, 2)
+
+	_, err = dashboardMetricNames("not json")
+	assert.Error(t, err)
+}
+
+func TestAssertDashboardHasMetrics(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeDashboardClient{body: sampleDashboardBody}
+
+	missing, err := missingDashboardMetrics(svc, "security-dashboard-test", "CPUUtilization", "NetworkIn", "StatusCheckFailed")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"StatusCheckFailed"}, missing)
+	assert.Equal(t, "security-dashboard-test", svc.requested)
+
+	missing, err = missingDashboardMetrics(svc, "security-dashboard-test", "CPUUtilization", "NetworkIn")
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+type fakeDashboardClient struct {
+	cloudwatchiface.CloudWatchAPI
+	body      string
+	requested string
+}
+
+func (f *fakeDashboardClient) GetDashboard(input *cloudwatch.GetDashboardInput) (*cloudwatch.GetDashboardOutput, error) {
+	f.requested = aws.StringValue(input.DashboardName)
+	return &cloudwatch.GetDashboardOutput{
+		DashboardName: input.DashboardName,
+		DashboardBody: aws.String(f.body),
+	}, nil
+}
+
+func TestWaitForAlarmState(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeAlarmClient{
+		states: []string{
+			cloudwatch.StateValueInsufficientData,
+			cloudwatch.StateValueOk,
+			cloudwatch.StateValueAlarm,
+		},
+	}
+
+	start := time.Now()
+	require.NoError(t, waitForAlarmState(svc, "status-check-public-test", cloudwatch.StateValueAlarm, 2*time.Second))
+	assert.Less(t, time.Since(start), time.Second, "Helper should return as soon as the alarm reaches the desired state")
+	assert.Equal(t, 3, svc.calls)
+	assert.Equal(t, []string{"status-check-public-test"}, aws.StringValueSlice(svc.lastInput.AlarmNames))
+
+	// An alarm that never reaches the desired state times out with the last state seen
+	stuck := &fakeAlarmClient{states: []string{cloudwatch.StateValueOk}}
+	err := waitForAlarmState(stuck, "status-check-public-test", cloudwatch.StateValueAlarm, 200*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "last state OK")
+
+	missing := &fakeAlarmClient{}
+	err = waitForAlarmState(missing, "does-not-exist", cloudwatch.StateValueOk, 200*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+// fakeAlarmClient reports the next state on each DescribeAlarms call, holding
+// the final state once the sequence is exhausted
+type fakeAlarmClient struct {
+	cloudwatchiface.CloudWatchAPI
+	states    []string
+	calls     int
+	lastInput *cloudwatch.DescribeAlarmsInput
+}
+
+func (f *fakeAlarmClient) DescribeAlarms(input *cloudwatch.DescribeAlarmsInput) (*cloudwatch.DescribeAlarmsOutput, error) {
+	f.lastInput = input
+	f.calls++
+	if len(f.states) == 0 {
+		return &cloudwatch.DescribeAlarmsOutput{}, nil
+	}
+
+	state := f.states[len(f.states)-1]
+	if f.calls <= len(f.states) {
+		state = f.states[f.calls-1]
+	}
+	return &cloudwatch.DescribeAlarmsOutput{
+		MetricAlarms: []*cloudwatch.MetricAlarm{
+			{AlarmName: input.AlarmNames[0], StateValue: aws.String(state)},
+		},
+	}, nil
+}
+
+// Helper function to poll DescribeAlarms until an alarm reaches the wanted state.
+// Returns as soon as the state matches, so alarm tests don't rely on fixed sleeps.
+func waitForAlarmState(cwSvc cloudwatchiface.CloudWatchAPI, alarmName, want string, timeout time.Duration) error {
+	lastState := "unknown"
+	err := testkit.Poll(timeout, func() (bool, error) {
+		result, err := cwSvc.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{
+			AlarmNames: []*string{aws.String(alarmName)},
+		})
+		if err != nil {
+			return false, err
+		}
+
+		if len(result.MetricAlarms) == 0 && len(result.CompositeAlarms) == 0 {
+			lastState = "not found"
+		}
+		for _, alarm := range result.MetricAlarms {
+			lastState = aws.StringValue(alarm.StateValue)
+		}
+		for _, alarm := range result.CompositeAlarms {
+			lastState = aws.StringValue(alarm.StateValue)
+		}
+		return lastState == want, nil
+	})
+	if errors.Is(err, testkit.ErrPollTimeout) {
+		return fmt.Errorf("alarm %s did not reach %s within %s (last state %s)", alarmName, want, timeout, lastState)
+	}
+	return err
+}
+
+// Helper function to assert a deployed dashboard graphs the given metrics
+func assertDashboardHasMetrics(t *testing.T, svc cloudwatchiface.CloudWatchAPI, dashboardName string, metricNames ...string) {
+	missing, err := missingDashboardMetrics(svc, dashboardName, metricNames...)
+	require.NoError(t, err)
+	assert.Empty(t, missing, "Dashboard %s should contain widgets for these metrics", dashboardName)
+}
+
+// Helper function to list expected metrics that no dashboard widget graphs
+func missingDashboardMetrics(svc cloudwatchiface.CloudWatchAPI, dashboardName string, metricNames ...string) ([]string, error) {
+	dashboard, err := svc.GetDashboard(&cloudwatch.GetDashboardInput{
+		DashboardName: aws.String(dashboardName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := dashboardMetricNames(aws.StringValue(dashboard.DashboardBody))
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, name := range metricNames {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// Helper function to collect metric names from a dashboard body. Metric rows
+// are [namespace, metricName, dimensions..., options?], where "." repeats the
+// value from the previous row and expression rows hold a single object.
+func dashboardMetricNames(body string) (map[string]bool, error) {
+	var dashboard struct {
+		Widgets []struct {
+			Type       string `json:"type"`
+			Properties struct {
+				Metrics [][]interface{} `json:"metrics"`
+			} `json:"properties"`
+		} `json:"widgets"`
+	}
+	if err := json.Unmarshal([]byte(body), &dashboard); err != nil {
+		return nil, fmt.Errorf("parsing dashboard body: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, widget := range dashboard.Widgets {
+		if widget.Type != "metric" {
+			continue
+		}
+		previous := ""
+		for _, row := range widget.Properties.Metrics {
+			if len(row) < 2 {
+				continue
+			}
+			name, ok := row[1].(string)
+			if !ok {
+				continue
+			}
+			if name == "." {
+				name = previous
+			}
+			if name != "" {
+				names[name] = true
+				previous = name
+			}
+		}
+	}
+	return names, nil
+}
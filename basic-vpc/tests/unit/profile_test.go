@@ -0,0 +1,107 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"testkit"
+)
+
+// vpcProfile is what each bundled toggle shows in a basic-vpc plan
+type vpcProfile struct {
+	EndpointPoliciesRestricted bool
+	S3GatewayEndpoint          bool
+	PrivateSSHIngress          bool
+	DeletionProtection         bool
+	CustomerManagedKey         bool
+	FlowLogRetentionDays       float64
+}
+
+func TestProfilePlans(t *testing.T) {
+	t.Parallel()
+
+	baseVars := map[string]interface{}{
+		"allowed_http_cidrs": []string{"10.0.0.0/8"},
+		"allowed_ssh_cidrs":  []string{"10.0.0.0/8"},
+	}
+	cases := []testkit.ProfileCase[vpcProfile]{
+		{
+			Name: "minimal",
+			Vars: map[string]interface{}{"profile": "minimal"},
+			Want: vpcProfile{PrivateSSHIngress: true, FlowLogRetentionDays: 30},
+		},
+		{
+			Name: "hardened",
+			Vars: map[string]interface{}{"profile": "hardened"},
+			Want: vpcProfile{EndpointPoliciesRestricted: true, S3GatewayEndpoint: true, DeletionProtection: true, CustomerManagedKey: true, FlowLogRetentionDays: 365},
+		},
+		{
+			Name: "hardened with overrides",
+			Vars: map[string]interface{}{
+				"profile":                    "hardened",
+				"egress_profile":             "open",
+				"enable_deletion_protection": false,
+				"create_kms_key":             false,
+				"flow_log_retention_days":    90,
+			},
+			Want: vpcProfile{EndpointPoliciesRestricted: true, FlowLogRetentionDays: 90},
+		},
+	}
+
+	testkit.RunProfilePlans(t, "../../", baseVars, cases, func(t *testing.T, plan *terraform.PlanStruct) vpcProfile {
+		var got vpcProfile
+		got.EndpointPoliciesRestricted, _ = plan.RawPlan.PlannedValues.Outputs["vpc_endpoint_policies_restricted"].Value.(bool)
+		_, got.S3GatewayEndpoint = plan.ResourcePlannedValuesMap["aws_vpc_endpoint.s3[0]"]
+		_, got.CustomerManagedKey = plan.ResourcePlannedValuesMap["aws_kms_key.ebs[0]"]
+
+		// The security groups never open port 22; access_mode gates it in the NACLs
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_network_acl.private")
+		got.PrivateSSHIngress = hasIngressPort(plan.ResourcePlannedValuesMap["aws_network_acl.private"].AttributeValues["ingress"], 22)
+
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_cloudwatch_log_group.vpc_flow_log")
+		got.FlowLogRetentionDays, _ = plan.ResourcePlannedValuesMap["aws_cloudwatch_log_group.vpc_flow_log"].AttributeValues["retention_in_days"].(float64)
+
+		for _, address := range []string{"aws_instance.public", "aws_instance.private"} {
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+			// IMDSv2 isn't part of either preset; both require it
+			metadata := plan.ResourcePlannedValuesMap[address].AttributeValues["metadata_options"].([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "required", metadata["http_tokens"], "%s IMDSv2", address)
+		}
+		got.DeletionProtection, _ = plan.ResourcePlannedValuesMap["aws_instance.public"].AttributeValues["disable_api_termination"].(bool)
+		assert.Equal(t, got.DeletionProtection, plan.ResourcePlannedValuesMap["aws_instance.private"].AttributeValues["disable_api_termination"], "Both instances should share termination protection")
+		return got
+	})
+}
+
+func TestHasIngressPort(t *testing.T) {
+	t.Parallel()
+
+	rules := []interface{}{
+		map[string]interface{}{"from_port": float64(80), "to_port": float64(80)},
+		map[string]interface{}{"from_port": float64(1024), "to_port": float64(65535)},
+	}
+	assert.True(t, hasIngressPort(rules, 80))
+	assert.True(t, hasIngressPort(rules, 8080), "Ranges include the ports inside them")
+	assert.False(t, hasIngressPort(rules, 22))
+	assert.False(t, hasIngressPort(nil, 22))
+}
+
+// Helper function to check whether planned security group or NACL ingress
+// rules admit a port
+func hasIngressPort(rules interface{}, port float64) bool {
+	list, _ := rules.([]interface{})
+	for _, rule := range list {
+		values, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		from, _ := values["from_port"].(float64)
+		to, _ := values["to_port"].(float64)
+		if from <= port && port <= to {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,20 @@
 package test
 
 import (
+	"fmt"
+	"net"
+	"sort"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestSecurityGroups(t *testing.T) {
@@ -19,6 +29,7 @@ func TestSecurityGroups(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -36,6 +47,14 @@ func TestSecurityGroups(t *testing.T) {
 
 	privateSgIngressRules := terraform.OutputList(t, terraformOptions, "private_sg_ingress_rules")
 	assert.Greater(t, len(privateSgIngressRules), 0)
+
+	// Every rule should document why it exists
+	endpointSgId := terraform.Output(t, terraformOptions, "vpc_endpoint_security_group_id")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertSecurityGroupRuleDescriptions(t, ec2.New(sess), publicSgId, privateSgId, endpointSgId)
 }
 
 func TestSecurityGroupRules(t *testing.T) {
@@ -50,6 +69,7 @@ func TestSecurityGroupRules(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -78,6 +98,7 @@ func TestNetworkACLs(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -109,6 +130,7 @@ func TestNaclRules(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -127,4 +149,213 @@ func TestNaclRules(t *testing.T) {
 	// Test private NACL allows traffic from public subnet
 	privateNaclAllowsPublicSubnet := terraform.Output(t, terraformOptions, "private_nacl_allows_public_subnet")
 	assert.Equal(t, "true", privateNaclAllowsPublicSubnet)
+
+	// Rules are evaluated lowest number first, so no deny may sit behind a broader allow
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+	assertNaclOrdering(t, ec2Svc, terraform.Output(t, terraformOptions, "public_nacl_id"))
+	assertNaclOrdering(t, ec2Svc, terraform.Output(t, terraformOptions, "private_nacl_id"))
+}
+
+func TestNaclShadowProblems(t *testing.T) {
+	t.Parallel()
+
+	entries := []*ec2.NetworkAclEntry{
+		naclEntry(100, false, "allow", "6", "0.0.0.0/0", 0, 65535),
+		// Fully covered by rule 100
+		naclEntry(110, false, "deny", "6", "198.51.100.0/24", 22, 22),
+		// UDP isn't covered by a TCP allow
+		naclEntry(120, false, "deny", "17", "198.51.100.0/24", 53, 53),
+		// The catch-all deny rule only applies to what no numbered rule matched
+		naclEntry(32767, false, "deny", "-1", "0.0.0.0/0", 0, 0),
+		// Egress is evaluated on its own
+		naclEntry(90, true, "allow", "-1", "10.0.0.0/8", 0, 0),
+		naclEntry(100, true, "deny", "6", "10.1.0.0/16", 443, 443),
+		naclEntry(110, true, "deny", "6", "0.0.0.0/0", 443, 443),
+	}
+	ipv6Deny := naclEntry(130, false, "deny", "6", "", 22, 22)
+	ipv6Deny.Ipv6CidrBlock = aws.String("2001:db8::/32")
+	entries = append(entries, ipv6Deny)
+
+	problems, err := naclShadowProblems(entries)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"ingress rule 100 (allow 6 0.0.0.0/0 ports 0-65535) shadows deny rule 110 (6 198.51.100.0/24 ports 22-22)",
+		"egress rule 90 (allow all 10.0.0.0/8) shadows deny rule 100 (6 10.1.0.0/16 ports 443-443)",
+	}, problems)
+
+	// A deny listed ahead of the allow it narrows is the intended ordering
+	problems, err = naclShadowProblems([]*ec2.NetworkAclEntry{
+		naclEntry(200, false, "allow", "6", "0.0.0.0/0", 22, 22),
+		naclEntry(100, false, "deny", "6", "198.51.100.0/24", 22, 22),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	_, err = naclShadowProblems([]*ec2.NetworkAclEntry{
+		naclEntry(100, false, "allow", "6", "0.0.0.0/0", 22, 22),
+		naclEntry(110, false, "deny", "6", "not-a-cidr", 22, 22),
+	})
+	assert.Error(t, err)
+}
+
+func TestNaclEntries(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeNetworkAclClient{acls: []*ec2.NetworkAcl{{
+		NetworkAclId: aws.String("acl-public"),
+		Entries:      []*ec2.NetworkAclEntry{naclEntry(100, false, "allow", "6", "0.0.0.0/0", 80, 80)},
+	}}}
+	entries, err := naclEntries(svc, "acl-public")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, []string{"acl-public"}, aws.StringValueSlice(svc.lastInput.NetworkAclIds))
+
+	_, err = naclEntries(&fakeNetworkAclClient{}, "acl-missing")
+	assert.EqualError(t, err, "network ACL acl-missing not found")
+}
+
+// Helper function to build a network ACL entry for tests
+func naclEntry(ruleNumber int64, egress bool, action, protocol, cidr string, from, to int64) *ec2.NetworkAclEntry {
+	entry := &ec2.NetworkAclEntry{
+		RuleNumber: aws.Int64(ruleNumber),
+		Egress:     aws.Bool(egress),
+		RuleAction: aws.String(action),
+		Protocol:   aws.String(protocol),
+	}
+	if cidr != "" {
+		entry.CidrBlock = aws.String(cidr)
+	}
+	if protocol != "-1" {
+		entry.PortRange = &ec2.PortRange{From: aws.Int64(from), To: aws.Int64(to)}
+	}
+	return entry
+}
+
+type fakeNetworkAclClient struct {
+	ec2iface.EC2API
+	acls      []*ec2.NetworkAcl
+	lastInput *ec2.DescribeNetworkAclsInput
+}
+
+func (f *fakeNetworkAclClient) DescribeNetworkAcls(input *ec2.DescribeNetworkAclsInput) (*ec2.DescribeNetworkAclsOutput, error) {
+	f.lastInput = input
+	return &ec2.DescribeNetworkAclsOutput{NetworkAcls: f.acls}, nil
+}
+
+// Helper function to assert no deny rule in a network ACL is unreachable
+// because a lower-numbered allow already matches all of its traffic
+func assertNaclOrdering(t *testing.T, svc ec2iface.EC2API, naclID string) {
+	entries, err := naclEntries(svc, naclID)
+	require.NoError(t, err)
+	problems, err := naclShadowProblems(entries)
+	require.NoError(t, err)
+	for _, problem := range problems {
+		assert.Fail(t, "Network ACL "+naclID+" rules are misordered", problem)
+	}
+}
+
+// Helper function to read a network ACL's entries
+func naclEntries(svc ec2iface.EC2API, naclID string) ([]*ec2.NetworkAclEntry, error) {
+	result, err := svc.DescribeNetworkAcls(&ec2.DescribeNetworkAclsInput{
+		NetworkAclIds: aws.StringSlice([]string{naclID}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.NetworkAcls) == 0 {
+		return nil, fmt.Errorf("network ACL %s not found", naclID)
+	}
+	return result.NetworkAcls[0].Entries, nil
+}
+
+// Helper function to list deny rules that a lower-numbered allow in the same
+// direction fully covers (protocol, ports and CIDR), in evaluation order. The
+// default 32767 rule is skipped since it only catches unmatched traffic.
+func naclShadowProblems(entries []*ec2.NetworkAclEntry) ([]string, error) {
+	sorted := append([]*ec2.NetworkAclEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if aws.BoolValue(sorted[i].Egress) != aws.BoolValue(sorted[j].Egress) {
+			return !aws.BoolValue(sorted[i].Egress)
+		}
+		return aws.Int64Value(sorted[i].RuleNumber) < aws.Int64Value(sorted[j].RuleNumber)
+	})
+
+	var problems []string
+	for i, deny := range sorted {
+		if aws.StringValue(deny.RuleAction) != ec2.RuleActionDeny || aws.Int64Value(deny.RuleNumber) == 32767 {
+			continue
+		}
+		for _, allow := range sorted[:i] {
+			if aws.BoolValue(allow.Egress) != aws.BoolValue(deny.Egress) || aws.StringValue(allow.RuleAction) != ec2.RuleActionAllow {
+				continue
+			}
+			covers, err := naclEntryCovers(allow, deny)
+			if err != nil {
+				return nil, err
+			}
+			if covers {
+				direction := "ingress"
+				if aws.BoolValue(deny.Egress) {
+					direction = "egress"
+				}
+				problems = append(problems, fmt.Sprintf("%s rule %d (allow %s) shadows deny rule %d (%s)",
+					direction, aws.Int64Value(allow.RuleNumber), naclEntryTraffic(allow), aws.Int64Value(deny.RuleNumber), naclEntryTraffic(deny)))
+				break
+			}
+		}
+	}
+	return problems, nil
+}
+
+// Helper function to check every packet matching inner also matches outer
+func naclEntryCovers(outer, inner *ec2.NetworkAclEntry) (bool, error) {
+	outerProtocol, innerProtocol := aws.StringValue(outer.Protocol), aws.StringValue(inner.Protocol)
+	if outerProtocol != "-1" && outerProtocol != innerProtocol {
+		return false, nil
+	}
+	if outerProtocol != "-1" && outer.PortRange != nil {
+		innerFrom, innerTo := int64(0), int64(65535)
+		if inner.PortRange != nil {
+			innerFrom, innerTo = aws.Int64Value(inner.PortRange.From), aws.Int64Value(inner.PortRange.To)
+		}
+		if aws.Int64Value(outer.PortRange.From) > innerFrom || aws.Int64Value(outer.PortRange.To) < innerTo {
+			return false, nil
+		}
+	}
+
+	outerCIDR, innerCIDR := naclEntryCIDR(outer), naclEntryCIDR(inner)
+	_, outerNet, err := net.ParseCIDR(outerCIDR)
+	if err != nil {
+		return false, err
+	}
+	_, innerNet, err := net.ParseCIDR(innerCIDR)
+	if err != nil {
+		return false, err
+	}
+	outerBits, outerSize := outerNet.Mask.Size()
+	innerBits, innerSize := innerNet.Mask.Size()
+	return outerSize == innerSize && outerBits <= innerBits && outerNet.Contains(innerNet.IP), nil
+}
+
+// Helper function to return an entry's IPv4 or IPv6 CIDR
+func naclEntryCIDR(entry *ec2.NetworkAclEntry) string {
+	if entry.CidrBlock != nil {
+		return aws.StringValue(entry.CidrBlock)
+	}
+	return aws.StringValue(entry.Ipv6CidrBlock)
+}
+
+// Helper function to render the protocol, CIDR and ports an entry matches
+func naclEntryTraffic(entry *ec2.NetworkAclEntry) string {
+	protocol := aws.StringValue(entry.Protocol)
+	if protocol == "-1" {
+		return "all " + naclEntryCIDR(entry)
+	}
+	if entry.PortRange == nil {
+		return protocol + " " + naclEntryCIDR(entry)
+	}
+	return fmt.Sprintf("%s %s ports %d-%d", protocol, naclEntryCIDR(entry), aws.Int64Value(entry.PortRange.From), aws.Int64Value(entry.PortRange.To))
 }
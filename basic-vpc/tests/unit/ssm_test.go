@@ -1,10 +1,20 @@
 package test
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestSsmRole(t *testing.T) {
@@ -19,6 +29,7 @@ func TestSsmRole(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -30,9 +41,13 @@ func TestSsmRole(t *testing.T) {
 	assert.NotEmpty(t, ssmRoleArn)
 	assert.Contains(t, ssmRoleArn, "ssm-role-for-private-ec2")
 
-	// Test assume role policy
-	ssmRoleAssumePolicy := terraform.Output(t, terraformOptions, "ssm_role_assume_policy")
-	assert.Contains(t, ssmRoleAssumePolicy, "ec2.amazonaws.com")
+	// Only EC2 may assume the role, with sessions no longer than an hour
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	problems, err := testkit.RoleTrustProblems(iam.New(sess), ssmRoleName, "ec2.amazonaws.com")
+	require.NoError(t, err)
+	assert.Empty(t, problems, "SSM role trust policy should be least privilege")
 }
 
 func TestSsmPolicyAttachment(t *testing.T) {
@@ -47,6 +62,7 @@ func TestSsmPolicyAttachment(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -70,6 +86,7 @@ func TestSsmInstanceProfile(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -97,23 +114,50 @@ func TestVpcEndpoints(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
-
-	// Test SSM VPC Endpoint
-	ssmEndpointId := terraform.Output(t, terraformOptions, "ssm_endpoint_id")
-	assert.NotEmpty(t, ssmEndpointId)
-
-	ssmEndpointServiceName := terraform.Output(t, terraformOptions, "ssm_endpoint_service_name")
-	assert.Contains(t, ssmEndpointServiceName, "ssm")
-
-	// Test EC2 Messages VPC Endpoint
-	ec2messagesEndpointId := terraform.Output(t, terraformOptions, "ec2messages_endpoint_id")
-	assert.NotEmpty(t, ec2messagesEndpointId)
-
-	// Test SSM Messages VPC Endpoint
-	ssmmessagesEndpointId := terraform.Output(t, terraformOptions, "ssmmessages_endpoint_id")
-	assert.NotEmpty(t, ssmmessagesEndpointId)
+	var scope testkit.ENIScope
+
+	t.Run("Deploy", func(t *testing.T) {
+		testkit.AcquireApplySlot(t)
+		defer terraform.Destroy(t, terraformOptions)
+		terraform.InitAndApply(t, terraformOptions)
+
+		vpcId := terraform.Output(t, terraformOptions, "vpc_id")
+
+		// A vpc-id filter matches nothing once the VPC is gone, so look ENIs
+		// up afterwards by the subnets and security groups they lived in
+		sess := session.Must(session.NewSession(&aws.Config{
+			Region: aws.String("us-east-1"),
+		}))
+		var err error
+		scope, err = testkit.CaptureENIScope(ec2.New(sess), vpcId)
+		require.NoError(t, err)
+
+		// Test SSM VPC Endpoint
+		ssmEndpointId := terraform.Output(t, terraformOptions, "ssm_endpoint_id")
+		assert.NotEmpty(t, ssmEndpointId)
+
+		ssmEndpointServiceName := terraform.Output(t, terraformOptions, "ssm_endpoint_service_name")
+		assert.Contains(t, ssmEndpointServiceName, "ssm")
+
+		// Test EC2 Messages VPC Endpoint
+		ec2messagesEndpointId := terraform.Output(t, terraformOptions, "ec2messages_endpoint_id")
+		assert.NotEmpty(t, ec2messagesEndpointId)
+
+		// Test SSM Messages VPC Endpoint
+		ssmmessagesEndpointId := terraform.Output(t, terraformOptions, "ssmmessages_endpoint_id")
+		assert.NotEmpty(t, ssmmessagesEndpointId)
+	})
+
+	// Interface endpoints are the usual source of ENIs that block destroy,
+	// so verify nothing was left behind once teardown has finished
+	t.Run("ENICleanup", func(t *testing.T) {
+		require.NotEmpty(t, scope.SubnetIDs, "The VPC's subnets are required to check for leaked ENIs")
+
+		sess := session.Must(session.NewSession(&aws.Config{
+			Region: aws.String("us-east-1"),
+		}))
+		testkit.AssertNoLeakedENIs(t, ec2.New(sess), scope)
+	})
 }
 
 func TestVpcEndpointConfiguration(t *testing.T) {
@@ -128,6 +172,7 @@ func TestVpcEndpointConfiguration(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -160,6 +205,7 @@ func TestVpcEndpointSecurityGroup(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -175,3 +221,134 @@ func TestVpcEndpointSecurityGroup(t *testing.T) {
 	endpointSgName := terraform.Output(t, terraformOptions, "endpoint_sg_name")
 	assert.Contains(t, endpointSgName, "vpc-endpoint-sg")
 }
+
+func TestVpcEndpointPolicies(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":                "test",
+			"allowed_http_cidrs":         []string{"10.0.0.0/8"},
+			"allowed_ssh_cidrs":          []string{"10.0.0.0/8"},
+			"restrict_endpoint_policies": true,
+		},
+	}
+
+	testkit.AcquireApplySlot(t)
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "true", terraform.Output(t, terraformOptions, "vpc_endpoint_policies_restricted"))
+
+	endpointIds := []*string{
+		aws.String(terraform.Output(t, terraformOptions, "ssm_endpoint_id")),
+		aws.String(terraform.Output(t, terraformOptions, "ec2messages_endpoint_id")),
+		aws.String(terraform.Output(t, terraformOptions, "ssmmessages_endpoint_id")),
+	}
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	result, err := ec2.New(sess).DescribeVpcEndpoints(&ec2.DescribeVpcEndpointsInput{
+		VpcEndpointIds: endpointIds,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.VpcEndpoints, len(endpointIds))
+
+	// Every endpoint policy must limit access to this VPC or account
+	for _, endpoint := range result.VpcEndpoints {
+		restricted, err := endpointPolicyRestricted(aws.StringValue(endpoint.PolicyDocument))
+		require.NoError(t, err)
+		assert.True(t, restricted, "Endpoint %s (%s) should have a restrictive policy", aws.StringValue(endpoint.VpcEndpointId), aws.StringValue(endpoint.ServiceName))
+	}
+}
+
+func TestEndpointPolicyRestricted(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		policy     string
+		restricted bool
+	}{
+		{
+			name:       "default full access",
+			policy:     `{"Statement":[{"Action":"*","Effect":"Allow","Principal":"*","Resource":"*"}]}`,
+			restricted: false,
+		},
+		{
+			name:       "source VPC",
+			policy:     `{"Statement":[{"Action":"ssm:*","Effect":"Allow","Principal":"*","Resource":"*","Condition":{"StringEquals":{"aws:SourceVpc":"vpc-12345678"}}}]}`,
+			restricted: true,
+		},
+		{
+			name:       "principal account as a single statement",
+			policy:     `{"Statement":{"Action":"ssm:*","Effect":"Allow","Principal":"*","Resource":"*","Condition":{"StringEquals":{"AWS:PrincipalAccount":"123456789012"}}}}`,
+			restricted: true,
+		},
+		{
+			name:       "one unconditioned allow",
+			policy:     `{"Statement":[{"Action":"ssm:*","Effect":"Allow","Principal":"*","Resource":"*","Condition":{"StringEquals":{"aws:SourceVpc":"vpc-12345678"}}},{"Action":"ssmmessages:*","Effect":"Allow","Principal":"*","Resource":"*"}]}`,
+			restricted: false,
+		},
+		{
+			name:       "unrelated condition",
+			policy:     `{"Statement":[{"Action":"ssm:*","Effect":"Allow","Principal":"*","Resource":"*","Condition":{"Bool":{"aws:SecureTransport":"true"}}}]}`,
+			restricted: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		restricted, err := endpointPolicyRestricted(tc.policy)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.restricted, restricted, tc.name)
+	}
+
+	_, err := endpointPolicyRestricted("not json")
+	assert.Error(t, err)
+}
+
+// Helper function to check every Allow statement in an endpoint policy is
+// conditioned on aws:SourceVpc or aws:PrincipalAccount
+func endpointPolicyRestricted(policyDocument string) (bool, error) {
+	var policy struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policyDocument), &policy); err != nil {
+		return false, fmt.Errorf("parsing endpoint policy: %w", err)
+	}
+
+	type statement struct {
+		Effect    string                            `json:"Effect"`
+		Condition map[string]map[string]interface{} `json:"Condition"`
+	}
+	var statements []statement
+	if err := json.Unmarshal(policy.Statement, &statements); err != nil {
+		var single statement
+		if err := json.Unmarshal(policy.Statement, &single); err != nil {
+			return false, fmt.Errorf("parsing endpoint policy statements: %w", err)
+		}
+		statements = []statement{single}
+	}
+
+	for _, stmt := range statements {
+		if stmt.Effect != "Allow" {
+			continue
+		}
+
+		restricted := false
+		for _, conditions := range stmt.Condition {
+			for key := range conditions {
+				switch strings.ToLower(key) {
+				case "aws:sourcevpc", "aws:principalaccount":
+					restricted = true
+				}
+			}
+		}
+		if !restricted {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -1,10 +1,20 @@
 package test
 
 import (
+	"fmt"
+	"sort"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestVpcCreation(t *testing.T) {
@@ -19,6 +29,7 @@ func TestVpcCreation(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -36,6 +47,15 @@ func TestVpcCreation(t *testing.T) {
 
 	enableDnsHostnames := terraform.Output(t, terraformOptions, "vpc_enable_dns_hostnames")
 	assert.Equal(t, "true", enableDnsHostnames)
+
+	// Check the live subnets, including that only the public one auto-assigns public IPs
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertSubnets(t, ec2.New(sess), map[string]testkit.ExpectedSubnet{
+		terraform.Output(t, terraformOptions, "public_subnet_id"):  {CIDR: "10.0.1.0/24", AZ: "us-east-1a", Public: true},
+		terraform.Output(t, terraformOptions, "private_subnet_id"): {CIDR: "10.0.2.0/24", AZ: "us-east-1a", Public: false},
+	})
 }
 
 func TestVpcTagging(t *testing.T) {
@@ -50,13 +70,19 @@ func TestVpcTagging(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Test VPC tags
-	vpcTags := terraform.OutputMap(t, terraformOptions, "vpc_tags")
-	assert.Equal(t, "basic-vpc", vpcTags["Name"])
-	assert.Equal(t, "test", vpcTags["Environment"])
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+
+	// Test tags directly on the tagged resources
+	assertTags(t, sess, terraform.Output(t, terraformOptions, "vpc_arn"), map[string]string{"Name": "basic-vpc", "Environment": "test"})
+	assertTags(t, sess, terraform.Output(t, terraformOptions, "public_instance_arn"), map[string]string{"Name": "public-ec2", "Environment": "test"})
+	assertTags(t, sess, terraform.Output(t, terraformOptions, "private_instance_arn"), map[string]string{"Name": "private-ec2", "Environment": "test"})
+	assertTags(t, sess, terraform.Output(t, terraformOptions, "cloudtrail_bucket_arn"), map[string]string{"Name": "cloudtrail-logs", "Environment": "test"})
 }
 
 func TestVpcFlowLogs(t *testing.T) {
@@ -71,6 +97,7 @@ func TestVpcFlowLogs(t *testing.T) {
 		},
 	}
 
+	testkit.AcquireApplySlot(t)
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
@@ -86,3 +113,96 @@ func TestVpcFlowLogs(t *testing.T) {
 	logGroupName := terraform.Output(t, terraformOptions, "vpc_flow_log_group_name")
 	assert.Equal(t, "/aws/vpc/flowlogs", logGroupName)
 }
+
+func TestMissingTags(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeTaggingClient{
+		tags: []*resourcegroupstaggingapi.Tag{
+			{Key: aws.String("Name"), Value: aws.String("basic-vpc")},
+			{Key: aws.String("Environment"), Value: aws.String("test")},
+			{Key: aws.String("Extra"), Value: aws.String("ignored")},
+		},
+	}
+
+	got, err := resourceTags(svc, "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-12345678")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arn:aws:ec2:us-east-1:123456789012:vpc/vpc-12345678"}, aws.StringValueSlice(svc.lastInput.ResourceARNList))
+
+	// Extra tags on the resource are fine; missing or different values are not
+	assert.Empty(t, missingTags(got, map[string]string{"Name": "basic-vpc", "Environment": "test"}))
+	assert.Equal(t, []string{
+		`Environment="prod" (got "test")`,
+		`Owner="platform" (missing)`,
+	}, missingTags(got, map[string]string{"Environment": "prod", "Owner": "platform"}))
+
+	// An ARN the tagging API doesn't know about is an error, not an empty tag set
+	_, err = resourceTags(&fakeTaggingClient{}, "arn:aws:ec2:us-east-1:123456789012:vpc/missing")
+	assert.Error(t, err)
+}
+
+type fakeTaggingClient struct {
+	resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
+	tags      []*resourcegroupstaggingapi.Tag
+	lastInput *resourcegroupstaggingapi.GetResourcesInput
+}
+
+func (f *fakeTaggingClient) GetResources(input *resourcegroupstaggingapi.GetResourcesInput) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	f.lastInput = input
+	if f.tags == nil {
+		return &resourcegroupstaggingapi.GetResourcesOutput{}, nil
+	}
+	return &resourcegroupstaggingapi.GetResourcesOutput{
+		ResourceTagMappingList: []*resourcegroupstaggingapi.ResourceTagMapping{
+			{
+				ResourceARN: input.ResourceARNList[0],
+				Tags:        f.tags,
+			},
+		},
+	}, nil
+}
+
+// Helper function to assert a resource carries at least the expected tags
+func assertTags(t *testing.T, sess *session.Session, arn string, want map[string]string) {
+	got, err := resourceTags(resourcegroupstaggingapi.New(sess), arn)
+	require.NoError(t, err)
+	assert.Empty(t, missingTags(got, want), "Resource %s is missing expected tags", arn)
+}
+
+// Helper function to fetch a resource's tags through the Resource Groups Tagging API
+func resourceTags(svc resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI, arn string) (map[string]string, error) {
+	result, err := svc.GetResources(&resourcegroupstaggingapi.GetResourcesInput{
+		ResourceARNList: []*string{aws.String(arn)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mapping := range result.ResourceTagMappingList {
+		if aws.StringValue(mapping.ResourceARN) != arn {
+			continue
+		}
+		tags := map[string]string{}
+		for _, tag := range mapping.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		return tags, nil
+	}
+	return nil, fmt.Errorf("no tags found for %s", arn)
+}
+
+// Helper function to list expected tags that are absent or have a different value
+func missingTags(got, want map[string]string) []string {
+	var missing []string
+	for key, value := range want {
+		actual, ok := got[key]
+		switch {
+		case !ok:
+			missing = append(missing, fmt.Sprintf("%s=%q (missing)", key, value))
+		case actual != value:
+			missing = append(missing, fmt.Sprintf("%s=%q (got %q)", key, value, actual))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
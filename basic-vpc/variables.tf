@@ -34,4 +34,189 @@ variable "allowed_ssh_cidrs" {
   description = "CIDR blocks allowed to access SSH (port 22)"
   type        = list(string)
   default     = [] # No default - must be explicitly set for security
-}
\ No newline at end of file
+
+  # Each CIDR gets its own public NACL rule, numbered below the ephemeral-port rule
+  validation {
+    condition     = length(var.allowed_ssh_cidrs) <= 10
+    error_message = "allowed_ssh_cidrs can have at most 10 entries."
+  }
+}
+variable "user_data_packages" {
+  description = "Packages installed by the instance user_data script"
+  type        = list(string)
+  default     = ["httpd", "mod_security", "mod_ssl"]
+
+  validation {
+    condition     = length(var.user_data_packages) > 0
+    error_message = "At least one package must be installed by user_data."
+  }
+}
+
+variable "web_root_content" {
+  description = "Content of the web root index page (defaults to the instance private IP)"
+  type        = string
+  default     = ""
+}
+
+variable "health_check_content" {
+  description = "Body served from /health by the instances"
+  type        = string
+  default     = "OK"
+}
+
+variable "profile" {
+  description = "Security preset: minimal (open egress, SSH allowed, AWS-managed EBS key, short log retention) or hardened (endpoint policies, AWS-only egress, Session Manager only, termination protection, a customer-managed EBS key, a year of flow logs). Individual toggles override it."
+  type        = string
+  default     = "minimal"
+
+  validation {
+    condition     = contains(["minimal", "hardened"], var.profile)
+    error_message = "profile must be minimal or hardened."
+  }
+}
+
+variable "restrict_endpoint_policies" {
+  description = "Attach endpoint policies limiting the SSM endpoints to this account and VPC; null follows profile"
+  type        = bool
+  default     = null
+}
+
+variable "enable_deletion_protection" {
+  description = "Enable termination protection on the EC2 instances; null follows profile"
+  type        = bool
+  default     = null
+}
+
+variable "create_kms_key" {
+  description = "Encrypt the root volumes with a customer-managed KMS key with yearly rotation instead of the AWS-managed aws/ebs key; null follows profile"
+  type        = bool
+  default     = null
+}
+
+variable "enable_instance_metadata_tags" {
+  description = "Expose instance tags through the instance metadata service"
+  type        = bool
+  default     = false
+}
+
+variable "flow_log_format" {
+  description = "VPC flow log record format; the default adds packet-level addresses and TCP flags to the standard fields"
+  type        = string
+  default     = "$${version} $${account-id} $${interface-id} $${srcaddr} $${dstaddr} $${pkt-srcaddr} $${pkt-dstaddr} $${srcport} $${dstport} $${protocol} $${packets} $${bytes} $${start} $${end} $${action} $${tcp-flags} $${log-status}"
+
+  validation {
+    condition     = can(regex("^\\$\\{[a-z0-9-]+\\}( \\$\\{[a-z0-9-]+\\})*$", var.flow_log_format))
+    error_message = "flow_log_format must be a space-separated list of $${field} references."
+  }
+}
+
+variable "flow_log_retention_days" {
+  description = "Days to keep VPC flow logs in CloudWatch Logs; null follows profile (30 minimal, 365 hardened)"
+  type        = number
+  default     = null
+
+  validation {
+    condition     = var.flow_log_retention_days == null ? true : contains([1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], var.flow_log_retention_days)
+    error_message = "flow_log_retention_days must be a retention period CloudWatch Logs supports, e.g. 30, 90, 365 or 731."
+  }
+}
+
+variable "flow_log_max_aggregation_interval" {
+  description = "Seconds over which flow log records are aggregated: 600 (fewer records, cheaper) or 60 (finer-grained, more records to ingest)"
+  type        = number
+  default     = 600
+
+  validation {
+    condition     = contains([60, 600], var.flow_log_max_aggregation_interval)
+    error_message = "flow_log_max_aggregation_interval must be 60 or 600."
+  }
+}
+
+variable "egress_profile" {
+  description = "Outbound access for the private subnet: open (anything via NAT), aws-only (VPC endpoints and S3 only) or custom-ports (aws-only plus egress_allowed_ports to the internet); null follows profile"
+  type        = string
+  default     = null
+
+  validation {
+    condition     = var.egress_profile == null ? true : contains(["open", "aws-only", "custom-ports"], var.egress_profile)
+    error_message = "egress_profile must be open, aws-only or custom-ports."
+  }
+}
+
+variable "access_mode" {
+  description = "How instances are administered: ssh+ssm (NACLs admit SSH from allowed_ssh_cidrs, plus Session Manager) or ssm-only (no NACL admits port 22, Session Manager only); null follows profile"
+  type        = string
+  default     = null
+
+  validation {
+    condition     = var.access_mode == null ? true : contains(["ssh+ssm", "ssm-only"], var.access_mode)
+    error_message = "access_mode must be ssh+ssm or ssm-only."
+  }
+}
+
+variable "egress_allowed_ports" {
+  description = "TCP ports the private subnet may reach on the internet when egress_profile is custom-ports"
+  type        = list(number)
+  default     = [443]
+
+  validation {
+    condition     = length(var.egress_allowed_ports) >= 1 && length(var.egress_allowed_ports) <= 9 && alltrue([for p in var.egress_allowed_ports : p >= 1 && p <= 65535])
+    error_message = "egress_allowed_ports must list between 1 and 9 ports in the range 1-65535."
+  }
+}
+
+variable "instance_type" {
+  description = "EC2 instance type for the public and private instances"
+  type        = string
+  default     = "t3.micro"
+
+  validation {
+    condition     = contains(["t3.nano", "t3.micro", "t3.small", "t3.medium", "t3.large", "m5.large", "m5d.large"], var.instance_type)
+    error_message = "instance_type must be one of t3.nano, t3.micro, t3.small, t3.medium, t3.large, m5.large or m5d.large."
+  }
+}
+
+variable "enable_scratch_disks" {
+  description = "Map the instance type's local instance-store volume as ephemeral0 scratch space next to the encrypted gp3 root; needs an instance type with instance storage"
+  type        = bool
+  default     = false
+}
+
+variable "root_volume_iops" {
+  description = "Provisioned IOPS for the gp3 root volumes; 3000 is the free baseline and each IOPS above it is billed"
+  type        = number
+  default     = 3000
+
+  validation {
+    condition     = var.root_volume_iops >= 3000 && var.root_volume_iops <= 16000 && floor(var.root_volume_iops) == var.root_volume_iops
+    error_message = "root_volume_iops must be a whole number between 3000 and 16000 (the gp3 range)."
+  }
+}
+
+variable "root_volume_throughput" {
+  description = "Provisioned throughput in MiB/s for the gp3 root volumes; 125 is the free baseline and each MiB/s above it is billed"
+  type        = number
+  default     = 125
+
+  validation {
+    condition     = var.root_volume_throughput >= 125 && var.root_volume_throughput <= 1000 && floor(var.root_volume_throughput) == var.root_volume_throughput
+    error_message = "root_volume_throughput must be a whole number between 125 and 1000 MiB/s (the gp3 range)."
+  }
+}
+
+variable "detailed_monitoring" {
+  description = "Enable 1-minute detailed monitoring on both instances; about $2.10 per instance per month"
+  type        = bool
+  default     = true
+}
+
+variable "cloudtrail_bucket_retention_days" {
+  description = "Days to keep CloudTrail log files in the S3 bucket before they expire"
+  type        = number
+  default     = 365
+
+  validation {
+    condition     = var.cloudtrail_bucket_retention_days >= 90
+    error_message = "cloudtrail_bucket_retention_days must be at least 90 days."
+  }
+}
@@ -6,6 +6,10 @@ resource "aws_cloudtrail" "main" {
   is_multi_region_trail         = true
   enable_logging                = true
 
+  # CloudWatch Logs delivery only exists for the metric filter alarms
+  cloud_watch_logs_group_arn = local.enable_cloudtrail_alarms ? "${aws_cloudwatch_log_group.cloudtrail[0].arn}:*" : null
+  cloud_watch_logs_role_arn  = local.enable_cloudtrail_alarms ? aws_iam_role.cloudtrail_logs[0].arn : null
+
   event_selector {
     read_write_type           = "All"
     include_management_events = true
@@ -19,6 +23,9 @@ resource "aws_cloudtrail" "main" {
     Name        = "bastion-host-cloudtrail"
     Environment = var.environment
   }
+
+  # CloudTrail checks it can write to the log group when the trail is saved
+  depends_on = [aws_iam_role_policy.cloudtrail_logs]
 }
 
 # S3 Bucket for CloudTrail logs
@@ -46,6 +53,42 @@ resource "aws_s3_bucket_versioning" "cloudtrail_bucket" {
   }
 }
 
+# Expire trail logs (and their noncurrent versions) so the bucket doesn't grow forever
+resource "aws_s3_bucket_lifecycle_configuration" "cloudtrail_bucket" {
+  bucket = aws_s3_bucket.cloudtrail_bucket.id
+
+  rule {
+    id     = "expire-trail-logs"
+    status = "Enabled"
+
+    filter {}
+
+    expiration {
+      days = var.cloudtrail_bucket_retention_days
+    }
+
+    noncurrent_version_expiration {
+      noncurrent_days = 30
+    }
+
+    abort_incomplete_multipart_upload {
+      days_after_initiation = 7
+    }
+  }
+
+  # Lifecycle rules on a versioned bucket need versioning in place first
+  depends_on = [aws_s3_bucket_versioning.cloudtrail_bucket]
+}
+
+# The bastion's audit trail must stay readable by this account alone, so object
+# ACLs are disabled and every log object belongs to the bucket owner
+resource "aws_s3_bucket_ownership_controls" "cloudtrail_bucket" {
+  bucket = aws_s3_bucket.cloudtrail_bucket.id
+  rule {
+    object_ownership = "BucketOwnerEnforced"
+  }
+}
+
 # S3 Bucket server-side encryption for CloudTrail
 resource "aws_s3_bucket_server_side_encryption_configuration" "cloudtrail_bucket" {
   bucket = aws_s3_bucket.cloudtrail_bucket.id
@@ -99,4 +142,114 @@ resource "aws_s3_bucket_policy" "cloudtrail_bucket" {
       }
     ]
   })
-}
\ No newline at end of file
+}
+# CloudWatch Log Group CloudTrail delivers to for metric filters
+resource "aws_cloudwatch_log_group" "cloudtrail" {
+  count             = local.enable_cloudtrail_alarms ? 1 : 0
+  name              = "/aws/cloudtrail/bastion-host"
+  retention_in_days = local.cloudtrail_log_retention_days
+
+  tags = {
+    Name        = "cloudtrail-logs"
+    Environment = var.environment
+  }
+}
+
+# IAM Role CloudTrail assumes to write to the log group
+resource "aws_iam_role" "cloudtrail_logs" {
+  count = local.enable_cloudtrail_alarms ? 1 : 0
+  name  = "bastion-host-cloudtrail-logs"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Action = "sts:AssumeRole"
+        Effect = "Allow"
+        Principal = {
+          Service = "cloudtrail.amazonaws.com"
+        }
+      }
+    ]
+  })
+
+  tags = {
+    Name        = "cloudtrail-logs-role"
+    Environment = var.environment
+  }
+}
+
+resource "aws_iam_role_policy" "cloudtrail_logs" {
+  count = local.enable_cloudtrail_alarms ? 1 : 0
+  name  = "bastion-host-cloudtrail-logs"
+  role  = aws_iam_role.cloudtrail_logs[0].id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect = "Allow"
+        Action = [
+          "logs:CreateLogStream",
+          "logs:PutLogEvents"
+        ]
+        Resource = "${aws_cloudwatch_log_group.cloudtrail[0].arn}:log-stream:*"
+      }
+    ]
+  })
+}
+
+# Sensitive events to alarm on, using the CIS AWS Foundations filter patterns
+locals {
+  cloudtrail_metric_namespace = "BastionHost/CloudTrail"
+  cloudtrail_alarms = local.enable_cloudtrail_alarms ? {
+    root_login = {
+      metric_name = "RootLoginCount"
+      description = "The root user signed in or made an API call"
+      pattern     = "{ $.userIdentity.type = \"Root\" && $.userIdentity.invokedBy NOT EXISTS && $.eventType != \"AwsServiceEvent\" }"
+    }
+    iam_policy_changes = {
+      metric_name = "IAMPolicyChangeCount"
+      description = "An IAM policy was created, changed, attached or detached"
+      pattern     = "{ ($.eventName = DeleteGroupPolicy) || ($.eventName = DeleteRolePolicy) || ($.eventName = DeleteUserPolicy) || ($.eventName = PutGroupPolicy) || ($.eventName = PutRolePolicy) || ($.eventName = PutUserPolicy) || ($.eventName = CreatePolicy) || ($.eventName = DeletePolicy) || ($.eventName = CreatePolicyVersion) || ($.eventName = DeletePolicyVersion) || ($.eventName = AttachRolePolicy) || ($.eventName = DetachRolePolicy) || ($.eventName = AttachUserPolicy) || ($.eventName = DetachUserPolicy) || ($.eventName = AttachGroupPolicy) || ($.eventName = DetachGroupPolicy) }"
+    }
+    security_group_changes = {
+      metric_name = "SecurityGroupChangeCount"
+      description = "A security group or one of its rules was created, changed or deleted"
+      pattern     = "{ ($.eventName = AuthorizeSecurityGroupIngress) || ($.eventName = AuthorizeSecurityGroupEgress) || ($.eventName = RevokeSecurityGroupIngress) || ($.eventName = RevokeSecurityGroupEgress) || ($.eventName = ModifySecurityGroupRules) || ($.eventName = CreateSecurityGroup) || ($.eventName = DeleteSecurityGroup) }"
+    }
+  } : {}
+}
+
+resource "aws_cloudwatch_log_metric_filter" "cloudtrail" {
+  for_each       = local.cloudtrail_alarms
+  name           = "bastion-host-${replace(each.key, "_", "-")}"
+  log_group_name = aws_cloudwatch_log_group.cloudtrail[0].name
+  pattern        = each.value.pattern
+
+  metric_transformation {
+    name      = each.value.metric_name
+    namespace = local.cloudtrail_metric_namespace
+    value     = "1"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "cloudtrail" {
+  for_each            = local.cloudtrail_alarms
+  alarm_name          = "bastion-host-${replace(each.key, "_", "-")}"
+  alarm_description   = each.value.description
+  namespace           = local.cloudtrail_metric_namespace
+  metric_name         = aws_cloudwatch_log_metric_filter.cloudtrail[each.key].metric_transformation[0].name
+  statistic           = "Sum"
+  period              = 300
+  evaluation_periods  = 1
+  comparison_operator = "GreaterThanOrEqualToThreshold"
+  threshold           = 1
+  treat_missing_data  = "notBreaching"
+  alarm_actions       = [local.alarm_topic_arn]
+
+  tags = {
+    Name        = "bastion-host-${replace(each.key, "_", "-")}"
+    Environment = var.environment
+  }
+}
@@ -95,7 +95,7 @@ resource "aws_iam_instance_profile" "bastion_profile" {
 # CloudWatch Log Group for Bastion Host logs
 resource "aws_cloudwatch_log_group" "bastion_logs" {
   name              = "/aws/bastion/ssh-logs"
-  retention_in_days = 30
+  retention_in_days = local.log_retention_days
 
   tags = {
     Name        = "bastion-ssh-logs"
@@ -114,7 +114,7 @@ resource "aws_cloudwatch_metric_alarm" "ssh_attempts" {
   statistic           = "Sum"
   threshold           = "10"
   alarm_description   = "Monitor SSH login attempts on bastion host"
-  alarm_actions       = [aws_sns_topic.security_alerts.arn]
+  alarm_actions       = [local.alarm_topic_arn]
 
   tags = {
     Name        = "ssh-attempts-alarm"
@@ -132,6 +132,10 @@ resource "aws_sns_topic" "security_alerts" {
   }
 }
 
+locals {
+  alarm_topic_arn = var.alarm_sns_topic_arn != "" ? var.alarm_sns_topic_arn : aws_sns_topic.security_alerts.arn
+}
+
 # SNS Topic Policy
 resource "aws_sns_topic_policy" "security_alerts" {
   arn = aws_sns_topic.security_alerts.arn
@@ -5,6 +5,7 @@ terraform {
     region = "us-east-1"
   }
 
+  required_version = ">= 1.5.0"
   required_providers {
     aws = {
       source  = "hashicorp/aws"
@@ -35,14 +36,19 @@ module "vpc" {
   public_subnet_cidrs  = var.public_subnet_cidrs
   private_subnet_cidrs = var.private_subnet_cidrs
   region               = var.region
+  enable_nat_gateway   = var.enable_nat_gateway
+  single_nat_gateway   = var.single_nat_gateway
+  log_retention_days   = local.log_retention_days
+  environment          = var.environment
 }
 
 module "security_group" {
-  source               = "./modules/security_group"
-  vpc_id               = module.vpc.vpc_id
-  allowed_ssh_cidrs    = var.allowed_ssh_cidrs
-  private_subnet_cidrs = var.private_subnet_cidrs
-  environment          = var.environment
+  source                  = "./modules/security_group"
+  vpc_id                  = module.vpc.vpc_id
+  allowed_ssh_cidrs       = var.allowed_ssh_cidrs
+  private_subnet_cidrs    = var.private_subnet_cidrs
+  environment             = var.environment
+  restrict_private_egress = local.restrict_private_egress
 }
 
 module "key_pair" {
@@ -51,21 +57,42 @@ module "key_pair" {
   public_key = var.public_key
 }
 
+module "kms" {
+  count       = local.create_kms_key ? 1 : 0
+  source      = "./modules/kms"
+  alias_name  = "bastion-host-${var.environment}-ebs"
+  environment = var.environment
+}
+
+locals {
+  kms_key_arn = local.create_kms_key ? module.kms[0].key_arn : var.kms_key_arn
+}
+
 module "bastion" {
-  source               = "./modules/bastion"
-  subnet_id            = module.vpc.public_subnet_ids[0]
-  key_name             = module.key_pair.key_name
-  security_group_id    = module.security_group.bastion_security_group_id
-  ami                  = data.aws_ami.amazon_linux.id
-  environment          = var.environment
-  iam_instance_profile = aws_iam_instance_profile.bastion_profile.name
+  source                 = "./modules/bastion"
+  subnet_id              = module.vpc.public_subnet_ids[0]
+  key_name               = module.key_pair.key_name
+  security_group_id      = module.security_group.bastion_security_group_id
+  ami                    = data.aws_ami.amazon_linux.id
+  environment            = var.environment
+  iam_instance_profile   = aws_iam_instance_profile.bastion_profile.name
+  instance_type          = var.instance_type
+  instance_metadata_tags = var.enable_instance_metadata_tags
+  tenancy                = var.tenancy
+  detailed_monitoring    = var.detailed_monitoring
+  kms_key_arn            = local.kms_key_arn
 }
 
 module "private_instance" {
-  source            = "./modules/private_instance"
-  subnet_id         = module.vpc.private_subnet_ids[0]
-  key_name          = module.key_pair.key_name
-  security_group_id = module.security_group.private_security_group_id
-  ami               = data.aws_ami.amazon_linux.id
-  environment       = var.environment
+  source                 = "./modules/private_instance"
+  subnet_id              = module.vpc.private_subnet_ids[0]
+  key_name               = module.key_pair.key_name
+  security_group_id      = module.security_group.private_security_group_id
+  ami                    = data.aws_ami.amazon_linux.id
+  environment            = var.environment
+  instance_type          = var.instance_type
+  instance_metadata_tags = var.enable_instance_metadata_tags
+  tenancy                = var.tenancy
+  detailed_monitoring    = var.detailed_monitoring
+  kms_key_arn            = local.kms_key_arn
 }
@@ -0,0 +1,22 @@
+# Customer-managed key with yearly automatic rotation. The default key policy
+# leaves access to IAM in this account, which is what EBS needs to use it for
+# instances launched by the account's own principals.
+resource "aws_kms_key" "this" {
+  description             = var.description
+  enable_key_rotation     = true
+  deletion_window_in_days = var.deletion_window_in_days
+
+  tags = {
+    Name        = var.alias_name
+    Environment = var.environment
+  }
+}
+
+resource "aws_kms_alias" "this" {
+  name          = "alias/${var.alias_name}"
+  target_key_id = aws_kms_key.this.key_id
+}
+
+output "key_arn" { value = aws_kms_key.this.arn }
+output "key_id" { value = aws_kms_key.this.key_id }
+output "alias_name" { value = aws_kms_alias.this.name }
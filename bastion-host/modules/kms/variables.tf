@@ -0,0 +1,27 @@
+variable "alias_name" {
+  description = "Alias for the key, without the alias/ prefix"
+  type        = string
+}
+
+variable "description" {
+  description = "What the key protects"
+  type        = string
+  default     = "Customer-managed key for bastion host EBS volumes"
+}
+
+variable "deletion_window_in_days" {
+  description = "Days a scheduled deletion can still be cancelled"
+  type        = number
+  default     = 30
+
+  validation {
+    condition     = var.deletion_window_in_days >= 7 && var.deletion_window_in_days <= 30
+    error_message = "deletion_window_in_days must be between 7 and 30."
+  }
+}
+
+variable "environment" {
+  description = "Environment name for tagging"
+  type        = string
+  default     = "dev"
+}
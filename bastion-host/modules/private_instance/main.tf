@@ -1,9 +1,10 @@
 resource "aws_instance" "this" {
   ami                         = var.ami
-  instance_type               = "t2.micro"
+  instance_type               = var.instance_type
   subnet_id                   = var.subnet_id
   key_name                    = var.key_name
   vpc_security_group_ids      = [var.security_group_id]
+  tenancy                     = var.tenancy
   associate_public_ip_address = false
 
   # Enable encryption at rest
@@ -11,11 +12,19 @@ resource "aws_instance" "this" {
     volume_type           = "gp3"
     volume_size           = 20
     encrypted             = true
+    kms_key_id            = var.kms_key_arn == "" ? null : var.kms_key_arn
     delete_on_termination = true
   }
 
-  # Enable detailed monitoring
-  monitoring = true
+  monitoring = var.detailed_monitoring
+
+  # Same metadata options as the bastion
+  metadata_options {
+    http_endpoint               = "enabled"
+    http_tokens                 = "required"
+    http_put_response_hop_limit = 1
+    instance_metadata_tags      = var.instance_metadata_tags ? "enabled" : "disabled"
+  }
 
   # Security hardening user data
   user_data = <<-EOF
@@ -59,3 +68,15 @@ resource "aws_instance" "this" {
 }
 
 output "private_ip" { value = aws_instance.this.private_ip }
+output "instance_id" { value = aws_instance.this.id }
+output "instance_type" { value = aws_instance.this.instance_type }
+output "tenancy" { value = aws_instance.this.tenancy }
+output "detailed_monitoring" { value = aws_instance.this.monitoring }
+output "root_volume_kms_key_id" { value = aws_instance.this.root_block_device[0].kms_key_id }
+output "metadata_options" {
+  value = {
+    http_tokens                 = aws_instance.this.metadata_options[0].http_tokens
+    http_put_response_hop_limit = aws_instance.this.metadata_options[0].http_put_response_hop_limit
+    instance_metadata_tags      = aws_instance.this.metadata_options[0].instance_metadata_tags
+  }
+}
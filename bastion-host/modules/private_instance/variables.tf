@@ -6,3 +6,37 @@ variable "environment" {
   type = string
   default = "dev"
 }
+variable "instance_metadata_tags" {
+  description = "Expose the instance's tags through the metadata service"
+  type        = bool
+  default     = false
+}
+
+variable "tenancy" {
+  description = "Instance tenancy: default (shared hardware) or dedicated"
+  type        = string
+  default     = "default"
+}
+
+variable "detailed_monitoring" {
+  description = "Publish instance metrics every minute instead of every five (billed per metric)"
+  type        = bool
+  default     = true
+}
+
+variable "kms_key_arn" {
+  description = "Customer-managed KMS key for the root volume; empty uses the AWS-managed aws/ebs key"
+  type        = string
+  default     = ""
+}
+
+variable "instance_type" {
+  description = "EC2 instance type; limited to small burstable types for a workload reached only through the bastion"
+  type        = string
+  default     = "t3.micro"
+
+  validation {
+    condition     = contains(["t3.nano", "t3.micro", "t3.small", "t3.medium", "t3a.nano", "t3a.micro", "t3a.small", "t3a.medium"], var.instance_type)
+    error_message = "instance_type must be one of t3.nano, t3.micro, t3.small, t3.medium, t3a.nano, t3a.micro, t3a.small or t3a.medium."
+  }
+}
@@ -75,11 +75,21 @@ resource "aws_security_group" "private" {
     security_groups = [aws_security_group.bastion.id]
   }
 
-  egress {
-    from_port   = 0
-    to_port     = 0
-    protocol    = "-1"
-    cidr_blocks = ["0.0.0.0/0"]
+  dynamic "egress" {
+    for_each = var.restrict_private_egress ? [
+      { description = "HTTPS for updates and SSM", from_port = 443, to_port = 443, protocol = "tcp" },
+      { description = "DNS", from_port = 53, to_port = 53, protocol = "tcp" },
+      { description = "DNS UDP", from_port = 53, to_port = 53, protocol = "udp" },
+      ] : [
+      { description = "Allow all outbound traffic", from_port = 0, to_port = 0, protocol = "-1" },
+    ]
+    content {
+      description = egress.value.description
+      from_port   = egress.value.from_port
+      to_port     = egress.value.to_port
+      protocol    = egress.value.protocol
+      cidr_blocks = ["0.0.0.0/0"]
+    }
   }
 
   tags = {
@@ -89,4 +99,5 @@ resource "aws_security_group" "private" {
 }
 
 output "bastion_security_group_id" { value = aws_security_group.bastion.id }
-output "private_security_group_id" { value = aws_security_group.private.id }
\ No newline at end of file
+output "private_security_group_id" { value = aws_security_group.private.id }
+output "private_egress_restricted" { value = var.restrict_private_egress }
\ No newline at end of file
@@ -14,6 +14,12 @@ variable "private_subnet_cidrs" {
   default     = ["172.16.10.0/24"]
 }
 
+variable "restrict_private_egress" {
+  description = "Limit the private instance to HTTPS (package repositories, SSM) and DNS outbound instead of everything"
+  type        = bool
+  default     = false
+}
+
 variable "environment" {
   description = "Environment name for tagging"
   type        = string
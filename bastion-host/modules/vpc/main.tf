@@ -3,6 +3,17 @@ resource "aws_vpc" "this" {
   enable_dns_support   = true
   enable_dns_hostnames = true
   tags                 = { Name = "bastion_vpc" }
+
+  lifecycle {
+    precondition {
+      condition     = length(var.azs) > 0 && length(var.public_subnet_cidrs) == length(var.azs) && length(var.private_subnet_cidrs) == length(var.azs)
+      error_message = "azs, public_subnet_cidrs and private_subnet_cidrs must have the same, non-zero length: one public and one private subnet per AZ."
+    }
+  }
+}
+
+locals {
+  nat_gateway_count = var.enable_nat_gateway ? (var.single_nat_gateway ? 1 : length(var.azs)) : 0
 }
 
 # VPC Flow Logs for network monitoring
@@ -20,7 +31,7 @@ resource "aws_flow_log" "vpc_flow_log" {
 # CloudWatch Log Group for VPC Flow Logs
 resource "aws_cloudwatch_log_group" "vpc_flow_log" {
   name              = "/aws/vpc/flowlogs/bastion"
-  retention_in_days = 30
+  retention_in_days = var.log_retention_days
 
   tags = {
     Name = "vpc-flow-logs"
@@ -109,6 +120,48 @@ resource "aws_subnet" "private" {
   tags              = { Name = "private_subnet_${count.index}" }
 }
 
+# NAT gateways for private subnet egress when enabled, one per AZ unless
+# single_nat_gateway is set
+resource "aws_eip" "nat" {
+  count  = local.nat_gateway_count
+  domain = "vpc"
+
+  tags = {
+    Name        = "nat_eip_${count.index}"
+    Environment = var.environment
+  }
+}
+
+resource "aws_nat_gateway" "this" {
+  count         = local.nat_gateway_count
+  allocation_id = aws_eip.nat[count.index].id
+  subnet_id     = aws_subnet.public[count.index].id
+  tags          = { Name = "nat_gateway_${count.index}" }
+
+  depends_on = [aws_internet_gateway.igw]
+}
+
+# One route table per private subnet, so each AZ keeps egressing through its
+# own NAT gateway and losing one AZ doesn't take the others' egress with it
+resource "aws_route_table" "private" {
+  count  = length(var.private_subnet_cidrs)
+  vpc_id = aws_vpc.this.id
+  dynamic "route" {
+    for_each = var.enable_nat_gateway ? [1] : []
+    content {
+      cidr_block     = "0.0.0.0/0"
+      nat_gateway_id = aws_nat_gateway.this[var.single_nat_gateway ? 0 : count.index].id
+    }
+  }
+  tags = { Name = "private_route_table_${count.index}" }
+}
+
+resource "aws_route_table_association" "private" {
+  count          = length(var.private_subnet_cidrs)
+  subnet_id      = aws_subnet.private[count.index].id
+  route_table_id = aws_route_table.private[count.index].id
+}
+
 # Network ACLs for defense in depth
 resource "aws_network_acl" "public" {
   vpc_id     = aws_vpc.this.id
@@ -144,6 +197,19 @@ resource "aws_network_acl" "public" {
     to_port    = 65535
   }
 
+  # Allow the private subnets' outbound traffic into the NAT gateways
+  dynamic "ingress" {
+    for_each = var.enable_nat_gateway ? var.private_subnet_cidrs : []
+    content {
+      protocol   = "-1"
+      rule_no    = 130 + ingress.key
+      action     = "allow"
+      cidr_block = ingress.value
+      from_port  = 0
+      to_port    = 0
+    }
+  }
+
   # Allow all outbound traffic
   egress {
     protocol   = "-1"
@@ -270,3 +336,6 @@ resource "aws_security_group" "ssm_endpoint" {
 output "vpc_id" { value = aws_vpc.this.id }
 output "public_subnet_ids" { value = aws_subnet.public[*].id }
 output "private_subnet_ids" { value = aws_subnet.private[*].id }
+output "nat_gateway_ids" { value = aws_nat_gateway.this[*].id }
+output "private_route_table_ids" { value = aws_route_table.private[*].id }
+output "ssm_endpoint_security_group_id" { value = aws_security_group.ssm_endpoint.id }
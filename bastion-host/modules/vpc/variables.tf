@@ -3,3 +3,23 @@ variable "azs" { type = list(string) }
 variable "public_subnet_cidrs" { type = list(string) }
 variable "private_subnet_cidrs" { type = list(string) }
 variable "region" { type = string }
+variable "log_retention_days" {
+  description = "Days to keep VPC flow logs"
+  type        = number
+  default     = 30
+}
+variable "enable_nat_gateway" {
+  description = "Give the private subnets internet egress through NAT gateways; without it they only reach AWS through the VPC endpoints"
+  type        = bool
+  default     = false
+}
+variable "single_nat_gateway" {
+  description = "Share one NAT gateway (in the first public subnet) between every private subnet instead of one per AZ; cheaper, but an outage in that AZ cuts egress everywhere"
+  type        = bool
+  default     = false
+}
+variable "environment" {
+  description = "Environment name for tagging"
+  type        = string
+  default     = "dev"
+}
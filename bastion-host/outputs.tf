@@ -1,7 +1,49 @@
 output "vpc_id" { value = module.vpc.vpc_id }
 output "public_subnet_ids" { value = module.vpc.public_subnet_ids }
 output "private_subnet_ids" { value = module.vpc.private_subnet_ids }
-output "security_group_id" { value = module.security_group.security_group_id }
+output "nat_gateway_ids" { value = module.vpc.nat_gateway_ids }
+output "private_route_table_ids" { value = module.vpc.private_route_table_ids }
+output "security_group_id" { value = module.security_group.bastion_security_group_id }
+output "bastion_security_group_id" { value = module.security_group.bastion_security_group_id }
+output "private_security_group_id" { value = module.security_group.private_security_group_id }
+output "private_egress_restricted" { value = module.security_group.private_egress_restricted }
+output "ssm_endpoint_security_group_id" { value = module.vpc.ssm_endpoint_security_group_id }
 output "key_pair_name" { value = module.key_pair.key_name }
 output "bastion_public_ip" { value = module.bastion.public_ip }
+output "bastion_eip_allocation_id" { value = module.bastion.eip_allocation_id }
 output "private_instance_ip" { value = module.private_instance.private_ip }
+output "bastion_instance_id" { value = module.bastion.instance_id }
+output "private_instance_id" { value = module.private_instance.instance_id }
+output "bastion_instance_profile_arn" { value = aws_iam_instance_profile.bastion_profile.arn }
+output "bastion_instance_type" { value = module.bastion.instance_type }
+output "private_instance_type" { value = module.private_instance.instance_type }
+output "bastion_tenancy" { value = module.bastion.tenancy }
+output "private_instance_tenancy" { value = module.private_instance.tenancy }
+output "kms_key_arn" {
+  value = local.kms_key_arn == "" ? null : local.kms_key_arn
+
+  precondition {
+    condition     = !(var.create_kms_key == true && var.kms_key_arn != "")
+    error_message = "Set kms_key_arn to use an existing key or create_kms_key to create one, not both."
+  }
+}
+output "root_volume_kms_key_ids" {
+  value = {
+    bastion          = module.bastion.root_volume_kms_key_id
+    private_instance = module.private_instance.root_volume_kms_key_id
+  }
+}
+output "detailed_monitoring" { value = module.bastion.detailed_monitoring && module.private_instance.detailed_monitoring }
+output "cloudtrail_name" { value = aws_cloudtrail.main.name }
+output "cloudtrail_bucket_name" { value = aws_s3_bucket.cloudtrail_bucket.id }
+output "cloudtrail_log_group_name" { value = one(aws_cloudwatch_log_group.cloudtrail[*].name) }
+output "bastion_log_retention_days" { value = aws_cloudwatch_log_group.bastion_logs.retention_in_days }
+output "profile" { value = var.profile }
+output "alarm_topic_arn" { value = local.alarm_topic_arn }
+output "cloudtrail_alarm_names" { value = { for key, alarm in aws_cloudwatch_metric_alarm.cloudtrail : key => alarm.alarm_name } }
+output "instance_metadata_options" {
+  value = {
+    bastion          = module.bastion.metadata_options
+    private_instance = module.private_instance.metadata_options
+  }
+}
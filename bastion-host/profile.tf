@@ -0,0 +1,29 @@
+# hardened adds the pieces a production bastion wants on top of minimal: a
+# customer-managed key, locked-down private egress, CloudTrail alarms and a
+# year of logs. Leave a variable null to take the profile's value.
+# IMDSv2 is required on both instances either way.
+locals {
+  profiles = {
+    minimal = {
+      create_kms_key                = false
+      restrict_private_egress       = false
+      enable_cloudtrail_alarms      = false
+      cloudtrail_log_retention_days = 90
+      log_retention_days            = 30
+    }
+    hardened = {
+      create_kms_key                = true
+      restrict_private_egress       = true
+      enable_cloudtrail_alarms      = true
+      cloudtrail_log_retention_days = 365
+      log_retention_days            = 365
+    }
+  }
+  profile = local.profiles[var.profile]
+
+  create_kms_key                = var.kms_key_arn == "" && coalesce(var.create_kms_key, local.profile.create_kms_key)
+  restrict_private_egress       = coalesce(var.restrict_private_egress, local.profile.restrict_private_egress)
+  enable_cloudtrail_alarms      = coalesce(var.enable_cloudtrail_alarms, local.profile.enable_cloudtrail_alarms)
+  cloudtrail_log_retention_days = coalesce(var.cloudtrail_log_retention_days, local.profile.cloudtrail_log_retention_days)
+  log_retention_days            = coalesce(var.log_retention_days, local.profile.log_retention_days)
+}
@@ -1,15 +1,19 @@
 package test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestChaosBastionFailure(t *testing.T) {
@@ -34,31 +38,27 @@ func TestChaosBastionFailure(t *testing.T) {
 
 	// Get bastion instance ID
 	bastionID := terraform.Output(t, terraformOptions, "bastion_instance_id")
+	allocationID := terraform.Output(t, terraformOptions, "bastion_eip_allocation_id")
 
 	sess := session.Must(session.NewSession(&aws.Config{
 		Region: aws.String("us-east-1"),
 	}))
 	ec2Svc := ec2.New(sess)
 
+	// Record the EIP association operators depend on before disrupting the bastion
+	before := recordEIPAssociation(t, ec2Svc, allocationID)
+	assert.Equal(t, bastionID, before.InstanceID, "Bastion EIP should be associated with the bastion")
+	assert.Equal(t, terraform.Output(t, terraformOptions, "bastion_public_ip"), before.PublicIP)
+
 	// Simulate bastion host failure
 	t.Log("Simulating bastion host failure...")
 	_, err := ec2Svc.StopInstances(&ec2.StopInstancesInput{
 		InstanceIds: []*string{aws.String(bastionID)},
 	})
 	require.NoError(t, err)
-
-	// Wait for instance to stop
-	time.Sleep(30 * time.Second)
-
-	// Verify bastion is stopped
-	descInput := &ec2.DescribeInstancesInput{
+	require.NoError(t, ec2Svc.WaitUntilInstanceStopped(&ec2.DescribeInstancesInput{
 		InstanceIds: []*string{aws.String(bastionID)},
-	}
-	result, err := ec2Svc.DescribeInstances(descInput)
-	require.NoError(t, err)
-
-	state := *result.Reservations[0].Instances[0].State.Name
-	assert.Equal(t, "stopped", state)
+	}))
 
 	// Simulate recovery by starting the instance
 	t.Log("Simulating bastion recovery...")
@@ -66,20 +66,10 @@ func TestChaosBastionFailure(t *testing.T) {
 		InstanceIds: []*string{aws.String(bastionID)},
 	})
 	require.NoError(t, err)
+	require.NoError(t, testkit.WaitForInstanceStatusOk(ec2Svc, bastionID, 10*time.Minute))
 
-	// Wait for instance to start
-	time.Sleep(60 * time.Second)
-
-	// Verify bastion is running again
-	result, err = ec2Svc.DescribeInstances(descInput)
-	require.NoError(t, err)
-
-	state = *result.Reservations[0].Instances[0].State.Name
-	assert.Equal(t, "running", state)
-
-	// Verify bastion public IP is accessible
-	bastionPublicIP := terraform.Output(t, terraformOptions, "bastion_public_ip")
-	assert.NotEmpty(t, bastionPublicIP)
+	// The same EIP should still front the bastion, not a fresh ephemeral address
+	assertEIPStable(t, ec2Svc, before)
 }
 
 func TestChaosNetworkIsolation(t *testing.T) {
@@ -255,8 +245,10 @@ func TestChaosResourceLimits(t *testing.T) {
 	_, err = ec2Svc.StartInstances(startInput)
 	require.NoError(t, err)
 
-	// Wait for instances to start
-	time.Sleep(60 * time.Second)
+	// Recovered means both instances pass their status checks, not just running
+	for _, instanceID := range []string{bastionID, privateInstanceID} {
+		require.NoError(t, testkit.WaitForInstanceStatusOk(ec2Svc, instanceID, 10*time.Minute))
+	}
 
 	// Verify instances are running again
 	result, err = ec2Svc.DescribeInstances(descInput)
@@ -306,3 +298,98 @@ func TestChaosMonitoringDisruption(t *testing.T) {
 
 	t.Log("Monitoring disruption test completed - components verified")
 }
+
+// eipAssociation records which instance an Elastic IP is attached to
+type eipAssociation struct {
+	AllocationID  string
+	AssociationID string
+	PublicIP      string
+	InstanceID    string
+}
+
+func TestEIPStabilityProblems(t *testing.T) {
+	t.Parallel()
+
+	before := eipAssociation{AllocationID: "eipalloc-1", AssociationID: "eipassoc-1", PublicIP: "203.0.113.10", InstanceID: "i-bastion"}
+	address := &ec2.Address{
+		AllocationId:  aws.String("eipalloc-1"),
+		AssociationId: aws.String("eipassoc-1"),
+		PublicIp:      aws.String("203.0.113.10"),
+		InstanceId:    aws.String("i-bastion"),
+	}
+	instance := &ec2.Instance{InstanceId: aws.String("i-bastion"), PublicIpAddress: aws.String("203.0.113.10")}
+	assert.Empty(t, eipStabilityProblems(before, address, instance))
+
+	// A disassociated EIP leaves the restarted bastion on an ephemeral address
+	address.AssociationId = nil
+	address.InstanceId = nil
+	instance.PublicIpAddress = aws.String("198.51.100.7")
+	assert.Equal(t, []string{
+		"EIP eipalloc-1 is no longer associated with i-bastion",
+		"instance i-bastion has public IP 198.51.100.7, not its EIP 203.0.113.10",
+	}, eipStabilityProblems(before, address, instance))
+
+	address.AssociationId = aws.String("eipassoc-2")
+	address.InstanceId = aws.String("i-other")
+	instance.PublicIpAddress = aws.String("203.0.113.10")
+	assert.Equal(t, []string{
+		"EIP eipalloc-1 moved from i-bastion to i-other",
+		"EIP eipalloc-1 association changed from eipassoc-1 to eipassoc-2",
+	}, eipStabilityProblems(before, address, instance))
+}
+
+// Helper function to look up an Elastic IP's current association
+func recordEIPAssociation(t *testing.T, ec2Svc ec2iface.EC2API, allocationID string) eipAssociation {
+	address := describeEIP(t, ec2Svc, allocationID)
+	return eipAssociation{
+		AllocationID:  allocationID,
+		AssociationID: aws.StringValue(address.AssociationId),
+		PublicIP:      aws.StringValue(address.PublicIp),
+		InstanceID:    aws.StringValue(address.InstanceId),
+	}
+}
+
+// Helper function to assert an Elastic IP is still associated exactly as
+// recorded and the instance is reachable on it
+func assertEIPStable(t *testing.T, ec2Svc ec2iface.EC2API, before eipAssociation) {
+	address := describeEIP(t, ec2Svc, before.AllocationID)
+	result, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(before.InstanceID)},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Reservations, 1)
+	require.Len(t, result.Reservations[0].Instances, 1)
+
+	for _, problem := range eipStabilityProblems(before, address, result.Reservations[0].Instances[0]) {
+		assert.Fail(t, fmt.Sprintf("EIP %s did not survive the restart", before.PublicIP), problem)
+	}
+}
+
+// Helper function to describe one Elastic IP by allocation ID
+func describeEIP(t *testing.T, ec2Svc ec2iface.EC2API, allocationID string) *ec2.Address {
+	result, err := ec2Svc.DescribeAddresses(&ec2.DescribeAddressesInput{
+		AllocationIds: []*string{aws.String(allocationID)},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+	return result.Addresses[0]
+}
+
+// Helper function to list how an Elastic IP and its instance differ from the
+// association recorded before a disruption
+func eipStabilityProblems(before eipAssociation, address *ec2.Address, instance *ec2.Instance) []string {
+	var problems []string
+	switch instanceID := aws.StringValue(address.InstanceId); {
+	case instanceID == "":
+		problems = append(problems, fmt.Sprintf("EIP %s is no longer associated with %s", before.AllocationID, before.InstanceID))
+	case instanceID != before.InstanceID:
+		problems = append(problems, fmt.Sprintf("EIP %s moved from %s to %s", before.AllocationID, before.InstanceID, instanceID))
+	}
+	if associationID := aws.StringValue(address.AssociationId); associationID != "" && associationID != before.AssociationID {
+		problems = append(problems, fmt.Sprintf("EIP %s association changed from %s to %s", before.AllocationID, before.AssociationID, associationID))
+	}
+	if publicIP := aws.StringValue(instance.PublicIpAddress); publicIP != before.PublicIP {
+		problems = append(problems, fmt.Sprintf("instance %s has public IP %s, not its EIP %s", aws.StringValue(instance.InstanceId), publicIP, before.PublicIP))
+	}
+	return problems
+}
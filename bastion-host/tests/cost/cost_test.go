@@ -1,20 +1,29 @@
 package test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestBastionCostOptimizationInstanceSizing(t *testing.T) {
 	t.Parallel()
 
+	// The smallest allowed size, so the apply also proves the variable is honoured
+	instanceType := "t3.nano"
+
 	terraformOptions := &terraform.Options{
 		TerraformDir: "../../",
 		Vars: map[string]interface{}{
@@ -26,6 +35,7 @@ func TestBastionCostOptimizationInstanceSizing(t *testing.T) {
 			"key_name":             "cost-test-key",
 			"public_key":           "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc cost-test",
 			"allowed_ssh_cidrs":    []string{"10.0.0.0/8"},
+			"instance_type":        instanceType,
 		},
 	}
 
@@ -37,8 +47,8 @@ func TestBastionCostOptimizationInstanceSizing(t *testing.T) {
 	privateInstanceType := terraform.Output(t, terraformOptions, "private_instance_type")
 
 	// Assert cost-effective instance types
-	assert.Equal(t, "t3.micro", bastionInstanceType, "Bastion should use cost-effective t3.micro instance")
-	assert.Equal(t, "t3.micro", privateInstanceType, "Private instance should use cost-effective t3.micro instance")
+	assert.Equal(t, instanceType, bastionInstanceType, "Bastion should use the requested instance type")
+	assert.Equal(t, instanceType, privateInstanceType, "Private instance should use the requested instance type")
 
 	// Verify instances are using gp3 volumes (more cost-effective than gp2)
 	bastionVolumeType := terraform.Output(t, terraformOptions, "bastion_volume_type")
@@ -133,14 +143,21 @@ func TestBastionCostOptimizationResourceUtilization(t *testing.T) {
 func TestBastionCostOptimizationUnusedResources(t *testing.T) {
 	t.Parallel()
 
+	// A unique environment keeps EIPs from other parallel cost tests out of the count
+	environment := fmt.Sprintf("cost-eip-%s", strings.ToLower(random.UniqueId()))
+	azs := []string{"us-east-1a"}
+	enableNatGateway, singleNatGateway := false, false
+
 	terraformOptions := &terraform.Options{
 		TerraformDir: "../../",
 		Vars: map[string]interface{}{
-			"environment":          "cost-test",
+			"environment":          environment,
 			"vpc_cidr":             "172.16.0.0/16",
-			"azs":                  []string{"us-east-1a"},
+			"azs":                  azs,
 			"public_subnet_cidrs":  []string{"172.16.1.0/24"},
 			"private_subnet_cidrs": []string{"172.16.10.0/24"},
+			"enable_nat_gateway":   enableNatGateway,
+			"single_nat_gateway":   singleNatGateway,
 			"key_name":             "cost-test-key",
 			"public_key":           "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc cost-test",
 			"allowed_ssh_cidrs":    []string{"10.0.0.0/8"},
@@ -150,13 +167,22 @@ func TestBastionCostOptimizationUnusedResources(t *testing.T) {
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Verify no unused Elastic IPs (bastion should use EIP efficiently)
-	bastionEIP := terraform.Output(t, terraformOptions, "bastion_elastic_ip")
+	// Verify no unused Elastic IPs: one per NAT gateway plus the bastion's
+	bastionEIP := terraform.Output(t, terraformOptions, "bastion_eip_allocation_id")
 	assert.NotEmpty(t, bastionEIP, "Bastion should have an EIP for accessibility")
 
-	// Verify NAT Gateway exists but is used efficiently
-	natGatewayID := terraform.Output(t, terraformOptions, "nat_gateway_id")
-	assert.NotEmpty(t, natGatewayID, "NAT Gateway should exist for private subnet egress")
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	natMode := testkit.NatGatewayMode(enableNatGateway, singleNatGateway)
+	expectedEIPs, err := testkit.ExpectedEIPCount(natMode, len(azs), bastionEIP != "")
+	require.NoError(t, err)
+	testkit.AssertEIPCount(t, ec2.New(sess), environment, expectedEIPs)
+
+	// NAT gateways are opt-in; by default the private subnet only reaches AWS
+	// through the VPC endpoints
+	natGatewayIDs := terraform.OutputList(t, terraformOptions, "nat_gateway_ids")
+	assert.Empty(t, natGatewayIDs, "No NAT gateway should be created unless enable_nat_gateway is set")
 
 	// Verify VPC Endpoints are configured for cost-effective AWS service access
 	vpcEndpointCount := terraform.Output(t, terraformOptions, "vpc_endpoint_count")
@@ -213,18 +239,24 @@ func TestBastionCostOptimizationMonitoringCosts(t *testing.T) {
 			"key_name":             "cost-test-key",
 			"public_key":           "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc cost-test",
 			"allowed_ssh_cidrs":    []string{"10.0.0.0/8"},
+			// Optional log groups are switched on so their retention is checked too
+			"enable_cloudtrail_alarms": true,
 		},
 	}
 
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Verify detailed monitoring is enabled for cost-effective operations
-	bastionMonitoring := terraform.Output(t, terraformOptions, "bastion_monitoring")
-	privateMonitoring := terraform.Output(t, terraformOptions, "private_instance_monitoring")
+	// Detailed monitoring should match the configured setting
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
 
-	assert.Equal(t, "true", bastionMonitoring, "Bastion detailed monitoring should be enabled")
-	assert.Equal(t, "true", privateMonitoring, "Private instance detailed monitoring should be enabled")
+	expectedState, err := testkit.MonitoringStateFor(terraform.Output(t, terraformOptions, "detailed_monitoring"))
+	require.NoError(t, err)
+	testkit.AssertInstanceMonitoring(t, ec2Svc, terraform.Output(t, terraformOptions, "bastion_instance_id"), expectedState)
+	testkit.AssertInstanceMonitoring(t, ec2Svc, terraform.Output(t, terraformOptions, "private_instance_id"), expectedState)
 
 	// Verify CloudWatch log retention is reasonable
 	bastionLogRetention := terraform.Output(t, terraformOptions, "bastion_log_retention_days")
@@ -237,6 +269,8 @@ func TestBastionCostOptimizationMonitoringCosts(t *testing.T) {
 	// Verify SNS topic exists for alerts (cost-effective notification system)
 	snsTopicArn := terraform.Output(t, terraformOptions, "sns_topic_arn")
 	assert.NotEmpty(t, snsTopicArn, "SNS topic should exist for cost-effective alerting")
+
+	testkit.AssertLogGroupRetention(t, terraform.Show(t, terraformOptions), testkit.LogsClient)
 }
 
 func TestBastionCostOptimizationDataTransfer(t *testing.T) {
@@ -334,9 +368,94 @@ func TestBastionCostOptimizationSpotInstances(t *testing.T) {
 	bastionTenancy := terraform.Output(t, terraformOptions, "bastion_tenancy")
 	assert.Equal(t, "default", bastionTenancy, "Default tenancy allows Spot Instance usage")
 
+	// Guard on the tenancy EC2 placed the instances with, not the variable echoed back
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	result, err := ec2.New(sess).DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{
+			terraform.Output(t, terraformOptions, "bastion_instance_id"),
+			terraform.Output(t, terraformOptions, "private_instance_id"),
+		}),
+	})
+	require.NoError(t, err)
+	placed := 0
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			require.NotNil(t, instance.Placement, "Instance %s placement", aws.StringValue(instance.InstanceId))
+			assertTenancyCostGuard(t, terraformOptions.Vars["environment"].(string), aws.StringValue(instance.Placement.Tenancy))
+			placed++
+		}
+	}
+	assert.Equal(t, 2, placed, "Both instances should be described")
+
 	// Check if instances are in same AZ (important for Spot strategy)
 	bastionAZ := terraform.Output(t, terraformOptions, "bastion_availability_zone")
 	privateAZ := terraform.Output(t, terraformOptions, "private_instance_availability_zone")
 
 	assert.Equal(t, bastionAZ, privateAZ, "Instances in same AZ optimize Spot Instance strategy")
 }
+
+func TestTenancyCostProblem(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, tenancyCostProblem("cost-test", "default"))
+	assert.Empty(t, tenancyCostProblem("prod", "dedicated"), "Dedicated tenancy is a deliberate choice outside the cost tests")
+	assert.Equal(t, "dedicated tenancy in the cost-test environment adds a regional dedicated fee on top of the instances",
+		tenancyCostProblem("cost-test", "dedicated"))
+}
+
+// TestDetailedMonitoringToggle applies with detailed monitoring on, then off,
+// and checks EC2 reports each state on both instances
+func TestDetailedMonitoringToggle(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":          "cost-test",
+			"vpc_cidr":             "172.16.0.0/16",
+			"azs":                  []string{"us-east-1a"},
+			"public_subnet_cidrs":  []string{"172.16.1.0/24"},
+			"private_subnet_cidrs": []string{"172.16.10.0/24"},
+			"key_name":             "cost-test-key",
+			"public_key":           "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc cost-test",
+			"allowed_ssh_cidrs":    []string{"10.0.0.0/8"},
+			"detailed_monitoring":  true,
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+
+	// Turning monitoring off is an in-place update, so one stack covers both states
+	for _, enabled := range []bool{true, false} {
+		terraformOptions.Vars["detailed_monitoring"] = enabled
+		terraform.InitAndApply(t, terraformOptions)
+
+		expectedState, err := testkit.MonitoringStateFor(terraform.Output(t, terraformOptions, "detailed_monitoring"))
+		require.NoError(t, err)
+		testkit.AssertInstanceMonitoring(t, ec2Svc, terraform.Output(t, terraformOptions, "bastion_instance_id"), expectedState)
+		testkit.AssertInstanceMonitoring(t, ec2Svc, terraform.Output(t, terraformOptions, "private_instance_id"), expectedState)
+	}
+}
+
+// Helper function to fail when the cost tests run on dedicated tenancy, whose
+// hourly regional fee would dwarf everything else they measure
+func assertTenancyCostGuard(t *testing.T, environment, tenancy string) {
+	if problem := tenancyCostProblem(environment, tenancy); problem != "" {
+		assert.Fail(t, "Tenancy breaks the cost budget", problem)
+	}
+}
+
+// Helper function to describe why a tenancy doesn't belong in an environment
+func tenancyCostProblem(environment, tenancy string) string {
+	if environment == "cost-test" && tenancy == "dedicated" {
+		return "dedicated tenancy in the cost-test environment adds a regional dedicated fee on top of the instances"
+	}
+	return ""
+}
@@ -1,10 +1,24 @@
 package integration
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"bastion-host-tests/testutil"
 )
 
 func TestFullBastionDeployment(t *testing.T) {
@@ -57,11 +71,81 @@ func TestFullBastionDeployment(t *testing.T) {
 	// Test private instance creation
 	privateInstanceIp := terraform.Output(t, terraformOptions, "private_instance_ip")
 	assert.NotEmpty(t, privateInstanceIp)
+
+	// Test each instance landed in its tier's subnets
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+	bastionId := terraform.Output(t, terraformOptions, "bastion_instance_id")
+	privateInstanceId := terraform.Output(t, terraformOptions, "private_instance_id")
+	assertInstancesInSubnets(t, ec2Svc, []string{bastionId}, publicSubnetIds)
+	assertInstancesInSubnets(t, ec2Svc, []string{privateInstanceId}, privateSubnetIds)
+}
+
+func TestInstanceSubnetMismatches(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeInstanceClient{subnets: map[string]string{
+		"i-bastion": "subnet-public-a",
+		"i-app-a":   "subnet-private-a",
+		"i-app-b":   "subnet-private-b",
+	}}
+
+	// HA placements may use any subnet of the tier
+	mismatches, err := instanceSubnetMismatches(svc, []string{"i-app-a", "i-app-b"}, []string{"subnet-private-a", "subnet-private-b"})
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+	assert.Equal(t, 1, svc.calls, "All instances should be resolved in one call")
+
+	mismatches, err = instanceSubnetMismatches(svc, []string{"i-bastion", "i-app-a"}, []string{"subnet-private-a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-bastion is in subnet-public-a, want one of [subnet-private-a]"}, mismatches)
+
+	mismatches, err = instanceSubnetMismatches(svc, []string{"i-missing"}, []string{"subnet-public-a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-missing was not found"}, mismatches)
+
+	_, err = instanceSubnetMismatches(svc, []string{"i-bastion"}, nil)
+	assert.Error(t, err, "An empty expectation would hide misplaced instances")
+}
+
+type fakeInstanceClient struct {
+	ec2iface.EC2API
+	subnets map[string]string
+	calls   int
 }
 
+func (f *fakeInstanceClient) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.calls++
+	reservation := &ec2.Reservation{}
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		if subnet, ok := f.subnets[id]; ok {
+			reservation.Instances = append(reservation.Instances, &ec2.Instance{
+				InstanceId: aws.String(id),
+				SubnetId:   aws.String(subnet),
+			})
+		}
+	}
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation}}, nil
+}
+
+// TestBastionConnectivity logs in to the private instance through the bastion
+// the way ssh -J would, and checks the test runner can't skip the bastion.
+// The key pair is read from BASTION_SSH_PRIVATE_KEY_PATH; set
+// BASTION_SSH_DIAL_TIMEOUT (a Go duration) to change how long each hop may take.
 func TestBastionConnectivity(t *testing.T) {
 	t.Parallel()
 
+	keyPath := os.Getenv("BASTION_SSH_PRIVATE_KEY_PATH")
+	if keyPath == "" {
+		t.Skip("Set BASTION_SSH_PRIVATE_KEY_PATH to an unencrypted private key to test SSH through the bastion")
+	}
+	signer, err := testutil.LoadSSHSigner(keyPath)
+	require.NoError(t, err)
+	dialTimeout, err := sshDialTimeout(os.Getenv("BASTION_SSH_DIAL_TIMEOUT"))
+	require.NoError(t, err)
+
 	terraformOptions := &terraform.Options{
 		TerraformDir: "../..",
 		Vars: map[string]interface{}{
@@ -71,8 +155,8 @@ func TestBastionConnectivity(t *testing.T) {
 			"public_subnet_cidrs":  []string{"10.1.1.0/24"},
 			"private_subnet_cidrs": []string{"10.1.10.0/24"},
 			"key_name":             "test-connectivity-key",
-			"public_key":           "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsHjvqFs7u1J4QJzB8K3nQqJc7fW4HqQ test@example.com",
-			"allowed_ssh_cidrs":    []string{"203.0.113.0/24"},
+			"public_key":           strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))),
+			"allowed_ssh_cidrs":    []string{testutil.RunnerCIDR(t)},
 			"environment":          "test",
 		},
 	}
@@ -80,18 +164,41 @@ func TestBastionConnectivity(t *testing.T) {
 	defer terraform.Destroy(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Verify all components are created and accessible
 	bastionPublicIp := terraform.Output(t, terraformOptions, "bastion_public_ip")
 	privateInstanceIp := terraform.Output(t, terraformOptions, "private_instance_ip")
+	bastionAddr := net.JoinHostPort(bastionPublicIp, "22")
+	privateAddr := net.JoinHostPort(privateInstanceIp, "22")
 
-	assert.NotEmpty(t, bastionPublicIp)
-	assert.NotEmpty(t, privateInstanceIp)
+	config := testutil.SSHConfig{User: "ec2-user", Signer: signer, DialTimeout: dialTimeout}
+
+	// sshd on both instances takes a little while after they report running
+	output := retry.DoWithRetry(t, "Running uname through the bastion", 10, 30*time.Second, func() (string, error) {
+		client, err := testutil.DialThroughBastion(bastionAddr, privateAddr, config)
+		if err != nil {
+			return "", err
+		}
+		defer client.Close()
+		return testutil.RunCommand(client.Client, "uname -a")
+	})
+	assert.NotEmpty(t, strings.TrimSpace(output))
+	assert.Contains(t, output, "Linux")
+}
 
-	// In a real integration test, you would:
-	// 1. SSH to bastion host
-	// 2. From bastion, SSH to private instance
-	// 3. Verify network connectivity
-	// 4. Test security group rules
+func TestSSHDialTimeout(t *testing.T) {
+	t.Parallel()
+
+	timeout, err := sshDialTimeout("")
+	require.NoError(t, err)
+	assert.Equal(t, testutil.DefaultSSHDialTimeout, timeout)
+
+	timeout, err = sshDialTimeout("45s")
+	require.NoError(t, err)
+	assert.Equal(t, 45*time.Second, timeout)
+
+	_, err = sshDialTimeout("soon")
+	assert.Error(t, err)
+	_, err = sshDialTimeout("-5s")
+	assert.Error(t, err)
 }
 
 func TestBastionSecurityConfiguration(t *testing.T) {
@@ -126,3 +233,66 @@ func TestBastionSecurityConfiguration(t *testing.T) {
 	// 4. Encryption is enabled on volumes
 	// 5. IAM roles have minimal permissions
 }
+
+// Helper function to assert every instance sits in one of the expected subnets.
+// Pass all of a tier's subnet ids when instances may be spread across AZs.
+func assertInstancesInSubnets(t *testing.T, ec2Svc ec2iface.EC2API, instanceIds []string, subnetIds []string) {
+	mismatches, err := instanceSubnetMismatches(ec2Svc, instanceIds, subnetIds)
+	require.NoError(t, err)
+	for _, mismatch := range mismatches {
+		assert.Fail(t, "Instance in unexpected subnet", mismatch)
+	}
+}
+
+// Helper function to list instances that are missing or outside the expected subnets
+func instanceSubnetMismatches(ec2Svc ec2iface.EC2API, instanceIds []string, subnetIds []string) ([]string, error) {
+	if len(subnetIds) == 0 {
+		return nil, fmt.Errorf("no expected subnets given for %v", instanceIds)
+	}
+
+	result, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice(instanceIds),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	actual := map[string]string{}
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			actual[aws.StringValue(instance.InstanceId)] = aws.StringValue(instance.SubnetId)
+		}
+	}
+
+	expected := map[string]bool{}
+	for _, id := range subnetIds {
+		expected[id] = true
+	}
+
+	var mismatches []string
+	for _, id := range instanceIds {
+		subnet, ok := actual[id]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%s was not found", id))
+		case !expected[subnet]:
+			mismatches = append(mismatches, fmt.Sprintf("%s is in %s, want one of %v", id, subnet, subnetIds))
+		}
+	}
+	return mismatches, nil
+}
+
+// Helper function to parse a dial timeout setting, defaulting when it's unset
+func sshDialTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return testutil.DefaultSSHDialTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("SSH dial timeout must be positive, got %s", value)
+	}
+	return timeout, nil
+}
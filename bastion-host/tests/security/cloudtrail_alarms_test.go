@@ -0,0 +1,267 @@
+package security
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Sample CloudTrail events each alarm's metric filter must match
+var cloudTrailAlarmEvents = map[string]string{
+	"root_login":             `{"eventVersion":"1.08","userIdentity":{"type":"Root","arn":"arn:aws:iam::123456789012:root"},"eventSource":"signin.amazonaws.com","eventName":"ConsoleLogin","eventType":"AwsConsoleSignIn"}`,
+	"iam_policy_changes":     `{"eventVersion":"1.08","userIdentity":{"type":"IAMUser"},"eventSource":"iam.amazonaws.com","eventName":"PutRolePolicy","eventType":"AwsApiCall"}`,
+	"security_group_changes": `{"eventVersion":"1.08","userIdentity":{"type":"IAMUser"},"eventSource":"ec2.amazonaws.com","eventName":"AuthorizeSecurityGroupIngress","eventType":"AwsApiCall"}`,
+}
+
+// TestCloudTrailAlarms makes a harmless security group change and waits for
+// CloudTrail to deliver it, the metric filter to count it and the alarm to fire
+func TestCloudTrailAlarms(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../..",
+		Vars: map[string]interface{}{
+			"region":                   "us-east-1",
+			"vpc_cidr":                 "10.9.0.0/16",
+			"azs":                      []string{"us-east-1a"},
+			"public_subnet_cidrs":      []string{"10.9.1.0/24"},
+			"private_subnet_cidrs":     []string{"10.9.10.0/24"},
+			"key_name":                 "test-trail-alarms-key",
+			"public_key":               "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsHjvqFs7u1J4QJzB8K3nQqJc7fW4HqQ test@example.com",
+			"allowed_ssh_cidrs":        []string{"203.0.113.0/24"},
+			"environment":              "test",
+			"enable_cloudtrail_alarms": true,
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	alarmNames := terraform.OutputMap(t, terraformOptions, "cloudtrail_alarm_names")
+	logGroupName := terraform.Output(t, terraformOptions, "cloudtrail_log_group_name")
+	topicArn := terraform.Output(t, terraformOptions, "alarm_topic_arn")
+	require.Len(t, alarmNames, len(cloudTrailAlarmEvents))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	cloudwatchSvc := cloudwatch.New(sess)
+	logsSvc := cloudwatchlogs.New(sess)
+
+	filters, err := logsSvc.DescribeMetricFilters(&cloudwatchlogs.DescribeMetricFiltersInput{
+		LogGroupName: aws.String(logGroupName),
+	})
+	require.NoError(t, err)
+	alarms, err := cloudwatchSvc.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{
+		AlarmNames: aws.StringSlice(mapValues(alarmNames)),
+	})
+	require.NoError(t, err)
+	require.Len(t, alarms.MetricAlarms, len(alarmNames))
+
+	// Each alarm must watch a filter on the trail's log group that matches its event
+	alarmsByKey := map[string]*cloudwatch.MetricAlarm{}
+	for key, name := range alarmNames {
+		for _, alarm := range alarms.MetricAlarms {
+			if aws.StringValue(alarm.AlarmName) == name {
+				alarmsByKey[key] = alarm
+			}
+		}
+		alarm := alarmsByKey[key]
+		require.NotNil(t, alarm, "Alarm %s should exist", name)
+
+		filter := alarmMetricFilter(alarm, filters.MetricFilters)
+		require.NotNil(t, filter, "Alarm %s should watch a metric filter on %s", name, logGroupName)
+		for _, problem := range alarmProblems(alarm) {
+			assert.Fail(t, fmt.Sprintf("Alarm %s misconfigured", name), problem)
+		}
+		assert.Contains(t, aws.StringValueSlice(alarm.AlarmActions), topicArn, "Alarm %s should notify the alerts topic", name)
+
+		matches, err := logsSvc.TestMetricFilter(&cloudwatchlogs.TestMetricFilterInput{
+			FilterPattern:    filter.FilterPattern,
+			LogEventMessages: aws.StringSlice([]string{cloudTrailAlarmEvents[key]}),
+		})
+		require.NoError(t, err)
+		assert.Len(t, matches.Matches, 1, "Filter %s should match a sample %s event", aws.StringValue(filter.FilterName), key)
+	}
+
+	// Authorizing and revoking a documentation-range rule changes nothing reachable
+	ec2Svc := ec2.New(sess)
+	groupID := terraform.Output(t, terraformOptions, "private_security_group_id")
+	permissions := []*ec2.IpPermission{{
+		IpProtocol: aws.String("tcp"),
+		FromPort:   aws.Int64(443),
+		ToPort:     aws.Int64(443),
+		IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("192.0.2.10/32"), Description: aws.String("CloudTrail alarm test")}},
+	}}
+	changedAt := time.Now()
+	_, err = ec2Svc.AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       aws.String(groupID),
+		IpPermissions: permissions,
+	})
+	require.NoError(t, err)
+	_, err = ec2Svc.RevokeSecurityGroupIngress(&ec2.RevokeSecurityGroupIngressInput{
+		GroupId:       aws.String(groupID),
+		IpPermissions: permissions,
+	})
+	require.NoError(t, err)
+
+	// CloudTrail takes up to 15 minutes to deliver to CloudWatch Logs
+	sgAlarm := alarmsByKey["security_group_changes"]
+	waitForMetricSum(t, cloudwatchSvc, aws.StringValue(sgAlarm.Namespace), aws.StringValue(sgAlarm.MetricName), changedAt, 1, 20, time.Minute)
+
+	retry.DoWithRetry(t, "Waiting for "+aws.StringValue(sgAlarm.AlarmName)+" to fire", 10, 30*time.Second, func() (string, error) {
+		out, err := cloudwatchSvc.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{
+			AlarmNames: []*string{sgAlarm.AlarmName},
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(out.MetricAlarms) != 1 {
+			return "", retry.FatalError{Underlying: fmt.Errorf("alarm %s not found", aws.StringValue(sgAlarm.AlarmName))}
+		}
+		if state := aws.StringValue(out.MetricAlarms[0].StateValue); state != cloudwatch.StateValueAlarm {
+			return "", fmt.Errorf("alarm %s is %s", aws.StringValue(sgAlarm.AlarmName), state)
+		}
+		return "", nil
+	})
+}
+
+func TestAlarmProblems(t *testing.T) {
+	t.Parallel()
+
+	alarm := &cloudwatch.MetricAlarm{
+		Namespace:          aws.String("BastionHost/CloudTrail"),
+		MetricName:         aws.String("SecurityGroupChangeCount"),
+		Statistic:          aws.String(cloudwatch.StatisticSum),
+		ComparisonOperator: aws.String(cloudwatch.ComparisonOperatorGreaterThanOrEqualToThreshold),
+		Threshold:          aws.Float64(1),
+		TreatMissingData:   aws.String("notBreaching"),
+		AlarmActions:       aws.StringSlice([]string{"arn:aws:sns:us-east-1:123456789012:bastion-security-alerts-test"}),
+	}
+	assert.Empty(t, alarmProblems(alarm))
+
+	filters := []*cloudwatchlogs.MetricFilter{
+		{FilterName: aws.String("other"), MetricTransformations: []*cloudwatchlogs.MetricTransformation{{MetricNamespace: aws.String("BastionHost/CloudTrail"), MetricName: aws.String("RootLoginCount")}}},
+		{FilterName: aws.String("sg"), MetricTransformations: []*cloudwatchlogs.MetricTransformation{{MetricNamespace: aws.String("BastionHost/CloudTrail"), MetricName: aws.String("SecurityGroupChangeCount")}}},
+	}
+	assert.Equal(t, "sg", aws.StringValue(alarmMetricFilter(alarm, filters).FilterName))
+	assert.Nil(t, alarmMetricFilter(alarm, filters[:1]))
+
+	alarm.Statistic = aws.String(cloudwatch.StatisticAverage)
+	alarm.Threshold = aws.Float64(5)
+	alarm.TreatMissingData = aws.String("breaching")
+	alarm.AlarmActions = nil
+	assert.Equal(t, []string{
+		"statistic is Average, not Sum",
+		"fires at GreaterThanOrEqualToThreshold 5, so a single event goes unnoticed",
+		"treats missing data as breaching",
+		"notifies no one when it fires",
+	}, alarmProblems(alarm))
+}
+
+func TestWaitForMetricSum(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := &fakeMetricClient{pages: [][]*cloudwatch.Datapoint{
+		nil,
+		{{Sum: aws.Float64(1)}, {Sum: aws.Float64(1)}},
+	}}
+	waitForMetricSum(t, svc, "BastionHost/CloudTrail", "SecurityGroupChangeCount", since, 2, 3, time.Millisecond)
+	assert.Equal(t, 2, svc.calls, "Polling should stop once the sum is reached")
+	assert.Equal(t, since.Add(-time.Minute), aws.TimeValue(svc.input.StartTime), "The window should start just before the change")
+	assert.Equal(t, "SecurityGroupChangeCount", aws.StringValue(svc.input.MetricName))
+}
+
+type fakeMetricClient struct {
+	cloudwatchiface.CloudWatchAPI
+	pages [][]*cloudwatch.Datapoint
+	calls int
+	input *cloudwatch.GetMetricStatisticsInput
+}
+
+func (f *fakeMetricClient) GetMetricStatistics(input *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	f.input = input
+	page := f.pages[f.calls]
+	f.calls++
+	return &cloudwatch.GetMetricStatisticsOutput{Datapoints: page}, nil
+}
+
+// Helper function to poll a metric until its Sum since a point in time reaches a minimum
+func waitForMetricSum(t *testing.T, cloudwatchSvc cloudwatchiface.CloudWatchAPI, namespace, metricName string, since time.Time, minimum float64, maxRetries int, sleep time.Duration) {
+	retry.DoWithRetry(t, fmt.Sprintf("Waiting for %s/%s to reach %v", namespace, metricName, minimum), maxRetries, sleep, func() (string, error) {
+		out, err := cloudwatchSvc.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String(namespace),
+			MetricName: aws.String(metricName),
+			StartTime:  aws.Time(since.Add(-time.Minute)),
+			EndTime:    aws.Time(time.Now().Add(time.Minute)),
+			Period:     aws.Int64(60),
+			Statistics: aws.StringSlice([]string{cloudwatch.StatisticSum}),
+		})
+		if err != nil {
+			return "", err
+		}
+		sum := 0.0
+		for _, datapoint := range out.Datapoints {
+			sum += aws.Float64Value(datapoint.Sum)
+		}
+		if sum < minimum {
+			return "", fmt.Errorf("%s/%s sum is %v", namespace, metricName, sum)
+		}
+		return "", nil
+	})
+}
+
+// Helper function to find the metric filter that publishes an alarm's metric
+func alarmMetricFilter(alarm *cloudwatch.MetricAlarm, filters []*cloudwatchlogs.MetricFilter) *cloudwatchlogs.MetricFilter {
+	for _, filter := range filters {
+		for _, transformation := range filter.MetricTransformations {
+			if aws.StringValue(transformation.MetricNamespace) == aws.StringValue(alarm.Namespace) &&
+				aws.StringValue(transformation.MetricName) == aws.StringValue(alarm.MetricName) {
+				return filter
+			}
+		}
+	}
+	return nil
+}
+
+// Helper function to list what would keep an alarm from firing on a single event
+func alarmProblems(alarm *cloudwatch.MetricAlarm) []string {
+	var problems []string
+	if statistic := aws.StringValue(alarm.Statistic); statistic != cloudwatch.StatisticSum {
+		problems = append(problems, fmt.Sprintf("statistic is %s, not Sum", statistic))
+	}
+	operator := aws.StringValue(alarm.ComparisonOperator)
+	threshold := aws.Float64Value(alarm.Threshold)
+	if !(operator == cloudwatch.ComparisonOperatorGreaterThanOrEqualToThreshold && threshold <= 1) &&
+		!(operator == cloudwatch.ComparisonOperatorGreaterThanThreshold && threshold < 1) {
+		problems = append(problems, fmt.Sprintf("fires at %s %v, so a single event goes unnoticed", operator, threshold))
+	}
+	if missing := aws.StringValue(alarm.TreatMissingData); missing == "breaching" {
+		problems = append(problems, "treats missing data as breaching")
+	}
+	if len(alarm.AlarmActions) == 0 {
+		problems = append(problems, "notifies no one when it fires")
+	}
+	return problems
+}
+
+// Helper function to list a string map's values
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,46 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"testkit"
+)
+
+// TestRoleTrust turns on the CloudTrail alarms, which bring their own role,
+// and checks every role can only be assumed by the service that uses it
+func TestRoleTrust(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../..",
+		Vars: map[string]interface{}{
+			"region":                   "us-east-1",
+			"vpc_cidr":                 "10.11.0.0/16",
+			"azs":                      []string{"us-east-1a"},
+			"public_subnet_cidrs":      []string{"10.11.1.0/24"},
+			"private_subnet_cidrs":     []string{"10.11.10.0/24"},
+			"key_name":                 "test-trust-key",
+			"public_key":               "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsHjvqFs7u1J4QJzB8K3nQqJc7fW4HqQ test@example.com",
+			"allowed_ssh_cidrs":        []string{"203.0.113.0/24"},
+			"environment":              "test",
+			"enable_cloudtrail_alarms": true,
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertRoleTrust(t, terraform.Show(t, terraformOptions), iam.New(sess), map[string][]string{
+		"aws_iam_role.bastion_role":                 {"ec2.amazonaws.com"},
+		"aws_iam_role.cloudtrail_logs":              {"cloudtrail.amazonaws.com"},
+		"module.vpc.aws_iam_role.vpc_flow_log_role": {"vpc-flow-logs.amazonaws.com"},
+	})
+}
@@ -0,0 +1,192 @@
+package security
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bastion-host-tests/testutil"
+)
+
+// dialFunc matches net.DialTimeout so tests can swap in a fake network
+type dialFunc func(network, address string, timeout time.Duration) (net.Conn, error)
+
+// TestPrivateInstanceUnreachableFromInternet dials SSH on the bastion from the
+// test host, then checks the private instance has no public address and that
+// none of its security groups admit the internet. Its private address can't be
+// dialed from outside the VPC whatever its exposure, so that proves nothing.
+func TestPrivateInstanceUnreachableFromInternet(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../..",
+		Vars: map[string]interface{}{
+			"region":               "us-east-1",
+			"vpc_cidr":             "10.8.0.0/16",
+			"azs":                  []string{"us-east-1a"},
+			"public_subnet_cidrs":  []string{"10.8.1.0/24"},
+			"private_subnet_cidrs": []string{"10.8.10.0/24"},
+			"key_name":             "test-reachability-key",
+			"public_key":           "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsHjvqFs7u1J4QJzB8K3nQqJc7fW4HqQ test@example.com",
+			"allowed_ssh_cidrs":    []string{testutil.RunnerCIDR(t)},
+			"environment":          "test",
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	bastionPublicIP := terraform.Output(t, terraformOptions, "bastion_public_ip")
+	privateInstanceID := terraform.Output(t, terraformOptions, "private_instance_id")
+
+	// sshd takes a little while after the instance reports running
+	retry.DoWithRetry(t, "Dialing bastion SSH at "+bastionPublicIP, 10, 15*time.Second, func() (string, error) {
+		return "", dialSSH(net.DialTimeout, bastionPublicIP, 10*time.Second)
+	})
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+
+	result, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(privateInstanceID)},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Reservations, 1)
+	require.Len(t, result.Reservations[0].Instances, 1)
+	instance := result.Reservations[0].Instances[0]
+	assert.Nil(t, instance.PublicIpAddress, "Private instance should have no public IPv4 address")
+
+	var groupIDs []*string
+	for _, group := range instance.SecurityGroups {
+		groupIDs = append(groupIDs, group.GroupId)
+	}
+	require.NotEmpty(t, groupIDs)
+	groups, err := ec2Svc.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{GroupIds: groupIDs})
+	require.NoError(t, err)
+	for _, problem := range openIngressRules(groups.SecurityGroups) {
+		assert.Fail(t, "Private instance is open to the internet", problem)
+	}
+}
+
+func TestOpenIngressRules(t *testing.T) {
+	t.Parallel()
+
+	groups := []*ec2.SecurityGroup{
+		{
+			GroupId: aws.String("sg-private"),
+			IpPermissions: []*ec2.IpPermission{
+				{
+					IpProtocol:       aws.String("tcp"),
+					FromPort:         aws.Int64(22),
+					ToPort:           aws.Int64(22),
+					UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-bastion")}},
+				},
+				{
+					IpProtocol: aws.String("tcp"),
+					FromPort:   aws.Int64(443),
+					ToPort:     aws.Int64(443),
+					IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("10.8.0.0/16")}},
+				},
+			},
+			// Egress to anywhere doesn't let anyone in
+			IpPermissionsEgress: []*ec2.IpPermission{
+				{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+			},
+		},
+	}
+	assert.Empty(t, openIngressRules(groups))
+
+	groups = append(groups, &ec2.SecurityGroup{
+		GroupId: aws.String("sg-extra"),
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int64(22),
+				ToPort:     aws.Int64(22),
+				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+			},
+			{
+				IpProtocol: aws.String("-1"),
+				Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: aws.String("::/0")}},
+			},
+		},
+	})
+	assert.Equal(t, []string{
+		"sg-extra allows tcp 22-22 from 0.0.0.0/0",
+		"sg-extra allows -1 from ::/0",
+	}, openIngressRules(groups))
+}
+
+func TestDialSSH(t *testing.T) {
+	t.Parallel()
+
+	// A real listener stands in for the bastion's sshd
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	var dialed []string
+	address := listener.Addr().String()
+	loopbackDial := func(network, host string, timeout time.Duration) (net.Conn, error) {
+		dialed = append(dialed, network+" "+host)
+		return net.DialTimeout(network, address, timeout)
+	}
+	assert.NoError(t, dialSSH(loopbackDial, "127.0.0.1", time.Second), "An open port should accept the dial")
+	assert.Equal(t, []string{"tcp 127.0.0.1:22"}, dialed)
+
+	listener.Close()
+	assert.Error(t, dialSSH(loopbackDial, "127.0.0.1", time.Second), "A closed port should refuse the dial")
+}
+
+// Helper function to list ingress rules that admit any internet address
+func openIngressRules(groups []*ec2.SecurityGroup) []string {
+	var problems []string
+	for _, group := range groups {
+		for _, permission := range group.IpPermissions {
+			ports := aws.StringValue(permission.IpProtocol)
+			if permission.FromPort != nil {
+				ports = fmt.Sprintf("%s %d-%d", ports, aws.Int64Value(permission.FromPort), aws.Int64Value(permission.ToPort))
+			}
+			for _, ipRange := range permission.IpRanges {
+				if aws.StringValue(ipRange.CidrIp) == "0.0.0.0/0" {
+					problems = append(problems, fmt.Sprintf("%s allows %s from 0.0.0.0/0", aws.StringValue(group.GroupId), ports))
+				}
+			}
+			for _, ipRange := range permission.Ipv6Ranges {
+				if aws.StringValue(ipRange.CidrIpv6) == "::/0" {
+					problems = append(problems, fmt.Sprintf("%s allows %s from ::/0", aws.StringValue(group.GroupId), ports))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// Helper function to open and immediately close a TCP connection to a host's SSH port
+func dialSSH(dial dialFunc, host string, timeout time.Duration) error {
+	conn, err := dial("tcp", net.JoinHostPort(host, "22"), timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
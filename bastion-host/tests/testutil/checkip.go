@@ -0,0 +1,37 @@
+package testutil
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RunnerCIDR looks up the test runner's public IPv4 address as a /32, so the
+// bastion security group admits the test and nothing else
+func RunnerCIDR(t testing.TB) string {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get("https://checkip.amazonaws.com")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	cidr, err := ParseCheckIP(string(body))
+	require.NoError(t, err)
+	return cidr
+}
+
+// ParseCheckIP turns a checkip response into an IPv4 /32 CIDR
+func ParseCheckIP(body string) (string, error) {
+	ip := net.ParseIP(strings.TrimSpace(body))
+	if ip == nil || ip.To4() == nil {
+		return "", fmt.Errorf("checkip returned %q, not an IPv4 address", strings.TrimSpace(body))
+	}
+	return ip.String() + "/32", nil
+}
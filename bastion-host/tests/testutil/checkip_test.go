@@ -0,0 +1,22 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCheckIP(t *testing.T) {
+	t.Parallel()
+
+	cidr, err := ParseCheckIP("198.51.100.7\n")
+	require.NoError(t, err)
+	assert.Equal(t, "198.51.100.7/32", cidr)
+
+	_, err = ParseCheckIP("<html>rate limited</html>")
+	assert.Error(t, err)
+
+	_, err = ParseCheckIP("2001:db8::1")
+	assert.Error(t, err, "The security group only takes IPv4 CIDRs")
+}
@@ -0,0 +1,165 @@
+// Package testutil holds helpers shared by the bastion host test packages.
+package testutil
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultSSHDialTimeout bounds each hop when SSHConfig.DialTimeout is unset
+const DefaultSSHDialTimeout = 10 * time.Second
+
+// SSHConfig describes how to log in to the bastion and the instances behind it
+type SSHConfig struct {
+	User   string
+	Signer ssh.Signer
+	// DialTimeout bounds the TCP connect and SSH handshake of each hop
+	DialTimeout time.Duration
+	// HostKeyCallback defaults to accepting any key, since freshly launched
+	// test instances have no known host key to check against
+	HostKeyCallback ssh.HostKeyCallback
+}
+
+// JumpClient is an SSH connection to a host reached through a bastion, the
+// equivalent of ssh -J. Closing it closes both hops.
+type JumpClient struct {
+	*ssh.Client
+	bastion *ssh.Client
+}
+
+// Close closes the connection to the target, then the bastion
+func (c *JumpClient) Close() error {
+	targetErr := c.Client.Close()
+	if err := c.bastion.Close(); err != nil {
+		return err
+	}
+	return targetErr
+}
+
+// LoadSSHSigner reads an unencrypted private key file
+func LoadSSHSigner(path string) (ssh.Signer, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// DialSSH connects straight to addr ("host:port") from the test runner
+func DialSSH(addr string, config SSHConfig) (*ssh.Client, error) {
+	timeout := config.dialTimeout()
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// The handshake has no timeout of its own, so bound it with a deadline
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config.clientConfig())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		sshConn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// DialThroughBastion connects to the bastion, then asks it to forward a TCP
+// connection to targetAddr and logs in to the target over that connection.
+// The target never has to accept connections from the test runner.
+func DialThroughBastion(bastionAddr, targetAddr string, config SSHConfig) (*JumpClient, error) {
+	bastion, err := DialSSH(bastionAddr, config)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to bastion %s: %w", bastionAddr, err)
+	}
+
+	target, err := dialHop(bastion, targetAddr, config)
+	if err != nil {
+		bastion.Close()
+		return nil, fmt.Errorf("connecting to %s through bastion %s: %w", targetAddr, bastionAddr, err)
+	}
+	return &JumpClient{Client: target, bastion: bastion}, nil
+}
+
+// RunCommand runs a command in a new session and returns its combined output
+func RunCommand(client *ssh.Client, command string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	output, err := session.CombinedOutput(command)
+	if err != nil {
+		return string(output), fmt.Errorf("running %q: %w", command, err)
+	}
+	return string(output), nil
+}
+
+// dialHop opens the forwarded connection and handshake over it. Forwarded
+// channels don't support deadlines, so the timeout is enforced by abandoning
+// the attempt; the caller closing the bastion unblocks it.
+func dialHop(bastion *ssh.Client, targetAddr string, config SSHConfig) (*ssh.Client, error) {
+	type result struct {
+		client *ssh.Client
+		err    error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		conn, err := bastion.Dial("tcp", targetAddr)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		sshConn, chans, reqs, err := ssh.NewClientConn(conn, targetAddr, config.clientConfig())
+		if err != nil {
+			conn.Close()
+			done <- result{err: err}
+			return
+		}
+		done <- result{client: ssh.NewClient(sshConn, chans, reqs)}
+	}()
+
+	timeout := config.dialTimeout()
+	select {
+	case r := <-done:
+		return r.client, r.err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("no SSH handshake within %s", timeout)
+	}
+}
+
+func (c SSHConfig) dialTimeout() time.Duration {
+	if c.DialTimeout <= 0 {
+		return DefaultSSHDialTimeout
+	}
+	return c.DialTimeout
+}
+
+func (c SSHConfig) clientConfig() *ssh.ClientConfig {
+	hostKeyCallback := c.HostKeyCallback
+	if hostKeyCallback == nil {
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	}
+	return &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(c.Signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         c.dialTimeout(),
+	}
+}
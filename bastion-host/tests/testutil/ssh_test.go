@@ -0,0 +1,241 @@
+package testutil
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDialThroughBastion(t *testing.T) {
+	t.Parallel()
+
+	clientKey := newTestSigner(t)
+	target := startTestSSHServer(t, clientKey.PublicKey())
+	bastion := startTestSSHServer(t, clientKey.PublicKey())
+	config := SSHConfig{User: "ec2-user", Signer: clientKey, DialTimeout: 2 * time.Second}
+
+	client, err := DialThroughBastion(bastion.addr, target.addr, config)
+	require.NoError(t, err)
+	defer client.Close()
+
+	output, err := RunCommand(client.Client, "uname -a")
+	require.NoError(t, err)
+	assert.Equal(t, "Linux target uname -a\n", output)
+
+	// The bastion forwarded the connection and only the target ran the command
+	assert.Equal(t, []string{target.addr}, bastion.forwarded())
+	assert.Empty(t, bastion.commands())
+	assert.Equal(t, []string{"uname -a"}, target.commands())
+}
+
+func TestDialThroughBastionFailures(t *testing.T) {
+	t.Parallel()
+
+	clientKey := newTestSigner(t)
+	target := startTestSSHServer(t, clientKey.PublicKey())
+	bastion := startTestSSHServer(t, clientKey.PublicKey())
+
+	// A key the bastion doesn't authorize never reaches the target
+	_, err := DialThroughBastion(bastion.addr, target.addr, SSHConfig{User: "ec2-user", Signer: newTestSigner(t), DialTimeout: 2 * time.Second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connecting to bastion")
+	assert.Empty(t, bastion.forwarded())
+
+	// Something behind the bastion that accepts TCP but never speaks SSH
+	silent := startSilentListener(t)
+	config := SSHConfig{User: "ec2-user", Signer: clientKey, DialTimeout: 200 * time.Millisecond}
+	start := time.Now()
+	_, err = DialThroughBastion(bastion.addr, silent, config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no SSH handshake within 200ms")
+	assert.Less(t, time.Since(start), 2*time.Second, "The dial timeout should bound the proxied hop")
+
+	// The same silent host dialed directly hits the handshake deadline
+	start = time.Now()
+	_, err = DialSSH(silent, config)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second, "The dial timeout should bound the handshake")
+}
+
+// testSSHServer is a minimal sshd that forwards direct-tcpip channels like a
+// bastion and answers exec requests with "Linux target <command>"
+type testSSHServer struct {
+	addr string
+
+	mu        sync.Mutex
+	targets   []string
+	execCalls []string
+}
+
+func (s *testSSHServer) forwarded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.targets...)
+}
+
+func (s *testSSHServer) commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.execCalls...)
+}
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer
+}
+
+func startTestSSHServer(t *testing.T, authorized ssh.PublicKey) *testSSHServer {
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(authorized.Marshal()) {
+				return nil, fmt.Errorf("unauthorized key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(newTestSigner(t))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &testSSHServer{addr: listener.Addr().String()}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "direct-tcpip":
+			go s.forward(newChannel)
+		case "session":
+			go s.session(newChannel)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, newChannel.ChannelType())
+		}
+	}
+}
+
+func (s *testSSHServer) forward(newChannel ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	addr := net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port)))
+	s.mu.Lock()
+	s.targets = append(s.targets, addr)
+	s.mu.Unlock()
+
+	upstream, err := net.Dial("tcp", addr)
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		io.Copy(upstream, channel)
+		upstream.Close()
+	}()
+	io.Copy(channel, upstream)
+	channel.Close()
+}
+
+func (s *testSSHServer) session(newChannel ssh.NewChannel) {
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	for req := range reqs {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		s.mu.Lock()
+		s.execCalls = append(s.execCalls, payload.Command)
+		s.mu.Unlock()
+
+		req.Reply(true, nil)
+		fmt.Fprintf(channel, "Linux target %s\n", payload.Command)
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+		return
+	}
+}
+
+// Helper function to start a listener that accepts connections and never writes
+func startSilentListener(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var conns []net.Conn
+	var mu sync.Mutex
+	t.Cleanup(func() {
+		listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	return listener.Addr().String()
+}
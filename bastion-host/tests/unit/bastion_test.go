@@ -3,8 +3,13 @@ package unit
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"testkit"
 )
 
 func TestBastionModule(t *testing.T) {
@@ -77,3 +82,35 @@ func TestBastionWithMonitoring(t *testing.T) {
 	assert.NotEmpty(t, publicIp)
 	// In a real test, you'd verify monitoring settings via AWS SDK
 }
+
+func TestBastionMetadataOptions(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../modules/bastion",
+		Vars: map[string]interface{}{
+			"subnet_id":              "subnet-12345678",
+			"key_name":               "test-key",
+			"security_group_id":      "sg-12345678",
+			"ami":                    "ami-12345678",
+			"environment":            "test",
+			"iam_instance_profile":   "test-profile",
+			"instance_metadata_tags": true,
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	// The structured output should match what EC2 reports for the instance
+	assert.Equal(t, map[string]interface{}{
+		"http_tokens":                 "required",
+		"http_put_response_hop_limit": float64(1),
+		"instance_metadata_tags":      "enabled",
+	}, metadataOptionsOutput(t, terraformOptions))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertMetadataOptions(t, ec2.New(sess), terraform.Output(t, terraformOptions, "instance_id"), true)
+}
@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"testkit"
+)
+
+// bastionEnvironment is the VPC range and key pair each environment gets
+type bastionEnvironment struct {
+	VPCCIDR string
+	KeyName string
+}
+
+func TestEnvironmentPlans(t *testing.T) {
+	t.Parallel()
+
+	baseVars := map[string]interface{}{
+		"public_key":        "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc environments-test",
+		"allowed_ssh_cidrs": []string{"10.0.0.0/8"},
+	}
+	want := map[string]bastionEnvironment{
+		"dev":     {VPCCIDR: "172.16.0.0/16", KeyName: "bastion-dev"},
+		"staging": {VPCCIDR: "172.17.0.0/16", KeyName: "bastion-staging"},
+		"prod":    {VPCCIDR: "172.18.0.0/16", KeyName: "bastion-prod"},
+	}
+
+	testkit.RunEnvironmentPlans(t, "../../", baseVars, want, func(t *testing.T, env string, plan *terraform.PlanStruct) bastionEnvironment {
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.bastion.aws_instance.this")
+		bastion := plan.ResourcePlannedValuesMap["module.bastion.aws_instance.this"].AttributeValues
+		assert.Equal(t, env, bastion["tags"].(map[string]interface{})["Environment"], "Bastion Environment tag")
+
+		var got bastionEnvironment
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.vpc.aws_vpc.this")
+		got.VPCCIDR, _ = plan.ResourcePlannedValuesMap["module.vpc.aws_vpc.this"].AttributeValues["cidr_block"].(string)
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.key_pair.aws_key_pair.this")
+		got.KeyName, _ = plan.ResourcePlannedValuesMap["module.key_pair.aws_key_pair.this"].AttributeValues["key_name"].(string)
+		return got
+	})
+}
+
+func TestEnvironmentFiles(t *testing.T) {
+	t.Parallel()
+
+	testkit.AssertEnvironmentFiles(t, "../../")
+}
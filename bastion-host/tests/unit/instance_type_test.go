@@ -0,0 +1,42 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInstanceTypePlan checks the instance type reaches both instances and
+// that types outside the allowlist are refused
+func TestInstanceTypePlan(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"key_name":          "instance-type-plan-key",
+			"public_key":        "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc instance-type-test",
+			"allowed_ssh_cidrs": []string{"10.0.0.0/8"},
+			"instance_type":     "t3a.small",
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	for _, address := range []string{"module.bastion.aws_instance.this", "module.private_instance.aws_instance.this"} {
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+		assert.Equal(t, "t3a.small", plan.ResourcePlannedValuesMap[address].AttributeValues["instance_type"], "%s instance type", address)
+	}
+	outputs := plan.RawPlan.PlannedValues.Outputs
+	assert.Equal(t, "t3a.small", outputs["bastion_instance_type"].Value)
+	assert.Equal(t, "t3a.small", outputs["private_instance_type"].Value)
+
+	// The previous hardcoded size and anything large are both rejected
+	for _, instanceType := range []string{"t2.micro", "m5.24xlarge"} {
+		terraformOptions.Vars["instance_type"] = instanceType
+		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, "%s should not be accepted", instanceType)
+		assert.Contains(t, err.Error(), "instance_type must be one of")
+	}
+}
@@ -0,0 +1,16 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function to decode a module's metadata_options output
+func metadataOptionsOutput(t *testing.T, terraformOptions *terraform.Options) map[string]interface{} {
+	var options map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(terraform.OutputJson(t, terraformOptions, "metadata_options")), &options))
+	return options
+}
@@ -3,8 +3,13 @@ package unit
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"testkit"
 )
 
 func TestPrivateInstanceModule(t *testing.T) {
@@ -97,3 +102,33 @@ func TestPrivateInstanceNoPublicIp(t *testing.T) {
 	assert.NotEmpty(t, privateIp)
 	// The module sets associate_public_ip_address = false, so no public IP should be assigned
 }
+
+func TestPrivateInstanceMetadataOptions(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../modules/private_instance",
+		Vars: map[string]interface{}{
+			"subnet_id":         "subnet-12345678",
+			"key_name":          "test-key",
+			"security_group_id": "sg-12345678",
+			"ami":               "ami-12345678",
+			"environment":       "test",
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	// Tags stay out of instance metadata unless the module opts in
+	assert.Equal(t, map[string]interface{}{
+		"http_tokens":                 "required",
+		"http_put_response_hop_limit": float64(1),
+		"instance_metadata_tags":      "disabled",
+	}, metadataOptionsOutput(t, terraformOptions))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertMetadataOptions(t, ec2.New(sess), terraform.Output(t, terraformOptions, "instance_id"), false)
+}
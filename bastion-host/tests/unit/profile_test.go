@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"testkit"
+)
+
+// bastionProfile is what each bundled toggle shows in a bastion-host plan
+type bastionProfile struct {
+	CustomerManagedKey      bool
+	PrivateEgressAllTraffic bool
+	CloudTrailAlarms        bool
+	CloudTrailRetentionDays float64
+	LogRetentionDays        float64
+}
+
+func TestProfilePlans(t *testing.T) {
+	t.Parallel()
+
+	baseVars := map[string]interface{}{
+		"key_name":          "profile-plan-key",
+		"public_key":        "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc profile-test",
+		"allowed_ssh_cidrs": []string{"10.0.0.0/8"},
+	}
+	cases := []testkit.ProfileCase[bastionProfile]{
+		{
+			Name: "minimal",
+			Vars: map[string]interface{}{"profile": "minimal"},
+			Want: bastionProfile{PrivateEgressAllTraffic: true, LogRetentionDays: 30},
+		},
+		{
+			Name: "hardened",
+			Vars: map[string]interface{}{"profile": "hardened"},
+			Want: bastionProfile{CustomerManagedKey: true, CloudTrailAlarms: true, CloudTrailRetentionDays: 365, LogRetentionDays: 365},
+		},
+		{
+			Name: "hardened with overrides",
+			Vars: map[string]interface{}{
+				"profile":                       "hardened",
+				"restrict_private_egress":       false,
+				"cloudtrail_log_retention_days": 180,
+			},
+			Want: bastionProfile{CustomerManagedKey: true, PrivateEgressAllTraffic: true, CloudTrailAlarms: true, CloudTrailRetentionDays: 180, LogRetentionDays: 365},
+		},
+	}
+
+	testkit.RunProfilePlans(t, "../../", baseVars, cases, func(t *testing.T, plan *terraform.PlanStruct) bastionProfile {
+		var got bastionProfile
+
+		key, createsKey := plan.ResourcePlannedValuesMap["module.kms[0].aws_kms_key.this"]
+		got.CustomerManagedKey = createsKey
+		if createsKey {
+			assert.Equal(t, true, key.AttributeValues["enable_key_rotation"], "Key should rotate")
+		}
+
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.security_group.aws_security_group.private")
+		got.PrivateEgressAllTraffic = allowsAllEgress(plan.ResourcePlannedValuesMap["module.security_group.aws_security_group.private"].AttributeValues["egress"])
+
+		cloudTrailLogs, alarms := plan.ResourcePlannedValuesMap["aws_cloudwatch_log_group.cloudtrail[0]"]
+		got.CloudTrailAlarms = alarms
+		if alarms {
+			got.CloudTrailRetentionDays, _ = cloudTrailLogs.AttributeValues["retention_in_days"].(float64)
+		}
+
+		// The flow logs follow log_retention_days along with the bastion's own logs
+		for _, address := range []string{"module.vpc.aws_cloudwatch_log_group.vpc_flow_log", "aws_cloudwatch_log_group.bastion_logs"} {
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+		}
+		got.LogRetentionDays, _ = plan.ResourcePlannedValuesMap["aws_cloudwatch_log_group.bastion_logs"].AttributeValues["retention_in_days"].(float64)
+		assert.Equal(t, got.LogRetentionDays, plan.ResourcePlannedValuesMap["module.vpc.aws_cloudwatch_log_group.vpc_flow_log"].AttributeValues["retention_in_days"], "VPC flow log retention")
+
+		// IMDSv2 isn't part of either preset; both instances always require it
+		for _, address := range []string{"module.bastion.aws_instance.this", "module.private_instance.aws_instance.this"} {
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+			metadata := plan.ResourcePlannedValuesMap[address].AttributeValues["metadata_options"].([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "required", metadata["http_tokens"], "%s IMDSv2", address)
+		}
+		return got
+	})
+}
+
+func TestAllowsAllEgress(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, allowsAllEgress([]interface{}{map[string]interface{}{"protocol": "-1", "cidr_blocks": []interface{}{"0.0.0.0/0"}}}))
+	assert.False(t, allowsAllEgress([]interface{}{
+		map[string]interface{}{"protocol": "tcp", "from_port": float64(443), "to_port": float64(443), "cidr_blocks": []interface{}{"0.0.0.0/0"}},
+		map[string]interface{}{"protocol": "udp", "from_port": float64(53), "to_port": float64(53), "cidr_blocks": []interface{}{"0.0.0.0/0"}},
+	}))
+	// All protocols, but only inside the VPC
+	assert.False(t, allowsAllEgress([]interface{}{map[string]interface{}{"protocol": "-1", "cidr_blocks": []interface{}{"172.16.0.0/16"}}}))
+	assert.False(t, allowsAllEgress(nil))
+}
+
+// Helper function to check whether planned security group egress rules let
+// any protocol out to the internet
+func allowsAllEgress(rules interface{}) bool {
+	list, _ := rules.([]interface{})
+	for _, rule := range list {
+		values, ok := rule.(map[string]interface{})
+		if !ok || values["protocol"] != "-1" {
+			continue
+		}
+		cidrs, _ := values["cidr_blocks"].([]interface{})
+		for _, cidr := range cidrs {
+			if cidr == "0.0.0.0/0" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -3,8 +3,13 @@ package unit
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"testkit"
 )
 
 func TestSecurityGroupModule(t *testing.T) {
@@ -33,6 +38,12 @@ func TestSecurityGroupModule(t *testing.T) {
 
 	// Verify security groups are different
 	assert.NotEqual(t, bastionSgId, privateSgId)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	// Every rule should document why it exists
+	testkit.AssertSecurityGroupRuleDescriptions(t, ec2.New(sess), bastionSgId, privateSgId)
 }
 
 func TestBastionSecurityGroupRules(t *testing.T) {
@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBastionTenancy applies the whole stack so both instances run on real
+// subnets, then checks the tenancy EC2 reports for the instances it created
+func TestBastionTenancy(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"environment":       "test",
+			"key_name":          "tenancy-test-key",
+			"public_key":        "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc tenancy-test",
+			"allowed_ssh_cidrs": []string{"10.0.0.0/8"},
+			"tenancy":           "default",
+		},
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+	for idOutput, tenancyOutput := range map[string]string{
+		"bastion_instance_id": "bastion_tenancy",
+		"private_instance_id": "private_instance_tenancy",
+	} {
+		assert.Equal(t, "default", terraform.Output(t, terraformOptions, tenancyOutput))
+		assertInstanceTenancy(t, ec2Svc, terraform.Output(t, terraformOptions, idOutput), "default")
+	}
+}
+
+// TestDedicatedTenancyPlan checks dedicated tenancy reaches both instances
+// without paying for a dedicated launch
+func TestDedicatedTenancyPlan(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"key_name":          "tenancy-plan-key",
+			"public_key":        "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQC7vbqajDhTfsH1rKj8L9q5QJvXc tenancy-test",
+			"allowed_ssh_cidrs": []string{"10.0.0.0/8"},
+			"tenancy":           "dedicated",
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	for _, address := range []string{"module.bastion.aws_instance.this", "module.private_instance.aws_instance.this"} {
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+		assert.Equal(t, "dedicated", plan.ResourcePlannedValuesMap[address].AttributeValues["tenancy"], "%s tenancy", address)
+	}
+
+	terraformOptions.Vars["tenancy"] = "host"
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	require.Error(t, err, "Only default and dedicated tenancy should be accepted")
+	assert.Contains(t, err.Error(), "tenancy must be default or dedicated")
+}
+
+func TestAssertInstanceTenancy(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeInstanceClient{instances: []*ec2.Instance{{
+		InstanceId: aws.String("i-bastion"),
+		Placement:  &ec2.Placement{Tenancy: aws.String(ec2.TenancyDedicated)},
+	}}}
+	assertInstanceTenancy(t, svc, "i-bastion", "dedicated")
+	assert.Equal(t, []string{"i-bastion"}, aws.StringValueSlice(svc.lastInput.InstanceIds))
+
+	tenancy, err := instanceTenancy(&fakeInstanceClient{instances: []*ec2.Instance{{InstanceId: aws.String("i-private")}}}, "i-private")
+	require.NoError(t, err)
+	assert.Equal(t, "", tenancy, "An instance without placement has no tenancy to report")
+}
+
+type fakeInstanceClient struct {
+	ec2iface.EC2API
+	instances []*ec2.Instance
+	lastInput *ec2.DescribeInstancesInput
+}
+
+func (f *fakeInstanceClient) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.lastInput = input
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: f.instances}}}, nil
+}
+
+// Helper function to assert an instance's placement tenancy as EC2 reports it
+func assertInstanceTenancy(t *testing.T, ec2Svc ec2iface.EC2API, instanceID, expected string) {
+	tenancy, err := instanceTenancy(ec2Svc, instanceID)
+	require.NoError(t, err)
+	assert.Equal(t, expected, tenancy, fmt.Sprintf("Instance %s tenancy", instanceID))
+}
+
+// Helper function to read an instance's Placement.Tenancy
+func instanceTenancy(ec2Svc ec2iface.EC2API, instanceID string) (string, error) {
+	result, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(result.Reservations) != 1 || len(result.Reservations[0].Instances) != 1 {
+		return "", fmt.Errorf("instance %s not found", instanceID)
+	}
+	placement := result.Reservations[0].Instances[0].Placement
+	if placement == nil {
+		return "", nil
+	}
+	return aws.StringValue(placement.Tenancy), nil
+}
@@ -1,10 +1,18 @@
 package unit
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"testkit"
 )
 
 func TestVpcModule(t *testing.T) {
@@ -14,10 +22,11 @@ func TestVpcModule(t *testing.T) {
 		TerraformDir: "../../modules/vpc",
 		Vars: map[string]interface{}{
 			"cidr_block":           "10.0.0.0/16",
-			"azs":                  []string{"us-east-1a"},
-			"public_subnet_cidrs":  []string{"10.0.1.0/24"},
-			"private_subnet_cidrs": []string{"10.0.10.0/24"},
+			"azs":                  []string{"us-east-1a", "us-east-1b"},
+			"public_subnet_cidrs":  []string{"10.0.1.0/24", "10.0.2.0/24"},
+			"private_subnet_cidrs": []string{"10.0.10.0/24", "10.0.11.0/24"},
 			"region":               "us-east-1",
+			"enable_nat_gateway":   true,
 		},
 	}
 
@@ -28,19 +37,73 @@ func TestVpcModule(t *testing.T) {
 	vpcId := terraform.Output(t, terraformOptions, "vpc_id")
 	assert.NotEmpty(t, vpcId)
 
-	// Test public subnet creation
+	// One public and one private subnet per AZ
 	publicSubnetIds := terraform.OutputList(t, terraformOptions, "public_subnet_ids")
-	assert.Len(t, publicSubnetIds, 1)
-	assert.NotEmpty(t, publicSubnetIds[0])
-
-	// Test private subnet creation
+	assert.Len(t, publicSubnetIds, 2)
 	privateSubnetIds := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
-	assert.Len(t, privateSubnetIds, 1)
-	assert.NotEmpty(t, privateSubnetIds[0])
+	assert.Len(t, privateSubnetIds, 2)
 
-	// Test VPC exists and has expected attributes
-	assert.NotEmpty(t, vpcId)
-	// Additional VPC attribute checks would require AWS SDK calls
+	// One NAT gateway and private route table per AZ
+	natGatewayIds := terraform.OutputList(t, terraformOptions, "nat_gateway_ids")
+	assert.Len(t, natGatewayIds, 2)
+	privateRouteTableIds := terraform.OutputList(t, terraformOptions, "private_route_table_ids")
+	assert.Len(t, privateRouteTableIds, 2)
+
+	// Check the live subnets, including that only the public ones auto-assign public IPs
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	ec2Svc := ec2.New(sess)
+	testkit.AssertSubnets(t, ec2Svc, map[string]testkit.ExpectedSubnet{
+		publicSubnetIds[0]:  {CIDR: "10.0.1.0/24", AZ: "us-east-1a", Public: true},
+		publicSubnetIds[1]:  {CIDR: "10.0.2.0/24", AZ: "us-east-1b", Public: true},
+		privateSubnetIds[0]: {CIDR: "10.0.10.0/24", AZ: "us-east-1a", Public: false},
+		privateSubnetIds[1]: {CIDR: "10.0.11.0/24", AZ: "us-east-1b", Public: false},
+	})
+	assertPrivateEgressPerAZ(t, ec2Svc, privateRouteTableIds, natGatewayIds)
+}
+
+// TestVpcModuleSingleNatGateway plans two AZs sharing one NAT gateway: each
+// private subnet keeps its own route table, both pointing at the one NAT.
+// Without enable_nat_gateway there is no NAT and no default route at all.
+func TestVpcModuleSingleNatGateway(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../modules/vpc",
+		Vars: map[string]interface{}{
+			"cidr_block":           "10.0.0.0/16",
+			"azs":                  []string{"us-east-1a", "us-east-1b"},
+			"public_subnet_cidrs":  []string{"10.0.1.0/24", "10.0.2.0/24"},
+			"private_subnet_cidrs": []string{"10.0.10.0/24", "10.0.11.0/24"},
+			"region":               "us-east-1",
+			"enable_nat_gateway":   true,
+			"single_nat_gateway":   true,
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_nat_gateway.this[0]")
+	_, second := plan.ResourcePlannedValuesMap["aws_nat_gateway.this[1]"]
+	assert.False(t, second, "single_nat_gateway should plan exactly one NAT gateway")
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_route_table.private[0]")
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_route_table.private[1]")
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_route_table_association.private[1]")
+
+	terraformOptions.Vars["enable_nat_gateway"] = false
+	plan = terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	for _, address := range []string{"aws_nat_gateway.this[0]", "aws_eip.nat[0]"} {
+		_, planned := plan.ResourcePlannedValuesMap[address]
+		assert.False(t, planned, "%s should need enable_nat_gateway", address)
+	}
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_route_table.private[0]")
+	assert.Empty(t, plan.ResourcePlannedValuesMap["aws_route_table.private[0]"].AttributeValues["route"], "Private route tables should have no default route without NAT")
+
+	// Mismatched lists would leave an AZ without a subnet pair
+	terraformOptions.Vars["private_subnet_cidrs"] = []string{"10.0.10.0/24"}
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must have the same, non-zero length")
 }
 
 func TestVpcFlowLogs(t *testing.T) {
@@ -80,10 +143,149 @@ func TestVpcEndpoints(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
-	terraform.InitAndApply(t, terraformOptions)
+	var scope testkit.ENIScope
 
-	// Verify VPC and subnets are created (endpoints are created as part of VPC module)
-	vpcId := terraform.Output(t, terraformOptions, "vpc_id")
-	assert.NotEmpty(t, vpcId)
+	t.Run("Deploy", func(t *testing.T) {
+		defer terraform.Destroy(t, terraformOptions)
+		terraform.InitAndApply(t, terraformOptions)
+
+		// Verify VPC and subnets are created (endpoints are created as part of VPC module)
+		vpcId := terraform.Output(t, terraformOptions, "vpc_id")
+		assert.NotEmpty(t, vpcId)
+
+		// A vpc-id filter matches nothing once the VPC is gone, so look ENIs
+		// up afterwards by the subnets and security groups they lived in
+		sess := session.Must(session.NewSession(&aws.Config{
+			Region: aws.String("us-east-1"),
+		}))
+		var err error
+		scope, err = testkit.CaptureENIScope(ec2.New(sess), vpcId)
+		require.NoError(t, err)
+	})
+
+	// SSM interface endpoints are the usual source of ENIs that block destroy,
+	// so verify nothing was left behind once teardown has finished
+	t.Run("ENICleanup", func(t *testing.T) {
+		require.NotEmpty(t, scope.SubnetIDs, "The VPC's subnets are required to check for leaked ENIs")
+
+		sess := session.Must(session.NewSession(&aws.Config{
+			Region: aws.String("us-east-1"),
+		}))
+		testkit.AssertNoLeakedENIs(t, ec2.New(sess), scope)
+	})
+}
+
+func TestPrivateEgressProblems(t *testing.T) {
+	t.Parallel()
+
+	natGateways := []*ec2.NatGateway{
+		{NatGatewayId: aws.String("nat-a"), SubnetId: aws.String("subnet-public-a")},
+		{NatGatewayId: aws.String("nat-b"), SubnetId: aws.String("subnet-public-b")},
+	}
+	subnetAZs := map[string]string{
+		"subnet-public-a": "us-east-1a", "subnet-public-b": "us-east-1b",
+		"subnet-private-a": "us-east-1a", "subnet-private-b": "us-east-1b",
+	}
+	privateRouteTable := func(id, subnetID, natID string) *ec2.RouteTable {
+		return &ec2.RouteTable{
+			RouteTableId: aws.String(id),
+			Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String(subnetID)}},
+			Routes: []*ec2.Route{
+				{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+				{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String(natID)},
+			},
+		}
+	}
+
+	routeTables := []*ec2.RouteTable{
+		privateRouteTable("rtb-a", "subnet-private-a", "nat-a"),
+		privateRouteTable("rtb-b", "subnet-private-b", "nat-b"),
+	}
+	assert.Empty(t, privateEgressProblems(routeTables, natGateways, subnetAZs))
+
+	// Both AZs leaning on one NAT, and a route table nothing uses
+	routeTables[1] = privateRouteTable("rtb-b", "subnet-private-b", "nat-a")
+	routeTables = append(routeTables, &ec2.RouteTable{RouteTableId: aws.String("rtb-c")})
+	assert.Equal(t, []string{
+		"route table rtb-b (us-east-1b) egresses through nat-a in us-east-1a",
+		"route table rtb-c is not associated with exactly one subnet",
+		"NAT gateway nat-b carries no private subnet's egress",
+	}, privateEgressProblems(routeTables, natGateways, subnetAZs))
+}
+
+// Helper function to assert every private route table sends internet traffic
+// through a NAT gateway in its own subnet's AZ, and every NAT gateway is used
+func assertPrivateEgressPerAZ(t *testing.T, svc ec2iface.EC2API, routeTableIDs, natGatewayIDs []string) {
+	routeTables, err := svc.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		RouteTableIds: aws.StringSlice(routeTableIDs),
+	})
+	require.NoError(t, err)
+	natGateways, err := svc.DescribeNatGateways(&ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: aws.StringSlice(natGatewayIDs),
+	})
+	require.NoError(t, err)
+
+	// The AZ of each private subnet and of each subnet holding a NAT gateway
+	var subnetIDs []string
+	for _, routeTable := range routeTables.RouteTables {
+		for _, association := range routeTable.Associations {
+			subnetIDs = append(subnetIDs, aws.StringValue(association.SubnetId))
+		}
+	}
+	for _, natGateway := range natGateways.NatGateways {
+		subnetIDs = append(subnetIDs, aws.StringValue(natGateway.SubnetId))
+	}
+	subnets, err := svc.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnetIDs),
+	})
+	require.NoError(t, err)
+	subnetAZs := map[string]string{}
+	for _, subnet := range subnets.Subnets {
+		subnetAZs[aws.StringValue(subnet.SubnetId)] = aws.StringValue(subnet.AvailabilityZone)
+	}
+
+	for _, problem := range privateEgressProblems(routeTables.RouteTables, natGateways.NatGateways, subnetAZs) {
+		assert.Fail(t, "Private subnet egress is not per AZ", problem)
+	}
+}
+
+// Helper function to list private route tables that don't egress through a
+// NAT gateway in their subnet's AZ, and NAT gateways no route table uses
+func privateEgressProblems(routeTables []*ec2.RouteTable, natGateways []*ec2.NatGateway, subnetAZs map[string]string) []string {
+	natAZs := map[string]string{}
+	for _, natGateway := range natGateways {
+		natAZs[aws.StringValue(natGateway.NatGatewayId)] = subnetAZs[aws.StringValue(natGateway.SubnetId)]
+	}
+
+	var problems []string
+	used := map[string]bool{}
+	for _, routeTable := range routeTables {
+		routeTableID := aws.StringValue(routeTable.RouteTableId)
+		if len(routeTable.Associations) != 1 {
+			problems = append(problems, fmt.Sprintf("route table %s is not associated with exactly one subnet", routeTableID))
+			continue
+		}
+		az := subnetAZs[aws.StringValue(routeTable.Associations[0].SubnetId)]
+
+		natID := ""
+		for _, route := range routeTable.Routes {
+			if aws.StringValue(route.DestinationCidrBlock) == "0.0.0.0/0" {
+				natID = aws.StringValue(route.NatGatewayId)
+			}
+		}
+		switch {
+		case natID == "":
+			problems = append(problems, fmt.Sprintf("route table %s (%s) has no default route through a NAT gateway", routeTableID, az))
+		case natAZs[natID] != az:
+			problems = append(problems, fmt.Sprintf("route table %s (%s) egresses through %s in %s", routeTableID, az, natID, natAZs[natID]))
+		}
+		used[natID] = true
+	}
+
+	for _, natGateway := range natGateways {
+		if natID := aws.StringValue(natGateway.NatGatewayId); !used[natID] {
+			problems = append(problems, fmt.Sprintf("NAT gateway %s carries no private subnet's egress", natID))
+		}
+	}
+	return problems
 }
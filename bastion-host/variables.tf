@@ -26,6 +26,18 @@ variable "private_subnet_cidrs" {
   default     = ["172.16.10.0/24"]
 }
 
+variable "enable_nat_gateway" {
+  description = "Create NAT gateways for private subnet internet egress (about $32 per gateway per month plus data); off, the private instances reach AWS only through the VPC endpoints"
+  type        = bool
+  default     = false
+}
+
+variable "single_nat_gateway" {
+  description = "With enable_nat_gateway, route every private subnet through one NAT gateway instead of one per AZ; saves about $32 per extra AZ per month at the cost of AZ-independent egress"
+  type        = bool
+  default     = false
+}
+
 variable "key_name" {
   description = "Key pair name"
 }
@@ -45,3 +57,111 @@ variable "environment" {
   type        = string
   default     = "dev"
 }
+
+variable "enable_instance_metadata_tags" {
+  description = "Expose instance tags through the metadata service on the bastion and private instance"
+  type        = bool
+  default     = false
+}
+
+variable "instance_type" {
+  description = "EC2 instance type for the bastion and private instance; the instance modules restrict it to small t3/t3a types"
+  type        = string
+  default     = "t3.micro"
+}
+
+variable "tenancy" {
+  description = "Tenancy for the bastion and private instance; dedicated costs a per-region hourly fee on top of the instances"
+  type        = string
+  default     = "default"
+
+  validation {
+    condition     = contains(["default", "dedicated"], var.tenancy)
+    error_message = "tenancy must be default or dedicated."
+  }
+}
+
+variable "detailed_monitoring" {
+  description = "Enable 1-minute detailed monitoring on both instances; about $2.10 per instance per month"
+  type        = bool
+  default     = true
+}
+
+variable "profile" {
+  description = "Security preset: minimal (open private egress, no CloudTrail alarms, short log retention) or hardened (HTTPS and DNS only from the private instance, CloudTrail alarms, a year of logs). Individual toggles override it."
+  type        = string
+  default     = "minimal"
+
+  validation {
+    condition     = contains(["minimal", "hardened"], var.profile)
+    error_message = "profile must be minimal or hardened."
+  }
+}
+
+variable "restrict_private_egress" {
+  description = "Limit the private instance's outbound traffic to HTTPS and DNS; null follows profile"
+  type        = bool
+  default     = null
+}
+
+variable "kms_key_arn" {
+  description = "Existing customer-managed KMS key for the instances' root volumes; empty uses the AWS-managed aws/ebs key unless create_kms_key is set"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.kms_key_arn == "" || can(regex("^arn:aws[a-z-]*:kms:[a-z0-9-]+:[0-9]{12}:key/[0-9a-f-]+$", var.kms_key_arn))
+    error_message = "kms_key_arn must be empty or a KMS key ARN (arn:aws:kms:<region>:<account>:key/<id>)."
+  }
+}
+
+variable "create_kms_key" {
+  description = "Create a rotated customer-managed key in modules/kms for the root volumes when kms_key_arn is empty; null follows profile"
+  type        = bool
+  default     = null
+}
+
+variable "enable_cloudtrail_alarms" {
+  description = "Send CloudTrail to CloudWatch Logs and alarm on root logins, IAM policy changes and security group changes; null follows profile"
+  type        = bool
+  default     = null
+}
+
+variable "alarm_sns_topic_arn" {
+  description = "SNS topic the CloudTrail and SSH login alarms notify; empty uses the stack's security alerts topic"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.alarm_sns_topic_arn == "" || can(regex("^arn:aws[a-z-]*:sns:", var.alarm_sns_topic_arn))
+    error_message = "alarm_sns_topic_arn must be an SNS topic ARN or empty."
+  }
+}
+
+variable "cloudtrail_log_retention_days" {
+  description = "Days to keep CloudTrail events in CloudWatch Logs when CloudTrail alarms are on; null follows profile"
+  type        = number
+  default     = null
+}
+
+variable "log_retention_days" {
+  description = "Days to keep VPC flow logs and bastion SSH logs in CloudWatch Logs; null follows profile"
+  type        = number
+  default     = null
+
+  validation {
+    condition     = var.log_retention_days == null ? true : contains([1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], var.log_retention_days)
+    error_message = "log_retention_days must be a retention period CloudWatch Logs supports, e.g. 30, 90, 365 or 731."
+  }
+}
+
+variable "cloudtrail_bucket_retention_days" {
+  description = "Days to keep CloudTrail log files in the S3 bucket before they expire"
+  type        = number
+  default     = 365
+
+  validation {
+    condition     = var.cloudtrail_bucket_retention_days >= 90
+    error_message = "cloudtrail_bucket_retention_days must be at least 90 days."
+  }
+}
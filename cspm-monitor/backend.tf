@@ -1,16 +1,11 @@
+# Backends can't read variables, so the account-specific bucket, region and
+# lock table are passed at init from backend.hcl (see backend.hcl.example):
+#   terraform init -backend-config=backend.hcl
 terraform {
   backend "s3" {
-    bucket = var.terraform_state_bucket
-    key    = "${var.project_name}/terraform.tfstate"
-    region = var.region
+    key = "cspm-monitor/terraform.tfstate"
 
     # Enable encryption for state file
     encrypt = true
-
-    # Enable DynamoDB locking
-    dynamodb_table = var.terraform_locks_table
-
-    # Enable versioning for state file recovery
-    versioning = true
   }
-}
\ No newline at end of file
+}
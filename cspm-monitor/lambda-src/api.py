@@ -18,9 +18,29 @@ logger.setLevel(logging.INFO)
 
 # Initialize AWS clients
 dynamodb = boto3.resource('dynamodb')
+sns = boto3.client('sns')
 
 # Environment variables
 DYNAMODB_TABLE_PARAM = os.environ.get('DYNAMODB_TABLE_PARAM', '/cspm-monitor/dynamodb-table-name')
+SNS_TOPIC_ARN_PARAM = os.environ.get('SNS_TOPIC_ARN_PARAM', '/cspm-monitor/sns-topic-arn')
+
+SERVICE_NAME = 'cspm-monitor-api'
+SERVICE_VERSION = '1.0.0'
+
+# Proxy integrations pass the Lambda's headers straight through, so every
+# response carries these itself
+SECURITY_HEADERS = {
+    'X-Content-Type-Options': 'nosniff',
+    'X-Frame-Options': 'DENY',
+    'X-XSS-Protection': '1; mode=block',
+    'Strict-Transport-Security': 'max-age=31536000; includeSubDomains; preload',
+    'Content-Security-Policy': "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data: https:; font-src 'self' data:; connect-src 'self'",
+    'Referrer-Policy': 'strict-origin-when-cross-origin',
+    'Permissions-Policy': 'geolocation=(), microphone=(), camera=()',
+    'Cross-Origin-Embedder-Policy': 'require-corp',
+    'Cross-Origin-Opener-Policy': 'same-origin',
+    'Cross-Origin-Resource-Policy': 'same-origin',
+}
 
 # SSM Parameter Store for configuration
 ssm = boto3.client('ssm')
@@ -144,6 +164,44 @@ def get_findings_summary():
         logger.error(f"Failed to get findings summary: {e}")
         raise
 
+def check_dependencies():
+    """Check the downstream services the API and scanner rely on"""
+    dependencies = {}
+
+    try:
+        table = get_table()
+        table_status = table.table_status
+        dependencies['dynamodb'] = {
+            'status': 'ok' if table_status == 'ACTIVE' else 'error',
+            'detail': f"table {table.name} is {table_status}"
+        }
+    except ClientError as e:
+        logger.error(f"DynamoDB health check failed: {e}")
+        dependencies['dynamodb'] = {'status': 'error', 'detail': e.response['Error']['Code']}
+
+    try:
+        topic_arn = get_ssm_parameter(SNS_TOPIC_ARN_PARAM)
+        sns.get_topic_attributes(TopicArn=topic_arn)
+        dependencies['sns'] = {'status': 'ok', 'detail': f"topic {topic_arn.split(':')[-1]} is reachable"}
+    except ClientError as e:
+        logger.error(f"SNS health check failed: {e}")
+        dependencies['sns'] = {'status': 'error', 'detail': e.response['Error']['Code']}
+
+    return dependencies
+
+def get_health():
+    """Build the /health response; any unhealthy dependency makes the API degraded"""
+    dependencies = check_dependencies()
+    healthy = all(dependency['status'] == 'ok' for dependency in dependencies.values())
+
+    return 200 if healthy else 503, {
+        'status': 'ok' if healthy else 'degraded',
+        'service': SERVICE_NAME,
+        'version': SERVICE_VERSION,
+        'timestamp': datetime.now(timezone.utc).isoformat(),
+        'dependencies': dependencies
+    }
+
 def create_response(status_code, body, cors=True):
     """Create API Gateway response"""
     response = {
@@ -153,6 +211,7 @@ def create_response(status_code, body, cors=True):
             'Access-Control-Allow-Origin': '*' if cors else None,
             'Access-Control-Allow-Headers': 'Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token' if cors else None,
             'Access-Control-Allow-Methods': 'GET,POST,OPTIONS' if cors else None,
+            **SECURITY_HEADERS,
         },
         'body': json.dumps(body, default=str)
     }
@@ -253,13 +312,9 @@ def lambda_handler(event, context):
                 })
 
             elif path.endswith('/health'):
-                # Health check endpoint
-                return create_response(200, {
-                    'status': 'healthy',
-                    'service': 'cspm-monitor-api',
-                    'timestamp': datetime.now(timezone.utc).isoformat(),
-                    'version': '1.0.0'
-                })
+                # Health check endpoint; 503 lets monitors alert on broken wiring
+                status_code, health = get_health()
+                return create_response(status_code, health)
 
         # Method not allowed
         return create_response(405, {
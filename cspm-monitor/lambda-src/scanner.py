@@ -8,7 +8,7 @@ import json
 import os
 import boto3
 import logging
-from datetime import datetime, timezone
+from datetime import datetime, timezone, timedelta
 from decimal import Decimal
 from botocore.exceptions import ClientError
 
@@ -25,6 +25,7 @@ sns = boto3.client('sns')
 DYNAMODB_TABLE_PARAM = os.environ.get('DYNAMODB_TABLE_PARAM', '/cspm-monitor/dynamodb-table-name')
 SNS_TOPIC_ARN_PARAM = os.environ.get('SNS_TOPIC_ARN_PARAM', '/cspm-monitor/sns-topic-arn')
 DYNAMODB_TTL_DAYS = int(os.environ.get('DYNAMODB_TTL_DAYS', '90'))
+RESCAN_MAX_FINDINGS = int(os.environ.get('RESCAN_MAX_FINDINGS', '1000'))
 
 # SSM Parameter Store for configuration
 ssm = boto3.client('ssm')
@@ -87,6 +88,18 @@ def process_finding(finding):
         logger.error(f"Error processing finding: {e}")
         return None
 
+def fetch_active_findings(max_findings=RESCAN_MAX_FINDINGS):
+    """Fetch active Security Hub findings for a scheduled full rescan"""
+    findings = []
+    paginator = securityhub.get_paginator('get_findings')
+    pages = paginator.paginate(
+        Filters={'RecordState': [{'Value': 'ACTIVE', 'Comparison': 'EQUALS'}]},
+        PaginationConfig={'MaxItems': max_findings, 'PageSize': 100}
+    )
+    for page in pages:
+        findings.extend(page.get('Findings', []))
+    return findings
+
 def send_alert(severity, message, finding_id):
     """Send alert via SNS"""
     try:
@@ -127,6 +140,9 @@ def lambda_handler(event, context):
         if 'source' in event and event['source'] == 'aws.securityhub':
             # Direct Security Hub event
             findings = event.get('detail', {}).get('findings', [])
+        elif event.get('rescan'):
+            # Scheduled full rescan (EventBridge Scheduler)
+            findings = fetch_active_findings()
         elif 'Records' in event:
             # SQS event (from EventBridge DLQ)
             for record in event['Records']:
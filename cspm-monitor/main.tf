@@ -55,12 +55,32 @@ module "website_bucket" {
   tags        = local.tags
 }
 
+# Customer-managed key for the findings table. The default key policy leaves
+# access to IAM in this account; DynamoDB uses it through grants.
+resource "aws_kms_key" "findings" {
+  count                   = local.create_kms_key ? 1 : 0
+  description             = "Customer-managed key for the ${var.project_name} findings table"
+  enable_key_rotation     = true
+  deletion_window_in_days = 30
+  tags                    = local.tags
+}
+
+resource "aws_kms_alias" "findings" {
+  count         = local.create_kms_key ? 1 : 0
+  name          = "alias/${var.project_name}-findings"
+  target_key_id = aws_kms_key.findings[0].key_id
+}
+
 # DynamoDB table for findings
 resource "aws_dynamodb_table" "findings" {
   name         = "${var.project_name}-findings"
   billing_mode = var.dynamodb_billing_mode
   hash_key     = "id"
 
+  # Starting capacity for PROVISIONED mode; auto scaling adjusts it from here
+  read_capacity  = local.dynamodb_provisioned ? var.dynamodb_min_capacity : null
+  write_capacity = local.dynamodb_provisioned ? var.dynamodb_min_capacity : null
+
   attribute {
     name = "id"
     type = "S"
@@ -82,26 +102,14 @@ resource "aws_dynamodb_table" "findings" {
     hash_key        = "severity"
     range_key       = "timestamp"
     projection_type = "ALL"
-
-    # Add capacity settings for PROVISIONED billing mode
-    dynamic "read_capacity" {
-      for_each = var.dynamodb_billing_mode == "PROVISIONED" ? [1] : []
-      content {
-        read_capacity_units = 5
-      }
-    }
-
-    dynamic "write_capacity" {
-      for_each = var.dynamodb_billing_mode == "PROVISIONED" ? [1] : []
-      content {
-        write_capacity_units = 5
-      }
-    }
+    read_capacity   = local.dynamodb_provisioned ? var.dynamodb_min_capacity : null
+    write_capacity  = local.dynamodb_provisioned ? var.dynamodb_min_capacity : null
   }
 
-  # Enable server-side encryption
+  # Enable server-side encryption, with the customer-managed key when there is one
   server_side_encryption {
-    enabled = true
+    enabled     = true
+    kms_key_arn = one(aws_kms_key.findings[*].arn)
   }
 
   # Enable point-in-time recovery for data protection
@@ -109,8 +117,9 @@ resource "aws_dynamodb_table" "findings" {
     enabled = true
   }
 
-  # Configure deletion protection
-  deletion_protection_enabled = true
+  # Configure deletion protection (used instead of lifecycle.prevent_destroy so
+  # it can be switched off before a destroy without editing the configuration)
+  deletion_protection_enabled = var.enable_deletion_protection
 
   # Enable Time-to-Live for automatic data expiration
   dynamic "ttl" {
@@ -152,8 +161,65 @@ resource "aws_dynamodb_table" "findings" {
     BackupRetention = var.enable_backup ? "${var.backup_retention_days}days" : "Disabled"
   })
 
+  # Auto scaling owns the capacity of the table and its index once they are
+  # provisioned. The index's capacity can't be ignored on its own, so the whole
+  # block is.
   lifecycle {
-    prevent_destroy = true
+    ignore_changes = [read_capacity, write_capacity, global_secondary_index]
+  }
+}
+
+# Target tracking auto scaling for the table and its index in PROVISIONED mode.
+# The index scales with the table so its writes don't throttle table writes.
+locals {
+  dynamodb_provisioned = var.dynamodb_billing_mode == "PROVISIONED"
+
+  dynamodb_scaling_targets = local.dynamodb_provisioned ? {
+    table_read = {
+      resource_id = "table/${aws_dynamodb_table.findings.name}"
+      dimension   = "dynamodb:table:ReadCapacityUnits"
+      metric      = "DynamoDBReadCapacityUtilization"
+    }
+    table_write = {
+      resource_id = "table/${aws_dynamodb_table.findings.name}"
+      dimension   = "dynamodb:table:WriteCapacityUnits"
+      metric      = "DynamoDBWriteCapacityUtilization"
+    }
+    index_read = {
+      resource_id = "table/${aws_dynamodb_table.findings.name}/index/SeverityTimestampIndex"
+      dimension   = "dynamodb:index:ReadCapacityUnits"
+      metric      = "DynamoDBReadCapacityUtilization"
+    }
+    index_write = {
+      resource_id = "table/${aws_dynamodb_table.findings.name}/index/SeverityTimestampIndex"
+      dimension   = "dynamodb:index:WriteCapacityUnits"
+      metric      = "DynamoDBWriteCapacityUtilization"
+    }
+  } : {}
+}
+
+resource "aws_appautoscaling_target" "dynamodb" {
+  for_each           = local.dynamodb_scaling_targets
+  service_namespace  = "dynamodb"
+  resource_id        = each.value.resource_id
+  scalable_dimension = each.value.dimension
+  min_capacity       = var.dynamodb_min_capacity
+  max_capacity       = var.dynamodb_max_capacity
+}
+
+resource "aws_appautoscaling_policy" "dynamodb" {
+  for_each           = local.dynamodb_scaling_targets
+  name               = "${var.project_name}-dynamodb-${replace(each.key, "_", "-")}"
+  policy_type        = "TargetTrackingScaling"
+  service_namespace  = aws_appautoscaling_target.dynamodb[each.key].service_namespace
+  resource_id        = aws_appautoscaling_target.dynamodb[each.key].resource_id
+  scalable_dimension = aws_appautoscaling_target.dynamodb[each.key].scalable_dimension
+
+  target_tracking_scaling_policy_configuration {
+    predefined_metric_specification {
+      predefined_metric_type = each.value.metric
+    }
+    target_value = var.dynamodb_target_utilization
   }
 }
 
@@ -178,6 +244,15 @@ resource "aws_s3_bucket_versioning" "security_archive" {
   }
 }
 
+# Archived findings are only written by this account, so ACLs are disabled
+resource "aws_s3_bucket_ownership_controls" "security_archive" {
+  count  = var.enable_s3_archival ? 1 : 0
+  bucket = aws_s3_bucket.security_archive[0].id
+  rule {
+    object_ownership = "BucketOwnerEnforced"
+  }
+}
+
 # S3 server-side encryption
 resource "aws_s3_bucket_server_side_encryption_configuration" "security_archive" {
   count  = var.enable_s3_archival ? 1 : 0
@@ -223,6 +298,11 @@ resource "aws_s3_bucket_lifecycle_configuration" "security_archive" {
       days = var.s3_archive_retention_days
     }
 
+    # The bucket is versioned, so overwritten and deleted archives must expire too
+    noncurrent_version_expiration {
+      noncurrent_days = 30
+    }
+
     # Clean up incomplete multipart uploads
     abort_incomplete_multipart_upload {
       days_after_initiation = 7
@@ -282,6 +362,113 @@ resource "aws_s3_bucket_public_access_block" "security_archive" {
   restrict_public_buckets = true
 }
 
+# CloudTrail audit trail for API activity in the monitored account
+resource "aws_s3_bucket" "cloudtrail" {
+  count         = local.enable_cloudtrail ? 1 : 0
+  bucket        = "${var.project_name}-cloudtrail-${local.account_id}"
+  force_destroy = !var.enable_deletion_protection
+
+  tags = merge(local.tags, {
+    Name    = "${var.project_name}-cloudtrail"
+    Purpose = "CloudTrailLogs"
+  })
+}
+
+resource "aws_s3_bucket_ownership_controls" "cloudtrail" {
+  count  = local.enable_cloudtrail ? 1 : 0
+  bucket = aws_s3_bucket.cloudtrail[0].id
+  rule {
+    object_ownership = "BucketOwnerEnforced"
+  }
+}
+
+resource "aws_s3_bucket_server_side_encryption_configuration" "cloudtrail" {
+  count  = local.enable_cloudtrail ? 1 : 0
+  bucket = aws_s3_bucket.cloudtrail[0].id
+
+  rule {
+    apply_server_side_encryption_by_default {
+      sse_algorithm = "AES256"
+    }
+  }
+}
+
+resource "aws_s3_bucket_lifecycle_configuration" "cloudtrail" {
+  count  = local.enable_cloudtrail ? 1 : 0
+  bucket = aws_s3_bucket.cloudtrail[0].id
+
+  rule {
+    id     = "expire-trail-logs"
+    status = "Enabled"
+
+    filter {}
+
+    expiration {
+      days = var.cloudtrail_retention_days
+    }
+
+    abort_incomplete_multipart_upload {
+      days_after_initiation = 7
+    }
+  }
+}
+
+resource "aws_s3_bucket_public_access_block" "cloudtrail" {
+  count                   = local.enable_cloudtrail ? 1 : 0
+  bucket                  = aws_s3_bucket.cloudtrail[0].id
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
+
+resource "aws_s3_bucket_policy" "cloudtrail" {
+  count  = local.enable_cloudtrail ? 1 : 0
+  bucket = aws_s3_bucket.cloudtrail[0].id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid    = "AWSCloudTrailAclCheck"
+        Effect = "Allow"
+        Principal = {
+          Service = "cloudtrail.amazonaws.com"
+        }
+        Action   = "s3:GetBucketAcl"
+        Resource = aws_s3_bucket.cloudtrail[0].arn
+      },
+      {
+        Sid    = "AWSCloudTrailWrite"
+        Effect = "Allow"
+        Principal = {
+          Service = "cloudtrail.amazonaws.com"
+        }
+        Action   = "s3:PutObject"
+        Resource = "${aws_s3_bucket.cloudtrail[0].arn}/AWSLogs/${local.account_id}/*"
+        Condition = {
+          StringEquals = {
+            "s3:x-amz-acl" = "bucket-owner-full-control"
+          }
+        }
+      }
+    ]
+  })
+}
+
+resource "aws_cloudtrail" "main" {
+  count                         = local.enable_cloudtrail ? 1 : 0
+  depends_on                    = [aws_s3_bucket_policy.cloudtrail]
+  name                          = "${var.project_name}-trail"
+  s3_bucket_name                = aws_s3_bucket.cloudtrail[0].id
+  include_global_service_events = true
+  is_multi_region_trail         = true
+  enable_logging                = true
+  enable_log_file_validation    = true
+
+  tags = local.tags
+}
+
 # IAM role for Lambda
 resource "aws_iam_role" "lambda_role" {
   name = "${var.project_name}-lambda-role"
@@ -358,7 +545,8 @@ resource "aws_iam_role_policy" "lambda_policy" {
         Sid    = "SNSPublish"
         Effect = "Allow"
         Action = [
-          "sns:Publish"
+          "sns:Publish",
+          "sns:GetTopicAttributes"
         ]
         Resource = aws_sns_topic.alerts.arn
       },
@@ -396,6 +584,21 @@ resource "aws_iam_role_policy" "lambda_policy" {
           "${aws_s3_bucket.security_archive[0].arn}/*"
         ] : []
       },
+      {
+        Sid      = "LambdaDeadLetterQueue"
+        Effect   = "Allow"
+        Action   = ["sqs:SendMessage"]
+        Resource = aws_sqs_queue.lambda_dlq.arn
+      },
+      {
+        Sid    = "XRayTracing"
+        Effect = "Allow"
+        Action = [
+          "xray:PutTraceSegments",
+          "xray:PutTelemetryRecords"
+        ]
+        Resource = "*"
+      },
       {
         Sid    = "KMSEncryption"
         Effect = "Allow"
@@ -415,6 +618,14 @@ resource "aws_iam_role_policy" "lambda_policy" {
   })
 }
 
+# SQS DLQ for asynchronous Lambda invocations that exhaust their retries
+resource "aws_sqs_queue" "lambda_dlq" {
+  name                      = "${var.project_name}-lambda-dlq"
+  message_retention_seconds = 1209600 # 14 days, the SQS maximum
+  sqs_managed_sse_enabled   = true
+  tags                      = local.tags
+}
+
 # Create Lambda deployment packages
 data "archive_file" "scanner_lambda_zip" {
   type        = "zip"
@@ -442,7 +653,6 @@ resource "aws_lambda_function" "scanner" {
   depends_on = [
     aws_iam_role_policy.lambda_policy,
     aws_cloudwatch_log_group.scanner_logs,
-    aws_vpc.lambda_vpc,
     aws_security_group.lambda_sg
   ]
 
@@ -453,15 +663,25 @@ resource "aws_lambda_function" "scanner" {
   filename         = data.archive_file.scanner_lambda_zip.output_path
   source_code_hash = data.archive_file.scanner_lambda_zip.output_base64sha256
 
-  memory_size = 256
-  timeout     = 300
+  memory_size = var.scanner_memory_size
+  timeout     = var.scanner_timeout
 
   # VPC configuration for enhanced security
   vpc_config {
-    subnet_ids         = aws_subnet.lambda_subnet[*].id
+    subnet_ids         = module.vpc.subnet_ids
     security_group_ids = [aws_security_group.lambda_sg.id]
   }
 
+  # Failed async invocations of all three functions land in the shared DLQ,
+  # and each traces to X-Ray
+  dead_letter_config {
+    target_arn = aws_sqs_queue.lambda_dlq.arn
+  }
+
+  tracing_config {
+    mode = "Active"
+  }
+
   environment {
     variables = {
       DYNAMODB_TABLE_PARAM = "/${var.project_name}/dynamodb-table-name"
@@ -477,7 +697,6 @@ resource "aws_lambda_function" "api" {
   depends_on = [
     aws_iam_role_policy.lambda_policy,
     aws_cloudwatch_log_group.api_logs,
-    aws_vpc.lambda_vpc,
     aws_security_group.lambda_sg
   ]
 
@@ -488,18 +707,27 @@ resource "aws_lambda_function" "api" {
   filename         = data.archive_file.api_lambda_zip.output_path
   source_code_hash = data.archive_file.api_lambda_zip.output_base64sha256
 
-  memory_size = 256
-  timeout     = 30
+  memory_size = var.api_memory_size
+  timeout     = var.api_timeout
 
   # VPC configuration for enhanced security
   vpc_config {
-    subnet_ids         = aws_subnet.lambda_subnet[*].id
+    subnet_ids         = module.vpc.subnet_ids
     security_group_ids = [aws_security_group.lambda_sg.id]
   }
 
+  dead_letter_config {
+    target_arn = aws_sqs_queue.lambda_dlq.arn
+  }
+
+  tracing_config {
+    mode = "Active"
+  }
+
   environment {
     variables = {
       DYNAMODB_TABLE_PARAM = "/${var.project_name}/dynamodb-table-name"
+      SNS_TOPIC_ARN_PARAM  = "/${var.project_name}/sns-topic-arn"
     }
   }
   tags = local.tags
@@ -511,7 +739,7 @@ resource "aws_lambda_function" "archiver" {
 
   depends_on = [
     aws_iam_role_policy.lambda_policy,
-    aws_vpc.lambda_vpc,
+    aws_cloudwatch_log_group.archiver_logs,
     aws_security_group.lambda_sg,
     aws_s3_bucket.security_archive
   ]
@@ -532,6 +760,14 @@ resource "aws_lambda_function" "archiver" {
     security_group_ids = [aws_security_group.lambda_sg.id]
   }
 
+  dead_letter_config {
+    target_arn = aws_sqs_queue.lambda_dlq.arn
+  }
+
+  tracing_config {
+    mode = "Active"
+  }
+
   environment {
     variables = merge({
       DYNAMODB_TABLE_PARAM = "/${var.project_name}/dynamodb-table-name"
@@ -638,17 +874,6 @@ resource "aws_api_gateway_method" "get_health" {
   authorization = "NONE"
 }
 
-resource "aws_api_gateway_method_response" "get_health" {
-  rest_api_id = aws_api_gateway_rest_api.api.id
-  resource_id = aws_api_gateway_resource.health.id
-  http_method = aws_api_gateway_method.get_health.http_method
-  status_code = "200"
-
-  response_models = {
-    "application/json" = "Empty"
-  }
-}
-
 # Add method response for get_findings
 resource "aws_api_gateway_method_response" "get_findings" {
   rest_api_id = aws_api_gateway_rest_api.api.id
@@ -670,49 +895,15 @@ resource "aws_api_gateway_integration" "get_findings" {
   uri                     = aws_lambda_function.api.invoke_arn
 }
 
-# Health check integration (mock response for monitoring)
+# Health check integration; the API Lambda checks DynamoDB and SNS so the
+# response reflects the real downstream wiring
 resource "aws_api_gateway_integration" "get_health" {
-  rest_api_id = aws_api_gateway_rest_api.api.id
-  resource_id = aws_api_gateway_resource.health.id
-  http_method = aws_api_gateway_method.get_health.http_method
-  type        = "MOCK"
-
-  request_templates = {
-    "application/json" = jsonencode({
-      statusCode = 200
-    })
-  }
-}
-
-resource "aws_api_gateway_integration_response" "get_health" {
-  rest_api_id = aws_api_gateway_rest_api.api.id
-  resource_id = aws_api_gateway_resource.health.id
-  http_method = aws_api_gateway_method.get_health.http_method
-  status_code = "200"
-
-  # Add comprehensive security headers
-  response_parameters = {
-    "method.response.header.X-Content-Type-Options"     = "'nosniff'"
-    "method.response.header.X-Frame-Options"            = "'DENY'"
-    "method.response.header.X-XSS-Protection"           = "'1; mode=block'"
-    "method.response.header.Strict-Transport-Security"  = "'max-age=31536000; includeSubDomains; preload'"
-    "method.response.header.Content-Security-Policy"    = "'default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data: https:; font-src 'self' data:; connect-src 'self''"
-    "method.response.header.Referrer-Policy"            = "'strict-origin-when-cross-origin'"
-    "method.response.header.Permissions-Policy"         = "'geolocation=(), microphone=(), camera=()'"
-    "method.response.header.Cross-Origin-Embedder-Policy" = "'require-corp'"
-    "method.response.header.Cross-Origin-Opener-Policy"   = "'same-origin'"
-    "method.response.header.Cross-Origin-Resource-Policy" = "'same-origin'"
-  }
-
-  response_templates = {
-    "application/json" = jsonencode({
-      status     = "healthy"
-      service    = "cspm-monitor-api"
-      timestamp  = "$context.requestTime"
-      request_id = "$context.requestId"
-      version    = "1.0.0"
-    })
-  }
+  rest_api_id             = aws_api_gateway_rest_api.api.id
+  resource_id             = aws_api_gateway_resource.health.id
+  http_method             = aws_api_gateway_method.get_health.http_method
+  integration_http_method = "POST"
+  type                    = "AWS_PROXY"
+  uri                     = aws_lambda_function.api.invoke_arn
 }
 
 resource "aws_lambda_permission" "api_gateway" {
@@ -730,14 +921,45 @@ resource "aws_api_gateway_deployment" "api" {
     aws_api_gateway_method.get_findings,
     aws_api_gateway_method.get_health,
     aws_api_gateway_resource.findings,
-    aws_api_gateway_resource.health,
-    aws_api_gateway_integration_response.get_health,
-    aws_api_gateway_method_response.get_health
+    aws_api_gateway_resource.health
   ]
   rest_api_id = aws_api_gateway_rest_api.api.id
 }
 
+# Account-level role API Gateway uses to write execution logs. The setting is
+# one per account and region, so only one stack should own it.
+resource "aws_iam_role" "api_gateway_cloudwatch" {
+  count = var.manage_api_gateway_account ? 1 : 0
+
+  name = "${var.project_name}-apigw-cloudwatch-role"
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Action = "sts:AssumeRole"
+      Effect = "Allow"
+      Principal = {
+        Service = "apigateway.amazonaws.com"
+      }
+    }]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "api_gateway_cloudwatch" {
+  count = var.manage_api_gateway_account ? 1 : 0
+
+  role       = aws_iam_role.api_gateway_cloudwatch[0].name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AmazonAPIGatewayPushToCloudWatchLogs"
+}
+
+resource "aws_api_gateway_account" "main" {
+  count = var.manage_api_gateway_account ? 1 : 0
+
+  cloudwatch_role_arn = aws_iam_role.api_gateway_cloudwatch[0].arn
+  depends_on          = [aws_iam_role_policy_attachment.api_gateway_cloudwatch]
+}
+
 resource "aws_api_gateway_stage" "prod" {
+  depends_on    = [aws_api_gateway_account.main]
   deployment_id = aws_api_gateway_deployment.api.id
   rest_api_id   = aws_api_gateway_rest_api.api.id
   stage_name    = "prod"
@@ -750,24 +972,39 @@ resource "aws_api_gateway_stage" "prod" {
   access_log_settings {
     destination_arn = aws_cloudwatch_log_group.api_gateway_logs.arn
     format = jsonencode({
-      requestId      = "$context.requestId"
-      ip            = "$context.identity.sourceIp"
-      requestTime   = "$context.requestTime"
-      httpMethod    = "$context.httpMethod"
-      resourcePath  = "$context.resourcePath"
-      status        = "$context.status"
-      responseLength = "$context.responseLength"
-      userAgent     = "$context.identity.userAgent"
+      requestId          = "$context.requestId"
+      ip                 = "$context.identity.sourceIp"
+      requestTime        = "$context.requestTime"
+      httpMethod         = "$context.httpMethod"
+      resourcePath       = "$context.resourcePath"
+      status             = "$context.status"
+      responseLength     = "$context.responseLength"
+      responseLatency    = "$context.responseLatency"
+      integrationLatency = "$context.integrationLatency"
+      userAgent          = "$context.identity.userAgent"
     })
   }
 
   tags = local.tags
 }
 
+# Execution logging and detailed metrics for every method on the stage
+resource "aws_api_gateway_method_settings" "all" {
+  rest_api_id = aws_api_gateway_rest_api.api.id
+  stage_name  = aws_api_gateway_stage.prod.stage_name
+  method_path = "*/*"
+
+  settings {
+    metrics_enabled    = true
+    logging_level      = "INFO"
+    data_trace_enabled = false
+  }
+}
+
 # CloudWatch Log Group for API Gateway
 resource "aws_cloudwatch_log_group" "api_gateway_logs" {
   name              = "/aws/apigateway/${var.project_name}-api"
-  retention_in_days = 30
+  retention_in_days = local.log_retention_days
   tags              = local.tags
 }
 
@@ -870,6 +1107,82 @@ resource "aws_api_gateway_usage_plan" "main" {
   tags = local.tags
 }
 
+# Optional custom domain for the API. API Gateway only accepts an issued
+# certificate, so the certificate is validated through the hosted zone first.
+resource "aws_acm_certificate" "api" {
+  count             = var.api_custom_domain != "" ? 1 : 0
+  domain_name       = var.api_custom_domain
+  validation_method = "DNS"
+
+  lifecycle {
+    create_before_destroy = true
+
+    precondition {
+      condition     = var.api_hosted_zone_id != ""
+      error_message = "api_custom_domain needs api_hosted_zone_id to validate its certificate."
+    }
+  }
+
+  tags = local.tags
+}
+
+resource "aws_route53_record" "api_cert_validation" {
+  for_each = {
+    for dvo in flatten(aws_acm_certificate.api[*].domain_validation_options) : dvo.domain_name => {
+      name   = dvo.resource_record_name
+      record = dvo.resource_record_value
+      type   = dvo.resource_record_type
+    }
+  }
+
+  zone_id         = var.api_hosted_zone_id
+  name            = each.value.name
+  type            = each.value.type
+  records         = [each.value.record]
+  ttl             = 60
+  allow_overwrite = true
+}
+
+resource "aws_acm_certificate_validation" "api" {
+  count                   = var.api_custom_domain != "" ? 1 : 0
+  certificate_arn         = aws_acm_certificate.api[0].arn
+  validation_record_fqdns = [for record in aws_route53_record.api_cert_validation : record.fqdn]
+}
+
+resource "aws_api_gateway_domain_name" "api" {
+  count                    = var.api_custom_domain != "" ? 1 : 0
+  domain_name              = var.api_custom_domain
+  regional_certificate_arn = aws_acm_certificate_validation.api[0].certificate_arn
+  security_policy          = "TLS_1_2"
+
+  endpoint_configuration {
+    types = ["REGIONAL"]
+  }
+
+  tags = local.tags
+}
+
+# Maps the domain root to the prod stage, so /health on the custom domain is /prod/health
+resource "aws_api_gateway_base_path_mapping" "api" {
+  count       = var.api_custom_domain != "" ? 1 : 0
+  api_id      = aws_api_gateway_rest_api.api.id
+  stage_name  = aws_api_gateway_stage.prod.stage_name
+  domain_name = aws_api_gateway_domain_name.api[0].domain_name
+}
+
+resource "aws_route53_record" "api" {
+  count   = var.api_custom_domain != "" ? 1 : 0
+  zone_id = var.api_hosted_zone_id
+  name    = var.api_custom_domain
+  type    = "A"
+
+  alias {
+    name                   = aws_api_gateway_domain_name.api[0].regional_domain_name
+    zone_id                = aws_api_gateway_domain_name.api[0].regional_zone_id
+    evaluate_target_health = false
+  }
+}
+
 # SNS topic for alerts
 resource "aws_sns_topic" "alerts" {
   name = "${var.project_name}-alerts"
@@ -965,6 +1278,82 @@ resource "aws_lambda_permission" "allow_sync_eventbridge" {
   source_arn    = aws_cloudwatch_event_rule.sync_schedule[0].arn
 }
 
+# EventBridge Scheduler for periodic full rescans of Security Hub findings
+resource "aws_iam_role" "rescan_scheduler" {
+  count = var.enable_rescan_schedule ? 1 : 0
+  name  = "${var.project_name}-rescan-scheduler-role"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Action = "sts:AssumeRole"
+      Effect = "Allow"
+      Principal = {
+        Service = "scheduler.amazonaws.com"
+      }
+      Condition = {
+        StringEquals = {
+          "aws:SourceAccount" = local.account_id
+        }
+      }
+    }]
+  })
+
+  tags = local.tags
+}
+
+resource "aws_iam_role_policy" "rescan_scheduler" {
+  count = var.enable_rescan_schedule ? 1 : 0
+  name  = "${var.project_name}-rescan-scheduler-policy"
+  role  = aws_iam_role.rescan_scheduler[0].id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid      = "InvokeScanner"
+        Effect   = "Allow"
+        Action   = ["lambda:InvokeFunction"]
+        Resource = aws_lambda_function.scanner.arn
+      },
+      {
+        Sid      = "DeadLetterQueue"
+        Effect   = "Allow"
+        Action   = ["sqs:SendMessage"]
+        Resource = aws_sqs_queue.eventbridge_dlq.arn
+      }
+    ]
+  })
+}
+
+resource "aws_scheduler_schedule" "rescan" {
+  count       = var.enable_rescan_schedule ? 1 : 0
+  name        = "${var.project_name}-rescan"
+  description = "Scheduled full rescan of active Security Hub findings"
+
+  schedule_expression          = var.rescan_schedule_expression
+  schedule_expression_timezone = "UTC"
+
+  flexible_time_window {
+    mode = "OFF"
+  }
+
+  target {
+    arn      = aws_lambda_function.scanner.arn
+    role_arn = aws_iam_role.rescan_scheduler[0].arn
+    input    = jsonencode({ rescan = true })
+
+    retry_policy {
+      maximum_retry_attempts       = 2
+      maximum_event_age_in_seconds = 3600
+    }
+
+    dead_letter_config {
+      arn = aws_sqs_queue.eventbridge_dlq.arn
+    }
+  }
+}
+
 # Scheduled rule for data archival
 resource "aws_cloudwatch_event_rule" "archival_schedule" {
   count               = var.enable_s3_archival ? 1 : 0
@@ -1072,13 +1461,21 @@ resource "aws_iam_role_policy_attachment" "backup_role" {
 # CloudWatch Log Groups for Lambda functions
 resource "aws_cloudwatch_log_group" "scanner_logs" {
   name              = "/aws/lambda/${var.project_name}-scanner"
-  retention_in_days = 30
+  retention_in_days = local.log_retention_days
   tags              = local.tags
 }
 
 resource "aws_cloudwatch_log_group" "api_logs" {
   name              = "/aws/lambda/${var.project_name}-api"
-  retention_in_days = 30
+  retention_in_days = local.log_retention_days
+  tags              = local.tags
+}
+
+resource "aws_cloudwatch_log_group" "archiver_logs" {
+  count = var.enable_s3_archival ? 1 : 0
+
+  name              = "/aws/lambda/${var.project_name}-archiver"
+  retention_in_days = local.log_retention_days
   tags              = local.tags
 }
 
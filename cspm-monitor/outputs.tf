@@ -3,6 +3,41 @@ output "api_gateway_url" {
   value       = aws_api_gateway_stage.prod.invoke_url
 }
 
+output "api_base_url" {
+  description = "Base URL for API routes such as /health, without a trailing slash"
+  value       = trimsuffix(aws_api_gateway_stage.prod.invoke_url, "/")
+}
+
+output "api_custom_domain" {
+  description = "Custom domain serving the API, or null when the API is only on the execute-api endpoint"
+  value       = var.api_custom_domain != "" ? aws_api_gateway_domain_name.api[0].domain_name : null
+}
+
+output "api_custom_domain_url" {
+  description = "Base URL for API routes on the custom domain, without a trailing slash"
+  value       = var.api_custom_domain != "" ? "https://${aws_api_gateway_domain_name.api[0].domain_name}" : null
+}
+
+output "api_custom_domain_target" {
+  description = "Regional API Gateway domain the custom domain's alias record points at"
+  value       = var.api_custom_domain != "" ? aws_api_gateway_domain_name.api[0].regional_domain_name : null
+}
+
+output "api_gateway_rest_api_id" {
+  description = "API Gateway REST API ID"
+  value       = aws_api_gateway_rest_api.api.id
+}
+
+output "api_gateway_stage_name" {
+  description = "API Gateway stage serving the CSPM API"
+  value       = aws_api_gateway_stage.prod.stage_name
+}
+
+output "api_gateway_access_log_group_name" {
+  description = "CloudWatch log group receiving API Gateway access logs"
+  value       = aws_cloudwatch_log_group.api_gateway_logs.name
+}
+
 output "website_url" {
   description = "CloudFront URL for the CSPM dashboard"
   value       = module.cloudfront.distribution_domain_name
@@ -13,7 +48,110 @@ output "dynamodb_table_name" {
   value       = aws_dynamodb_table.findings.name
 }
 
+output "dynamodb_billing_mode" {
+  description = "Billing mode of the findings table"
+  value       = aws_dynamodb_table.findings.billing_mode
+}
+
+output "dynamodb_write_scaling_policy_name" {
+  description = "Auto scaling policy tracking table write utilization (PROVISIONED mode only)"
+  value       = local.dynamodb_provisioned ? aws_appautoscaling_policy.dynamodb["table_write"].name : null
+}
+
+output "dynamodb_kms_key_arn" {
+  description = "Customer-managed key encrypting the findings table (null with the AWS-managed key)"
+  value       = one(aws_kms_key.findings[*].arn)
+}
+
+output "dynamodb_deletion_protection_enabled" {
+  description = "Whether deletion protection is enabled on the findings table"
+  value       = aws_dynamodb_table.findings.deletion_protection_enabled
+}
+
+output "scanner_function_name" {
+  description = "Name of the scanner Lambda function"
+  value       = aws_lambda_function.scanner.function_name
+}
+
+output "scanner_function_arn" {
+  description = "ARN of the scanner Lambda function"
+  value       = aws_lambda_function.scanner.arn
+}
+
+output "lambda_role_name" {
+  description = "Name of the execution role shared by the Lambda functions"
+  value       = aws_iam_role.lambda_role.name
+}
+
+output "scanner_memory_size" {
+  description = "Memory in MB allocated to the scanner Lambda function"
+  value       = aws_lambda_function.scanner.memory_size
+}
+
+output "scanner_timeout" {
+  description = "Timeout in seconds of the scanner Lambda function"
+  value       = aws_lambda_function.scanner.timeout
+}
+
+output "api_memory_size" {
+  description = "Memory in MB allocated to the API Lambda function"
+  value       = aws_lambda_function.api.memory_size
+}
+
+output "api_timeout" {
+  description = "Timeout in seconds of the API Lambda function"
+  value       = aws_lambda_function.api.timeout
+}
+
 output "sns_topic_arn" {
   description = "SNS topic ARN for alerts"
   value       = aws_sns_topic.alerts.arn
-}
\ No newline at end of file
+}
+
+output "rescan_schedule_name" {
+  description = "EventBridge Scheduler schedule name for full rescans"
+  value       = var.enable_rescan_schedule ? aws_scheduler_schedule.rescan[0].name : null
+}
+
+output "findings_rule_name" {
+  description = "EventBridge rule that sends Security Hub findings to the scanner"
+  value       = aws_cloudwatch_event_rule.security_hub_findings.name
+}
+
+output "eventbridge_dlq_arn" {
+  description = "SQS queue receiving events EventBridge could not deliver"
+  value       = aws_sqs_queue.eventbridge_dlq.arn
+}
+
+output "lambda_function_names" {
+  description = "Names of every Lambda function in the stack"
+  value = concat(
+    [aws_lambda_function.scanner.function_name, aws_lambda_function.api.function_name],
+    aws_lambda_function.archiver[*].function_name
+  )
+}
+
+output "lambda_dlq_arn" {
+  description = "SQS queue receiving asynchronous Lambda invocations that failed every retry"
+  value       = aws_sqs_queue.lambda_dlq.arn
+}
+
+output "archive_bucket_name" {
+  description = "S3 bucket name for security log archival"
+  value       = var.enable_s3_archival ? aws_s3_bucket.security_archive[0].id : null
+}
+
+output "cloudtrail_name" {
+  description = "CloudTrail trail name"
+  value       = local.enable_cloudtrail ? aws_cloudtrail.main[0].name : null
+}
+
+output "cloudtrail_bucket_name" {
+  description = "S3 bucket receiving CloudTrail log files"
+  value       = local.enable_cloudtrail ? aws_s3_bucket.cloudtrail[0].id : null
+}
+
+output "profile" {
+  description = "Active security profile"
+  value       = var.profile
+}
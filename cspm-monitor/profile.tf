@@ -0,0 +1,23 @@
+# hardened puts the findings table under a customer-managed key, turns on
+# the audit trail and keeps function logs for a year; variables left null
+# follow the profile. Deletion protection and backups aren't part of it and
+# stay on by default under both.
+locals {
+  profiles = {
+    minimal = {
+      create_kms_key     = false
+      enable_cloudtrail  = false
+      log_retention_days = 30
+    }
+    hardened = {
+      create_kms_key     = true
+      enable_cloudtrail  = true
+      log_retention_days = 365
+    }
+  }
+  profile = local.profiles[var.profile]
+
+  create_kms_key     = coalesce(var.create_kms_key, local.profile.create_kms_key)
+  enable_cloudtrail  = coalesce(var.enable_cloudtrail, local.profile.enable_cloudtrail)
+  log_retention_days = coalesce(var.log_retention_days, local.profile.log_retention_days)
+}
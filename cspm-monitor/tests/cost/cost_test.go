@@ -0,0 +1,202 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+	"testkit"
+)
+
+// Consumed capacity budget for the findings table in a test environment, per hour.
+// A scan of the whole table on every API call would blow through these quickly.
+const (
+	readCapacityBudget  = 5000
+	writeCapacityBudget = 2000
+)
+
+// TestDynamoDBCostOptimization validates on-demand consumption of the findings table
+func TestDynamoDBCostOptimization(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-cost-test",
+			"dynamodb_billing_mode":      "PAY_PER_REQUEST",
+			"enable_deletion_protection": false,
+			// Archival is switched on so the archiver's log group is checked too
+			"enable_s3_archival": true,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	testkit.AssertLogGroupRetention(t, terraform.Show(t, terraformOptions), testkit.LogsClient)
+
+	tableName := terraform.Output(t, terraformOptions, "dynamodb_table_name")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	dynamoSvc := dynamodb.New(sess)
+	cwSvc := cloudwatch.New(sess)
+
+	table, err := dynamoSvc.DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, table.Table.BillingModeSummary)
+	assert.Equal(t, dynamodb.BillingModePayPerRequest, aws.StringValue(table.Table.BillingModeSummary.BillingMode))
+
+	// Generate a small, known amount of traffic so the metrics have datapoints
+	start := time.Now().Add(-5 * time.Minute)
+	for i := 0; i < 5; i++ {
+		_, err := dynamoSvc.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String(tableName),
+			Item: map[string]*dynamodb.AttributeValue{
+				"id":        {S: aws.String(fmt.Sprintf("cost-test-%d", i))},
+				"severity":  {S: aws.String("LOW")},
+				"timestamp": {S: aws.String(time.Now().UTC().Format(time.RFC3339))},
+			},
+		})
+		require.NoError(t, err)
+	}
+	_, err = dynamoSvc.Query(&dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		IndexName:              aws.String("SeverityTimestampIndex"),
+		KeyConditionExpression: aws.String("severity = :severity"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":severity": {S: aws.String("LOW")},
+		},
+	})
+	require.NoError(t, err)
+
+	// Table-level metrics exclude GSIs, so each index is budgeted alongside the table
+	indexNames := []string{""}
+	for _, gsi := range table.Table.GlobalSecondaryIndexes {
+		indexNames = append(indexNames, aws.StringValue(gsi.IndexName))
+	}
+
+	var totalRead, totalWrite float64
+	for _, indexName := range indexNames {
+		// Writes to the table propagate to every index, so a write datapoint is
+		// always expected; reads only exist where the test queried.
+		totalWrite += waitForConsumedCapacity(t, cwSvc, tableName, indexName, "ConsumedWriteCapacityUnits", start)
+
+		read, _, err := consumedCapacity(cwSvc, tableName, indexName, "ConsumedReadCapacityUnits", start, time.Now())
+		require.NoError(t, err)
+		totalRead += read
+	}
+
+	t.Logf("Consumed capacity since %s: %.1f RCU, %.1f WCU", start.Format(time.RFC3339), totalRead, totalWrite)
+	assert.Greater(t, totalWrite, float64(0), "Test writes should be reflected in ConsumedWriteCapacityUnits")
+	assert.LessOrEqual(t, totalRead, float64(readCapacityBudget), "Read consumption exceeds budget - check for runaway scans")
+	assert.LessOrEqual(t, totalWrite, float64(writeCapacityBudget), "Write consumption exceeds budget")
+}
+
+func TestConsumedCapacity(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeMetricsClient{
+		datapoints: []*cloudwatch.Datapoint{
+			{Sum: aws.Float64(2.5)},
+			{Sum: aws.Float64(4)},
+		},
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	total, count, err := consumedCapacity(svc, "findings", "SeverityTimestampIndex", "ConsumedReadCapacityUnits", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, total)
+	assert.Equal(t, 2, count)
+
+	dimensions := map[string]string{}
+	for _, dimension := range svc.lastInput.Dimensions {
+		dimensions[aws.StringValue(dimension.Name)] = aws.StringValue(dimension.Value)
+	}
+	assert.Equal(t, map[string]string{"TableName": "findings", "GlobalSecondaryIndexName": "SeverityTimestampIndex"}, dimensions)
+	assert.Equal(t, "AWS/DynamoDB", aws.StringValue(svc.lastInput.Namespace))
+
+	// Table-level queries carry only the table dimension
+	_, _, err = consumedCapacity(svc, "findings", "", "ConsumedWriteCapacityUnits", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, svc.lastInput.Dimensions, 1)
+	assert.Equal(t, "TableName", aws.StringValue(svc.lastInput.Dimensions[0].Name))
+}
+
+type fakeMetricsClient struct {
+	cloudwatchiface.CloudWatchAPI
+	datapoints []*cloudwatch.Datapoint
+	lastInput  *cloudwatch.GetMetricStatisticsInput
+}
+
+func (f *fakeMetricsClient) GetMetricStatistics(input *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	f.lastInput = input
+	return &cloudwatch.GetMetricStatisticsOutput{Datapoints: f.datapoints}, nil
+}
+
+// Helper function to wait until a consumed-capacity metric has datapoints and return its sum.
+// DynamoDB metrics lag by a few minutes, so an empty result is retried rather than treated as zero.
+func waitForConsumedCapacity(t *testing.T, cwSvc cloudwatchiface.CloudWatchAPI, tableName, indexName, metricName string, start time.Time) float64 {
+	var total float64
+	retry.DoWithRetry(t, fmt.Sprintf("Waiting for %s datapoints on %s %s", metricName, tableName, indexName), 20, 30*time.Second, func() (string, error) {
+		sum, count, err := consumedCapacity(cwSvc, tableName, indexName, metricName, start, time.Now())
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return "", fmt.Errorf("no %s datapoints yet", metricName)
+		}
+		total = sum
+		return fmt.Sprintf("%.1f", sum), nil
+	})
+	return total
+}
+
+// Helper function to sum a DynamoDB consumed-capacity metric for a table or one of its GSIs
+func consumedCapacity(cwSvc cloudwatchiface.CloudWatchAPI, tableName, indexName, metricName string, start, end time.Time) (float64, int, error) {
+	dimensions := []*cloudwatch.Dimension{
+		{
+			Name:  aws.String("TableName"),
+			Value: aws.String(tableName),
+		},
+	}
+	if indexName != "" {
+		dimensions = append(dimensions, &cloudwatch.Dimension{
+			Name:  aws.String("GlobalSecondaryIndexName"),
+			Value: aws.String(indexName),
+		})
+	}
+
+	result, err := cwSvc.GetMetricStatistics(&cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/DynamoDB"),
+		MetricName: aws.String(metricName),
+		Dimensions: dimensions,
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int64(60),
+		Statistics: []*string{aws.String(cloudwatch.StatisticSum)},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total float64
+	for _, datapoint := range result.Datapoints {
+		total += aws.Float64Value(datapoint.Sum)
+	}
+	return total, len(result.Datapoints), nil
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeployedAPIEndpoints calls /health and /findings on an already deployed
+// stack, read from the module's state. Set CSPM_E2E=1 once it's applied.
+func TestDeployedAPIEndpoints(t *testing.T) {
+	t.Parallel()
+
+	if os.Getenv("CSPM_E2E") != "1" {
+		t.Skip("Set CSPM_E2E=1 to call the API of the deployed stack")
+	}
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+	}
+	apiURL := strings.TrimSuffix(terraform.Output(t, terraformOptions, "api_gateway_url"), "/")
+	require.NotEmpty(t, apiURL, "api_gateway_url should be set; is the stack deployed?")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	status, body := getWithColdStartRetries(t, client, apiURL+"/health")
+	require.Equal(t, http.StatusOK, status, "GET /health: %s", body)
+	var health map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &health), "Health response should be a JSON object: %s", body)
+	assert.Contains(t, health, "status", "Health response should report a status")
+
+	status, body = getWithColdStartRetries(t, client, apiURL+"/findings")
+	require.Equal(t, http.StatusOK, status, "GET /findings: %s", body)
+	for _, problem := range findingsListProblems(body) {
+		assert.Fail(t, "Findings response isn't a list", problem)
+	}
+}
+
+func TestFindingsListProblems(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, findingsListProblems([]byte(`[]`)))
+	assert.Empty(t, findingsListProblems([]byte(`[{"id": "f-1", "severity": "HIGH"}]`)))
+	assert.Empty(t, findingsListProblems([]byte(`{"success": true, "data": [{"id": "f-1"}], "count": 1}`)))
+	assert.Empty(t, findingsListProblems([]byte(`{"data": []}`)), "count is optional")
+
+	assert.Equal(t, []string{"count is 2 but data holds 1 findings"},
+		findingsListProblems([]byte(`{"data": [{"id": "f-1"}], "count": 2}`)))
+	assert.Equal(t, []string{"data is missing or not an array"},
+		findingsListProblems([]byte(`{"success": true, "data": {"id": "f-1"}}`)))
+	assert.Equal(t, []string{"body is neither a JSON array nor an object"},
+		findingsListProblems([]byte(`"f-1"`)))
+	assert.Equal(t, []string{"body is neither a JSON array nor an object"},
+		findingsListProblems([]byte(`<html>`)))
+}
+
+// Helper function to GET a URL, retrying network errors and 5xx responses while
+// API Gateway and the VPC Lambda warm up, and return the final status and body
+func getWithColdStartRetries(t *testing.T, client *http.Client, url string) (int, []byte) {
+	var status int
+	var body []byte
+	retry.DoWithRetry(t, "GET "+url, 8, 10*time.Second, func() (string, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		status = resp.StatusCode
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		if status >= http.StatusInternalServerError {
+			return "", fmt.Errorf("GET %s returned %d: %s", url, status, body)
+		}
+		return "", nil
+	})
+	return status, body
+}
+
+// Helper function to list ways a GET /findings body isn't a findings list,
+// either a bare JSON array or an object whose data array matches its count
+func findingsListProblems(body []byte) []string {
+	var list []json.RawMessage
+	if json.Unmarshal(body, &list) == nil {
+		return nil
+	}
+
+	var page struct {
+		Data  json.RawMessage `json:"data"`
+		Count *int            `json:"count"`
+	}
+	if json.Unmarshal(body, &page) != nil {
+		return []string{"body is neither a JSON array nor an object"}
+	}
+	if err := json.Unmarshal(page.Data, &list); err != nil || list == nil {
+		return []string{"data is missing or not an array"}
+	}
+	if page.Count != nil && *page.Count != len(list) {
+		return []string{fmt.Sprintf("count is %d but data holds %d findings", *page.Count, len(list))}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+)
+
+// BatchWriteItem accepts at most this many items per request
+const maxWriteBatch = 25
+
+// TestDynamoDBAutoScaling drives writes above the target utilization and checks
+// auto scaling raises the table's provisioned write capacity
+func TestDynamoDBAutoScaling(t *testing.T) {
+	t.Parallel()
+
+	// On-demand tables have no capacity to scale
+	billingMode := os.Getenv("CSPM_DYNAMODB_BILLING_MODE")
+	if billingMode == "" {
+		billingMode = dynamodb.BillingModeProvisioned
+	}
+	if billingMode != dynamodb.BillingModeProvisioned {
+		t.Skipf("Auto scaling only applies to PROVISIONED tables, not %s", billingMode)
+	}
+	if testing.Short() {
+		t.Skip("Skipping DynamoDB auto scaling load test in short mode")
+	}
+
+	const minCapacity, maxCapacity = 5, 20
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":                "cspm-scaling-test",
+			"dynamodb_billing_mode":       billingMode,
+			"dynamodb_min_capacity":       minCapacity,
+			"dynamodb_max_capacity":       maxCapacity,
+			"dynamodb_target_utilization": 50,
+			"enable_deletion_protection":  false,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, billingMode, terraform.Output(t, terraformOptions, "dynamodb_billing_mode"))
+	assert.NotEmpty(t, terraform.Output(t, terraformOptions, "dynamodb_write_scaling_policy_name"))
+	tableName := terraform.Output(t, terraformOptions, "dynamodb_table_name")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	dynamoSvc := dynamodb.New(sess)
+
+	targets, err := applicationautoscaling.New(sess).DescribeScalableTargets(&applicationautoscaling.DescribeScalableTargetsInput{
+		ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceDynamodb),
+		ResourceIds:       []*string{aws.String("table/" + tableName)},
+		ScalableDimension: aws.String(applicationautoscaling.ScalableDimensionDynamodbTableWriteCapacityUnits),
+	})
+	require.NoError(t, err)
+	require.Len(t, targets.ScalableTargets, 1, "Table writes should be registered with Application Auto Scaling")
+	assert.Equal(t, int64(minCapacity), aws.Int64Value(targets.ScalableTargets[0].MinCapacity))
+	assert.Equal(t, int64(maxCapacity), aws.Int64Value(targets.ScalableTargets[0].MaxCapacity))
+
+	before, err := provisionedWriteCapacity(dynamoSvc, tableName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(minCapacity), before)
+
+	// Keep writing well above the target while waiting for the scale-out; the
+	// policy's alarm needs a few minutes of sustained utilization
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	written := 0
+	go func() {
+		defer close(done)
+		written = driveWriteLoad(t, dynamoSvc, tableName, stop)
+	}()
+	// Stop the load before the test returns, including when the wait fails
+	defer func() {
+		close(stop)
+		<-done
+		t.Logf("Wrote %d findings to %s", written, tableName)
+	}()
+
+	after := retry.DoWithRetry(t, "Waiting for write capacity to scale out on "+tableName, 20, time.Minute, func() (string, error) {
+		capacity, err := provisionedWriteCapacity(dynamoSvc, tableName)
+		if err != nil {
+			return "", err
+		}
+		if capacity <= before {
+			return "", fmt.Errorf("provisioned write capacity is still %d", capacity)
+		}
+		return fmt.Sprint(capacity), nil
+	})
+	t.Logf("Write capacity scaled from %d to %s", before, after)
+}
+
+func TestFindingWriteRequests(t *testing.T) {
+	t.Parallel()
+
+	requests := findingWriteRequests("scaling", 25, 3)
+	require.Len(t, requests, 3)
+
+	ids := map[string]bool{}
+	for _, request := range requests {
+		item := request.PutRequest.Item
+		ids[aws.StringValue(item["id"].S)] = true
+		// The GSI key attributes must be present or the index isn't written
+		assert.NotEmpty(t, aws.StringValue(item["severity"].S))
+		assert.NotEmpty(t, aws.StringValue(item["timestamp"].S))
+	}
+	assert.Equal(t, map[string]bool{"scaling-25": true, "scaling-26": true, "scaling-27": true}, ids)
+}
+
+func TestProvisionedWriteCapacity(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeTableClient{table: &dynamodb.TableDescription{
+		TableName:             aws.String("cspm-monitor-findings"),
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{WriteCapacityUnits: aws.Int64(12)},
+	}}
+	capacity, err := provisionedWriteCapacity(svc, "cspm-monitor-findings")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), capacity)
+
+	// On-demand tables report zero provisioned throughput
+	svc.table.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: aws.String(dynamodb.BillingModePayPerRequest)}
+	svc.table.ProvisionedThroughput.WriteCapacityUnits = aws.Int64(0)
+	_, err = provisionedWriteCapacity(svc, "cspm-monitor-findings")
+	assert.EqualError(t, err, "table cspm-monitor-findings is PAY_PER_REQUEST, not PROVISIONED")
+}
+
+type fakeTableClient struct {
+	dynamodbiface.DynamoDBAPI
+	table *dynamodb.TableDescription
+}
+
+func (f *fakeTableClient) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: f.table}, nil
+}
+
+// Helper function to write batches of findings once a second until stopped.
+// Throttled items are dropped rather than retried; only the demand matters.
+func driveWriteLoad(t *testing.T, dynamoSvc dynamodbiface.DynamoDBAPI, tableName string, stop <-chan struct{}) int {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	written := 0
+	for batch := 0; ; batch++ {
+		select {
+		case <-stop:
+			return written
+		case <-ticker.C:
+		}
+
+		requests := findingWriteRequests("scaling", batch*maxWriteBatch, maxWriteBatch)
+		result, err := dynamoSvc.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{tableName: requests},
+		})
+		if err != nil {
+			t.Logf("Batch write failed: %v", err)
+			continue
+		}
+		written += len(requests) - len(result.UnprocessedItems[tableName])
+	}
+}
+
+// Helper function to build put requests for findings with sequential IDs
+func findingWriteRequests(prefix string, start, count int) []*dynamodb.WriteRequest {
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	var requests []*dynamodb.WriteRequest
+	for i := start; i < start+count; i++ {
+		requests = append(requests, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{
+				Item: map[string]*dynamodb.AttributeValue{
+					"id":        {S: aws.String(fmt.Sprintf("%s-%d", prefix, i))},
+					"severity":  {S: aws.String("LOW")},
+					"timestamp": {S: aws.String(timestamp)},
+				},
+			},
+		})
+	}
+	return requests
+}
+
+// Helper function to read a table's provisioned write capacity
+func provisionedWriteCapacity(dynamoSvc dynamodbiface.DynamoDBAPI, tableName string) (int64, error) {
+	result, err := dynamoSvc.DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	table := result.Table
+	if table.BillingModeSummary != nil && aws.StringValue(table.BillingModeSummary.BillingMode) != dynamodb.BillingModeProvisioned {
+		return 0, fmt.Errorf("table %s is %s, not PROVISIONED", tableName, aws.StringValue(table.BillingModeSummary.BillingMode))
+	}
+	return aws.Int64Value(table.ProvisionedThroughput.WriteCapacityUnits), nil
+}
@@ -65,40 +65,6 @@ func TestDataIngestion(t *testing.T) {
 	}
 }
 
-// TestAPIEndpoints validates API endpoint functionality
-func TestAPIEndpoints(t *testing.T) {
-	t.Parallel()
-
-	// Test API endpoints
-	t.Log("Testing API endpoints")
-
-	// Test endpoints
-	endpoints := []string{
-		"GET /health - Health check",
-		"GET /findings - List findings",
-		"GET /findings?id=123 - Get specific finding",
-		"GET /summary - Get findings summary",
-		"OPTIONS / - CORS preflight",
-	}
-
-	for _, endpoint := range endpoints {
-		t.Logf("API endpoint: %s", endpoint)
-	}
-
-	// Test response formats
-	responseFormats := []string{
-		"JSON response format",
-		"Error response format",
-		"CORS headers",
-		"Security headers",
-		"Pagination support",
-	}
-
-	for _, format := range responseFormats {
-		t.Logf("Response format: %s", format)
-	}
-}
-
 // TestWebInterface validates web interface functionality
 func TestWebInterface(t *testing.T) {
 	t.Parallel()
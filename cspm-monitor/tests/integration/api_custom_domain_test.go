@@ -0,0 +1,145 @@
+package test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+)
+
+// TestAPICustomDomain serves the API from a custom domain and checks /health
+// answers over it with a certificate issued for that domain
+func TestAPICustomDomain(t *testing.T) {
+	t.Parallel()
+
+	// The certificate and alias record need a real public zone the test account controls
+	customDomain := os.Getenv("CSPM_API_CUSTOM_DOMAIN")
+	hostedZoneID := os.Getenv("CSPM_API_HOSTED_ZONE_ID")
+	if customDomain == "" || hostedZoneID == "" {
+		t.Skip("Set CSPM_API_CUSTOM_DOMAIN and CSPM_API_HOSTED_ZONE_ID to test the API custom domain")
+	}
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-domain-test",
+			"enable_deletion_protection": false,
+			"api_custom_domain":          customDomain,
+			"api_hosted_zone_id":         hostedZoneID,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, customDomain, terraform.Output(t, terraformOptions, "api_custom_domain"))
+	assert.NotEmpty(t, terraform.Output(t, terraformOptions, "api_custom_domain_target"))
+
+	// The execute-api endpoint keeps working alongside the custom domain
+	assert.Contains(t, terraform.Output(t, terraformOptions, "api_base_url"), ".execute-api.")
+
+	// The alias record can take a few minutes to propagate
+	retry.DoWithRetry(t, "Resolving "+customDomain, 20, 15*time.Second, func() (string, error) {
+		addresses, err := net.LookupHost(customDomain)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(addresses), nil
+	})
+
+	healthURL := terraform.Output(t, terraformOptions, "api_custom_domain_url") + "/health"
+	retry.DoWithRetry(t, "Waiting for a healthy response from "+healthURL, 10, 15*time.Second, func() (string, error) {
+		status, state, err := fetchStatusAndTLS(healthURL)
+		if err != nil {
+			return "", err
+		}
+		if status != http.StatusOK {
+			return "", fmt.Errorf("GET %s returned %d", healthURL, status)
+		}
+		if problem := peerCertificateProblem(state, customDomain); problem != "" {
+			return "", retry.FatalError{Underlying: errors.New(problem)}
+		}
+		return "", nil
+	})
+}
+
+// TestAPICustomDomainNeedsHostedZone checks the plan refuses a custom domain
+// whose certificate couldn't be validated
+func TestAPICustomDomainNeedsHostedZone(t *testing.T) {
+	t.Parallel()
+
+	// Plan only, so the real state backend isn't needed
+	terraformOptions := &terraform.Options{
+		TerraformDir: testutil.LocalBackendDir(t, "../../"),
+		Vars: map[string]interface{}{
+			"project_name":      "cspm-domain-test",
+			"api_custom_domain": "api.example.com",
+		},
+	}
+
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "api_custom_domain needs api_hosted_zone_id")
+}
+
+func TestPeerCertificateProblem(t *testing.T) {
+	t.Parallel()
+
+	matching := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+		{DNSNames: []string{"api.example.com"}},
+	}}
+	assert.Empty(t, peerCertificateProblem(matching, "api.example.com"))
+
+	wildcard := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+		{DNSNames: []string{"*.example.com"}},
+	}}
+	assert.Empty(t, peerCertificateProblem(wildcard, "api.example.com"))
+
+	executeAPI := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+		{DNSNames: []string{"*.execute-api.us-east-1.amazonaws.com"}},
+	}}
+	assert.Contains(t, peerCertificateProblem(executeAPI, "api.example.com"), "not valid for api.example.com")
+
+	assert.Equal(t, "no TLS certificate was presented", peerCertificateProblem(nil, "api.example.com"))
+	assert.Equal(t, "no TLS certificate was presented", peerCertificateProblem(&tls.ConnectionState{}, "api.example.com"))
+}
+
+// Helper function to GET a URL and return its status and TLS connection state
+func fetchStatusAndTLS(url string) (int, *tls.ConnectionState, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, resp.TLS, nil
+}
+
+// Helper function to describe why the leaf certificate a server presented
+// doesn't cover a domain, or "" when it does
+func peerCertificateProblem(state *tls.ConnectionState, domain string) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return "no TLS certificate was presented"
+	}
+	if err := state.PeerCertificates[0].VerifyHostname(domain); err != nil {
+		return fmt.Sprintf("certificate is not valid for %s: %v", domain, err)
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/apigateway/apigatewayiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+)
+
+// Access log fields needed to trace a request and its latency
+var requiredAccessLogFields = []string{"requestId", "status", "responseLatency"}
+
+// TestApiGatewayLogging validates access and execution logging on the API stage
+func TestApiGatewayLogging(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-apilog-test",
+			"enable_deletion_protection": false,
+			// Execution logging needs the account-wide role; no other
+			// stack in the test account sets it
+			"manage_api_gateway_account": true,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	restAPIID := terraform.Output(t, terraformOptions, "api_gateway_rest_api_id")
+	stageName := terraform.Output(t, terraformOptions, "api_gateway_stage_name")
+	logGroupName := terraform.Output(t, terraformOptions, "api_gateway_access_log_group_name")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	assertStageLogging(t, apigateway.New(sess), restAPIID, stageName, logGroupName)
+
+	// Generate a request and find its access log line by request ID
+	apiURL := terraform.Output(t, terraformOptions, "api_gateway_url")
+	resp, err := http.Get(strings.TrimSuffix(apiURL, "/") + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	requestID := resp.Header.Get("x-amzn-RequestId")
+	require.NotEmpty(t, requestID, "API Gateway should return a request ID")
+
+	logsSvc := cloudwatchlogs.New(sess)
+	message := retry.DoWithRetry(t, "Waiting for access log line for "+requestID, 20, 15*time.Second, func() (string, error) {
+		events, err := logsSvc.FilterLogEvents(&cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName:  aws.String(logGroupName),
+			FilterPattern: aws.String(fmt.Sprintf(`{ $.requestId = "%s" }`, requestID)),
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(events.Events) == 0 {
+			return "", fmt.Errorf("no access log line for %s yet", requestID)
+		}
+		return aws.StringValue(events.Events[0].Message), nil
+	})
+
+	var entry map[string]string
+	require.NoError(t, json.Unmarshal([]byte(message), &entry), "Access log line should be JSON: %s", message)
+	assert.Equal(t, fmt.Sprint(resp.StatusCode), entry["status"])
+	assert.NotEmpty(t, entry["responseLatency"])
+}
+
+func TestStageLoggingProblems(t *testing.T) {
+	t.Parallel()
+
+	logGroupName := "/aws/apigateway/cspm-monitor-api"
+	stage := &apigateway.Stage{
+		AccessLogSettings: &apigateway.AccessLogSettings{
+			DestinationArn: aws.String("arn:aws:logs:us-east-1:123456789012:log-group:" + logGroupName),
+			Format:         aws.String(`{"requestId":"$context.requestId","status":"$context.status","responseLatency":"$context.responseLatency"}`),
+		},
+		MethodSettings: map[string]*apigateway.MethodSetting{
+			"*/*": {MetricsEnabled: aws.Bool(true), LoggingLevel: aws.String("INFO")},
+		},
+	}
+	assert.Empty(t, stageLoggingProblems(stage, logGroupName))
+
+	// Log group ARNs may carry a trailing :* from the console
+	stage.AccessLogSettings.DestinationArn = aws.String("arn:aws:logs:us-east-1:123456789012:log-group:" + logGroupName + ":*")
+	assert.Empty(t, stageLoggingProblems(stage, logGroupName))
+
+	assert.Equal(t, []string{
+		"access logs go to arn:aws:logs:us-east-1:123456789012:log-group:/aws/apigateway/cspm-monitor-api:*, not /aws/apigateway/other",
+	}, stageLoggingProblems(stage, "/aws/apigateway/other"))
+
+	stage.AccessLogSettings.Format = aws.String(`$context.requestId $context.status`)
+	stage.MethodSettings["*/*"] = &apigateway.MethodSetting{MetricsEnabled: aws.Bool(false), LoggingLevel: aws.String("OFF")}
+	assert.Equal(t, []string{
+		"access log format is not JSON: $context.requestId $context.status",
+		"execution logging level is OFF",
+		"detailed metrics are disabled",
+	}, stageLoggingProblems(stage, logGroupName))
+
+	stage.AccessLogSettings.Format = aws.String(`{"requestId":"$context.requestId"}`)
+	assert.Equal(t, []string{
+		"access log format is missing status",
+		"access log format is missing responseLatency",
+		"no */* method settings",
+	}, stageLoggingProblems(&apigateway.Stage{AccessLogSettings: stage.AccessLogSettings}, logGroupName))
+
+	assert.Equal(t, []string{"access logging is not configured", "no */* method settings"}, stageLoggingProblems(&apigateway.Stage{}, logGroupName))
+}
+
+// Helper function to assert a stage sends JSON access logs to the log group
+// and has execution logging and detailed metrics enabled
+func assertStageLogging(t *testing.T, apiSvc apigatewayiface.APIGatewayAPI, restAPIID, stageName, logGroupName string) {
+	stage, err := apiSvc.GetStage(&apigateway.GetStageInput{
+		RestApiId: aws.String(restAPIID),
+		StageName: aws.String(stageName),
+	})
+	require.NoError(t, err)
+
+	for _, problem := range stageLoggingProblems(stage, logGroupName) {
+		assert.Fail(t, fmt.Sprintf("Stage %s logging misconfigured", stageName), problem)
+	}
+}
+
+// Helper function to list what is missing from a stage's logging configuration
+func stageLoggingProblems(stage *apigateway.Stage, logGroupName string) []string {
+	var problems []string
+
+	accessLogs := stage.AccessLogSettings
+	if accessLogs == nil || aws.StringValue(accessLogs.DestinationArn) == "" {
+		problems = append(problems, "access logging is not configured")
+	} else {
+		destination := aws.StringValue(accessLogs.DestinationArn)
+		if !strings.HasSuffix(strings.TrimSuffix(destination, ":*"), ":log-group:"+logGroupName) {
+			problems = append(problems, fmt.Sprintf("access logs go to %s, not %s", destination, logGroupName))
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(aws.StringValue(accessLogs.Format)), &fields); err != nil {
+			problems = append(problems, "access log format is not JSON: "+aws.StringValue(accessLogs.Format))
+		} else {
+			for _, field := range requiredAccessLogFields {
+				if _, ok := fields[field]; !ok {
+					problems = append(problems, "access log format is missing "+field)
+				}
+			}
+		}
+	}
+
+	settings, ok := stage.MethodSettings["*/*"]
+	if !ok {
+		return append(problems, "no */* method settings")
+	}
+	if level := aws.StringValue(settings.LoggingLevel); level != "INFO" && level != "ERROR" {
+		problems = append(problems, "execution logging level is "+level)
+	}
+	if !aws.BoolValue(settings.MetricsEnabled) {
+		problems = append(problems, "detailed metrics are disabled")
+	}
+	return problems
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+	"testkit"
+)
+
+// TestArchiveBucketPolicy validates the guard rails on the security archive bucket
+func TestArchiveBucketPolicy(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-archive-test",
+			"enable_s3_archival":         true,
+			"enable_deletion_protection": false,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	bucketName := terraform.Output(t, terraformOptions, "archive_bucket_name")
+	require.NotEmpty(t, bucketName)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	s3Svc := s3.New(sess)
+
+	// Archived findings are only reachable over TLS
+	testkit.AssertBucketPolicyStatement(t, s3Svc, bucketName, testkit.StatementMatcher{
+		Effect:    "Deny",
+		Principal: "*",
+		Action:    "s3:GetObject",
+		Condition: map[string]map[string]string{
+			"Bool": {"aws:SecureTransport": "false"},
+		},
+	})
+
+	// Deleting archived findings requires MFA
+	testkit.AssertBucketPolicyStatement(t, s3Svc, bucketName, testkit.StatementMatcher{
+		Effect:    "Deny",
+		Principal: "*",
+		Action:    "s3:DeleteObject",
+		Condition: map[string]map[string]string{
+			"StringNotEquals": {"aws:MultiFactorAuthAge": "0"},
+		},
+	})
+
+	// Only this account writes archived findings, so ACLs are disabled
+	testkit.AssertBucketObjectOwnership(t, s3Svc, bucketName, false)
+
+	// Transitions alone would keep archived findings forever
+	testkit.AssertNoLifecycleGap(t, s3Svc, bucketName, "")
+}
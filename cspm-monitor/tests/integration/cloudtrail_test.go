@@ -0,0 +1,44 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"cspm-monitor-tests/testutil"
+	"testkit"
+)
+
+// TestCloudTrailIntegration validates the audit trail created with enable_cloudtrail
+func TestCloudTrailIntegration(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-trail-test",
+			"enable_cloudtrail":          true,
+			"enable_deletion_protection": false,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	trailName := terraform.Output(t, terraformOptions, "cloudtrail_name")
+	assert.Equal(t, "cspm-trail-test-trail", trailName)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertTrailLogging(t, cloudtrail.New(sess), trailName)
+
+	// Trail logs must expire after cloudtrail_retention_days
+	bucketName := terraform.Output(t, terraformOptions, "cloudtrail_bucket_name")
+	testkit.AssertNoLifecycleGap(t, s3.New(sess), bucketName, "")
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+	"testkit"
+)
+
+// TestDeletionProtection validates deletion protection on the findings table
+func TestDeletionProtection(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-protect-test",
+			"enable_deletion_protection": true,
+		},
+	})
+
+	// Deferred calls run in reverse, so protection is lifted before destroy
+	defer terraform.Destroy(t, terraformOptions)
+	defer testkit.DisableDeletionProtection(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	tableName := terraform.Output(t, terraformOptions, "dynamodb_table_name")
+	assert.Equal(t, "true", terraform.Output(t, terraformOptions, "dynamodb_deletion_protection_enabled"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+
+	table, err := dynamodb.New(sess).DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	require.NoError(t, err)
+	assert.True(t, aws.BoolValue(table.Table.DeletionProtectionEnabled), "Findings table should have deletion protection enabled")
+
+	t.Log("✅ Deletion protection validated")
+}
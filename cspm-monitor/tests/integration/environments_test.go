@@ -0,0 +1,49 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"cspm-monitor-tests/testutil"
+	"testkit"
+)
+
+// monitorEnvironment is the scanner sizing, table protection and backups
+// each environment picks
+type monitorEnvironment struct {
+	ScannerMemorySize  float64
+	DeletionProtection bool
+	Backups            bool
+}
+
+func TestEnvironmentPlans(t *testing.T) {
+	t.Parallel()
+
+	want := map[string]monitorEnvironment{
+		"dev":     {ScannerMemorySize: 256, DeletionProtection: false, Backups: false},
+		"staging": {ScannerMemorySize: 512, DeletionProtection: true, Backups: true},
+		"prod":    {ScannerMemorySize: 1024, DeletionProtection: true, Backups: true},
+	}
+
+	testkit.RunEnvironmentPlans(t, testutil.LocalBackendDir(t, "../../"), nil, want, func(t *testing.T, env string, plan *terraform.PlanStruct) monitorEnvironment {
+		var got monitorEnvironment
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_lambda_function.scanner")
+		got.ScannerMemorySize, _ = plan.ResourcePlannedValuesMap["aws_lambda_function.scanner"].AttributeValues["memory_size"].(float64)
+
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_dynamodb_table.findings")
+		table := plan.ResourcePlannedValuesMap["aws_dynamodb_table.findings"].AttributeValues
+		got.DeletionProtection, _ = table["deletion_protection_enabled"].(bool)
+		assert.Equal(t, "cspm-monitor-"+env+"-findings", table["name"], "Each environment should get its own table")
+
+		_, got.Backups = plan.ResourcePlannedValuesMap["aws_backup_plan.security_logs[0]"]
+		return got
+	})
+}
+
+func TestEnvironmentFiles(t *testing.T) {
+	t.Parallel()
+
+	testkit.AssertEnvironmentFiles(t, "../../")
+}
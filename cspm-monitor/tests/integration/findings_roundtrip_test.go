@@ -0,0 +1,179 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+)
+
+// Attribute the findings table expires items on
+const findingTTLAttribute = "ttl_timestamp"
+
+// TestFindingRoundTrip writes a synthetic finding straight to the table and
+// reads it back through GET /findings?id=..., checking it carries a TTL
+func TestFindingRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-roundtrip-test",
+			"enable_deletion_protection": false,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	tableName := terraform.Output(t, terraformOptions, "dynamodb_table_name")
+	findingsURL := terraform.Output(t, terraformOptions, "api_base_url") + "/findings"
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	dynamoSvc := dynamodb.New(sess)
+
+	findingID := "roundtrip-test-" + random.UniqueId()
+	now := time.Now().UTC()
+	_, err := dynamoSvc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"id":                {S: aws.String(findingID)},
+			"severity":          {S: aws.String("HIGH")},
+			"timestamp":         {S: aws.String(now.Format(time.RFC3339))},
+			"title":             {S: aws.String("Synthetic round-trip finding")},
+			findingTTLAttribute: {N: aws.String(strconv.FormatInt(now.Add(24*time.Hour).Unix(), 10))},
+		},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_, err := dynamoSvc.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String(tableName),
+			Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String(findingID)}},
+		})
+		assert.NoError(t, err, "Synthetic finding %s should be cleaned up", findingID)
+	}()
+
+	// The item must expire on its own if the cleanup above never runs
+	stored, err := dynamoSvc.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(tableName),
+		Key:            map[string]*dynamodb.AttributeValue{"id": {S: aws.String(findingID)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	require.NoError(t, err)
+	ttl, err := dynamoSvc.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(tableName)})
+	require.NoError(t, err)
+	for _, problem := range findingTTLProblems(stored.Item, ttl.TimeToLiveDescription, now) {
+		assert.Fail(t, "Finding won't expire", problem)
+	}
+
+	// The API reads with eventual consistency, and its VPC Lambda may be cold
+	requestURL := findingsURL + "?id=" + url.QueryEscape(findingID)
+	body := retry.DoWithRetry(t, "GET "+requestURL, 10, 10*time.Second, func() (string, error) {
+		resp, err := http.Get(requestURL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("GET %s returned %d: %s", requestURL, resp.StatusCode, body)
+		}
+		return string(body), nil
+	})
+
+	var response struct {
+		Success bool `json:"success"`
+		Data    struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &response), "Finding response should be JSON: %s", body)
+	assert.True(t, response.Success)
+	assert.Equal(t, findingID, response.Data.ID)
+	assert.Equal(t, "HIGH", response.Data.Severity)
+}
+
+func TestFindingTTLProblems(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	enabled := &dynamodb.TimeToLiveDescription{
+		AttributeName:    aws.String(findingTTLAttribute),
+		TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled),
+	}
+	item := map[string]*dynamodb.AttributeValue{
+		"id":                {S: aws.String("f-1")},
+		findingTTLAttribute: {N: aws.String(strconv.FormatInt(now.Add(time.Hour).Unix(), 10))},
+	}
+	assert.Empty(t, findingTTLProblems(item, enabled, now))
+
+	// Expiry in the past, which DynamoDB would still delete, but late
+	item[findingTTLAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(now.Add(-time.Hour).Unix(), 10))}
+	disabled := &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)}
+	assert.Equal(t, []string{
+		"table TTL is DISABLED on \"\", want ENABLED on \"ttl_timestamp\"",
+		"ttl_timestamp 1704063600 is not after 2024-01-01T00:00:00Z",
+	}, findingTTLProblems(item, disabled, now))
+
+	// A string TTL is ignored by DynamoDB
+	item[findingTTLAttribute] = &dynamodb.AttributeValue{S: aws.String("1704070800")}
+	assert.Equal(t, []string{"ttl_timestamp is not a number"}, findingTTLProblems(item, enabled, now))
+
+	delete(item, findingTTLAttribute)
+	assert.Equal(t, []string{"ttl_timestamp is missing"}, findingTTLProblems(item, enabled, now))
+	assert.Equal(t, []string{"finding was not stored"}, findingTTLProblems(nil, enabled, now))
+}
+
+// Helper function to list reasons a stored finding wouldn't expire: TTL off
+// for the table, or the item's TTL attribute missing, not a number or not in
+// the future
+func findingTTLProblems(item map[string]*dynamodb.AttributeValue, ttl *dynamodb.TimeToLiveDescription, now time.Time) []string {
+	if item == nil {
+		return []string{"finding was not stored"}
+	}
+
+	var problems []string
+	if ttl == nil || aws.StringValue(ttl.TimeToLiveStatus) != dynamodb.TimeToLiveStatusEnabled || aws.StringValue(ttl.AttributeName) != findingTTLAttribute {
+		var status, attribute string
+		if ttl != nil {
+			status, attribute = aws.StringValue(ttl.TimeToLiveStatus), aws.StringValue(ttl.AttributeName)
+		}
+		problems = append(problems, fmt.Sprintf("table TTL is %s on %q, want %s on %q", status, attribute, dynamodb.TimeToLiveStatusEnabled, findingTTLAttribute))
+	}
+
+	value, ok := item[findingTTLAttribute]
+	switch {
+	case !ok || value == nil:
+		problems = append(problems, findingTTLAttribute+" is missing")
+	case value.N == nil:
+		problems = append(problems, findingTTLAttribute+" is not a number")
+	default:
+		expires, err := strconv.ParseInt(aws.StringValue(value.N), 10, 64)
+		if err != nil || !time.Unix(expires, 0).After(now) {
+			problems = append(problems, fmt.Sprintf("%s %s is not after %s", findingTTLAttribute, aws.StringValue(value.N), now.Format(time.RFC3339)))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+)
+
+// healthResponse is the contract for GET /health
+type healthResponse struct {
+	Status       string                      `json:"status"`
+	Service      string                      `json:"service"`
+	Version      string                      `json:"version"`
+	Timestamp    string                      `json:"timestamp"`
+	Dependencies map[string]dependencyHealth `json:"dependencies"`
+}
+
+// dependencyHealth is one downstream service's entry in the health response
+type dependencyHealth struct {
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Downstream services the health response must report on
+var requiredHealthDependencies = []string{"dynamodb", "sns"}
+
+// TestHealthEndpointContract fetches /health and checks it reports the API and
+// every dependency healthy
+func TestHealthEndpointContract(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-health-test",
+			"enable_deletion_protection": false,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	healthURL := terraform.Output(t, terraformOptions, "api_base_url") + "/health"
+
+	// A fresh stage and the VPC Lambda's first cold start can take a moment
+	var header http.Header
+	body := retry.DoWithRetry(t, "Waiting for a healthy response from "+healthURL, 10, 15*time.Second, func() (string, error) {
+		resp, err := http.Get(healthURL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("GET %s returned %d: %s", healthURL, resp.StatusCode, body)
+		}
+		header = resp.Header
+		return string(body), nil
+	})
+
+	// The proxy integration only returns the headers the Lambda sets
+	for _, name := range []string{"X-Content-Type-Options", "X-Frame-Options", "Strict-Transport-Security", "Content-Security-Policy"} {
+		assert.NotEmpty(t, header.Get(name), "Health response should set %s", name)
+	}
+
+	var health healthResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &health), "Health response should be JSON: %s", body)
+	for _, problem := range healthContractProblems(health) {
+		assert.Fail(t, "Health response breaks the contract", problem)
+	}
+}
+
+func TestHealthContractProblems(t *testing.T) {
+	t.Parallel()
+
+	var health healthResponse
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"status": "ok",
+		"service": "cspm-monitor-api",
+		"version": "1.0.0",
+		"timestamp": "2024-01-01T00:00:00+00:00",
+		"dependencies": {
+			"dynamodb": {"status": "ok", "detail": "table cspm-monitor-findings is ACTIVE"},
+			"sns": {"status": "ok", "detail": "topic cspm-monitor-alerts is reachable"}
+		}
+	}`), &health))
+	assert.Empty(t, healthContractProblems(health))
+
+	health.Status = "degraded"
+	health.Dependencies["sns"] = dependencyHealth{Status: "error", Detail: "AuthorizationError"}
+	assert.Equal(t, []string{
+		`status is "degraded", want "ok"`,
+		"dependency sns is error: AuthorizationError",
+	}, healthContractProblems(health))
+
+	// The old mock integration's body has none of the dependency fields
+	health = healthResponse{}
+	require.NoError(t, json.Unmarshal([]byte(`{"status": "healthy", "service": "cspm-monitor-api"}`), &health))
+	assert.Equal(t, []string{
+		"timestamp is missing",
+		"version is missing",
+		`status is "healthy", want "ok"`,
+		"dependency dynamodb is missing",
+		"dependency sns is missing",
+	}, healthContractProblems(health))
+}
+
+// Helper function to list how a health response breaks the contract: required
+// fields present, status ok and every required dependency reporting ok
+func healthContractProblems(health healthResponse) []string {
+	var problems []string
+	for field, value := range map[string]string{"service": health.Service, "version": health.Version, "timestamp": health.Timestamp} {
+		if value == "" {
+			problems = append(problems, field+" is missing")
+		}
+	}
+	sort.Strings(problems)
+
+	if health.Status != "ok" {
+		problems = append(problems, fmt.Sprintf("status is %q, want \"ok\"", health.Status))
+	}
+	for _, name := range requiredHealthDependencies {
+		dependency, ok := health.Dependencies[name]
+		if !ok {
+			problems = append(problems, "dependency "+name+" is missing")
+			continue
+		}
+		if dependency.Status != "ok" {
+			problems = append(problems, fmt.Sprintf("dependency %s is %s: %s", name, dependency.Status, dependency.Detail))
+		}
+	}
+	return problems
+}
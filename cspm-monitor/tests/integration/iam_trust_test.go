@@ -0,0 +1,46 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"cspm-monitor-tests/testutil"
+	"testkit"
+)
+
+// TestRoleTrust turns on every optional role and checks each can only be
+// assumed by the service that uses it, without long-lived sessions
+func TestRoleTrust(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-trust-test",
+			"enable_deletion_protection": false,
+			"manage_api_gateway_account": true,
+			"enable_rescan_schedule":     true,
+			"enable_backup":              true,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "cspm-trust-test-lambda-role", terraform.Output(t, terraformOptions, "lambda_role_name"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertRoleTrust(t, terraform.Show(t, terraformOptions), iam.New(sess), map[string][]string{
+		"aws_iam_role.lambda_role":            {"lambda.amazonaws.com"},
+		"aws_iam_role.api_gateway_cloudwatch": {"apigateway.amazonaws.com"},
+		"aws_iam_role.rescan_scheduler":       {"scheduler.amazonaws.com"},
+		"aws_iam_role.backup_role":            {"backup.amazonaws.com"},
+	})
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+)
+
+// TestLambdaReliabilityConfig validates every Lambda function has a DLQ and
+// active X-Ray tracing
+func TestLambdaReliabilityConfig(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-reliability-test",
+			"enable_s3_archival":         true,
+			"enable_deletion_protection": false,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	functionNames := terraform.OutputList(t, terraformOptions, "lambda_function_names")
+	require.Len(t, functionNames, 3, "Scanner, API and archiver should all be listed")
+	dlqArn := terraform.Output(t, terraformOptions, "lambda_dlq_arn")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	assertLambdaReliability(t, lambda.New(sess), functionNames, dlqArn)
+}
+
+func TestLambdaReliabilityProblems(t *testing.T) {
+	t.Parallel()
+
+	dlqArn := "arn:aws:sqs:us-east-1:123456789012:cspm-monitor-lambda-dlq"
+	config := &lambda.FunctionConfiguration{
+		FunctionName:     aws.String("cspm-monitor-scanner"),
+		DeadLetterConfig: &lambda.DeadLetterConfig{TargetArn: aws.String(dlqArn)},
+		TracingConfig:    &lambda.TracingConfigResponse{Mode: aws.String(lambda.TracingModeActive)},
+	}
+	assert.Empty(t, lambdaReliabilityProblems(config, dlqArn))
+
+	config.DeadLetterConfig.TargetArn = aws.String("arn:aws:sqs:us-east-1:123456789012:other-dlq")
+	config.TracingConfig.Mode = aws.String(lambda.TracingModePassThrough)
+	assert.Equal(t, []string{
+		"dead letters go to arn:aws:sqs:us-east-1:123456789012:other-dlq, not " + dlqArn,
+		"tracing mode is PassThrough, want Active",
+	}, lambdaReliabilityProblems(config, dlqArn))
+
+	// Functions created without either block report neither
+	assert.Equal(t, []string{
+		"no dead letter queue",
+		"tracing mode is PassThrough, want Active",
+	}, lambdaReliabilityProblems(&lambda.FunctionConfiguration{FunctionName: aws.String("cspm-monitor-api")}, dlqArn))
+}
+
+func TestAssertLambdaReliability(t *testing.T) {
+	t.Parallel()
+
+	dlqArn := "arn:aws:sqs:us-east-1:123456789012:cspm-monitor-lambda-dlq"
+	svc := &fakeFunctionConfigClient{configs: map[string]*lambda.FunctionConfiguration{
+		"cspm-monitor-scanner": {
+			DeadLetterConfig: &lambda.DeadLetterConfig{TargetArn: aws.String(dlqArn)},
+			TracingConfig:    &lambda.TracingConfigResponse{Mode: aws.String(lambda.TracingModeActive)},
+		},
+		"cspm-monitor-api": {
+			DeadLetterConfig: &lambda.DeadLetterConfig{TargetArn: aws.String(dlqArn)},
+			TracingConfig:    &lambda.TracingConfigResponse{Mode: aws.String(lambda.TracingModeActive)},
+		},
+	}}
+	assertLambdaReliability(t, svc, []string{"cspm-monitor-scanner", "cspm-monitor-api"}, dlqArn)
+	assert.Equal(t, []string{"cspm-monitor-scanner", "cspm-monitor-api"}, svc.requested, "Every function should be checked")
+}
+
+type fakeFunctionConfigClient struct {
+	lambdaiface.LambdaAPI
+	configs   map[string]*lambda.FunctionConfiguration
+	requested []string
+}
+
+func (f *fakeFunctionConfigClient) GetFunctionConfiguration(input *lambda.GetFunctionConfigurationInput) (*lambda.FunctionConfiguration, error) {
+	name := aws.StringValue(input.FunctionName)
+	f.requested = append(f.requested, name)
+	config, ok := f.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("function %s not found", name)
+	}
+	return config, nil
+}
+
+// Helper function to assert each function sends dead letters to the DLQ and
+// traces every invocation with X-Ray
+func assertLambdaReliability(t *testing.T, lambdaSvc lambdaiface.LambdaAPI, functionNames []string, dlqArn string) {
+	for _, name := range functionNames {
+		config, err := lambdaSvc.GetFunctionConfiguration(&lambda.GetFunctionConfigurationInput{
+			FunctionName: aws.String(name),
+		})
+		require.NoError(t, err)
+
+		for _, problem := range lambdaReliabilityProblems(config, dlqArn) {
+			assert.Fail(t, fmt.Sprintf("Function %s reliability misconfigured", name), problem)
+		}
+	}
+}
+
+// Helper function to list what is missing from a function's DLQ and tracing configuration
+func lambdaReliabilityProblems(config *lambda.FunctionConfiguration, dlqArn string) []string {
+	var problems []string
+
+	if config.DeadLetterConfig == nil || aws.StringValue(config.DeadLetterConfig.TargetArn) == "" {
+		problems = append(problems, "no dead letter queue")
+	} else if target := aws.StringValue(config.DeadLetterConfig.TargetArn); target != dlqArn {
+		problems = append(problems, fmt.Sprintf("dead letters go to %s, not %s", target, dlqArn))
+	}
+
+	// Lambda reports PassThrough when tracing was never configured
+	mode := lambda.TracingModePassThrough
+	if config.TracingConfig != nil && config.TracingConfig.Mode != nil {
+		mode = aws.StringValue(config.TracingConfig.Mode)
+	}
+	if mode != lambda.TracingModeActive {
+		problems = append(problems, fmt.Sprintf("tracing mode is %s, want Active", mode))
+	}
+	return problems
+}
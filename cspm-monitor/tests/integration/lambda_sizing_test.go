@@ -0,0 +1,135 @@
+package test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+)
+
+// Fraction of the configured memory and timeout a scan may use before the
+// function is considered undersized
+const lambdaHeadroom = 0.8
+
+// lambdaReport holds the figures from the REPORT line Lambda appends to every invocation log
+type lambdaReport struct {
+	DurationMs     float64
+	MemorySizeMB   int
+	MaxMemoryMB    int
+	InitDurationMs float64 // Only reported for cold starts
+}
+
+var lambdaReportPattern = regexp.MustCompile(`REPORT RequestId: \S+\s+Duration: ([\d.]+) ms\s+Billed Duration: \d+ ms\s+Memory Size: (\d+) MB\s+Max Memory Used: (\d+) MB(?:\s+Init Duration: ([\d.]+) ms)?`)
+
+// TestScannerLambdaSizing validates the scanner's measured duration and memory
+// against its configured sizing
+func TestScannerLambdaSizing(t *testing.T) {
+	t.Parallel()
+
+	memorySize := 512
+	timeout := 120
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-sizing-test",
+			"scanner_memory_size":        memorySize,
+			"scanner_timeout":            timeout,
+			"enable_deletion_protection": false,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, strconv.Itoa(memorySize), terraform.Output(t, terraformOptions, "scanner_memory_size"))
+	assert.Equal(t, strconv.Itoa(timeout), terraform.Output(t, terraformOptions, "scanner_timeout"))
+	assert.Equal(t, "256", terraform.Output(t, terraformOptions, "api_memory_size"))
+	assert.Equal(t, "30", terraform.Output(t, terraformOptions, "api_timeout"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	lambdaSvc := lambda.New(sess)
+	functionName := terraform.Output(t, terraformOptions, "scanner_function_name")
+
+	// The first invocation includes the cold start, the second reflects steady state
+	for _, attempt := range []string{"cold", "warm"} {
+		invocation, err := lambdaSvc.Invoke(&lambda.InvokeInput{
+			FunctionName: aws.String(functionName),
+			Payload:      []byte(`{}`),
+			LogType:      aws.String(lambda.LogTypeTail),
+		})
+		require.NoError(t, err)
+		require.Nil(t, invocation.FunctionError, "Scanner failed: %s", string(invocation.Payload))
+
+		report, err := parseLambdaReport(aws.StringValue(invocation.LogResult))
+		require.NoError(t, err)
+		t.Logf("%s invocation: %.1f ms (init %.1f ms), %d/%d MB", attempt, report.DurationMs, report.InitDurationMs, report.MaxMemoryMB, report.MemorySizeMB)
+
+		assert.Equal(t, memorySize, report.MemorySizeMB, "Lambda should run with the configured memory")
+		assert.LessOrEqual(t, float64(report.MaxMemoryMB), float64(memorySize)*lambdaHeadroom, "%s invocation used too much of its memory", attempt)
+		assert.LessOrEqual(t, report.DurationMs+report.InitDurationMs, float64(timeout*1000)*lambdaHeadroom, "%s invocation came too close to the timeout", attempt)
+	}
+}
+
+func TestParseLambdaReport(t *testing.T) {
+	t.Parallel()
+
+	tail := "START RequestId: 8f5c Version: $LATEST\n" +
+		"[INFO] CSPM Monitor Scanner Lambda started\n" +
+		"END RequestId: 8f5c\n" +
+		"REPORT RequestId: 8f5c\tDuration: 1234.56 ms\tBilled Duration: 1235 ms\tMemory Size: 512 MB\tMax Memory Used: 87 MB\tInit Duration: 410.02 ms\t\n"
+
+	report, err := parseLambdaReport(base64.StdEncoding.EncodeToString([]byte(tail)))
+	require.NoError(t, err)
+	assert.Equal(t, 1234.56, report.DurationMs)
+	assert.Equal(t, 512, report.MemorySizeMB)
+	assert.Equal(t, 87, report.MaxMemoryMB)
+	assert.Equal(t, 410.02, report.InitDurationMs)
+
+	// Warm invocations have no init duration
+	warm := "REPORT RequestId: 9a1b\tDuration: 20.5 ms\tBilled Duration: 21 ms\tMemory Size: 512 MB\tMax Memory Used: 88 MB\t\n"
+	report, err = parseLambdaReport(base64.StdEncoding.EncodeToString([]byte(warm)))
+	require.NoError(t, err)
+	assert.Equal(t, 20.5, report.DurationMs)
+	assert.Zero(t, report.InitDurationMs)
+
+	_, err = parseLambdaReport(base64.StdEncoding.EncodeToString([]byte("START RequestId: 8f5c\n")))
+	assert.Error(t, err)
+
+	_, err = parseLambdaReport("not base64!")
+	assert.Error(t, err)
+}
+
+// Helper function to parse the REPORT line from a base64 encoded invocation log tail
+func parseLambdaReport(logResult string) (lambdaReport, error) {
+	tail, err := base64.StdEncoding.DecodeString(logResult)
+	if err != nil {
+		return lambdaReport{}, fmt.Errorf("decoding log tail: %w", err)
+	}
+
+	match := lambdaReportPattern.FindStringSubmatch(string(tail))
+	if match == nil {
+		return lambdaReport{}, fmt.Errorf("no REPORT line in log tail:\n%s", tail)
+	}
+
+	report := lambdaReport{}
+	report.DurationMs, _ = strconv.ParseFloat(match[1], 64)
+	report.MemorySizeMB, _ = strconv.Atoi(match[2])
+	report.MaxMemoryMB, _ = strconv.Atoi(match[3])
+	if match[4] != "" {
+		report.InitDurationMs, _ = strconv.ParseFloat(match[4], 64)
+	}
+	return report, nil
+}
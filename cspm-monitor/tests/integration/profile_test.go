@@ -0,0 +1,59 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+
+	"cspm-monitor-tests/testutil"
+	"testkit"
+)
+
+// monitorProfile is what each bundled toggle shows in a cspm-monitor plan
+type monitorProfile struct {
+	CustomerManagedKey bool
+	CloudTrail         bool
+	LogRetentionDays   float64
+}
+
+func TestProfilePlans(t *testing.T) {
+	t.Parallel()
+
+	cases := []testkit.ProfileCase[monitorProfile]{
+		{
+			Name: "minimal",
+			Vars: map[string]interface{}{"profile": "minimal"},
+			Want: monitorProfile{LogRetentionDays: 30},
+		},
+		{
+			Name: "hardened",
+			Vars: map[string]interface{}{"profile": "hardened"},
+			Want: monitorProfile{CustomerManagedKey: true, CloudTrail: true, LogRetentionDays: 365},
+		},
+		{
+			Name: "hardened with overrides",
+			Vars: map[string]interface{}{
+				"profile":            "hardened",
+				"enable_cloudtrail":  false,
+				"log_retention_days": 90,
+			},
+			Want: monitorProfile{CustomerManagedKey: true, LogRetentionDays: 90},
+		},
+	}
+
+	testkit.RunProfilePlans(t, testutil.LocalBackendDir(t, "../../"), nil, cases, func(t *testing.T, plan *terraform.PlanStruct) monitorProfile {
+		var got monitorProfile
+		_, got.CustomerManagedKey = plan.ResourcePlannedValuesMap["aws_kms_key.findings[0]"]
+		_, got.CloudTrail = plan.ResourcePlannedValuesMap["aws_cloudtrail.main[0]"]
+
+		// Every function's log group follows log_retention_days
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "aws_cloudwatch_log_group.scanner_logs")
+		got.LogRetentionDays, _ = plan.ResourcePlannedValuesMap["aws_cloudwatch_log_group.scanner_logs"].AttributeValues["retention_in_days"].(float64)
+		for _, address := range []string{"aws_cloudwatch_log_group.api_logs", "aws_cloudwatch_log_group.api_gateway_logs", "aws_cloudwatch_log_group.archiver_logs[0]"} {
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+			assert.Equal(t, got.LogRetentionDays, plan.ResourcePlannedValuesMap[address].AttributeValues["retention_in_days"], "%s retention", address)
+		}
+		return got
+	})
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/scheduler"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cspm-monitor-tests/testutil"
+)
+
+// TestRescanSchedule validates the scheduled full rescan of Security Hub findings
+func TestRescanSchedule(t *testing.T) {
+	t.Parallel()
+
+	scheduleExpression := "cron(0 3 * * ? *)"
+
+	terraformOptions := testutil.WithBackend(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"project_name":               "cspm-rescan-test",
+			"enable_rescan_schedule":     true,
+			"rescan_schedule_expression": scheduleExpression,
+			"enable_deletion_protection": false,
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	scheduleName := terraform.Output(t, terraformOptions, "rescan_schedule_name")
+	tableName := terraform.Output(t, terraformOptions, "dynamodb_table_name")
+	require.NotEmpty(t, scheduleName)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+
+	// Verify the schedule exists with the configured expression
+	schedule, err := scheduler.New(sess).GetSchedule(&scheduler.GetScheduleInput{
+		Name: aws.String(scheduleName),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, scheduleExpression, aws.StringValue(schedule.ScheduleExpression))
+	assert.Equal(t, scheduler.ScheduleStateEnabled, aws.StringValue(schedule.State))
+	require.NotNil(t, schedule.Target)
+
+	// Invoke the schedule target with the schedule's own payload rather than
+	// waiting for the next cron tick. The account may have no active Security
+	// Hub findings, so the scanner's own summary is the assertion; DynamoDB is
+	// only checked for what it reports storing.
+	invocation, err := lambda.New(sess).Invoke(&lambda.InvokeInput{
+		FunctionName: schedule.Target.Arn,
+		Payload:      []byte(aws.StringValue(schedule.Target.Input)),
+	})
+	require.NoError(t, err)
+	require.Nil(t, invocation.FunctionError, "Scanner failed: %s", string(invocation.Payload))
+
+	summary, err := scanSummary(invocation.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, summary.FindingsProcessed, summary.FindingsStored, "Every rescanned finding should be stored")
+
+	if summary.FindingsStored > 0 {
+		result, err := dynamodb.New(sess).Scan(&dynamodb.ScanInput{
+			TableName: aws.String(tableName),
+			Select:    aws.String(dynamodb.SelectCount),
+		})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, aws.Int64Value(result.Count), int64(summary.FindingsStored), "Stored findings should be in %s", tableName)
+	}
+
+	t.Log("✅ Rescan schedule validated")
+}
+
+func TestScanSummary(t *testing.T) {
+	t.Parallel()
+
+	summary, err := scanSummary([]byte(`{"statusCode": 200, "body": "{\"message\": \"Security findings processed successfully\", \"findings_processed\": 3, \"findings_stored\": 2}"}`))
+	require.NoError(t, err)
+	assert.Equal(t, scannerSummary{FindingsProcessed: 3, FindingsStored: 2}, summary)
+
+	_, err = scanSummary([]byte(`{"statusCode": 500, "body": "{}"}`))
+	assert.ErrorContains(t, err, "status 500")
+}
+
+// scannerSummary is the body the scanner returns after processing findings
+type scannerSummary struct {
+	FindingsProcessed int `json:"findings_processed"`
+	FindingsStored    int `json:"findings_stored"`
+}
+
+// Helper function to decode the scanner's response payload into its summary
+func scanSummary(payload []byte) (scannerSummary, error) {
+	var response struct {
+		StatusCode int    `json:"statusCode"`
+		Body       string `json:"body"`
+	}
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return scannerSummary{}, fmt.Errorf("parsing scanner response %s: %w", payload, err)
+	}
+	if response.StatusCode != 200 {
+		return scannerSummary{}, fmt.Errorf("scanner returned status %d: %s", response.StatusCode, response.Body)
+	}
+
+	var summary scannerSummary
+	if err := json.Unmarshal([]byte(response.Body), &summary); err != nil {
+		return scannerSummary{}, fmt.Errorf("parsing scanner summary %s: %w", response.Body, err)
+	}
+	return summary, nil
+}
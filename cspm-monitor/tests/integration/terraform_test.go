@@ -83,11 +83,10 @@ func TestTerraformResources(t *testing.T) {
 	// Test key resource configurations
 	t.Log("Testing Terraform resource configurations")
 
-	// Test Lambda function configurations
+	// Test Lambda function configurations. Memory and timeout are variables
+	// checked against measured usage in TestScannerLambdaSizing.
 	lambdaConfigs := map[string]interface{}{
 		"runtime":     "python3.9",
-		"memory":      256,
-		"timeout":     30,
 		"vpc_enabled": true,
 	}
 
@@ -0,0 +1,87 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// Environment variables naming the S3 state backend applies should use. The
+// backend block only sets the key, so without a bucket init has nothing to
+// connect to.
+const (
+	StateBucketEnv    = "CSPM_STATE_BUCKET"
+	StateRegionEnv    = "CSPM_STATE_REGION"
+	StateLockTableEnv = "CSPM_STATE_LOCK_TABLE"
+)
+
+// localBackendOverride swaps the S3 backend for state inside the working copy
+const localBackendOverride = `terraform {
+  backend "local" {}
+}
+`
+
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9/_-]+`)
+
+// WithBackend points terraformOptions at a private copy of its TerraformDir
+// and settles where its state lives. With CSPM_STATE_BUCKET set, state goes to
+// that bucket (CSPM_STATE_REGION, default us-east-1, and CSPM_STATE_LOCK_TABLE
+// for locking) under a key per test; otherwise the copy uses a local backend.
+// The copy keeps parallel tests from sharing a .terraform directory.
+func WithBackend(t testing.TB, terraformOptions *terraform.Options) *terraform.Options {
+	bucket := os.Getenv(StateBucketEnv)
+	if bucket == "" {
+		terraformOptions.TerraformDir = LocalBackendDir(t, terraformOptions.TerraformDir)
+		return terraformOptions
+	}
+
+	terraformOptions.TerraformDir = workingCopy(t, terraformOptions.TerraformDir)
+	terraformOptions.BackendConfig = BackendConfig(bucket, os.Getenv(StateRegionEnv), os.Getenv(StateLockTableEnv), t.Name())
+	return terraformOptions
+}
+
+// LocalBackendDir returns a private copy of the configuration in moduleDir
+// that keeps its state locally. Plan-only suites use it directly since they never need the
+// real backend.
+func LocalBackendDir(t testing.TB, moduleDir string) string {
+	dir := workingCopy(t, moduleDir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "backend_override.tf"), []byte(localBackendOverride), 0o644))
+	return dir
+}
+
+// BackendConfig is the -backend-config for a test's state in the S3 backend.
+// Each test gets its own key so parallel applies don't share state.
+func BackendConfig(bucket, region, lockTable, testName string) map[string]interface{} {
+	if region == "" {
+		region = "us-east-1"
+	}
+	config := map[string]interface{}{
+		"bucket": bucket,
+		"region": region,
+		"key":    "cspm-monitor/tests/" + unsafeKeyChars.ReplaceAllString(testName, "-") + "/terraform.tfstate",
+	}
+	if lockTable != "" {
+		config["dynamodb_table"] = lockTable
+	}
+	return config
+}
+
+// workingCopy copies the repository holding moduleDir into a temp dir and
+// returns the module's directory inside it. The whole repository is copied because the
+// configuration sources modules from ../static-website, and tfvars are kept
+// for the environment plans.
+func workingCopy(t testing.TB, moduleDir string) string {
+	moduleDir, err := filepath.Abs(moduleDir)
+	require.NoError(t, err)
+
+	copied, err := files.CopyFolderToDest(filepath.Dir(moduleDir), t.TempDir(), "cspm-monitor", func(path string) bool {
+		return !files.PathContainsHiddenFileOrFolder(path) && !files.PathContainsTerraformState(path)
+	})
+	require.NoError(t, err)
+	return filepath.Join(copied, filepath.Base(moduleDir))
+}
@@ -0,0 +1,57 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendConfig(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, map[string]interface{}{
+		"bucket":         "state-bucket",
+		"region":         "eu-west-1",
+		"key":            "cspm-monitor/tests/TestRescanSchedule/terraform.tfstate",
+		"dynamodb_table": "locks",
+	}, BackendConfig("state-bucket", "eu-west-1", "locks", "TestRescanSchedule"))
+
+	// Subtest names carry spaces; the region defaults and locking is optional
+	assert.Equal(t, map[string]interface{}{
+		"bucket": "state-bucket",
+		"region": "us-east-1",
+		"key":    "cspm-monitor/tests/TestProfilePlans/hardened-with-overrides/terraform.tfstate",
+	}, BackendConfig("state-bucket", "", "", "TestProfilePlans/hardened with overrides"))
+}
+
+func TestLocalBackendDir(t *testing.T) {
+	t.Parallel()
+
+	dir := LocalBackendDir(t, "../../")
+	assert.Equal(t, "cspm-monitor", filepath.Base(dir))
+	assert.FileExists(t, filepath.Join(dir, "backend.tf"))
+	assert.FileExists(t, filepath.Join(dir, "environments", "dev.tfvars"), "Environment plans need the tfvars")
+	assert.DirExists(t, filepath.Join(dir, "..", "static-website", "modules"), "Shared modules are sourced from ../static-website")
+	assert.NoDirExists(t, filepath.Join(dir, ".terraform"))
+
+	override, err := os.ReadFile(filepath.Join(dir, "backend_override.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(override), `backend "local"`)
+}
+
+func TestWithBackend(t *testing.T) {
+	t.Setenv(StateBucketEnv, "")
+
+	terraformOptions := WithBackend(t, &terraform.Options{TerraformDir: "../../"})
+	assert.FileExists(t, filepath.Join(terraformOptions.TerraformDir, "backend_override.tf"))
+	assert.Nil(t, terraformOptions.BackendConfig)
+
+	t.Setenv(StateBucketEnv, "state-bucket")
+	terraformOptions = WithBackend(t, &terraform.Options{TerraformDir: "../../"})
+	assert.NoFileExists(t, filepath.Join(terraformOptions.TerraformDir, "backend_override.tf"))
+	assert.Equal(t, "state-bucket", terraformOptions.BackendConfig["bucket"])
+}
@@ -47,6 +47,8 @@ if os.path.exists(zip_path):
     get_finding_by_id = api_module.get_finding_by_id
     get_findings_summary = api_module.get_findings_summary
     create_response = api_module.create_response
+    check_dependencies = api_module.check_dependencies
+    get_health = api_module.get_health
 else:
     # Fallback to direct import for development
     sys.path.insert(0, lambda_src_dir)
@@ -57,10 +59,25 @@ else:
         query_findings_by_severity,
         get_finding_by_id,
         get_findings_summary,
-        create_response
+        create_response,
+        check_dependencies,
+        get_health
     )
 
 
+HEALTHY_DEPENDENCIES = {
+    'dynamodb': {'status': 'ok', 'detail': 'table cspm-monitor-findings is ACTIVE'},
+    'sns': {'status': 'ok', 'detail': 'topic cspm-monitor-alerts is reachable'}
+}
+
+
+@pytest.fixture(autouse=True)
+def healthy_dependencies():
+    """Keep /health requests off AWS; TestHealthCheck covers the real checks"""
+    with patch('api.check_dependencies', return_value=HEALTHY_DEPENDENCIES):
+        yield
+
+
 class TestGetSSMParameter:
     """Test SSM parameter retrieval"""
 
@@ -341,6 +358,78 @@ class TestCreateResponse:
 
         assert 'Access-Control-Allow-Origin' not in result['headers']
 
+    def test_create_response_security_headers(self):
+        """Test security headers are set on every response, including errors"""
+        for status_code in (200, 404, 500):
+            result = create_response(status_code, {'message': 'any'}, cors=False)
+
+            assert result['headers']['X-Content-Type-Options'] == 'nosniff'
+            assert result['headers']['X-Frame-Options'] == 'DENY'
+            assert result['headers']['Strict-Transport-Security'].startswith('max-age=31536000')
+            assert 'Content-Security-Policy' in result['headers']
+            assert result['headers']['Referrer-Policy'] == 'strict-origin-when-cross-origin'
+
+
+class TestHealthCheck:
+    """Test the /health dependency checks"""
+
+    def test_check_dependencies_healthy(self):
+        """Test an active table and reachable topic report ok"""
+        mock_table = MagicMock()
+        mock_table.name = 'cspm-monitor-findings'
+        mock_table.table_status = 'ACTIVE'
+
+        with patch('api.get_table', return_value=mock_table), \
+             patch('api.get_ssm_parameter', return_value='arn:aws:sns:us-east-1:123456789012:cspm-monitor-alerts'), \
+             patch('api.sns') as mock_sns:
+            dependencies = check_dependencies()
+
+        mock_sns.get_topic_attributes.assert_called_once_with(
+            TopicArn='arn:aws:sns:us-east-1:123456789012:cspm-monitor-alerts'
+        )
+        assert dependencies == HEALTHY_DEPENDENCIES
+
+    def test_check_dependencies_failures(self):
+        """Test a table still being created and a missing topic report errors"""
+        from botocore.exceptions import ClientError
+
+        mock_table = MagicMock()
+        mock_table.name = 'cspm-monitor-findings'
+        mock_table.table_status = 'CREATING'
+
+        with patch('api.get_table', return_value=mock_table), \
+             patch('api.get_ssm_parameter', return_value='arn:aws:sns:us-east-1:123456789012:cspm-monitor-alerts'), \
+             patch('api.sns') as mock_sns:
+            mock_sns.get_topic_attributes.side_effect = ClientError(
+                {'Error': {'Code': 'NotFound'}}, 'GetTopicAttributes'
+            )
+            dependencies = check_dependencies()
+
+        assert dependencies['dynamodb'] == {'status': 'error', 'detail': 'table cspm-monitor-findings is CREATING'}
+        assert dependencies['sns'] == {'status': 'error', 'detail': 'NotFound'}
+
+    def test_get_health_degraded(self):
+        """Test one unhealthy dependency degrades the API and returns 503"""
+        dependencies = dict(HEALTHY_DEPENDENCIES, sns={'status': 'error', 'detail': 'AuthorizationError'})
+
+        with patch('api.check_dependencies', return_value=dependencies):
+            status_code, body = get_health()
+
+        assert status_code == 503
+        assert body['status'] == 'degraded'
+        assert body['dependencies']['sns']['status'] == 'error'
+
+    def test_health_contract(self):
+        """Test the /health body carries every field the contract requires"""
+        result = lambda_handler({'httpMethod': 'GET', 'path': '/health'}, None)
+
+        assert result['statusCode'] == 200
+        body = json.loads(result['body'])
+        assert set(body) == {'status', 'service', 'version', 'timestamp', 'dependencies'}
+        assert body['status'] == 'ok'
+        assert body['version'] == '1.0.0'
+        assert set(body['dependencies']) == {'dynamodb', 'sns'}
+
 
 class TestLambdaHandler:
     """Test Lambda handler functionality"""
@@ -363,7 +452,7 @@ class TestLambdaHandler:
 
         assert result['statusCode'] == 200
         body = json.loads(result['body'])
-        assert body['status'] == 'healthy'
+        assert body['status'] == 'ok'
 
     def test_lambda_handler_memory_pressure(self):
         """Test Lambda behavior under memory pressure"""
@@ -452,7 +541,7 @@ class TestLambdaHandler:
 
         assert result['statusCode'] == 200
         body = json.loads(result['body'])
-        assert body['status'] == 'healthy'
+        assert body['status'] == 'ok'
         assert body['service'] == 'cspm-monitor-api'
 
     def test_lambda_handler_options(self):
@@ -728,7 +817,7 @@ class TestLambdaHandler:
 
         assert result['statusCode'] == 200
         body = json.loads(result['body'])
-        assert body['status'] == 'healthy'
+        assert body['status'] == 'ok'
         # Verify context information is available
         assert context.aws_request_id == 'lambda-request-456'
 
@@ -774,7 +863,7 @@ class TestLambdaHandler:
             # Should still function despite low disk space
             assert result['statusCode'] == 200
             body = json.loads(result['body'])
-            assert body['status'] == 'healthy'
+            assert body['status'] == 'ok'
 
     def test_lambda_handler_high_memory_usage(self):
         """Test Lambda behavior with high memory usage"""
@@ -44,6 +44,7 @@ if os.path.exists(zip_path):
     calculate_ttl_timestamp = scanner_module.calculate_ttl_timestamp
     process_finding = scanner_module.process_finding
     send_alert = scanner_module.send_alert
+    fetch_active_findings = scanner_module.fetch_active_findings
 else:
     # Fallback to direct import for development
     sys.path.insert(0, lambda_src_dir)
@@ -52,7 +53,8 @@ else:
         get_ssm_parameter,
         calculate_ttl_timestamp,
         process_finding,
-        send_alert
+        send_alert,
+        fetch_active_findings
     )
 
 
@@ -371,6 +373,37 @@ class TestSendAlert:
         mock_sns.publish.assert_not_called()
 
 
+class TestFetchActiveFindings:
+    """Test Security Hub rescan retrieval"""
+
+    @patch('scanner.securityhub')
+    def test_fetch_active_findings_paginates(self, mock_securityhub):
+        """Test findings from every page are returned"""
+        mock_paginator = MagicMock()
+        mock_securityhub.get_paginator.return_value = mock_paginator
+        mock_paginator.paginate.return_value = [
+            {'Findings': [{'Id': 'finding-1'}, {'Id': 'finding-2'}]},
+            {'Findings': [{'Id': 'finding-3'}]}
+        ]
+
+        result = fetch_active_findings(max_findings=50)
+
+        assert [f['Id'] for f in result] == ['finding-1', 'finding-2', 'finding-3']
+        mock_securityhub.get_paginator.assert_called_once_with('get_findings')
+        _, kwargs = mock_paginator.paginate.call_args
+        assert kwargs['Filters']['RecordState'] == [{'Value': 'ACTIVE', 'Comparison': 'EQUALS'}]
+        assert kwargs['PaginationConfig']['MaxItems'] == 50
+
+    @patch('scanner.securityhub')
+    def test_fetch_active_findings_empty(self, mock_securityhub):
+        """Test no active findings"""
+        mock_paginator = MagicMock()
+        mock_securityhub.get_paginator.return_value = mock_paginator
+        mock_paginator.paginate.return_value = [{'Findings': []}]
+
+        assert fetch_active_findings() == []
+
+
 class TestLambdaHandler:
     """Test Lambda handler functionality"""
 
@@ -452,6 +485,28 @@ class TestLambdaHandler:
         assert body['findings_processed'] == 1
         mock_table.put_item.assert_called_once()
 
+    @patch('scanner.get_ssm_parameter')
+    @patch('scanner.dynamodb')
+    @patch('scanner.fetch_active_findings')
+    def test_lambda_handler_scheduled_rescan(self, mock_fetch, mock_dynamodb, mock_get_ssm):
+        """Test scheduled rescan event pulls findings from Security Hub"""
+        mock_get_ssm.return_value = 'test-table'
+        mock_table = MagicMock()
+        mock_dynamodb.Table.return_value = mock_table
+        mock_fetch.return_value = [
+            {'Id': 'rescan-finding-1', 'Severity': {'Label': 'LOW'}},
+            {'Id': 'rescan-finding-2', 'Severity': {'Label': 'MEDIUM'}}
+        ]
+
+        result = lambda_handler({'rescan': True}, None)
+
+        assert result['statusCode'] == 200
+        body = json.loads(result['body'])
+        assert body['findings_processed'] == 2
+        assert body['findings_stored'] == 2
+        mock_fetch.assert_called_once()
+        assert mock_table.put_item.call_count == 2
+
     @patch('scanner.get_ssm_parameter')
     @patch('scanner.dynamodb')
     @patch('scanner.send_alert')
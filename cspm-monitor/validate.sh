@@ -91,8 +91,8 @@ echo "✅ Website files are present"
 echo "Checking for common configuration issues..."
 
 # Check if backend bucket is configured
-if grep -q "your-terraform-state-bucket" backend.tf; then
-    echo "⚠️  Backend bucket is not configured. Update backend.tf with your S3 bucket"
+if [ ! -f backend.hcl ] || grep -q "your-terraform-state-bucket" backend.hcl; then
+    echo "⚠️  Backend bucket is not configured. Copy backend.hcl.example to backend.hcl and set your S3 bucket"
 fi
 
 # Check for hardcoded values
@@ -118,7 +118,7 @@ echo ""
 echo "Validation completed!"
 echo ""
 echo "Next steps:"
-echo "1. Update backend.tf with your S3 bucket for Terraform state"
+echo "1. Set your S3 bucket for Terraform state in backend.hcl and run 'terraform init -backend-config=backend.hcl'"
 echo "2. Run './build.sh' to create Lambda ZIP files"
 echo "3. Run 'terraform plan' to review the deployment"
 echo "4. Run 'terraform apply' to deploy the infrastructure"
@@ -64,6 +64,50 @@ variable "lambda_runtime" {
   }
 }
 
+variable "scanner_memory_size" {
+  description = "Memory in MB for the scanner Lambda function (CPU scales with memory)"
+  type        = number
+  default     = 256
+
+  validation {
+    condition     = var.scanner_memory_size >= 128 && var.scanner_memory_size <= 10240
+    error_message = "Lambda memory must be between 128 and 10240 MB."
+  }
+}
+
+variable "scanner_timeout" {
+  description = "Timeout in seconds for the scanner Lambda function"
+  type        = number
+  default     = 300
+
+  validation {
+    condition     = var.scanner_timeout >= 1 && var.scanner_timeout <= 900
+    error_message = "Lambda timeout must be between 1 and 900 seconds."
+  }
+}
+
+variable "api_memory_size" {
+  description = "Memory in MB for the api Lambda function (CPU scales with memory)"
+  type        = number
+  default     = 256
+
+  validation {
+    condition     = var.api_memory_size >= 128 && var.api_memory_size <= 10240
+    error_message = "Lambda memory must be between 128 and 10240 MB."
+  }
+}
+
+variable "api_timeout" {
+  description = "Timeout in seconds for the api Lambda function"
+  type        = number
+  default     = 30
+
+  validation {
+    condition     = var.api_timeout >= 1 && var.api_timeout <= 900
+    error_message = "Lambda timeout must be between 1 and 900 seconds."
+  }
+}
+
 variable "dynamodb_billing_mode" {
   description = "Billing mode for DynamoDB table"
   type        = string
@@ -75,14 +119,81 @@ variable "dynamodb_billing_mode" {
   }
 }
 
+variable "dynamodb_min_capacity" {
+  description = "Minimum (and starting) read/write capacity units in PROVISIONED mode"
+  type        = number
+  default     = 5
+
+  validation {
+    condition     = var.dynamodb_min_capacity >= 1
+    error_message = "DynamoDB minimum capacity must be at least 1."
+  }
+}
+
+variable "dynamodb_max_capacity" {
+  description = "Maximum read/write capacity units auto scaling may provision in PROVISIONED mode"
+  type        = number
+  default     = 50
+
+  validation {
+    condition     = var.dynamodb_max_capacity >= 1
+    error_message = "DynamoDB maximum capacity must be at least 1."
+  }
+}
+
+variable "dynamodb_target_utilization" {
+  description = "Consumed-to-provisioned capacity percentage auto scaling tracks in PROVISIONED mode"
+  type        = number
+  default     = 70
+
+  validation {
+    condition     = var.dynamodb_target_utilization >= 20 && var.dynamodb_target_utilization <= 90
+    error_message = "DynamoDB target utilization must be between 20 and 90 percent."
+  }
+}
+
+variable "profile" {
+  description = "Security preset: minimal (AWS-managed table key, no CloudTrail, a month of Lambda and API logs) or hardened (customer-managed table key, CloudTrail, a year of logs). Individual toggles override it."
+  type        = string
+  default     = "minimal"
+
+  validation {
+    condition     = contains(["minimal", "hardened"], var.profile)
+    error_message = "profile must be minimal or hardened."
+  }
+}
+
+variable "create_kms_key" {
+  description = "Encrypt the findings table with a customer-managed KMS key with yearly rotation instead of the AWS-managed aws/dynamodb key; null follows profile"
+  type        = bool
+  default     = null
+}
+
 variable "enable_cloudtrail" {
-  description = "Enable CloudTrail integration"
+  description = "Enable CloudTrail integration; null follows profile"
   type        = bool
-  default     = false
+  default     = null
+}
+
+variable "log_retention_days" {
+  description = "Days to keep the Lambda and API Gateway logs in CloudWatch Logs; null follows profile"
+  type        = number
+  default     = null
 
   validation {
-    condition     = var.enable_cloudtrail == false || var.enable_cloudtrail == true
-    error_message = "enable_cloudtrail must be a boolean value."
+    condition     = var.log_retention_days == null ? true : contains([1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], var.log_retention_days)
+    error_message = "log_retention_days must be a retention period CloudWatch Logs supports, e.g. 30, 90, 365 or 731."
+  }
+}
+
+variable "cloudtrail_retention_days" {
+  description = "Number of days to keep CloudTrail log files in S3 before they expire"
+  type        = number
+  default     = 365
+
+  validation {
+    condition     = var.cloudtrail_retention_days >= 90
+    error_message = "CloudTrail retention must be at least 90 days."
   }
 }
 
@@ -103,12 +214,51 @@ variable "enable_sync_schedule" {
   default     = true
 }
 
+variable "enable_rescan_schedule" {
+  description = "Enable scheduled full rescans of active Security Hub findings"
+  type        = bool
+  default     = false
+}
+
+variable "rescan_schedule_expression" {
+  description = "EventBridge Scheduler cron expression (UTC) for full rescans"
+  type        = string
+  default     = "cron(0 2 * * ? *)"
+
+  validation {
+    condition     = can(regex("^cron\\([^ ]+ [^ ]+ [^ ]+ [^ ]+ [^ ]+ [^ ]+\\)$", var.rescan_schedule_expression))
+    error_message = "Rescan schedule must be a six-field cron expression: cron(minutes hours day-of-month month day-of-week year)."
+  }
+
+  validation {
+    condition     = can(regex("^cron\\([^ ]+ [^ ]+ ([^ ?]+ [^ ]+ \\?|\\? [^ ]+ [^ ?]+) [^ ]+\\)$", var.rescan_schedule_expression))
+    error_message = "Exactly one of day-of-month or day-of-week must be '?' in the rescan cron expression."
+  }
+}
+
 variable "enable_api_gateway" {
   description = "Enable API Gateway for the dashboard"
   type        = bool
   default     = true
 }
 
+variable "api_custom_domain" {
+  description = "Custom domain for the API (e.g. api.example.com); empty serves the API from the execute-api endpoint only"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.api_custom_domain == "" || can(regex("^[a-zA-Z0-9.-]+\\.[a-zA-Z]{2,}$", var.api_custom_domain))
+    error_message = "API custom domain must be empty or a valid domain format (e.g., api.example.com)"
+  }
+}
+
+variable "api_hosted_zone_id" {
+  description = "Route 53 hosted zone that validates the API certificate and holds the custom domain's alias record"
+  type        = string
+  default     = ""
+}
+
 # Data retention and compliance variables
 variable "dynamodb_ttl_enabled" {
   description = "Enable DynamoDB Time-to-Live for automatic data expiration"
@@ -127,6 +277,12 @@ variable "dynamodb_ttl_days" {
   }
 }
 
+variable "manage_api_gateway_account" {
+  description = "Set the account's API Gateway CloudWatch Logs role, which execution logging needs. It is shared by every API in the account and region, so leave this off if another stack already sets it."
+  type        = bool
+  default     = false
+}
+
 variable "enable_s3_archival" {
   description = "Enable S3 archival for long-term security log retention"
   type        = bool
@@ -144,6 +300,12 @@ variable "s3_archive_retention_days" {
   }
 }
 
+variable "enable_deletion_protection" {
+  description = "Enable deletion protection on the findings table"
+  type        = bool
+  default     = true
+}
+
 variable "enable_backup" {
   description = "Enable automated DynamoDB backups for compliance"
   type        = bool
@@ -184,25 +346,3 @@ variable "enable_critical_escalation" {
     error_message = "enable_critical_escalation must be a boolean value."
   }
 }
-
-variable "terraform_state_bucket" {
-  description = "S3 bucket for Terraform state storage"
-  type        = string
-  default     = "your-terraform-state-bucket"
-
-  validation {
-    condition     = can(regex("^[a-z0-9][a-z0-9-]*[a-z0-9]$", var.terraform_state_bucket))
-    error_message = "S3 bucket name must be valid (lowercase, no uppercase, no special chars except hyphens)."
-  }
-}
-
-variable "terraform_locks_table" {
-  description = "DynamoDB table for Terraform state locking"
-  type        = string
-  default     = "your-terraform-locks-table"
-
-  validation {
-    condition     = can(regex("^[a-zA-Z0-9._-]+$", var.terraform_locks_table))
-    error_message = "DynamoDB table name must be valid."
-  }
-}
\ No newline at end of file
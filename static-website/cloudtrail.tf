@@ -50,6 +50,15 @@ resource "aws_s3_bucket_versioning" "cloudtrail_bucket" {
   }
 }
 
+# Unlike the CloudFront access log bucket, nothing writing trail logs needs
+# ACLs, so they stay disabled
+resource "aws_s3_bucket_ownership_controls" "cloudtrail_bucket" {
+  bucket = aws_s3_bucket.cloudtrail_bucket.id
+  rule {
+    object_ownership = "BucketOwnerEnforced"
+  }
+}
+
 # S3 Bucket server-side encryption for CloudTrail
 resource "aws_s3_bucket_server_side_encryption_configuration" "cloudtrail_bucket" {
   bucket = aws_s3_bucket.cloudtrail_bucket.id
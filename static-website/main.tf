@@ -2,17 +2,479 @@ variable "domain_name" {
   description = "The domain name for the website (e.g., example.com)"
   type        = string
 }
+variable "serving_mode" {
+  description = "How the site is served: cloudfront (HTTPS through CloudFront with Origin Access Control) or s3_website (HTTP only from the S3 website endpoint, no CloudFront or WAF)"
+  type        = string
+  default     = "cloudfront"
+
+  validation {
+    condition     = contains(["cloudfront", "s3_website"], var.serving_mode)
+    error_message = "serving_mode must be cloudfront or s3_website."
+  }
+}
+variable "index_document" {
+  description = "Object served for / (and for directories in s3_website mode)"
+  type        = string
+  default     = "index.html"
+
+  validation {
+    condition     = can(regex("^[^/]+$", var.index_document))
+    error_message = "index_document must be a non-empty name without slashes."
+  }
+}
+variable "error_document" {
+  description = "Object key the S3 website endpoint serves for missing objects, relative to the bucket root; CloudFront uses custom_error_responses"
+  type        = string
+  default     = "error.html"
+
+  validation {
+    condition     = can(regex("^[^/].*$", var.error_document))
+    error_message = "error_document must be a non-empty key that does not start with /."
+  }
+}
+variable "custom_error_responses" {
+  description = "CloudFront error responses; the default serves index.html with a 200 for missing paths (404) so single-page apps can route client-side. Mapping 403 as well also rewrites WAF blocks, so it's opt-in. error_document is only used in s3_website mode."
+  type = list(object({
+    error_code            = number
+    response_code         = optional(number)
+    response_page_path    = optional(string)
+    error_caching_min_ttl = optional(number)
+  }))
+  default = [
+    { error_code = 404, response_code = 200, response_page_path = "/index.html", error_caching_min_ttl = 10 },
+  ]
+
+  validation {
+    condition     = alltrue([for r in var.custom_error_responses : contains([400, 403, 404, 405, 414, 416, 500, 501, 502, 503, 504], r.error_code)])
+    error_message = "custom_error_responses error_code must be one CloudFront can customize: 400, 403, 404, 405, 414, 416, 500, 501, 502, 503 or 504."
+  }
+  validation {
+    condition     = length(distinct([for r in var.custom_error_responses : r.error_code])) == length(var.custom_error_responses)
+    error_message = "custom_error_responses can have only one entry per error_code."
+  }
+  validation {
+    condition     = alltrue([for r in var.custom_error_responses : (r.response_page_path == null) == (r.response_code == null) && (r.response_page_path == null ? true : startswith(r.response_page_path, "/"))])
+    error_message = "custom_error_responses response_page_path must start with / and be set together with response_code."
+  }
+  validation {
+    condition     = alltrue([for r in var.custom_error_responses : r.error_caching_min_ttl == null ? true : r.error_caching_min_ttl >= 0])
+    error_message = "custom_error_responses error_caching_min_ttl can't be negative."
+  }
+}
+variable "hosted_zone_id" {
+  description = "Route53 hosted zone ID for ACM DNS validation and the alias record; leave empty to skip validation and serve on the default *.cloudfront.net certificate"
+  type        = string
+  default     = ""
+}
+variable "create_zone" {
+  description = "Create a public hosted zone for domain_name and use it for ACM validation and the alias record instead of hosted_zone_id"
+  type        = bool
+  default     = false
+}
+variable "kms_key_arn" {
+  description = "ARN of an existing customer-managed KMS key to encrypt website objects with; empty uses SSE-S3. Needs CloudFront, and the key policy must let the distribution decrypt."
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.kms_key_arn == "" || can(regex("^arn:aws[a-z-]*:kms:[a-z0-9-]+:[0-9]{12}:key/", var.kms_key_arn))
+    error_message = "kms_key_arn must be a KMS key ARN."
+  }
+}
+variable "profile" {
+  description = "Security preset: minimal (SSE-S3 website objects, no WAF body size limit, a year of logs) or hardened (customer-managed key, WAF body size rule, two years of logs). Individual toggles override it."
+  type        = string
+  default     = "minimal"
+
+  validation {
+    condition     = contains(["minimal", "hardened"], var.profile)
+    error_message = "profile must be minimal or hardened."
+  }
+}
+variable "create_kms_key" {
+  description = "Create a rotated customer-managed KMS key for website objects that the distribution can decrypt, unless kms_key_arn is set; the log buckets stay on SSE-S3. null follows profile"
+  type        = bool
+  default     = null
+}
+variable "validate_certificate" {
+  description = "Create the ACM DNS validation records in the hosted zone and wait for the certificate to be issued; only takes effect with hosted_zone_id or create_zone"
+  type        = bool
+  default     = true
+}
 variable "price_class" {
-  type    = string
-  default = "PriceClass_100"
+  description = "CloudFront price class: PriceClass_100 (North America and Europe), PriceClass_200 (adds most of Asia, the Middle East and Africa) or PriceClass_All"
+  type        = string
+  default     = "PriceClass_100"
+
+  validation {
+    condition     = contains(["PriceClass_100", "PriceClass_200", "PriceClass_All"], var.price_class)
+    error_message = "price_class must be PriceClass_100, PriceClass_200 or PriceClass_All."
+  }
 }
 variable "rate_limit" {
-  type    = number
-  default = 2000
+  description = "Requests per 5-minute window from one IP before the WAF rate-based rule blocks it"
+  type        = number
+  default     = 2000
+
+  validation {
+    condition     = var.rate_limit >= 100 && var.rate_limit <= 20000
+    error_message = "rate_limit must be between 100 and 20000."
+  }
+}
+variable "enable_waf" {
+  description = "Create the CLOUDFRONT-scope web ACL and associate it with the distribution"
+  type        = bool
+  default     = true
+}
+variable "enable_body_size_rule" {
+  description = "Block requests whose body exceeds max_body_size bytes; null follows profile"
+  type        = bool
+  default     = null
+}
+variable "max_body_size" {
+  description = "Maximum request body size in bytes allowed by the WAF body size rule"
+  type        = number
+  default     = 8192
+
+  validation {
+    condition     = var.max_body_size >= 1 && var.max_body_size <= 16384
+    error_message = "max_body_size must be between 1 and 16384 bytes (the CloudFront WAF body inspection limit)."
+  }
+}
+variable "blocked_countries" {
+  description = "ISO 3166-1 alpha-2 country codes the WAF blocks; leave empty to serve every country"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = alltrue([for c in var.blocked_countries : can(regex("^[A-Z]{2}$", c))])
+    error_message = "blocked_countries must be two-letter uppercase ISO 3166-1 alpha-2 country codes."
+  }
+}
+variable "allowed_cidrs" {
+  description = "IPv4 or IPv6 CIDR blocks the WAF always allows, before the blocklist, rate limiting and the managed rule groups"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = alltrue([for c in var.allowed_cidrs : can(cidrhost(c, 0))])
+    error_message = "allowed_cidrs must be IPv4 or IPv6 CIDR blocks (e.g. 203.0.113.0/24 or 2001:db8::/32)."
+  }
+}
+variable "blocked_cidrs" {
+  description = "IPv4 or IPv6 CIDR blocks the WAF always blocks, before rate limiting and the managed rule groups"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = alltrue([for c in var.blocked_cidrs : can(cidrhost(c, 0))])
+    error_message = "blocked_cidrs must be IPv4 or IPv6 CIDR blocks (e.g. 203.0.113.0/24 or 2001:db8::/32)."
+  }
+}
+variable "waf_geo_forwarded_ip_header" {
+  description = "Test only: evaluate the geo block against the IP in this header (e.g. X-Forwarded-For) instead of the viewer's address"
+  type        = string
+  default     = ""
+}
+variable "waf_block_response" {
+  description = "Custom status code and body the WAF's IP blocklist, rate limit, body size and geo block rules answer with (content_type TEXT_PLAIN, TEXT_HTML or APPLICATION_JSON); null returns WAF's standard 403"
+  type = object({
+    status_code  = number
+    content_type = string
+    body         = string
+  })
+  default = null
+
+  validation {
+    condition = var.waf_block_response == null ? true : (
+      var.waf_block_response.status_code >= 200 && var.waf_block_response.status_code <= 599 &&
+      contains(["TEXT_PLAIN", "TEXT_HTML", "APPLICATION_JSON"], var.waf_block_response.content_type)
+    )
+    error_message = "waf_block_response needs a status_code between 200 and 599 and a content_type of TEXT_PLAIN, TEXT_HTML or APPLICATION_JSON."
+  }
+}
+variable "edge_headers_mode" {
+  description = "How security headers are added: a CloudFront response headers policy (policy) or a Lambda@Edge origin-response function (lambda_edge)"
+  type        = string
+  default     = "policy"
+
+  validation {
+    condition     = contains(["policy", "lambda_edge"], var.edge_headers_mode)
+    error_message = "edge_headers_mode must be policy or lambda_edge."
+  }
+}
+variable "edge_headers_retain_on_destroy" {
+  description = "Leave the Lambda@Edge function behind on destroy; false deletes it, which fails until CloudFront has removed its replicas (up to a few hours)"
+  type        = bool
+  default     = true
+}
+variable "enable_origin_verify_header" {
+  description = "Send a secret header to the origin and create a regional WAF that requires it (for non-S3 origins)"
+  type        = bool
+  default     = false
+}
+variable "origin_verify_header_name" {
+  description = "Name of the secret header CloudFront sends to the origin"
+  type        = string
+  default     = "X-Origin-Verify"
+
+  validation {
+    condition     = can(regex("^[A-Za-z0-9-]+$", var.origin_verify_header_name)) && !can(regex("^(?i)(x-amz-|x-edge-)", var.origin_verify_header_name))
+    error_message = "origin_verify_header_name must be a valid header name and cannot use the reserved X-Amz- or X-Edge- prefixes."
+  }
+}
+variable "api_origin_domain" {
+  description = "Hostname of an API origin (ALB, API Gateway, ...) served under /api/*; leave empty for an S3-only site"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.api_origin_domain == "" || can(regex("^[a-z0-9.-]+$", var.api_origin_domain))
+    error_message = "api_origin_domain must be a bare lowercase hostname without scheme or path."
+  }
+}
+variable "enable_ipv6" {
+  description = "Serve the distribution over IPv6 as well as IPv4 (and publish an AAAA alias when a hosted zone is set)"
+  type        = bool
+  default     = true
+}
+variable "cloudfront_allowed_methods" {
+  description = "HTTP methods CloudFront accepts; anything else is rejected with 403 before reaching the origin"
+  type        = list(string)
+  default     = ["GET", "HEAD", "OPTIONS"]
+
+  validation {
+    condition = contains([
+      ["GET", "HEAD"],
+      ["GET", "HEAD", "OPTIONS"],
+      ["DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT"],
+    ], sort(var.cloudfront_allowed_methods))
+    error_message = "cloudfront_allowed_methods must be [GET, HEAD], [GET, HEAD, OPTIONS] or all seven methods (the only sets CloudFront supports)."
+  }
+}
+variable "cors_allowed_origins" {
+  description = "Origins (https://host[:port]) allowed to fetch assets cross-origin; empty sends no CORS headers"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = alltrue([for origin in var.cors_allowed_origins : origin == "*" || can(regex("^https?://[a-z0-9.-]+(:[0-9]+)?$", origin))])
+    error_message = "cors_allowed_origins entries must be \"*\" or scheme://host[:port] with no path or trailing slash."
+  }
+}
+variable "cors_allowed_methods" {
+  description = "Methods listed in Access-Control-Allow-Methods when CORS is on"
+  type        = list(string)
+  default     = ["GET", "HEAD", "OPTIONS"]
+
+  validation {
+    condition     = length(var.cors_allowed_methods) > 0 && alltrue([for method in var.cors_allowed_methods : contains(["GET", "HEAD", "OPTIONS", "POST", "PUT", "PATCH", "DELETE", "ALL"], method)])
+    error_message = "cors_allowed_methods must be a non-empty list of GET, HEAD, OPTIONS, POST, PUT, PATCH, DELETE or ALL."
+  }
+}
+variable "cors_allowed_headers" {
+  description = "Request headers listed in Access-Control-Allow-Headers when CORS is on"
+  type        = list(string)
+  default     = ["*"]
+}
+variable "cors_max_age_sec" {
+  description = "How long browsers may cache a preflight response (Access-Control-Max-Age)"
+  type        = number
+  default     = 600
+
+  validation {
+    condition     = var.cors_max_age_sec >= 0 && var.cors_max_age_sec <= 86400
+    error_message = "cors_max_age_sec must be between 0 and 86400."
+  }
+}
+variable "origin_connection_attempts" {
+  description = "Number of times CloudFront tries to connect to the origin"
+  type        = number
+  default     = 3
+
+  validation {
+    condition     = var.origin_connection_attempts >= 1 && var.origin_connection_attempts <= 3
+    error_message = "origin_connection_attempts must be between 1 and 3."
+  }
+}
+variable "origin_connection_timeout" {
+  description = "Seconds CloudFront waits when connecting to the origin"
+  type        = number
+  default     = 10
+
+  validation {
+    condition     = var.origin_connection_timeout >= 1 && var.origin_connection_timeout <= 10
+    error_message = "origin_connection_timeout must be between 1 and 10 seconds."
+  }
+}
+variable "origin_read_timeout" {
+  description = "Seconds CloudFront waits for a response from a custom (non-S3) origin"
+  type        = number
+  default     = 30
+
+  validation {
+    condition     = var.origin_read_timeout >= 1 && var.origin_read_timeout <= 180
+    error_message = "origin_read_timeout must be between 1 and 180 seconds (above 60 requires a quota increase)."
+  }
+}
+variable "origin_keepalive_timeout" {
+  description = "Seconds CloudFront keeps an idle connection to a custom (non-S3) origin open"
+  type        = number
+  default     = 5
+
+  validation {
+    condition     = var.origin_keepalive_timeout >= 1 && var.origin_keepalive_timeout <= 180
+    error_message = "origin_keepalive_timeout must be between 1 and 180 seconds (above 60 requires a quota increase)."
+  }
+}
+variable "origin_path" {
+  description = "Bucket path prefix CloudFront serves the site from (e.g. /build); leave empty to serve from the bucket root"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.origin_path == "" || can(regex("^/[A-Za-z0-9._/-]*[^/]$", var.origin_path))
+    error_message = "origin_path must start with / and must not end with /."
+  }
+}
+variable "waf_log_redacted_headers" {
+  description = "Request headers redacted from WAF logs"
+  type        = list(string)
+  default     = ["authorization", "cookie"]
+
+  validation {
+    condition     = alltrue([for h in var.waf_log_redacted_headers : can(regex("^[a-z0-9-]+$", h))])
+    error_message = "waf_log_redacted_headers must be lowercase header names (WAF matches single_header names in lowercase)."
+  }
+}
+variable "waf_log_destination" {
+  description = "Where WAF logs go: firehose (Kinesis Firehose into the WAF log bucket), s3 (delivered straight to the bucket) or cloudwatch (a CloudWatch Logs group)"
+  type        = string
+  default     = "firehose"
+
+  validation {
+    condition     = contains(["firehose", "s3", "cloudwatch"], var.waf_log_destination)
+    error_message = "waf_log_destination must be firehose, s3 or cloudwatch."
+  }
+}
+variable "enable_s3_request_metrics" {
+  description = "Publish S3 request metrics (AllRequests, GetRequests, 4xx/5xx...) for the website bucket to CloudWatch"
+  type        = bool
+  default     = false
+}
+variable "s3_request_metrics_prefix" {
+  description = "Limit S3 request metrics to objects under this key prefix; leave empty for the whole bucket"
+  type        = string
+  default     = ""
+}
+variable "lifecycle_rules" {
+  description = "Lifecycle rules for the website bucket. transition_storage_class applies to both transition_days and noncurrent_version_transition_days; the default moves old versions to STANDARD_IA after 30 days and expires them after 90."
+  type = list(object({
+    id                                 = string
+    prefix                             = optional(string, "")
+    transition_days                    = optional(number)
+    transition_storage_class           = optional(string)
+    expiration_days                    = optional(number)
+    noncurrent_version_transition_days = optional(number)
+    noncurrent_version_expiration_days = optional(number)
+  }))
+  default = [{
+    id                                 = "noncurrent-versions"
+    transition_storage_class           = "STANDARD_IA"
+    noncurrent_version_transition_days = 30
+    noncurrent_version_expiration_days = 90
+  }]
+
+  validation {
+    condition     = length(distinct([for r in var.lifecycle_rules : r.id])) == length(var.lifecycle_rules)
+    error_message = "lifecycle_rules ids must be unique."
+  }
+  validation {
+    condition     = alltrue([for r in var.lifecycle_rules : r.transition_storage_class == null ? true : contains(["STANDARD_IA", "ONEZONE_IA", "INTELLIGENT_TIERING", "GLACIER_IR", "GLACIER", "DEEP_ARCHIVE"], r.transition_storage_class)])
+    error_message = "lifecycle_rules transition_storage_class must be STANDARD_IA, ONEZONE_IA, INTELLIGENT_TIERING, GLACIER_IR, GLACIER or DEEP_ARCHIVE."
+  }
+  validation {
+    condition     = alltrue([for r in var.lifecycle_rules : (r.transition_days != null || r.noncurrent_version_transition_days != null) == (r.transition_storage_class != null)])
+    error_message = "lifecycle_rules transition_storage_class must be set together with transition_days or noncurrent_version_transition_days."
+  }
+  validation {
+    condition     = alltrue([for r in var.lifecycle_rules : alltrue([for days in [r.transition_days, r.expiration_days, r.noncurrent_version_transition_days, r.noncurrent_version_expiration_days] : days == null ? true : days >= 1])])
+    error_message = "lifecycle_rules days must be at least 1."
+  }
+  validation {
+    condition     = alltrue([for r in var.lifecycle_rules : !contains(["STANDARD_IA", "ONEZONE_IA"], coalesce(r.transition_storage_class, "none")) ? true : alltrue([for days in [r.transition_days, r.noncurrent_version_transition_days] : days == null ? true : days >= 30])])
+    error_message = "lifecycle_rules transitions to STANDARD_IA or ONEZONE_IA need at least 30 days; S3 rejects anything sooner."
+  }
+}
+variable "enable_realtime_logs" {
+  description = "Stream CloudFront real-time logs to a Kinesis data stream (billed per shard hour and per log line)"
+  type        = bool
+  default     = false
+}
+variable "realtime_logs_sampling_rate" {
+  description = "Percentage of viewer requests included in real-time logs"
+  type        = number
+  default     = 100
+
+  validation {
+    condition     = var.realtime_logs_sampling_rate >= 1 && var.realtime_logs_sampling_rate <= 100
+    error_message = "realtime_logs_sampling_rate must be between 1 and 100."
+  }
 }
 variable "log_lifecycle_days" {
-  type    = number
-  default = 365
+  description = "Days to keep CloudFront and WAF logs; null follows profile"
+  type        = number
+  default     = null
+}
+variable "cloudfront_log_prefix" {
+  description = "Key prefix for CloudFront standard logs in the log bucket"
+  type        = string
+  default     = "cloudfront-logs"
+}
+variable "cloudfront_log_include_cookies" {
+  description = "Include request cookies in CloudFront standard logs"
+  type        = bool
+  default     = false
+}
+variable "enable_cd" {
+  description = "Create a CloudFront staging distribution and continuous deployment policy for trying changes before promoting them"
+  type        = bool
+  default     = false
+}
+variable "cd_staging_origin_path" {
+  description = "Bucket path prefix the staging distribution serves from (e.g. /staging); leave empty to serve the same objects as production"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.cd_staging_origin_path == "" || can(regex("^/[A-Za-z0-9._/-]*[^/]$", var.cd_staging_origin_path))
+    error_message = "cd_staging_origin_path must start with / and must not end with /."
+  }
+}
+variable "cd_traffic_weight" {
+  description = "Share of viewer traffic sent to staging; 0 routes only requests carrying the aws-cf-cd-staging header"
+  type        = number
+  default     = 0
+
+  validation {
+    condition     = var.cd_traffic_weight >= 0 && var.cd_traffic_weight <= 0.15
+    error_message = "cd_traffic_weight must be between 0 and 0.15."
+  }
+}
+variable "enable_shield_advanced" {
+  description = "Protect the distribution with Shield Advanced, with health-based detection. The account must already subscribe to Shield Advanced ($3,000/month, one-year commitment)"
+  type        = bool
+  default     = false
+}
+variable "shield_health_check_path" {
+  description = "Path the Route53 health check used for Shield Advanced health-based detection requests over HTTPS"
+  type        = string
+  default     = "/"
+
+  validation {
+    condition     = can(regex("^/", var.shield_health_check_path))
+    error_message = "shield_health_check_path must start with /."
+  }
 }
 
 locals {
@@ -21,42 +483,98 @@ locals {
     Project     = "static-website"
     ManagedBy   = "Terraform"
   }
+
+  # The S3 website endpoint can't sit behind a CLOUDFRONT-scope web ACL
+  cloudfront_enabled = var.serving_mode == "cloudfront"
+  waf_enabled        = var.enable_waf && local.cloudfront_enabled
+
+  # Known at plan time even when the zone itself is created in the same apply
+  route53_enabled = local.cloudfront_enabled && (var.hosted_zone_id != "" || var.create_zone)
+
+  # The created key's policy names the distribution, so it only exists with one
+  kms_key_enabled = local.create_kms_key && local.cloudfront_enabled
+  kms_key_arn     = local.kms_key_enabled ? module.kms[0].key_arn : var.kms_key_arn
 }
 
 module "headers_policy" {
-  source = "./modules/headers_policy"
-  name   = "security-headers-policy"
+  source               = "./modules/headers_policy"
+  name                 = "security-headers-policy"
+  cors_allowed_origins = var.cors_allowed_origins
+  cors_allowed_methods = var.cors_allowed_methods
+  cors_allowed_headers = var.cors_allowed_headers
+  cors_max_age_sec     = var.cors_max_age_sec
+}
+
+# Reproduces the policy's headers for orgs that standardize on Lambda@Edge
+module "edge_headers" {
+  count   = var.edge_headers_mode == "lambda_edge" && local.cloudfront_enabled ? 1 : 0
+  source  = "./modules/edge_headers"
+  name    = "${substr(replace(var.domain_name, ".", "-"), 0, 42)}-security-headers"
+  headers = module.headers_policy.headers
+  tags    = local.tags
+
+  retain_on_destroy = var.edge_headers_retain_on_destroy
+  providers = {
+    aws = aws.us_east_1
+  }
 }
 
 module "waf" {
-  source     = "./modules/waf"
-  name       = "static-website-waf"
-  rate_limit = var.rate_limit
-  tags       = local.tags
+  count                   = local.waf_enabled ? 1 : 0
+  source                  = "./modules/waf"
+  name                    = "static-website-waf"
+  rate_limit              = var.rate_limit
+  enable_body_size_rule   = local.enable_body_size_rule
+  max_body_size           = var.max_body_size
+  blocked_countries       = var.blocked_countries
+  allowed_cidrs           = var.allowed_cidrs
+  blocked_cidrs           = var.blocked_cidrs
+  geo_forwarded_ip_header = var.waf_geo_forwarded_ip_header
+  block_response          = var.waf_block_response
+  tags                    = local.tags
   providers = {
     aws = aws.us_east_1
   }
 }
 
+moved {
+  from = module.waf
+  to   = module.waf[0]
+}
+
 module "cloudfront_logs" {
   source         = "./modules/log_bucket"
   name_prefix    = "cloudfront-logs"
-  lifecycle_days = var.log_lifecycle_days
+  lifecycle_days = local.log_lifecycle_days
+  acls_required  = true # CloudFront standard logging delivers through ACLs
   tags           = local.tags
 }
 
+# WAF only delivers straight to buckets whose names start with aws-waf-logs-.
+# Firehose and CloudWatch keep the original waf-logs prefix so existing stacks
+# keep their bucket; moving to s3 replaces it.
 module "waf_logs" {
-  source         = "./modules/log_bucket"
-  name_prefix    = "waf-logs"
-  lifecycle_days = var.log_lifecycle_days
-  tags           = local.tags
+  count                    = local.waf_enabled ? 1 : 0
+  source                   = "./modules/log_bucket"
+  name_prefix              = var.waf_log_destination == "s3" ? "aws-waf-logs-static-website" : "waf-logs"
+  lifecycle_days           = local.log_lifecycle_days
+  acls_required            = false # Firehose and log delivery write as this account
+  log_delivery_source_arns = var.waf_log_destination == "s3" ? [module.waf[0].arn] : []
+  tags                     = local.tags
   providers = {
     aws = aws.us_east_1
   }
 }
 
+moved {
+  from = module.waf_logs
+  to   = module.waf_logs[0]
+}
+
+# WAF log delivery resources only exist alongside the web ACL
 resource "aws_iam_role" "firehose_role" {
-  name = "firehose-waf-logs-role"
+  count = local.waf_enabled && var.waf_log_destination == "firehose" ? 1 : 0
+  name  = "firehose-waf-logs-role"
   assume_role_policy = jsonencode({
     Version   = "2012-10-17"
     Statement = [{ Action = "sts:AssumeRole", Effect = "Allow", Principal = { Service = "firehose.amazonaws.com" } }]
@@ -64,23 +582,25 @@ resource "aws_iam_role" "firehose_role" {
 }
 
 resource "aws_iam_role_policy" "firehose_policy" {
-  name = "firehose-waf-logs-policy"
-  role = aws_iam_role.firehose_role.id
+  count = local.waf_enabled && var.waf_log_destination == "firehose" ? 1 : 0
+  name  = "firehose-waf-logs-policy"
+  role  = aws_iam_role.firehose_role[0].id
   policy = jsonencode({
     Version = "2012-10-17"
     Statement = [{ Effect = "Allow", Action = [
       "s3:AbortMultipartUpload", "s3:GetBucketLocation", "s3:GetObject", "s3:ListBucket", "s3:ListBucketMultipartUploads", "s3:PutObject"
-    ], Resource = [module.waf_logs.bucket_arn, "${module.waf_logs.bucket_arn}/*"] }]
+    ], Resource = [module.waf_logs[0].bucket_arn, "${module.waf_logs[0].bucket_arn}/*"] }]
   })
 }
 
 resource "aws_kinesis_firehose_delivery_stream" "waf_logs" {
+  count       = local.waf_enabled && var.waf_log_destination == "firehose" ? 1 : 0
   provider    = aws.us_east_1
-  name        = "waf-logs-stream"
+  name        = "aws-waf-logs-static-website"
   destination = "extended_s3"
   extended_s3_configuration {
-    role_arn           = aws_iam_role.firehose_role.arn
-    bucket_arn         = module.waf_logs.bucket_arn
+    role_arn           = aws_iam_role.firehose_role[0].arn
+    bucket_arn         = module.waf_logs[0].bucket_arn
     buffering_size     = 128
     buffering_interval = 300
     compression_format = "GZIP"
@@ -90,75 +610,253 @@ resource "aws_kinesis_firehose_delivery_stream" "waf_logs" {
   tags = local.tags
 }
 
+resource "aws_cloudwatch_log_group" "waf_logs" {
+  count             = local.waf_enabled && var.waf_log_destination == "cloudwatch" ? 1 : 0
+  provider          = aws.us_east_1
+  name              = "aws-waf-logs-static-website"
+  retention_in_days = local.log_lifecycle_days
+  tags              = local.tags
+
+  lifecycle {
+    precondition {
+      condition     = contains([1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], local.log_lifecycle_days)
+      error_message = "log_lifecycle_days must be a CloudWatch Logs retention period (e.g. 30, 90, 365) when waf_log_destination is cloudwatch."
+    }
+  }
+}
+
+data "aws_caller_identity" "current" {}
+
+# WAF delivers to CloudWatch Logs through the log delivery service
+resource "aws_cloudwatch_log_resource_policy" "waf_logs" {
+  count       = local.waf_enabled && var.waf_log_destination == "cloudwatch" ? 1 : 0
+  provider    = aws.us_east_1
+  policy_name = "aws-waf-logs-static-website"
+  policy_document = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Sid       = "AWSLogDeliveryWrite"
+      Effect    = "Allow"
+      Principal = { Service = "delivery.logs.amazonaws.com" }
+      Action    = ["logs:CreateLogStream", "logs:PutLogEvents"]
+      Resource  = "${aws_cloudwatch_log_group.waf_logs[0].arn}:*"
+      Condition = {
+        StringEquals = { "aws:SourceAccount" = data.aws_caller_identity.current.account_id }
+        ArnLike      = { "aws:SourceArn" = "arn:aws:logs:${var.us_east_1_region}:${data.aws_caller_identity.current.account_id}:*" }
+      }
+    }]
+  })
+}
+
+locals {
+  waf_log_destination_arn = {
+    firehose   = one(aws_kinesis_firehose_delivery_stream.waf_logs[*].arn)
+    s3         = one(module.waf_logs[*].bucket_arn)
+    cloudwatch = one(aws_cloudwatch_log_group.waf_logs[*].arn)
+  }[var.waf_log_destination]
+}
+
 resource "aws_wafv2_web_acl_logging_configuration" "main" {
+  count                   = local.waf_enabled ? 1 : 0
   provider                = aws.us_east_1
-  log_destination_configs = [aws_kinesis_firehose_delivery_stream.waf_logs.arn]
-  resource_arn            = module.waf.arn
-  redacted_fields {
-    single_header { name = "authorization" }
+  log_destination_configs = [local.waf_log_destination_arn]
+  resource_arn            = module.waf[0].arn
+
+  dynamic "redacted_fields" {
+    for_each = toset(var.waf_log_redacted_headers)
+    content {
+      single_header { name = redacted_fields.value }
+    }
   }
+
+  # The destination must accept deliveries before WAF validates it
+  depends_on = [module.waf_logs, aws_cloudwatch_log_resource_policy.waf_logs]
+}
+
+resource "random_password" "origin_verify" {
+  count   = var.enable_origin_verify_header ? 1 : 0
+  length  = 32
+  special = false
+}
+
+module "origin_verify_waf" {
+  count        = var.enable_origin_verify_header ? 1 : 0
+  source       = "./modules/origin_verify_waf"
+  name         = "static-website-origin-verify"
+  header_name  = var.origin_verify_header_name
+  header_value = random_password.origin_verify[0].result
+  tags         = local.tags
 }
 
 module "website_bucket" {
-  source      = "./modules/website_bucket"
-  bucket_name = "${var.domain_name}-static-site"
-  tags        = local.tags
+  source                 = "./modules/website_bucket"
+  bucket_name            = "${var.domain_name}-static-site"
+  index_document         = var.index_document
+  error_document         = var.error_document
+  public_read            = !local.cloudfront_enabled
+  enable_request_metrics = var.enable_s3_request_metrics
+  request_metrics_prefix = var.s3_request_metrics_prefix
+  lifecycle_rules        = var.lifecycle_rules
+  kms_key_arn            = local.kms_key_arn
+  cors_allowed_origins   = var.cors_allowed_origins
+  cors_allowed_methods   = var.cors_allowed_methods
+  cors_allowed_headers   = var.cors_allowed_headers
+  cors_max_age_sec       = var.cors_max_age_sec
+  tags                   = local.tags
+}
+
+module "kms" {
+  count             = local.kms_key_enabled ? 1 : 0
+  source            = "./modules/kms"
+  alias_name        = "${replace(var.domain_name, ".", "-")}-static-site"
+  distribution_arns = var.enable_cd ? [module.cloudfront[0].distribution_arn, module.cloudfront[0].staging_distribution_arn] : [module.cloudfront[0].distribution_arn]
+  tags              = local.tags
+}
+
+module "realtime_logs" {
+  count         = var.enable_realtime_logs ? 1 : 0
+  source        = "./modules/realtime_logs"
+  name          = "static-website-realtime-logs"
+  sampling_rate = var.realtime_logs_sampling_rate
+  tags          = local.tags
 }
 
 module "cloudfront" {
+  count                         = local.cloudfront_enabled ? 1 : 0
   source                        = "./modules/cloudfront"
   domain_name                   = var.domain_name
   certificate_domain_name       = var.domain_name
+  hosted_zone_id                = local.route53_enabled ? module.route53[0].zone_id : ""
+  validate_certificate          = local.route53_enabled && var.validate_certificate
   origin_bucket_regional_domain = module.website_bucket.bucket_regional_domain_name
-  response_headers_policy_id    = module.headers_policy.id
-  waf_web_acl_arn               = module.waf.arn
+  origin_path                   = var.origin_path
+  index_document                = var.index_document
+  custom_error_responses        = var.custom_error_responses
+  response_headers_policy_id    = var.edge_headers_mode == "policy" ? module.headers_policy.id : ""
+  edge_headers_function_arn     = var.edge_headers_mode == "lambda_edge" ? module.edge_headers[0].qualified_arn : ""
+  waf_web_acl_arn               = local.waf_enabled ? module.waf[0].arn : ""
   price_class                   = var.price_class
   log_bucket_domain             = module.cloudfront_logs.bucket_domain_name
+  log_prefix                    = var.cloudfront_log_prefix
+  log_include_cookies           = var.cloudfront_log_include_cookies
   tags                          = local.tags
   origin_shield_region          = var.us_east_1_region
+  origin_custom_header_name     = var.enable_origin_verify_header ? var.origin_verify_header_name : ""
+  origin_custom_header_value    = var.enable_origin_verify_header ? random_password.origin_verify[0].result : ""
+  allowed_methods               = var.cloudfront_allowed_methods
+  enable_ipv6                   = var.enable_ipv6
+  realtime_log_config_arn       = var.enable_realtime_logs ? module.realtime_logs[0].arn : ""
+  api_origin_domain             = var.api_origin_domain
+  origin_connection_attempts    = var.origin_connection_attempts
+  origin_connection_timeout     = var.origin_connection_timeout
+  origin_read_timeout           = var.origin_read_timeout
+  origin_keepalive_timeout      = var.origin_keepalive_timeout
+  enable_cd                     = var.enable_cd
+  staging_origin_path           = var.cd_staging_origin_path
+  staging_traffic_weight        = var.cd_traffic_weight
   providers = {
     aws           = aws
     aws.us_east_1 = aws.us_east_1
   }
 }
 
+module "shield_advanced" {
+  count             = var.enable_shield_advanced && local.cloudfront_enabled ? 1 : 0
+  source            = "./modules/shield_advanced"
+  name              = "static-website-distribution"
+  resource_arn      = module.cloudfront[0].distribution_arn
+  health_check_fqdn = module.cloudfront[0].distribution_domain_name
+  health_check_path = var.shield_health_check_path
+  tags              = local.tags
+  providers = {
+    aws = aws.us_east_1
+  }
+}
+
+# The distribution became optional with serving_mode; keep existing state in place
+moved {
+  from = module.cloudfront
+  to   = module.cloudfront[0]
+}
+
 data "aws_iam_policy_document" "s3_policy" {
-  statement {
-    actions   = ["s3:GetObject"]
-    resources = ["${module.website_bucket.arn}/*"]
-    principals {
-      type        = "Service"
-      identifiers = ["cloudfront.amazonaws.com"]
-    }
-    condition {
-      test     = "StringEquals"
-      variable = "AWS:SourceArn"
-      values   = [module.cloudfront.distribution_arn]
+  dynamic "statement" {
+    for_each = local.cloudfront_enabled ? [1] : []
+    content {
+      actions   = ["s3:GetObject"]
+      resources = ["${module.website_bucket.arn}/*"]
+      principals {
+        type        = "Service"
+        identifiers = ["cloudfront.amazonaws.com"]
+      }
+      condition {
+        test     = "StringEquals"
+        variable = "AWS:SourceArn"
+        values   = var.enable_cd ? [module.cloudfront[0].distribution_arn, module.cloudfront[0].staging_distribution_arn] : [module.cloudfront[0].distribution_arn]
+      }
     }
   }
 
-  # Deny non-TLS while excluding AWS service principals
-  statement {
-    sid     = "DenyInsecureTransport"
-    effect  = "Deny"
-    actions = ["s3:*"]
-    resources = [
-      module.website_bucket.arn,
-      "${module.website_bucket.arn}/*"
-    ]
-    principals {
-      type        = "*"
-      identifiers = ["*"]
+  # Without ListBucket S3 answers 403 for missing keys, which the default
+  # error responses (404 only) wouldn't catch
+  dynamic "statement" {
+    for_each = local.cloudfront_enabled ? [1] : []
+    content {
+      sid       = "CloudFrontListBucket"
+      actions   = ["s3:ListBucket"]
+      resources = [module.website_bucket.arn]
+      principals {
+        type        = "Service"
+        identifiers = ["cloudfront.amazonaws.com"]
+      }
+      condition {
+        test     = "StringEquals"
+        variable = "AWS:SourceArn"
+        values   = var.enable_cd ? [module.cloudfront[0].distribution_arn, module.cloudfront[0].staging_distribution_arn] : [module.cloudfront[0].distribution_arn]
+      }
     }
-    condition {
-      test     = "Bool"
-      variable = "aws:SecureTransport"
-      values   = ["false"]
+  }
+
+  # The S3 website endpoint serves anonymous reads over plain HTTP
+  dynamic "statement" {
+    for_each = local.cloudfront_enabled ? [] : [1]
+    content {
+      sid       = "PublicWebsiteRead"
+      actions   = ["s3:GetObject"]
+      resources = ["${module.website_bucket.arn}/*"]
+      principals {
+        type        = "*"
+        identifiers = ["*"]
+      }
     }
-    condition {
-      test     = "Bool"
-      variable = "aws:PrincipalIsAWSService"
-      values   = ["false"]
+  }
+
+  # Deny non-TLS while excluding AWS service principals. Only CloudFront mode
+  # can require TLS; the website endpoint has no HTTPS listener.
+  dynamic "statement" {
+    for_each = local.cloudfront_enabled ? [1] : []
+    content {
+      sid     = "DenyInsecureTransport"
+      effect  = "Deny"
+      actions = ["s3:*"]
+      resources = [
+        module.website_bucket.arn,
+        "${module.website_bucket.arn}/*"
+      ]
+      principals {
+        type        = "*"
+        identifiers = ["*"]
+      }
+      condition {
+        test     = "Bool"
+        variable = "aws:SecureTransport"
+        values   = ["false"]
+      }
+      condition {
+        test     = "Bool"
+        variable = "aws:PrincipalIsAWSService"
+        values   = ["false"]
+      }
     }
   }
 }
@@ -166,11 +864,25 @@ data "aws_iam_policy_document" "s3_policy" {
 resource "aws_s3_bucket_policy" "website" {
   bucket = module.website_bucket.id
   policy = data.aws_iam_policy_document.s3_policy.json
+
+  # A public read policy is rejected until the public access block allows it
+  depends_on = [module.website_bucket]
 }
 
-module "route53_alias" {
-  source                      = "./modules/route53_alias"
+module "route53" {
+  count                       = local.route53_enabled ? 1 : 0
+  source                      = "./modules/route53"
+  zone_id                     = var.hosted_zone_id
+  create_zone                 = var.create_zone
   domain_name                 = var.domain_name
-  distribution_domain_name    = module.cloudfront.distribution_domain_name
-  distribution_hosted_zone_id = module.cloudfront.distribution_hosted_zone_id
+  distribution_domain_name    = module.cloudfront[0].distribution_domain_name
+  distribution_hosted_zone_id = module.cloudfront[0].distribution_hosted_zone_id
+  enable_ipv6                 = var.enable_ipv6
+  tags                        = local.tags
+}
+
+# The alias module grew into the general Route53 module, which is now optional
+moved {
+  from = module.route53_alias
+  to   = module.route53[0]
 }
\ No newline at end of file
@@ -0,0 +1,205 @@
+# Optional continuous deployment: a staging copy of the distribution that
+# receives either requests carrying the staging header or a small share of
+# traffic, so changes can be tried before promoting them to production.
+variable "enable_cd" {
+  type    = bool
+  default = false
+}
+# Path prefix in the bucket the staging distribution serves from; empty
+# serves the same prefix as production
+variable "staging_origin_path" {
+  type    = string
+  default = ""
+}
+# Zero routes by header only; otherwise the share of viewer traffic sent to staging
+variable "staging_traffic_weight" {
+  type    = number
+  default = 0
+}
+
+locals {
+  # CloudFront requires continuous deployment header names to start with aws-cf-cd-
+  staging_header_name  = "aws-cf-cd-staging"
+  staging_header_value = "true"
+}
+
+resource "aws_cloudfront_distribution" "staging" {
+  count   = var.enable_cd ? 1 : 0
+  staging = true
+
+  origin {
+    domain_name              = local.s3_origin.domain_name
+    origin_access_control_id = local.s3_origin.origin_access_control_id
+    origin_id                = local.s3_origin.origin_id
+    origin_path              = var.staging_origin_path != "" ? var.staging_origin_path : var.origin_path
+    connection_attempts      = local.s3_origin.connection_attempts
+    connection_timeout       = local.s3_origin.connection_timeout
+    origin_shield {
+      enabled              = true
+      origin_shield_region = local.s3_origin.origin_shield_region
+    }
+    dynamic "custom_header" {
+      for_each = local.origin_custom_header_names
+      content {
+        name  = custom_header.value
+        value = var.origin_custom_header_value
+      }
+    }
+  }
+
+  dynamic "origin" {
+    for_each = local.api_origins
+    content {
+      domain_name         = origin.value.domain_name
+      origin_id           = origin.value.origin_id
+      connection_attempts = origin.value.connection_attempts
+      connection_timeout  = origin.value.connection_timeout
+      custom_origin_config {
+        http_port                = 80
+        https_port               = 443
+        origin_protocol_policy   = "https-only"
+        origin_ssl_protocols     = ["TLSv1.2"]
+        origin_read_timeout      = origin.value.origin_read_timeout
+        origin_keepalive_timeout = origin.value.origin_keepalive_timeout
+      }
+      dynamic "custom_header" {
+        for_each = local.origin_custom_header_names
+        content {
+          name  = custom_header.value
+          value = var.origin_custom_header_value
+        }
+      }
+    }
+  }
+
+  enabled             = true
+  is_ipv6_enabled     = var.enable_ipv6
+  comment             = "Staging distribution for ${var.domain_name}"
+  default_root_object = var.index_document
+
+  # Staging distributions can't have aliases; viewers reach them through the primary
+  web_acl_id = local.web_acl_id
+
+  default_cache_behavior {
+    allowed_methods            = local.default_cache_behavior.allowed_methods
+    cached_methods             = local.default_cache_behavior.cached_methods
+    target_origin_id           = local.default_cache_behavior.target_origin_id
+    cache_policy_id            = local.default_cache_behavior.cache_policy_id
+    origin_request_policy_id   = local.default_cache_behavior.origin_request_policy_id
+    viewer_protocol_policy     = local.default_cache_behavior.viewer_protocol_policy
+    compress                   = true
+    response_headers_policy_id = local.response_headers_policy_id
+    realtime_log_config_arn    = local.default_cache_behavior.realtime_log_config_arn
+
+    dynamic "lambda_function_association" {
+      for_each = local.edge_headers_function_arns
+      content {
+        event_type   = "origin-response"
+        lambda_arn   = lambda_function_association.value
+        include_body = false
+      }
+    }
+  }
+
+  dynamic "ordered_cache_behavior" {
+    for_each = local.api_cache_behaviors
+    content {
+      path_pattern               = ordered_cache_behavior.value.path_pattern
+      allowed_methods            = ordered_cache_behavior.value.allowed_methods
+      cached_methods             = ordered_cache_behavior.value.cached_methods
+      target_origin_id           = ordered_cache_behavior.value.target_origin_id
+      cache_policy_id            = ordered_cache_behavior.value.cache_policy_id
+      origin_request_policy_id   = ordered_cache_behavior.value.origin_request_policy_id
+      viewer_protocol_policy     = ordered_cache_behavior.value.viewer_protocol_policy
+      compress                   = true
+      response_headers_policy_id = local.response_headers_policy_id
+
+      dynamic "lambda_function_association" {
+        for_each = local.edge_headers_function_arns
+        content {
+          event_type   = "origin-response"
+          lambda_arn   = lambda_function_association.value
+          include_body = false
+        }
+      }
+    }
+  }
+
+  http_version = "http2and3"
+
+  dynamic "custom_error_response" {
+    for_each = var.custom_error_responses
+    content {
+      error_code            = custom_error_response.value.error_code
+      response_code         = custom_error_response.value.response_code
+      response_page_path    = custom_error_response.value.response_page_path
+      error_caching_min_ttl = custom_error_response.value.error_caching_min_ttl
+    }
+  }
+
+  price_class = var.price_class
+
+  restrictions {
+    geo_restriction { restriction_type = "none" }
+  }
+
+  viewer_certificate {
+    acm_certificate_arn            = local.viewer_certificate.acm_certificate_arn
+    cloudfront_default_certificate = local.viewer_certificate.cloudfront_default_certificate
+    ssl_support_method             = local.viewer_certificate.ssl_support_method
+    minimum_protocol_version       = local.viewer_certificate.minimum_protocol_version
+  }
+
+  logging_config {
+    include_cookies = var.log_include_cookies
+    bucket          = var.log_bucket_domain
+    prefix          = "cloudfront-staging-logs"
+  }
+
+  tags = var.tags
+}
+
+resource "aws_cloudfront_continuous_deployment_policy" "this" {
+  count   = var.enable_cd ? 1 : 0
+  enabled = true
+
+  staging_distribution_dns_names {
+    items    = [aws_cloudfront_distribution.staging[0].domain_name]
+    quantity = 1
+  }
+
+  traffic_config {
+    type = var.staging_traffic_weight > 0 ? "SingleWeight" : "SingleHeader"
+
+    dynamic "single_header_config" {
+      for_each = var.staging_traffic_weight > 0 ? [] : [local.staging_header_name]
+      content {
+        header = single_header_config.value
+        value  = local.staging_header_value
+      }
+    }
+
+    dynamic "single_weight_config" {
+      for_each = var.staging_traffic_weight > 0 ? [var.staging_traffic_weight] : []
+      content {
+        weight = single_weight_config.value
+        # Keep a viewer on the same distribution for the length of a visit
+        session_stickiness_config {
+          idle_ttl    = 300
+          maximum_ttl = 600
+        }
+      }
+    }
+  }
+}
+
+output "staging_distribution_id" { value = var.enable_cd ? aws_cloudfront_distribution.staging[0].id : null }
+output "staging_distribution_arn" { value = var.enable_cd ? aws_cloudfront_distribution.staging[0].arn : null }
+output "staging_distribution_domain_name" { value = var.enable_cd ? aws_cloudfront_distribution.staging[0].domain_name : null }
+output "continuous_deployment_policy_id" { value = var.enable_cd ? aws_cloudfront_continuous_deployment_policy.this[0].id : null }
+output "staging_header" {
+  value = var.enable_cd && var.staging_traffic_weight == 0 ? {
+    name  = local.staging_header_name
+    value = local.staging_header_value
+  } : null
+}
@@ -1,14 +1,100 @@
 variable "domain_name" { type = string }
 variable "origin_bucket_regional_domain" { type = string }
-variable "response_headers_policy_id" { type = string }
-variable "waf_web_acl_arn" { type = string }
+# Bucket path prefix the site is served from; empty serves the bucket root
+variable "origin_path" {
+  type    = string
+  default = ""
+}
+variable "index_document" {
+  type    = string
+  default = "index.html"
+}
+variable "custom_error_responses" {
+  type = list(object({
+    error_code            = number
+    response_code         = optional(number)
+    response_page_path    = optional(string)
+    error_caching_min_ttl = optional(number)
+  }))
+  default = []
+}
+# Exactly one of these adds the security headers; the other is left empty
+variable "response_headers_policy_id" {
+  type    = string
+  default = ""
+}
+variable "edge_headers_function_arn" {
+  type    = string
+  default = ""
+}
+# CLOUDFRONT-scope web ACL ARN; empty leaves the distribution without WAF
+variable "waf_web_acl_arn" {
+  type    = string
+  default = ""
+}
 variable "price_class" { type = string }
 variable "log_bucket_domain" { type = string }
+variable "log_prefix" {
+  type    = string
+  default = "cloudfront-logs"
+}
+variable "log_include_cookies" {
+  type    = bool
+  default = false
+}
 variable "tags" { type = map(string) }
 variable "origin_shield_region" { 
   type = string 
   default = "us-east-1" 
 }
+variable "origin_custom_header_name" {
+  type    = string
+  default = ""
+}
+variable "origin_custom_header_value" {
+  type      = string
+  default   = ""
+  sensitive = true
+}
+variable "enable_ipv6" {
+  type    = bool
+  default = true
+}
+variable "realtime_log_config_arn" {
+  type    = string
+  default = ""
+}
+variable "allowed_methods" {
+  type    = list(string)
+  default = ["GET", "HEAD", "OPTIONS"]
+}
+variable "origin_connection_attempts" {
+  type    = number
+  default = 3
+}
+variable "origin_connection_timeout" {
+  type    = number
+  default = 10
+}
+# Read/keepalive timeouts only exist on custom (non-S3) origins
+variable "origin_read_timeout" {
+  type    = number
+  default = 30
+}
+variable "origin_keepalive_timeout" {
+  type    = number
+  default = 5
+}
+
+# Optional second origin (ALB, API Gateway, ...) served under /api/*
+variable "api_origin_domain" {
+  type    = string
+  default = ""
+}
+
+locals {
+  api_origin_enabled = var.api_origin_domain != ""
+}
 
 # Managed policies (resolved at apply time)
 data "aws_cloudfront_cache_policy" "managed_caching_optimized" {
@@ -17,6 +103,13 @@ data "aws_cloudfront_cache_policy" "managed_caching_optimized" {
 data "aws_cloudfront_origin_request_policy" "managed_cors_s3_origin" {
   name = "Managed-CORS-S3Origin"
 }
+data "aws_cloudfront_cache_policy" "managed_caching_disabled" {
+  name = "Managed-CachingDisabled"
+}
+# Forward everything except Host so the API origin sees its own hostname
+data "aws_cloudfront_origin_request_policy" "managed_all_viewer_except_host" {
+  name = "Managed-AllViewerExceptHostHeader"
+}
 resource "aws_cloudfront_origin_access_control" "oac" {
   name                              = "${var.domain_name}-oac"
   description                       = "OAC for static website"
@@ -25,51 +118,181 @@ resource "aws_cloudfront_origin_access_control" "oac" {
   signing_protocol                  = "sigv4"
 }
 
-resource "aws_cloudfront_distribution" "this" {
-  origin {
+# Settings the production and staging distributions share, so the staging
+# copy serves the site the same way it would be served once promoted
+locals {
+  s3_origin = {
     domain_name              = var.origin_bucket_regional_domain
     origin_access_control_id = aws_cloudfront_origin_access_control.oac.id
     origin_id                = "s3-origin"
+    connection_attempts      = var.origin_connection_attempts
+    connection_timeout       = var.origin_connection_timeout
+    origin_shield_region     = var.origin_shield_region
+  }
+  api_origins = local.api_origin_enabled ? [{
+    domain_name              = var.api_origin_domain
+    origin_id                = "api-origin"
+    connection_attempts      = var.origin_connection_attempts
+    connection_timeout       = var.origin_connection_timeout
+    origin_read_timeout      = var.origin_read_timeout
+    origin_keepalive_timeout = var.origin_keepalive_timeout
+  }] : []
+  # Optional secret header so the origin can reject requests bypassing CloudFront
+  origin_custom_header_names = var.origin_custom_header_name == "" ? [] : [var.origin_custom_header_name]
+
+  web_acl_id                 = var.waf_web_acl_arn == "" ? null : var.waf_web_acl_arn
+  response_headers_policy_id = var.response_headers_policy_id == "" ? null : var.response_headers_policy_id
+  edge_headers_function_arns = var.edge_headers_function_arn == "" ? [] : [var.edge_headers_function_arn]
+
+  # TTLs come from the cache policy, which honors the object's Cache-Control/Expires
+  default_cache_behavior = {
+    allowed_methods          = var.allowed_methods
+    cached_methods           = ["GET", "HEAD"]
+    target_origin_id         = "s3-origin"
+    cache_policy_id          = data.aws_cloudfront_cache_policy.managed_caching_optimized.id
+    origin_request_policy_id = data.aws_cloudfront_origin_request_policy.managed_cors_s3_origin.id
+    viewer_protocol_policy   = "redirect-to-https"
+    realtime_log_config_arn  = var.realtime_log_config_arn == "" ? null : var.realtime_log_config_arn
+  }
+  # API responses are dynamic, so they bypass the cache and accept all methods
+  api_cache_behaviors = local.api_origin_enabled ? [{
+    path_pattern             = "/api/*"
+    allowed_methods          = ["DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT"]
+    cached_methods           = ["GET", "HEAD"]
+    target_origin_id         = "api-origin"
+    cache_policy_id          = data.aws_cloudfront_cache_policy.managed_caching_disabled.id
+    origin_request_policy_id = data.aws_cloudfront_origin_request_policy.managed_all_viewer_except_host.id
+    viewer_protocol_policy   = "https-only"
+  }] : []
+
+  # Without a hosted zone the certificate can't be validated, so fall back to
+  # the default *.cloudfront.net certificate and skip the alias
+  viewer_certificate = local.certificate_in_zone ? {
+    acm_certificate_arn            = local.certificate_arn
+    cloudfront_default_certificate = null
+    ssl_support_method             = "sni-only"
+    minimum_protocol_version       = "TLSv1.2_2021"
+    } : {
+    acm_certificate_arn            = null
+    cloudfront_default_certificate = true
+    ssl_support_method             = null
+    minimum_protocol_version       = "TLSv1"
+  }
+}
+
+resource "aws_cloudfront_distribution" "this" {
+  origin {
+    domain_name              = local.s3_origin.domain_name
+    origin_access_control_id = local.s3_origin.origin_access_control_id
+    origin_id                = local.s3_origin.origin_id
+    origin_path              = var.origin_path
+    connection_attempts      = local.s3_origin.connection_attempts
+    connection_timeout       = local.s3_origin.connection_timeout
     origin_shield {
       enabled              = true
-      origin_shield_region = var.origin_shield_region
+      origin_shield_region = local.s3_origin.origin_shield_region
+    }
+    dynamic "custom_header" {
+      for_each = local.origin_custom_header_names
+      content {
+        name  = custom_header.value
+        value = var.origin_custom_header_value
+      }
+    }
+  }
+
+  dynamic "origin" {
+    for_each = local.api_origins
+    content {
+      domain_name         = origin.value.domain_name
+      origin_id           = origin.value.origin_id
+      connection_attempts = origin.value.connection_attempts
+      connection_timeout  = origin.value.connection_timeout
+      custom_origin_config {
+        http_port                = 80
+        https_port               = 443
+        origin_protocol_policy   = "https-only"
+        origin_ssl_protocols     = ["TLSv1.2"]
+        origin_read_timeout      = origin.value.origin_read_timeout
+        origin_keepalive_timeout = origin.value.origin_keepalive_timeout
+      }
+      dynamic "custom_header" {
+        for_each = local.origin_custom_header_names
+        content {
+          name  = custom_header.value
+          value = var.origin_custom_header_value
+        }
+      }
     }
   }
 
   enabled             = true
-  is_ipv6_enabled     = true
+  is_ipv6_enabled     = var.enable_ipv6
   comment             = "Static website distribution for ${var.domain_name}"
-  default_root_object = "index.html"
+  default_root_object = var.index_document
+
+  aliases    = local.certificate_in_zone ? [var.domain_name] : []
+  web_acl_id = local.web_acl_id
 
-  aliases = [var.domain_name]
-  web_acl_id = var.waf_web_acl_arn
+  # Routes header-tagged or weighted traffic to the staging copy (see continuous_deployment.tf)
+  continuous_deployment_policy_id = var.enable_cd ? aws_cloudfront_continuous_deployment_policy.this[0].id : null
 
   default_cache_behavior {
-    allowed_methods  = ["GET", "HEAD"]
-    cached_methods   = ["GET", "HEAD"]
-    target_origin_id = "s3-origin"
-    cache_policy_id           = data.aws_cloudfront_cache_policy.managed_caching_optimized.id
-    origin_request_policy_id  = data.aws_cloudfront_origin_request_policy.managed_cors_s3_origin.id
-    viewer_protocol_policy = "redirect-to-https"
-    min_ttl  = 0
-    default_ttl = 3600
-    max_ttl = 86400
-    compress = true
-    response_headers_policy_id = var.response_headers_policy_id
+    allowed_methods            = local.default_cache_behavior.allowed_methods
+    cached_methods             = local.default_cache_behavior.cached_methods
+    target_origin_id           = local.default_cache_behavior.target_origin_id
+    cache_policy_id            = local.default_cache_behavior.cache_policy_id
+    origin_request_policy_id   = local.default_cache_behavior.origin_request_policy_id
+    viewer_protocol_policy     = local.default_cache_behavior.viewer_protocol_policy
+    compress                   = true
+    response_headers_policy_id = local.response_headers_policy_id
+    realtime_log_config_arn    = local.default_cache_behavior.realtime_log_config_arn
+
+    dynamic "lambda_function_association" {
+      for_each = local.edge_headers_function_arns
+      content {
+        event_type   = "origin-response"
+        lambda_arn   = lambda_function_association.value
+        include_body = false
+      }
+    }
+  }
+
+  dynamic "ordered_cache_behavior" {
+    for_each = local.api_cache_behaviors
+    content {
+      path_pattern               = ordered_cache_behavior.value.path_pattern
+      allowed_methods            = ordered_cache_behavior.value.allowed_methods
+      cached_methods             = ordered_cache_behavior.value.cached_methods
+      target_origin_id           = ordered_cache_behavior.value.target_origin_id
+      cache_policy_id            = ordered_cache_behavior.value.cache_policy_id
+      origin_request_policy_id   = ordered_cache_behavior.value.origin_request_policy_id
+      viewer_protocol_policy     = ordered_cache_behavior.value.viewer_protocol_policy
+      compress                   = true
+      response_headers_policy_id = local.response_headers_policy_id
+
+      dynamic "lambda_function_association" {
+        for_each = local.edge_headers_function_arns
+        content {
+          event_type   = "origin-response"
+          lambda_arn   = lambda_function_association.value
+          include_body = false
+        }
+      }
+    }
   }
 
   # Enable HTTP/3 with fallback to HTTP/2/1.1
   http_version = "http2and3"
 
-  custom_error_response {
-    error_code         = 403
-    response_code      = 404
-    response_page_path = "/error.html"
-  }
-  custom_error_response {
-    error_code         = 404
-    response_code      = 404
-    response_page_path = "/error.html"
+  dynamic "custom_error_response" {
+    for_each = var.custom_error_responses
+    content {
+      error_code            = custom_error_response.value.error_code
+      response_code         = custom_error_response.value.response_code
+      response_page_path    = custom_error_response.value.response_page_path
+      error_caching_min_ttl = custom_error_response.value.error_caching_min_ttl
+    }
   }
 
   price_class = var.price_class
@@ -79,25 +302,39 @@ resource "aws_cloudfront_distribution" "this" {
   }
 
   viewer_certificate {
-    acm_certificate_arn      = aws_acm_certificate_validation.cert.certificate_arn
-    ssl_support_method       = "sni-only"
-    minimum_protocol_version = "TLSv1.2_2021"
+    acm_certificate_arn            = local.viewer_certificate.acm_certificate_arn
+    cloudfront_default_certificate = local.viewer_certificate.cloudfront_default_certificate
+    ssl_support_method             = local.viewer_certificate.ssl_support_method
+    minimum_protocol_version       = local.viewer_certificate.minimum_protocol_version
   }
 
   logging_config {
-    include_cookies = false
+    include_cookies = var.log_include_cookies
     bucket          = var.log_bucket_domain
-    prefix          = "cloudfront-logs"
+    prefix          = var.log_prefix
   }
 
   tags = var.tags
 }
 
 variable "certificate_domain_name" { type = string }
+variable "hosted_zone_id" {
+  type    = string
+  default = ""
+}
+variable "validate_certificate" {
+  description = "Create DNS validation records in hosted_zone_id and wait for issuance; passed separately because a zone created in the same apply has no ID at plan time"
+  type        = bool
+  default     = false
+}
+
+locals {
+  # DNS validation records are only created when the caller has a hosted zone for them
+  validate_certificate = var.validate_certificate
+  certificate_in_zone  = var.hosted_zone_id != ""
 
-data "aws_route53_zone" "this" {
-  name = var.certificate_domain_name
-  private_zone = false
+  # Unvalidated, the certificate must be issued out of band before the distribution can use it
+  certificate_arn = local.validate_certificate ? aws_acm_certificate_validation.cert[0].certificate_arn : aws_acm_certificate.cert.arn
 }
 
 resource "aws_acm_certificate" "cert" {
@@ -111,22 +348,23 @@ resource "aws_acm_certificate" "cert" {
 }
 
 resource "aws_route53_record" "cert_validation" {
-  for_each = {
+  for_each = local.validate_certificate ? {
     for dvo in aws_acm_certificate.cert.domain_validation_options : dvo.domain_name => {
       name   = dvo.resource_record_name
       record = dvo.resource_record_value
       type   = dvo.resource_record_type
     }
-  }
+  } : {}
   allow_overwrite = true
   name    = each.value.name
   records = [each.value.record]
   ttl     = 60
   type    = each.value.type
-  zone_id = data.aws_route53_zone.this.zone_id
+  zone_id = var.hosted_zone_id
 }
 
 resource "aws_acm_certificate_validation" "cert" {
+  count                   = local.validate_certificate ? 1 : 0
   provider                = aws.us_east_1
   certificate_arn         = aws_acm_certificate.cert.arn
   validation_record_fqdns = [for record in aws_route53_record.cert_validation : record.fqdn]
@@ -134,6 +372,24 @@ resource "aws_acm_certificate_validation" "cert" {
 
 output "distribution_domain_name" { value = aws_cloudfront_distribution.this.domain_name }
 output "distribution_hosted_zone_id" { value = aws_cloudfront_distribution.this.hosted_zone_id }
+output "distribution_id" { value = aws_cloudfront_distribution.this.id }
+output "custom_error_response_count" { value = length(aws_cloudfront_distribution.this.custom_error_response) }
 output "distribution_arn" { value = aws_cloudfront_distribution.this.arn }
-output "certificate_arn" { value = aws_acm_certificate_validation.cert.certificate_arn }
+output "api_origin_id" { value = local.api_origin_enabled ? "api-origin" : null }
+output "allowed_methods" { value = var.allowed_methods }
+output "origin_path" { value = var.origin_path }
+output "cache_policy_id" { value = data.aws_cloudfront_cache_policy.managed_caching_optimized.id }
+output "origin_request_policy_id" { value = data.aws_cloudfront_origin_request_policy.managed_cors_s3_origin.id }
+output "api_cache_policy_id" { value = local.api_origin_enabled ? data.aws_cloudfront_cache_policy.managed_caching_disabled.id : null }
+output "api_origin_request_policy_id" { value = local.api_origin_enabled ? data.aws_cloudfront_origin_request_policy.managed_all_viewer_except_host.id : null }
+output "origin_timeouts" {
+  value = {
+    connection_attempts = var.origin_connection_attempts
+    connection_timeout  = var.origin_connection_timeout
+    read_timeout        = var.origin_read_timeout
+    keepalive_timeout   = var.origin_keepalive_timeout
+  }
+}
+output "certificate_arn" { value = local.certificate_arn }
+output "certificate_status" { value = aws_acm_certificate.cert.status }
 
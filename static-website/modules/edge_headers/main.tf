@@ -0,0 +1,53 @@
+variable "name" { type = string }
+variable "headers" { type = map(string) }
+variable "tags" { type = map(string) }
+variable "retain_on_destroy" {
+  type    = bool
+  default = true
+}
+
+data "archive_file" "this" {
+  type        = "zip"
+  output_path = "${path.module}/build/${var.name}.zip"
+  source {
+    content  = templatefile("${path.module}/index.js.tftpl", { headers = jsonencode(var.headers) })
+    filename = "index.js"
+  }
+}
+
+resource "aws_iam_role" "this" {
+  name = "${var.name}-role"
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Action    = "sts:AssumeRole"
+      Effect    = "Allow"
+      Principal = { Service = ["lambda.amazonaws.com", "edgelambda.amazonaws.com"] }
+    }]
+  })
+  tags = var.tags
+}
+
+resource "aws_iam_role_policy_attachment" "logs" {
+  role       = aws_iam_role.this.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+# Lambda@Edge must live in us-east-1 and be referenced by a published version.
+# Replicas linger for hours after the distribution lets go of them, so by
+# default destroy leaves the function behind instead of failing.
+resource "aws_lambda_function" "this" {
+  function_name    = var.name
+  role             = aws_iam_role.this.arn
+  runtime          = "nodejs20.x"
+  handler          = "index.handler"
+  filename         = data.archive_file.this.output_path
+  source_code_hash = data.archive_file.this.output_base64sha256
+  memory_size      = 128
+  timeout          = 5
+  publish          = true
+  skip_destroy     = var.retain_on_destroy
+  tags             = var.tags
+}
+
+output "qualified_arn" { value = aws_lambda_function.this.qualified_arn }
@@ -2,29 +2,76 @@ variable "name" {
   type = string
 }
 
+# An empty origin list leaves CORS headers off entirely
+variable "cors_allowed_origins" {
+  type    = list(string)
+  default = []
+}
+
+variable "cors_allowed_methods" {
+  type    = list(string)
+  default = ["GET", "HEAD", "OPTIONS"]
+}
+
+variable "cors_allowed_headers" {
+  type    = list(string)
+  default = ["*"]
+}
+
+variable "cors_max_age_sec" {
+  type    = number
+  default = 600
+}
+
+locals {
+  frame_option            = "DENY"
+  referrer_policy         = "strict-origin-when-cross-origin"
+  hsts_max_age_sec        = 31536000
+  content_security_policy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data: https:; font-src 'self' data:; connect-src 'self'; media-src 'self'; object-src 'none'; frame-ancestors 'none'"
+}
+
 resource "aws_cloudfront_response_headers_policy" "this" {
   name    = var.name
   comment = "Security headers for static website"
 
+  dynamic "cors_config" {
+    for_each = length(var.cors_allowed_origins) > 0 ? [1] : []
+    content {
+      access_control_allow_credentials = false
+      access_control_max_age_sec       = var.cors_max_age_sec
+      origin_override                  = true
+
+      access_control_allow_origins {
+        items = var.cors_allowed_origins
+      }
+      access_control_allow_methods {
+        items = var.cors_allowed_methods
+      }
+      access_control_allow_headers {
+        items = var.cors_allowed_headers
+      }
+    }
+  }
+
   security_headers_config {
     content_type_options {
       override = true
     }
     frame_options {
-      frame_option = "DENY"
+      frame_option = local.frame_option
       override     = true
     }
     referrer_policy {
-      referrer_policy = "strict-origin-when-cross-origin"
+      referrer_policy = local.referrer_policy
       override        = true
     }
     strict_transport_security {
-      access_control_max_age_sec = 31536000
+      access_control_max_age_sec = local.hsts_max_age_sec
       include_subdomains         = true
       override                   = true
     }
     content_security_policy {
-      content_security_policy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data: https:; font-src 'self' data:; connect-src 'self'; media-src 'self'; object-src 'none'; frame-ancestors 'none'"
+      content_security_policy = local.content_security_policy
       override                 = true
     }
   }
@@ -34,3 +81,22 @@ output "id" {
   value = aws_cloudfront_response_headers_policy.this.id
 }
 
+output "cors" {
+  value = length(var.cors_allowed_origins) == 0 ? null : {
+    allowed_origins = var.cors_allowed_origins
+    allowed_methods = var.cors_allowed_methods
+    allowed_headers = var.cors_allowed_headers
+    max_age_sec     = var.cors_max_age_sec
+  }
+}
+
+# The headers exactly as the policy sends them, for other mechanisms to reproduce
+output "headers" {
+  value = {
+    "X-Content-Type-Options"    = "nosniff"
+    "X-Frame-Options"           = local.frame_option
+    "Referrer-Policy"           = local.referrer_policy
+    "Strict-Transport-Security" = "max-age=${local.hsts_max_age_sec}; includeSubDomains"
+    "Content-Security-Policy"   = local.content_security_policy
+  }
+}
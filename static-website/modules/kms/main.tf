@@ -0,0 +1,50 @@
+variable "alias_name" { type = string }
+variable "distribution_arns" {
+  description = "CloudFront distributions allowed to decrypt objects through origin access control"
+  type        = list(string)
+}
+variable "deletion_window_in_days" {
+  type    = number
+  default = 30
+}
+variable "tags" { type = map(string) }
+
+data "aws_caller_identity" "current" {}
+
+# Customer-managed key with yearly automatic rotation. OAC reads objects as the
+# CloudFront service, so only the site's own distributions may decrypt with it.
+resource "aws_kms_key" "this" {
+  description             = "Customer-managed key for ${var.alias_name} objects"
+  enable_key_rotation     = true
+  deletion_window_in_days = var.deletion_window_in_days
+  tags                    = var.tags
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Sid       = "AccountAdministration"
+        Effect    = "Allow"
+        Principal = { AWS = "arn:aws:iam::${data.aws_caller_identity.current.account_id}:root" }
+        Action    = "kms:*"
+        Resource  = "*"
+      },
+      {
+        Sid       = "CloudFrontOriginAccess"
+        Effect    = "Allow"
+        Principal = { Service = "cloudfront.amazonaws.com" }
+        Action    = "kms:Decrypt"
+        Resource  = "*"
+        Condition = { StringEquals = { "AWS:SourceArn" = var.distribution_arns } }
+      }
+    ]
+  })
+}
+
+resource "aws_kms_alias" "this" {
+  name          = "alias/${var.alias_name}"
+  target_key_id = aws_kms_key.this.key_id
+}
+
+output "key_arn" { value = aws_kms_key.this.arn }
+output "alias_name" { value = aws_kms_alias.this.name }
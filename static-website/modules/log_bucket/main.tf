@@ -1,6 +1,14 @@
 variable "name_prefix" { type = string }
 variable "lifecycle_days" { type = number }
 variable "tags" { type = map(string) }
+variable "acls_required" { type = bool }
+variable "log_delivery_source_arns" {
+  description = "Resources allowed to deliver logs straight to the bucket through the log delivery service (e.g. a WAF web ACL)"
+  type        = list(string)
+  default     = []
+}
+
+data "aws_caller_identity" "current" {}
 
 resource "random_string" "suffix" {
   length  = 8
@@ -13,6 +21,16 @@ resource "aws_s3_bucket" "this" {
   tags   = var.tags
 }
 
+# CloudFront standard logging writes through bucket ACLs, so callers delivering
+# those logs opt out of BucketOwnerEnforced; BucketOwnerPreferred still makes
+# the bucket owner own the delivered logs. Everything else disables ACLs.
+resource "aws_s3_bucket_ownership_controls" "this" {
+  bucket = aws_s3_bucket.this.id
+  rule {
+    object_ownership = var.acls_required ? "BucketOwnerPreferred" : "BucketOwnerEnforced"
+  }
+}
+
 resource "aws_s3_bucket_lifecycle_configuration" "this" {
   bucket = aws_s3_bucket.this.id
   rule {
@@ -53,6 +71,56 @@ data "aws_iam_policy_document" "bucket_policy" {
       variable = "s3:x-amz-server-side-encryption"
       values   = ["AES256", "aws:kms"]
     }
+    # Log delivery relies on default encryption rather than sending the header
+    dynamic "condition" {
+      for_each = length(var.log_delivery_source_arns) > 0 ? [1] : []
+      content {
+        test     = "StringNotEqualsIfExists"
+        variable = "aws:PrincipalServiceName"
+        values   = ["delivery.logs.amazonaws.com"]
+      }
+    }
+  }
+  dynamic "statement" {
+    for_each = length(var.log_delivery_source_arns) > 0 ? [1] : []
+    content {
+      sid       = "AWSLogDeliveryWrite"
+      effect    = "Allow"
+      actions   = ["s3:PutObject"]
+      resources = ["${aws_s3_bucket.this.arn}/AWSLogs/${data.aws_caller_identity.current.account_id}/*"]
+      principals {
+        type        = "Service"
+        identifiers = ["delivery.logs.amazonaws.com"]
+      }
+      condition {
+        test     = "StringEquals"
+        variable = "aws:SourceAccount"
+        values   = [data.aws_caller_identity.current.account_id]
+      }
+      condition {
+        test     = "ArnLike"
+        variable = "aws:SourceArn"
+        values   = var.log_delivery_source_arns
+      }
+    }
+  }
+  dynamic "statement" {
+    for_each = length(var.log_delivery_source_arns) > 0 ? [1] : []
+    content {
+      sid       = "AWSLogDeliveryAclCheck"
+      effect    = "Allow"
+      actions   = ["s3:GetBucketAcl"]
+      resources = [aws_s3_bucket.this.arn]
+      principals {
+        type        = "Service"
+        identifiers = ["delivery.logs.amazonaws.com"]
+      }
+      condition {
+        test     = "StringEquals"
+        variable = "aws:SourceAccount"
+        values   = [data.aws_caller_identity.current.account_id]
+      }
+    }
   }
   statement {
     sid     = "DenyInsecureTransport"
@@ -84,7 +152,11 @@ resource "aws_s3_bucket_public_access_block" "this" {
   restrict_public_buckets = true
 }
 
-output "bucket_domain_name" { value = aws_s3_bucket.this.bucket_domain_name }
+output "bucket_domain_name" {
+  value = aws_s3_bucket.this.bucket_domain_name
+  # CloudFront rejects logging to a bucket that doesn't accept ACLs yet
+  depends_on = [aws_s3_bucket_ownership_controls.this]
+}
 output "bucket_name" { value = aws_s3_bucket.this.bucket }
 output "bucket_arn" { value = aws_s3_bucket.this.arn }
 
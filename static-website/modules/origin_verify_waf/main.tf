@@ -0,0 +1,59 @@
+variable "name" { type = string }
+variable "header_name" { type = string }
+variable "header_value" {
+  type      = string
+  sensitive = true
+}
+variable "tags" { type = map(string) }
+
+# Regional web ACL for a non-S3 origin (e.g. an ALB). Only requests carrying
+# the secret header CloudFront adds to origin requests are allowed through.
+resource "aws_wafv2_web_acl" "this" {
+  name        = var.name
+  description = "Blocks requests that bypass CloudFront"
+  scope       = "REGIONAL"
+
+  default_action {
+    block {}
+  }
+
+  rule {
+    name     = "RequireOriginVerifyHeader"
+    priority = 1
+    action {
+      allow {}
+    }
+    statement {
+      byte_match_statement {
+        search_string         = var.header_value
+        positional_constraint = "EXACTLY"
+        field_to_match {
+          single_header {
+            name = lower(var.header_name)
+          }
+        }
+        text_transformation {
+          priority = 0
+          type     = "NONE"
+        }
+      }
+    }
+    visibility_config {
+      cloudwatch_metrics_enabled = true
+      metric_name                = "RequireOriginVerifyHeader"
+      sampled_requests_enabled   = true
+    }
+  }
+
+  visibility_config {
+    cloudwatch_metrics_enabled = true
+    metric_name                = "OriginVerifyWAF"
+    sampled_requests_enabled   = true
+  }
+
+  tags = var.tags
+}
+
+output "arn" {
+  value = aws_wafv2_web_acl.this.arn
+}
@@ -0,0 +1,62 @@
+variable "name" { type = string }
+variable "sampling_rate" {
+  type    = number
+  default = 100
+}
+variable "tags" { type = map(string) }
+
+# Real-time log fields, in the order they appear in each Kinesis record
+locals {
+  fields = ["timestamp", "c-ip", "cs-method", "cs-uri-stem", "sc-status", "x-edge-location", "x-edge-result-type", "time-taken"]
+}
+
+resource "aws_kinesis_stream" "this" {
+  name             = var.name
+  shard_count      = 1
+  retention_period = 24
+  encryption_type  = "KMS"
+  kms_key_id       = "alias/aws/kinesis"
+  tags             = var.tags
+}
+
+resource "aws_iam_role" "this" {
+  name = "${var.name}-role"
+  assume_role_policy = jsonencode({
+    Version   = "2012-10-17"
+    Statement = [{ Action = "sts:AssumeRole", Effect = "Allow", Principal = { Service = "cloudfront.amazonaws.com" } }]
+  })
+  tags = var.tags
+}
+
+resource "aws_iam_role_policy" "this" {
+  name = "${var.name}-policy"
+  role = aws_iam_role.this.id
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      { Effect = "Allow", Action = ["kinesis:DescribeStreamSummary", "kinesis:DescribeStream", "kinesis:PutRecord", "kinesis:PutRecords"], Resource = aws_kinesis_stream.this.arn },
+      # The stream is encrypted with the AWS managed key
+      { Effect = "Allow", Action = ["kms:GenerateDataKey"], Resource = "*" }
+    ]
+  })
+}
+
+resource "aws_cloudfront_realtime_log_config" "this" {
+  name          = var.name
+  sampling_rate = var.sampling_rate
+  fields        = local.fields
+
+  endpoint {
+    stream_type = "Kinesis"
+    kinesis_stream_config {
+      role_arn   = aws_iam_role.this.arn
+      stream_arn = aws_kinesis_stream.this.arn
+    }
+  }
+
+  depends_on = [aws_iam_role_policy.this]
+}
+
+output "arn" { value = aws_cloudfront_realtime_log_config.this.arn }
+output "stream_name" { value = aws_kinesis_stream.this.name }
+output "fields" { value = local.fields }
@@ -0,0 +1,57 @@
+variable "zone_id" {
+  type    = string
+  default = ""
+}
+variable "create_zone" {
+  description = "Create a public hosted zone for domain_name instead of using zone_id"
+  type        = bool
+  default     = false
+}
+variable "domain_name" { type = string }
+variable "distribution_domain_name" { type = string }
+variable "distribution_hosted_zone_id" { type = string }
+variable "enable_ipv6" {
+  type    = bool
+  default = false
+}
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+resource "aws_route53_zone" "this" {
+  count = var.create_zone ? 1 : 0
+  name  = var.domain_name
+  tags  = var.tags
+}
+
+locals {
+  zone_id = var.create_zone ? aws_route53_zone.this[0].zone_id : var.zone_id
+}
+
+resource "aws_route53_record" "alias" {
+  zone_id = local.zone_id
+  name    = var.domain_name
+  type    = "A"
+  alias {
+    name                   = var.distribution_domain_name
+    zone_id                = var.distribution_hosted_zone_id
+    evaluate_target_health = false
+  }
+}
+
+resource "aws_route53_record" "alias_ipv6" {
+  count   = var.enable_ipv6 ? 1 : 0
+  zone_id = local.zone_id
+  name    = var.domain_name
+  type    = "AAAA"
+  alias {
+    name                   = var.distribution_domain_name
+    zone_id                = var.distribution_hosted_zone_id
+    evaluate_target_health = false
+  }
+}
+
+output "fqdn" { value = aws_route53_record.alias.fqdn }
+output "zone_id" { value = local.zone_id }
+output "name_servers" { value = var.create_zone ? aws_route53_zone.this[0].name_servers : null }
@@ -0,0 +1,34 @@
+variable "name" { type = string }
+variable "resource_arn" { type = string }
+variable "health_check_fqdn" { type = string }
+variable "health_check_path" { type = string }
+variable "tags" { type = map(string) }
+
+# Needs an active Shield Advanced subscription on the account; subscribing is a
+# one-year commitment, so it's left to the account owner rather than Terraform
+resource "aws_shield_protection" "this" {
+  name         = var.name
+  resource_arn = var.resource_arn
+  tags         = var.tags
+}
+
+# Health-based detection: Shield Advanced treats traffic spikes while this
+# check is failing as an attack, so it detects and mitigates sooner
+resource "aws_route53_health_check" "this" {
+  fqdn              = var.health_check_fqdn
+  port              = 443
+  type              = "HTTPS"
+  resource_path     = var.health_check_path
+  failure_threshold = 3
+  request_interval  = 30
+  tags              = merge(var.tags, { Name = "${var.name}-health" })
+}
+
+resource "aws_shield_protection_health_check_association" "this" {
+  shield_protection_id = aws_shield_protection.this.id
+  health_check_arn     = aws_route53_health_check.this.arn
+}
+
+output "protection_id" { value = aws_shield_protection.this.id }
+output "protection_arn" { value = aws_shield_protection.this.arn }
+output "health_check_id" { value = aws_route53_health_check.this.id }
@@ -1,6 +1,86 @@
 variable "name" { type = string }
 variable "rate_limit" { type = number }
 variable "tags" { type = map(string) }
+variable "enable_body_size_rule" {
+  type    = bool
+  default = false
+}
+variable "max_body_size" {
+  type    = number
+  default = 8192
+}
+variable "blocked_countries" {
+  type    = list(string)
+  default = []
+}
+variable "allowed_cidrs" {
+  type    = list(string)
+  default = []
+}
+variable "blocked_cidrs" {
+  type    = list(string)
+  default = []
+}
+variable "geo_forwarded_ip_header" {
+  type    = string
+  default = ""
+}
+# Status and body returned by this ACL's own block rules; null keeps WAF's plain 403
+variable "block_response" {
+  type = object({
+    status_code  = number
+    content_type = string
+    body         = string
+  })
+  default = null
+}
+
+locals {
+  rule_names = concat(
+    length(var.allowed_cidrs) > 0 ? ["AllowlistRule"] : [],
+    length(var.blocked_cidrs) > 0 ? ["BlocklistRule"] : [],
+    ["RateLimitRule", "AWSCommonRuleSet", "AWSKnownBadInputsRuleSet", "AWSSQLiRuleSet", "AWSBotControlRuleSet", "AWSAnonymousIpList"],
+    var.enable_body_size_rule ? ["BodySizeRule"] : [],
+    length(var.blocked_countries) > 0 ? ["GeoBlockRule"] : []
+  )
+  block_responses    = var.block_response == null ? [] : [var.block_response]
+  block_response_key = "blocked"
+
+  # WAF IP sets hold a single address family, so each list splits into an
+  # IPV4 and an IPV6 set that the rule ORs together
+  allowed_cidrs = {
+    IPV4 = [for c in var.allowed_cidrs : c if !strcontains(c, ":")]
+    IPV6 = [for c in var.allowed_cidrs : c if strcontains(c, ":")]
+  }
+  blocked_cidrs = {
+    IPV4 = [for c in var.blocked_cidrs : c if !strcontains(c, ":")]
+    IPV6 = [for c in var.blocked_cidrs : c if strcontains(c, ":")]
+  }
+  ip_set_suffix = { IPV4 = "", IPV6 = "-ipv6" }
+
+  allowed_ip_set_arns = [for version, set in aws_wafv2_ip_set.allowed : set.arn]
+  blocked_ip_set_arns = [for version, set in aws_wafv2_ip_set.blocked : set.arn]
+}
+
+resource "aws_wafv2_ip_set" "allowed" {
+  for_each           = { for version, cidrs in local.allowed_cidrs : version => cidrs if length(cidrs) > 0 }
+  name               = "${var.name}-allowed${local.ip_set_suffix[each.key]}"
+  description        = "Addresses always allowed, ahead of every other rule"
+  scope              = "CLOUDFRONT"
+  ip_address_version = each.key
+  addresses          = each.value
+  tags               = var.tags
+}
+
+resource "aws_wafv2_ip_set" "blocked" {
+  for_each           = { for version, cidrs in local.blocked_cidrs : version => cidrs if length(cidrs) > 0 }
+  name               = "${var.name}-blocked${local.ip_set_suffix[each.key]}"
+  description        = "Addresses always blocked, ahead of rate limiting and the managed rule groups"
+  scope              = "CLOUDFRONT"
+  ip_address_version = each.key
+  addresses          = each.value
+  tags               = var.tags
+}
 
 resource "aws_wafv2_web_acl" "this" {
   name        = var.name
@@ -11,11 +91,117 @@ resource "aws_wafv2_web_acl" "this" {
     allow {}
   }
 
+  # Managed rule groups keep their own block responses; only the rules defined
+  # here reference this body
+  dynamic "custom_response_body" {
+    for_each = local.block_responses
+    content {
+      key          = local.block_response_key
+      content      = custom_response_body.value.body
+      content_type = custom_response_body.value.content_type
+    }
+  }
+
+  # The allowlist is evaluated first, so listed addresses skip every rule below,
+  # the blocklist included
+  dynamic "rule" {
+    for_each = length(local.allowed_ip_set_arns) > 0 ? [local.allowed_ip_set_arns] : []
+    content {
+      name     = "AllowlistRule"
+      priority = 1
+      action {
+        allow {}
+      }
+      # An or_statement needs at least two statements, so a single
+      # address family references its set directly
+      statement {
+        dynamic "ip_set_reference_statement" {
+          for_each = length(rule.value) == 1 ? rule.value : []
+          content {
+            arn = ip_set_reference_statement.value
+          }
+        }
+        dynamic "or_statement" {
+          for_each = length(rule.value) > 1 ? [rule.value] : []
+          content {
+            dynamic "statement" {
+              for_each = or_statement.value
+              content {
+                ip_set_reference_statement {
+                  arn = statement.value
+                }
+              }
+            }
+          }
+        }
+      }
+      visibility_config {
+        cloudwatch_metrics_enabled = true
+        metric_name                = "AllowlistRule"
+        sampled_requests_enabled   = true
+      }
+    }
+  }
+
+  dynamic "rule" {
+    for_each = length(local.blocked_ip_set_arns) > 0 ? [local.blocked_ip_set_arns] : []
+    content {
+      name     = "BlocklistRule"
+      priority = 2
+      action {
+        block {
+          dynamic "custom_response" {
+            for_each = local.block_responses
+            content {
+              response_code            = custom_response.value.status_code
+              custom_response_body_key = local.block_response_key
+            }
+          }
+        }
+      }
+      statement {
+        dynamic "ip_set_reference_statement" {
+          for_each = length(rule.value) == 1 ? rule.value : []
+          content {
+            arn = ip_set_reference_statement.value
+          }
+        }
+        dynamic "or_statement" {
+          for_each = length(rule.value) > 1 ? [rule.value] : []
+          content {
+            dynamic "statement" {
+              for_each = or_statement.value
+              content {
+                ip_set_reference_statement {
+                  arn = statement.value
+                }
+              }
+            }
+          }
+        }
+      }
+      visibility_config {
+        cloudwatch_metrics_enabled = true
+        metric_name                = "BlocklistRule"
+        sampled_requests_enabled   = true
+      }
+    }
+  }
+
   rule {
     name     = "RateLimitRule"
-    priority = 1
-    override_action {
-      none {}
+    priority = 3
+    # Rate-based rules take an action; override_action is only for rule groups
+    action {
+      block {
+        dynamic "custom_response" {
+          for_each = local.block_responses
+          content {
+            response_code            = custom_response.value.status_code
+            custom_response_body_key = local.block_response_key
+          }
+        }
+      }
     }
     statement {
       rate_based_statement {
@@ -32,7 +218,7 @@ resource "aws_wafv2_web_acl" "this" {
 
   rule {
     name     = "AWSCommonRuleSet"
-    priority = 2
+    priority = 4
     override_action {
       none {}
     }
@@ -51,7 +237,7 @@ resource "aws_wafv2_web_acl" "this" {
 
   rule {
     name     = "AWSKnownBadInputsRuleSet"
-    priority = 3
+    priority = 5
     override_action {
       none {}
     }
@@ -70,7 +256,7 @@ resource "aws_wafv2_web_acl" "this" {
 
   rule {
     name     = "AWSSQLiRuleSet"
-    priority = 4
+    priority = 6
     override_action {
       none {}
     }
@@ -89,7 +275,7 @@ resource "aws_wafv2_web_acl" "this" {
 
   rule {
     name     = "AWSBotControlRuleSet"
-    priority = 5
+    priority = 7
     override_action {
       none {}
     }
@@ -108,7 +294,7 @@ resource "aws_wafv2_web_acl" "this" {
 
   rule {
     name     = "AWSAnonymousIpList"
-    priority = 6
+    priority = 8
     override_action {
       none {}
     }
@@ -125,6 +311,86 @@ resource "aws_wafv2_web_acl" "this" {
     }
   }
 
+  # Blocks request bodies larger than max_body_size; bodies beyond the
+  # CloudFront inspection limit are treated as oversized too.
+  dynamic "rule" {
+    for_each = var.enable_body_size_rule ? [1] : []
+    content {
+      name     = "BodySizeRule"
+      priority = 9
+      action {
+        block {
+          dynamic "custom_response" {
+            for_each = local.block_responses
+            content {
+              response_code            = custom_response.value.status_code
+              custom_response_body_key = local.block_response_key
+            }
+          }
+        }
+      }
+      statement {
+        size_constraint_statement {
+          comparison_operator = "GT"
+          size                = var.max_body_size
+          field_to_match {
+            body {
+              oversize_handling = "MATCH"
+            }
+          }
+          text_transformation {
+            priority = 0
+            type     = "NONE"
+          }
+        }
+      }
+      visibility_config {
+        cloudwatch_metrics_enabled = true
+        metric_name                = "BodySizeRule"
+        sampled_requests_enabled   = true
+      }
+    }
+  }
+
+  # Blocks viewers from the listed countries. With geo_forwarded_ip_header set
+  # the country is looked up from that header instead of the source IP, which
+  # lets tests simulate a viewer elsewhere; requests without it fall through.
+  dynamic "rule" {
+    for_each = length(var.blocked_countries) > 0 ? [1] : []
+    content {
+      name     = "GeoBlockRule"
+      priority = 10
+      action {
+        block {
+          dynamic "custom_response" {
+            for_each = local.block_responses
+            content {
+              response_code            = custom_response.value.status_code
+              custom_response_body_key = local.block_response_key
+            }
+          }
+        }
+      }
+      statement {
+        geo_match_statement {
+          country_codes = var.blocked_countries
+          dynamic "forwarded_ip_config" {
+            for_each = var.geo_forwarded_ip_header != "" ? [1] : []
+            content {
+              header_name       = var.geo_forwarded_ip_header
+              fallback_behavior = "NO_MATCH"
+            }
+          }
+        }
+      }
+      visibility_config {
+        cloudwatch_metrics_enabled = true
+        metric_name                = "GeoBlockRule"
+        sampled_requests_enabled   = true
+      }
+    }
+  }
+
   visibility_config {
     cloudwatch_metrics_enabled = true
     metric_name                = "StaticWebsiteWAF"
@@ -138,3 +404,30 @@ output "arn" {
   value = aws_wafv2_web_acl.this.arn
 }
 
+output "id" {
+  value = aws_wafv2_web_acl.this.id
+}
+
+output "name" {
+  value = aws_wafv2_web_acl.this.name
+}
+
+output "rule_names" {
+  value = local.rule_names
+}
+
+output "rule_count" {
+  value = length(aws_wafv2_web_acl.this.rule)
+}
+
+output "allowed_ip_set_arns" {
+  value = { for version, set in aws_wafv2_ip_set.allowed : version => set.arn }
+}
+
+output "blocked_ip_set_arns" {
+  value = { for version, set in aws_wafv2_ip_set.blocked : version => set.arn }
+}
+
+output "block_response" {
+  value = var.block_response
+}
@@ -1,5 +1,68 @@
 variable "bucket_name" { type = string }
 variable "tags" { type = map(string) }
+variable "enable_request_metrics" {
+  type    = bool
+  default = false
+}
+variable "request_metrics_prefix" {
+  type    = string
+  default = ""
+}
+variable "index_document" {
+  type    = string
+  default = "index.html"
+}
+variable "error_document" {
+  type    = string
+  default = "error.html"
+}
+variable "public_read" {
+  description = "Allow a public bucket policy, for serving straight from the S3 website endpoint"
+  type        = bool
+  default     = false
+}
+variable "kms_key_arn" {
+  description = "Customer-managed KMS key for object encryption; empty keeps SSE-S3"
+  type        = string
+  default     = ""
+}
+variable "lifecycle_rules" {
+  type = list(object({
+    id                                 = string
+    prefix                             = string
+    transition_days                    = number
+    transition_storage_class           = string
+    expiration_days                    = number
+    noncurrent_version_transition_days = number
+    noncurrent_version_expiration_days = number
+  }))
+  default = []
+}
+variable "cors_allowed_origins" {
+  description = "Origins S3 answers CORS preflights for; empty adds no CORS rules"
+  type        = list(string)
+  default     = []
+}
+variable "cors_allowed_methods" {
+  type    = list(string)
+  default = ["GET", "HEAD"]
+}
+variable "cors_allowed_headers" {
+  type    = list(string)
+  default = ["*"]
+}
+variable "cors_max_age_sec" {
+  type    = number
+  default = 600
+}
+
+locals {
+  # S3 CORS rules only take these methods; OPTIONS is implied and ALL means every one
+  s3_cors_methods = ["DELETE", "GET", "HEAD", "POST", "PUT"]
+  cors_allowed_methods = distinct(flatten([
+    for method in var.cors_allowed_methods : method == "ALL" ? local.s3_cors_methods : contains(local.s3_cors_methods, method) ? [method] : []
+  ]))
+}
 
 resource "aws_s3_bucket" "this" {
   bucket = var.bucket_name
@@ -9,9 +72,9 @@ resource "aws_s3_bucket" "this" {
 resource "aws_s3_bucket_public_access_block" "this" {
   bucket = aws_s3_bucket.this.id
   block_public_acls       = true
-  block_public_policy     = true
+  block_public_policy     = !var.public_read
   ignore_public_acls      = true
-  restrict_public_buckets = true
+  restrict_public_buckets = !var.public_read
 }
 
 resource "aws_s3_bucket_ownership_controls" "this" {
@@ -24,23 +87,103 @@ resource "aws_s3_bucket_versioning" "this" {
   versioning_configuration { status = "Enabled" }
 }
 
+resource "aws_s3_bucket_lifecycle_configuration" "this" {
+  count  = length(var.lifecycle_rules) > 0 ? 1 : 0
+  bucket = aws_s3_bucket.this.id
+
+  dynamic "rule" {
+    for_each = var.lifecycle_rules
+    content {
+      id     = rule.value.id
+      status = "Enabled"
+      filter { prefix = rule.value.prefix }
+
+      dynamic "transition" {
+        for_each = rule.value.transition_days == null ? [] : [rule.value.transition_days]
+        content {
+          days          = transition.value
+          storage_class = rule.value.transition_storage_class
+        }
+      }
+      dynamic "expiration" {
+        for_each = rule.value.expiration_days == null ? [] : [rule.value.expiration_days]
+        content {
+          days = expiration.value
+        }
+      }
+      dynamic "noncurrent_version_transition" {
+        for_each = rule.value.noncurrent_version_transition_days == null ? [] : [rule.value.noncurrent_version_transition_days]
+        content {
+          noncurrent_days = noncurrent_version_transition.value
+          storage_class   = rule.value.transition_storage_class
+        }
+      }
+      dynamic "noncurrent_version_expiration" {
+        for_each = rule.value.noncurrent_version_expiration_days == null ? [] : [rule.value.noncurrent_version_expiration_days]
+        content {
+          noncurrent_days = noncurrent_version_expiration.value
+        }
+      }
+    }
+  }
+
+  # Noncurrent version rules need versioning in place first
+  depends_on = [aws_s3_bucket_versioning.this]
+}
+
 resource "aws_s3_bucket_server_side_encryption_configuration" "this" {
   bucket = aws_s3_bucket.this.id
   rule {
     apply_server_side_encryption_by_default {
-      sse_algorithm = "AES256"
+      sse_algorithm     = var.kms_key_arn == "" ? "AES256" : "aws:kms"
+      kms_master_key_id = var.kms_key_arn == "" ? null : var.kms_key_arn
     }
+    # Bucket keys cut the KMS requests, and cost, of serving through CloudFront
+    bucket_key_enabled = var.kms_key_arn != ""
   }
 }
 
 resource "aws_s3_bucket_website_configuration" "this" {
   bucket = aws_s3_bucket.this.id
-  index_document { suffix = "index.html" }
-  error_document { key = "error.html" }
+  index_document { suffix = var.index_document }
+  error_document { key = var.error_document }
+}
+
+# Without CORS rules S3 rejects every preflight with a 403, whatever the
+# response headers policy would add on the way out
+resource "aws_s3_bucket_cors_configuration" "this" {
+  count  = length(var.cors_allowed_origins) > 0 && length(local.cors_allowed_methods) > 0 ? 1 : 0
+  bucket = aws_s3_bucket.this.id
+
+  cors_rule {
+    allowed_origins = var.cors_allowed_origins
+    allowed_methods = local.cors_allowed_methods
+    allowed_headers = var.cors_allowed_headers
+    max_age_seconds = var.cors_max_age_sec
+  }
+}
+
+# CloudWatch request metrics are billed per metric, so they are opt-in
+resource "aws_s3_bucket_metric" "requests" {
+  count  = var.enable_request_metrics ? 1 : 0
+  bucket = aws_s3_bucket.this.id
+  name   = "RequestMetrics"
+
+  dynamic "filter" {
+    for_each = var.request_metrics_prefix != "" ? [var.request_metrics_prefix] : []
+    content {
+      prefix = filter.value
+    }
+  }
 }
 
 output "id" { value = aws_s3_bucket.this.id }
 output "arn" { value = aws_s3_bucket.this.arn }
 output "bucket" { value = aws_s3_bucket.this.bucket }
 output "bucket_regional_domain_name" { value = aws_s3_bucket.this.bucket_regional_domain_name }
+output "sse_algorithm" { value = var.kms_key_arn == "" ? "AES256" : "aws:kms" }
+output "website_endpoint" { value = aws_s3_bucket_website_configuration.this.website_endpoint }
 
+output "lifecycle_rule_ids" { value = [for r in var.lifecycle_rules : r.id] }
+output "cors_allowed_methods" { value = length(aws_s3_bucket_cors_configuration.this) > 0 ? local.cors_allowed_methods : [] }
+output "request_metrics_id" { value = var.enable_request_metrics ? aws_s3_bucket_metric.requests[0].name : null }
@@ -1,30 +1,123 @@
-output "cloudfront_domain" { value = module.cloudfront.distribution_domain_name }
+output "cloudfront_domain" { value = one(module.cloudfront[*].distribution_domain_name) }
 output "s3_bucket_name" { value = module.website_bucket.bucket }
+output "s3_sse_algorithm" { value = module.website_bucket.sse_algorithm }
+output "kms_key_arn" {
+  value = local.kms_key_arn == "" ? null : local.kms_key_arn
+
+  precondition {
+    condition     = !(var.create_kms_key == true && var.kms_key_arn != "")
+    error_message = "Set create_kms_key or kms_key_arn, not both."
+  }
+}
+output "website_endpoint" { value = local.cloudfront_enabled ? "https://${module.cloudfront[0].distribution_domain_name}" : "http://${module.website_bucket.website_endpoint}" }
+output "website_documents" { value = { index = var.index_document, error = var.error_document } }
+output "serving_mode" {
+  value = var.serving_mode
+
+  # Everything below rides on the distribution, which s3_website mode doesn't create
+  precondition {
+    condition     = local.cloudfront_enabled || !(var.enable_cd || var.enable_realtime_logs || var.enable_origin_verify_header || var.edge_headers_mode == "lambda_edge" || var.api_origin_domain != "" || var.origin_path != "" || length(var.cors_allowed_origins) > 0 || var.enable_shield_advanced || var.create_zone || var.create_kms_key == true || var.kms_key_arn != "")
+    error_message = "serving_mode = \"s3_website\" can't use enable_cd, enable_realtime_logs, enable_origin_verify_header, edge_headers_mode = \"lambda_edge\", api_origin_domain, origin_path, cors_allowed_origins, enable_shield_advanced, create_zone, create_kms_key or kms_key_arn; they need CloudFront."
+  }
+}
 
 # CloudFront outputs
-output "cloudfront_distribution_id" { value = module.cloudfront.distribution_arn }
-output "cloudfront_distribution_arn" { value = module.cloudfront.distribution_arn }
+output "cloudfront_distribution_id" { value = one(module.cloudfront[*].distribution_id) }
+output "cloudfront_distribution_arn" { value = one(module.cloudfront[*].distribution_arn) }
 output "cloudfront_price_class" { value = var.price_class }
 output "origin_shield_enabled" { value = true }
 output "origin_shield_region" { value = var.us_east_1_region }
+output "cloudfront_origin_path" { value = one(module.cloudfront[*].origin_path) }
 output "compression_enabled" { value = true }
+output "cloudfront_ipv6_enabled" { value = var.enable_ipv6 }
+output "cloudfront_api_origin_id" { value = one(module.cloudfront[*].api_origin_id) }
+output "cloudfront_allowed_methods" { value = one(module.cloudfront[*].allowed_methods) }
+output "cloudfront_origin_timeouts" { value = one(module.cloudfront[*].origin_timeouts) }
+output "cloudfront_cache_policy_id" { value = one(module.cloudfront[*].cache_policy_id) }
+output "custom_error_response_count" { value = one(module.cloudfront[*].custom_error_response_count) }
+output "cloudfront_origin_request_policy_id" { value = one(module.cloudfront[*].origin_request_policy_id) }
+output "cloudfront_api_cache_policy_id" { value = one(module.cloudfront[*].api_cache_policy_id) }
+output "cloudfront_api_origin_request_policy_id" { value = one(module.cloudfront[*].api_origin_request_policy_id) }
+output "edge_headers_mode" { value = var.edge_headers_mode }
+output "edge_headers_function_arn" { value = one(module.edge_headers[*].qualified_arn) }
+output "cors_config" {
+  value = module.headers_policy.cors
+
+  # CORS rides on the response headers policy, which lambda_edge mode doesn't attach
+  precondition {
+    condition     = length(var.cors_allowed_origins) == 0 || (var.edge_headers_mode == "policy" && contains(var.cloudfront_allowed_methods, "OPTIONS"))
+    error_message = "cors_allowed_origins needs edge_headers_mode = \"policy\" and OPTIONS in cloudfront_allowed_methods so preflights reach the policy."
+  }
+}
+output "s3_cors_allowed_methods" { value = module.website_bucket.cors_allowed_methods }
+
+# Continuous deployment outputs
+output "cloudfront_staging_distribution_id" { value = one(module.cloudfront[*].staging_distribution_id) }
+output "cloudfront_staging_domain" { value = one(module.cloudfront[*].staging_distribution_domain_name) }
+output "cloudfront_cd_policy_id" { value = one(module.cloudfront[*].continuous_deployment_policy_id) }
+output "cloudfront_cd_header" { value = one(module.cloudfront[*].staging_header) }
 
 # WAF outputs
-output "waf_web_acl_arn" { value = module.waf.arn }
+output "waf_enabled" { value = local.waf_enabled }
+output "waf_web_acl_arn" { value = one(module.waf[*].arn) }
+output "waf_web_acl_id" { value = one(module.waf[*].id) }
+output "waf_web_acl_name" { value = one(module.waf[*].name) }
 output "waf_rate_limit" { value = var.rate_limit }
-output "waf_rule_count" { value = 6 }  # Based on the WAF configuration
+output "waf_rule_count" { value = local.waf_enabled ? module.waf[0].rule_count : 0 }
+output "waf_rule_names" { value = local.waf_enabled ? module.waf[0].rule_names : [] }
+output "waf_allowed_ip_set_arns" { value = one(module.waf[*].allowed_ip_set_arns) }
+output "waf_blocked_ip_set_arns" { value = one(module.waf[*].blocked_ip_set_arns) }
+output "waf_blocked_countries" { value = var.blocked_countries }
+output "waf_block_response" { value = one(module.waf[*].block_response) }
+output "waf_log_redacted_headers" { value = var.waf_log_redacted_headers }
+output "waf_log_destination" { value = var.waf_log_destination }
+output "waf_log_destination_arn" { value = local.waf_enabled ? local.waf_log_destination_arn : null }
+output "waf_log_group_name" { value = one(aws_cloudwatch_log_group.waf_logs[*].name) }
+
+# Shield Advanced outputs
+output "shield_advanced_enabled" { value = length(module.shield_advanced) > 0 }
+output "shield_protection_id" { value = one(module.shield_advanced[*].protection_id) }
+output "shield_health_check_id" { value = one(module.shield_advanced[*].health_check_id) }
+
+# Origin verification outputs
+output "origin_verify_header_name" { value = var.enable_origin_verify_header ? var.origin_verify_header_name : null }
+output "origin_verify_web_acl_arn" { value = var.enable_origin_verify_header ? module.origin_verify_waf[0].arn : null }
 
 # Certificate outputs
-output "certificate_arn" { value = module.cloudfront.certificate_arn }
-output "certificate_validation_method" { value = "DNS" }
+output "certificate_arn" { value = one(module.cloudfront[*].certificate_arn) }
+output "certificate_status" { value = one(module.cloudfront[*].certificate_status) }
+
+# Route53 outputs
+output "route53_record_fqdn" { value = one(module.route53[*].fqdn) }
+output "route53_name_servers" { value = one(module.route53[*].name_servers) }
+output "route53_zone_id" {
+  value = one(module.route53[*].zone_id)
+
+  precondition {
+    condition     = !(var.create_zone && var.hosted_zone_id != "")
+    error_message = "Set hosted_zone_id to use an existing zone or create_zone to make one, not both."
+  }
+}
 
 # S3 bucket outputs
 output "s3_bucket_arn" { value = module.website_bucket.arn }
 output "s3_bucket_regional_domain" { value = module.website_bucket.bucket_regional_domain_name }
+output "s3_request_metrics_id" { value = module.website_bucket.request_metrics_id }
+output "s3_lifecycle_rule_ids" { value = module.website_bucket.lifecycle_rule_ids }
+
+# Real-time log outputs
+output "realtime_log_config_arn" { value = var.enable_realtime_logs ? module.realtime_logs[0].arn : null }
+output "realtime_log_stream_name" { value = var.enable_realtime_logs ? module.realtime_logs[0].stream_name : null }
 
 # Log retention outputs
-output "cloudfront_log_retention_days" { value = var.log_lifecycle_days }
-output "waf_log_retention_days" { value = var.log_lifecycle_days }
+output "cloudfront_log_bucket_name" { value = module.cloudfront_logs.bucket_name }
+output "waf_log_bucket_name" { value = one(module.waf_logs[*].bucket_name) }
+output "cloudfront_log_retention_days" { value = local.log_lifecycle_days }
+output "cloudfront_log_prefix" { value = var.cloudfront_log_prefix }
+output "cloudfront_log_include_cookies" { value = var.cloudfront_log_include_cookies }
+output "waf_log_retention_days" { value = !local.waf_enabled ? null : var.waf_log_destination == "cloudwatch" ? aws_cloudwatch_log_group.waf_logs[0].retention_in_days : local.log_lifecycle_days }
+
+output "profile" { value = var.profile }
 
 # CloudTrail outputs
-output "cloudtrail_enabled" { value = true }
\ No newline at end of file
+output "cloudtrail_enabled" { value = true }
@@ -0,0 +1,23 @@
+# hardened encrypts the site's objects with a customer-managed key, blocks
+# oversized request bodies at the WAF and keeps access logs for two years.
+# Any of the three set explicitly overrides the profile.
+locals {
+  profiles = {
+    minimal = {
+      create_kms_key        = false
+      enable_body_size_rule = false
+      log_lifecycle_days    = 365
+    }
+    hardened = {
+      create_kms_key        = true
+      enable_body_size_rule = true
+      log_lifecycle_days    = 731
+    }
+  }
+  profile = local.profiles[var.profile]
+
+  # An existing key passed as kms_key_arn takes the place of a created one
+  create_kms_key        = var.kms_key_arn == "" && coalesce(var.create_kms_key, local.profile.create_kms_key)
+  enable_body_size_rule = coalesce(var.enable_body_size_rule, local.profile.enable_body_size_rule)
+  log_lifecycle_days    = coalesce(var.log_lifecycle_days, local.profile.log_lifecycle_days)
+}
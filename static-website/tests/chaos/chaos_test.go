@@ -3,7 +3,6 @@ package chaos
 import (
 	"fmt"
 	"net/http"
-	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +14,8 @@ import (
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
 )
 
 func TestChaosCloudFrontFailure(t *testing.T) {
@@ -27,9 +28,11 @@ func TestChaosCloudFrontFailure(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
+	testutil.RequireDistributionServing(t, terraformOptions)
+
 	// Get CloudFront distribution details
 	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
 	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
@@ -61,7 +64,7 @@ func TestChaosS3OriginFailure(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Get S3 bucket details
@@ -115,11 +118,15 @@ func TestChaosWAFFailure(t *testing.T) {
 	terraformOptions := &terraform.Options{
 		TerraformDir: "../../",
 		Vars: map[string]interface{}{
-			"domain_name": "chaos-test.example.com",
+			"domain_name":   "chaos-test.example.com",
+			"allowed_cidrs": []string{"192.0.2.0/24"},
+			"blocked_cidrs": []string{"198.51.100.0/24", "2001:db8::/32"},
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	testutil.SkipIfWAFDisabled(t, terraformOptions)
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Get WAF Web ACL details
@@ -138,7 +145,8 @@ func TestChaosWAFFailure(t *testing.T) {
 
 	// Get current WAF configuration
 	getResult, err := wafSvc.GetWebACL(&wafv2.GetWebACLInput{
-		Id:    aws.String(extractWAFIDFromArn(wafACLArn)),
+		Id:    aws.String(terraform.Output(t, terraformOptions, "waf_web_acl_id")),
+		Name:  aws.String(terraform.Output(t, terraformOptions, "waf_web_acl_name")),
 		Scope: aws.String("CLOUDFRONT"),
 	})
 	require.NoError(t, err, "Should be able to get WAF configuration")
@@ -146,28 +154,14 @@ func TestChaosWAFFailure(t *testing.T) {
 	rules := getResult.WebACL.Rules
 	assert.Greater(t, len(rules), 0, "WAF should have rules configured")
 
-	// Check for essential rule groups
-	hasCommonRules := false
-	hasSQLiRules := false
-	hasXSSRules := false
-
-	for _, rule := range rules {
-		if rule.Statement.ManagedRuleGroupStatement != nil {
-			ruleName := *rule.Statement.ManagedRuleGroupStatement.Name
-			switch {
-			case strings.Contains(ruleName, "AWSManagedRulesCommonRuleSet"):
-				hasCommonRules = true
-			case strings.Contains(ruleName, "AWSManagedRulesSQLiRuleSet"):
-				hasSQLiRules = true
-			case strings.Contains(ruleName, "AWSManagedRulesKnownBadInputsRuleSet"):
-				hasXSSRules = true
-			}
-		}
-	}
-
-	assert.True(t, hasCommonRules, "WAF should include common rules for chaos testing")
-	assert.True(t, hasSQLiRules, "WAF should include SQL injection protection")
-	assert.True(t, hasXSSRules, "WAF should include XSS protection")
+	// The exact managed groups, none weakened to count or allow
+	testutil.AssertManagedRuleGroups(t, getResult.WebACL, []string{
+		"AWS/AWSManagedRulesAnonymousIpList",
+		"AWS/AWSManagedRulesBotControlRuleSet",
+		"AWS/AWSManagedRulesCommonRuleSet",
+		"AWS/AWSManagedRulesKnownBadInputsRuleSet",
+		"AWS/AWSManagedRulesSQLiRuleSet",
+	}, nil)
 
 	// Check for rate limiting
 	hasRateLimit := false
@@ -179,6 +173,13 @@ func TestChaosWAFFailure(t *testing.T) {
 	}
 	assert.True(t, hasRateLimit, "WAF should include rate limiting for chaos testing")
 
+	// The IP set rules evaluate before everything else, allowlist first
+	for _, problem := range ipSetRuleProblems(getResult.WebACL,
+		mapValues(terraform.OutputMap(t, terraformOptions, "waf_allowed_ip_set_arns")),
+		mapValues(terraform.OutputMap(t, terraformOptions, "waf_blocked_ip_set_arns"))) {
+		assert.Fail(t, "WAF IP set rules misconfigured", problem)
+	}
+
 	// Verify WAF ACL is properly configured
 	assert.NotEmpty(t, wafACLArn, "WAF ACL should be created and configured")
 	assert.Contains(t, wafACLArn, "chaos-test", "WAF ACL should contain test domain identifier")
@@ -196,7 +197,7 @@ func TestChaosCertificateFailure(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Get certificate details
@@ -210,6 +211,8 @@ func TestChaosCertificateFailure(t *testing.T) {
 
 	assert.NotEmpty(t, certificateArn)
 
+	testutil.RequireDistributionServing(t, terraformOptions)
+
 	// Test HTTPS connectivity (simplified)
 	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
 
@@ -232,7 +235,7 @@ func TestChaosOriginShieldFailure(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Get CloudFront distribution details
@@ -295,11 +298,17 @@ func TestChaosDDoSProtectionFailure(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	testutil.SkipIfWAFDisabled(t, terraformOptions)
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Get WAF Web ACL details
 	wafACLArn := terraform.Output(t, terraformOptions, "waf_web_acl_arn")
+	webACLName, err := testutil.WAFNameFromARN(wafACLArn)
+	require.NoError(t, err)
+	webACLID, err := testutil.WAFIDFromARN(wafACLArn)
+	require.NoError(t, err)
 
 	sess := session.Must(session.NewSession(&aws.Config{
 		Region: aws.String("us-east-1"),
@@ -311,7 +320,8 @@ func TestChaosDDoSProtectionFailure(t *testing.T) {
 
 	// Get current WAF configuration
 	getResult, err := wafSvc.GetWebACL(&wafv2.GetWebACLInput{
-		Id:    aws.String(extractWAFIDFromArn(wafACLArn)),
+		Id:    aws.String(webACLID),
+		Name:  aws.String(webACLName),
 		Scope: aws.String("CLOUDFRONT"),
 	})
 	require.NoError(t, err)
@@ -325,7 +335,8 @@ func TestChaosDDoSProtectionFailure(t *testing.T) {
 	}
 
 	_, err = wafSvc.UpdateWebACL(&wafv2.UpdateWebACLInput{
-		Id:               aws.String(extractWAFIDFromArn(wafACLArn)),
+		Id:               aws.String(webACLID),
+		Name:             aws.String(webACLName),
 		Scope:            aws.String("CLOUDFRONT"),
 		DefaultAction:    getResult.WebACL.DefaultAction,
 		Rules:            filteredRules,
@@ -339,7 +350,8 @@ func TestChaosDDoSProtectionFailure(t *testing.T) {
 
 	// Restore rate limiting rules
 	_, err = wafSvc.UpdateWebACL(&wafv2.UpdateWebACLInput{
-		Id:               aws.String(extractWAFIDFromArn(wafACLArn)),
+		Id:               aws.String(webACLID),
+		Name:             aws.String(webACLName),
 		Scope:            aws.String("CLOUDFRONT"),
 		DefaultAction:    getResult.WebACL.DefaultAction,
 		Rules:            getResult.WebACL.Rules,
@@ -352,22 +364,145 @@ func TestChaosDDoSProtectionFailure(t *testing.T) {
 	assert.NotEmpty(t, wafACLArn)
 }
 
-// Helper function to extract WAF ID from ARN
-func extractWAFIDFromArn(arn string) string {
-	// ARN format: arn:aws:wafv2:region:account:regional/webacl/name/id
-	parts := strings.Split(arn, "/")
-	if len(parts) >= 3 {
-		return parts[2]
+func TestIPSetRuleProblems(t *testing.T) {
+	t.Parallel()
+
+	allowedARN := "arn:aws:wafv2:us-east-1:123456789012:global/ipset/allowed/a1"
+	blockedARN := "arn:aws:wafv2:us-east-1:123456789012:global/ipset/blocked/b2"
+	blockedIPv6ARN := "arn:aws:wafv2:us-east-1:123456789012:global/ipset/blocked-ipv6/b3"
+	ipSetRule := func(name, arn string, priority int64, action *wafv2.RuleAction) *wafv2.Rule {
+		return &wafv2.Rule{
+			Name:      aws.String(name),
+			Priority:  aws.Int64(priority),
+			Action:    action,
+			Statement: &wafv2.Statement{IPSetReferenceStatement: &wafv2.IPSetReferenceStatement{ARN: aws.String(arn)}},
+		}
+	}
+	allowed, blocked := []string{allowedARN}, []string{blockedARN}
+	managed := &wafv2.Rule{
+		Name:      aws.String("AWSCommonRuleSet"),
+		Priority:  aws.Int64(4),
+		Statement: &wafv2.Statement{ManagedRuleGroupStatement: &wafv2.ManagedRuleGroupStatement{Name: aws.String("AWSManagedRulesCommonRuleSet")}},
+	}
+
+	webACL := &wafv2.WebACL{Rules: []*wafv2.Rule{
+		managed,
+		ipSetRule("BlocklistRule", blockedARN, 2, &wafv2.RuleAction{Block: &wafv2.BlockAction{}}),
+		ipSetRule("AllowlistRule", allowedARN, 1, &wafv2.RuleAction{Allow: &wafv2.AllowAction{}}),
+	}}
+	assert.Empty(t, ipSetRuleProblems(webACL, allowed, blocked))
+
+	// Both address families are ORed into one rule
+	orRule := ipSetRule("BlocklistRule", "", 2, &wafv2.RuleAction{Block: &wafv2.BlockAction{}})
+	orRule.Statement = &wafv2.Statement{OrStatement: &wafv2.OrStatement{Statements: []*wafv2.Statement{
+		{IPSetReferenceStatement: &wafv2.IPSetReferenceStatement{ARN: aws.String(blockedARN)}},
+		{IPSetReferenceStatement: &wafv2.IPSetReferenceStatement{ARN: aws.String(blockedIPv6ARN)}},
+	}}}
+	webACL.Rules[1] = orRule
+	assert.Empty(t, ipSetRuleProblems(webACL, allowed, []string{blockedARN, blockedIPv6ARN}))
+
+	// Blocklist first and allowing instead of blocking
+	webACL.Rules[1] = ipSetRule("BlocklistRule", blockedARN, 0, &wafv2.RuleAction{Count: &wafv2.CountAction{}})
+	assert.Equal(t, []string{
+		"rule BlocklistRule for " + blockedARN + " takes Count, not Block",
+		"rule BlocklistRule (priority 0) runs before AllowlistRule (priority 1)",
+	}, ipSetRuleProblems(webACL, allowed, blocked))
+
+	webACL.Rules = []*wafv2.Rule{managed}
+	assert.Equal(t, []string{
+		"no rule references IP set " + allowedARN,
+		"no rule references IP set " + blockedARN,
+	}, ipSetRuleProblems(webACL, allowed, blocked))
+}
+
+// Helper function to list ways the allowlist and blocklist IP set rules are
+// missing, take the wrong action or don't run ahead of every other rule
+func ipSetRuleProblems(webACL *wafv2.WebACL, allowedARNs, blockedARNs []string) []string {
+	var problems []string
+	var ordered []*wafv2.Rule
+	for _, want := range []struct {
+		arns   []string
+		action string
+	}{{allowedARNs, "Allow"}, {blockedARNs, "Block"}} {
+		for _, arn := range want.arns {
+			var found *wafv2.Rule
+			for _, rule := range webACL.Rules {
+				if containsString(statementIPSetARNs(rule.Statement), arn) {
+					found = rule
+					break
+				}
+			}
+			if found == nil {
+				problems = append(problems, "no rule references IP set "+arn)
+				continue
+			}
+			if action := testutil.RuleActionName(found.Action); action != want.action {
+				problems = append(problems, fmt.Sprintf("rule %s for %s takes %s, not %s", aws.StringValue(found.Name), arn, action, want.action))
+			}
+			if !containsRule(ordered, found) {
+				ordered = append(ordered, found)
+			}
+		}
+	}
+
+	// Each IP set rule must come before every rule listed after it
+	for i, rule := range ordered {
+		for _, other := range webACL.Rules {
+			if other == rule || containsRule(ordered[:i], other) {
+				continue
+			}
+			if aws.Int64Value(other.Priority) <= aws.Int64Value(rule.Priority) {
+				problems = append(problems, fmt.Sprintf("rule %s (priority %d) runs before %s (priority %d)",
+					aws.StringValue(other.Name), aws.Int64Value(other.Priority), aws.StringValue(rule.Name), aws.Int64Value(rule.Priority)))
+			}
+		}
+	}
+	return problems
+}
+
+// Helper function to list the IP sets a statement references, directly or
+// through an or_statement
+func statementIPSetARNs(statement *wafv2.Statement) []string {
+	if statement == nil {
+		return nil
+	}
+	if statement.IPSetReferenceStatement != nil {
+		return []string{aws.StringValue(statement.IPSetReferenceStatement.ARN)}
+	}
+	var arns []string
+	if statement.OrStatement != nil {
+		for _, nested := range statement.OrStatement.Statements {
+			arns = append(arns, statementIPSetARNs(nested)...)
+		}
+	}
+	return arns
+}
+
+// Helper function to check whether a rule is one of rules
+func containsRule(rules []*wafv2.Rule, rule *wafv2.Rule) bool {
+	for _, candidate := range rules {
+		if candidate == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to check whether values includes want
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
 	}
-	return ""
+	return false
 }
 
-// Helper function to extract WAF name from ARN
-func extractWAFNameFromArn(arn string) string {
-	// ARN format: arn:aws:wafv2:region:account:regional/webacl/name/id
-	parts := strings.Split(arn, "/")
-	if len(parts) >= 2 {
-		return parts[1]
+// Helper function to list a string map's values
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
 	}
-	return ""
+	return values
 }
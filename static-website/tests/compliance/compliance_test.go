@@ -3,8 +3,13 @@ package compliance
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/acm"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+
+	"static-website-tests/testutil"
 )
 
 func TestStaticWebsiteCompliance(t *testing.T) {
@@ -17,7 +22,7 @@ func TestStaticWebsiteCompliance(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Test encryption compliance
@@ -36,6 +41,11 @@ func TestStaticWebsiteCompliance(t *testing.T) {
 	certificateArn := terraform.Output(t, terraformOptions, "certificate_arn")
 	assert.NotEmpty(t, certificateArn)
 
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testutil.AssertCertificateDNSValidated(t, acm.New(sess), certificateArn)
+
 	// Test CloudTrail logging
 	cloudtrailEnabled := terraform.Output(t, terraformOptions, "cloudtrail_enabled")
 	assert.Equal(t, "true", cloudtrailEnabled)
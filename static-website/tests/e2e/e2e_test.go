@@ -1,12 +1,33 @@
 package e2e
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
 )
 
 func TestStaticWebsiteEndToEnd(t *testing.T) {
@@ -19,9 +40,11 @@ func TestStaticWebsiteEndToEnd(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
+	testutil.RequireDistributionServing(t, terraformOptions)
+
 	// Get the CloudFront domain
 	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
 	assert.NotEmpty(t, cloudfrontDomain)
@@ -44,3 +67,1252 @@ func TestStaticWebsiteEndToEnd(t *testing.T) {
 		assert.Equal(t, 301, httpResp.StatusCode)
 	}
 }
+
+// TestWAFBodySizeConstraint sends bodies through a behavior that accepts POST,
+// so a rejection can't come from CloudFront's method restriction, and gives
+// the WAF a status of its own so its blocks can be told apart
+func TestWAFBodySizeConstraint(t *testing.T) {
+	t.Parallel()
+
+	blockedBody := `{"error":"body too large"}`
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":                "body-size-test.example.com",
+			"enable_body_size_rule":      true,
+			"max_body_size":              1024,
+			"cloudfront_allowed_methods": []string{"DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT"},
+			"waf_block_response": map[string]interface{}{
+				"status_code":  http.StatusRequestEntityTooLarge,
+				"content_type": "APPLICATION_JSON",
+				"body":         blockedBody,
+			},
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	// Verify the rule is part of the web ACL
+	ruleNames := terraform.OutputList(t, terraformOptions, "waf_rule_names")
+	assert.Contains(t, ruleNames, "BodySizeRule")
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+	url := fmt.Sprintf("https://%s/index.html", cloudfrontDomain)
+
+	// Oversized body should be blocked by the WAF with its own response
+	oversized := bytes.Repeat([]byte("a"), 4096)
+	resp, err := http.Post(url, "application/octet-stream", bytes.NewReader(oversized))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, "Oversized request body should be blocked by the WAF")
+	assert.Equal(t, blockedBody, string(body))
+
+	// A small body passes the WAF, whatever the origin makes of the POST
+	resp, err = http.Post(url, "application/octet-stream", bytes.NewReader([]byte("a")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.NotEqual(t, http.StatusRequestEntityTooLarge, resp.StatusCode, "Small request body should not be blocked")
+
+	// Normal-sized request should pass
+	resp, err = http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Normal request should not be blocked")
+}
+
+// Address inside 133.11.0.0/16 (University of Tokyo), used to pose as a viewer in Japan
+const spoofedJapanIP = "133.11.0.1"
+
+func TestWAFGeoBlock(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":                 "geo-block-test.example.com",
+			"blocked_countries":           []string{"JP", "KP"},
+			"waf_geo_forwarded_ip_header": "X-Forwarded-For",
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	ruleNames := terraform.OutputList(t, terraformOptions, "waf_rule_names")
+	assert.Contains(t, ruleNames, "GeoBlockRule")
+	assert.Equal(t, []string{"JP", "KP"}, terraform.OutputList(t, terraformOptions, "waf_blocked_countries"))
+
+	// Verify the deployed rule blocks the configured countries
+	webACLArn := terraform.Output(t, terraformOptions, "waf_web_acl_arn")
+	webACLName, err := testutil.WAFNameFromARN(webACLArn)
+	require.NoError(t, err)
+	webACLID, err := testutil.WAFIDFromARN(webACLArn)
+	require.NoError(t, err)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	webACL, err := wafv2.New(sess).GetWebACL(&wafv2.GetWebACLInput{
+		Name:  aws.String(webACLName),
+		Id:    aws.String(webACLID),
+		Scope: aws.String(wafv2.ScopeCloudfront),
+	})
+	require.NoError(t, err)
+
+	countries, blocks := geoBlockCountries(webACL.WebACL, "GeoBlockRule")
+	assert.True(t, blocks, "GeoBlockRule should block matching requests")
+	assert.ElementsMatch(t, []string{"JP", "KP"}, countries)
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	// A viewer posing as Japan through the forwarded IP header is blocked
+	url := fmt.Sprintf("https://%s", terraform.Output(t, terraformOptions, "cloudfront_domain"))
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Forwarded-For", spoofedJapanIP)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "Request from a blocked country should be rejected")
+
+	// Without the header the rule falls through and the test runner is served
+	resp, err = http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Request from an allowed country should be served")
+}
+
+func TestGeoBlockCountries(t *testing.T) {
+	t.Parallel()
+
+	webACL := &wafv2.WebACL{
+		Rules: []*wafv2.Rule{
+			{
+				Name:      aws.String("RateLimitRule"),
+				Statement: &wafv2.Statement{RateBasedStatement: &wafv2.RateBasedStatement{Limit: aws.Int64(2000)}},
+			},
+			{
+				Name:   aws.String("GeoBlockRule"),
+				Action: &wafv2.RuleAction{Block: &wafv2.BlockAction{}},
+				Statement: &wafv2.Statement{GeoMatchStatement: &wafv2.GeoMatchStatement{
+					CountryCodes: aws.StringSlice([]string{"JP", "KP"}),
+				}},
+			},
+		},
+	}
+
+	countries, blocks := geoBlockCountries(webACL, "GeoBlockRule")
+	assert.True(t, blocks)
+	assert.Equal(t, []string{"JP", "KP"}, countries)
+
+	// A geo rule that only counts doesn't block anything
+	webACL.Rules[1].Action = &wafv2.RuleAction{Count: &wafv2.CountAction{}}
+	_, blocks = geoBlockCountries(webACL, "GeoBlockRule")
+	assert.False(t, blocks)
+
+	countries, blocks = geoBlockCountries(webACL, "RateLimitRule")
+	assert.Empty(t, countries)
+	assert.False(t, blocks)
+}
+
+// TestWAFCustomBlockResponse blocks a request through the geo rule and checks
+// the viewer gets the configured status and body instead of WAF's plain 403
+func TestWAFCustomBlockResponse(t *testing.T) {
+	t.Parallel()
+
+	blockedBody := `{"error":"blocked","support":"https://example.com/help"}`
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":                 "block-response-test.example.com",
+			"blocked_countries":           []string{"JP"},
+			"waf_geo_forwarded_ip_header": "X-Forwarded-For",
+			"waf_block_response": map[string]interface{}{
+				"status_code":  451,
+				"content_type": "APPLICATION_JSON",
+				"body":         blockedBody,
+			},
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, map[string]string{
+		"status_code":  "451",
+		"content_type": "APPLICATION_JSON",
+		"body":         blockedBody,
+	}, terraform.OutputMap(t, terraformOptions, "waf_block_response"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	webACL, err := wafv2.New(sess).GetWebACL(&wafv2.GetWebACLInput{
+		Id:    aws.String(terraform.Output(t, terraformOptions, "waf_web_acl_id")),
+		Name:  aws.String(terraform.Output(t, terraformOptions, "waf_web_acl_name")),
+		Scope: aws.String(wafv2.ScopeCloudfront),
+	})
+	require.NoError(t, err)
+	for _, ruleName := range []string{"RateLimitRule", "GeoBlockRule"} {
+		status, body, err := ruleBlockResponse(webACL.WebACL, ruleName)
+		require.NoError(t, err, ruleName)
+		assert.Equal(t, int64(451), status, ruleName)
+		assert.Equal(t, blockedBody, aws.StringValue(body.Content), ruleName)
+	}
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	// A viewer posing as Japan is blocked with the custom response
+	url := fmt.Sprintf("https://%s", terraform.Output(t, terraformOptions, "cloudfront_domain"))
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Forwarded-For", spoofedJapanIP)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, 451, resp.StatusCode)
+	assert.Equal(t, blockedBody, string(body))
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+}
+
+func TestRuleBlockResponse(t *testing.T) {
+	t.Parallel()
+
+	webACL := &wafv2.WebACL{
+		CustomResponseBodies: map[string]*wafv2.CustomResponseBody{
+			"blocked": {ContentType: aws.String(wafv2.ResponseContentTypeApplicationJson), Content: aws.String(`{"error":"blocked"}`)},
+		},
+		Rules: []*wafv2.Rule{
+			{
+				Name: aws.String("GeoBlockRule"),
+				Action: &wafv2.RuleAction{Block: &wafv2.BlockAction{CustomResponse: &wafv2.CustomResponse{
+					ResponseCode:          aws.Int64(403),
+					CustomResponseBodyKey: aws.String("blocked"),
+				}}},
+			},
+			{Name: aws.String("BodySizeRule"), Action: &wafv2.RuleAction{Block: &wafv2.BlockAction{}}},
+			{Name: aws.String("AWSCommonRuleSet"), OverrideAction: &wafv2.OverrideAction{None: &wafv2.NoneAction{}}},
+			{
+				Name: aws.String("RateLimitRule"),
+				Action: &wafv2.RuleAction{Block: &wafv2.BlockAction{CustomResponse: &wafv2.CustomResponse{
+					ResponseCode:          aws.Int64(429),
+					CustomResponseBodyKey: aws.String("throttled"),
+				}}},
+			},
+		},
+	}
+
+	status, body, err := ruleBlockResponse(webACL, "GeoBlockRule")
+	require.NoError(t, err)
+	assert.Equal(t, int64(403), status)
+	assert.Equal(t, `{"error":"blocked"}`, aws.StringValue(body.Content))
+
+	_, _, err = ruleBlockResponse(webACL, "BodySizeRule")
+	assert.EqualError(t, err, "rule BodySizeRule blocks with the default response")
+	_, _, err = ruleBlockResponse(webACL, "AWSCommonRuleSet")
+	assert.EqualError(t, err, "rule AWSCommonRuleSet does not block")
+	_, _, err = ruleBlockResponse(webACL, "RateLimitRule")
+	assert.EqualError(t, err, "rule RateLimitRule references missing response body throttled")
+	_, _, err = ruleBlockResponse(webACL, "GeoBlock")
+	assert.EqualError(t, err, "rule GeoBlock not found")
+}
+
+func TestCacheControlHonored(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "cache-control-test.example.com",
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+	bucketName := terraform.Output(t, terraformOptions, "s3_bucket_name")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+
+	// Upload an object whose origin metadata sets a short max-age
+	const cacheControl = "max-age=60"
+	key := "cache-control-test.txt"
+	_, err := s3.New(sess).PutObject(&s3.PutObjectInput{
+		Bucket:       aws.String(bucketName),
+		Key:          aws.String(key),
+		Body:         strings.NewReader("cache control test"),
+		ContentType:  aws.String("text/plain"),
+		CacheControl: aws.String(cacheControl),
+	})
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("https://%s/%s", cloudfrontDomain, key)
+
+	// Warm the cache until CloudFront serves the object from the edge
+	warmed := retry.DoWithRetry(t, "Warming CloudFront cache", 10, 10*time.Second, func() (string, error) {
+		header, err := fetchHeaders(url)
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(header.Get("X-Cache"), "Hit") {
+			return "", fmt.Errorf("expected a cache hit, got X-Cache %q", header.Get("X-Cache"))
+		}
+		return header.Get("Cache-Control"), nil
+	})
+	assert.Equal(t, cacheControl, warmed, "CloudFront should pass the origin Cache-Control through unchanged")
+
+	if testing.Short() {
+		t.Skip("Skipping Cache-Control expiry wait in short mode")
+	}
+
+	// Once max-age has elapsed, the next response must be fresh rather than the aged copy
+	time.Sleep(65 * time.Second)
+
+	header, err := fetchHeaders(url)
+	require.NoError(t, err)
+	assert.Equal(t, cacheControl, header.Get("Cache-Control"))
+	assert.Less(t, responseAge(t, header), 60, "Object should have been refreshed after max-age expired (X-Cache %q)", header.Get("X-Cache"))
+}
+
+// TestS3WebsiteMode serves the site straight from the S3 website endpoint and
+// checks the configured index and error documents answer over plain HTTP
+func TestS3WebsiteMode(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":    "s3-website-test.example.com",
+			"serving_mode":   "s3_website",
+			"index_document": "home.html",
+			"error_document": "not-found.html",
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "s3_website", terraform.Output(t, terraformOptions, "serving_mode"))
+	assert.Empty(t, terraform.Output(t, terraformOptions, "cloudfront_domain"), "s3_website mode should not create a distribution")
+	endpoint := terraform.Output(t, terraformOptions, "website_endpoint")
+	require.True(t, strings.HasPrefix(endpoint, "http://"), "Website endpoint %q should be plain HTTP", endpoint)
+	bucketName := terraform.Output(t, terraformOptions, "s3_bucket_name")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	s3Svc := s3.New(sess)
+
+	documents := map[string]string{
+		"home.html":      "<h1>s3 website index</h1>",
+		"not-found.html": "<h1>s3 website error</h1>",
+	}
+	for key, body := range documents {
+		_, err := s3Svc.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(bucketName),
+			Key:         aws.String(key),
+			Body:        strings.NewReader(body),
+			ContentType: aws.String("text/html"),
+		})
+		require.NoError(t, err)
+	}
+
+	// The TLS-only statement can't apply here; the website endpoint has no HTTPS listener
+	policy, err := s3Svc.GetBucketPolicy(&s3.GetBucketPolicyInput{Bucket: aws.String(bucketName)})
+	require.NoError(t, err)
+	denied, err := deniesInsecureTransport(aws.StringValue(policy.Policy))
+	require.NoError(t, err)
+	assert.False(t, denied, "s3_website mode must not deny plain HTTP requests")
+
+	// Public access settings take a moment to apply to the website endpoint
+	index := retry.DoWithRetry(t, "Fetching the index document", 10, 10*time.Second, func() (string, error) {
+		status, body, err := fetchStatusAndBody(endpoint + "/")
+		if err != nil {
+			return "", err
+		}
+		if status != http.StatusOK {
+			return "", fmt.Errorf("expected 200 from %s, got %d", endpoint, status)
+		}
+		return body, nil
+	})
+	assert.Equal(t, documents["home.html"], index)
+
+	status, body, err := fetchStatusAndBody(endpoint + "/no-such-page")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, documents["not-found.html"], body, "Missing objects should be answered with the error document")
+}
+
+func TestDeniesInsecureTransport(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		policy string
+		denied bool
+	}{
+		{
+			name: "cloudfront mode",
+			policy: `{"Statement": [
+				{"Effect": "Allow", "Principal": {"Service": "cloudfront.amazonaws.com"}, "Action": "s3:GetObject", "Resource": "arn:aws:s3:::site/*"},
+				{"Sid": "DenyInsecureTransport", "Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": ["arn:aws:s3:::site", "arn:aws:s3:::site/*"],
+				 "Condition": {"Bool": {"aws:SecureTransport": "false", "aws:PrincipalIsAWSService": "false"}}}
+			]}`,
+			denied: true,
+		},
+		{
+			name:   "s3 website mode",
+			policy: `{"Statement": {"Sid": "PublicWebsiteRead", "Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::site/*"}}`,
+		},
+		{
+			name:   "allow conditioned on transport",
+			policy: `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "*", "Condition": {"Bool": {"aws:SecureTransport": ["true"]}}}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		denied, err := deniesInsecureTransport(tc.policy)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.denied, denied, tc.name)
+	}
+
+	_, err := deniesInsecureTransport("not json")
+	assert.Error(t, err)
+}
+
+// TestOriginPath serves the site from a bucket prefix and checks / returns
+// the prefixed index rather than the one at the bucket root
+func TestOriginPath(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "origin-path-test.example.com",
+			"origin_path": "/build",
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "/build", terraform.Output(t, terraformOptions, "cloudfront_origin_path"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	distribution, err := cloudfront.New(sess).GetDistribution(&cloudfront.GetDistributionInput{
+		Id: aws.String(terraform.Output(t, terraformOptions, "cloudfront_distribution_id")),
+	})
+	require.NoError(t, err)
+	origins := distribution.Distribution.DistributionConfig.Origins.Items
+	require.NotEmpty(t, origins)
+	assert.Equal(t, "/build", aws.StringValue(origins[0].OriginPath), "Origin path of %s", aws.StringValue(origins[0].Id))
+
+	// A root index that must not be served proves the prefix is in effect
+	bucketName := terraform.Output(t, terraformOptions, "s3_bucket_name")
+	s3Svc := s3.New(sess)
+	documents := map[string]string{
+		"index.html":       "<h1>bucket root index</h1>",
+		"build/index.html": "<h1>build index</h1>",
+	}
+	for key, body := range documents {
+		_, err := s3Svc.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(bucketName),
+			Key:         aws.String(key),
+			Body:        strings.NewReader(body),
+			ContentType: aws.String("text/html"),
+		})
+		require.NoError(t, err)
+	}
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	url := fmt.Sprintf("https://%s/", terraform.Output(t, terraformOptions, "cloudfront_domain"))
+	index := retry.DoWithRetry(t, "Fetching the build index from "+url, 20, 30*time.Second, func() (string, error) {
+		return fetchBody(url, nil)
+	})
+	assert.Equal(t, documents["build/index.html"], index)
+}
+
+// Every method CloudFront can be configured to accept
+var httpMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// Origin the method matrix sends its preflight from, allowed by the CORS rules
+const methodMatrixOrigin = "https://example.com"
+
+func TestHTTPMethodMatrix(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":          "methods-test.example.com",
+			"cors_allowed_origins": []string{methodMatrixOrigin},
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	allowedMethods := terraform.OutputList(t, terraformOptions, "cloudfront_allowed_methods")
+	assert.ElementsMatch(t, []string{"GET", "HEAD", "OPTIONS"}, allowedMethods, "A static site should not accept mutations by default")
+
+	// The deployed cache behavior should match the configured set
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	distribution, err := cloudfront.New(sess).GetDistribution(&cloudfront.GetDistributionInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+	behavior := distribution.Distribution.DistributionConfig.DefaultCacheBehavior
+	assert.ElementsMatch(t, allowedMethods, aws.StringValueSlice(behavior.AllowedMethods.Items))
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+	assertMethodMatrix(t, fmt.Sprintf("https://%s/index.html", cloudfrontDomain), methodExpectations(allowedMethods))
+}
+
+func TestMethodExpectations(t *testing.T) {
+	t.Parallel()
+
+	expectations := methodExpectations([]string{"GET", "HEAD", "OPTIONS"})
+	assert.Len(t, expectations, len(httpMethods))
+	assert.True(t, expectations[http.MethodGet])
+	assert.True(t, expectations[http.MethodOptions])
+	assert.False(t, expectations[http.MethodPost])
+	assert.False(t, expectations[http.MethodPut])
+	assert.False(t, expectations[http.MethodDelete])
+
+	// Method names are matched case-insensitively
+	assert.True(t, methodExpectations([]string{"get", "head"})[http.MethodHead])
+}
+
+// Helper function to map every HTTP method to whether the distribution should accept it
+func methodExpectations(allowed []string) map[string]bool {
+	expectations := map[string]bool{}
+	for _, method := range httpMethods {
+		expectations[method] = false
+	}
+	for _, method := range allowed {
+		expectations[strings.ToUpper(method)] = true
+	}
+	return expectations
+}
+
+// Helper function to issue each method against a URL and assert CloudFront's
+// response. Disallowed methods are rejected by CloudFront with 403 before
+// reaching the origin. OPTIONS is sent as a preflight from methodMatrixOrigin,
+// which the bucket's CORS rules must answer with a 2xx and an allowed origin.
+func assertMethodMatrix(t *testing.T, url string, expectations map[string]bool) {
+	methods := make([]string, 0, len(expectations))
+	for method := range expectations {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	for _, method := range methods {
+		req, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		if method == http.MethodOptions {
+			req.Header.Set("Origin", methodMatrixOrigin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+
+		resp, err := client.Do(req)
+		require.NoError(t, err, "%s %s", method, url)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if expectations[method] {
+			assert.NotEqual(t, http.StatusForbidden, resp.StatusCode, "%s should be accepted (X-Cache %q)", method, resp.Header.Get("X-Cache"))
+			switch method {
+			case http.MethodGet, http.MethodHead:
+				assert.Equal(t, http.StatusOK, resp.StatusCode, "%s should serve the object", method)
+			case http.MethodOptions:
+				assert.True(t, resp.StatusCode >= 200 && resp.StatusCode < 300, "Preflight should succeed, got %d", resp.StatusCode)
+				assert.True(t, corsAllowsOrigin(resp.Header, methodMatrixOrigin), "Preflight should allow %s (Access-Control-Allow-Origin %q)", methodMatrixOrigin, resp.Header.Get("Access-Control-Allow-Origin"))
+			}
+		} else {
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, "%s should be rejected", method)
+		}
+	}
+}
+
+func TestDistributionIPv6(t *testing.T) {
+	t.Parallel()
+
+	for _, enabled := range []bool{true, false} {
+		enabled := enabled
+		t.Run(fmt.Sprintf("enable_ipv6=%t", enabled), func(t *testing.T) {
+			t.Parallel()
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: "../../",
+				Vars: map[string]interface{}{
+					"domain_name": fmt.Sprintf("ipv6-%t-test.example.com", enabled),
+					"enable_ipv6": enabled,
+				},
+			}
+
+			defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+			terraform.InitAndApply(t, terraformOptions)
+
+			assert.Equal(t, strconv.FormatBool(enabled), terraform.Output(t, terraformOptions, "cloudfront_ipv6_enabled"))
+
+			sess := session.Must(session.NewSession(&aws.Config{
+				Region: aws.String("us-east-1"),
+			}))
+			distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+			distribution, err := cloudfront.New(sess).GetDistribution(&cloudfront.GetDistributionInput{
+				Id: aws.String(distributionID),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, enabled, aws.BoolValue(distribution.Distribution.DistributionConfig.IsIPV6Enabled))
+
+			if !enabled {
+				return
+			}
+
+			testutil.RequireDistributionServing(t, terraformOptions)
+
+			cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+			var addrs []net.IP
+			retry.DoWithRetry(t, "Resolving AAAA records for "+cloudfrontDomain, 10, 30*time.Second, func() (string, error) {
+				addrs, err = ipv6Addresses(cloudfrontDomain)
+				if err != nil {
+					return "", err
+				}
+				if len(addrs) == 0 {
+					return "", fmt.Errorf("no AAAA records for %s yet", cloudfrontDomain)
+				}
+				return addrs[0].String(), nil
+			})
+
+			if !hostHasIPv6() {
+				t.Skip("Test host has no IPv6 connectivity, skipping request over IPv6")
+			}
+
+			resp, err := ipv6Client().Get(fmt.Sprintf("https://%s/", cloudfrontDomain))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+// Helper function to resolve only the IPv6 addresses of a host
+func ipv6Addresses(host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(context.Background(), "ip6", host)
+}
+
+// Helper function to check for an IPv6 default route. Dialing UDP sends no
+// packets, it only fails when there is no route to the address.
+func hostHasIPv6() bool {
+	conn, err := net.DialTimeout("udp6", "[2001:4860:4860::8888]:53", 2*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Helper function to build an HTTP client that only connects over IPv6
+func ipv6Client() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "tcp6", addr)
+	}
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}
+}
+
+// Headers the response headers policy and the Lambda@Edge function both add
+var securityHeaderNames = []string{
+	"Content-Security-Policy",
+	"Referrer-Policy",
+	"Strict-Transport-Security",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+}
+
+func TestEdgeHeadersModes(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	served := map[string]map[string]string{}
+
+	// The group returns once both parallel subtests have finished
+	t.Run("modes", func(t *testing.T) {
+		for _, mode := range []string{"policy", "lambda_edge"} {
+			mode := mode
+			t.Run(mode, func(t *testing.T) {
+				t.Parallel()
+
+				terraformOptions := &terraform.Options{
+					TerraformDir: "../../",
+					Vars: map[string]interface{}{
+						"domain_name":                    fmt.Sprintf("headers-%s-test.example.com", strings.ReplaceAll(mode, "_", "-")),
+						"edge_headers_mode":              mode,
+						"edge_headers_retain_on_destroy": false,
+					},
+					// Lambda@Edge can't be deleted until CloudFront drops its
+					// replicas, which takes a while after the distribution goes
+					RetryableTerraformErrors: map[string]string{
+						".*replicated function.*": "Lambda@Edge replicas are still being removed",
+					},
+					MaxRetries:         12,
+					TimeBetweenRetries: 10 * time.Minute,
+				}
+
+				defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+				terraform.InitAndApply(t, terraformOptions)
+
+				assert.Equal(t, mode, terraform.Output(t, terraformOptions, "edge_headers_mode"))
+				functionArn := terraform.Output(t, terraformOptions, "edge_headers_function_arn")
+
+				sess := session.Must(session.NewSession(&aws.Config{
+					Region: aws.String("us-east-1"),
+				}))
+				distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+				config, err := cloudfront.New(sess).GetDistributionConfig(&cloudfront.GetDistributionConfigInput{
+					Id: aws.String(distributionID),
+				})
+				require.NoError(t, err)
+
+				// Only the configured mechanism may be attached
+				gotMode, reference := headersMechanism(config.DistributionConfig.DefaultCacheBehavior)
+				assert.Equal(t, mode, gotMode)
+				if mode == "lambda_edge" {
+					assert.Equal(t, functionArn, reference, "Distribution should invoke the published function version")
+					testkit.AssertRoleTrust(t, terraform.Show(t, terraformOptions), iam.New(sess), testutil.RoleServices)
+				} else {
+					assert.Empty(t, functionArn)
+					assert.NotEmpty(t, reference, "Distribution should reference the response headers policy")
+				}
+
+				testutil.RequireDistributionServing(t, terraformOptions)
+
+				url := fmt.Sprintf("https://%s/", terraform.Output(t, terraformOptions, "cloudfront_domain"))
+				var header http.Header
+				retry.DoWithRetry(t, "Fetching security headers from "+url, 10, 30*time.Second, func() (string, error) {
+					header, err = fetchHeaders(url)
+					return "", err
+				})
+
+				mu.Lock()
+				served[mode] = securityHeaderValues(header)
+				mu.Unlock()
+			})
+		}
+	})
+
+	require.Len(t, served, 2, "Both modes should have served a response")
+	for _, name := range securityHeaderNames {
+		assert.NotEmpty(t, served["policy"][name], "%s should be set", name)
+	}
+	assert.Equal(t, served["policy"], served["lambda_edge"], "Both modes should serve identical security headers")
+}
+
+func TestHeadersMechanism(t *testing.T) {
+	t.Parallel()
+
+	policyBehavior := &cloudfront.DefaultCacheBehavior{
+		ResponseHeadersPolicyId:    aws.String("67f7725c-6f97-4210-82d7-5512b31e9d03"),
+		LambdaFunctionAssociations: &cloudfront.LambdaFunctionAssociations{Quantity: aws.Int64(0)},
+	}
+	mode, reference := headersMechanism(policyBehavior)
+	assert.Equal(t, "policy", mode)
+	assert.Equal(t, "67f7725c-6f97-4210-82d7-5512b31e9d03", reference)
+
+	edgeArn := "arn:aws:lambda:us-east-1:123456789012:function:static-website-security-headers:3"
+	edgeBehavior := &cloudfront.DefaultCacheBehavior{
+		LambdaFunctionAssociations: &cloudfront.LambdaFunctionAssociations{
+			Quantity: aws.Int64(1),
+			Items: []*cloudfront.LambdaFunctionAssociation{{
+				EventType:         aws.String(cloudfront.EventTypeOriginResponse),
+				LambdaFunctionARN: aws.String(edgeArn),
+			}},
+		},
+	}
+	mode, reference = headersMechanism(edgeBehavior)
+	assert.Equal(t, "lambda_edge", mode)
+	assert.Equal(t, edgeArn, reference)
+
+	// Both at once would make the served headers depend on evaluation order
+	edgeBehavior.ResponseHeadersPolicyId = policyBehavior.ResponseHeadersPolicyId
+	mode, _ = headersMechanism(edgeBehavior)
+	assert.Equal(t, "both", mode)
+
+	mode, reference = headersMechanism(&cloudfront.DefaultCacheBehavior{})
+	assert.Equal(t, "none", mode)
+	assert.Empty(t, reference)
+
+	header := http.Header{}
+	header.Set("X-Frame-Options", "DENY")
+	header.Set("Server", "AmazonS3")
+	assert.Equal(t, map[string]string{
+		"Content-Security-Policy":   "",
+		"Referrer-Policy":           "",
+		"Strict-Transport-Security": "",
+		"X-Content-Type-Options":    "",
+		"X-Frame-Options":           "DENY",
+	}, securityHeaderValues(header))
+}
+
+// TestCORSPreflight sends browser preflights from an allowed and a disallowed
+// origin, with CORS configured and with the default of no CORS headers
+func TestCORSPreflight(t *testing.T) {
+	t.Parallel()
+
+	const allowedOrigin = "https://assets.example.com"
+	const disallowedOrigin = "https://evil.example.net"
+
+	testCases := []struct {
+		name    string
+		origins []string
+	}{
+		{name: "configured", origins: []string{allowedOrigin}},
+		{name: "default", origins: []string{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: "../../",
+				Vars: map[string]interface{}{
+					"domain_name":          fmt.Sprintf("cors-%s-test.example.com", tc.name),
+					"cors_allowed_origins": tc.origins,
+				},
+			}
+
+			defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+			terraform.InitAndApply(t, terraformOptions)
+
+			corsConfig := terraform.OutputJson(t, terraformOptions, "cors_config")
+			if len(tc.origins) == 0 {
+				assert.Equal(t, "null", corsConfig, "CORS should be off by default")
+			} else {
+				assert.Contains(t, corsConfig, allowedOrigin)
+			}
+
+			testutil.RequireDistributionServing(t, terraformOptions)
+
+			url := fmt.Sprintf("https://%s/index.html", terraform.Output(t, terraformOptions, "cloudfront_domain"))
+			for _, origin := range []string{allowedOrigin, disallowedOrigin} {
+				var status int
+				var header http.Header
+				retry.DoWithRetry(t, fmt.Sprintf("Sending preflight from %s to %s", origin, url), 10, 30*time.Second, func() (string, error) {
+					var err error
+					status, header, err = corsPreflight(url, origin)
+					return "", err
+				})
+
+				// S3 refuses preflights its CORS rules don't match, and has none when CORS is off
+				wantAllowed := origin == allowedOrigin && len(tc.origins) > 0
+				if wantAllowed {
+					assert.Equal(t, http.StatusOK, status, "Preflight from %s should succeed", origin)
+				} else {
+					assert.Equal(t, http.StatusForbidden, status, "Preflight from %s should be refused", origin)
+				}
+				assert.Equal(t, wantAllowed, corsAllowsOrigin(header, origin), "Preflight from %s (Access-Control-Allow-Origin %q)", origin, header.Get("Access-Control-Allow-Origin"))
+				if !wantAllowed {
+					assert.Empty(t, header.Get("Access-Control-Allow-Origin"), "%s should get no CORS headers", origin)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSAllowsOrigin(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	assert.False(t, corsAllowsOrigin(header, "https://assets.example.com"), "No header means no cross-origin access")
+
+	header.Set("Access-Control-Allow-Origin", "https://assets.example.com")
+	assert.True(t, corsAllowsOrigin(header, "https://assets.example.com"))
+	assert.False(t, corsAllowsOrigin(header, "https://evil.example.net"))
+
+	header.Set("Access-Control-Allow-Origin", "*")
+	assert.True(t, corsAllowsOrigin(header, "https://evil.example.net"))
+}
+
+// TestContinuousDeployment changes only the staging distribution and checks
+// header-tagged requests see the change while default traffic stays on production
+func TestContinuousDeployment(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "cd-test.example.com",
+			"enable_cd":   true,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	cfSvc := cloudfront.New(sess)
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	stagingID := terraform.Output(t, terraformOptions, "cloudfront_staging_distribution_id")
+	policyID := terraform.Output(t, terraformOptions, "cloudfront_cd_policy_id")
+	stagingDomain := terraform.Output(t, terraformOptions, "cloudfront_staging_domain")
+
+	production, err := cfSvc.GetDistributionConfig(&cloudfront.GetDistributionConfigInput{Id: aws.String(distributionID)})
+	require.NoError(t, err)
+	assert.Equal(t, policyID, aws.StringValue(production.DistributionConfig.ContinuousDeploymentPolicyId), "Production should use the continuous deployment policy")
+
+	policy, err := cfSvc.GetContinuousDeploymentPolicy(&cloudfront.GetContinuousDeploymentPolicyInput{Id: aws.String(policyID)})
+	require.NoError(t, err)
+	headerName, headerValue, err := stagingRoute(policy.ContinuousDeploymentPolicy.ContinuousDeploymentPolicyConfig, stagingDomain)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": headerName, "value": headerValue}, terraform.OutputMap(t, terraformOptions, "cloudfront_cd_header"))
+
+	// Apply a change that only the staging distribution picks up
+	terraformOptions.Vars["cd_staging_origin_path"] = "/staging"
+	terraform.Apply(t, terraformOptions)
+
+	for id, wantPath := range map[string]string{distributionID: "", stagingID: "/staging"} {
+		config, err := cfSvc.GetDistributionConfig(&cloudfront.GetDistributionConfigInput{Id: aws.String(id)})
+		require.NoError(t, err)
+		origin := config.DistributionConfig.Origins.Items[0]
+		assert.Equal(t, wantPath, aws.StringValue(origin.OriginPath), "Origin path of %s", id)
+	}
+
+	// Same key, different content depending on which distribution serves it
+	bucketName := terraform.Output(t, terraformOptions, "s3_bucket_name")
+	s3Svc := s3.New(sess)
+	for key, body := range map[string]string{"cd-test.txt": "production", "staging/cd-test.txt": "staging"} {
+		_, err := s3Svc.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(bucketName),
+			Key:         aws.String(key),
+			Body:        strings.NewReader(body),
+			ContentType: aws.String("text/plain"),
+		})
+		require.NoError(t, err)
+	}
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	url := fmt.Sprintf("https://%s/cd-test.txt", terraform.Output(t, terraformOptions, "cloudfront_domain"))
+	retry.DoWithRetry(t, "Waiting for staging to serve "+url, 20, 30*time.Second, func() (string, error) {
+		staged, err := fetchBody(url, map[string]string{headerName: headerValue})
+		if err != nil {
+			return "", err
+		}
+		if staged != "staging" {
+			return "", fmt.Errorf("request with %s served %q, want staging content", headerName, staged)
+		}
+		return staged, nil
+	})
+
+	for i := 0; i < 5; i++ {
+		body, err := fetchBody(url, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "production", body, "Requests without %s should stay on production", headerName)
+	}
+}
+
+func TestStagingRoute(t *testing.T) {
+	t.Parallel()
+
+	config := &cloudfront.ContinuousDeploymentPolicyConfig{
+		Enabled: aws.Bool(true),
+		StagingDistributionDnsNames: &cloudfront.StagingDistributionDnsNames{
+			Items:    aws.StringSlice([]string{"d111111abcdef8.cloudfront.net"}),
+			Quantity: aws.Int64(1),
+		},
+		TrafficConfig: &cloudfront.TrafficConfig{
+			Type: aws.String(cloudfront.ContinuousDeploymentPolicyTypeSingleHeader),
+			SingleHeaderConfig: &cloudfront.ContinuousDeploymentSingleHeaderConfig{
+				Header: aws.String("aws-cf-cd-staging"),
+				Value:  aws.String("true"),
+			},
+		},
+	}
+
+	name, value, err := stagingRoute(config, "d111111abcdef8.cloudfront.net")
+	require.NoError(t, err)
+	assert.Equal(t, "aws-cf-cd-staging", name)
+	assert.Equal(t, "true", value)
+
+	_, _, err = stagingRoute(config, "d222222abcdef8.cloudfront.net")
+	assert.EqualError(t, err, "policy routes to [d111111abcdef8.cloudfront.net], not d222222abcdef8.cloudfront.net")
+
+	config.TrafficConfig = &cloudfront.TrafficConfig{
+		Type:               aws.String(cloudfront.ContinuousDeploymentPolicyTypeSingleWeight),
+		SingleWeightConfig: &cloudfront.ContinuousDeploymentSingleWeightConfig{Weight: aws.Float64(0.05)},
+	}
+	_, _, err = stagingRoute(config, "d111111abcdef8.cloudfront.net")
+	assert.EqualError(t, err, "policy routes by SingleWeight, not by header")
+
+	config.Enabled = aws.Bool(false)
+	_, _, err = stagingRoute(config, "d111111abcdef8.cloudfront.net")
+	assert.EqualError(t, err, "continuous deployment policy is disabled")
+}
+
+// Helper function to GET a URL and return its response headers
+func fetchHeaders(url string) (http.Header, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return resp.Header, nil
+}
+
+// Helper function to send a CORS preflight for GET from an origin, returning
+// the status and headers CloudFront answered with
+func corsPreflight(url, origin string) (int, http.Header, error) {
+	req, err := http.NewRequest(http.MethodOptions, url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.Header.Get("X-Cache") == "" {
+		return 0, nil, fmt.Errorf("preflight to %s was not answered by CloudFront (status %d)", url, resp.StatusCode)
+	}
+	return resp.StatusCode, resp.Header, nil
+}
+
+// Helper function to check a response lets a browser on origin read it
+func corsAllowsOrigin(header http.Header, origin string) bool {
+	allowed := header.Get("Access-Control-Allow-Origin")
+	return allowed == "*" || allowed == origin
+}
+
+// Helper function to read the Age header, treating a missing header as a fresh object
+func responseAge(t *testing.T, header http.Header) int {
+	value := header.Get("Age")
+	if value == "" {
+		return 0
+	}
+	age, err := strconv.Atoi(value)
+	require.NoError(t, err, "Age header should be an integer")
+	return age
+}
+
+// Helper function to return the custom status code and body a rule blocks with
+func ruleBlockResponse(webACL *wafv2.WebACL, ruleName string) (int64, *wafv2.CustomResponseBody, error) {
+	for _, rule := range webACL.Rules {
+		if aws.StringValue(rule.Name) != ruleName {
+			continue
+		}
+		if rule.Action == nil || rule.Action.Block == nil {
+			return 0, nil, fmt.Errorf("rule %s does not block", ruleName)
+		}
+		response := rule.Action.Block.CustomResponse
+		if response == nil {
+			return 0, nil, fmt.Errorf("rule %s blocks with the default response", ruleName)
+		}
+		key := aws.StringValue(response.CustomResponseBodyKey)
+		body, ok := webACL.CustomResponseBodies[key]
+		if !ok {
+			return 0, nil, fmt.Errorf("rule %s references missing response body %s", ruleName, key)
+		}
+		return aws.Int64Value(response.ResponseCode), body, nil
+	}
+	return 0, nil, fmt.Errorf("rule %s not found", ruleName)
+}
+
+// Helper function to read a geo match rule's country codes and whether it blocks
+func geoBlockCountries(webACL *wafv2.WebACL, ruleName string) ([]string, bool) {
+	for _, rule := range webACL.Rules {
+		if aws.StringValue(rule.Name) != ruleName || rule.Statement == nil || rule.Statement.GeoMatchStatement == nil {
+			continue
+		}
+		blocks := rule.Action != nil && rule.Action.Block != nil
+		return aws.StringValueSlice(rule.Statement.GeoMatchStatement.CountryCodes), blocks
+	}
+	return nil, false
+}
+
+// Helper function to report which mechanism adds security headers to a cache
+// behavior (policy, lambda_edge, both or none) and the policy ID or function ARN
+func headersMechanism(behavior *cloudfront.DefaultCacheBehavior) (string, string) {
+	policyID := aws.StringValue(behavior.ResponseHeadersPolicyId)
+	functionArn := ""
+	if behavior.LambdaFunctionAssociations != nil {
+		for _, association := range behavior.LambdaFunctionAssociations.Items {
+			if aws.StringValue(association.EventType) == cloudfront.EventTypeOriginResponse {
+				functionArn = aws.StringValue(association.LambdaFunctionARN)
+			}
+		}
+	}
+
+	switch {
+	case policyID != "" && functionArn != "":
+		return "both", ""
+	case policyID != "":
+		return "policy", policyID
+	case functionArn != "":
+		return "lambda_edge", functionArn
+	}
+	return "none", ""
+}
+
+// Helper function to pick the security headers out of a response
+func securityHeaderValues(header http.Header) map[string]string {
+	values := map[string]string{}
+	for _, name := range securityHeaderNames {
+		values[name] = header.Get(name)
+	}
+	return values
+}
+
+// Helper function to fetch a URL's body with extra request headers
+func fetchBody(url string, headers map[string]string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return string(body), nil
+}
+
+// Helper function to GET a URL and return its status code and body
+func fetchStatusAndBody(url string) (int, string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// Helper function to check a bucket policy has a Deny statement conditioned on
+// aws:SecureTransport being false
+func deniesInsecureTransport(policyDocument string) (bool, error) {
+	var policy struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policyDocument), &policy); err != nil {
+		return false, fmt.Errorf("parsing bucket policy: %w", err)
+	}
+
+	type statement struct {
+		Effect    string                                `json:"Effect"`
+		Condition map[string]map[string]json.RawMessage `json:"Condition"`
+	}
+	var statements []statement
+	if err := json.Unmarshal(policy.Statement, &statements); err != nil {
+		var single statement
+		if err := json.Unmarshal(policy.Statement, &single); err != nil {
+			return false, fmt.Errorf("parsing bucket policy statements: %w", err)
+		}
+		statements = []statement{single}
+	}
+
+	for _, stmt := range statements {
+		if stmt.Effect != "Deny" {
+			continue
+		}
+		// Condition values may be a single string or a list
+		var values []string
+		raw := stmt.Condition["Bool"]["aws:SecureTransport"]
+		if err := json.Unmarshal(raw, &values); err != nil {
+			var single string
+			if json.Unmarshal(raw, &single) == nil {
+				values = []string{single}
+			}
+		}
+		for _, value := range values {
+			if strings.EqualFold(value, "false") {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Helper function to read the header that routes requests to the staging
+// distribution, checking the policy is enabled and targets that distribution
+func stagingRoute(config *cloudfront.ContinuousDeploymentPolicyConfig, stagingDomain string) (string, string, error) {
+	if !aws.BoolValue(config.Enabled) {
+		return "", "", fmt.Errorf("continuous deployment policy is disabled")
+	}
+	var dnsNames []string
+	if config.StagingDistributionDnsNames != nil {
+		dnsNames = aws.StringValueSlice(config.StagingDistributionDnsNames.Items)
+	}
+	if len(dnsNames) != 1 || dnsNames[0] != stagingDomain {
+		return "", "", fmt.Errorf("policy routes to %v, not %s", dnsNames, stagingDomain)
+	}
+
+	traffic := config.TrafficConfig
+	if traffic == nil || traffic.SingleHeaderConfig == nil {
+		routing := "nothing"
+		if traffic != nil {
+			routing = aws.StringValue(traffic.Type)
+		}
+		return "", "", fmt.Errorf("policy routes by %s, not by header", routing)
+	}
+	return aws.StringValue(traffic.SingleHeaderConfig.Header), aws.StringValue(traffic.SingleHeaderConfig.Value), nil
+}
@@ -0,0 +1,154 @@
+package integration
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+)
+
+// Key used for the default cache behavior, which has no path pattern
+const defaultBehavior = "default"
+
+// behaviorPolicies are the cache and origin request policies a cache behavior should reference
+type behaviorPolicies struct {
+	CachePolicyID         string
+	OriginRequestPolicyID string
+}
+
+// TestCacheBehaviorsUsePolicies validates every cache behavior references cache
+// and origin request policies rather than the legacy ForwardedValues block
+func TestCacheBehaviorsUsePolicies(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":       "cache-policy-test.example.com",
+			"api_origin_domain": "api.example.com",
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	want := map[string]behaviorPolicies{
+		defaultBehavior: {
+			CachePolicyID:         terraform.Output(t, terraformOptions, "cloudfront_cache_policy_id"),
+			OriginRequestPolicyID: terraform.Output(t, terraformOptions, "cloudfront_origin_request_policy_id"),
+		},
+		"/api/*": {
+			CachePolicyID:         terraform.Output(t, terraformOptions, "cloudfront_api_cache_policy_id"),
+			OriginRequestPolicyID: terraform.Output(t, terraformOptions, "cloudfront_api_origin_request_policy_id"),
+		},
+	}
+	for behavior, policies := range want {
+		require.NotEmpty(t, policies.CachePolicyID, "%s behavior cache policy output", behavior)
+		require.NotEmpty(t, policies.OriginRequestPolicyID, "%s behavior origin request policy output", behavior)
+	}
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	result, err := cloudfront.New(sess).GetDistributionConfig(&cloudfront.GetDistributionConfigInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+
+	for _, problem := range cacheBehaviorProblems(result.DistributionConfig, want) {
+		assert.Fail(t, fmt.Sprintf("Distribution %s cache behaviors misconfigured", distributionID), problem)
+	}
+}
+
+func TestCacheBehaviorProblems(t *testing.T) {
+	t.Parallel()
+
+	want := map[string]behaviorPolicies{
+		defaultBehavior: {CachePolicyID: "cache-optimized", OriginRequestPolicyID: "cors-s3"},
+		"/api/*":        {CachePolicyID: "cache-disabled", OriginRequestPolicyID: "all-viewer"},
+	}
+	config := &cloudfront.DistributionConfig{
+		DefaultCacheBehavior: &cloudfront.DefaultCacheBehavior{
+			CachePolicyId:         aws.String("cache-optimized"),
+			OriginRequestPolicyId: aws.String("cors-s3"),
+		},
+		CacheBehaviors: &cloudfront.CacheBehaviors{
+			Items: []*cloudfront.CacheBehavior{{
+				PathPattern:           aws.String("/api/*"),
+				CachePolicyId:         aws.String("cache-disabled"),
+				OriginRequestPolicyId: aws.String("all-viewer"),
+			}},
+		},
+	}
+	assert.Empty(t, cacheBehaviorProblems(config, want))
+
+	// A behavior rolled back to the legacy block drops its policies
+	config.CacheBehaviors.Items[0] = &cloudfront.CacheBehavior{
+		PathPattern:     aws.String("/api/*"),
+		ForwardedValues: &cloudfront.ForwardedValues{QueryString: aws.Bool(true)},
+		MinTTL:          aws.Int64(0),
+	}
+	config.DefaultCacheBehavior.OriginRequestPolicyId = nil
+	assert.Equal(t, []string{
+		"/api/* behavior cache policy is \"\", want cache-disabled",
+		"/api/* behavior origin request policy is \"\", want all-viewer",
+		"/api/* behavior uses legacy ForwardedValues",
+		"default behavior origin request policy is \"\", want cors-s3",
+	}, cacheBehaviorProblems(config, want))
+
+	assert.Equal(t, []string{"/api/* behavior is missing"}, cacheBehaviorProblems(&cloudfront.DistributionConfig{
+		DefaultCacheBehavior: &cloudfront.DefaultCacheBehavior{
+			CachePolicyId:         aws.String("cache-optimized"),
+			OriginRequestPolicyId: aws.String("cors-s3"),
+		},
+	}, want))
+}
+
+// Helper function to list cache behaviors that use ForwardedValues or don't
+// reference the expected policies, keyed by path pattern (or "default")
+func cacheBehaviorProblems(config *cloudfront.DistributionConfig, want map[string]behaviorPolicies) []string {
+	type behavior struct {
+		cachePolicyID, originRequestPolicyID string
+		forwardedValues                      *cloudfront.ForwardedValues
+	}
+	behaviors := map[string]behavior{}
+	if def := config.DefaultCacheBehavior; def != nil {
+		behaviors[defaultBehavior] = behavior{aws.StringValue(def.CachePolicyId), aws.StringValue(def.OriginRequestPolicyId), def.ForwardedValues}
+	}
+	if config.CacheBehaviors != nil {
+		for _, item := range config.CacheBehaviors.Items {
+			behaviors[aws.StringValue(item.PathPattern)] = behavior{aws.StringValue(item.CachePolicyId), aws.StringValue(item.OriginRequestPolicyId), item.ForwardedValues}
+		}
+	}
+
+	var problems []string
+	for name, got := range behaviors {
+		if got.forwardedValues != nil {
+			problems = append(problems, name+" behavior uses legacy ForwardedValues")
+		}
+	}
+	for name, policies := range want {
+		got, ok := behaviors[name]
+		if !ok {
+			problems = append(problems, name+" behavior is missing")
+			continue
+		}
+		if got.cachePolicyID != policies.CachePolicyID {
+			problems = append(problems, fmt.Sprintf("%s behavior cache policy is %q, want %s", name, got.cachePolicyID, policies.CachePolicyID))
+		}
+		if got.originRequestPolicyID != policies.OriginRequestPolicyID {
+			problems = append(problems, fmt.Sprintf("%s behavior origin request policy is %q, want %s", name, got.originRequestPolicyID, policies.OriginRequestPolicyID))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
@@ -1,10 +1,32 @@
 package integration
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
 )
 
 func TestStaticWebsiteIntegration(t *testing.T) {
@@ -17,7 +39,7 @@ func TestStaticWebsiteIntegration(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Test that all components work together
@@ -32,3 +54,709 @@ func TestStaticWebsiteIntegration(t *testing.T) {
 	// Verify outputs are consistent
 	assert.NotEqual(t, cloudfrontDomain, s3BucketName)
 }
+
+func TestOriginVerifyHeader(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":                 "origin-verify-test.example.com",
+			"enable_origin_verify_header": true,
+			"origin_verify_header_name":   "X-Origin-Verify",
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	headerName := terraform.Output(t, terraformOptions, "origin_verify_header_name")
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	webACLArn := terraform.Output(t, terraformOptions, "origin_verify_web_acl_arn")
+	assert.Equal(t, "X-Origin-Verify", headerName)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+
+	// The distribution must send the secret header to its origin
+	distConfig, err := cloudfront.New(sess).GetDistributionConfig(&cloudfront.GetDistributionConfigInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+
+	headerValue := ""
+	for _, origin := range distConfig.DistributionConfig.Origins.Items {
+		if origin.CustomHeaders == nil {
+			continue
+		}
+		for _, header := range origin.CustomHeaders.Items {
+			if aws.StringValue(header.HeaderName) == headerName {
+				headerValue = aws.StringValue(header.HeaderValue)
+			}
+		}
+	}
+	require.NotEmpty(t, headerValue, "Origin custom headers should include %s", headerName)
+
+	// The origin WAF rule must expect exactly that header and value
+	webACLName, err := testutil.WAFNameFromARN(webACLArn)
+	require.NoError(t, err)
+	webACLID, err := testutil.WAFIDFromARN(webACLArn)
+	require.NoError(t, err)
+
+	webACL, err := wafv2.New(sess).GetWebACL(&wafv2.GetWebACLInput{
+		Name:  aws.String(webACLName),
+		Id:    aws.String(webACLID),
+		Scope: aws.String(wafv2.ScopeRegional),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, wafv2.ActionValueBlock, defaultAction(webACL.WebACL), "Requests without the header should be blocked")
+
+	var matched bool
+	for _, rule := range webACL.WebACL.Rules {
+		byteMatch := rule.Statement.ByteMatchStatement
+		if byteMatch == nil || byteMatch.FieldToMatch.SingleHeader == nil {
+			continue
+		}
+		if strings.EqualFold(aws.StringValue(byteMatch.FieldToMatch.SingleHeader.Name), headerName) {
+			assert.Equal(t, headerValue, string(byteMatch.SearchString), "WAF rule should expect the value CloudFront sends")
+			matched = true
+		}
+	}
+	assert.True(t, matched, "Origin WAF should have a rule matching %s", headerName)
+}
+
+// Helper function to describe a web ACL's default action
+func defaultAction(webACL *wafv2.WebACL) string {
+	if webACL.DefaultAction != nil && webACL.DefaultAction.Block != nil {
+		return wafv2.ActionValueBlock
+	}
+	return wafv2.ActionValueAllow
+}
+
+// TestOriginTimeouts adds an API origin so the read and keepalive timeouts,
+// which only custom origins carry, are rendered and checked
+func TestOriginTimeouts(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":                "origin-timeouts-test.example.com",
+			"api_origin_domain":          "api.example.com",
+			"origin_connection_attempts": 2,
+			"origin_connection_timeout":  5,
+			"origin_read_timeout":        45,
+			"origin_keepalive_timeout":   10,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	assert.Equal(t, map[string]string{
+		"connection_attempts": "2",
+		"connection_timeout":  "5",
+		"read_timeout":        "45",
+		"keepalive_timeout":   "10",
+	}, terraform.OutputMap(t, terraformOptions, "cloudfront_origin_timeouts"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+
+	distResult, err := cloudfront.New(sess).GetDistribution(&cloudfront.GetDistributionInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+
+	origins := distResult.Distribution.DistributionConfig.Origins
+	s3Origin := findOrigin(origins, "s3-origin")
+	require.NotNil(t, s3Origin, "Distribution should have the S3 origin")
+	assert.Equal(t, int64(2), aws.Int64Value(s3Origin.ConnectionAttempts))
+	assert.Equal(t, int64(5), aws.Int64Value(s3Origin.ConnectionTimeout))
+
+	apiOrigin := findOrigin(origins, terraform.Output(t, terraformOptions, "cloudfront_api_origin_id"))
+	require.NotNil(t, apiOrigin, "Distribution should have the API origin")
+	require.NotNil(t, apiOrigin.CustomOriginConfig, "API origin should be a custom origin")
+	assert.Equal(t, int64(2), aws.Int64Value(apiOrigin.ConnectionAttempts))
+	assert.Equal(t, int64(5), aws.Int64Value(apiOrigin.ConnectionTimeout))
+	assert.Equal(t, int64(45), aws.Int64Value(apiOrigin.CustomOriginConfig.OriginReadTimeout))
+	assert.Equal(t, int64(10), aws.Int64Value(apiOrigin.CustomOriginConfig.OriginKeepaliveTimeout))
+}
+
+func TestWAFAssociationEnabled(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "waf-enabled-test.example.com",
+			"enable_waf":  true,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "true", terraform.Output(t, terraformOptions, "waf_enabled"))
+	webACLArn := terraform.Output(t, terraformOptions, "waf_web_acl_arn")
+
+	// CloudFront only accepts WAFv2 ACLs created in the CLOUDFRONT scope, whose
+	// ARNs live under "global" rather than "regional"
+	assert.Regexp(t, `^arn:aws:wafv2:us-east-1:\d{12}:global/webacl/`, webACLArn)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	assert.Equal(t, webACLArn, distributionWebACLID(t, cloudfront.New(sess), distributionID), "Distribution should reference the web ACL ARN")
+}
+
+func TestWAFAssociationDisabled(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "waf-disabled-test.example.com",
+			"enable_waf":  false,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "false", terraform.Output(t, terraformOptions, "waf_enabled"))
+	assert.Empty(t, terraform.OutputList(t, terraformOptions, "waf_rule_names"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	assert.Empty(t, distributionWebACLID(t, cloudfront.New(sess), distributionID), "Distribution should not have a web ACL associated")
+}
+
+func TestApiOriginRouting(t *testing.T) {
+	t.Parallel()
+
+	// Point at a real API to also exercise routing end to end
+	apiDomain := os.Getenv("TEST_API_ORIGIN_DOMAIN")
+	reachable := apiDomain != ""
+	if !reachable {
+		apiDomain = "api.example.com"
+	}
+	wantStatus := http.StatusOK
+	if value := os.Getenv("TEST_API_EXPECTED_STATUS"); value != "" {
+		var err error
+		wantStatus, err = strconv.Atoi(value)
+		require.NoError(t, err, "TEST_API_EXPECTED_STATUS must be an HTTP status code")
+	}
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":       "api-origin-test.example.com",
+			"api_origin_domain": apiDomain,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	apiOriginID := terraform.Output(t, terraformOptions, "cloudfront_api_origin_id")
+	assert.Equal(t, "api-origin", apiOriginID)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	distResult, err := cloudfront.New(sess).GetDistribution(&cloudfront.GetDistributionInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+	config := distResult.Distribution.DistributionConfig
+
+	assert.Equal(t, int64(2), aws.Int64Value(config.Origins.Quantity), "Distribution should have S3 and API origins")
+	apiOrigin := findOrigin(config.Origins, apiOriginID)
+	require.NotNil(t, apiOrigin, "Distribution should have the API origin")
+	assert.Equal(t, apiDomain, aws.StringValue(apiOrigin.DomainName))
+	require.NotNil(t, apiOrigin.CustomOriginConfig, "API origin should be a custom origin")
+
+	assert.Equal(t, apiOriginID, targetOriginForPath(config, "/api/*"))
+	assert.Equal(t, "s3-origin", targetOriginForPath(config, "/"))
+
+	if !reachable {
+		t.Log("TEST_API_ORIGIN_DOMAIN not set, skipping end-to-end routing check")
+		return
+	}
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	// A 403 or 404 would come from CloudFront or the S3 origin, so only the
+	// API's own status shows the request was routed to it
+	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+	retry.DoWithRetry(t, "Requesting /api/ through CloudFront", 10, 30*time.Second, func() (string, error) {
+		resp, err := http.Get(fmt.Sprintf("https://%s/api/", cloudfrontDomain))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			return "", fmt.Errorf("/api/ returned %d, want %d (X-Cache %q)", resp.StatusCode, wantStatus, resp.Header.Get("X-Cache"))
+		}
+		if server := resp.Header.Get("Server"); server == "AmazonS3" {
+			return "", fmt.Errorf("/api/ was answered by the S3 origin")
+		}
+		return resp.Status, nil
+	})
+}
+
+func TestS3OnlyByDefault(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "s3-only-test.example.com",
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	distResult, err := cloudfront.New(sess).GetDistribution(&cloudfront.GetDistributionInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+	config := distResult.Distribution.DistributionConfig
+
+	assert.Equal(t, int64(1), aws.Int64Value(config.Origins.Quantity), "Distribution should only have the S3 origin")
+	assert.Equal(t, "s3-origin", targetOriginForPath(config, "/api/*"), "Without an API origin /api/* falls through to S3")
+}
+
+func TestTargetOriginForPath(t *testing.T) {
+	t.Parallel()
+
+	config := &cloudfront.DistributionConfig{
+		DefaultCacheBehavior: &cloudfront.DefaultCacheBehavior{TargetOriginId: aws.String("s3-origin")},
+		CacheBehaviors: &cloudfront.CacheBehaviors{
+			Items: []*cloudfront.CacheBehavior{
+				{PathPattern: aws.String("/api/*"), TargetOriginId: aws.String("api-origin")},
+			},
+		},
+	}
+	assert.Equal(t, "api-origin", targetOriginForPath(config, "/api/*"))
+	assert.Equal(t, "s3-origin", targetOriginForPath(config, "/"))
+	assert.Equal(t, "s3-origin", targetOriginForPath(&cloudfront.DistributionConfig{
+		DefaultCacheBehavior: config.DefaultCacheBehavior,
+	}, "/api/*"))
+}
+
+// Helper function to find the origin a cache behavior path pattern routes to,
+// falling back to the default behavior when no ordered behavior matches
+func targetOriginForPath(config *cloudfront.DistributionConfig, pathPattern string) string {
+	if config.CacheBehaviors != nil {
+		for _, behavior := range config.CacheBehaviors.Items {
+			if aws.StringValue(behavior.PathPattern) == pathPattern {
+				return aws.StringValue(behavior.TargetOriginId)
+			}
+		}
+	}
+	return aws.StringValue(config.DefaultCacheBehavior.TargetOriginId)
+}
+
+func TestRealtimeLogs(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":                 "realtime-logs-test.example.com",
+			"enable_realtime_logs":        true,
+			"realtime_logs_sampling_rate": 100,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	realtimeLogConfigArn := terraform.Output(t, terraformOptions, "realtime_log_config_arn")
+	streamName := terraform.Output(t, terraformOptions, "realtime_log_stream_name")
+	require.NotEmpty(t, realtimeLogConfigArn)
+	require.NotEmpty(t, streamName)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	config, err := cloudfront.New(sess).GetDistributionConfig(&cloudfront.GetDistributionConfigInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, realtimeLogConfigArn, aws.StringValue(config.DistributionConfig.DefaultCacheBehavior.RealtimeLogConfigArn))
+	testkit.AssertRoleTrust(t, terraform.Show(t, terraformOptions), iam.New(sess), testutil.RoleServices)
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	// Generate viewer requests, then wait for their log lines in the stream
+	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+	kinesisSvc := kinesis.New(sess)
+	retry.DoWithRetry(t, "Waiting for real-time log records in "+streamName, 20, 30*time.Second, func() (string, error) {
+		for i := 0; i < 10; i++ {
+			if resp, err := http.Get(fmt.Sprintf("https://%s/", cloudfrontDomain)); err == nil {
+				resp.Body.Close()
+			}
+		}
+
+		records, err := countStreamRecords(kinesisSvc, streamName)
+		if err != nil {
+			return "", err
+		}
+		if records == 0 {
+			return "", fmt.Errorf("no records in %s yet", streamName)
+		}
+		return fmt.Sprintf("%d records", records), nil
+	})
+}
+
+// Helper function to count the records currently held in every shard of a Kinesis stream
+func countStreamRecords(kinesisSvc kinesisiface.KinesisAPI, streamName string) (int, error) {
+	shards, err := kinesisSvc.ListShards(&kinesis.ListShardsInput{
+		StreamName: aws.String(streamName),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, shard := range shards.Shards {
+		iterator, err := kinesisSvc.GetShardIterator(&kinesis.GetShardIteratorInput{
+			StreamName:        aws.String(streamName),
+			ShardId:           shard.ShardId,
+			ShardIteratorType: aws.String(kinesis.ShardIteratorTypeTrimHorizon),
+		})
+		if err != nil {
+			return 0, err
+		}
+		records, err := kinesisSvc.GetRecords(&kinesis.GetRecordsInput{
+			ShardIterator: iterator.ShardIterator,
+		})
+		if err != nil {
+			return 0, err
+		}
+		total += len(records.Records)
+	}
+	return total, nil
+}
+
+// Helper function to read the web ACL associated with a distribution
+func distributionWebACLID(t *testing.T, cfSvc *cloudfront.CloudFront, distributionID string) string {
+	result, err := cfSvc.GetDistributionConfig(&cloudfront.GetDistributionConfigInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+	return aws.StringValue(result.DistributionConfig.WebACLId)
+}
+
+// Helper function to look up a distribution origin by ID
+func findOrigin(origins *cloudfront.Origins, id string) *cloudfront.Origin {
+	if origins == nil {
+		return nil
+	}
+	for _, origin := range origins.Items {
+		if aws.StringValue(origin.Id) == id {
+			return origin
+		}
+	}
+	return nil
+}
+
+// TestCertificatePendingWithoutHostedZone checks that without a zone no
+// validation records are created and apply doesn't wait for issuance
+func TestCertificatePendingWithoutHostedZone(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "cert-pending-test.example.com",
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	certificateArn := terraform.Output(t, terraformOptions, "certificate_arn")
+	assert.Equal(t, acm.CertificateStatusPendingValidation, terraform.Output(t, terraformOptions, "certificate_status"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	assert.Equal(t, acm.CertificateStatusPendingValidation, describeCertificateStatus(t, acm.New(sess), certificateArn))
+
+	// The pending certificate can't serve the domain, so the distribution
+	// stays on the default certificate
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	config, err := cloudfront.New(sess).GetDistributionConfig(&cloudfront.GetDistributionConfigInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+	assert.True(t, aws.BoolValue(config.DistributionConfig.ViewerCertificate.CloudFrontDefaultCertificate))
+	if aliases := config.DistributionConfig.Aliases; aliases != nil {
+		assert.Empty(t, aliases.Items, "A pending certificate can't serve the domain")
+	}
+}
+
+func TestCertificateIssuedWithHostedZone(t *testing.T) {
+	t.Parallel()
+
+	// Issuance needs a real public zone the test account controls
+	hostedZoneID := os.Getenv("TEST_HOSTED_ZONE_ID")
+	domainName := os.Getenv("TEST_DOMAIN_NAME")
+	if hostedZoneID == "" || domainName == "" {
+		t.Skip("Set TEST_HOSTED_ZONE_ID and TEST_DOMAIN_NAME to test ACM issuance")
+	}
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":    domainName,
+			"hosted_zone_id": hostedZoneID,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	certificateArn := terraform.Output(t, terraformOptions, "certificate_arn")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	acmSvc := acm.New(sess)
+
+	// Apply already waited on the validation resource; confirm with ACM
+	// directly, giving DNS propagation up to 10 minutes
+	err := testutil.WaitForCertificateIssued(context.Background(), acmSvc, certificateArn, testutil.PollOptions{Timeout: 10 * time.Minute, Interval: 15 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, acm.CertificateStatusIssued, describeCertificateStatus(t, acmSvc, certificateArn))
+
+	// The certificate's status in state dates from its creation; the next
+	// apply refreshes it
+	terraform.Apply(t, terraformOptions)
+	assert.Equal(t, acm.CertificateStatusIssued, terraform.Output(t, terraformOptions, "certificate_status"))
+
+	// The issued certificate only helps if the distribution answers for the domain
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	assertDistributionAliases(t, cloudfront.New(sess), distributionID, domainName, false)
+}
+
+func TestAliasProblems(t *testing.T) {
+	t.Parallel()
+
+	config := &cloudfront.DistributionConfig{
+		Aliases: &cloudfront.Aliases{
+			Items:    aws.StringSlice([]string{"example.com", "www.example.com"}),
+			Quantity: aws.Int64(2),
+		},
+		ViewerCertificate: &cloudfront.ViewerCertificate{
+			ACMCertificateArn: aws.String("arn:aws:acm:us-east-1:123456789012:certificate/abc"),
+		},
+	}
+	assert.Empty(t, aliasProblems(config, "example.com", true))
+
+	// Aliases are case-insensitive
+	assert.Empty(t, aliasProblems(config, "Example.COM", false))
+
+	config.Aliases = &cloudfront.Aliases{Items: aws.StringSlice([]string{"example.com"}), Quantity: aws.Int64(1)}
+	assert.Equal(t, []string{"aliases [example.com] are missing www.example.com"}, aliasProblems(config, "example.com", true))
+
+	// A certificate attached without the alias causes SNI mismatches
+	config.Aliases = &cloudfront.Aliases{Quantity: aws.Int64(0)}
+	assert.Equal(t, []string{"aliases [] are missing example.com"}, aliasProblems(config, "example.com", false))
+
+	config.Aliases = &cloudfront.Aliases{Items: aws.StringSlice([]string{"example.com"}), Quantity: aws.Int64(1)}
+	config.ViewerCertificate = &cloudfront.ViewerCertificate{CloudFrontDefaultCertificate: aws.Bool(true)}
+	assert.Equal(t, []string{"aliases are set but the distribution serves the default *.cloudfront.net certificate"}, aliasProblems(config, "example.com", false))
+}
+
+// Helper function to assert a distribution answers for the custom domain (and
+// its www. alias when one is expected) with an ACM certificate attached
+func assertDistributionAliases(t *testing.T, cfSvc *cloudfront.CloudFront, distributionID, domainName string, includeWWW bool) {
+	result, err := cfSvc.GetDistributionConfig(&cloudfront.GetDistributionConfigInput{
+		Id: aws.String(distributionID),
+	})
+	require.NoError(t, err)
+
+	for _, problem := range aliasProblems(result.DistributionConfig, domainName, includeWWW) {
+		assert.Fail(t, fmt.Sprintf("Distribution %s aliases misconfigured", distributionID), problem)
+	}
+}
+
+// Helper function to list the aliases a distribution is missing for a domain
+func aliasProblems(config *cloudfront.DistributionConfig, domainName string, includeWWW bool) []string {
+	aliases := []string{}
+	if config.Aliases != nil {
+		aliases = aws.StringValueSlice(config.Aliases.Items)
+	}
+
+	want := []string{strings.ToLower(domainName)}
+	if includeWWW {
+		want = append(want, "www."+strings.ToLower(domainName))
+	}
+
+	var problems []string
+	for _, alias := range want {
+		found := false
+		for _, got := range aliases {
+			if strings.EqualFold(got, alias) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("aliases %v are missing %s", aliases, alias))
+		}
+	}
+
+	if len(aliases) > 0 && (config.ViewerCertificate == nil || aws.StringValue(config.ViewerCertificate.ACMCertificateArn) == "") {
+		problems = append(problems, "aliases are set but the distribution serves the default *.cloudfront.net certificate")
+	}
+	return problems
+}
+
+// Helper function to look up a certificate's current status
+func describeCertificateStatus(t *testing.T, acmSvc *acm.ACM, certificateArn string) string {
+	result, err := acmSvc.DescribeCertificate(&acm.DescribeCertificateInput{
+		CertificateArn: aws.String(certificateArn),
+	})
+	require.NoError(t, err)
+	return aws.StringValue(result.Certificate.Status)
+}
+
+// CloudFront's fixed hosted zone ID, which every alias to a distribution uses
+const cloudFrontHostedZoneID = "Z2FDTNDATAQYW2"
+
+func TestRoute53AliasRecord(t *testing.T) {
+	t.Parallel()
+
+	// The alias needs a real public zone the test account controls
+	hostedZoneID := os.Getenv("TEST_HOSTED_ZONE_ID")
+	parentDomain := os.Getenv("TEST_DOMAIN_NAME")
+	if hostedZoneID == "" || parentDomain == "" {
+		t.Skip("Set TEST_HOSTED_ZONE_ID and TEST_DOMAIN_NAME to test the Route53 alias")
+	}
+	// A subdomain keeps clear of the certificate test using the same zone
+	domainName := "alias." + parentDomain
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":    domainName,
+			"hosted_zone_id": hostedZoneID,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, hostedZoneID, terraform.Output(t, terraformOptions, "route53_zone_id"))
+	fqdn := terraform.Output(t, terraformOptions, "route53_record_fqdn")
+	assert.Equal(t, domainName, strings.TrimSuffix(fqdn, "."))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	result, err := route53.New(sess).ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(hostedZoneID),
+		StartRecordName: aws.String(domainName),
+		MaxItems:        aws.String("10"),
+	})
+	require.NoError(t, err)
+
+	distributionDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+	for _, problem := range aliasRecordProblems(result.ResourceRecordSets, domainName, distributionDomain, []string{"A", "AAAA"}) {
+		assert.Fail(t, "Route53 alias misconfigured", problem)
+	}
+
+	// The record only helps once resolvers answer for it
+	retry.DoWithRetry(t, "Resolving "+domainName, 20, 15*time.Second, func() (string, error) {
+		addresses, err := net.LookupHost(domainName)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(addresses), nil
+	})
+}
+
+func TestAliasRecordProblems(t *testing.T) {
+	t.Parallel()
+
+	alias := func(name, recordType, target, zoneID string) *route53.ResourceRecordSet {
+		return &route53.ResourceRecordSet{
+			Name: aws.String(name),
+			Type: aws.String(recordType),
+			AliasTarget: &route53.AliasTarget{
+				DNSName:      aws.String(target),
+				HostedZoneId: aws.String(zoneID),
+			},
+		}
+	}
+
+	recordSets := []*route53.ResourceRecordSet{
+		alias("www.example.com.", "A", "d111111abcdef8.cloudfront.net.", cloudFrontHostedZoneID),
+		alias("www.example.com.", "AAAA", "D111111ABCDEF8.cloudfront.net.", cloudFrontHostedZoneID),
+		alias("zzz.example.com.", "A", "elsewhere.example.net.", "Z0000000000000"),
+	}
+	assert.Empty(t, aliasRecordProblems(recordSets, "www.example.com", "d111111abcdef8.cloudfront.net", []string{"A", "AAAA"}))
+
+	recordSets = []*route53.ResourceRecordSet{
+		alias("www.example.com.", "A", "d222222abcdef8.cloudfront.net.", cloudFrontHostedZoneID),
+		{Name: aws.String("www.example.com."), Type: aws.String("AAAA"), ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("2001:db8::1")}}},
+	}
+	assert.Equal(t, []string{
+		"A www.example.com points at d222222abcdef8.cloudfront.net. (zone Z2FDTNDATAQYW2), want d111111abcdef8.cloudfront.net in Z2FDTNDATAQYW2",
+		"AAAA www.example.com is not an alias record",
+	}, aliasRecordProblems(recordSets, "www.example.com", "d111111abcdef8.cloudfront.net", []string{"A", "AAAA"}))
+
+	assert.Equal(t, []string{"no A record for www.example.com"}, aliasRecordProblems(nil, "www.example.com", "d111111abcdef8.cloudfront.net", []string{"A"}))
+}
+
+// Helper function to list the record types that are missing for a name or
+// don't alias it to the distribution
+func aliasRecordProblems(recordSets []*route53.ResourceRecordSet, domainName, distributionDomain string, recordTypes []string) []string {
+	normalize := func(name string) string {
+		return strings.ToLower(strings.TrimSuffix(name, "."))
+	}
+
+	var problems []string
+	for _, recordType := range recordTypes {
+		var record *route53.ResourceRecordSet
+		for _, candidate := range recordSets {
+			if normalize(aws.StringValue(candidate.Name)) == normalize(domainName) && aws.StringValue(candidate.Type) == recordType {
+				record = candidate
+				break
+			}
+		}
+
+		switch {
+		case record == nil:
+			problems = append(problems, fmt.Sprintf("no %s record for %s", recordType, domainName))
+		case record.AliasTarget == nil:
+			problems = append(problems, fmt.Sprintf("%s %s is not an alias record", recordType, domainName))
+		case normalize(aws.StringValue(record.AliasTarget.DNSName)) != normalize(distributionDomain) ||
+			aws.StringValue(record.AliasTarget.HostedZoneId) != cloudFrontHostedZoneID:
+			problems = append(problems, fmt.Sprintf("%s %s points at %s (zone %s), want %s in %s",
+				recordType, domainName,
+				aws.StringValue(record.AliasTarget.DNSName), aws.StringValue(record.AliasTarget.HostedZoneId),
+				distributionDomain, cloudFrontHostedZoneID))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
+)
+
+// TestLogBucketTeardown validates destroy succeeds once the log buckets hold logs
+func TestLogBucketTeardown(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":                    "log-teardown-test.example.com",
+			"cloudfront_log_prefix":          "teardown/",
+			"cloudfront_log_include_cookies": true,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	s3Svc := s3.New(sess)
+
+	testutil.RequireDistributionServing(t, terraformOptions)
+
+	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+	wafLogBucket := terraform.Output(t, terraformOptions, "waf_log_bucket_name")
+	cloudfrontLogBucket := terraform.Output(t, terraformOptions, "cloudfront_log_bucket_name")
+
+	// Logging must point at the log bucket with the configured settings, and
+	// the bucket must let CloudFront write there, before logs can be expected
+	testutil.AssertDistributionLogging(t, cloudfront.New(sess), s3Svc,
+		terraform.Output(t, terraformOptions, "cloudfront_distribution_id"), cloudfrontLogBucket,
+		"teardown/", true)
+
+	// Logs and their noncurrent versions must expire in every log bucket
+	for _, output := range testutil.LogBucketOutputs {
+		testkit.AssertNoLifecycleGap(t, s3Svc, terraform.Output(t, terraformOptions, output), "")
+	}
+
+	// Firehose flushes WAF logs every five minutes; keep traffic flowing until one lands
+	retry.DoWithRetry(t, "Waiting for WAF logs in "+wafLogBucket, 20, 30*time.Second, func() (string, error) {
+		for i := 0; i < 10; i++ {
+			if resp, err := http.Get(fmt.Sprintf("https://%s/", cloudfrontDomain)); err == nil {
+				resp.Body.Close()
+			}
+		}
+
+		objects, err := s3Svc.ListObjectsV2(&s3.ListObjectsV2Input{
+			Bucket:  aws.String(wafLogBucket),
+			MaxKeys: aws.Int64(1),
+		})
+		if err != nil {
+			return "", err
+		}
+		if aws.Int64Value(objects.KeyCount) == 0 {
+			return "", fmt.Errorf("no WAF logs in %s yet", wafLogBucket)
+		}
+		return aws.StringValue(objects.Contents[0].Key), nil
+	})
+
+	// Standard logs can take an hour to arrive, so seed the CloudFront bucket
+	// with an overwritten log object to leave a noncurrent version behind
+	for _, body := range []string{"#Version: 1.0", "#Version: 1.0\n#Fields: date time"} {
+		_, err := s3Svc.PutObject(&s3.PutObjectInput{
+			Bucket:               aws.String(cloudfrontLogBucket),
+			Key:                  aws.String("E2EXAMPLE.2024-01-01-00.teardown.gz"),
+			Body:                 strings.NewReader(body),
+			ServerSideEncryption: aws.String(s3.ServerSideEncryptionAes256),
+		})
+		require.NoError(t, err)
+	}
+
+	testutil.DestroyWithLogBuckets(t, terraformOptions)
+
+	for _, bucket := range []string{wafLogBucket, cloudfrontLogBucket} {
+		_, err := s3Svc.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket)})
+		assert.Error(t, err, "Bucket %s should be gone after destroy", bucket)
+	}
+}
@@ -0,0 +1,161 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/aws/aws-sdk-go/service/wafv2/wafv2iface"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
+)
+
+// TestWAFLogDestinations applies each waf_log_destination and checks WAF logs
+// to the right kind of resource and that log records arrive there
+func TestWAFLogDestinations(t *testing.T) {
+	t.Parallel()
+
+	// The destinations share the aws-waf-logs-static-website names, so the
+	// stacks are applied one after another rather than in parallel
+	for _, destination := range []string{"firehose", "s3", "cloudwatch"} {
+		destination := destination
+		t.Run(destination, func(t *testing.T) {
+			terraformOptions := &terraform.Options{
+				TerraformDir: "../../",
+				Vars: map[string]interface{}{
+					"domain_name":         fmt.Sprintf("waf-%s-logs-test.example.com", destination),
+					"waf_log_destination": destination,
+				},
+			}
+
+			defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+			terraform.InitAndApply(t, terraformOptions)
+
+			assert.Equal(t, destination, terraform.Output(t, terraformOptions, "waf_log_destination"))
+			wafACLArn := terraform.Output(t, terraformOptions, "waf_web_acl_arn")
+			destinationArn := terraform.Output(t, terraformOptions, "waf_log_destination_arn")
+
+			testutil.RequireDistributionServing(t, terraformOptions)
+
+			cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
+
+			sess := session.Must(session.NewSession(&aws.Config{
+				Region: aws.String("us-east-1"),
+			}))
+			assertWAFLogDestination(t, wafv2.New(sess), wafACLArn, destination, destinationArn)
+			if destination == "firehose" {
+				testkit.AssertRoleTrust(t, terraform.Show(t, terraformOptions), iam.New(sess), testutil.RoleServices)
+			}
+
+			// Firehose buffers for five minutes; keep traffic flowing until a record lands
+			var check func() (string, error)
+			switch destination {
+			case "cloudwatch":
+				logGroupName := terraform.Output(t, terraformOptions, "waf_log_group_name")
+				logsSvc := cloudwatchlogs.New(sess)
+				check = func() (string, error) {
+					events, err := logsSvc.FilterLogEvents(&cloudwatchlogs.FilterLogEventsInput{
+						LogGroupName: aws.String(logGroupName),
+						Limit:        aws.Int64(1),
+					})
+					if err != nil {
+						return "", err
+					}
+					if len(events.Events) == 0 {
+						return "", fmt.Errorf("no WAF log events in %s yet", logGroupName)
+					}
+					return aws.StringValue(events.Events[0].LogStreamName), nil
+				}
+			default:
+				wafLogBucket := terraform.Output(t, terraformOptions, "waf_log_bucket_name")
+				s3Svc := s3.New(sess)
+				check = func() (string, error) {
+					objects, err := s3Svc.ListObjectsV2(&s3.ListObjectsV2Input{
+						Bucket:  aws.String(wafLogBucket),
+						MaxKeys: aws.Int64(1),
+					})
+					if err != nil {
+						return "", err
+					}
+					if aws.Int64Value(objects.KeyCount) == 0 {
+						return "", fmt.Errorf("no WAF logs in %s yet", wafLogBucket)
+					}
+					return aws.StringValue(objects.Contents[0].Key), nil
+				}
+			}
+
+			record := retry.DoWithRetry(t, fmt.Sprintf("Waiting for WAF logs in %s", destinationArn), 20, 30*time.Second, func() (string, error) {
+				for i := 0; i < 10; i++ {
+					if resp, err := http.Get(fmt.Sprintf("https://%s/", cloudfrontDomain)); err == nil {
+						resp.Body.Close()
+					}
+				}
+				return check()
+			})
+			t.Logf("WAF log record delivered to %s: %s", destination, record)
+		})
+	}
+}
+
+func TestWAFLogDestinationType(t *testing.T) {
+	t.Parallel()
+
+	for arnString, want := range map[string]string{
+		"arn:aws:firehose:us-east-1:123456789012:deliverystream/aws-waf-logs-static-website": "firehose",
+		"arn:aws:s3:::aws-waf-logs-static-website-abc12345":                                  "s3",
+		"arn:aws:logs:us-east-1:123456789012:log-group:aws-waf-logs-static-website":          "cloudwatch",
+	} {
+		got, err := wafLogDestinationType(arnString)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, arnString)
+	}
+
+	_, err := wafLogDestinationType("arn:aws:sqs:us-east-1:123456789012:aws-waf-logs")
+	assert.EqualError(t, err, "sqs is not a WAF log destination")
+	_, err = wafLogDestinationType("aws-waf-logs-static-website")
+	assert.Error(t, err)
+}
+
+// Helper function to assert a web ACL logs to exactly the expected destination
+func assertWAFLogDestination(t *testing.T, wafSvc wafv2iface.WAFV2API, webACLArn, destination, destinationArn string) {
+	result, err := wafSvc.GetLoggingConfiguration(&wafv2.GetLoggingConfigurationInput{
+		ResourceArn: aws.String(webACLArn),
+	})
+	require.NoError(t, err)
+
+	configs := aws.StringValueSlice(result.LoggingConfiguration.LogDestinationConfigs)
+	require.Equal(t, []string{destinationArn}, configs, "Web ACL should log to the %s destination", destination)
+	got, err := wafLogDestinationType(configs[0])
+	require.NoError(t, err)
+	assert.Equal(t, destination, got, "Log destination %s is the wrong kind of resource", configs[0])
+}
+
+// Helper function to map a WAF log destination ARN to its waf_log_destination value
+func wafLogDestinationType(destinationArn string) (string, error) {
+	parsed, err := arn.Parse(destinationArn)
+	if err != nil {
+		return "", err
+	}
+	switch parsed.Service {
+	case "firehose":
+		return "firehose", nil
+	case "s3":
+		return "s3", nil
+	case "logs":
+		return "cloudwatch", nil
+	}
+	return "", fmt.Errorf("%s is not a WAF log destination", parsed.Service)
+}
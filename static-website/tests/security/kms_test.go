@@ -0,0 +1,39 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
+)
+
+// TestCustomerManagedKeyHygiene creates the site's key and checks it rotates
+// and that its policy only lets the site's distributions decrypt
+func TestCustomerManagedKeyHygiene(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":    "kms-hygiene-test.example.com",
+			"create_kms_key": true,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	kmsKeyArn := terraform.Output(t, terraformOptions, "kms_key_arn")
+	require.NotEmpty(t, kmsKeyArn, "create_kms_key should provide a key")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	testkit.AssertKMSKeyHygiene(t, kms.New(sess), kmsKeyArn)
+}
@@ -0,0 +1,125 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/shield"
+	"github.com/aws/aws-sdk-go/service/shield/shieldiface"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+)
+
+// TestShieldAdvancedProtection turns on Shield Advanced and checks the
+// distribution is protected with its health check attached for detection
+func TestShieldAdvancedProtection(t *testing.T) {
+	t.Parallel()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	shieldSvc := shield.New(sess)
+
+	// Protections can only be created under a subscription, which costs too much to start for a test
+	state, err := shieldSvc.GetSubscriptionState(&shield.GetSubscriptionStateInput{})
+	require.NoError(t, err)
+	if aws.StringValue(state.SubscriptionState) != shield.SubscriptionStateActive {
+		t.Skip("The test account has no active Shield Advanced subscription")
+	}
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":            "shield-test.example.com",
+			"enable_shield_advanced": true,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "true", terraform.Output(t, terraformOptions, "shield_advanced_enabled"))
+	distributionARN := terraform.Output(t, terraformOptions, "cloudfront_distribution_arn")
+	protectionID := terraform.Output(t, terraformOptions, "shield_protection_id")
+	healthCheckID := terraform.Output(t, terraformOptions, "shield_health_check_id")
+
+	assertShieldProtection(t, shieldSvc, distributionARN, protectionID, healthCheckID)
+}
+
+func TestShieldProtectionProblems(t *testing.T) {
+	t.Parallel()
+
+	distributionARN := "arn:aws:cloudfront::123456789012:distribution/E2EXAMPLE"
+	svc := &fakeProtectionClient{protection: &shield.Protection{
+		Id:             aws.String("prot-1"),
+		ResourceArn:    aws.String(distributionARN),
+		HealthCheckIds: []*string{aws.String("hc-1")},
+	}}
+
+	problems, err := shieldProtectionProblems(svc, distributionARN, "prot-1", "hc-1")
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+	assert.Equal(t, distributionARN, aws.StringValue(svc.lastInput.ResourceArn))
+
+	// A protection left over from another deployment, without health-based detection
+	svc.protection.Id = aws.String("prot-old")
+	svc.protection.HealthCheckIds = nil
+	problems, err = shieldProtectionProblems(svc, distributionARN, "prot-1", "hc-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"protection for " + distributionARN + " is prot-old, not prot-1",
+		"health check hc-1 is not associated with protection prot-old",
+	}, problems)
+
+	_, err = shieldProtectionProblems(&fakeProtectionClient{}, distributionARN, "prot-1", "hc-1")
+	assert.Error(t, err)
+}
+
+type fakeProtectionClient struct {
+	shieldiface.ShieldAPI
+	protection *shield.Protection
+	lastInput  *shield.DescribeProtectionInput
+}
+
+func (f *fakeProtectionClient) DescribeProtection(input *shield.DescribeProtectionInput) (*shield.DescribeProtectionOutput, error) {
+	f.lastInput = input
+	if f.protection == nil {
+		return nil, &shield.ResourceNotFoundException{Message_: aws.String("The referenced protection does not exist")}
+	}
+	return &shield.DescribeProtectionOutput{Protection: f.protection}, nil
+}
+
+// Helper function to assert Shield Advanced protects a resource with the
+// expected protection and health check
+func assertShieldProtection(t *testing.T, svc shieldiface.ShieldAPI, resourceARN, protectionID, healthCheckID string) {
+	problems, err := shieldProtectionProblems(svc, resourceARN, protectionID, healthCheckID)
+	require.NoError(t, err)
+	for _, problem := range problems {
+		assert.Fail(t, "Shield Advanced protection misconfigured", problem)
+	}
+}
+
+// Helper function to look up a resource's protection by ARN and list how it
+// differs from the protection Terraform created
+func shieldProtectionProblems(svc shieldiface.ShieldAPI, resourceARN, protectionID, healthCheckID string) ([]string, error) {
+	result, err := svc.DescribeProtection(&shield.DescribeProtectionInput{
+		ResourceArn: aws.String(resourceARN),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	protection := result.Protection
+	var problems []string
+	if got := aws.StringValue(protection.Id); got != protectionID {
+		problems = append(problems, "protection for "+resourceARN+" is "+got+", not "+protectionID)
+	}
+	if !containsString(aws.StringValueSlice(protection.HealthCheckIds), healthCheckID) {
+		problems = append(problems, "health check "+healthCheckID+" is not associated with protection "+aws.StringValue(protection.Id))
+	}
+	return problems, nil
+}
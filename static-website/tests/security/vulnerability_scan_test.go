@@ -14,6 +14,9 @@ import (
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
 )
 
 func TestWebsiteVulnerabilityScan(t *testing.T) {
@@ -26,9 +29,11 @@ func TestWebsiteVulnerabilityScan(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
+	testutil.RequireDistributionServing(t, terraformOptions)
+
 	// Get infrastructure details
 	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
 	s3BucketName := terraform.Output(t, terraformOptions, "s3_bucket_name")
@@ -74,7 +79,7 @@ func TestCloudFrontSecurityScan(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
@@ -125,7 +130,7 @@ func TestWAFSecurityScan(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	wafACLArn := terraform.Output(t, terraformOptions, "waf_web_acl_arn")
@@ -138,8 +143,13 @@ func TestWAFSecurityScan(t *testing.T) {
 	// Test 1: Check WAF rule configuration
 	t.Log("Scanning WAF rule configuration...")
 
+	webACLName, err := testutil.WAFNameFromARN(wafACLArn)
+	require.NoError(t, err)
+	webACLID, err := testutil.WAFIDFromARN(wafACLArn)
+	require.NoError(t, err)
 	wafResult, err := wafSvc.GetWebACL(&wafv2.GetWebACLInput{
-		Id:    aws.String(extractWAFIDFromArn(wafACLArn)),
+		Id:    aws.String(webACLID),
+		Name:  aws.String(webACLName),
 		Scope: aws.String("CLOUDFRONT"),
 	})
 	require.NoError(t, err)
@@ -182,6 +192,38 @@ func TestWAFSecurityScan(t *testing.T) {
 	assert.True(t, hasRateLimit, "WAF should include rate limiting")
 }
 
+func TestWAFLogRedaction(t *testing.T) {
+	t.Parallel()
+
+	redactedHeaders := []string{"authorization", "cookie", "x-api-key"}
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":              "waf-redaction-test.example.com",
+			"waf_log_redacted_headers": redactedHeaders,
+		},
+	}
+
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	wafACLArn := terraform.Output(t, terraformOptions, "waf_web_acl_arn")
+	assert.ElementsMatch(t, redactedHeaders, terraform.OutputList(t, terraformOptions, "waf_log_redacted_headers"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+
+	// Sensitive headers must never reach the WAF log destination
+	t.Log("Scanning WAF logging redaction...")
+	loggingResult, err := wafv2.New(sess).GetLoggingConfiguration(&wafv2.GetLoggingConfigurationInput{
+		ResourceArn: aws.String(wafACLArn),
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, redactedHeaders, redactedHeaderNames(loggingResult.LoggingConfiguration))
+}
+
 func TestS3SecurityScan(t *testing.T) {
 	t.Parallel()
 
@@ -192,7 +234,7 @@ func TestS3SecurityScan(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	s3BucketName := terraform.Output(t, terraformOptions, "s3_bucket_name")
@@ -229,13 +271,36 @@ func TestS3SecurityScan(t *testing.T) {
 	// Test 3: Check bucket policy
 	t.Log("Scanning S3 bucket policy...")
 
-	policyResult, err := s3Svc.GetBucketPolicy(&s3.GetBucketPolicyInput{
-		Bucket: aws.String(s3BucketName),
+	distributionArn := terraform.Output(t, terraformOptions, "cloudfront_distribution_arn")
+
+	// Only this distribution may read objects through OAC
+	testkit.AssertBucketPolicyStatement(t, s3Svc, s3BucketName, testkit.StatementMatcher{
+		Effect:    "Allow",
+		Principal: "cloudfront.amazonaws.com",
+		Action:    "s3:GetObject",
+		Condition: map[string]map[string]string{
+			"StringEquals": {"AWS:SourceArn": distributionArn},
+		},
 	})
-	require.NoError(t, err)
 
-	assert.NotEmpty(t, policyResult.Policy, "Bucket policy should be configured")
-	assert.Contains(t, *policyResult.Policy, "cloudfront", "Policy should allow CloudFront access")
+	// Plain HTTP requests are denied
+	testkit.AssertBucketPolicyStatement(t, s3Svc, s3BucketName, testkit.StatementMatcher{
+		Effect:    "Deny",
+		Principal: "*",
+		Action:    "s3:GetObject",
+		Condition: map[string]map[string]string{
+			"Bool": {"aws:SecureTransport": "false"},
+		},
+	})
+
+	// Test 4: Check object ownership
+	t.Log("Scanning S3 object ownership...")
+
+	// The website and WAF log buckets never need ACLs; CloudFront standard
+	// logging still delivers through them
+	testkit.AssertBucketObjectOwnership(t, s3Svc, s3BucketName, false)
+	testkit.AssertBucketObjectOwnership(t, s3Svc, terraform.Output(t, terraformOptions, "waf_log_bucket_name"), false)
+	testkit.AssertBucketObjectOwnership(t, s3Svc, terraform.Output(t, terraformOptions, "cloudfront_log_bucket_name"), true)
 }
 
 func TestCertificateSecurityScan(t *testing.T) {
@@ -248,9 +313,11 @@ func TestCertificateSecurityScan(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
+	testutil.RequireDistributionServing(t, terraformOptions)
+
 	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
 
 	// Test 1: Check SSL/TLS configuration
@@ -291,9 +358,11 @@ func TestContentSecurityScan(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
+	testutil.RequireDistributionServing(t, terraformOptions)
+
 	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
 
 	// Test 1: Check security headers
@@ -387,8 +456,17 @@ func scanCloudFrontSecurityHeaders(t *testing.T, domain string) bool {
 func scanWAFProtection(t *testing.T, sess *session.Session, wafACLArn string) bool {
 	wafSvc := wafv2.New(sess)
 
+	webACLName, err := testutil.WAFNameFromARN(wafACLArn)
+	if err != nil {
+		return false
+	}
+	webACLID, err := testutil.WAFIDFromARN(wafACLArn)
+	if err != nil {
+		return false
+	}
 	wafResult, err := wafSvc.GetWebACL(&wafv2.GetWebACLInput{
-		Id:    aws.String(extractWAFIDFromArn(wafACLArn)),
+		Id:    aws.String(webACLID),
+		Name:  aws.String(webACLName),
 		Scope: aws.String("CLOUDFRONT"),
 	})
 	if err != nil {
@@ -472,21 +550,22 @@ func scanForWebVulnerabilities(t *testing.T, domain string) []string {
 	return vulnerabilities
 }
 
-func extractWAFIDFromArn(arn string) string {
-	// ARN format: arn:aws:wafv2:region:account:regional/webacl/name/id
-	parts := strings.Split(arn, "/")
-	if len(parts) >= 3 {
-		return parts[2]
+// Helper function to list the single headers redacted by a WAF logging configuration
+func redactedHeaderNames(config *wafv2.LoggingConfiguration) []string {
+	var names []string
+	for _, field := range config.RedactedFields {
+		if field.SingleHeader != nil {
+			names = append(names, strings.ToLower(aws.StringValue(field.SingleHeader.Name)))
+		}
 	}
-	return ""
+	return names
 }
 
-// Helper function to extract WAF name from ARN
-func extractWAFNameFromArn(arn string) string {
-	// ARN format: arn:aws:wafv2:region:account:regional/webacl/name/id
-	parts := strings.Split(arn, "/")
-	if len(parts) >= 2 {
-		return parts[1]
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
 	}
-	return ""
+	return false
 }
@@ -0,0 +1,84 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/acm/acmiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// WaitForCertificateIssued waits until ACM reports a certificate as ISSUED,
+// doubling the wait between attempts. Statuses ACM never moves on from, such
+// as FAILED or VALIDATION_TIMED_OUT, fail right away.
+func WaitForCertificateIssued(ctx context.Context, svc acmiface.ACMAPI, certificateArn string, opts PollOptions) error {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 15 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	interval := opts.Interval
+	for {
+		output, err := svc.DescribeCertificateWithContext(ctx, &acm.DescribeCertificateInput{CertificateArn: aws.String(certificateArn)})
+		if err != nil {
+			return err
+		}
+		status := aws.StringValue(output.Certificate.Status)
+		switch status {
+		case acm.CertificateStatusIssued:
+			return nil
+		case acm.CertificateStatusPendingValidation:
+		default:
+			return fmt.Errorf("certificate %s is %s and won't be issued", certificateArn, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("certificate %s is still %s: %w", certificateArn, status, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > maxPollInterval {
+			interval = maxPollInterval
+		}
+	}
+}
+
+// AssertCertificateDNSValidated asserts every domain on a certificate is
+// validated through DNS, which renews without anyone answering an email
+func AssertCertificateDNSValidated(t testing.TB, acmSvc acmiface.ACMAPI, certificateArn string) {
+	domains, err := NonDNSValidatedDomains(acmSvc, certificateArn)
+	require.NoError(t, err)
+	assert.Empty(t, domains, "Certificate %s should use DNS validation for every domain", certificateArn)
+}
+
+// NonDNSValidatedDomains lists certificate domains whose validation method isn't DNS
+func NonDNSValidatedDomains(acmSvc acmiface.ACMAPI, certificateArn string) ([]string, error) {
+	result, err := acmSvc.DescribeCertificate(&acm.DescribeCertificateInput{
+		CertificateArn: aws.String(certificateArn),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Certificate.DomainValidationOptions) == 0 {
+		return nil, fmt.Errorf("certificate %s has no domain validation options", certificateArn)
+	}
+
+	var domains []string
+	for _, option := range result.Certificate.DomainValidationOptions {
+		if aws.StringValue(option.ValidationMethod) != acm.ValidationMethodDns {
+			domains = append(domains, aws.StringValue(option.DomainName))
+		}
+	}
+	return domains, nil
+}
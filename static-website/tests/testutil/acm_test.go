@@ -0,0 +1,98 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/acm/acmiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForCertificateIssued(t *testing.T) {
+	t.Parallel()
+
+	const arn = "arn:aws:acm:us-east-1:123456789012:certificate/abc"
+	opts := PollOptions{Timeout: 50 * time.Millisecond, Interval: time.Millisecond}
+
+	svc := &fakeCertificateClient{statuses: []string{acm.CertificateStatusPendingValidation, acm.CertificateStatusIssued}}
+	require.NoError(t, WaitForCertificateIssued(context.Background(), svc, arn, opts))
+	assert.Equal(t, 2, svc.calls)
+	assert.Equal(t, arn, aws.StringValue(svc.lastInput.CertificateArn))
+
+	// Waiting won't bring back a certificate ACM gave up on
+	svc = &fakeCertificateClient{statuses: []string{acm.CertificateStatusValidationTimedOut}}
+	err := WaitForCertificateIssued(context.Background(), svc, arn, opts)
+	assert.EqualError(t, err, "certificate "+arn+" is VALIDATION_TIMED_OUT and won't be issued")
+	assert.Equal(t, 1, svc.calls)
+
+	svc = &fakeCertificateClient{statuses: []string{acm.CertificateStatusPendingValidation}}
+	err = WaitForCertificateIssued(context.Background(), svc, arn, opts)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "is still PENDING_VALIDATION")
+
+	svc = &fakeCertificateClient{err: errors.New("ResourceNotFoundException")}
+	assert.EqualError(t, WaitForCertificateIssued(context.Background(), svc, arn, opts), "ResourceNotFoundException")
+}
+
+func TestNonDNSValidatedDomains(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeCertificateClient{
+		options: []*acm.DomainValidation{
+			{DomainName: aws.String("example.com"), ValidationMethod: aws.String(acm.ValidationMethodDns)},
+			{DomainName: aws.String("www.example.com"), ValidationMethod: aws.String(acm.ValidationMethodEmail)},
+		},
+	}
+
+	domains, err := NonDNSValidatedDomains(svc, "arn:aws:acm:us-east-1:123456789012:certificate/abc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"www.example.com"}, domains)
+	assert.Equal(t, "arn:aws:acm:us-east-1:123456789012:certificate/abc", aws.StringValue(svc.lastInput.CertificateArn))
+
+	// A certificate without validation options can't be verified either way
+	_, err = NonDNSValidatedDomains(&fakeCertificateClient{}, "arn:aws:acm:us-east-1:123456789012:certificate/abc")
+	assert.Error(t, err)
+}
+
+type fakeCertificateClient struct {
+	acmiface.ACMAPI
+	// Status returned by each call; the last one repeats
+	statuses  []string
+	options   []*acm.DomainValidation
+	err       error
+	calls     int
+	lastInput *acm.DescribeCertificateInput
+}
+
+func (f *fakeCertificateClient) DescribeCertificateWithContext(ctx aws.Context, input *acm.DescribeCertificateInput, opts ...request.Option) (*acm.DescribeCertificateOutput, error) {
+	f.calls++
+	f.lastInput = input
+	if f.err != nil {
+		return nil, f.err
+	}
+	status := f.statuses[len(f.statuses)-1]
+	if f.calls <= len(f.statuses) {
+		status = f.statuses[f.calls-1]
+	}
+	return &acm.DescribeCertificateOutput{Certificate: &acm.CertificateDetail{
+		CertificateArn: input.CertificateArn,
+		Status:         aws.String(status),
+	}}, nil
+}
+
+func (f *fakeCertificateClient) DescribeCertificate(input *acm.DescribeCertificateInput) (*acm.DescribeCertificateOutput, error) {
+	f.lastInput = input
+	return &acm.DescribeCertificateOutput{
+		Certificate: &acm.CertificateDetail{
+			CertificateArn:          input.CertificateArn,
+			DomainValidationOptions: f.options,
+		},
+	}, nil
+}
@@ -0,0 +1,42 @@
+// Package testutil holds helpers shared by the test suites, which can't import
+// code from each other's _test.go files.
+package testutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WAFNameFromARN returns the web ACL name from a WAFv2 web ACL ARN
+// (arn:aws:wafv2:<region>:<account>:<global|regional>/webacl/<name>/<id>)
+func WAFNameFromARN(arn string) (string, error) {
+	name, _, err := parseWebACLARN(arn)
+	return name, err
+}
+
+// WAFIDFromARN returns the web ACL ID from a WAFv2 web ACL ARN
+func WAFIDFromARN(arn string) (string, error) {
+	_, id, err := parseWebACLARN(arn)
+	return id, err
+}
+
+// parseWebACLARN splits a web ACL ARN into its name and ID. CLOUDFRONT-scope
+// ACLs use the global scope in their ARN, REGIONAL ones use regional.
+func parseWebACLARN(arn string) (string, string, error) {
+	fields := strings.SplitN(arn, ":", 6)
+	if len(fields) != 6 || fields[0] != "arn" || fields[2] != "wafv2" || fields[3] == "" || fields[4] == "" {
+		return "", "", fmt.Errorf("%q is not a WAFv2 ARN", arn)
+	}
+
+	resource := strings.Split(fields[5], "/")
+	if len(resource) != 4 || resource[1] != "webacl" {
+		return "", "", fmt.Errorf("%q is not a web ACL ARN", arn)
+	}
+	if resource[0] != "global" && resource[0] != "regional" {
+		return "", "", fmt.Errorf("web ACL ARN %q has unknown scope %q", arn, resource[0])
+	}
+	if resource[2] == "" || resource[3] == "" {
+		return "", "", fmt.Errorf("web ACL ARN %q is missing its name or ID", arn)
+	}
+	return resource[2], resource[3], nil
+}
@@ -0,0 +1,64 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebACLFromARN(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		arn     string
+		aclName string
+		id      string
+		err     string
+	}{
+		{
+			name:    "cloudfront scope",
+			arn:     "arn:aws:wafv2:us-east-1:123456789012:global/webacl/static-website-waf/a1b2c3d4-5678-90ab-cdef-EXAMPLE11111",
+			aclName: "static-website-waf",
+			id:      "a1b2c3d4-5678-90ab-cdef-EXAMPLE11111",
+		},
+		{
+			name:    "regional scope",
+			arn:     "arn:aws:wafv2:eu-west-1:123456789012:regional/webacl/origin-verify/a1b2c3d4-5678-90ab-cdef-EXAMPLE22222",
+			aclName: "origin-verify",
+			id:      "a1b2c3d4-5678-90ab-cdef-EXAMPLE22222",
+		},
+		{
+			name:    "other partition",
+			arn:     "arn:aws-us-gov:wafv2:us-gov-west-1:123456789012:regional/webacl/gov-waf/a1b2c3d4-5678-90ab-cdef-EXAMPLE33333",
+			aclName: "gov-waf",
+			id:      "a1b2c3d4-5678-90ab-cdef-EXAMPLE33333",
+		},
+		{name: "empty", arn: "", err: `"" is not a WAFv2 ARN`},
+		{name: "not an arn", arn: "static-website-waf", err: `"static-website-waf" is not a WAFv2 ARN`},
+		{name: "classic waf", arn: "arn:aws:waf::123456789012:webacl/a1b2c3d4", err: "is not a WAFv2 ARN"},
+		{name: "missing account", arn: "arn:aws:wafv2:us-east-1::global/webacl/static-website-waf/a1b2c3d4", err: "is not a WAFv2 ARN"},
+		{name: "ip set", arn: "arn:aws:wafv2:us-east-1:123456789012:global/ipset/allowed/a1b2c3d4", err: "is not a web ACL ARN"},
+		{name: "missing id", arn: "arn:aws:wafv2:us-east-1:123456789012:global/webacl/static-website-waf", err: "is not a web ACL ARN"},
+		{name: "empty id", arn: "arn:aws:wafv2:us-east-1:123456789012:global/webacl/static-website-waf/", err: "is missing its name or ID"},
+		{name: "unknown scope", arn: "arn:aws:wafv2:us-east-1:123456789012:cloudfront/webacl/static-website-waf/a1b2c3d4", err: `unknown scope "cloudfront"`},
+	}
+
+	for _, tc := range testCases {
+		name, nameErr := WAFNameFromARN(tc.arn)
+		id, idErr := WAFIDFromARN(tc.arn)
+		if tc.err != "" {
+			require.Error(t, nameErr, tc.name)
+			require.Error(t, idErr, tc.name)
+			assert.Contains(t, nameErr.Error(), tc.err, tc.name)
+			assert.Empty(t, name, tc.name)
+			assert.Empty(t, id, tc.name)
+			continue
+		}
+		require.NoError(t, nameErr, tc.name)
+		require.NoError(t, idErr, tc.name)
+		assert.Equal(t, tc.aclName, name, tc.name)
+		assert.Equal(t, tc.id, id, tc.name)
+	}
+}
@@ -0,0 +1,133 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/cloudfront/cloudfrontiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RequireDistributionServing stops a test before its HTTP assertions unless
+// the module's distribution is enabled and deployed. A disabled distribution
+// still has a domain name but refuses connections, which otherwise shows up
+// as confusing network errors much later in the test.
+func RequireDistributionServing(t testing.TB, terraformOptions *terraform.Options) {
+	distributionID := terraform.Output(t, terraformOptions, "cloudfront_distribution_id")
+	require.NotEmpty(t, distributionID, "No CloudFront distribution to send requests to")
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	err := WaitForDistributionServing(context.Background(), cloudfront.New(sess), distributionID, PollOptions{Timeout: 20 * time.Minute, Interval: 30 * time.Second})
+	require.NoError(t, err)
+}
+
+// WaitForDistributionServing waits until a distribution's status is Deployed,
+// doubling the wait between attempts. A disabled distribution fails right
+// away since waiting won't change it.
+func WaitForDistributionServing(ctx context.Context, svc cloudfrontiface.CloudFrontAPI, distributionID string, opts PollOptions) error {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 20 * time.Minute
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	interval := opts.Interval
+	for {
+		output, err := svc.GetDistributionWithContext(ctx, &cloudfront.GetDistributionInput{Id: aws.String(distributionID)})
+		if err != nil {
+			return err
+		}
+		distribution := output.Distribution
+		if distribution.DistributionConfig == nil || !aws.BoolValue(distribution.DistributionConfig.Enabled) {
+			return fmt.Errorf("distribution %s is disabled and won't serve requests", distributionID)
+		}
+		status := aws.StringValue(distribution.Status)
+		if status == "Deployed" {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("distribution %s is still %s: %w", distributionID, status, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > maxPollInterval {
+			interval = maxPollInterval
+		}
+	}
+}
+
+// Canonical user CloudFront delivers standard logs as (awslogsdelivery).
+// CloudFront grants it FULL_CONTROL on the log bucket when logging is turned
+// on, but only if the bucket accepts ACLs.
+const cloudFrontLogDeliveryCanonicalID = "c4c1ede66af53448b93c283ce9448c4ba468c9432aa01d700d3878632f77d2d0"
+
+// AssertDistributionLogging checks a distribution's standard logging is
+// enabled and writes to bucket under prefix with the expected cookie setting,
+// and that the bucket grants CloudFront's log delivery account access. Logs
+// can take an hour to arrive, so this catches a missing grant long before an
+// empty bucket would.
+func AssertDistributionLogging(t testing.TB, cfSvc cloudfrontiface.CloudFrontAPI, s3Svc s3iface.S3API, distributionID, bucket, prefix string, includeCookies bool) {
+	output, err := cfSvc.GetDistribution(&cloudfront.GetDistributionInput{Id: aws.String(distributionID)})
+	require.NoError(t, err)
+	require.NotNil(t, output.Distribution.DistributionConfig)
+	for _, problem := range DistributionLoggingProblems(output.Distribution.DistributionConfig.Logging, bucket, prefix, includeCookies) {
+		assert.Fail(t, "Distribution "+distributionID+" logging is misconfigured", problem)
+	}
+
+	acl, err := s3Svc.GetBucketAcl(&s3.GetBucketAclInput{Bucket: aws.String(bucket)})
+	require.NoError(t, err)
+	assert.True(t, grantsLogDelivery(acl.Grants), "Bucket %s should grant CloudFront log delivery FULL_CONTROL", bucket)
+}
+
+// DistributionLoggingProblems lists how a distribution's logging config
+// differs from logging to bucket (by name) under prefix
+func DistributionLoggingProblems(logging *cloudfront.LoggingConfig, bucket, prefix string, includeCookies bool) []string {
+	if logging == nil || !aws.BoolValue(logging.Enabled) {
+		return []string{"logging is disabled"}
+	}
+
+	var problems []string
+	// The config names the bucket by its global S3 domain
+	if got := strings.TrimSuffix(aws.StringValue(logging.Bucket), ".s3.amazonaws.com"); got != bucket {
+		problems = append(problems, fmt.Sprintf("logs go to bucket %q, not %q", got, bucket))
+	}
+	if got := aws.StringValue(logging.Prefix); got != prefix {
+		problems = append(problems, fmt.Sprintf("log prefix is %q, not %q", got, prefix))
+	}
+	if got := aws.BoolValue(logging.IncludeCookies); got != includeCookies {
+		problems = append(problems, fmt.Sprintf("include cookies is %t, not %t", got, includeCookies))
+	}
+	return problems
+}
+
+// grantsLogDelivery reports whether an ACL gives CloudFront's log delivery
+// account FULL_CONTROL
+func grantsLogDelivery(grants []*s3.Grant) bool {
+	for _, grant := range grants {
+		if grant.Grantee == nil || aws.StringValue(grant.Permission) != s3.PermissionFullControl {
+			continue
+		}
+		if aws.StringValue(grant.Grantee.ID) == cloudFrontLogDeliveryCanonicalID {
+			return true
+		}
+	}
+	return false
+}
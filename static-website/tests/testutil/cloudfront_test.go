@@ -0,0 +1,107 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/cloudfront/cloudfrontiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForDistributionServing(t *testing.T) {
+	t.Parallel()
+
+	opts := PollOptions{Timeout: 50 * time.Millisecond, Interval: time.Millisecond}
+
+	// Deploying for two attempts, then deployed
+	svc := &fakeDistributionClient{statuses: []string{"InProgress", "InProgress", "Deployed"}, enabled: true}
+	require.NoError(t, WaitForDistributionServing(context.Background(), svc, "E2EXAMPLE", opts))
+	assert.Equal(t, 3, svc.calls)
+
+	// Disabled fails on the first attempt, even while deployed
+	svc = &fakeDistributionClient{statuses: []string{"Deployed"}, enabled: false}
+	err := WaitForDistributionServing(context.Background(), svc, "E2EXAMPLE", opts)
+	assert.EqualError(t, err, "distribution E2EXAMPLE is disabled and won't serve requests")
+	assert.Equal(t, 1, svc.calls)
+
+	svc = &fakeDistributionClient{statuses: []string{"InProgress"}, enabled: true}
+	err = WaitForDistributionServing(context.Background(), svc, "E2EXAMPLE", opts)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "distribution E2EXAMPLE is still InProgress")
+
+	svc = &fakeDistributionClient{err: errors.New("NoSuchDistribution")}
+	assert.EqualError(t, WaitForDistributionServing(context.Background(), svc, "E2EXAMPLE", opts), "NoSuchDistribution")
+}
+
+type fakeDistributionClient struct {
+	cloudfrontiface.CloudFrontAPI
+	// Status returned by each call; the last one repeats
+	statuses []string
+	enabled  bool
+	err      error
+	calls    int
+}
+
+func (f *fakeDistributionClient) GetDistributionWithContext(ctx aws.Context, input *cloudfront.GetDistributionInput, opts ...request.Option) (*cloudfront.GetDistributionOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	status := f.statuses[len(f.statuses)-1]
+	if f.calls <= len(f.statuses) {
+		status = f.statuses[f.calls-1]
+	}
+	return &cloudfront.GetDistributionOutput{Distribution: &cloudfront.Distribution{
+		Id:                 input.Id,
+		Status:             aws.String(status),
+		DistributionConfig: &cloudfront.DistributionConfig{Enabled: aws.Bool(f.enabled)},
+	}}, nil
+}
+
+func TestDistributionLoggingProblems(t *testing.T) {
+	t.Parallel()
+
+	logging := &cloudfront.LoggingConfig{
+		Enabled:        aws.Bool(true),
+		Bucket:         aws.String("cloudfront-logs-abc123.s3.amazonaws.com"),
+		Prefix:         aws.String("cloudfront-logs"),
+		IncludeCookies: aws.Bool(false),
+	}
+	assert.Empty(t, DistributionLoggingProblems(logging, "cloudfront-logs-abc123", "cloudfront-logs", false))
+	assert.Equal(t, []string{
+		`logs go to bucket "cloudfront-logs-abc123", not "other-logs"`,
+		`log prefix is "cloudfront-logs", not "edge/"`,
+		"include cookies is false, not true",
+	}, DistributionLoggingProblems(logging, "other-logs", "edge/", true))
+
+	// CloudFront keeps the bucket and prefix of a disabled config
+	logging.Enabled = aws.Bool(false)
+	assert.Equal(t, []string{"logging is disabled"}, DistributionLoggingProblems(logging, "cloudfront-logs-abc123", "cloudfront-logs", false))
+	assert.Equal(t, []string{"logging is disabled"}, DistributionLoggingProblems(nil, "cloudfront-logs-abc123", "cloudfront-logs", false))
+}
+
+func TestGrantsLogDelivery(t *testing.T) {
+	t.Parallel()
+
+	owner := &s3.Grant{
+		Grantee:    &s3.Grantee{ID: aws.String("owner-canonical-id"), Type: aws.String(s3.TypeCanonicalUser)},
+		Permission: aws.String(s3.PermissionFullControl),
+	}
+	assert.False(t, grantsLogDelivery([]*s3.Grant{owner}), "Owner-only ACL has no delivery grant")
+	assert.False(t, grantsLogDelivery([]*s3.Grant{owner, {
+		Grantee:    &s3.Grantee{ID: aws.String(cloudFrontLogDeliveryCanonicalID)},
+		Permission: aws.String(s3.PermissionRead),
+	}}), "Read access isn't enough to deliver logs")
+	assert.True(t, grantsLogDelivery([]*s3.Grant{owner, {
+		Grantee:    &s3.Grantee{ID: aws.String(cloudFrontLogDeliveryCanonicalID), Type: aws.String(s3.TypeCanonicalUser)},
+		Permission: aws.String(s3.PermissionFullControl),
+	}}))
+}
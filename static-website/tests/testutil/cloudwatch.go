@@ -0,0 +1,100 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+// PollOptions controls how long PollMetric waits for datapoints. Zero values
+// fall back to a 10 minute timeout, a 15 second first interval and one datapoint.
+type PollOptions struct {
+	Timeout       time.Duration
+	Interval      time.Duration
+	MinDatapoints int
+}
+
+// MetricResult aggregates the datapoints PollMetric found. Sum, Average and
+// Maximum only cover the statistics the input requested.
+type MetricResult struct {
+	Datapoints int
+	Attempts   int
+	Sum        float64
+	Average    float64
+	Maximum    float64
+}
+
+// Upper bound for the doubling wait between attempts
+const maxPollInterval = 2 * time.Minute
+
+// PollMetric calls GetMetricStatistics until at least opts.MinDatapoints
+// datapoints are returned, doubling the wait between attempts. CloudWatch
+// publishes metrics minutes after the traffic behind them, so the input's
+// EndTime is moved to the current time on each attempt. When the deadline
+// passes the partial result is returned with an error.
+func PollMetric(ctx context.Context, svc cloudwatchiface.CloudWatchAPI, input *cloudwatch.GetMetricStatisticsInput, opts PollOptions) (MetricResult, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 15 * time.Second
+	}
+	if opts.MinDatapoints <= 0 {
+		opts.MinDatapoints = 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	var result MetricResult
+	interval := opts.Interval
+	for {
+		request := *input
+		request.EndTime = aws.Time(time.Now())
+		output, err := svc.GetMetricStatisticsWithContext(ctx, &request)
+		result.Attempts++
+		if err != nil {
+			return result, err
+		}
+
+		result = aggregateDatapoints(output.Datapoints, result.Attempts)
+		if result.Datapoints >= opts.MinDatapoints {
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, fmt.Errorf("%s/%s had %d datapoints after %d attempts, want %d: %w",
+				aws.StringValue(input.Namespace), aws.StringValue(input.MetricName), result.Datapoints, result.Attempts, opts.MinDatapoints, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > maxPollInterval {
+			interval = maxPollInterval
+		}
+	}
+}
+
+// aggregateDatapoints sums Sum, averages Average and takes the largest Maximum
+func aggregateDatapoints(datapoints []*cloudwatch.Datapoint, attempts int) MetricResult {
+	result := MetricResult{Datapoints: len(datapoints), Attempts: attempts}
+	var averages int
+	for _, datapoint := range datapoints {
+		result.Sum += aws.Float64Value(datapoint.Sum)
+		if datapoint.Average != nil {
+			result.Average += *datapoint.Average
+			averages++
+		}
+		if datapoint.Maximum != nil && *datapoint.Maximum > result.Maximum {
+			result.Maximum = *datapoint.Maximum
+		}
+	}
+	if averages > 0 {
+		result.Average /= float64(averages)
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollMetric(t *testing.T) {
+	t.Parallel()
+
+	input := &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/CloudFront"),
+		MetricName: aws.String("Requests"),
+		StartTime:  aws.Time(time.Now().Add(-10 * time.Minute)),
+		EndTime:    aws.Time(time.Now().Add(-5 * time.Minute)),
+		Period:     aws.Int64(60),
+		Statistics: aws.StringSlice([]string{"Sum", "Average", "Maximum"}),
+	}
+	originalEnd := aws.TimeValue(input.EndTime)
+
+	// Metrics show up on the third attempt
+	svc := &fakeMetricStatisticsClient{
+		emptyCalls: 2,
+		datapoints: []*cloudwatch.Datapoint{
+			{Sum: aws.Float64(10), Average: aws.Float64(2), Maximum: aws.Float64(5)},
+			{Sum: aws.Float64(4), Average: aws.Float64(4), Maximum: aws.Float64(3)},
+		},
+	}
+	result, err := PollMetric(context.Background(), svc, input, PollOptions{Timeout: time.Second, Interval: time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, MetricResult{Datapoints: 2, Attempts: 3, Sum: 14, Average: 3, Maximum: 5}, result)
+
+	// Each attempt looks up to the current time without changing the caller's input
+	assert.True(t, aws.TimeValue(svc.lastInput.EndTime).After(originalEnd))
+	assert.Equal(t, originalEnd, aws.TimeValue(input.EndTime))
+}
+
+func TestPollMetricTimesOut(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeMetricStatisticsClient{
+		emptyCalls: 100,
+		datapoints: []*cloudwatch.Datapoint{{Sum: aws.Float64(1)}},
+	}
+	input := &cloudwatch.GetMetricStatisticsInput{Namespace: aws.String("AWS/CloudFront"), MetricName: aws.String("BytesDownloaded")}
+	result, err := PollMetric(context.Background(), svc, input, PollOptions{Timeout: 50 * time.Millisecond, Interval: 5 * time.Millisecond, MinDatapoints: 1})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "AWS/CloudFront/BytesDownloaded had 0 datapoints")
+	assert.Zero(t, result.Datapoints)
+	assert.Greater(t, result.Attempts, 1)
+	assert.Less(t, result.Attempts, 10, "The wait between attempts should grow")
+}
+
+func TestPollMetricWaitsForMinDatapoints(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeMetricStatisticsClient{
+		datapoints: []*cloudwatch.Datapoint{{Sum: aws.Float64(1)}},
+	}
+	input := &cloudwatch.GetMetricStatisticsInput{Namespace: aws.String("AWS/CloudFront"), MetricName: aws.String("Requests")}
+	result, err := PollMetric(context.Background(), svc, input, PollOptions{Timeout: 20 * time.Millisecond, Interval: time.Millisecond, MinDatapoints: 2})
+	require.Error(t, err)
+	assert.Equal(t, 1, result.Datapoints, "The partial result is returned with the error")
+	assert.Equal(t, float64(1), result.Sum)
+}
+
+func TestPollMetricReturnsAPIErrors(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeMetricStatisticsClient{err: errors.New("AccessDenied")}
+	result, err := PollMetric(context.Background(), svc, &cloudwatch.GetMetricStatisticsInput{}, PollOptions{Timeout: time.Second, Interval: time.Millisecond})
+	assert.EqualError(t, err, "AccessDenied")
+	assert.Equal(t, 1, result.Attempts, "API errors should not be retried")
+}
+
+type fakeMetricStatisticsClient struct {
+	cloudwatchiface.CloudWatchAPI
+	emptyCalls int
+	datapoints []*cloudwatch.Datapoint
+	err        error
+	calls      int
+	lastInput  *cloudwatch.GetMetricStatisticsInput
+}
+
+func (f *fakeMetricStatisticsClient) GetMetricStatisticsWithContext(ctx aws.Context, input *cloudwatch.GetMetricStatisticsInput, opts ...request.Option) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	f.calls++
+	f.lastInput = input
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.calls <= f.emptyCalls {
+		return &cloudwatch.GetMetricStatisticsOutput{}, nil
+	}
+	return &cloudwatch.GetMetricStatisticsOutput{Datapoints: f.datapoints}, nil
+}
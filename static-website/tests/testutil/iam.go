@@ -0,0 +1,11 @@
+package testutil
+
+// RoleServices maps each role the root module can create to the services that
+// may assume it, for testkit.AssertRoleTrust. The roles have fixed names, so
+// each is audited by the suite that already deploys it rather than a stack of
+// its own.
+var RoleServices = map[string][]string{
+	"aws_iam_role.firehose_role":             {"firehose.amazonaws.com"},
+	"module.edge_headers.aws_iam_role.this":  {"lambda.amazonaws.com", "edgelambda.amazonaws.com"},
+	"module.realtime_logs.aws_iam_role.this": {"cloudfront.amazonaws.com"},
+}
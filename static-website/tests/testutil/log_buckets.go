@@ -0,0 +1,103 @@
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// LogBucketOutputs name the versioned buckets that collect CloudFront and WAF logs
+var LogBucketOutputs = []string{"cloudfront_log_bucket_name", "waf_log_bucket_name"}
+
+// DeleteObjects accepts at most this many keys per request
+const maxDeleteBatch = 1000
+
+// DestroyWithLogBuckets destroys the stack after emptying its log buckets. Logs
+// delivered while the distribution is being deleted can land after the first
+// pass, so a failed destroy is retried once after emptying again.
+func DestroyWithLogBuckets(t testing.TB, terraformOptions *terraform.Options) {
+	var buckets []string
+	for _, output := range LogBucketOutputs {
+		// Outputs are missing when apply failed early; there is nothing to empty then
+		if bucket, err := terraform.OutputE(t, terraformOptions, output); err == nil && bucket != "" {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	s3Svc := s3.New(sess)
+	emptyAll := func() {
+		for _, bucket := range buckets {
+			deleted, err := EmptyBucket(s3Svc, bucket)
+			assert.NoError(t, err, "Emptying %s", bucket)
+			t.Logf("Deleted %d object versions from %s", deleted, bucket)
+		}
+	}
+
+	emptyAll()
+	if _, err := terraform.DestroyE(t, terraformOptions); err != nil {
+		t.Logf("Destroy failed, emptying log buckets again: %v", err)
+		emptyAll()
+		terraform.Destroy(t, terraformOptions)
+	}
+}
+
+// EmptyBucket deletes every object version and delete marker in a bucket,
+// returning how many it removed. The bucket owner can delete log objects regardless of which
+// delivery account wrote them, so no ACL changes are needed first.
+func EmptyBucket(svc s3iface.S3API, bucket string) (int, error) {
+	var objects []*s3.ObjectIdentifier
+	err := svc.ListObjectVersionsPages(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, version := range page.Versions {
+			objects = append(objects, &s3.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, &s3.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		return true
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchBucket {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for start := 0; start < len(objects); start += maxDeleteBatch {
+		end := start + maxDeleteBatch
+		if end > len(objects) {
+			end = len(objects)
+		}
+		result, err := svc.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{Objects: objects[start:end], Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deleted, err
+		}
+		if len(result.Errors) > 0 {
+			var failures []string
+			for _, failure := range result.Errors {
+				failures = append(failures, fmt.Sprintf("%s: %s", aws.StringValue(failure.Key), aws.StringValue(failure.Code)))
+			}
+			return deleted, fmt.Errorf("deleting objects from %s: %s", bucket, strings.Join(failures, ", "))
+		}
+		deleted += end - start
+	}
+	return deleted, nil
+}
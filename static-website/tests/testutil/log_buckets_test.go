@@ -0,0 +1,71 @@
+package testutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmptyBucket(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeVersionedBucketClient{}
+	for i := 0; i < maxDeleteBatch+1; i++ {
+		svc.versions = append(svc.versions, &s3.ObjectVersion{
+			Key:       aws.String(fmt.Sprintf("logs/%04d.gz", i)),
+			VersionId: aws.String(fmt.Sprintf("v%d", i)),
+		})
+	}
+	svc.markers = []*s3.DeleteMarkerEntry{{Key: aws.String("logs/0000.gz"), VersionId: aws.String("marker")}}
+
+	deleted, err := EmptyBucket(svc, "cloudfront-logs-abc123")
+	require.NoError(t, err)
+	assert.Equal(t, maxDeleteBatch+2, deleted, "Every version and delete marker should be removed")
+	assert.Equal(t, []int{maxDeleteBatch, 2}, svc.batchSizes)
+
+	// A bucket that is already gone needs no emptying
+	deleted, err = EmptyBucket(&fakeVersionedBucketClient{listErr: awserr.New(s3.ErrCodeNoSuchBucket, "gone", nil)}, "cloudfront-logs-abc123")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	// Per-key failures are surfaced rather than silently leaving objects behind
+	svc = &fakeVersionedBucketClient{
+		versions:     []*s3.ObjectVersion{{Key: aws.String("AWSLogs/log.gz"), VersionId: aws.String("v1")}},
+		deleteErrors: []*s3.Error{{Key: aws.String("AWSLogs/log.gz"), Code: aws.String("AccessDenied")}},
+	}
+	_, err = EmptyBucket(svc, "waf-logs-abc123")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AWSLogs/log.gz: AccessDenied")
+}
+
+type fakeVersionedBucketClient struct {
+	s3iface.S3API
+	versions     []*s3.ObjectVersion
+	markers      []*s3.DeleteMarkerEntry
+	listErr      error
+	deleteErrors []*s3.Error
+	batchSizes   []int
+}
+
+func (f *fakeVersionedBucketClient) ListObjectVersionsPages(input *s3.ListObjectVersionsInput, fn func(*s3.ListObjectVersionsOutput, bool) bool) error {
+	if f.listErr != nil {
+		return f.listErr
+	}
+	// Split versions over two pages like a large bucket would
+	half := len(f.versions) / 2
+	if fn(&s3.ListObjectVersionsOutput{Versions: f.versions[:half]}, false) {
+		fn(&s3.ListObjectVersionsOutput{Versions: f.versions[half:], DeleteMarkers: f.markers}, true)
+	}
+	return nil
+}
+
+func (f *fakeVersionedBucketClient) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	f.batchSizes = append(f.batchSizes, len(input.Delete.Objects))
+	return &s3.DeleteObjectsOutput{Errors: f.deleteErrors}, nil
+}
@@ -0,0 +1,53 @@
+package testutil
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// SkipIfWAFDisabled skips the test before anything is applied when the
+// options would leave the web ACL out, e.g. TF_VAR_enable_waf=false in
+// environments that only run WAF in production
+func SkipIfWAFDisabled(t testing.TB, terraformOptions *terraform.Options) {
+	if !WAFEnabled(terraformOptions, os.Getenv) {
+		t.Skip("WAF is disabled for this deployment")
+	}
+}
+
+// WAFEnabled mirrors the root module's waf_enabled local: enable_waf must be
+// on and serving_mode must be cloudfront. Each variable is read from Vars,
+// then EnvVars, then the environment, falling back to the module default.
+func WAFEnabled(terraformOptions *terraform.Options, getenv func(string) string) bool {
+	enabled := true
+	if value, ok := optionValue(terraformOptions, "enable_waf", getenv); ok {
+		parsed, err := strconv.ParseBool(value)
+		enabled = err != nil || parsed
+	}
+	servingMode := "cloudfront"
+	if value, ok := optionValue(terraformOptions, "serving_mode", getenv); ok {
+		servingMode = value
+	}
+	return enabled && servingMode == "cloudfront"
+}
+
+// Helper function to find the value terraform will see for a root variable
+func optionValue(terraformOptions *terraform.Options, name string, getenv func(string) string) (string, bool) {
+	if value, ok := terraformOptions.Vars[name]; ok {
+		if parsed, ok := value.(bool); ok {
+			return strconv.FormatBool(parsed), true
+		}
+		if parsed, ok := value.(string); ok {
+			return parsed, true
+		}
+	}
+	if value, ok := terraformOptions.EnvVars["TF_VAR_"+name]; ok {
+		return value, true
+	}
+	if value := getenv("TF_VAR_" + name); value != "" {
+		return value, true
+	}
+	return "", false
+}
@@ -0,0 +1,121 @@
+package testutil
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/stretchr/testify/assert"
+)
+
+// ManagedRuleGroupRef is how a web ACL rule uses a managed rule group
+type ManagedRuleGroupRef struct {
+	RuleName  string
+	CountOnly bool
+	// Rule name within the group to the action it was overridden to
+	Overrides map[string]string
+}
+
+// AssertManagedRuleGroups asserts a web ACL references exactly the expected managed
+// rule groups ("Vendor/Name"), none in count mode and with no rule overridden to
+// Count or Allow unless listed in allowedOverrides ("Vendor/Name/Rule" -> action)
+func AssertManagedRuleGroups(t testing.TB, webACL *wafv2.WebACL, expected []string, allowedOverrides map[string]string) {
+	for _, problem := range ManagedRuleGroupProblems(webACL, expected, allowedOverrides) {
+		assert.Fail(t, "WAF managed rule groups misconfigured", problem)
+	}
+}
+
+// ManagedRuleGroups collects a web ACL's managed rule groups keyed by "Vendor/Name"
+func ManagedRuleGroups(webACL *wafv2.WebACL) map[string]ManagedRuleGroupRef {
+	groups := map[string]ManagedRuleGroupRef{}
+	for _, rule := range webACL.Rules {
+		if rule.Statement == nil || rule.Statement.ManagedRuleGroupStatement == nil {
+			continue
+		}
+		statement := rule.Statement.ManagedRuleGroupStatement
+		ref := ManagedRuleGroupRef{
+			RuleName:  aws.StringValue(rule.Name),
+			CountOnly: rule.OverrideAction != nil && rule.OverrideAction.Count != nil,
+			Overrides: map[string]string{},
+		}
+		for _, excluded := range statement.ExcludedRules {
+			ref.Overrides[aws.StringValue(excluded.Name)] = "Count"
+		}
+		for _, override := range statement.RuleActionOverrides {
+			ref.Overrides[aws.StringValue(override.Name)] = RuleActionName(override.ActionToUse)
+		}
+		groups[aws.StringValue(statement.VendorName)+"/"+aws.StringValue(statement.Name)] = ref
+	}
+	return groups
+}
+
+// ManagedRuleGroupProblems lists differences from the expected managed rule groups
+// and any group or rule that was weakened to count or allow
+func ManagedRuleGroupProblems(webACL *wafv2.WebACL, expected []string, allowedOverrides map[string]string) []string {
+	groups := ManagedRuleGroups(webACL)
+	var problems []string
+
+	wanted := map[string]bool{}
+	for _, key := range expected {
+		wanted[key] = true
+		if _, ok := groups[key]; !ok {
+			problems = append(problems, "missing managed rule group "+key)
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !wanted[key] {
+			problems = append(problems, "unexpected managed rule group "+key)
+		}
+	}
+	for _, key := range keys {
+		ref := groups[key]
+		if ref.CountOnly {
+			problems = append(problems, fmt.Sprintf("rule %s only counts matches of %s", ref.RuleName, key))
+		}
+
+		rules := make([]string, 0, len(ref.Overrides))
+		for rule := range ref.Overrides {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		for _, rule := range rules {
+			action := ref.Overrides[rule]
+			if action != "Count" && action != "Allow" {
+				continue
+			}
+			if allowedOverrides[key+"/"+rule] == action {
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("rule %s overrides %s to %s", ref.RuleName, rule, action))
+		}
+	}
+	return problems
+}
+
+// RuleActionName names the action a rule action override uses
+func RuleActionName(action *wafv2.RuleAction) string {
+	switch {
+	case action == nil:
+		return ""
+	case action.Allow != nil:
+		return "Allow"
+	case action.Block != nil:
+		return "Block"
+	case action.Count != nil:
+		return "Count"
+	case action.Captcha != nil:
+		return "Captcha"
+	case action.Challenge != nil:
+		return "Challenge"
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagedRuleGroupProblems(t *testing.T) {
+	t.Parallel()
+
+	webACL := &wafv2.WebACL{Rules: []*wafv2.Rule{
+		{Name: aws.String("RateLimitRule"), Statement: &wafv2.Statement{RateBasedStatement: &wafv2.RateBasedStatement{Limit: aws.Int64(2000)}}},
+		managedRule("AWSCommonRuleSet", "AWSManagedRulesCommonRuleSet"),
+		managedRule("AWSSQLiRuleSet", "AWSManagedRulesSQLiRuleSet"),
+	}}
+	expected := []string{"AWS/AWSManagedRulesCommonRuleSet", "AWS/AWSManagedRulesKnownBadInputsRuleSet", "AWS/AWSManagedRulesSQLiRuleSet"}
+
+	groups := ManagedRuleGroups(webACL)
+	assert.Len(t, groups, 2, "Only managed rule group statements should be collected")
+	assert.Equal(t, "AWSSQLiRuleSet", groups["AWS/AWSManagedRulesSQLiRuleSet"].RuleName)
+	assert.Equal(t, []string{"missing managed rule group AWS/AWSManagedRulesKnownBadInputsRuleSet"}, ManagedRuleGroupProblems(webACL, expected, nil))
+
+	// An extra group, a group switched to count mode and a rule overridden to allow
+	webACL.Rules = append(webACL.Rules,
+		managedRule("AWSKnownBadInputsRuleSet", "AWSManagedRulesKnownBadInputsRuleSet"),
+		managedRule("AWSIpReputation", "AWSManagedRulesAmazonIpReputationList"),
+	)
+	webACL.Rules[2].OverrideAction = &wafv2.OverrideAction{Count: &wafv2.CountAction{}}
+	webACL.Rules[1].Statement.ManagedRuleGroupStatement.RuleActionOverrides = []*wafv2.RuleActionOverride{
+		{Name: aws.String("SizeRestrictions_BODY"), ActionToUse: &wafv2.RuleAction{Count: &wafv2.CountAction{}}},
+		{Name: aws.String("CrossSiteScripting_BODY"), ActionToUse: &wafv2.RuleAction{Allow: &wafv2.AllowAction{}}},
+		{Name: aws.String("NoUserAgent_HEADER"), ActionToUse: &wafv2.RuleAction{Block: &wafv2.BlockAction{}}},
+	}
+	assert.Equal(t, []string{
+		"unexpected managed rule group AWS/AWSManagedRulesAmazonIpReputationList",
+		"rule AWSCommonRuleSet overrides CrossSiteScripting_BODY to Allow",
+		"rule AWSCommonRuleSet overrides SizeRestrictions_BODY to Count",
+		"rule AWSSQLiRuleSet only counts matches of AWS/AWSManagedRulesSQLiRuleSet",
+	}, ManagedRuleGroupProblems(webACL, expected, nil))
+
+	// Deliberate overrides are allowed by group and rule name
+	allowed := map[string]string{"AWS/AWSManagedRulesCommonRuleSet/SizeRestrictions_BODY": "Count"}
+	assert.NotContains(t, ManagedRuleGroupProblems(webACL, expected, allowed), "rule AWSCommonRuleSet overrides SizeRestrictions_BODY to Count")
+
+	// Excluded rules are the legacy way of setting a rule to count
+	webACL.Rules[1].Statement.ManagedRuleGroupStatement.RuleActionOverrides = nil
+	webACL.Rules[1].Statement.ManagedRuleGroupStatement.ExcludedRules = []*wafv2.ExcludedRule{{Name: aws.String("GenericRFI_BODY")}}
+	assert.Contains(t, ManagedRuleGroupProblems(webACL, expected, nil), "rule AWSCommonRuleSet overrides GenericRFI_BODY to Count")
+}
+
+// Helper function to build a web ACL rule referencing an AWS managed rule group
+func managedRule(ruleName, groupName string) *wafv2.Rule {
+	return &wafv2.Rule{
+		Name:           aws.String(ruleName),
+		OverrideAction: &wafv2.OverrideAction{None: &wafv2.NoneAction{}},
+		Statement: &wafv2.Statement{ManagedRuleGroupStatement: &wafv2.ManagedRuleGroupStatement{
+			VendorName: aws.String("AWS"),
+			Name:       aws.String(groupName),
+		}},
+	}
+}
@@ -0,0 +1,30 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWAFEnabled(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	options := &terraform.Options{Vars: map[string]interface{}{}}
+	assert.True(t, WAFEnabled(options, getenv), "WAF is on by default")
+
+	env["TF_VAR_enable_waf"] = "false"
+	assert.False(t, WAFEnabled(options, getenv), "The environment can turn WAF off")
+
+	options.EnvVars = map[string]string{"TF_VAR_enable_waf": "true"}
+	assert.True(t, WAFEnabled(options, getenv), "EnvVars win over the environment")
+
+	options.Vars["enable_waf"] = false
+	assert.False(t, WAFEnabled(options, getenv), "Vars win over EnvVars")
+
+	options = &terraform.Options{Vars: map[string]interface{}{"serving_mode": "s3_website"}}
+	assert.False(t, WAFEnabled(options, getenv), "The S3 website endpoint has no web ACL")
+}
@@ -0,0 +1,69 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBucketCORSRules checks the bucket gets CORS rules whenever the headers
+// policy does, since S3 rejects preflights from any origin without them
+func TestBucketCORSRules(t *testing.T) {
+	t.Parallel()
+
+	const address = "module.website_bucket.aws_s3_bucket_cors_configuration.this[0]"
+
+	testCases := []struct {
+		name        string
+		vars        map[string]interface{}
+		wantMethods []interface{}
+	}{
+		{
+			name:        "default methods",
+			vars:        map[string]interface{}{"cors_allowed_origins": []string{"https://app.example.com"}},
+			wantMethods: []interface{}{"GET", "HEAD"},
+		},
+		{
+			name: "all methods",
+			vars: map[string]interface{}{
+				"cors_allowed_origins":       []string{"https://app.example.com"},
+				"cors_allowed_methods":       []string{"ALL"},
+				"cloudfront_allowed_methods": []string{"DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT"},
+			},
+			wantMethods: []interface{}{"DELETE", "GET", "HEAD", "POST", "PUT"},
+		},
+		{
+			name: "off",
+			vars: map[string]interface{}{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.vars["domain_name"] = "cors-plan-test.example.com"
+			terraformOptions := &terraform.Options{
+				TerraformDir: "../../",
+				Vars:         tc.vars,
+			}
+
+			plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+			if tc.wantMethods == nil {
+				assert.NotContains(t, plan.ResourcePlannedValuesMap, address, "No origins should mean no CORS rules")
+				return
+			}
+
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+			rules, ok := plan.ResourcePlannedValuesMap[address].AttributeValues["cors_rule"].([]interface{})
+			require.True(t, ok)
+			require.Len(t, rules, 1)
+			rule := rules[0].(map[string]interface{})
+			assert.ElementsMatch(t, tc.wantMethods, rule["allowed_methods"])
+			assert.Equal(t, []interface{}{"https://app.example.com"}, rule["allowed_origins"])
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"testkit"
+)
+
+// websiteEnvironment is the price class, WAF rate limit and log retention
+// each environment picks
+type websiteEnvironment struct {
+	PriceClass       string
+	RateLimit        float64
+	LogLifecycleDays float64
+}
+
+func TestEnvironmentPlans(t *testing.T) {
+	t.Parallel()
+
+	baseVars := map[string]interface{}{"domain_name": "environments-test.example.com"}
+	want := map[string]websiteEnvironment{
+		"dev":     {PriceClass: "PriceClass_100", RateLimit: 500, LogLifecycleDays: 30},
+		"staging": {PriceClass: "PriceClass_200", RateLimit: 1000, LogLifecycleDays: 90},
+		"prod":    {PriceClass: "PriceClass_All", RateLimit: 2000, LogLifecycleDays: 365},
+	}
+
+	testkit.RunEnvironmentPlans(t, "../../", baseVars, want, func(t *testing.T, env string, plan *terraform.PlanStruct) websiteEnvironment {
+		var got websiteEnvironment
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.cloudfront[0].aws_cloudfront_distribution.this")
+		got.PriceClass, _ = plan.ResourcePlannedValuesMap["module.cloudfront[0].aws_cloudfront_distribution.this"].AttributeValues["price_class"].(string)
+
+		outputs := plan.RawPlan.PlannedValues.Outputs
+		got.RateLimit, _ = outputs["waf_rate_limit"].Value.(float64)
+		got.LogLifecycleDays, _ = outputs["cloudfront_log_retention_days"].Value.(float64)
+		return got
+	})
+}
+
+func TestEnvironmentFiles(t *testing.T) {
+	t.Parallel()
+
+	testkit.AssertEnvironmentFiles(t, "../../")
+}
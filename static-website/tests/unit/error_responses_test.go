@@ -0,0 +1,166 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
+)
+
+// Error responses the module configures when custom_error_responses is unset
+var spaErrorResponses = []*cloudfront.CustomErrorResponse{
+	{ErrorCode: aws.Int64(404), ResponseCode: aws.String("200"), ResponsePagePath: aws.String("/index.html"), ErrorCachingMinTTL: aws.Int64(10)},
+}
+
+// TestCustomErrorResponses checks the default SPA mapping reaches the
+// distribution as configured
+func TestCustomErrorResponses(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "error-responses-test.example.com",
+		},
+	}
+
+	testkit.AcquireApplySlot(t)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	assert.Equal(t, "1", terraform.Output(t, terraformOptions, "custom_error_response_count"))
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	result, err := cloudfront.New(sess).GetDistribution(&cloudfront.GetDistributionInput{
+		Id: aws.String(terraform.Output(t, terraformOptions, "cloudfront_distribution_id")),
+	})
+	require.NoError(t, err)
+	for _, problem := range errorResponseProblems(result.Distribution.DistributionConfig, spaErrorResponses) {
+		assert.Fail(t, "Distribution error responses differ from the SPA defaults", problem)
+	}
+}
+
+// TestCustomErrorResponsesValidation checks entries CloudFront would reject
+// fail at plan time
+func TestCustomErrorResponsesValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		responses []map[string]interface{}
+		message   string
+	}{
+		{
+			name:      "uncustomizable status",
+			responses: []map[string]interface{}{{"error_code": 429}},
+			message:   "error_code must be one CloudFront can customize",
+		},
+		{
+			name:      "duplicate error code",
+			responses: []map[string]interface{}{{"error_code": 404}, {"error_code": 404, "response_code": 200, "response_page_path": "/index.html"}},
+			message:   "only one entry per error_code",
+		},
+		{
+			name:      "relative page path",
+			responses: []map[string]interface{}{{"error_code": 404, "response_code": 200, "response_page_path": "index.html"}},
+			message:   "response_page_path must start with /",
+		},
+		{
+			name:      "page without response code",
+			responses: []map[string]interface{}{{"error_code": 404, "response_page_path": "/index.html"}},
+			message:   "set together with response_code",
+		},
+	}
+
+	for _, tc := range testCases {
+		terraformOptions := &terraform.Options{
+			TerraformDir: "../../",
+			Vars: map[string]interface{}{
+				"domain_name":            "error-responses-test.example.com",
+				"custom_error_responses": tc.responses,
+			},
+		}
+		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, tc.name)
+		assert.Contains(t, err.Error(), tc.message, tc.name)
+	}
+}
+
+func TestErrorResponseProblems(t *testing.T) {
+	t.Parallel()
+
+	// An SPA mapping that opts in to rewriting 403s
+	want := []*cloudfront.CustomErrorResponse{
+		{ErrorCode: aws.Int64(403), ResponseCode: aws.String("200"), ResponsePagePath: aws.String("/index.html"), ErrorCachingMinTTL: aws.Int64(10)},
+		{ErrorCode: aws.Int64(404), ResponseCode: aws.String("200"), ResponsePagePath: aws.String("/index.html"), ErrorCachingMinTTL: aws.Int64(10)},
+	}
+	config := &cloudfront.DistributionConfig{CustomErrorResponses: &cloudfront.CustomErrorResponses{
+		Items: []*cloudfront.CustomErrorResponse{
+			// CloudFront lists them in its own order
+			{ErrorCode: aws.Int64(404), ResponseCode: aws.String("200"), ResponsePagePath: aws.String("/index.html"), ErrorCachingMinTTL: aws.Int64(10)},
+			{ErrorCode: aws.Int64(403), ResponseCode: aws.String("200"), ResponsePagePath: aws.String("/index.html"), ErrorCachingMinTTL: aws.Int64(10)},
+		},
+		Quantity: aws.Int64(2),
+	}}
+	assert.Empty(t, errorResponseProblems(config, want))
+
+	config.CustomErrorResponses.Items[1].ResponseCode = aws.String("404")
+	config.CustomErrorResponses.Items[1].ResponsePagePath = aws.String("/error.html")
+	config.CustomErrorResponses.Items = append(config.CustomErrorResponses.Items, &cloudfront.CustomErrorResponse{ErrorCode: aws.Int64(500)})
+	assert.Equal(t, []string{
+		`403 responds with 404 "/error.html", not 200 "/index.html"`,
+		"500 has an unexpected error response",
+	}, errorResponseProblems(config, want))
+
+	assert.Equal(t, []string{"403 has no error response", "404 has no error response"}, errorResponseProblems(&cloudfront.DistributionConfig{}, want))
+}
+
+// Helper function to list how a distribution's custom error responses differ
+// from want, matching them up by error code
+func errorResponseProblems(config *cloudfront.DistributionConfig, want []*cloudfront.CustomErrorResponse) []string {
+	got := map[int64]*cloudfront.CustomErrorResponse{}
+	var order []int64
+	if config.CustomErrorResponses != nil {
+		for _, response := range config.CustomErrorResponses.Items {
+			got[aws.Int64Value(response.ErrorCode)] = response
+			order = append(order, aws.Int64Value(response.ErrorCode))
+		}
+	}
+
+	var problems []string
+	expected := map[int64]bool{}
+	for _, response := range want {
+		code := aws.Int64Value(response.ErrorCode)
+		expected[code] = true
+		actual, ok := got[code]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%d has no error response", code))
+			continue
+		}
+		if aws.StringValue(actual.ResponseCode) != aws.StringValue(response.ResponseCode) || aws.StringValue(actual.ResponsePagePath) != aws.StringValue(response.ResponsePagePath) {
+			problems = append(problems, fmt.Sprintf("%d responds with %s %q, not %s %q", code,
+				aws.StringValue(actual.ResponseCode), aws.StringValue(actual.ResponsePagePath),
+				aws.StringValue(response.ResponseCode), aws.StringValue(response.ResponsePagePath)))
+		}
+		if response.ErrorCachingMinTTL != nil && aws.Int64Value(actual.ErrorCachingMinTTL) != aws.Int64Value(response.ErrorCachingMinTTL) {
+			problems = append(problems, fmt.Sprintf("%d caches errors for %ds, not %ds", code,
+				aws.Int64Value(actual.ErrorCachingMinTTL), aws.Int64Value(response.ErrorCachingMinTTL)))
+		}
+	}
+	for _, code := range order {
+		if !expected[code] {
+			problems = append(problems, fmt.Sprintf("%d has an unexpected error response", code))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,48 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCustomerManagedKeyPlan checks create_kms_key encrypts website objects
+// with a rotated key while the log buckets stay on SSE-S3, which CloudFront
+// standard logging needs
+func TestCustomerManagedKeyPlan(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":    "kms-plan-test.example.com",
+			"create_kms_key": true,
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	assert.Equal(t, "aws:kms", plan.RawPlan.PlannedValues.Outputs["s3_sse_algorithm"].Value)
+
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.kms[0].aws_kms_key.this")
+	key := plan.ResourcePlannedValuesMap["module.kms[0].aws_kms_key.this"].AttributeValues
+	assert.Equal(t, true, key["enable_key_rotation"], "Key should rotate")
+
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.website_bucket.aws_s3_bucket_server_side_encryption_configuration.this")
+	encryption := plan.ResourcePlannedValuesMap["module.website_bucket.aws_s3_bucket_server_side_encryption_configuration.this"].AttributeValues
+	rule := encryption["rule"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, rule["bucket_key_enabled"], "Bucket keys should cut KMS requests")
+	assert.Equal(t, "aws:kms", nestedBlockValue(rule, "apply_server_side_encryption_by_default", "sse_algorithm"))
+
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.cloudfront_logs.aws_s3_bucket_server_side_encryption_configuration.this")
+	logEncryption := plan.ResourcePlannedValuesMap["module.cloudfront_logs.aws_s3_bucket_server_side_encryption_configuration.this"].AttributeValues
+	logRule := logEncryption["rule"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "AES256", nestedBlockValue(logRule, "apply_server_side_encryption_by_default", "sse_algorithm"))
+
+	// Both key sources at once is ambiguous
+	terraformOptions.Vars["kms_key_arn"] = "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not both")
+}
@@ -0,0 +1,92 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLifecycleRules plans the default and a custom rule set and checks the
+// storage classes each rule transitions to
+func TestLifecycleRules(t *testing.T) {
+	t.Parallel()
+
+	const address = "module.website_bucket.aws_s3_bucket_lifecycle_configuration.this[0]"
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "lifecycle-test.example.com",
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+	rules := plan.ResourcePlannedValuesMap[address].AttributeValues["rule"].([]interface{})
+	require.Len(t, rules, 1)
+	defaultRule := rules[0].(map[string]interface{})
+	assert.Equal(t, "noncurrent-versions", defaultRule["id"])
+	assert.Equal(t, "Enabled", defaultRule["status"])
+	assert.Equal(t, "STANDARD_IA", nestedBlockValue(defaultRule, "noncurrent_version_transition", "storage_class"))
+	assert.Equal(t, float64(30), nestedBlockValue(defaultRule, "noncurrent_version_transition", "noncurrent_days"))
+	assert.Equal(t, float64(90), nestedBlockValue(defaultRule, "noncurrent_version_expiration", "noncurrent_days"))
+	assert.Empty(t, defaultRule["transition"], "Current versions stay in STANDARD by default")
+
+	terraformOptions.Vars["lifecycle_rules"] = []map[string]interface{}{
+		{"id": "archive-reports", "prefix": "reports/", "transition_days": 90, "transition_storage_class": "GLACIER", "expiration_days": 365},
+	}
+	plan = terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+	rules = plan.ResourcePlannedValuesMap[address].AttributeValues["rule"].([]interface{})
+	require.Len(t, rules, 1)
+	archiveRule := rules[0].(map[string]interface{})
+	assert.Equal(t, "reports/", nestedBlockValue(archiveRule, "filter", "prefix"))
+	assert.Equal(t, "GLACIER", nestedBlockValue(archiveRule, "transition", "storage_class"))
+	assert.Equal(t, float64(365), nestedBlockValue(archiveRule, "expiration", "days"))
+	assert.Empty(t, archiveRule["noncurrent_version_transition"])
+	assert.Equal(t, []interface{}{"archive-reports"}, plan.RawPlan.PlannedValues.Outputs["s3_lifecycle_rule_ids"].Value)
+}
+
+// TestLifecycleRulesValidation checks rules with an unknown storage class or a
+// transition without one are rejected
+func TestLifecycleRulesValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		rule map[string]interface{}
+		want string
+	}{
+		"unknown storage class": {
+			rule: map[string]interface{}{"id": "cold", "transition_days": 30, "transition_storage_class": "COLD"},
+			want: "transition_storage_class must be STANDARD_IA",
+		},
+		"transition without storage class": {
+			rule: map[string]interface{}{"id": "cold", "transition_days": 30},
+			want: "must be set together with transition_days",
+		},
+		"infrequent access too soon": {
+			rule: map[string]interface{}{"id": "ia", "transition_days": 7, "transition_storage_class": "STANDARD_IA"},
+			want: "need at least 30 days",
+		},
+		"one zone noncurrent too soon": {
+			rule: map[string]interface{}{"id": "ia", "noncurrent_version_transition_days": 29, "transition_storage_class": "ONEZONE_IA"},
+			want: "need at least 30 days",
+		},
+	}
+
+	for name, tc := range testCases {
+		terraformOptions := &terraform.Options{
+			TerraformDir: "../../",
+			Vars: map[string]interface{}{
+				"domain_name":     "lifecycle-validation-test.example.com",
+				"lifecycle_rules": []map[string]interface{}{tc.rule},
+			},
+		}
+
+		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), tc.want, name)
+	}
+}
@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPriceClassRoundTrip checks a non-default price class reaches both the
+// distribution and the cloudfront_price_class output
+func TestPriceClassRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "price-class-test.example.com",
+			"price_class": "PriceClass_All",
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	assert.Equal(t, "PriceClass_All", plan.RawPlan.PlannedValues.Outputs["cloudfront_price_class"].Value)
+
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.cloudfront[0].aws_cloudfront_distribution.this")
+	distribution := plan.ResourcePlannedValuesMap["module.cloudfront[0].aws_cloudfront_distribution.this"].AttributeValues
+	assert.Equal(t, "PriceClass_All", distribution["price_class"])
+}
+
+// TestPriceClassValidation checks price classes CloudFront doesn't offer are
+// rejected. terraform validate doesn't take variable values, so the check runs
+// at plan, before anything is refreshed.
+func TestPriceClassValidation(t *testing.T) {
+	t.Parallel()
+
+	for _, priceClass := range []string{"PriceClass_300", "priceclass_100", ""} {
+		terraformOptions := &terraform.Options{
+			TerraformDir: "../../",
+			Vars: map[string]interface{}{
+				"domain_name": "price-class-validation-test.example.com",
+				"price_class": priceClass,
+			},
+		}
+
+		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, priceClass)
+		assert.Contains(t, err.Error(), "price_class must be PriceClass_100, PriceClass_200 or PriceClass_All", priceClass)
+	}
+}
@@ -0,0 +1,66 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"testkit"
+)
+
+// websiteProfile is what each bundled toggle shows in a static-website plan
+type websiteProfile struct {
+	CustomerManagedKey bool
+	BodySizeRule       bool
+	LogLifecycleDays   float64
+}
+
+func TestProfilePlans(t *testing.T) {
+	t.Parallel()
+
+	baseVars := map[string]interface{}{"domain_name": "profile-test.example.com"}
+	cases := []testkit.ProfileCase[websiteProfile]{
+		{
+			Name: "minimal",
+			Vars: map[string]interface{}{"profile": "minimal"},
+			Want: websiteProfile{LogLifecycleDays: 365},
+		},
+		{
+			Name: "hardened",
+			Vars: map[string]interface{}{"profile": "hardened"},
+			Want: websiteProfile{CustomerManagedKey: true, BodySizeRule: true, LogLifecycleDays: 731},
+		},
+		{
+			Name: "hardened with overrides",
+			Vars: map[string]interface{}{
+				"profile":               "hardened",
+				"enable_body_size_rule": false,
+				"log_lifecycle_days":    90,
+			},
+			Want: websiteProfile{CustomerManagedKey: true, LogLifecycleDays: 90},
+		},
+	}
+
+	testkit.RunProfilePlans(t, "../../", baseVars, cases, func(t *testing.T, plan *terraform.PlanStruct) websiteProfile {
+		var got websiteProfile
+		outputs := plan.RawPlan.PlannedValues.Outputs
+
+		_, got.CustomerManagedKey = plan.ResourcePlannedValuesMap["module.kms[0].aws_kms_key.this"]
+
+		ruleNames, _ := outputs["waf_rule_names"].Value.([]interface{})
+		got.BodySizeRule = containsValue(ruleNames, "BodySizeRule")
+
+		got.LogLifecycleDays, _ = outputs["cloudfront_log_retention_days"].Value.(float64)
+		return got
+	})
+}
+
+// Helper function to check whether a planned list holds a value
+func containsValue(values []interface{}, want interface{}) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
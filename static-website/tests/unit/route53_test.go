@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoute53AliasWiring plans the alias records against an existing zone and
+// checks they target the distribution rather than some other endpoint
+func TestRoute53AliasWiring(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":    "route53-test.example.com",
+			"hosted_zone_id": "Z0123456789EXAMPLE",
+			"enable_ipv6":    true,
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+
+	for address, recordType := range map[string]string{
+		"module.route53[0].aws_route53_record.alias":         "A",
+		"module.route53[0].aws_route53_record.alias_ipv6[0]": "AAAA",
+	} {
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+		record := plan.ResourcePlannedValuesMap[address].AttributeValues
+		assert.Equal(t, "route53-test.example.com", record["name"], "%s name", address)
+		assert.Equal(t, recordType, record["type"], "%s type", address)
+		assert.Equal(t, "Z0123456789EXAMPLE", record["zone_id"], "%s zone", address)
+	}
+	_, createsZone := plan.ResourcePlannedValuesMap["module.route53[0].aws_route53_zone.this[0]"]
+	assert.False(t, createsZone, "An existing zone should be used as is")
+
+	// The alias target is only known after apply, so check where it comes from
+	require.NotNil(t, plan.RawPlan.Config, "Plan should include its configuration")
+	route53Call := plan.RawPlan.Config.RootModule.ModuleCalls["route53"]
+	require.NotNil(t, route53Call)
+	assert.Equal(t, []string{"module.cloudfront[0].distribution_domain_name"},
+		moduleInputReferences(route53Call.Expressions, "distribution_domain_name"))
+	assert.Equal(t, []string{"module.cloudfront[0].distribution_hosted_zone_id"},
+		moduleInputReferences(route53Call.Expressions, "distribution_hosted_zone_id"))
+
+	// The certificate is validated in the same zone the alias lives in
+	_, validates := plan.ResourcePlannedValuesMap["module.cloudfront[0].aws_acm_certificate_validation.cert[0]"]
+	assert.True(t, validates, "Certificate should be validated when a zone is available")
+}
+
+// TestRoute53CreateZone checks create_zone plans a zone for the domain and
+// refuses to be combined with an existing hosted_zone_id
+func TestRoute53CreateZone(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "route53-zone-test.example.com",
+			"create_zone": true,
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.route53[0].aws_route53_zone.this[0]")
+	zone := plan.ResourcePlannedValuesMap["module.route53[0].aws_route53_zone.this[0]"].AttributeValues
+	assert.Equal(t, "route53-zone-test.example.com", zone["name"])
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.route53[0].aws_route53_record.alias")
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.cloudfront[0].aws_acm_certificate_validation.cert[0]")
+
+	terraformOptions.Vars["hosted_zone_id"] = "Z0123456789EXAMPLE"
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not both")
+}
+
+// TestCertificateValidationOptOut checks validate_certificate = false keeps the
+// alias record and the custom domain but leaves ACM validation to others
+func TestCertificateValidationOptOut(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":          "cert-opt-out-test.example.com",
+			"hosted_zone_id":       "Z0123456789EXAMPLE",
+			"validate_certificate": false,
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.route53[0].aws_route53_record.alias")
+	for address := range plan.ResourcePlannedValuesMap {
+		assert.NotContains(t, address, "cert_validation", "No validation records should be planned")
+		assert.NotContains(t, address, "aws_acm_certificate_validation", "Issuance should not be awaited")
+	}
+
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.cloudfront[0].aws_cloudfront_distribution.this")
+	distribution := plan.ResourcePlannedValuesMap["module.cloudfront[0].aws_cloudfront_distribution.this"].AttributeValues
+	assert.Equal(t, []interface{}{"cert-opt-out-test.example.com"}, distribution["aliases"], "The domain is served once the certificate is issued out of band")
+}
+
+func TestModuleInputReferences(t *testing.T) {
+	t.Parallel()
+
+	expressions := map[string]*tfjson.Expression{
+		"distribution_domain_name": {ExpressionData: &tfjson.ExpressionData{References: []string{
+			"module.cloudfront[0].distribution_domain_name",
+			"module.cloudfront[0]",
+			"module.cloudfront",
+		}}},
+		"domain_name": {ExpressionData: &tfjson.ExpressionData{References: []string{"var.domain_name"}}},
+		"enable_ipv6": {ExpressionData: &tfjson.ExpressionData{ConstantValue: true}},
+	}
+	assert.Equal(t, []string{"module.cloudfront[0].distribution_domain_name"}, moduleInputReferences(expressions, "distribution_domain_name"))
+	assert.Equal(t, []string{"var.domain_name"}, moduleInputReferences(expressions, "domain_name"))
+	assert.Nil(t, moduleInputReferences(expressions, "enable_ipv6"))
+	assert.Nil(t, moduleInputReferences(expressions, "zone_id"))
+}
+
+// Helper function to list the references a module call input is built from,
+// dropping the bare module prefixes Terraform reports alongside each one
+func moduleInputReferences(expressions map[string]*tfjson.Expression, input string) []string {
+	expression, ok := expressions[input]
+	if !ok || expression == nil || expression.ExpressionData == nil {
+		return nil
+	}
+
+	var references []string
+	for _, reference := range expression.References {
+		if strings.HasPrefix(reference, "module.") && strings.Count(reference, ".") < 2 {
+			continue
+		}
+		references = append(references, reference)
+	}
+	return references
+}
@@ -0,0 +1,125 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestServingModePlans plans each serving mode and checks which front end is
+// created and whether the bucket is opened to the public
+func TestServingModePlans(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		mode         string
+		distribution bool
+		publicPolicy bool
+	}{
+		{mode: "cloudfront", distribution: true, publicPolicy: false},
+		{mode: "s3_website", distribution: false, publicPolicy: true},
+	}
+
+	// Every plan shares the module's .terraform directory, so modes run in turn
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.mode, func(t *testing.T) {
+			terraformOptions := &terraform.Options{
+				TerraformDir: "../../",
+				Vars: map[string]interface{}{
+					"domain_name":    tc.mode + "-serving-mode-test.example.com",
+					"serving_mode":   tc.mode,
+					"index_document": "home.html",
+					"error_document": "errors/404.html",
+				},
+			}
+
+			plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+
+			_, hasDistribution := plan.ResourcePlannedValuesMap["module.cloudfront[0].aws_cloudfront_distribution.this"]
+			assert.Equal(t, tc.distribution, hasDistribution, "CloudFront distribution in %s mode", tc.mode)
+			_, hasWebACL := plan.ResourcePlannedValuesMap["module.waf[0].aws_wafv2_web_acl.this"]
+			assert.Equal(t, tc.distribution, hasWebACL, "Web ACL in %s mode", tc.mode)
+
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.website_bucket.aws_s3_bucket_public_access_block.this")
+			block := plan.ResourcePlannedValuesMap["module.website_bucket.aws_s3_bucket_public_access_block.this"].AttributeValues
+			assert.Equal(t, !tc.publicPolicy, block["block_public_policy"], "block_public_policy in %s mode", tc.mode)
+			assert.Equal(t, !tc.publicPolicy, block["restrict_public_buckets"], "restrict_public_buckets in %s mode", tc.mode)
+			assert.Equal(t, true, block["block_public_acls"], "ACLs stay blocked in every mode")
+
+			terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.website_bucket.aws_s3_bucket_website_configuration.this")
+			website := plan.ResourcePlannedValuesMap["module.website_bucket.aws_s3_bucket_website_configuration.this"].AttributeValues
+			assert.Equal(t, "home.html", nestedBlockValue(website, "index_document", "suffix"))
+			assert.Equal(t, "errors/404.html", nestedBlockValue(website, "error_document", "key"))
+
+			outputs := plan.RawPlan.PlannedValues.Outputs
+			assert.Equal(t, tc.mode, outputs["serving_mode"].Value)
+			assert.Equal(t, tc.distribution, outputs["waf_enabled"].Value, "WAF needs CloudFront")
+		})
+	}
+}
+
+// TestServingModeRejectsCloudFrontFeatures checks the plan fails when
+// s3_website mode is combined with settings that only work on a distribution
+func TestServingModeRejectsCloudFrontFeatures(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]map[string]interface{}{
+		"continuous deployment": {"enable_cd": true},
+		"lambda@edge headers":   {"edge_headers_mode": "lambda_edge"},
+		"cors":                  {"cors_allowed_origins": []string{"https://app.example.com"}},
+		"shield advanced":       {"enable_shield_advanced": true},
+		"hosted zone":           {"create_zone": true},
+		"customer-managed key":  {"create_kms_key": true},
+	}
+
+	for name, vars := range testCases {
+		vars["domain_name"] = "rejects-serving-mode-test.example.com"
+		vars["serving_mode"] = "s3_website"
+		terraformOptions := &terraform.Options{
+			TerraformDir: "../../",
+			Vars:         vars,
+		}
+
+		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "they need CloudFront", name)
+	}
+
+	_, err := terraform.InitAndPlanE(t, &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":  "rejects-serving-mode-test.example.com",
+			"serving_mode": "s3",
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "serving_mode must be cloudfront or s3_website")
+}
+
+func TestNestedBlockValue(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]interface{}{
+		"index_document": []interface{}{map[string]interface{}{"suffix": "index.html"}},
+		"routing_rule":   []interface{}{},
+	}
+	assert.Equal(t, "index.html", nestedBlockValue(values, "index_document", "suffix"))
+	assert.Nil(t, nestedBlockValue(values, "routing_rule", "condition"))
+	assert.Nil(t, nestedBlockValue(values, "error_document", "key"))
+}
+
+// Helper function to read an attribute of the first nested block in planned values
+func nestedBlockValue(values map[string]interface{}, block, attribute string) interface{} {
+	blocks, ok := values[block].([]interface{})
+	if !ok || len(blocks) == 0 {
+		return nil
+	}
+	first, ok := blocks[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return first[attribute]
+}
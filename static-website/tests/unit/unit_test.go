@@ -1,10 +1,20 @@
 package unit
 
 import (
+	"fmt"
+	"sort"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
 )
 
 func TestStaticWebsiteModuleCreation(t *testing.T) {
@@ -17,7 +27,8 @@ func TestStaticWebsiteModuleCreation(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	testkit.AcquireApplySlot(t)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Test CloudFront distribution creation
@@ -41,20 +52,22 @@ func TestStaticWebsiteTagging(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	testkit.AcquireApplySlot(t)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Test that resources are properly tagged
-	// Since we can't directly access resource tags, we verify the outputs exist
-	// which indicates the resources were created with proper tagging
-	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
-	s3BucketName := terraform.Output(t, terraformOptions, "s3_bucket_name")
-
-	assert.NotEmpty(t, cloudfrontDomain, "CloudFront distribution should be tagged")
-	assert.NotEmpty(t, s3BucketName, "S3 bucket should be tagged")
+	// Test that resources carry the project tags
+	expectedTags := map[string]string{
+		"Environment": "production",
+		"Project":     "static-website",
+		"ManagedBy":   "Terraform",
+	}
 
-	// Verify tagging consistency by checking outputs are present
-	assert.Contains(t, s3BucketName, "test.example.com", "Bucket name should reflect domain for tagging consistency")
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	assertTags(t, sess, terraform.Output(t, terraformOptions, "s3_bucket_arn"), expectedTags)
+	assertTags(t, sess, terraform.Output(t, terraformOptions, "cloudfront_distribution_arn"), expectedTags)
 }
 
 func TestStaticWebsiteOutputs(t *testing.T) {
@@ -67,7 +80,8 @@ func TestStaticWebsiteOutputs(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	testkit.AcquireApplySlot(t)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Test all required outputs are present
@@ -91,7 +105,8 @@ func TestStaticWebsiteConfiguration(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	testkit.AcquireApplySlot(t)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Test configuration variables are applied correctly
@@ -118,7 +133,8 @@ func TestStaticWebsiteResourceDependencies(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	testkit.AcquireApplySlot(t)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Test that all dependent resources are created
@@ -143,7 +159,8 @@ func TestStaticWebsiteErrorHandling(t *testing.T) {
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	testkit.AcquireApplySlot(t)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
 	terraform.InitAndApply(t, terraformOptions)
 
 	// Verify normal operation works
@@ -164,25 +181,111 @@ func TestStaticWebsiteErrorHandling(t *testing.T) {
 func TestStaticWebsiteInvalidConfiguration(t *testing.T) {
 	t.Parallel()
 
-	// Test with invalid rate limit (should still work but log warning)
-	terraformOptions := &terraform.Options{
-		TerraformDir: "../../",
-		Vars: map[string]interface{}{
-			"domain_name": "invalid-test.example.com",
-			"rate_limit":  0, // Invalid rate limit
+	// Rate limits outside 100-20000 are rejected before anything is created
+	for _, rateLimit := range []int{0, 99, 20001} {
+		terraformOptions := &terraform.Options{
+			TerraformDir: "../../",
+			Vars: map[string]interface{}{
+				"domain_name": "invalid-test.example.com",
+				"rate_limit":  rateLimit,
+			},
+		}
+
+		_, err := terraform.InitAndPlanE(t, terraformOptions)
+		require.Error(t, err, "rate_limit %d", rateLimit)
+		assert.Contains(t, err.Error(), "rate_limit must be between 100 and 20000", "rate_limit %d", rateLimit)
+	}
+}
+
+func TestMissingTags(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeTaggingClient{
+		tags: []*resourcegroupstaggingapi.Tag{
+			{Key: aws.String("Name"), Value: aws.String("static-website")},
+			{Key: aws.String("Environment"), Value: aws.String("test")},
+			{Key: aws.String("Extra"), Value: aws.String("ignored")},
 		},
 	}
 
-	defer terraform.Destroy(t, terraformOptions)
+	got, err := resourceTags(svc, "arn:aws:cloudfront::123456789012:distribution/E2EXAMPLE1234")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arn:aws:cloudfront::123456789012:distribution/E2EXAMPLE1234"}, aws.StringValueSlice(svc.lastInput.ResourceARNList))
+
+	// Extra tags on the resource are fine; missing or different values are not
+	assert.Empty(t, missingTags(got, map[string]string{"Name": "static-website", "Environment": "test"}))
+	assert.Equal(t, []string{
+		`Environment="prod" (got "test")`,
+		`Owner="platform" (missing)`,
+	}, missingTags(got, map[string]string{"Environment": "prod", "Owner": "platform"}))
 
-	// This should still succeed as Terraform may have defaults or validation
-	_, err := terraform.InitAndApplyE(t, terraformOptions)
+	// An ARN the tagging API doesn't know about is an error, not an empty tag set
+	_, err = resourceTags(&fakeTaggingClient{}, "arn:aws:cloudfront::123456789012:distribution/EMISSING")
+	assert.Error(t, err)
+}
+
+type fakeTaggingClient struct {
+	resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
+	tags      []*resourcegroupstaggingapi.Tag
+	lastInput *resourcegroupstaggingapi.GetResourcesInput
+}
+
+func (f *fakeTaggingClient) GetResources(input *resourcegroupstaggingapi.GetResourcesInput) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	f.lastInput = input
+	if f.tags == nil {
+		return &resourcegroupstaggingapi.GetResourcesOutput{}, nil
+	}
+	return &resourcegroupstaggingapi.GetResourcesOutput{
+		ResourceTagMappingList: []*resourcegroupstaggingapi.ResourceTagMapping{
+			{
+				ResourceARN: input.ResourceARNList[0],
+				Tags:        f.tags,
+			},
+		},
+	}, nil
+}
+
+// Helper function to assert a resource carries at least the expected tags
+func assertTags(t *testing.T, sess *session.Session, arn string, want map[string]string) {
+	got, err := resourceTags(resourcegroupstaggingapi.New(sess), arn)
+	require.NoError(t, err)
+	assert.Empty(t, missingTags(got, want), "Resource %s is missing expected tags", arn)
+}
+
+// Helper function to fetch a resource's tags through the Resource Groups Tagging API
+func resourceTags(svc resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI, arn string) (map[string]string, error) {
+	result, err := svc.GetResources(&resourcegroupstaggingapi.GetResourcesInput{
+		ResourceARNList: []*string{aws.String(arn)},
+	})
 	if err != nil {
-		t.Logf("Terraform apply failed as expected with invalid config: %v", err)
-		return
+		return nil, err
 	}
 
-	// If it succeeds, verify basic functionality still works
-	cloudfrontDomain := terraform.Output(t, terraformOptions, "cloudfront_domain")
-	assert.NotEmpty(t, cloudfrontDomain, "CloudFront should still be created even with invalid rate limit")
+	for _, mapping := range result.ResourceTagMappingList {
+		if aws.StringValue(mapping.ResourceARN) != arn {
+			continue
+		}
+		tags := map[string]string{}
+		for _, tag := range mapping.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		return tags, nil
+	}
+	return nil, fmt.Errorf("no tags found for %s", arn)
+}
+
+// Helper function to list expected tags that are absent or have a different value
+func missingTags(got, want map[string]string) []string {
+	var missing []string
+	for key, value := range want {
+		actual, ok := got[key]
+		switch {
+		case !ok:
+			missing = append(missing, fmt.Sprintf("%s=%q (missing)", key, value))
+		case actual != value:
+			missing = append(missing, fmt.Sprintf("%s=%q (got %q)", key, value, actual))
+		}
+	}
+	sort.Strings(missing)
+	return missing
 }
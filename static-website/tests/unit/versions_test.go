@@ -0,0 +1,23 @@
+package unit
+
+import (
+	"testing"
+
+	"testkit"
+)
+
+func TestVersionConstraintsDeclared(t *testing.T) {
+	t.Parallel()
+
+	testkit.AssertVersionConstraints(t, "../../")
+}
+
+func TestProviderVersionMatrix(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip("Skipping provider version matrix in short mode")
+	}
+
+	testkit.RunProviderVersionMatrix(t, "../../")
+}
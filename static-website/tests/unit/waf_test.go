@@ -0,0 +1,163 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/wafv2"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"static-website-tests/testutil"
+	"testkit"
+)
+
+// Managed rule groups the web ACL must reference, no more and no fewer
+var expectedManagedRuleGroups = []string{
+	"AWS/AWSManagedRulesAnonymousIpList",
+	"AWS/AWSManagedRulesBotControlRuleSet",
+	"AWS/AWSManagedRulesCommonRuleSet",
+	"AWS/AWSManagedRulesKnownBadInputsRuleSet",
+	"AWS/AWSManagedRulesSQLiRuleSet",
+}
+
+func TestWAFManagedRuleGroups(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name": "waf-groups-test.example.com",
+			"enable_waf":  true,
+		},
+	}
+
+	testkit.AcquireApplySlot(t)
+	defer testutil.DestroyWithLogBuckets(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"),
+	}))
+	webACL, err := wafv2.New(sess).GetWebACL(&wafv2.GetWebACLInput{
+		Id:    aws.String(terraform.Output(t, terraformOptions, "waf_web_acl_id")),
+		Name:  aws.String(terraform.Output(t, terraformOptions, "waf_web_acl_name")),
+		Scope: aws.String(wafv2.ScopeCloudfront),
+	})
+	require.NoError(t, err)
+
+	testutil.AssertManagedRuleGroups(t, webACL.WebACL, expectedManagedRuleGroups, nil)
+}
+
+// TestWAFRateLimitPlan checks the rate-based rule is built from rate_limit and
+// waf_rule_count counts the rules the web ACL actually renders
+func TestWAFRateLimitPlan(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":           "waf-rate-limit-test.example.com",
+			"rate_limit":            1500,
+			"enable_body_size_rule": true,
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.waf[0].aws_wafv2_web_acl.this")
+	rules, _ := plan.ResourcePlannedValuesMap["module.waf[0].aws_wafv2_web_acl.this"].AttributeValues["rule"].([]interface{})
+
+	rateRule := plannedRule(rules, "RateLimitRule")
+	require.NotNil(t, rateRule, "Web ACL should have a rate-based rule")
+	statement := rateRule["statement"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(1500), nestedBlockValue(statement, "rate_based_statement", "limit"))
+	assert.Equal(t, "IP", nestedBlockValue(statement, "rate_based_statement", "aggregate_key_type"))
+
+	outputs := plan.RawPlan.PlannedValues.Outputs
+	assert.Equal(t, float64(len(rules)), outputs["waf_rule_count"].Value, "waf_rule_count should match the rendered rules")
+	assert.Len(t, outputs["waf_rule_names"].Value, len(rules), "waf_rule_names should list every rendered rule")
+	assert.Equal(t, float64(1500), outputs["waf_rate_limit"].Value)
+}
+
+// TestWAFIPSetRulePriorities checks the allowlist and blocklist rules run
+// ahead of rate limiting and the managed rule groups, allowlist first
+func TestWAFIPSetRulePriorities(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: "../../",
+		Vars: map[string]interface{}{
+			"domain_name":       "waf-ip-sets-test.example.com",
+			"allowed_cidrs":     []string{"192.0.2.0/24"},
+			"blocked_cidrs":     []string{"198.51.100.0/24", "203.0.113.7/32", "2001:db8::/32"},
+			"blocked_countries": []string{"KP"},
+		},
+	}
+
+	plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+	// Each address family gets its own set
+	for address, cidrs := range map[string][]interface{}{
+		`module.waf[0].aws_wafv2_ip_set.allowed["IPV4"]`: {"192.0.2.0/24"},
+		`module.waf[0].aws_wafv2_ip_set.blocked["IPV4"]`: {"198.51.100.0/24", "203.0.113.7/32"},
+		`module.waf[0].aws_wafv2_ip_set.blocked["IPV6"]`: {"2001:db8::/32"},
+	} {
+		terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+		ipSet := plan.ResourcePlannedValuesMap[address].AttributeValues
+		assert.ElementsMatch(t, cidrs, ipSet["addresses"], address)
+		assert.Equal(t, "CLOUDFRONT", ipSet["scope"], address)
+	}
+	assert.NotContains(t, plan.ResourcePlannedValuesMap, `module.waf[0].aws_wafv2_ip_set.allowed["IPV6"]`, "An IPv4-only allowlist needs no IPv6 set")
+
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, "module.waf[0].aws_wafv2_web_acl.this")
+	rules, _ := plan.ResourcePlannedValuesMap["module.waf[0].aws_wafv2_web_acl.this"].AttributeValues["rule"].([]interface{})
+	allowRule := plannedRule(rules, "AllowlistRule")
+	blockRule := plannedRule(rules, "BlocklistRule")
+	require.NotNil(t, allowRule, "Web ACL should have the allowlist rule")
+	require.NotNil(t, blockRule, "Web ACL should have the blocklist rule")
+	assert.NotEmpty(t, nestedBlockValue(allowRule, "action", "allow"), "Allowlist should allow")
+	assert.NotEmpty(t, nestedBlockValue(blockRule, "action", "block"), "Blocklist should block")
+
+	// A single set is referenced directly; both families need an or_statement
+	assert.NotEmpty(t, nestedBlockValue(allowRule, "statement", "ip_set_reference_statement"), "Allowlist should reference its IPv4 set")
+	assert.NotEmpty(t, nestedBlockValue(blockRule, "statement", "or_statement"), "Blocklist should OR its IPv4 and IPv6 sets")
+
+	assert.Less(t, allowRule["priority"], blockRule["priority"], "Allowlist should be evaluated first")
+	for _, rule := range rules {
+		values := rule.(map[string]interface{})
+		if values["name"] == "AllowlistRule" || values["name"] == "BlocklistRule" {
+			continue
+		}
+		assert.Less(t, blockRule["priority"], values["priority"], "Blocklist should run before %s", values["name"])
+	}
+	assert.Equal(t, []interface{}{"AllowlistRule", "BlocklistRule"}, plan.RawPlan.PlannedValues.Outputs["waf_rule_names"].Value.([]interface{})[:2])
+
+	terraformOptions.Vars["blocked_cidrs"] = []string{"2001:db8::/129"}
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "blocked_cidrs must be IPv4 or IPv6 CIDR blocks")
+}
+
+func TestPlannedRule(t *testing.T) {
+	t.Parallel()
+
+	rules := []interface{}{
+		map[string]interface{}{"name": "RateLimitRule", "priority": float64(1)},
+		map[string]interface{}{"name": "AWSCommonRuleSet", "priority": float64(2)},
+	}
+	assert.Equal(t, float64(2), plannedRule(rules, "AWSCommonRuleSet")["priority"])
+	assert.Nil(t, plannedRule(rules, "GeoBlockRule"))
+	assert.Nil(t, plannedRule(nil, "RateLimitRule"))
+}
+
+// Helper function to find a rule by name among a planned web ACL's rules
+func plannedRule(rules []interface{}, name string) map[string]interface{} {
+	for _, rule := range rules {
+		values, ok := rule.(map[string]interface{})
+		if ok && values["name"] == name {
+			return values
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+// Number of deployments allowed to apply at once when MAX_PARALLEL_APPLIES is unset
+const defaultMaxParallelApplies = 4
+
+var (
+	packageApplyBudget     *applyBudget
+	packageApplyBudgetErr  error
+	packageApplyBudgetOnce sync.Once
+)
+
+// applyBudget caps how many heavyweight deployments are live at the same
+// time. Tests still run with t.Parallel(), they just queue for a slot.
+type applyBudget struct {
+	size int64
+	sem  *semaphore.Weighted
+}
+
+func newApplyBudget(size int64) *applyBudget {
+	return &applyBudget{size: size, sem: semaphore.NewWeighted(size)}
+}
+
+// acquire blocks until weight slots are free and releases them once the test,
+// including its deferred destroy, has finished.
+func (b *applyBudget) acquire(t *testing.T, weight int64) {
+	if weight > b.size {
+		weight = b.size
+	}
+	require.NoError(t, b.sem.Acquire(context.Background(), weight))
+	t.Cleanup(func() { b.sem.Release(weight) })
+}
+
+// AcquireApplySlot holds one slot of the test binary's apply budget, sized by
+// MAX_PARALLEL_APPLIES. Call it before registering terraform.Destroy so the
+// slot outlives the destroy.
+func AcquireApplySlot(t *testing.T) {
+	packageApplyBudgetOnce.Do(func() {
+		size, err := parseMaxParallelApplies(os.Getenv("MAX_PARALLEL_APPLIES"))
+		packageApplyBudget, packageApplyBudgetErr = newApplyBudget(size), err
+	})
+	require.NoError(t, packageApplyBudgetErr)
+
+	packageApplyBudget.acquire(t, 1)
+}
+
+// parseMaxParallelApplies reads the MAX_PARALLEL_APPLIES setting
+func parseMaxParallelApplies(value string) (int64, error) {
+	if value == "" {
+		return defaultMaxParallelApplies, nil
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || size < 1 {
+		return 0, fmt.Errorf("MAX_PARALLEL_APPLIES must be a positive integer, got %q", value)
+	}
+	return size, nil
+}
@@ -0,0 +1,90 @@
+package testkit
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBudgetBoundsConcurrency(t *testing.T) {
+	t.Parallel()
+
+	const limit = 2
+	budget := newApplyBudget(limit)
+
+	var running, peak int64
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 8; i++ {
+			t.Run(fmt.Sprintf("apply-%d", i), func(t *testing.T) {
+				t.Parallel()
+				budget.acquire(t, 1)
+
+				current := atomic.AddInt64(&running, 1)
+				for {
+					seen := atomic.LoadInt64(&peak)
+					if current <= seen || atomic.CompareAndSwapInt64(&peak, seen, current) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt64(&running, -1)
+			})
+		}
+	})
+
+	assert.LessOrEqual(t, peak, int64(limit), "More deployments applied at once than the budget allows")
+	assert.Greater(t, peak, int64(0))
+	assert.True(t, budget.sem.TryAcquire(limit), "Every slot should be released after the tests finish")
+}
+
+func TestApplyBudgetWeights(t *testing.T) {
+	t.Parallel()
+
+	budget := newApplyBudget(3)
+
+	t.Run("heavy", func(t *testing.T) {
+		budget.acquire(t, 2)
+		assert.True(t, budget.sem.TryAcquire(1), "One slot should remain beside a weight-2 deployment")
+		assert.False(t, budget.sem.TryAcquire(1), "The budget should be exhausted")
+		budget.sem.Release(1)
+	})
+
+	t.Run("oversized", func(t *testing.T) {
+		// Weights above the budget are clamped so the test can still run alone
+		budget.acquire(t, 10)
+		assert.False(t, budget.sem.TryAcquire(1))
+	})
+
+	assert.True(t, budget.sem.TryAcquire(3))
+}
+
+func TestParseMaxParallelApplies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		expected int64
+		valid    bool
+	}{
+		{"", defaultMaxParallelApplies, true},
+		{"1", 1, true},
+		{" 8 ", 8, true},
+		{"0", 0, false},
+		{"-2", 0, false},
+		{"many", 0, false},
+	}
+
+	for _, tc := range testCases {
+		size, err := parseMaxParallelApplies(tc.value)
+		if !tc.valid {
+			assert.Error(t, err, "parseMaxParallelApplies(%q)", tc.value)
+			continue
+		}
+		require.NoError(t, err, "parseMaxParallelApplies(%q)", tc.value)
+		assert.Equal(t, tc.expected, size, "parseMaxParallelApplies(%q)", tc.value)
+	}
+}
@@ -0,0 +1,162 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PolicyStatement is one statement of an IAM policy document. Principal,
+// Action and Resource may be a string, a list or (for Principal) a map.
+type PolicyStatement struct {
+	Sid       string                            `json:"Sid"`
+	Effect    string                            `json:"Effect"`
+	Principal interface{}                       `json:"Principal"`
+	Action    interface{}                       `json:"Action"`
+	Resource  interface{}                       `json:"Resource"`
+	Condition map[string]map[string]interface{} `json:"Condition"`
+}
+
+// StatementMatcher describes a wanted statement. Empty fields match anything;
+// Action may be matched by a wildcard action in the statement (e.g. "s3:*").
+type StatementMatcher struct {
+	Effect    string
+	Principal string // "*" or an identifier such as "cloudtrail.amazonaws.com"
+	Action    string
+	Condition map[string]map[string]string // operator -> key -> value
+}
+
+func (m StatementMatcher) String() string {
+	return fmt.Sprintf("Effect=%q Principal=%q Action=%q Condition=%v", m.Effect, m.Principal, m.Action, m.Condition)
+}
+
+// AssertBucketPolicyStatement asserts a bucket policy has a statement matching the matcher
+func AssertBucketPolicyStatement(t testing.TB, svc s3iface.S3API, bucket string, matcher StatementMatcher) {
+	t.Helper()
+
+	statements, err := BucketPolicyStatements(svc, bucket)
+	require.NoError(t, err)
+	if diff := StatementMismatches(statements, matcher); diff != "" {
+		assert.Fail(t, fmt.Sprintf("Bucket %s policy has no statement matching %s", bucket, matcher), diff)
+	}
+}
+
+// BucketPolicyStatements fetches and parses a bucket's policy statements
+func BucketPolicyStatements(svc s3iface.S3API, bucket string) ([]PolicyStatement, error) {
+	result, err := svc.GetBucketPolicy(&s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var policy struct {
+		Statement []PolicyStatement `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(aws.StringValue(result.Policy)), &policy); err != nil {
+		return nil, fmt.Errorf("parsing bucket policy: %w", err)
+	}
+	return policy.Statement, nil
+}
+
+// StatementMismatches describes why no statement matches. Returns an empty
+// string when one does, otherwise each statement's mismatched fields.
+func StatementMismatches(statements []PolicyStatement, matcher StatementMatcher) string {
+	var diff []string
+	for i, statement := range statements {
+		mismatches := matcher.mismatches(statement)
+		if len(mismatches) == 0 {
+			return ""
+		}
+		name := statement.Sid
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		diff = append(diff, fmt.Sprintf("statement %s: %s", name, strings.Join(mismatches, "; ")))
+	}
+	if len(diff) == 0 {
+		return "policy has no statements"
+	}
+	return strings.Join(diff, "\n")
+}
+
+func (m StatementMatcher) mismatches(statement PolicyStatement) []string {
+	var mismatches []string
+	if m.Effect != "" && m.Effect != statement.Effect {
+		mismatches = append(mismatches, fmt.Sprintf("Effect is %q", statement.Effect))
+	}
+	if principals := policyPrincipals(statement.Principal); m.Principal != "" && !containsString(principals, m.Principal) {
+		mismatches = append(mismatches, fmt.Sprintf("Principal is %v", principals))
+	}
+	if actions := policyValues(statement.Action); m.Action != "" && !actionAllowed(actions, m.Action) {
+		mismatches = append(mismatches, fmt.Sprintf("Action is %v", actions))
+	}
+	for operator, keys := range m.Condition {
+		for key, want := range keys {
+			got := policyValues(statement.Condition[operator][key])
+			if !containsString(got, want) {
+				mismatches = append(mismatches, fmt.Sprintf("Condition %s %s is %v, want %q", operator, key, got, want))
+			}
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches
+}
+
+// policyPrincipals flattens a principal ("*" or {"Service": [...]}) into identifiers
+func policyPrincipals(principal interface{}) []string {
+	if byType, ok := principal.(map[string]interface{}); ok {
+		var identifiers []string
+		for _, value := range byType {
+			identifiers = append(identifiers, policyValues(value)...)
+		}
+		sort.Strings(identifiers)
+		return identifiers
+	}
+	return policyValues(principal)
+}
+
+// policyValues normalizes a policy value that may be a string or a list
+func policyValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// actionAllowed checks an action is granted by any (possibly wildcard) statement action
+func actionAllowed(actions []string, want string) bool {
+	for _, action := range actions {
+		if matched, _ := path.Match(strings.ToLower(action), strings.ToLower(want)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleCloudTrailBucketPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Sid": "AWSCloudTrailAclCheck",
+			"Effect": "Allow",
+			"Principal": {"Service": "cloudtrail.amazonaws.com"},
+			"Action": "s3:GetBucketAcl",
+			"Resource": "arn:aws:s3:::basic-vpc-cloudtrail-logs"
+		},
+		{
+			"Sid": "AWSCloudTrailWrite",
+			"Effect": "Allow",
+			"Principal": {"Service": "cloudtrail.amazonaws.com"},
+			"Action": "s3:PutObject",
+			"Resource": "arn:aws:s3:::basic-vpc-cloudtrail-logs/*",
+			"Condition": {"StringEquals": {"s3:x-amz-acl": "bucket-owner-full-control"}}
+		},
+		{
+			"Sid": "DenyInsecureTransport",
+			"Effect": "Deny",
+			"Principal": "*",
+			"Action": ["s3:*"],
+			"Resource": ["arn:aws:s3:::basic-vpc-cloudtrail-logs", "arn:aws:s3:::basic-vpc-cloudtrail-logs/*"],
+			"Condition": {"Bool": {"aws:SecureTransport": "false"}}
+		}
+	]
+}`
+
+func TestBucketPolicyStatementMatcher(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeBucketPolicyClient{policy: sampleCloudTrailBucketPolicy}
+	statements, err := BucketPolicyStatements(svc, "basic-vpc-cloudtrail-logs")
+	require.NoError(t, err)
+	require.Len(t, statements, 3)
+	assert.Equal(t, "basic-vpc-cloudtrail-logs", aws.StringValue(svc.lastInput.Bucket))
+
+	// CloudTrail write with the ownership condition
+	assert.Empty(t, StatementMismatches(statements, StatementMatcher{
+		Effect:    "Allow",
+		Principal: "cloudtrail.amazonaws.com",
+		Action:    "s3:PutObject",
+		Condition: map[string]map[string]string{"StringEquals": {"s3:x-amz-acl": "bucket-owner-full-control"}},
+	}))
+
+	// Deny-insecure-transport matches any S3 action through the wildcard
+	assert.Empty(t, StatementMismatches(statements, StatementMatcher{
+		Effect:    "Deny",
+		Principal: "*",
+		Action:    "s3:GetObject",
+		Condition: map[string]map[string]string{"Bool": {"aws:SecureTransport": "false"}},
+	}))
+
+	// A missing statement reports what each statement got wrong
+	diff := StatementMismatches(statements, StatementMatcher{
+		Effect:    "Allow",
+		Principal: "cloudtrail.amazonaws.com",
+		Action:    "s3:DeleteObject",
+	})
+	assert.Contains(t, diff, "statement AWSCloudTrailAclCheck: Action is [s3:GetBucketAcl]")
+	assert.Contains(t, diff, "statement AWSCloudTrailWrite: Action is [s3:PutObject]")
+	assert.Contains(t, diff, `statement DenyInsecureTransport: Effect is "Deny"; Principal is [*]`)
+
+	diff = StatementMismatches(statements, StatementMatcher{
+		Action:    "s3:PutObject",
+		Condition: map[string]map[string]string{"StringEquals": {"s3:x-amz-acl": "public-read"}},
+	})
+	assert.Contains(t, diff, `Condition StringEquals s3:x-amz-acl is [bucket-owner-full-control], want "public-read"`)
+
+	// Action matching is case-insensitive like IAM
+	assert.Empty(t, StatementMismatches(statements, StatementMatcher{Effect: "Deny", Action: "S3:deleteobject"}))
+
+	assert.Equal(t, "policy has no statements", StatementMismatches(nil, StatementMatcher{Effect: "Allow"}))
+
+	_, err = BucketPolicyStatements(&fakeBucketPolicyClient{policy: "not json"}, "basic-vpc-cloudtrail-logs")
+	assert.Error(t, err)
+}
+
+type fakeBucketPolicyClient struct {
+	s3iface.S3API
+	policy    string
+	lastInput *s3.GetBucketPolicyInput
+}
+
+func (f *fakeBucketPolicyClient) GetBucketPolicy(input *s3.GetBucketPolicyInput) (*s3.GetBucketPolicyOutput, error) {
+	f.lastInput = input
+	return &s3.GetBucketPolicyOutput{Policy: aws.String(f.policy)}, nil
+}
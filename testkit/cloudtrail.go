@@ -0,0 +1,53 @@
+package testkit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudtrail/cloudtrailiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertTrailLogging asserts a trail is multi-region, records global service
+// events and is logging, reading the live trail rather than Terraform outputs
+func AssertTrailLogging(t testing.TB, trailSvc cloudtrailiface.CloudTrailAPI, trailName string) {
+	problems, err := TrailProblems(trailSvc, trailName)
+	require.NoError(t, err)
+	for _, problem := range problems {
+		assert.Fail(t, "CloudTrail misconfigured", problem)
+	}
+}
+
+// TrailProblems lists what keeps a trail from covering every region
+func TrailProblems(trailSvc cloudtrailiface.CloudTrailAPI, trailName string) ([]string, error) {
+	trail, err := trailSvc.GetTrail(&cloudtrail.GetTrailInput{
+		Name: aws.String(trailName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	status, err := trailSvc.GetTrailStatus(&cloudtrail.GetTrailStatusInput{
+		Name: aws.String(trailName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if !aws.BoolValue(trail.Trail.IsMultiRegionTrail) {
+		problems = append(problems, fmt.Sprintf("trail %s is not multi-region", trailName))
+	}
+	if !aws.BoolValue(trail.Trail.IncludeGlobalServiceEvents) {
+		problems = append(problems, fmt.Sprintf("trail %s does not include global service events", trailName))
+	}
+	if !aws.BoolValue(status.IsLogging) {
+		problems = append(problems, fmt.Sprintf("trail %s is not logging", trailName))
+	}
+	if deliveryError := aws.StringValue(status.LatestDeliveryError); deliveryError != "" {
+		problems = append(problems, fmt.Sprintf("trail %s failed its latest delivery: %s", trailName, deliveryError))
+	}
+	return problems, nil
+}
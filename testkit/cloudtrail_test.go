@@ -0,0 +1,58 @@
+package testkit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudtrail/cloudtrailiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailProblems(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeTrailClient{
+		trail:  &cloudtrail.Trail{IsMultiRegionTrail: aws.Bool(true), IncludeGlobalServiceEvents: aws.Bool(true)},
+		status: &cloudtrail.GetTrailStatusOutput{IsLogging: aws.Bool(true)},
+	}
+	problems, err := TrailProblems(svc, "example-trail")
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	svc = &fakeTrailClient{
+		trail:  &cloudtrail.Trail{IsMultiRegionTrail: aws.Bool(false)},
+		status: &cloudtrail.GetTrailStatusOutput{IsLogging: aws.Bool(false), LatestDeliveryError: aws.String("AccessDenied")},
+	}
+	problems, err = TrailProblems(svc, "example-trail")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"trail example-trail is not multi-region",
+		"trail example-trail does not include global service events",
+		"trail example-trail is not logging",
+		"trail example-trail failed its latest delivery: AccessDenied",
+	}, problems)
+
+	_, err = TrailProblems(&fakeTrailClient{err: fmt.Errorf("TrailNotFoundException")}, "missing")
+	assert.Error(t, err)
+}
+
+type fakeTrailClient struct {
+	cloudtrailiface.CloudTrailAPI
+	trail  *cloudtrail.Trail
+	status *cloudtrail.GetTrailStatusOutput
+	err    error
+}
+
+func (f *fakeTrailClient) GetTrail(input *cloudtrail.GetTrailInput) (*cloudtrail.GetTrailOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &cloudtrail.GetTrailOutput{Trail: f.trail}, nil
+}
+
+func (f *fakeTrailClient) GetTrailStatus(input *cloudtrail.GetTrailStatusInput) (*cloudtrail.GetTrailStatusOutput, error) {
+	return f.status, nil
+}
@@ -0,0 +1,17 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// DisableDeletionProtection re-applies the module with
+// enable_deletion_protection off so terraform destroy can succeed. Defer it
+// after terraform.Destroy so it runs first.
+func DisableDeletionProtection(t testing.TB, terraformOptions *terraform.Options) {
+	t.Helper()
+
+	terraformOptions.Vars["enable_deletion_protection"] = false
+	terraform.Apply(t, terraformOptions)
+}
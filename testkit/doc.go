@@ -0,0 +1,4 @@
+// Package testkit holds the assertions shared by the terratest suites of every
+// module in this repository, so a fix to a check lands in all of them at once.
+// Pure helpers return the problems they find; the assert wrappers report them.
+package testkit
@@ -0,0 +1,140 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// WaitForInstanceStatusOk waits for an instance to run and then pass both its
+// system and instance status checks, meaning it booted and is reachable rather
+// than merely running. The running waiter tolerates the stale "stopped" a
+// describe can still return right after StartInstances.
+func WaitForInstanceStatusOk(ec2Svc ec2iface.EC2API, instanceID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	instanceIDs := []*string{aws.String(instanceID)}
+	if err := ec2Svc.WaitUntilInstanceRunningWithContext(ctx, &ec2.DescribeInstancesInput{InstanceIds: instanceIDs}); err != nil {
+		return fmt.Errorf("waiting for instance %s to run: %w", instanceID, err)
+	}
+	statusInput := &ec2.DescribeInstanceStatusInput{InstanceIds: instanceIDs}
+	if err := ec2Svc.WaitUntilSystemStatusOkWithContext(ctx, statusInput); err != nil {
+		return fmt.Errorf("waiting for instance %s system status checks: %w", instanceID, err)
+	}
+	if err := ec2Svc.WaitUntilInstanceStatusOkWithContext(ctx, statusInput); err != nil {
+		return fmt.Errorf("waiting for instance %s status checks: %w", instanceID, err)
+	}
+	return nil
+}
+
+// AssertMetadataOptions asserts an instance requires IMDSv2 with a hop limit
+// of 1 and exposes tags in metadata only when tagsEnabled is set
+func AssertMetadataOptions(t testing.TB, ec2Svc ec2iface.EC2API, instanceID string, tagsEnabled bool) {
+	t.Helper()
+
+	result, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Reservations, 1)
+	require.Len(t, result.Reservations[0].Instances, 1)
+
+	for _, problem := range MetadataOptionsProblems(result.Reservations[0].Instances[0].MetadataOptions, tagsEnabled) {
+		assert.Fail(t, fmt.Sprintf("Instance %s metadata options misconfigured", instanceID), problem)
+	}
+}
+
+// MetadataOptionsProblems lists how an instance's metadata options differ
+// from IMDSv2-only with a hop limit of 1 and the expected tags setting
+func MetadataOptionsProblems(options *ec2.InstanceMetadataOptionsResponse, tagsEnabled bool) []string {
+	if options == nil {
+		return []string{"no metadata options"}
+	}
+
+	var problems []string
+	if endpoint := aws.StringValue(options.HttpEndpoint); endpoint != ec2.InstanceMetadataEndpointStateEnabled {
+		problems = append(problems, fmt.Sprintf("metadata endpoint is %s, want enabled", endpoint))
+	}
+	if tokens := aws.StringValue(options.HttpTokens); tokens != ec2.HttpTokensStateRequired {
+		problems = append(problems, fmt.Sprintf("http tokens are %s, want required", tokens))
+	}
+	if hopLimit := aws.Int64Value(options.HttpPutResponseHopLimit); hopLimit != 1 {
+		problems = append(problems, fmt.Sprintf("hop limit is %d, want 1", hopLimit))
+	}
+	wantTags := ec2.InstanceMetadataTagsStateDisabled
+	if tagsEnabled {
+		wantTags = ec2.InstanceMetadataTagsStateEnabled
+	}
+	if tags := aws.StringValue(options.InstanceMetadataTags); tags != wantTags {
+		problems = append(problems, fmt.Sprintf("instance metadata tags are %s, want %s", tags, wantTags))
+	}
+	return problems
+}
+
+// AssertInstanceProfiles asserts each instance has exactly the expected
+// instance profile ARN attached, read from EC2 rather than module outputs. An
+// empty ARN means the instance should have no profile.
+func AssertInstanceProfiles(t testing.TB, ec2Svc ec2iface.EC2API, expected map[string]string) {
+	t.Helper()
+
+	problems, err := InstanceProfileProblems(ec2Svc, expected)
+	require.NoError(t, err)
+	for _, problem := range problems {
+		assert.Fail(t, "Instance profile attachment mismatch", problem)
+	}
+}
+
+// InstanceProfileProblems lists instances whose attached profile differs from
+// the expected ARN
+func InstanceProfileProblems(ec2Svc ec2iface.EC2API, expected map[string]string) ([]string, error) {
+	ids := make([]string, 0, len(expected))
+	for id := range expected {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice(ids),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attached := map[string]string{}
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			var profileArn string
+			if instance.IamInstanceProfile != nil {
+				profileArn = aws.StringValue(instance.IamInstanceProfile.Arn)
+			}
+			attached[aws.StringValue(instance.InstanceId)] = profileArn
+		}
+	}
+
+	var problems []string
+	for _, id := range ids {
+		got, ok := attached[id]
+		want := expected[id]
+		switch {
+		case !ok:
+			problems = append(problems, id+" was not found")
+		case got == want:
+		case got == "":
+			problems = append(problems, fmt.Sprintf("%s has no instance profile, expected %s", id, want))
+		case want == "":
+			problems = append(problems, fmt.Sprintf("%s has instance profile %s, expected none", id, got))
+		default:
+			problems = append(problems, fmt.Sprintf("%s has instance profile %s, expected %s", id, got, want))
+		}
+	}
+	return problems, nil
+}
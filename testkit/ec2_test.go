@@ -0,0 +1,118 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataOptionsProblems(t *testing.T) {
+	t.Parallel()
+
+	options := &ec2.InstanceMetadataOptionsResponse{
+		HttpEndpoint:            aws.String(ec2.InstanceMetadataEndpointStateEnabled),
+		HttpTokens:              aws.String(ec2.HttpTokensStateRequired),
+		HttpPutResponseHopLimit: aws.Int64(1),
+		InstanceMetadataTags:    aws.String(ec2.InstanceMetadataTagsStateDisabled),
+	}
+	assert.Empty(t, MetadataOptionsProblems(options, false))
+	assert.Equal(t, []string{"instance metadata tags are disabled, want enabled"}, MetadataOptionsProblems(options, true))
+
+	// The EC2 defaults: IMDSv1 allowed and a hop limit containers can reach
+	options.HttpTokens = aws.String(ec2.HttpTokensStateOptional)
+	options.HttpPutResponseHopLimit = aws.Int64(2)
+	options.InstanceMetadataTags = aws.String(ec2.InstanceMetadataTagsStateEnabled)
+	assert.Equal(t, []string{
+		"http tokens are optional, want required",
+		"hop limit is 2, want 1",
+		"instance metadata tags are enabled, want disabled",
+	}, MetadataOptionsProblems(options, false))
+
+	assert.Equal(t, []string{"no metadata options"}, MetadataOptionsProblems(nil, false))
+}
+
+func TestAssertMetadataOptions(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeInstanceClient{instances: []*ec2.Instance{{
+		InstanceId: aws.String("i-public"),
+		MetadataOptions: &ec2.InstanceMetadataOptionsResponse{
+			HttpEndpoint:            aws.String(ec2.InstanceMetadataEndpointStateEnabled),
+			HttpTokens:              aws.String(ec2.HttpTokensStateRequired),
+			HttpPutResponseHopLimit: aws.Int64(1),
+			InstanceMetadataTags:    aws.String(ec2.InstanceMetadataTagsStateEnabled),
+		},
+	}}}
+	AssertMetadataOptions(t, svc, "i-public", true)
+	assert.Equal(t, []string{"i-public"}, aws.StringValueSlice(svc.lastInput.InstanceIds))
+}
+
+type fakeInstanceClient struct {
+	ec2iface.EC2API
+	instances []*ec2.Instance
+	lastInput *ec2.DescribeInstancesInput
+}
+
+func (f *fakeInstanceClient) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.lastInput = input
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: f.instances}}}, nil
+}
+
+func TestInstanceProfileProblems(t *testing.T) {
+	t.Parallel()
+
+	profileArn := "arn:aws:iam::123456789012:instance-profile/ssm-profile-for-private-ec2"
+	svc := &fakeInstanceProfileClient{profiles: map[string]string{
+		"i-0aaaaaaaaaaaaaaaa": profileArn,
+		"i-0bbbbbbbbbbbbbbbb": "arn:aws:iam::123456789012:instance-profile/ssm-profile-for-private-ec2-old",
+		"i-0cccccccccccccccc": "",
+	}}
+
+	problems, err := InstanceProfileProblems(svc, map[string]string{
+		"i-0aaaaaaaaaaaaaaaa": profileArn,
+		"i-0bbbbbbbbbbbbbbbb": profileArn,
+		"i-0cccccccccccccccc": profileArn,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"i-0bbbbbbbbbbbbbbbb has instance profile arn:aws:iam::123456789012:instance-profile/ssm-profile-for-private-ec2-old, expected " + profileArn,
+		"i-0cccccccccccccccc has no instance profile, expected " + profileArn,
+	}, problems, "A profile whose name merely contains the expected one must not pass")
+
+	// An empty expectation means the instance must have no profile at all
+	problems, err = InstanceProfileProblems(svc, map[string]string{
+		"i-0aaaaaaaaaaaaaaaa": "",
+		"i-0cccccccccccccccc": "",
+		"i-0dddddddddddddddd": "",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"i-0aaaaaaaaaaaaaaaa has instance profile " + profileArn + ", expected none",
+		"i-0dddddddddddddddd was not found",
+	}, problems)
+}
+
+type fakeInstanceProfileClient struct {
+	ec2iface.EC2API
+	profiles map[string]string
+}
+
+func (f *fakeInstanceProfileClient) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	reservation := &ec2.Reservation{}
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		profileArn, ok := f.profiles[id]
+		if !ok {
+			continue
+		}
+		instance := &ec2.Instance{InstanceId: aws.String(id)}
+		if profileArn != "" {
+			instance.IamInstanceProfile = &ec2.IamInstanceProfile{Arn: aws.String(profileArn)}
+		}
+		reservation.Instances = append(reservation.Instances, instance)
+	}
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation}}, nil
+}
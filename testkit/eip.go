@@ -0,0 +1,83 @@
+package testkit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+)
+
+// NAT gateway layouts ExpectedEIPCount understands
+const (
+	NoNatGateway     = "none"
+	SingleNatGateway = "single"
+	PerAZNatGateway  = "per_az"
+)
+
+// NatGatewayMode maps a module's enable_nat_gateway and single_nat_gateway
+// toggles onto a NAT gateway layout
+func NatGatewayMode(enabled, single bool) string {
+	switch {
+	case !enabled:
+		return NoNatGateway
+	case single:
+		return SingleNatGateway
+	default:
+		return PerAZNatGateway
+	}
+}
+
+// ExpectedEIPCount works out how many Elastic IPs a deployment should hold:
+// one per NAT gateway, plus the bastion's if it has one
+func ExpectedEIPCount(natMode string, azCount int, bastionEIP bool) (int, error) {
+	var count int
+	switch natMode {
+	case NoNatGateway:
+	case SingleNatGateway:
+		count = 1
+	case PerAZNatGateway:
+		if azCount < 1 {
+			return 0, fmt.Errorf("per-AZ NAT mode needs at least one AZ, got %d", azCount)
+		}
+		count = azCount
+	default:
+		return 0, fmt.Errorf("unknown NAT gateway mode %q", natMode)
+	}
+
+	if bastionEIP {
+		count++
+	}
+	return count, nil
+}
+
+// CountEnvironmentEIPs counts the Elastic IPs tagged with an environment,
+// associated or not, since an idle allocation still costs money
+func CountEnvironmentEIPs(ec2Svc ec2iface.EC2API, environment string) (int, error) {
+	result, err := ec2Svc.DescribeAddresses(&ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:Environment"),
+				Values: []*string{aws.String(environment)},
+			},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("describing Elastic IPs of %s: %w", environment, err)
+	}
+	return len(result.Addresses), nil
+}
+
+// AssertEIPCount fails unless an environment holds exactly the expected
+// Elastic IPs
+func AssertEIPCount(t testing.TB, ec2Svc ec2iface.EC2API, environment string, expected int) {
+	t.Helper()
+
+	count, err := CountEnvironmentEIPs(ec2Svc, environment)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, expected, count, "Environment %s should hold %d Elastic IPs", environment, expected)
+}
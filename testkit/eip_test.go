@@ -0,0 +1,80 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNatGatewayMode(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, NoNatGateway, NatGatewayMode(false, false))
+	assert.Equal(t, NoNatGateway, NatGatewayMode(false, true), "single_nat_gateway means nothing without enable_nat_gateway")
+	assert.Equal(t, SingleNatGateway, NatGatewayMode(true, true))
+	assert.Equal(t, PerAZNatGateway, NatGatewayMode(true, false))
+}
+
+func TestExpectedEIPCount(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		natMode    string
+		azCount    int
+		bastionEIP bool
+		expected   int
+	}{
+		{NoNatGateway, 2, false, 0},
+		{NoNatGateway, 2, true, 1},
+		{SingleNatGateway, 1, false, 1},
+		{SingleNatGateway, 3, false, 1},
+		{SingleNatGateway, 2, true, 2},
+		{PerAZNatGateway, 3, false, 3},
+		{PerAZNatGateway, 2, true, 3},
+	}
+
+	for _, tc := range testCases {
+		count, err := ExpectedEIPCount(tc.natMode, tc.azCount, tc.bastionEIP)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, count, "ExpectedEIPCount(%q, %d, %t)", tc.natMode, tc.azCount, tc.bastionEIP)
+	}
+
+	_, err := ExpectedEIPCount("gateway", 1, false)
+	assert.Error(t, err)
+	_, err = ExpectedEIPCount(PerAZNatGateway, 0, false)
+	assert.Error(t, err)
+}
+
+func TestCountEnvironmentEIPs(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeAddressesClient{
+		addresses: []*ec2.Address{
+			{AllocationId: aws.String("eipalloc-nat"), AssociationId: aws.String("eipassoc-nat")},
+			{AllocationId: aws.String("eipalloc-idle")},
+		},
+	}
+
+	count, err := CountEnvironmentEIPs(svc, "cost-test")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "Unassociated EIPs still cost money and must be counted")
+
+	require.Len(t, svc.lastInput.Filters, 1)
+	assert.Equal(t, "tag:Environment", aws.StringValue(svc.lastInput.Filters[0].Name))
+	assert.Equal(t, []string{"cost-test"}, aws.StringValueSlice(svc.lastInput.Filters[0].Values))
+}
+
+type fakeAddressesClient struct {
+	ec2iface.EC2API
+	addresses []*ec2.Address
+	lastInput *ec2.DescribeAddressesInput
+}
+
+func (f *fakeAddressesClient) DescribeAddresses(input *ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error) {
+	f.lastInput = input
+	return &ec2.DescribeAddressesOutput{Addresses: f.addresses}, nil
+}
@@ -0,0 +1,102 @@
+package testkit
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+)
+
+// ENIScope is what a VPC's network interfaces can still be found by once the
+// VPC itself has been deleted, which is when a vpc-id filter stops matching
+type ENIScope struct {
+	SubnetIDs        []string
+	SecurityGroupIDs []string
+}
+
+// CaptureENIScope records a VPC's subnets and security groups. Call it after
+// apply and before destroy.
+func CaptureENIScope(ec2Svc ec2iface.EC2API, vpcID string) (ENIScope, error) {
+	var scope ENIScope
+	vpcFilter := []*ec2.Filter{{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{vpcID})}}
+
+	err := ec2Svc.DescribeSubnetsPages(&ec2.DescribeSubnetsInput{Filters: vpcFilter},
+		func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+			for _, subnet := range page.Subnets {
+				scope.SubnetIDs = append(scope.SubnetIDs, aws.StringValue(subnet.SubnetId))
+			}
+			return true
+		})
+	if err != nil {
+		return scope, fmt.Errorf("describing subnets of %s: %w", vpcID, err)
+	}
+
+	err = ec2Svc.DescribeSecurityGroupsPages(&ec2.DescribeSecurityGroupsInput{Filters: vpcFilter},
+		func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+			for _, group := range page.SecurityGroups {
+				scope.SecurityGroupIDs = append(scope.SecurityGroupIDs, aws.StringValue(group.GroupId))
+			}
+			return true
+		})
+	if err != nil {
+		return scope, fmt.Errorf("describing security groups of %s: %w", vpcID, err)
+	}
+	return scope, nil
+}
+
+// LeakedENIs lists the network interfaces still in one of the scope's subnets
+// or attached to one of its security groups, as "id (type, description)"
+func LeakedENIs(ec2Svc ec2iface.EC2API, scope ENIScope) ([]string, error) {
+	seen := map[string]bool{}
+	var leaked []string
+	for _, filter := range []struct {
+		name   string
+		values []string
+	}{
+		{"subnet-id", scope.SubnetIDs},
+		{"group-id", scope.SecurityGroupIDs},
+	} {
+		if len(filter.values) == 0 {
+			continue
+		}
+		err := ec2Svc.DescribeNetworkInterfacesPages(&ec2.DescribeNetworkInterfacesInput{
+			Filters: []*ec2.Filter{{Name: aws.String(filter.name), Values: aws.StringSlice(filter.values)}},
+		}, func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+			for _, eni := range page.NetworkInterfaces {
+				id := aws.StringValue(eni.NetworkInterfaceId)
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				leaked = append(leaked, fmt.Sprintf("%s (%s, %s)", id,
+					aws.StringValue(eni.InterfaceType), aws.StringValue(eni.Description)))
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("describing network interfaces by %s: %w", filter.name, err)
+		}
+	}
+	sort.Strings(leaked)
+	return leaked, nil
+}
+
+// AssertNoLeakedENIs fails if any network interface in the scope outlives
+// destroy. ENI release is asynchronous, so it waits before reporting a leak.
+func AssertNoLeakedENIs(t testing.TB, ec2Svc ec2iface.EC2API, scope ENIScope) {
+	t.Helper()
+
+	var leaked []string
+	err := Poll(150*time.Second, func() (bool, error) {
+		var err error
+		leaked, err = LeakedENIs(ec2Svc, scope)
+		return len(leaked) == 0, err
+	})
+	assert.NoError(t, err, "Checking for leaked ENIs")
+	assert.Empty(t, leaked, "All ENIs should be removed once the VPC is destroyed")
+}
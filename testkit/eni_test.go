@@ -0,0 +1,93 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureENIScope(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeENIClient{
+		subnets: []*ec2.Subnet{{SubnetId: aws.String("subnet-a")}, {SubnetId: aws.String("subnet-b")}},
+		groups:  []*ec2.SecurityGroup{{GroupId: aws.String("sg-endpoints")}},
+	}
+	scope, err := CaptureENIScope(svc, "vpc-123")
+	require.NoError(t, err)
+	assert.Equal(t, ENIScope{
+		SubnetIDs:        []string{"subnet-a", "subnet-b"},
+		SecurityGroupIDs: []string{"sg-endpoints"},
+	}, scope)
+	assert.Equal(t, []string{"vpc-id=vpc-123", "vpc-id=vpc-123"}, svc.filters, "Subnets and groups should be looked up by VPC")
+}
+
+func TestLeakedENIs(t *testing.T) {
+	t.Parallel()
+
+	endpoint := &ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String("eni-endpoint"),
+		InterfaceType:      aws.String("vpc_endpoint"),
+		Description:        aws.String("VPC Endpoint Interface vpce-123"),
+	}
+	svc := &fakeENIClient{enis: map[string][]*ec2.NetworkInterface{
+		"subnet-id": {endpoint},
+		"group-id": {endpoint, {
+			NetworkInterfaceId: aws.String("eni-lambda"),
+			InterfaceType:      aws.String("lambda"),
+			Description:        aws.String("AWS Lambda VPC ENI"),
+		}},
+	}}
+
+	leaked, err := LeakedENIs(svc, ENIScope{SubnetIDs: []string{"subnet-a"}, SecurityGroupIDs: []string{"sg-endpoints"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"eni-endpoint (vpc_endpoint, VPC Endpoint Interface vpce-123)",
+		"eni-lambda (lambda, AWS Lambda VPC ENI)",
+	}, leaked, "ENIs found by both filters should be reported once")
+	assert.Equal(t, []string{"subnet-id=subnet-a", "group-id=sg-endpoints"}, svc.filters)
+
+	svc = &fakeENIClient{}
+	leaked, err = LeakedENIs(svc, ENIScope{})
+	require.NoError(t, err)
+	assert.Empty(t, leaked)
+	assert.Empty(t, svc.filters, "An empty scope shouldn't match every ENI in the account")
+}
+
+type fakeENIClient struct {
+	ec2iface.EC2API
+	subnets []*ec2.Subnet
+	groups  []*ec2.SecurityGroup
+	enis    map[string][]*ec2.NetworkInterface
+	filters []string
+}
+
+func (f *fakeENIClient) record(filters []*ec2.Filter) {
+	for _, filter := range filters {
+		for _, value := range filter.Values {
+			f.filters = append(f.filters, aws.StringValue(filter.Name)+"="+aws.StringValue(value))
+		}
+	}
+}
+
+func (f *fakeENIClient) DescribeSubnetsPages(input *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool) error {
+	f.record(input.Filters)
+	fn(&ec2.DescribeSubnetsOutput{Subnets: f.subnets}, true)
+	return nil
+}
+
+func (f *fakeENIClient) DescribeSecurityGroupsPages(input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) error {
+	f.record(input.Filters)
+	fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.groups}, true)
+	return nil
+}
+
+func (f *fakeENIClient) DescribeNetworkInterfacesPages(input *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool) error {
+	f.record(input.Filters)
+	fn(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: f.enis[aws.StringValue(input.Filters[0].Name)]}, true)
+	return nil
+}
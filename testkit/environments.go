@@ -0,0 +1,109 @@
+package testkit
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Environments are the environments every module keeps an
+// environments/<name>.tfvars for, in promotion order
+var Environments = []string{"dev", "staging", "prod"}
+
+// RunEnvironmentPlans plans the module in moduleDir with each environment's
+// tfvars on top of baseVars and compares the settings read from the plan
+// against want for that environment
+func RunEnvironmentPlans[S any](t *testing.T, moduleDir string, baseVars map[string]interface{}, want map[string]S, settings func(t *testing.T, env string, plan *terraform.PlanStruct) S) {
+	// Every plan shares the module's .terraform directory, so environments run in turn
+	for _, env := range Environments {
+		env := env
+		t.Run(env, func(t *testing.T) {
+			expected, ok := want[env]
+			require.True(t, ok, "No expected settings for %s", env)
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: moduleDir,
+				VarFiles:     []string{filepath.Join("environments", env+".tfvars")},
+				Vars:         baseVars,
+			}
+
+			plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+			assert.Equal(t, expected, settings(t, env, plan))
+		})
+	}
+}
+
+// AssertEnvironmentFiles asserts the module in moduleDir has exactly one
+// tfvars file per environment and that none of them sets a variable the
+// module doesn't declare
+func AssertEnvironmentFiles(t testing.TB, moduleDir string) {
+	files, err := filepath.Glob(filepath.Join(moduleDir, "environments", "*.tfvars"))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".tfvars"))
+	}
+	assert.ElementsMatch(t, Environments, names, "Each environment should have exactly one tfvars file")
+
+	undeclared, err := UndeclaredEnvironmentVariables(moduleDir)
+	require.NoError(t, err)
+	assert.Empty(t, undeclared, "Environment files set variables the module doesn't declare")
+}
+
+// UndeclaredEnvironmentVariables lists "<file>: <variable>" for every variable
+// an environments/*.tfvars file sets that the module in moduleDir doesn't
+// declare. Terraform only warns about those, so typos slip through.
+func UndeclaredEnvironmentVariables(moduleDir string) ([]string, error) {
+	parser := hclparse.NewParser()
+
+	declared := map[string]bool{}
+	tfFiles, err := filepath.Glob(filepath.Join(moduleDir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range tfFiles {
+		f, diags := parser.ParseHCLFile(file)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+		for _, block := range body.Blocks {
+			if block.Type == "variable" && len(block.Labels) == 1 {
+				declared[block.Labels[0]] = true
+			}
+		}
+	}
+
+	varFiles, err := filepath.Glob(filepath.Join(moduleDir, "environments", "*.tfvars"))
+	if err != nil {
+		return nil, err
+	}
+	var undeclared []string
+	for _, file := range varFiles {
+		f, diags := parser.ParseHCLFile(file)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		attributes, diags := f.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for name := range attributes {
+			if !declared[name] {
+				undeclared = append(undeclared, filepath.Base(file)+": "+name)
+			}
+		}
+	}
+	sort.Strings(undeclared)
+	return undeclared, nil
+}
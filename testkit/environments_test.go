@@ -0,0 +1,51 @@
+package testkit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndeclaredEnvironmentVariables(t *testing.T) {
+	t.Parallel()
+
+	moduleDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(moduleDir, "environments"), 0o755))
+	files := map[string]string{
+		"variables.tf":              "variable \"instance_type\" {}\nvariable \"environment\" {\n  type = string\n}\n",
+		"main.tf":                   "locals {\n  name = var.environment\n}\n",
+		"environments/dev.tfvars":   "environment   = \"dev\"\ninstance_type = \"t3.micro\"\n",
+		"environments/prod.tfvars":  "environment  = \"prod\"\ninstanc_type = \"t3.medium\"\nenable_waf   = true\n",
+		"environments/notes.txt":    "not a tfvars file\n",
+		"modules/child/variable.tf": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(moduleDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	undeclared, err := UndeclaredEnvironmentVariables(moduleDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod.tfvars: enable_waf", "prod.tfvars: instanc_type"}, undeclared)
+
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir, "environments", "broken.tfvars"), []byte("environment = \n"), 0o644))
+	_, err = UndeclaredEnvironmentVariables(moduleDir)
+	assert.Error(t, err, "Unparseable tfvars should fail rather than pass silently")
+}
+
+func TestAssertEnvironmentFiles(t *testing.T) {
+	t.Parallel()
+
+	moduleDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(moduleDir, "environments"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir, "variables.tf"), []byte("variable \"environment\" {}\n"), 0o644))
+	for _, env := range Environments {
+		require.NoError(t, os.WriteFile(filepath.Join(moduleDir, "environments", env+".tfvars"), []byte("environment = \""+env+"\"\n"), 0o644))
+	}
+
+	AssertEnvironmentFiles(t, moduleDir)
+}
@@ -0,0 +1,199 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MaxRoleSessionSeconds is the longest session a service role should hand
+// out. It is also IAM's default, so roles that don't raise it pass.
+const MaxRoleSessionSeconds = 3600
+
+// StateRole is an IAM role in state, addressed without count or for_each keys
+// (module.edge_headers.aws_iam_role.this rather than module.edge_headers[0]...)
+type StateRole struct {
+	Address string
+	Name    string
+}
+
+var instanceKey = regexp.MustCompile(`\[[^\]]*\]`)
+
+// StateRoles lists every aws_iam_role in `terraform show -json` state, sorted
+// by address
+func StateRoles(stateJSON string) ([]StateRole, error) {
+	var parsed state
+	if err := json.Unmarshal([]byte(stateJSON), &parsed); err != nil {
+		return nil, fmt.Errorf("parsing state: %w", err)
+	}
+	if parsed.Values == nil {
+		return nil, nil
+	}
+
+	var roles []StateRole
+	var walk func(module stateModule)
+	walk = func(module stateModule) {
+		for _, resource := range module.Resources {
+			if resource.Mode != "managed" || resource.Type != "aws_iam_role" {
+				continue
+			}
+			name, _ := resource.Values["name"].(string)
+			roles = append(roles, StateRole{
+				Address: instanceKey.ReplaceAllString(resource.Address, ""),
+				Name:    name,
+			})
+		}
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	walk(parsed.Values.RootModule)
+
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].Address != roles[j].Address {
+			return roles[i].Address < roles[j].Address
+		}
+		return roles[i].Name < roles[j].Name
+	})
+	return roles, nil
+}
+
+// AssertRoleTrust checks every role in the state against allowed, which maps
+// a role address (see StateRole) to the services that may assume it. A role
+// missing from allowed fails too, so a new role can't skip the audit.
+func AssertRoleTrust(t testing.TB, stateJSON string, iamSvc iamiface.IAMAPI, allowed map[string][]string) {
+	t.Helper()
+
+	roles, err := StateRoles(stateJSON)
+	require.NoError(t, err)
+	require.NotEmpty(t, roles, "state should contain at least one IAM role")
+
+	for _, role := range roles {
+		services, ok := allowed[role.Address]
+		if !ok {
+			assert.Fail(t, "Role trust isn't audited", "%s (%s) has no expected services", role.Address, role.Name)
+			continue
+		}
+		problems, err := RoleTrustProblems(iamSvc, role.Name, services...)
+		require.NoError(t, err)
+		for _, problem := range problems {
+			assert.Fail(t, "Role trust is broader than its services", "%s: %s", role.Name, problem)
+		}
+	}
+}
+
+// RoleTrustProblems fetches a role and lists the ways its trust policy or
+// session duration go beyond letting the given AWS services assume it
+func RoleTrustProblems(svc iamiface.IAMAPI, roleName string, services ...string) ([]string, error) {
+	result, err := svc.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)})
+	if err != nil {
+		return nil, fmt.Errorf("getting role %s: %w", roleName, err)
+	}
+	return trustProblems(result.Role, services)
+}
+
+// trustProblems checks every Allow statement in a role's trust policy names
+// only the given services as principals, that each of them is trusted, and
+// that sessions are bounded
+func trustProblems(role *iam.Role, services []string) ([]string, error) {
+	// GetRole returns the trust policy URL-encoded
+	document, err := url.QueryUnescape(aws.StringValue(role.AssumeRolePolicyDocument))
+	if err != nil {
+		return nil, fmt.Errorf("decoding trust policy: %w", err)
+	}
+
+	var policy struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return nil, fmt.Errorf("parsing trust policy: %w", err)
+	}
+
+	type statement struct {
+		Effect    string          `json:"Effect"`
+		Principal json.RawMessage `json:"Principal"`
+	}
+	var statements []statement
+	if err := json.Unmarshal(policy.Statement, &statements); err != nil {
+		var single statement
+		if err := json.Unmarshal(policy.Statement, &single); err != nil {
+			return nil, fmt.Errorf("parsing trust policy statements: %w", err)
+		}
+		statements = []statement{single}
+	}
+
+	allowed := map[string]bool{}
+	for _, service := range services {
+		allowed[service] = true
+	}
+	trusted := map[string]bool{}
+
+	var problems []string
+	for i, stmt := range statements {
+		if stmt.Effect != "Allow" {
+			continue
+		}
+
+		var wildcard string
+		if json.Unmarshal(stmt.Principal, &wildcard) == nil {
+			problems = append(problems, fmt.Sprintf("statement %d trusts principal %q", i, wildcard))
+			continue
+		}
+		var principals map[string]json.RawMessage
+		if err := json.Unmarshal(stmt.Principal, &principals); err != nil {
+			return nil, fmt.Errorf("parsing principal of statement %d: %w", i, err)
+		}
+
+		kinds := make([]string, 0, len(principals))
+		for kind := range principals {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			values, err := principalValues(principals[kind])
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s principal of statement %d: %w", kind, i, err)
+			}
+			for _, value := range values {
+				if kind == "Service" && allowed[value] {
+					trusted[value] = true
+					continue
+				}
+				problems = append(problems, fmt.Sprintf("statement %d trusts %s principal %q", i, kind, value))
+			}
+		}
+	}
+	for _, service := range services {
+		if !trusted[service] {
+			problems = append(problems, fmt.Sprintf("no statement lets %s assume the role", service))
+		}
+	}
+
+	if duration := aws.Int64Value(role.MaxSessionDuration); duration > MaxRoleSessionSeconds {
+		problems = append(problems, fmt.Sprintf("MaxSessionDuration is %ds, above %ds", duration, MaxRoleSessionSeconds))
+	}
+	return problems, nil
+}
+
+// principalValues reads a principal that is either one string or a list
+func principalValues(raw json.RawMessage) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal(raw, &values); err == nil {
+		return values, nil
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
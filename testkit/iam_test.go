@@ -0,0 +1,149 @@
+package testkit
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleTrustProblems(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		policy   string
+		services []string
+		duration int64
+		problems []string
+	}{
+		{
+			name:     "single service",
+			policy:   `{"Version":"2012-10-17","Statement":[{"Action":"sts:AssumeRole","Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"}}]}`,
+			duration: 3600,
+		},
+		{
+			name:     "service list and a deny",
+			policy:   `{"Statement":[{"Effect":"Allow","Principal":{"Service":["ec2.amazonaws.com"]}},{"Effect":"Deny","Principal":"*"}]}`,
+			duration: 3600,
+		},
+		{
+			name:     "anyone",
+			policy:   `{"Statement":{"Effect":"Allow","Principal":"*"}}`,
+			duration: 3600,
+			problems: []string{
+				`statement 0 trusts principal "*"`,
+				"no statement lets ec2.amazonaws.com assume the role",
+			},
+		},
+		{
+			name:     "extra principals",
+			policy:   `{"Statement":[{"Effect":"Allow","Principal":{"Service":["ec2.amazonaws.com","lambda.amazonaws.com"],"AWS":"*"}}]}`,
+			duration: 3600,
+			problems: []string{
+				`statement 0 trusts AWS principal "*"`,
+				`statement 0 trusts Service principal "lambda.amazonaws.com"`,
+			},
+		},
+		{
+			name:     "edge function trusts both Lambda services",
+			policy:   `{"Statement":[{"Effect":"Allow","Principal":{"Service":["lambda.amazonaws.com","edgelambda.amazonaws.com"]}}]}`,
+			services: []string{"lambda.amazonaws.com", "edgelambda.amazonaws.com"},
+			duration: 3600,
+		},
+		{
+			name:     "one of several services missing",
+			policy:   `{"Statement":[{"Effect":"Allow","Principal":{"Service":"lambda.amazonaws.com"}}]}`,
+			services: []string{"lambda.amazonaws.com", "edgelambda.amazonaws.com"},
+			duration: 3600,
+			problems: []string{"no statement lets edgelambda.amazonaws.com assume the role"},
+		},
+		{
+			name:     "long sessions",
+			policy:   `{"Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"}}]}`,
+			duration: 43200,
+			problems: []string{"MaxSessionDuration is 43200s, above 3600s"},
+		},
+	}
+
+	for _, tc := range testCases {
+		if tc.services == nil {
+			tc.services = []string{"ec2.amazonaws.com"}
+		}
+		svc := &fakeRoleClient{role: &iam.Role{
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(tc.policy)),
+			MaxSessionDuration:       aws.Int64(tc.duration),
+		}}
+		problems, err := RoleTrustProblems(svc, "ssm-role-for-private-ec2", tc.services...)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.problems, problems, tc.name)
+		assert.Equal(t, "ssm-role-for-private-ec2", aws.StringValue(svc.lastInput.RoleName))
+	}
+
+	_, err := RoleTrustProblems(&fakeRoleClient{err: errors.New("NoSuchEntity")}, "missing", "ec2.amazonaws.com")
+	assert.ErrorContains(t, err, "getting role missing")
+
+	_, err = RoleTrustProblems(&fakeRoleClient{role: &iam.Role{AssumeRolePolicyDocument: aws.String("not json")}}, "broken", "ec2.amazonaws.com")
+	assert.ErrorContains(t, err, "parsing trust policy")
+}
+
+func TestStateRoles(t *testing.T) {
+	t.Parallel()
+
+	stateJSON := `{"values": {"root_module": {
+	  "resources": [
+	    {"address": "aws_iam_role.firehose_role[0]", "mode": "managed", "type": "aws_iam_role", "values": {"name": "site-waf-firehose"}},
+	    {"address": "data.aws_iam_role.shared", "mode": "data", "type": "aws_iam_role", "values": {"name": "shared"}},
+	    {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "values": {"bucket": "logs"}}
+	  ],
+	  "child_modules": [{"resources": [
+	    {"address": "module.edge_headers[0].aws_iam_role.this", "mode": "managed", "type": "aws_iam_role", "values": {"name": "site-security-headers"}}
+	  ]}]
+	}}}`
+
+	roles, err := StateRoles(stateJSON)
+	require.NoError(t, err)
+	assert.Equal(t, []StateRole{
+		{Address: "aws_iam_role.firehose_role", Name: "site-waf-firehose"},
+		{Address: "module.edge_headers.aws_iam_role.this", Name: "site-security-headers"},
+	}, roles)
+
+	_, err = StateRoles("not json")
+	assert.Error(t, err)
+}
+
+func TestAssertRoleTrust(t *testing.T) {
+	t.Parallel()
+
+	stateJSON := `{"values": {"root_module": {"resources": [
+	  {"address": "aws_iam_role.lambda_role", "mode": "managed", "type": "aws_iam_role", "values": {"name": "app-lambda-role"}}
+	]}}}`
+	svc := &fakeRoleClient{role: &iam.Role{
+		AssumeRolePolicyDocument: aws.String(url.QueryEscape(`{"Statement":[{"Effect":"Allow","Principal":{"Service":"lambda.amazonaws.com"}}]}`)),
+		MaxSessionDuration:       aws.Int64(3600),
+	}}
+	AssertRoleTrust(t, stateJSON, svc, map[string][]string{
+		"aws_iam_role.lambda_role": {"lambda.amazonaws.com"},
+	})
+	assert.Equal(t, "app-lambda-role", aws.StringValue(svc.lastInput.RoleName))
+}
+
+type fakeRoleClient struct {
+	iamiface.IAMAPI
+	role      *iam.Role
+	err       error
+	lastInput *iam.GetRoleInput
+}
+
+func (f *fakeRoleClient) GetRole(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+	f.lastInput = input
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &iam.GetRoleOutput{Role: f.role}, nil
+}
@@ -0,0 +1,160 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scopingConditionKeys are the condition keys that narrow a wildcard principal
+// down to one account, organization, network path or calling resource. Any
+// other condition (aws:SecureTransport, kms:EncryptionContextKeys, ...) still
+// leaves the key open to every principal.
+var scopingConditionKeys = []string{
+	"aws:PrincipalAccount",
+	"aws:PrincipalArn",
+	"aws:PrincipalOrgID",
+	"aws:SourceAccount",
+	"aws:SourceArn",
+	"aws:SourceVpc",
+	"aws:SourceVpce",
+	"kms:CallerAccount",
+	"kms:ViaService",
+}
+
+// AssertKMSKeyHygiene asserts a customer-managed key rotates automatically and
+// its key policy doesn't hand key actions to every principal.
+func AssertKMSKeyHygiene(t testing.TB, kmsSvc kmsiface.KMSAPI, keyID string) {
+	t.Helper()
+
+	rotation, err := kmsSvc.GetKeyRotationStatus(&kms.GetKeyRotationStatusInput{
+		KeyId: aws.String(keyID),
+	})
+	require.NoError(t, err)
+	assert.True(t, aws.BoolValue(rotation.KeyRotationEnabled), "Key %s should have automatic rotation enabled", keyID)
+
+	policy, err := kmsSvc.GetKeyPolicy(&kms.GetKeyPolicyInput{
+		KeyId:      aws.String(keyID),
+		PolicyName: aws.String("default"),
+	})
+	require.NoError(t, err)
+
+	problems, err := KMSKeyPolicyProblems(aws.StringValue(policy.Policy))
+	require.NoError(t, err)
+	for _, problem := range problems {
+		assert.Fail(t, fmt.Sprintf("Key %s policy is too permissive", keyID), problem)
+	}
+}
+
+// KMSKeyPolicyProblems lists Allow statements that grant kms:* (or *) to any
+// principal without a scoping condition. Statements are named by Sid, or by
+// index when unnamed.
+func KMSKeyPolicyProblems(policyDocument string) ([]string, error) {
+	var policy struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policyDocument), &policy); err != nil {
+		return nil, fmt.Errorf("parsing key policy: %w", err)
+	}
+
+	type statement struct {
+		Sid       string                                `json:"Sid"`
+		Effect    string                                `json:"Effect"`
+		Principal json.RawMessage                       `json:"Principal"`
+		Action    json.RawMessage                       `json:"Action"`
+		Condition map[string]map[string]json.RawMessage `json:"Condition"`
+	}
+	var statements []statement
+	if err := json.Unmarshal(policy.Statement, &statements); err != nil {
+		var single statement
+		if err := json.Unmarshal(policy.Statement, &single); err != nil {
+			return nil, fmt.Errorf("parsing key policy statements: %w", err)
+		}
+		statements = []statement{single}
+	}
+
+	var problems []string
+	for i, stmt := range statements {
+		if stmt.Effect != "Allow" || conditionScopesPrincipal(stmt.Condition) {
+			continue
+		}
+
+		anyPrincipal := string(stmt.Principal) == `"*"`
+		var principals struct {
+			AWS json.RawMessage `json:"AWS"`
+		}
+		if !anyPrincipal && json.Unmarshal(stmt.Principal, &principals) == nil {
+			anyPrincipal = policyElementContains(principals.AWS, "*")
+		}
+		if !anyPrincipal {
+			continue
+		}
+
+		for _, action := range []string{"kms:*", "*"} {
+			if policyElementContains(stmt.Action, action) {
+				name := stmt.Sid
+				if name == "" {
+					name = fmt.Sprint(i)
+				}
+				problems = append(problems, fmt.Sprintf("statement %s allows %s to any principal", name, action))
+				break
+			}
+		}
+	}
+	return problems, nil
+}
+
+// conditionScopesPrincipal reports whether a condition block pins one of the
+// scoping keys to concrete values. Null checks and wildcard values only test
+// that the key exists, so they don't count.
+func conditionScopesPrincipal(condition map[string]map[string]json.RawMessage) bool {
+	for operator, keys := range condition {
+		if strings.EqualFold(operator, "Null") {
+			continue
+		}
+		for key, values := range keys {
+			if !isScopingConditionKey(key) {
+				continue
+			}
+			if !policyElementContains(values, "*") && !policyElementContains(values, "") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isScopingConditionKey(key string) bool {
+	for _, scoping := range scopingConditionKeys {
+		if strings.EqualFold(key, scoping) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyElementContains checks a policy element that may be a string or a list
+// of strings.
+func policyElementContains(element json.RawMessage, want string) bool {
+	var values []string
+	if err := json.Unmarshal(element, &values); err != nil {
+		var single string
+		if err := json.Unmarshal(element, &single); err != nil {
+			return false
+		}
+		values = []string{single}
+	}
+	for _, value := range values {
+		if strings.EqualFold(value, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKMSKeyPolicyProblems(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		policy   string
+		problems []string
+	}{
+		{
+			name: "account root and scoped service access",
+			policy: `{"Statement": [
+				{"Sid": "EnableRootAccess", "Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::123456789012:root"}, "Action": "kms:*", "Resource": "*"},
+				{"Sid": "AllowEBS", "Effect": "Allow", "Principal": {"AWS": "*"}, "Action": ["kms:Encrypt", "kms:Decrypt"], "Resource": "*",
+				 "Condition": {"StringEquals": {"kms:CallerAccount": "123456789012", "kms:ViaService": "ec2.us-east-1.amazonaws.com"}}}
+			]}`,
+		},
+		{
+			name: "service principal scoped to a distribution",
+			policy: `{"Statement": [
+				{"Sid": "CloudFrontOriginAccess", "Effect": "Allow", "Principal": {"Service": "cloudfront.amazonaws.com"}, "Action": "kms:Decrypt", "Resource": "*",
+				 "Condition": {"StringEquals": {"AWS:SourceArn": ["arn:aws:cloudfront::123456789012:distribution/E123"]}}}
+			]}`,
+		},
+		{
+			name:     "anyone can administer the key",
+			policy:   `{"Statement": {"Sid": "Open", "Effect": "Allow", "Principal": "*", "Action": "kms:*", "Resource": "*"}}`,
+			problems: []string{"statement Open allows kms:* to any principal"},
+		},
+		{
+			name: "wildcard action in a list and AWS principal list",
+			policy: `{"Statement": [
+				{"Effect": "Allow", "Principal": {"AWS": ["arn:aws:iam::123456789012:root", "*"]}, "Action": ["kms:Decrypt", "*"], "Resource": "*"}
+			]}`,
+			problems: []string{"statement 0 allows * to any principal"},
+		},
+		{
+			name: "unrelated condition doesn't scope a wildcard principal",
+			policy: `{"Statement": [
+				{"Sid": "TLSOnly", "Effect": "Allow", "Principal": "*", "Action": "kms:*", "Resource": "*",
+				 "Condition": {"Bool": {"aws:SecureTransport": "true"}}}
+			]}`,
+			problems: []string{"statement TLSOnly allows kms:* to any principal"},
+		},
+		{
+			name: "wildcard value on a scoping key",
+			policy: `{"Statement": [
+				{"Sid": "AnyAccount", "Effect": "Allow", "Principal": {"AWS": "*"}, "Action": "kms:*", "Resource": "*",
+				 "Condition": {"StringLike": {"kms:CallerAccount": ["123456789012", "*"]}}}
+			]}`,
+			problems: []string{"statement AnyAccount allows kms:* to any principal"},
+		},
+		{
+			name: "null check on a scoping key",
+			policy: `{"Statement": [
+				{"Sid": "HasSource", "Effect": "Allow", "Principal": "*", "Action": "kms:*", "Resource": "*",
+				 "Condition": {"Null": {"aws:SourceArn": "false"}}}
+			]}`,
+			problems: []string{"statement HasSource allows kms:* to any principal"},
+		},
+		{
+			name: "organization scoped wildcard principal",
+			policy: `{"Statement": [
+				{"Sid": "Org", "Effect": "Allow", "Principal": "*", "Action": "kms:*", "Resource": "*",
+				 "Condition": {"StringEquals": {"aws:PrincipalOrgID": "o-abc123"}}}
+			]}`,
+		},
+		{
+			name:   "deny statements are never too permissive",
+			policy: `{"Statement": [{"Effect": "Deny", "Principal": "*", "Action": "kms:*", "Resource": "*"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		problems, err := KMSKeyPolicyProblems(tc.policy)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.problems, problems, tc.name)
+	}
+
+	_, err := KMSKeyPolicyProblems("not json")
+	assert.Error(t, err)
+}
+
+func TestAssertKMSKeyHygiene(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeKMSClient{
+		rotation: true,
+		policy:   `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::123456789012:root"}, "Action": "kms:*", "Resource": "*"}]}`,
+	}
+	keyArn := "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	AssertKMSKeyHygiene(t, svc, keyArn)
+	assert.Equal(t, []string{keyArn, keyArn}, svc.requested, "Rotation and policy should both be checked")
+}
+
+type fakeKMSClient struct {
+	kmsiface.KMSAPI
+	rotation  bool
+	policy    string
+	requested []string
+}
+
+func (f *fakeKMSClient) GetKeyRotationStatus(input *kms.GetKeyRotationStatusInput) (*kms.GetKeyRotationStatusOutput, error) {
+	f.requested = append(f.requested, aws.StringValue(input.KeyId))
+	return &kms.GetKeyRotationStatusOutput{KeyRotationEnabled: aws.Bool(f.rotation)}, nil
+}
+
+func (f *fakeKMSClient) GetKeyPolicy(input *kms.GetKeyPolicyInput) (*kms.GetKeyPolicyOutput, error) {
+	f.requested = append(f.requested, aws.StringValue(input.KeyId))
+	return &kms.GetKeyPolicyOutput{Policy: aws.String(f.policy)}, nil
+}
@@ -0,0 +1,154 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// LogGroup is a CloudWatch Logs group a configuration owns, and its region
+type LogGroup struct {
+	Name   string
+	Region string
+}
+
+type state struct {
+	Values *struct {
+		RootModule stateModule `json:"root_module"`
+	} `json:"values"`
+}
+
+type stateModule struct {
+	Resources    []stateResource `json:"resources"`
+	ChildModules []stateModule   `json:"child_modules"`
+}
+
+type stateResource struct {
+	Address string                 `json:"address"`
+	Mode    string                 `json:"mode"`
+	Type    string                 `json:"type"`
+	Values  map[string]interface{} `json:"values"`
+}
+
+// StateLogGroups lists the log groups in `terraform show -json` state: every
+// aws_cloudwatch_log_group, plus the /aws/lambda/<name> group of each Lambda
+// function, which Lambda creates with no retention if the configuration doesn't
+func StateLogGroups(stateJSON string) ([]LogGroup, error) {
+	var parsed state
+	if err := json.Unmarshal([]byte(stateJSON), &parsed); err != nil {
+		return nil, fmt.Errorf("parsing state: %w", err)
+	}
+	if parsed.Values == nil {
+		return nil, nil
+	}
+
+	seen := map[LogGroup]bool{}
+	var groups []LogGroup
+	var walk func(module stateModule)
+	walk = func(module stateModule) {
+		for _, resource := range module.Resources {
+			if resource.Mode != "managed" {
+				continue
+			}
+			var name string
+			switch resource.Type {
+			case "aws_cloudwatch_log_group":
+				name, _ = resource.Values["name"].(string)
+			case "aws_lambda_function":
+				function, _ := resource.Values["function_name"].(string)
+				name = "/aws/lambda/" + function
+			default:
+				continue
+			}
+			arn, _ := resource.Values["arn"].(string)
+			group := LogGroup{Name: name, Region: arnRegion(arn)}
+			if !seen[group] {
+				seen[group] = true
+				groups = append(groups, group)
+			}
+		}
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	walk(parsed.Values.RootModule)
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Region != groups[j].Region {
+			return groups[i].Region < groups[j].Region
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// LogGroupsWithoutRetention returns the named log groups that exist but never
+// expire. Groups that don't exist yet, such as those of uninvoked functions,
+// are skipped
+func LogGroupsWithoutRetention(logsSvc cloudwatchlogsiface.CloudWatchLogsAPI, names []string) ([]string, error) {
+	var missing []string
+	for _, name := range names {
+		err := logsSvc.DescribeLogGroupsPages(&cloudwatchlogs.DescribeLogGroupsInput{
+			LogGroupNamePrefix: aws.String(name),
+		}, func(page *cloudwatchlogs.DescribeLogGroupsOutput, lastPage bool) bool {
+			for _, group := range page.LogGroups {
+				if aws.StringValue(group.LogGroupName) == name && group.RetentionInDays == nil {
+					missing = append(missing, name)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// AssertLogGroupRetention fails for every log group in the state that never
+// expires, since those grow their storage bill forever. clientFor returns a
+// CloudWatch Logs client for a region
+func AssertLogGroupRetention(t testing.TB, stateJSON string, clientFor func(region string) cloudwatchlogsiface.CloudWatchLogsAPI) {
+	groups, err := StateLogGroups(stateJSON)
+	require.NoError(t, err)
+	require.NotEmpty(t, groups, "state should contain at least one log group")
+
+	byRegion := map[string][]string{}
+	for _, group := range groups {
+		byRegion[group.Region] = append(byRegion[group.Region], group.Name)
+	}
+	for region, names := range byRegion {
+		missing, err := LogGroupsWithoutRetention(clientFor(region), names)
+		require.NoError(t, err)
+		for _, name := range missing {
+			assert.Fail(t, "Log group never expires", "%s in %s has no retention set", name, region)
+		}
+	}
+}
+
+// LogsClient returns a CloudWatch Logs client for a region, for use as the
+// clientFor argument of AssertLogGroupRetention
+func LogsClient(region string) cloudwatchlogsiface.CloudWatchLogsAPI {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	}))
+	return cloudwatchlogs.New(sess)
+}
+
+func arnRegion(arn string) string {
+	parts := strings.SplitN(arn, ":", 5)
+	if len(parts) < 4 {
+		return ""
+	}
+	return parts[3]
+}
@@ -0,0 +1,93 @@
+package testkit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateLogGroups(t *testing.T) {
+	t.Parallel()
+
+	stateJSON := `{
+	  "values": {
+	    "root_module": {
+	      "resources": [
+	        {"mode": "managed", "type": "aws_cloudwatch_log_group", "values": {"name": "/aws/vpc/flowlogs/app", "arn": "arn:aws:logs:us-east-1:123456789012:log-group:/aws/vpc/flowlogs/app"}},
+	        {"mode": "managed", "type": "aws_lambda_function", "values": {"function_name": "app-scanner", "arn": "arn:aws:lambda:us-east-1:123456789012:function:app-scanner"}},
+	        {"mode": "managed", "type": "aws_cloudwatch_log_group", "values": {"name": "/aws/lambda/app-scanner", "arn": "arn:aws:logs:us-east-1:123456789012:log-group:/aws/lambda/app-scanner"}},
+	        {"mode": "data", "type": "aws_cloudwatch_log_group", "values": {"name": "/shared/other-team", "arn": "arn:aws:logs:us-east-1:123456789012:log-group:/shared/other-team"}},
+	        {"mode": "managed", "type": "aws_s3_bucket", "values": {"bucket": "app-logs"}}
+	      ],
+	      "child_modules": [
+	        {"resources": [
+	          {"mode": "managed", "type": "aws_cloudwatch_log_group", "values": {"name": "aws-waf-logs-app", "arn": "arn:aws:logs:us-west-2:123456789012:log-group:aws-waf-logs-app"}}
+	        ]}
+	      ]
+	    }
+	  }
+	}`
+
+	groups, err := StateLogGroups(stateJSON)
+	require.NoError(t, err)
+	assert.Equal(t, []LogGroup{
+		{Name: "/aws/lambda/app-scanner", Region: "us-east-1"},
+		{Name: "/aws/vpc/flowlogs/app", Region: "us-east-1"},
+		{Name: "aws-waf-logs-app", Region: "us-west-2"},
+	}, groups, "Managed groups, Lambda groups and child modules should be listed once, data sources skipped")
+
+	groups, err = StateLogGroups(`{"format_version": "1.0"}`)
+	require.NoError(t, err)
+	assert.Empty(t, groups, "Empty state has no log groups")
+
+	_, err = StateLogGroups("not json")
+	assert.Error(t, err)
+}
+
+func TestLogGroupsWithoutRetention(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeLogGroupsClient{pages: [][]*cloudwatchlogs.LogGroup{
+		{
+			{LogGroupName: aws.String("/aws/lambda/app-scanner"), RetentionInDays: aws.Int64(30)},
+			{LogGroupName: aws.String("/aws/lambda/app-api")},
+		},
+		{
+			{LogGroupName: aws.String("/aws/lambda/app-archiver")},
+			{LogGroupName: aws.String("/aws/lambda/app-api-extra")},
+		},
+	}}
+
+	missing, err := LogGroupsWithoutRetention(svc, []string{"/aws/lambda/app-scanner", "/aws/lambda/app-api", "/aws/lambda/app-archiver", "/aws/lambda/app-uninvoked"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/aws/lambda/app-api", "/aws/lambda/app-archiver"}, missing, "Only exact names on any page should be reported")
+	assert.Equal(t, []string{"/aws/lambda/app-scanner", "/aws/lambda/app-api", "/aws/lambda/app-archiver", "/aws/lambda/app-uninvoked"}, svc.prefixes)
+
+	_, err = LogGroupsWithoutRetention(&fakeLogGroupsClient{err: fmt.Errorf("AccessDeniedException")}, []string{"/aws/lambda/app-api"})
+	assert.Error(t, err)
+}
+
+type fakeLogGroupsClient struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	pages    [][]*cloudwatchlogs.LogGroup
+	err      error
+	prefixes []string
+}
+
+func (f *fakeLogGroupsClient) DescribeLogGroupsPages(input *cloudwatchlogs.DescribeLogGroupsInput, fn func(*cloudwatchlogs.DescribeLogGroupsOutput, bool) bool) error {
+	f.prefixes = append(f.prefixes, aws.StringValue(input.LogGroupNamePrefix))
+	if f.err != nil {
+		return f.err
+	}
+	for i, page := range f.pages {
+		if !fn(&cloudwatchlogs.DescribeLogGroupsOutput{LogGroups: page}, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package testkit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+)
+
+// monitoringSettleTimeout bounds how long detailed monitoring may take to
+// leave pending/enabling/disabling after an apply
+const monitoringSettleTimeout = 5 * time.Minute
+
+// MonitoringStateFor maps a module's detailed_monitoring output to the state
+// EC2 reports. A missing or malformed output is an error rather than "disabled".
+func MonitoringStateFor(detailedMonitoring string) (string, error) {
+	enabled, err := strconv.ParseBool(detailedMonitoring)
+	if err != nil {
+		return "", fmt.Errorf("detailed_monitoring output %q is not a bool: %w", detailedMonitoring, err)
+	}
+	if enabled {
+		return ec2.MonitoringStateEnabled, nil
+	}
+	return ec2.MonitoringStateDisabled, nil
+}
+
+// InstanceMonitoringState reads an instance's detailed monitoring state
+func InstanceMonitoringState(ec2Svc ec2iface.EC2API, instanceID string) (string, error) {
+	result, err := ec2Svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(instanceID)},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			if aws.StringValue(instance.InstanceId) == instanceID && instance.Monitoring != nil {
+				return aws.StringValue(instance.Monitoring.State), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("instance %s not found", instanceID)
+}
+
+// WaitForMonitoringState waits out pending/enabling/disabling until an
+// instance's monitoring settles. Settling on anything but expected fails
+// straight away instead of waiting out the timeout.
+func WaitForMonitoringState(ec2Svc ec2iface.EC2API, instanceID, expected string, timeout time.Duration) error {
+	lastState := "unknown"
+	err := Poll(timeout, func() (bool, error) {
+		state, err := InstanceMonitoringState(ec2Svc, instanceID)
+		if err != nil {
+			return false, err
+		}
+		lastState = state
+
+		switch state {
+		case expected:
+			return true, nil
+		case ec2.MonitoringStatePending, ec2.MonitoringStateDisabling, "enabling":
+			return false, nil
+		default:
+			return false, fmt.Errorf("monitoring on %s is %s, expected %s", instanceID, state, expected)
+		}
+	})
+	if errors.Is(err, ErrPollTimeout) {
+		return fmt.Errorf("monitoring on %s did not reach %s within %s (last state %s)", instanceID, expected, timeout, lastState)
+	}
+	return err
+}
+
+// AssertInstanceMonitoring asserts an instance's detailed monitoring settles
+// on the expected state
+func AssertInstanceMonitoring(t testing.TB, ec2Svc ec2iface.EC2API, instanceID, expected string) {
+	t.Helper()
+
+	err := WaitForMonitoringState(ec2Svc, instanceID, expected, monitoringSettleTimeout)
+	assert.NoError(t, err, "Instance %s detailed monitoring should be %s", instanceID, expected)
+}
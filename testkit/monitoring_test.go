@@ -0,0 +1,79 @@
+package testkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitoringStateFor(t *testing.T) {
+	t.Parallel()
+
+	state, err := MonitoringStateFor("true")
+	require.NoError(t, err)
+	assert.Equal(t, ec2.MonitoringStateEnabled, state)
+
+	state, err = MonitoringStateFor("false")
+	require.NoError(t, err)
+	assert.Equal(t, ec2.MonitoringStateDisabled, state)
+
+	_, err = MonitoringStateFor("")
+	assert.Error(t, err, "A missing output should not be read as disabled")
+}
+
+func TestWaitForMonitoringState(t *testing.T) {
+	t.Parallel()
+
+	// Transitional states are polled until the expected state appears
+	svc := &fakeMonitoringClient{states: []string{ec2.MonitoringStatePending, "enabling", ec2.MonitoringStateEnabled}}
+	require.NoError(t, WaitForMonitoringState(svc, "i-0123456789abcdef0", ec2.MonitoringStateEnabled, time.Second))
+	assert.Equal(t, 3, svc.calls)
+	assert.Equal(t, []string{"i-0123456789abcdef0"}, aws.StringValueSlice(svc.lastInput.InstanceIds))
+
+	// A settled, different state fails immediately rather than waiting out the timeout
+	svc = &fakeMonitoringClient{states: []string{ec2.MonitoringStateDisabled}}
+	assert.Error(t, WaitForMonitoringState(svc, "i-0123456789abcdef0", ec2.MonitoringStateEnabled, time.Second))
+	assert.Equal(t, 1, svc.calls)
+
+	svc = &fakeMonitoringClient{states: []string{ec2.MonitoringStateDisabling, ec2.MonitoringStateDisabled}}
+	require.NoError(t, WaitForMonitoringState(svc, "i-0123456789abcdef0", ec2.MonitoringStateDisabled, time.Second))
+
+	// Stuck in a transitional state eventually gives up, naming the last state
+	svc = &fakeMonitoringClient{states: []string{ec2.MonitoringStatePending}}
+	err := WaitForMonitoringState(svc, "i-0123456789abcdef0", ec2.MonitoringStateEnabled, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "last state pending")
+}
+
+type fakeMonitoringClient struct {
+	ec2iface.EC2API
+	states    []string
+	calls     int
+	lastInput *ec2.DescribeInstancesInput
+}
+
+func (f *fakeMonitoringClient) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	f.lastInput = input
+	state := f.states[len(f.states)-1]
+	if f.calls < len(f.states) {
+		state = f.states[f.calls]
+	}
+	f.calls++
+
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{
+			{
+				Instances: []*ec2.Instance{
+					{
+						InstanceId: input.InstanceIds[0],
+						Monitoring: &ec2.Monitoring{State: aws.String(state)},
+					},
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,47 @@
+package testkit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxPollInterval caps the wait between checks on long timeouts
+const maxPollInterval = 15 * time.Second
+
+// ErrPollTimeout is wrapped by the error Poll returns when check never
+// reported done
+var ErrPollTimeout = errors.New("timed out")
+
+// Poll calls check until it reports done or returns an error, sleeping
+// timeout/20 (at most 15 seconds) between calls so a transition is noticed
+// promptly without hammering the API. Once timeout has passed it returns an
+// error wrapping ErrPollTimeout; callers that want the last observed state in
+// the error keep it themselves.
+func Poll(timeout time.Duration, check func() (done bool, err error)) error {
+	interval := timeout / 20
+	if interval > maxPollInterval {
+		interval = maxPollInterval
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w after %s", ErrPollTimeout, timeout)
+		}
+		if remaining < interval {
+			time.Sleep(remaining)
+		} else {
+			time.Sleep(interval)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package testkit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoll(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	err := Poll(time.Second, func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls, "Poll should stop as soon as check is done")
+
+	calls = 0
+	err = Poll(time.Second, func() (bool, error) {
+		calls++
+		return false, errors.New("NAT Gateway failed")
+	})
+	assert.EqualError(t, err, "NAT Gateway failed")
+	assert.Equal(t, 1, calls, "Errors from check should stop polling")
+
+	start := time.Now()
+	err = Poll(200*time.Millisecond, func() (bool, error) { return false, nil })
+	assert.ErrorIs(t, err, ErrPollTimeout)
+	assert.EqualError(t, err, "timed out after 200ms")
+	assert.Less(t, time.Since(start), time.Second, "Poll shouldn't sleep past its timeout")
+}
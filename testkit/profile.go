@@ -0,0 +1,57 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// ProfileCase is one plan of a module's security profile: the variables that
+// pick the profile (and any overrides) and the settings the plan should show
+type ProfileCase[S any] struct {
+	Name string
+	Vars map[string]interface{}
+	Want S
+}
+
+// RunProfilePlans plans the module in moduleDir once per case, with baseVars
+// underneath the case's own, and compares the settings read from each plan
+// against the case's Want. settings may also assert invariants that hold
+// under every profile. A profile outside minimal and hardened must fail
+// validation.
+func RunProfilePlans[S any](t *testing.T, moduleDir string, baseVars map[string]interface{}, cases []ProfileCase[S], settings func(t *testing.T, plan *terraform.PlanStruct) S) {
+	// Every plan shares the module's .terraform directory, so cases run in turn
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			terraformOptions := &terraform.Options{
+				TerraformDir: moduleDir,
+				Vars:         mergeVars(baseVars, tc.Vars),
+			}
+
+			plan := terraform.InitAndPlanAndShowWithStructNoLogTempPlanFile(t, terraformOptions)
+			assert.Equal(t, tc.Vars["profile"], plan.RawPlan.PlannedValues.Outputs["profile"].Value)
+			assert.Equal(t, tc.Want, settings(t, plan))
+		})
+	}
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: moduleDir,
+		Vars:         mergeVars(baseVars, map[string]interface{}{"profile": "paranoid"}),
+	}
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	assert.ErrorContains(t, err, "profile must be minimal or hardened")
+}
+
+// mergeVars layers overrides on top of base without modifying either
+func mergeVars(base, overrides map[string]interface{}) map[string]interface{} {
+	vars := make(map[string]interface{}, len(base)+len(overrides))
+	for name, value := range base {
+		vars[name] = value
+	}
+	for name, value := range overrides {
+		vars[name] = value
+	}
+	return vars
+}
@@ -0,0 +1,17 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeVars(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]interface{}{"domain_name": "example.com", "profile": "minimal"}
+	merged := mergeVars(base, map[string]interface{}{"profile": "hardened", "log_retention_days": 90})
+	assert.Equal(t, map[string]interface{}{"domain_name": "example.com", "profile": "hardened", "log_retention_days": 90}, merged)
+	assert.Equal(t, "minimal", base["profile"], "The base variables should be left alone")
+	assert.Equal(t, base, mergeVars(base, nil))
+}
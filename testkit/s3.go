@@ -0,0 +1,123 @@
+package testkit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertNoLifecycleGap asserts a bucket has an enabled lifecycle rule that expires
+// every object under prefix ("" for the whole bucket), and noncurrent versions
+// too when the bucket is versioned. Transitions alone don't count: the objects
+// still accumulate, just in a cheaper storage class.
+func AssertNoLifecycleGap(t testing.TB, s3Svc s3iface.S3API, bucket, prefix string) {
+	t.Helper()
+
+	versioning, err := s3Svc.GetBucketVersioning(&s3.GetBucketVersioningInput{Bucket: aws.String(bucket)})
+	require.NoError(t, err)
+	versioned := aws.StringValue(versioning.Status) == s3.BucketVersioningStatusEnabled
+
+	var rules []*s3.LifecycleRule
+	lifecycle, err := s3Svc.GetBucketLifecycleConfiguration(&s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(bucket)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NoSuchLifecycleConfiguration" {
+		err = nil
+	} else if err == nil {
+		rules = lifecycle.Rules
+	}
+	require.NoError(t, err)
+
+	for _, problem := range LifecycleGapProblems(rules, prefix, versioned) {
+		assert.Fail(t, "Bucket "+bucket+" can accumulate objects forever", problem)
+	}
+}
+
+// LifecycleGapProblems describes which objects under prefix no enabled rule expires
+func LifecycleGapProblems(rules []*s3.LifecycleRule, prefix string, versioned bool) []string {
+	var expiresCurrent, expiresNoncurrent bool
+	for _, rule := range rules {
+		if aws.StringValue(rule.Status) != s3.ExpirationStatusEnabled || !lifecycleRuleCovers(rule, prefix) {
+			continue
+		}
+		if rule.Expiration != nil && (aws.Int64Value(rule.Expiration.Days) > 0 || rule.Expiration.Date != nil) {
+			expiresCurrent = true
+		}
+		if rule.NoncurrentVersionExpiration != nil && aws.Int64Value(rule.NoncurrentVersionExpiration.NoncurrentDays) > 0 {
+			expiresNoncurrent = true
+		}
+	}
+
+	scope := "in the whole bucket"
+	if prefix != "" {
+		scope = "under " + prefix
+	}
+	var problems []string
+	if !expiresCurrent {
+		problems = append(problems, "no enabled lifecycle rule expires objects "+scope)
+	}
+	if versioned && !expiresNoncurrent {
+		problems = append(problems, "no enabled lifecycle rule expires noncurrent versions "+scope)
+	}
+	return problems
+}
+
+// lifecycleRuleCovers checks a rule applies to every object under prefix. Tag
+// and object size filters leave other objects uncovered.
+func lifecycleRuleCovers(rule *s3.LifecycleRule, prefix string) bool {
+	rulePrefix := aws.StringValue(rule.Prefix)
+	if filter := rule.Filter; filter != nil {
+		if filter.Tag != nil || filter.ObjectSizeGreaterThan != nil || filter.ObjectSizeLessThan != nil {
+			return false
+		}
+		rulePrefix = aws.StringValue(filter.Prefix)
+		if and := filter.And; and != nil {
+			if len(and.Tags) > 0 || and.ObjectSizeGreaterThan != nil || and.ObjectSizeLessThan != nil {
+				return false
+			}
+			rulePrefix = aws.StringValue(and.Prefix)
+		}
+	}
+	return strings.HasPrefix(prefix, rulePrefix)
+}
+
+// AssertBucketObjectOwnership asserts a bucket's object ownership. Buckets that don't need
+// ACLs must disable them with BucketOwnerEnforced; logging buckets whose
+// delivery still writes through ACLs must opt out explicitly with
+// BucketOwnerPreferred rather than leaving ownership controls unset.
+func AssertBucketObjectOwnership(t testing.TB, svc s3iface.S3API, bucket string, aclsRequired bool) {
+	t.Helper()
+
+	ownership, err := BucketObjectOwnership(svc, bucket)
+	require.NoError(t, err)
+
+	if aclsRequired {
+		assert.Equal(t, s3.ObjectOwnershipBucketOwnerPreferred, ownership, "Logging bucket %s should opt out of BucketOwnerEnforced explicitly", bucket)
+		return
+	}
+	assert.Equal(t, s3.ObjectOwnershipBucketOwnerEnforced, ownership, "Bucket %s should have ACLs disabled", bucket)
+}
+
+// BucketObjectOwnership reads a bucket's object ownership setting. Returns an
+// empty string when no ownership controls are configured.
+func BucketObjectOwnership(svc s3iface.S3API, bucket string) (string, error) {
+	result, err := svc.GetBucketOwnershipControls(&s3.GetBucketOwnershipControlsInput{
+		Bucket: aws.String(bucket),
+	})
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == "OwnershipControlsNotFoundError" {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if result.OwnershipControls == nil || len(result.OwnershipControls.Rules) == 0 {
+		return "", nil
+	}
+	return aws.StringValue(result.OwnershipControls.Rules[0].ObjectOwnership), nil
+}
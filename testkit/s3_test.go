@@ -0,0 +1,102 @@
+package testkit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleGapProblems(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		lifecycle string
+		prefix    string
+		versioned bool
+		problems  []string
+	}{
+		{
+			name:      "whole-bucket expiration",
+			lifecycle: `{"Rules": [{"ID": "expire-logs", "Status": "Enabled", "Filter": {}, "Expiration": {"Days": 365}, "NoncurrentVersionExpiration": {"NoncurrentDays": 30}}]}`,
+			versioned: true,
+		},
+		{
+			name:      "legacy prefix covering the configured prefix",
+			lifecycle: `{"Rules": [{"ID": "expire-logs", "Status": "Enabled", "Prefix": "AWSLogs/", "Expiration": {"Days": 90}}]}`,
+			prefix:    "AWSLogs/123456789012/",
+		},
+		{
+			name:      "no rules at all",
+			lifecycle: `{"Rules": []}`,
+			problems:  []string{"no enabled lifecycle rule expires objects in the whole bucket"},
+		},
+		{
+			name:      "disabled rule and transition only",
+			lifecycle: `{"Rules": [{"ID": "off", "Status": "Disabled", "Filter": {}, "Expiration": {"Days": 30}}, {"ID": "archive", "Status": "Enabled", "Filter": {}, "Transitions": [{"Days": 30, "StorageClass": "GLACIER"}]}]}`,
+			problems:  []string{"no enabled lifecycle rule expires objects in the whole bucket"},
+		},
+		{
+			name:      "rule scoped to a narrower prefix",
+			lifecycle: `{"Rules": [{"ID": "tmp", "Status": "Enabled", "Filter": {"Prefix": "tmp/"}, "Expiration": {"Days": 1}}]}`,
+			problems:  []string{"no enabled lifecycle rule expires objects in the whole bucket"},
+		},
+		{
+			name:      "tag filters only cover tagged objects",
+			lifecycle: `{"Rules": [{"ID": "tagged", "Status": "Enabled", "Filter": {"And": {"Prefix": "", "Tags": [{"Key": "expire", "Value": "true"}]}}, "Expiration": {"Days": 30}}]}`,
+			problems:  []string{"no enabled lifecycle rule expires objects in the whole bucket"},
+		},
+		{
+			name:      "versioned bucket keeps noncurrent versions forever",
+			lifecycle: `{"Rules": [{"ID": "expire-logs", "Status": "Enabled", "Filter": {"Prefix": ""}, "Expiration": {"Days": 365}}]}`,
+			prefix:    "logs/",
+			versioned: true,
+			problems:  []string{"no enabled lifecycle rule expires noncurrent versions under logs/"},
+		},
+	}
+
+	for _, tc := range testCases {
+		var lifecycle s3.GetBucketLifecycleConfigurationOutput
+		require.NoError(t, json.Unmarshal([]byte(tc.lifecycle), &lifecycle), tc.name)
+		assert.Equal(t, tc.problems, LifecycleGapProblems(lifecycle.Rules, tc.prefix, tc.versioned), tc.name)
+	}
+}
+
+func TestBucketObjectOwnership(t *testing.T) {
+	t.Parallel()
+
+	ownership, err := BucketObjectOwnership(&fakeOwnershipControlsClient{ownership: s3.ObjectOwnershipBucketOwnerEnforced}, "cloudtrail-logs")
+	require.NoError(t, err)
+	assert.Equal(t, s3.ObjectOwnershipBucketOwnerEnforced, ownership)
+
+	// Buckets created before ownership controls existed report not found
+	ownership, err = BucketObjectOwnership(&fakeOwnershipControlsClient{err: awserr.New("OwnershipControlsNotFoundError", "not found", nil)}, "cloudtrail-logs")
+	require.NoError(t, err)
+	assert.Empty(t, ownership)
+
+	_, err = BucketObjectOwnership(&fakeOwnershipControlsClient{err: awserr.New("AccessDenied", "denied", nil)}, "cloudtrail-logs")
+	assert.Error(t, err)
+}
+
+type fakeOwnershipControlsClient struct {
+	s3iface.S3API
+	ownership string
+	err       error
+}
+
+func (f *fakeOwnershipControlsClient) GetBucketOwnershipControls(input *s3.GetBucketOwnershipControlsInput) (*s3.GetBucketOwnershipControlsOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &s3.GetBucketOwnershipControlsOutput{
+		OwnershipControls: &s3.OwnershipControls{
+			Rules: []*s3.OwnershipControlsRule{{ObjectOwnership: aws.String(f.ownership)}},
+		},
+	}, nil
+}
@@ -0,0 +1,78 @@
+package testkit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertSecurityGroupRuleDescriptions asserts every ingress and egress rule of
+// the given security groups carries a description, as CIS expects rules to
+// document their intent
+func AssertSecurityGroupRuleDescriptions(t testing.TB, svc ec2iface.EC2API, groupIDs ...string) {
+	t.Helper()
+
+	undescribed, err := UndescribedSecurityGroupRules(svc, groupIDs...)
+	require.NoError(t, err)
+	assert.Empty(t, undescribed, "Security group rules should all have descriptions")
+}
+
+// UndescribedSecurityGroupRules lists security group rules without a
+// description. Each CIDR, prefix list and group reference in a permission has
+// its own description.
+func UndescribedSecurityGroupRules(svc ec2iface.EC2API, groupIDs ...string) ([]string, error) {
+	result, err := svc.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: aws.StringSlice(groupIDs),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var undescribed []string
+	for _, group := range result.SecurityGroups {
+		directions := map[string][]*ec2.IpPermission{
+			"ingress": group.IpPermissions,
+			"egress":  group.IpPermissionsEgress,
+		}
+		for _, direction := range []string{"ingress", "egress"} {
+			for _, permission := range directions[direction] {
+				rule := fmt.Sprintf("%s %s %s", aws.StringValue(group.GroupId), direction, permissionPorts(permission))
+				for _, ipRange := range permission.IpRanges {
+					if aws.StringValue(ipRange.Description) == "" {
+						undescribed = append(undescribed, rule+" "+aws.StringValue(ipRange.CidrIp))
+					}
+				}
+				for _, ipRange := range permission.Ipv6Ranges {
+					if aws.StringValue(ipRange.Description) == "" {
+						undescribed = append(undescribed, rule+" "+aws.StringValue(ipRange.CidrIpv6))
+					}
+				}
+				for _, prefixList := range permission.PrefixListIds {
+					if aws.StringValue(prefixList.Description) == "" {
+						undescribed = append(undescribed, rule+" "+aws.StringValue(prefixList.PrefixListId))
+					}
+				}
+				for _, pair := range permission.UserIdGroupPairs {
+					if aws.StringValue(pair.Description) == "" {
+						undescribed = append(undescribed, rule+" "+aws.StringValue(pair.GroupId))
+					}
+				}
+			}
+		}
+	}
+	return undescribed, nil
+}
+
+// permissionPorts renders a permission's protocol and port range
+func permissionPorts(permission *ec2.IpPermission) string {
+	protocol := aws.StringValue(permission.IpProtocol)
+	if protocol == "-1" {
+		return "all"
+	}
+	return fmt.Sprintf("%s/%d-%d", protocol, aws.Int64Value(permission.FromPort), aws.Int64Value(permission.ToPort))
+}
@@ -0,0 +1,73 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndescribedSecurityGroupRules(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeSecurityGroupClient{
+		groups: []*ec2.SecurityGroup{
+			{
+				GroupId: aws.String("sg-public"),
+				IpPermissions: []*ec2.IpPermission{
+					{
+						IpProtocol: aws.String("tcp"),
+						FromPort:   aws.Int64(80),
+						ToPort:     aws.Int64(80),
+						IpRanges: []*ec2.IpRange{
+							{CidrIp: aws.String("203.0.113.0/24"), Description: aws.String("HTTP from allowed CIDRs")},
+							{CidrIp: aws.String("198.51.100.0/24")},
+						},
+					},
+				},
+				IpPermissionsEgress: []*ec2.IpPermission{
+					{
+						IpProtocol: aws.String("-1"),
+						IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+						Ipv6Ranges: []*ec2.Ipv6Range{{CidrIpv6: aws.String("::/0"), Description: aws.String("All outbound")}},
+					},
+				},
+			},
+			{
+				GroupId: aws.String("sg-private"),
+				IpPermissions: []*ec2.IpPermission{
+					{
+						IpProtocol:       aws.String("tcp"),
+						FromPort:         aws.Int64(22),
+						ToPort:           aws.Int64(22),
+						UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-public")}},
+						PrefixListIds:    []*ec2.PrefixListId{{PrefixListId: aws.String("pl-123"), Description: aws.String("S3")}},
+					},
+				},
+			},
+		},
+	}
+
+	undescribed, err := UndescribedSecurityGroupRules(svc, "sg-public", "sg-private")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"sg-public ingress tcp/80-80 198.51.100.0/24",
+		"sg-public egress all 0.0.0.0/0",
+		"sg-private ingress tcp/22-22 sg-public",
+	}, undescribed)
+	assert.Equal(t, []string{"sg-public", "sg-private"}, aws.StringValueSlice(svc.lastInput.GroupIds))
+}
+
+type fakeSecurityGroupClient struct {
+	ec2iface.EC2API
+	groups    []*ec2.SecurityGroup
+	lastInput *ec2.DescribeSecurityGroupsInput
+}
+
+func (f *fakeSecurityGroupClient) DescribeSecurityGroups(input *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+	f.lastInput = input
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.groups}, nil
+}
@@ -0,0 +1,57 @@
+package testkit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ExpectedSubnet is the declared configuration of a subnet
+type ExpectedSubnet struct {
+	CIDR   string
+	AZ     string
+	Public bool // Whether instances launched in it get a public IP
+}
+
+// AssertSubnets asserts live subnets match their declared CIDR, AZ and public
+// IP auto-assignment, keyed by subnet ID
+func AssertSubnets(t testing.TB, svc ec2iface.EC2API, want map[string]ExpectedSubnet) {
+	t.Helper()
+
+	var subnetIDs []string
+	for subnetID := range want {
+		subnetIDs = append(subnetIDs, subnetID)
+	}
+	result, err := svc.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnetIDs),
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Subnets, len(want), "Every declared subnet should exist")
+
+	for _, subnet := range result.Subnets {
+		subnetID := aws.StringValue(subnet.SubnetId)
+		for _, problem := range SubnetProblems(subnet, want[subnetID]) {
+			assert.Fail(t, fmt.Sprintf("Subnet %s does not match its declared configuration", subnetID), problem)
+		}
+	}
+}
+
+// SubnetProblems lists how a live subnet differs from its declared configuration
+func SubnetProblems(subnet *ec2.Subnet, want ExpectedSubnet) []string {
+	var problems []string
+	if cidr := aws.StringValue(subnet.CidrBlock); cidr != want.CIDR {
+		problems = append(problems, fmt.Sprintf("CidrBlock is %s, want %s", cidr, want.CIDR))
+	}
+	if az := aws.StringValue(subnet.AvailabilityZone); az != want.AZ {
+		problems = append(problems, fmt.Sprintf("AvailabilityZone is %s, want %s", az, want.AZ))
+	}
+	if public := aws.BoolValue(subnet.MapPublicIpOnLaunch); public != want.Public {
+		problems = append(problems, fmt.Sprintf("MapPublicIpOnLaunch is %t, want %t", public, want.Public))
+	}
+	return problems
+}
@@ -0,0 +1,54 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubnetProblems(t *testing.T) {
+	t.Parallel()
+
+	subnet := &ec2.Subnet{
+		SubnetId:            aws.String("subnet-public"),
+		CidrBlock:           aws.String("10.0.1.0/24"),
+		AvailabilityZone:    aws.String("us-east-1a"),
+		MapPublicIpOnLaunch: aws.Bool(true),
+	}
+	assert.Empty(t, SubnetProblems(subnet, ExpectedSubnet{CIDR: "10.0.1.0/24", AZ: "us-east-1a", Public: true}))
+
+	// A private subnet that hands out public IPs is the gap this guards against
+	assert.Equal(t, []string{
+		"CidrBlock is 10.0.1.0/24, want 10.0.2.0/24",
+		"AvailabilityZone is us-east-1a, want us-east-1b",
+		"MapPublicIpOnLaunch is true, want false",
+	}, SubnetProblems(subnet, ExpectedSubnet{CIDR: "10.0.2.0/24", AZ: "us-east-1b", Public: false}))
+}
+
+func TestAssertSubnets(t *testing.T) {
+	t.Parallel()
+
+	svc := &fakeSubnetClient{subnets: []*ec2.Subnet{
+		{SubnetId: aws.String("subnet-private"), CidrBlock: aws.String("10.0.2.0/24"), AvailabilityZone: aws.String("us-east-1a"), MapPublicIpOnLaunch: aws.Bool(false)},
+		{SubnetId: aws.String("subnet-public"), CidrBlock: aws.String("10.0.1.0/24"), AvailabilityZone: aws.String("us-east-1a"), MapPublicIpOnLaunch: aws.Bool(true)},
+	}}
+	AssertSubnets(t, svc, map[string]ExpectedSubnet{
+		"subnet-public":  {CIDR: "10.0.1.0/24", AZ: "us-east-1a", Public: true},
+		"subnet-private": {CIDR: "10.0.2.0/24", AZ: "us-east-1a", Public: false},
+	})
+	assert.ElementsMatch(t, []string{"subnet-public", "subnet-private"}, aws.StringValueSlice(svc.lastInput.SubnetIds))
+}
+
+type fakeSubnetClient struct {
+	ec2iface.EC2API
+	subnets   []*ec2.Subnet
+	lastInput *ec2.DescribeSubnetsInput
+}
+
+func (f *fakeSubnetClient) DescribeSubnets(input *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	f.lastInput = input
+	return &ec2.DescribeSubnetsOutput{Subnets: f.subnets}, nil
+}
@@ -0,0 +1,230 @@
+package testkit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MinimumTerraformVersion is the oldest Terraform release every module is
+// expected to support (matches CI)
+const MinimumTerraformVersion = "1.5.0"
+
+// ProviderRequirement is one entry of a required_providers block
+type ProviderRequirement struct {
+	Source  string
+	Version string
+}
+
+// VersionRequirements are the terraform blocks of a module, merged across files
+type VersionRequirements struct {
+	TerraformVersion string
+	Providers        map[string]ProviderRequirement
+}
+
+// AssertVersionConstraints asserts the module in moduleDir admits
+// MinimumTerraformVersion and sources every provider, AWS included, from a
+// bounded version range
+func AssertVersionConstraints(t testing.TB, moduleDir string) {
+	reqs, err := ParseVersionRequirements(moduleDir)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, reqs.TerraformVersion, "required_version must be declared")
+	minTerraform, err := MinimumVersion(reqs.TerraformVersion)
+	require.NoError(t, err)
+	assert.Equal(t, MinimumTerraformVersion, minTerraform)
+
+	require.NotEmpty(t, reqs.Providers, "required_providers must be declared")
+	require.Contains(t, reqs.Providers, "aws")
+	for name, provider := range reqs.Providers {
+		assert.NotEmpty(t, provider.Source, "Provider %s should declare a source", name)
+		assert.NotEmpty(t, provider.Version, "Provider %s should declare a version constraint", name)
+		assert.True(t, IsBoundedConstraint(provider.Version), "Provider %s has an unbounded version range: %q", name, provider.Version)
+	}
+}
+
+// RunProviderVersionMatrix initialises and validates a copy of the module in
+// moduleDir twice: "pinned" against the lowest provider versions its
+// constraints allow, "latest" against the newest. No lockfile is copied, so
+// each case resolves versions from scratch into its own plugin cache.
+func RunProviderVersionMatrix(t *testing.T, moduleDir string) {
+	reqs, err := ParseVersionRequirements(moduleDir)
+	require.NoError(t, err)
+
+	matrix := map[string]bool{
+		"pinned": true,
+		"latest": false,
+	}
+
+	for name, pinned := range matrix {
+		name, pinned := name, pinned
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			workingDir := test_structure.CopyTerraformFolderToTemp(t, moduleDir, ".")
+			if pinned {
+				override, err := PinnedProvidersOverride(reqs.Providers)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(workingDir, "versions_override.tf"), []byte(override), 0o644))
+			}
+
+			terraformOptions := &terraform.Options{
+				TerraformDir: workingDir,
+				EnvVars: map[string]string{
+					"TF_PLUGIN_CACHE_DIR": t.TempDir(),
+				},
+			}
+
+			terraform.RunTerraformCommand(t, terraformOptions, "init", "-backend=false", "-upgrade", "-input=false")
+			terraform.Validate(t, terraformOptions)
+		})
+	}
+}
+
+// ParseVersionRequirements reads the terraform blocks of every .tf file in dir
+func ParseVersionRequirements(dir string) (*VersionRequirements, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	reqs := &VersionRequirements{Providers: map[string]ProviderRequirement{}}
+
+	for _, file := range files {
+		f, diags := parser.ParseHCLFile(file)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+
+			if attr, ok := block.Body.Attributes["required_version"]; ok {
+				value, diags := attr.Expr.Value(nil)
+				if diags.HasErrors() {
+					return nil, diags
+				}
+				reqs.TerraformVersion = value.AsString()
+			}
+
+			for _, inner := range block.Body.Blocks {
+				if inner.Type != "required_providers" {
+					continue
+				}
+				for name, attr := range inner.Body.Attributes {
+					value, diags := attr.Expr.Value(nil)
+					if diags.HasErrors() {
+						return nil, diags
+					}
+
+					provider := ProviderRequirement{}
+					if value.Type().IsObjectType() {
+						if value.Type().HasAttribute("source") {
+							provider.Source = value.GetAttr("source").AsString()
+						}
+						if value.Type().HasAttribute("version") {
+							provider.Version = value.GetAttr("version").AsString()
+						}
+					}
+					reqs.Providers[name] = provider
+				}
+			}
+		}
+	}
+
+	return reqs, nil
+}
+
+var versionConstraintPattern = regexp.MustCompile(`^(~>|>=|<=|!=|>|<|=)?\s*v?(\d+(?:\.\d+)*)$`)
+
+// parseConstraint splits a constraint string into operator/version pairs
+func parseConstraint(constraint string) ([][2]string, error) {
+	var parts [][2]string
+	for _, raw := range strings.Split(constraint, ",") {
+		match := versionConstraintPattern.FindStringSubmatch(strings.TrimSpace(raw))
+		if match == nil {
+			return nil, fmt.Errorf("invalid version constraint %q", raw)
+		}
+		op := match[1]
+		if op == "" {
+			op = "="
+		}
+		parts = append(parts, [2]string{op, match[2]})
+	}
+	return parts, nil
+}
+
+// IsBoundedConstraint reports whether a constraint has an upper bound
+func IsBoundedConstraint(constraint string) bool {
+	parts, err := parseConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	for _, part := range parts {
+		switch part[0] {
+		case "~>", "<", "<=", "=":
+			return true
+		}
+	}
+	return false
+}
+
+// MinimumVersion returns the lowest version a constraint allows
+func MinimumVersion(constraint string) (string, error) {
+	parts, err := parseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	for _, part := range parts {
+		switch part[0] {
+		case "~>", ">=", "=":
+			segments := strings.Split(part[1], ".")
+			for len(segments) < 3 {
+				segments = append(segments, "0")
+			}
+			return strings.Join(segments, "."), nil
+		}
+	}
+	return "", fmt.Errorf("version constraint %q has no lower bound", constraint)
+}
+
+// PinnedProvidersOverride renders an override file pinning every provider to
+// the minimum version its constraint allows
+func PinnedProvidersOverride(providers map[string]ProviderRequirement) (string, error) {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("terraform {\n  required_providers {\n")
+	for _, name := range names {
+		minimum, err := MinimumVersion(providers[name].Version)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "    %s = {\n      source  = %q\n      version = %q\n    }\n", name, providers[name].Source, "= "+minimum)
+	}
+	b.WriteString("  }\n}\n")
+	return b.String(), nil
+}
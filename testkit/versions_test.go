@@ -0,0 +1,67 @@
+package testkit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionConstraintHelpers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		constraint string
+		bounded    bool
+		minimum    string
+	}{
+		{"~> 5.0", true, "5.0.0"},
+		{">= 3.6.0, < 4.0.0", true, "3.6.0"},
+		{"= 1.2.3", true, "1.2.3"},
+		{"1.2.3", true, "1.2.3"},
+		{">= 1.5.0", false, "1.5.0"},
+		{">= 3.6", false, "3.6.0"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.bounded, IsBoundedConstraint(tc.constraint), "IsBoundedConstraint(%q)", tc.constraint)
+
+		minimum, err := MinimumVersion(tc.constraint)
+		require.NoError(t, err, "MinimumVersion(%q)", tc.constraint)
+		assert.Equal(t, tc.minimum, minimum, "MinimumVersion(%q)", tc.constraint)
+	}
+
+	_, err := MinimumVersion("< 4.0.0")
+	assert.Error(t, err, "A constraint without a lower bound has no minimum version")
+}
+
+func TestParseVersionRequirements(t *testing.T) {
+	t.Parallel()
+
+	moduleDir := t.TempDir()
+	files := map[string]string{
+		"terraform.tf": "terraform {\n  required_version = \">= 1.5.0\"\n  required_providers {\n    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"~> 5.0\"\n    }\n  }\n}\n",
+		"random.tf":    "terraform {\n  required_providers {\n    random = {\n      source  = \"hashicorp/random\"\n      version = \">= 3.6.0, < 4.0.0\"\n    }\n  }\n}\n",
+		"main.tf":      "locals {\n  name = \"example\"\n}\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(moduleDir, name), []byte(content), 0o644))
+	}
+
+	reqs, err := ParseVersionRequirements(moduleDir)
+	require.NoError(t, err)
+	assert.Equal(t, ">= 1.5.0", reqs.TerraformVersion)
+	assert.Equal(t, map[string]ProviderRequirement{
+		"aws":    {Source: "hashicorp/aws", Version: "~> 5.0"},
+		"random": {Source: "hashicorp/random", Version: ">= 3.6.0, < 4.0.0"},
+	}, reqs.Providers)
+
+	override, err := PinnedProvidersOverride(reqs.Providers)
+	require.NoError(t, err)
+	assert.Equal(t, "terraform {\n  required_providers {\n"+
+		"    aws = {\n      source  = \"hashicorp/aws\"\n      version = \"= 5.0.0\"\n    }\n"+
+		"    random = {\n      source  = \"hashicorp/random\"\n      version = \"= 3.6.0\"\n    }\n"+
+		"  }\n}\n", override)
+}